@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// browseAddr returns the host:port to reach a server listening on addr. A
+// server listening on every interface is reached on localhost.
+func browseAddr(addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return addr.String()
+	}
+	host := tcp.IP.String()
+	if tcp.IP == nil || tcp.IP.IsUnspecified() {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(tcp.Port))
+}
+
+// openBrowser opens url in the user's default browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// don't leave a zombie behind
+	go cmd.Wait()
+	return nil
+}
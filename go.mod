@@ -3,11 +3,17 @@ module github.com/jmoiron/qbedit
 go 1.24.4
 
 require (
-	github.com/go-chi/chi/v5 v5.2.3 // indirect
-	github.com/go-sprout/sprout v1.0.2 // indirect
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/go-chi/chi/v5 v5.2.3
+	github.com/go-sprout/sprout v1.0.2
+	github.com/spf13/pflag v1.0.10
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
 	github.com/pointlander/compress v1.1.1-0.20190518213731-ff44bd196cc3 // indirect
 	github.com/pointlander/jetset v1.0.1-0.20190518214125-eee7eff80bd4 // indirect
 	github.com/pointlander/peg v1.0.1 // indirect
-	github.com/spf13/pflag v1.0.10 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.28.0 // indirect
 )
@@ -4,21 +4,30 @@ import (
 	"bytes"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"log/slog"
+	"maps"
 	"mime"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-sprout/sprout"
+	"github.com/jmoiron/qbedit/internal/app/i18n"
 	"github.com/jmoiron/qbedit/internal/app/mcformat"
 	"github.com/jmoiron/qbedit/snbt"
 )
@@ -26,17 +35,90 @@ import (
 // Chapter type is defined in quests.go
 
 type App struct {
-	Root      string
-	MCVersion string
-	Verbose   int
-	QB        *QuestBook
-	tpl       *template.Template
+	// paths are the book's ftbquests dir and lang file, which a sandbox
+	// changes while requests and the watcher read them; see Root
+	paths atomic.Pointer[bookPaths]
+	// Name is the book's name in a workspace, and Base the path its pages
+	// are served under there, eg. "/b/expert"; both are "" when the book is
+	// served on its own. See workspace.go
+	Name, Base string
+	workspace  *Workspace
+	MCVersion  string
+	Verbose    int
+	// qb is the loaded quest book. A book is never modified once loaded:
+	// edits are written to disk and reload swaps in a new one, so a handler
+	// can keep using the book it got from QB for the whole request.
+	qb atomic.Pointer[QuestBook]
+	// writeMu serializes handlers that read, modify and write quest files so
+	// two saves to the same chapter can't lose each other's edits.
+	writeMu sync.Mutex
+	// CompareRoot is an optional second ftbquests dir (eg. an expert mode
+	// book) that the compare page checks against by default.
+	CompareRoot string
+	// ProgressDir is an optional directory of team progress files (eg.
+	// <world>/ftbquests) for the progress page; see progress.go
+	ProgressDir string
+	// Messages translates qbedit's own UI; see the i18n package.
+	Messages *i18n.Catalog
+	tpl      *template.Template
+	// Prefs stores per-browser preferences like starred quests; see prefs.go
+	Prefs *Prefs
+	// Audit records every edit for the activity page; see audit.go
+	Audit *AuditLog
+	// events pushes reload notices to open pages; see watch.go
+	events eventHub
+	// shareKey signs read-only quest share links; see share.go
+	shareKey []byte
+	// ShareAddr is the host:port ShareRouter is served on; without it
+	// quests can't be shared
+	ShareAddr string
+	// Snippets is the pack's library of description snippets; see snippets.go
+	Snippets *SnippetStore
+	// Pack holds settings shared by everyone editing the pack; see pack.go
+	Pack *PackSettings
+	// Git commits every edit when it is set (--git); see gitrepo.go
+	Git *GitRepo
+	// Icons serves item textures when the game's assets are given
+	// (--assets); see icons.go
+	Icons *IconSet
+	// Items are the item ids the editor suggests and checks against
+	// (--items, or the textures in --assets); see items.go
+	Items *ItemRegistry
+	// Dictionary is what the spelling page checks quest text against
+	// (--dict, or the system's word list); see spelling.go
+	Dictionary Dictionary
+	// SpellAllow is the pack's own words, which are spelled right; see
+	// spelling.go
+	SpellAllow *Allowlist
+	// Backups snapshots the book on a schedule (--backup-interval); see
+	// backup.go
+	Backups *Backups
+	// SlowRequest is how long a request can take before it is logged with
+	// the time spent in each operation (--slow); 0 logs none. See timing.go
+	SlowRequest time.Duration
+	// requestTimes are the durations of requests by route
+	requestTimes requestTimes
+	// sandbox is the scratch copy of the book that Root points to while
+	// one is active; see sandbox.go
+	sandbox atomic.Pointer[Sandbox]
+	// cache keeps decoded chapter files between loads and restarts; see
+	// bookcache.go
+	cache *parseCache
+	// reloads coalesces overlapping reloads of the book
+	reloads reloadFlight
 }
 
+// Failure is a part of the book that couldn't be loaded.
 type Failure struct {
 	Name string
 	Path string
 	Err  string
+	// Chapter is the chapter the failure was skipped in, if the rest of it
+	// loaded, and Line is where in its file.
+	Chapter string
+	Line    int
+	// Snippet is the text around a syntax error.
+	Snippet string
 }
 
 // Group and TopItem types are defined in quests.go
@@ -45,9 +127,39 @@ type Failure struct {
 var templatesFS embed.FS
 
 func New(root, mc string, verbose int) (*App, error) {
-	a := &App{Root: root, MCVersion: mc, Verbose: verbose}
+	a := &App{MCVersion: mc, Verbose: verbose}
+	a.setPaths(root, findLangFile(root))
+	a.cache = openParseCache(filepath.Join(packDir(root), "cache"))
 	// XXX: maybe if we error we still have the app UI visible?
-	a.QB, _ = NewQuestBook(root)
+	qb, _ := a.loadBook()
+	a.qb.Store(qb)
+	if qb != nil {
+		warnDuplicateIDs(qb)
+	}
+	msgs, err := i18n.New(i18n.Fallback)
+	if err != nil {
+		return nil, err
+	}
+	a.Messages = msgs
+	a.SetShareSecret("")
+	// an in-memory store until main configures a file
+	a.Prefs = &Prefs{users: make(map[string]*UserPrefs)}
+	a.Audit = OpenAuditLog("")
+	// a broken settings file shouldn't keep the book from opening, so the
+	// defaults are used in its place
+	if a.Snippets, err = OpenSnippets(snippetsPath(root)); err != nil {
+		slog.Error("loading snippets, using the defaults", "error", err, "moved", setAside(snippetsPath(root)))
+		a.Snippets = newSnippetStore(snippetsPath(root))
+	}
+	if a.SpellAllow, err = OpenAllowlist(spellingPath(root)); err != nil {
+		return nil, err
+	}
+	packPath := filepath.Join(packDir(root), "pack.json")
+	if a.Pack, err = OpenPackSettings(packPath); err != nil {
+		slog.Error("loading pack settings, using the defaults", "error", err, "moved", setAside(packPath))
+		a.Pack = &PackSettings{path: packPath}
+	}
+	setProtection(root, a.Pack.Get().Protected, false)
 
 	// Load templates from embedded FS
 	sub, _ := fs.Sub(templatesFS, "templates")
@@ -56,6 +168,22 @@ func New(root, mc string, verbose int) (*App, error) {
 	// extend with a small helper
 	funcs["eq"] = func(a, b any) bool { return fmt.Sprint(a) == fmt.Sprint(b) }
 	funcs["mc"] = func(s string) template.HTML { return mcformat.Format(s) }
+	funcs["swatch"] = colorSwatch
+	funcs["itemIcon"] = a.itemIcon
+	funcs["unknownItem"] = a.unknownItem
+	funcs["canvasUnit"] = func() int { return canvasUnit }
+	funcs["colorArg"] = colorArg
+	funcs["colorLabel"] = colorLabel
+	funcs["has"] = func(ss []string, s string) bool { return slices.Contains(ss, s) }
+	// base prefixes the book's own paths when it is served in a workspace
+	funcs["base"] = func() string { return a.Base }
+	funcs["rewardTable"] = func(r Reward) *RewardTable { return a.QB().RewardTable(r) }
+	funcs["questTitle"] = func(id string) string {
+		if q, ok := a.QB().questMap[id]; ok && q.GetTitle() != "" {
+			return q.GetTitle()
+		}
+		return id
+	}
 	// helpers for pagination math
 	funcs["add"] = func(a, b int) int { return a + b }
 	funcs["mul"] = func(a, b int) int { return a * b }
@@ -71,6 +199,29 @@ func New(root, mc string, verbose int) (*App, error) {
 		}
 		return (a + b - 1) / b
 	}
+	funcs["ticks"] = formatTicks
+	funcs["heat"] = heatLevel
+	funcs["questHash"] = questHash
+	funcs["splitLines"] = splitLines
+	funcs["bytes"] = humanBytes
+	funcs["ms"] = millis
+	funcs["formatDefaults"] = formatDefaults
+	// t translates a UI message; th is for messages that contain markup, and
+	// escapes its arguments
+	funcs["t"] = func(lang, key string, args ...any) string { return a.Messages.T(lang, key, args...) }
+	funcs["th"] = func(lang, key string, args ...any) template.HTML {
+		for i, arg := range args {
+			if s, ok := arg.(string); ok {
+				args[i] = template.HTMLEscapeString(s)
+			}
+		}
+		msg := a.Messages.T(lang, key, args...)
+		// messages link to the book's pages from the root
+		if a.Base != "" {
+			msg = strings.ReplaceAll(msg, `href="/`, `href="`+a.Base+"/")
+		}
+		return template.HTML(msg)
+	}
 	tpl, err := template.New("base").Funcs(funcs).ParseFS(sub, "*.gohtml")
 	if err != nil {
 		return nil, err
@@ -79,49 +230,368 @@ func New(root, mc string, verbose int) (*App, error) {
 	return a, nil
 }
 
-// reload questbook from disk
-func (a *App) reload() { a.QB, _ = NewQuestBook(a.Root) }
+// QB returns the current quest book.
+func (a *App) QB() *QuestBook { return a.qb.Load() }
+
+// reloadFlight keeps at most one load of the book running. Callers can't
+// share a load that is already running, as it may have read the files before
+// their change, so those arriving during one wait for the next, which they
+// all share; the caller that started the running load also runs the next. A
+// burst of saves and watcher reloads, eg. while git checks out a branch,
+// costs two loads rather than one each.
+type reloadFlight struct {
+	mu      sync.Mutex
+	running bool
+	// next is closed when the load after the running one is done, or nil
+	// if no one is waiting for one
+	next chan struct{}
+	// requests and loads count the reloads asked for and done
+	requests, loads int
+}
+
+// counts returns how many reloads were asked for and how many loads did
+// them.
+func (f *reloadFlight) counts() (requests, loads int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests, f.loads
+}
+
+// reload questbook from disk. If it can't be loaded, eg. because the game is
+// halfway through saving, the current book is kept. When it returns, a load
+// started after it was called is done; see reloadFlight.
+func (a *App) reload() {
+	a.reloads.do(func() {
+		qb, err := a.loadBook()
+		if err != nil {
+			slog.Error("reloading quest book", "error", err)
+			return
+		}
+		a.qb.Store(qb)
+	})
+}
+
+// do calls load, or waits for the next call of another caller's load if one
+// is running, and returns once a call started after do was called is done.
+func (f *reloadFlight) do(load func()) {
+	f.mu.Lock()
+	f.requests++
+	if f.running {
+		if f.next == nil {
+			f.next = make(chan struct{})
+		}
+		next := f.next
+		f.mu.Unlock()
+		<-next
+		return
+	}
+	f.running = true
+	f.mu.Unlock()
+
+	// done is closed after the load for the callers that waited for it
+	var done chan struct{}
+	for {
+		f.mu.Lock()
+		f.loads++
+		f.mu.Unlock()
+		load()
+		if done != nil {
+			close(done)
+		}
+		f.mu.Lock()
+		done, f.next = f.next, nil
+		if done == nil {
+			f.running = false
+			f.mu.Unlock()
+			return
+		}
+		f.mu.Unlock()
+	}
+}
+
+// bookPaths is where an App's book is.
+type bookPaths struct {
+	root string
+	// lang is the lang file that translation keys in the book are resolved
+	// from, if any; see langfile.go
+	lang string
+}
+
+// Root returns the ftbquests dir the book is loaded from, which is the
+// sandbox's copy while one is active.
+func (a *App) Root() string { return a.paths.Load().root }
+
+// LangPath returns the lang file translation keys in the book are resolved
+// from, or "" if there is none.
+func (a *App) LangPath() string { return a.paths.Load().lang }
+
+// setPaths points the app at the book in root with the lang file lang.
+func (a *App) setPaths(root, lang string) {
+	a.paths.Store(&bookPaths{root: root, lang: lang})
+}
+
+// setLangPath changes the lang file, keeping the root.
+func (a *App) setLangPath(lang string) {
+	for {
+		old := a.paths.Load()
+		if a.paths.CompareAndSwap(old, &bookPaths{root: old.root, lang: lang}) {
+			return
+		}
+	}
+}
+
+// loadBook loads the quest book at a.Root() with its lang file.
+func (a *App) loadBook() (*QuestBook, error) {
+	defer timeOp(opParse)()
+	qb, err := loadQuestBook(a.Root(), a.cache)
+	if err != nil {
+		return nil, err
+	}
+	// every current chapter has been cached now
+	if err := a.cache.prune(); err != nil {
+		slog.Warn("pruning parse cache", "error", err)
+	}
+	if a.LangPath() != "" {
+		if err := qb.loadLang(a.LangPath()); err != nil {
+			// the quests still load, just showing their keys
+			slog.Error("loading lang file", "path", a.LangPath(), "error", err)
+		}
+	}
+	qb.quick = newQuickIndex(qb)
+	var prev *textIndex
+	if old := a.QB(); old != nil {
+		prev = old.text
+	}
+	qb.text = newTextIndex(qb, prev)
+	return qb, nil
+}
+
+// serializeWrites is middleware for handlers that write quest files.
+func (a *App) serializeWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.writeMu.Lock()
+		defer a.writeMu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
 
 // scanGroups is defined in quests.go
 
 func (a *App) Router() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
+	r.Use(a.timeRequests)
 	r.Use(middleware.RealIP)
 	if a.Verbose > 0 {
 		r.Use(middleware.Logger)
 	}
 	r.Use(middleware.Recoverer)
+	r.Use(ensureUser)
+	if a.Base != "" {
+		r.Use(a.prefixRedirects)
+	}
 
 	// Static assets
 	mime.AddExtensionType(".css", "text/css")
 	staticFS, _ := fs.Sub(templatesFS, "static")
 	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.FS(staticFS))))
 
+	// w is for routes that write quest files
+	w := r.With(a.serializeWrites)
+
 	r.Get("/", a.index)
 	r.Get("/batch/", a.batch)
 	r.Get("/batch/edit", a.batchEdit)
+	w.Post("/batch/save", a.batchSave)
+	w.Post("/batch/dependency", a.batchDependency)
+	r.Get("/batch/transform", a.batchTransform)
+	w.Post("/batch/transform", a.batchTransform)
 	r.Get("/colors/", a.colors)
-	r.Post("/colors/recolor", a.colorsRecolor)
-	r.Post("/colors/recolor_one", a.colorsRecolorOne)
+	w.Post("/colors/recolor", a.colorsRecolor)
+	w.Post("/colors/recolor_one", a.colorsRecolorOne)
+	w.Post("/colors/recolor_many", a.colorsRecolorMany)
+	w.Post("/colors/normalize", a.colorsNormalize)
+	r.Get("/colors/palettes", a.palettes)
+	r.Post("/colors/palettes", a.paletteSave)
+	r.Post("/colors/palettes/delete", a.paletteDelete)
+	r.Get("/colors/palettes/apply", a.paletteApply)
+	w.Post("/colors/palettes/apply", a.paletteApply)
+	r.Get("/recipes", a.recipes)
+	r.Post("/recipes", a.recipeSave)
+	r.Post("/recipes/delete", a.recipeDelete)
+	w.Post("/recipes/run", a.recipeRun)
+	w.Post("/chapters/new", a.chapterCreate)
+	r.Get("/chapters/order", a.chapterOrder)
+	w.Post("/chapters/order", a.chapterReorder)
+	r.Get("/outline", a.outline)
+	r.Post("/outline", a.outline)
+	r.Get("/outline.yaml", a.outlineExport)
+	w.Post("/outline/apply", a.outlineApply)
+	r.Get("/stubs", a.stubs)
+	r.Post("/stubs", a.stubs)
+	w.Post("/stubs/apply", a.stubsApply)
+	r.Get("/progress", a.progress)
 	r.Get("/chapter/{chapter}", a.chapterDetail)
+	w.Post("/chapter/{chapter}/rename", a.chapterRename)
+	w.Post("/chapter/{chapter}/delete", a.chapterDelete)
+	w.Post("/chapter/{chapter}/links", a.chapterLinkAdd)
+	w.Post("/chapter/{chapter}/links/{link}/delete", a.chapterLinkRemove)
 	r.Get("/chapter/{chapter}/{quest}", a.questDetail)
-	r.Post("/chapter/{chapter}/{quest}/save", a.questSave)
+	w.Post("/chapter/{chapter}/{quest}/save", a.questSave)
+	w.Post("/chapter/{chapter}/{quest}/dependencies", a.questDependencies)
+	w.Post("/chapter/{chapter}/{quest}/rename-id", a.questRenameID)
+	w.Post("/chapter/{chapter}/{quest}/duplicate", a.questDuplicate)
+	r.Post("/chapter/{chapter}/{quest}/template", a.questTemplateSave)
+	r.Get("/chapter/{chapter}/{quest}/deps", a.questDeps)
+	r.Get("/chapter/{chapter}/{quest}/tellraw", a.questTellraw)
+	r.Get("/chapter/{chapter}/{quest}/json", a.questExportOne)
 	r.Get("/chapter/{chapter}/raw", a.chapterRaw)
+	r.Get("/tables/{table}", a.rewardTable)
+	w.Post("/tables/{table}/save", a.rewardTableSave)
+	r.Get("/sandbox", a.sandboxPage)
+	w.Post("/sandbox/start", a.sandboxStart)
+	w.Post("/sandbox/apply", a.sandboxApply)
+	w.Post("/sandbox/discard", a.sandboxDiscard)
+	r.Get("/chapter/{chapter}/text", a.chapterText)
+	r.Get("/chapter/{chapter}/script", a.chapterScript)
+	r.Get("/chapter/{chapter}/tellraw", a.chapterTellraw)
+	r.Get("/chapter/{chapter}/map.svg", a.chapterMinimapSVG)
+	r.Get("/chapter/{chapter}/canvas", a.chapterCanvas)
+	w.Post("/chapter/{chapter}/positions", a.chapterPositions)
+	w.Post("/chapter/{chapter}/raw", a.chapterRawSave)
+	r.Get("/items/{id}", a.itemPNG)
+	r.Get("/api/items", a.apiItems)
+	r.Get("/api/search", a.apiSearch)
+	r.Get("/api/newid", a.apiNewID)
+	r.Get("/api/convert", a.apiConvert)
+	r.Get("/api/metrics", a.apiMetrics)
+	r.Get("/cvd.css", a.cvdCSS)
+	r.Post("/api/convert", a.apiConvert)
+	r.Get("/q/{quest}", a.questRedirect)
+	w.Post("/chapter/{chapter}/toc", a.chapterTOC)
+	r.Get("/compare", a.compare)
+	r.Get("/compare/quest", a.questCompare)
+	r.Get("/graph", a.graph)
+	r.Get("/graph.json", a.graphJSON)
+	r.Post("/chapter/{chapter}/{quest}/share", a.questShare)
+	r.Post("/chapter/{chapter}/{quest}/star", a.questStar)
+	r.Get("/events", a.eventStream)
+	r.Get("/activity", a.activity)
+	r.Post("/prefs/name", a.prefsName)
 	r.Get("/errors", a.errors)
+	w.Post("/errors/retry", a.errorsRetry)
+	r.Get("/status", a.status)
+	r.Get("/export", a.textExport)
+	r.Get("/export/quests.json", a.questExport)
+	r.Get("/import", a.textImport)
+	r.Post("/import", a.textImport)
+	w.Post("/import/apply", a.textImportApply)
+	r.Get("/localize", a.localize)
+	w.Post("/localize", a.localizeApply)
+	r.Get("/issues", a.issues)
+	w.Post("/issues/regenerate", a.issuesRegenerate)
+	r.Get("/duplicates", a.duplicates)
+	r.Get("/claims", a.claims)
+	w.Post("/duplicates/merge", a.duplicatesMerge)
+	r.Get("/orphans", a.orphans)
+	r.Get("/convert", a.convert)
+	r.Get("/console", a.console)
+	r.Get("/console.json", a.consoleJSON)
+	r.Post("/convert", a.convert)
+	w.Post("/orphans/relocate", a.orphansRelocate)
+	w.Post("/orphans/delete", a.orphansDelete)
+	r.Get("/lint", a.lint)
+	r.Post("/lint/policy", a.lintPolicy)
+	w.Post("/lint/fix", a.lintFix)
+	r.Post("/lint/blocklist", a.lintBlocklist)
+	r.Post("/lint/allow", a.lintAllow)
+	r.Get("/protect", a.protectPage)
+	r.Post("/protect", a.protectPatterns)
+	r.Post("/protect/lock", a.protectLock)
+	r.Get("/spelling", a.spelling)
+	r.Post("/spelling/words", a.spellingWords)
+	r.Post("/spelling/accept", a.spellingAccept)
+	r.Post("/spelling/ignore", a.spellingIgnore)
+	r.Get("/terms", a.terms)
+	r.Post("/terms/rules", a.termsRules)
+	w.Post("/terms/fix", a.termsFix)
+	r.Get("/git", a.gitLog)
+	w.Post("/git/revert", a.gitRevert)
+	r.Get("/snippets", a.snippets)
+	r.Post("/snippets", a.snippetSave)
+	r.Post("/snippets/{snippet}/delete", a.snippetDelete)
+	r.Get("/snippets/{snippet}/render", a.snippetRender)
+	r.Get("/templates", a.questTemplates)
+	w.Post("/templates/{template}/create", a.questTemplateCreate)
+	r.Post("/templates/{template}/delete", a.questTemplateDelete)
 
 	return r
 }
 
 func (a *App) render(w http.ResponseWriter, name string, data any) {
+	a.renderStatus(w, http.StatusOK, name, data)
+}
+
+// renderBufs holds the buffers pages are rendered into.
+var renderBufs = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// renderStatus renders the template name with data and the status code.
+// The page is rendered into a buffer first, so that a template that fails
+// partway, eg. on an odd value in a quest, sends an error page rather than
+// half of the page.
+func (a *App) renderStatus(w http.ResponseWriter, code int, name string, data any) {
+	defer timeOp(opRender)()
+	buf := renderBufs.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer renderBufs.Put(buf)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := a.tpl.ExecuteTemplate(w, name, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := a.tpl.ExecuteTemplate(buf, name, data); err != nil {
+		slog.Error("rendering page", "template", name, "error", err)
+		slog.Debug("rendering page", "template", name, "data", renderContext(data))
+		w.WriteHeader(http.StatusInternalServerError)
+		renderErrorPage.Execute(w, map[string]string{"Base": a.Base, "Template": name, "Error": err.Error()})
+		return
+	}
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
+
+// renderContext describes a page's data for the log: each value of a map,
+// cut short, by key.
+func renderContext(data any) string {
+	m, ok := data.(map[string]any)
+	if !ok {
+		return fmt.Sprintf("%.500v", data)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%.200v; ", k, m[k])
+	}
+	return b.String()
 }
 
+// renderErrorPage is shown when a page fails to render. It doesn't use the
+// app's templates, which are what failed.
+var renderErrorPage = template.Must(template.New("error").Parse(`<!doctype html>
+<html>
+<head><meta charset="utf-8"><title>qbedit: page error</title><link rel="stylesheet" href="{{ .Base }}/static/app.css"></head>
+<body><main class="main">
+<h1>This page couldn't be shown</h1>
+<p>Something in the book's data tripped up the <code>{{ .Template }}</code> page:</p>
+<pre class="raw wrap">{{ .Error }}</pre>
+<p>Nothing was changed. The <a href="{{ .Base }}/errors">errors page</a> lists parts of the book that failed to load, which may be the cause; or go back to the <a href="{{ .Base }}/">start</a>.</p>
+</main></body>
+</html>
+`))
+
 // baseData returns common template data to keep the sidebar consistent.
 func (a *App) baseData(r *http.Request, title string) map[string]any {
+	qb := a.QB()
 	// Dark mode detection precedence:
 	// 1) Explicit query param ?dark=true forces dark for this render
 	// 2) Fallback to cookie set by client toggle
@@ -135,29 +605,50 @@ func (a *App) baseData(r *http.Request, title string) map[string]any {
 	}
 	// Derive sidebar data from QuestBook
 	var chapters []Chapter
-	for _, cp := range a.QB.Chapters {
+	for _, cp := range qb.Chapters {
 		if cp != nil {
 			chapters = append(chapters, *cp)
 		}
 	}
 	var groups []Group
-	for _, gp := range a.QB.Groups {
+	for _, gp := range qb.Groups {
 		if gp != nil {
 			groups = append(groups, *gp)
 		}
 	}
-	top := a.QB.TopItems()
+	top := qb.TopItems()
 	return map[string]any{
 		"Chapters":    chapters,
 		"Groups":      groups,
+		"Tables":      qb.Tables,
 		"Top":         top,
 		"MCVersion":   a.MCVersion,
 		"Title":       title,
-		"Parsed":      len(a.QB.Chapters),
-		"Failed":      0,
-		"HasFailures": false,
+		"Parsed":      len(qb.Chapters),
+		"Failed":      len(qb.Failures),
+		"HasFailures": len(qb.Failures) > 0,
 		"ThemeDark":   themeDark,
+		"CVD":         string(cvdMode(r)),
+		"CVDModes":    mcformat.Deficiencies,
+		"Lang":        a.lang(r),
+		"Langs":       a.Messages.Langs(),
+		"Starred":     a.starredQuests(r),
+		"Sandboxed":   a.sandbox.Load() != nil,
+		"Book":        a.Name,
+		"Books":       a.workspace.Names(),
+	}
+}
+
+// lang returns the UI language for r: ?lang= for this render, then the lang
+// cookie set by the sidebar picker, then the browser's Accept-Language.
+func (a *App) lang(r *http.Request) string {
+	if v := r.URL.Query().Get("lang"); v != "" && a.Messages.Has(v) {
+		return v
+	}
+	if c, err := r.Cookie("lang"); err == nil && a.Messages.Has(c.Value) {
+		return c.Value
 	}
+	return a.Messages.Match(r.Header.Get("Accept-Language"))
 }
 
 // index handles GET "/".
@@ -168,13 +659,14 @@ func (a *App) index(w http.ResponseWriter, r *http.Request) {
 
 // batch handles GET "/batch/" and displays a search form plus results.
 func (a *App) batch(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
 	// Only show search form here; results are on /batch/edit
 	q := strings.TrimSpace(r.URL.Query().Get("q"))
 	cg := strings.TrimSpace(r.URL.Query().Get("cg"))
 	noTitle := r.URL.Query().Has("no_title")
 	noSubtitle := r.URL.Query().Has("no_subtitle")
 	noDesc := r.URL.Query().Has("no_desc")
-	caseSensitive := r.URL.Query().Has("case")
+	opts := searchOptionsFrom(r.URL.Query())
 	perPage := 5
 	if n := strings.TrimSpace(r.URL.Query().Get("n")); n != "" {
 		switch n {
@@ -189,17 +681,17 @@ func (a *App) batch(w http.ResponseWriter, r *http.Request) {
 	data["Form"] = map[string]any{
 		"cg": cg, "q": q,
 		"no_title": noTitle, "no_subtitle": noSubtitle, "no_desc": noDesc,
-		"case": caseSensitive,
-		"n":    perPage,
+		"case": opts.CaseSensitive, "regex": opts.Regex, "word": opts.WholeWord, "in": opts.Fields,
+		"n": perPage,
 	}
 	// Provide options for the Chapter/Group datalist
 	var cgOptions []string
-	for _, g := range a.QB.Groups {
+	for _, g := range qb.Groups {
 		if g.Title != "" {
 			cgOptions = append(cgOptions, g.Title)
 		}
 	}
-	for _, ch := range a.QB.Chapters {
+	for _, ch := range qb.Chapters {
 		if ch.Title != "" {
 			cgOptions = append(cgOptions, ch.Title)
 		}
@@ -211,15 +703,90 @@ func (a *App) batch(w http.ResponseWriter, r *http.Request) {
 	a.render(w, "batch.gohtml", data)
 }
 
+// batchMatch is a quest found by a batch search.
+type batchMatch struct {
+	Chapter *Chapter
+	Quest   *Quest
+}
+
+// batchMatches returns the quests of a batch search, in book order: those
+// listed by "ids", or those in the chapters and groups matching "cg" whose
+// text matches "q" (see searchMatchers), optionally only those without a
+// title, subtitle or description. The error is that of an invalid regular
+// expression.
+func batchMatches(qb *QuestBook, query url.Values) ([]batchMatch, error) {
+	var matches []batchMatch
+	if ids := strings.TrimSpace(query.Get("ids")); ids != "" {
+		idset := make(map[string]struct{})
+		for _, s := range strings.Split(ids, ",") {
+			s = strings.TrimSpace(s)
+			if s != "" {
+				idset[s] = struct{}{}
+			}
+		}
+		for _, ch := range qb.Chapters {
+			for _, qs := range ch.Quests {
+				if _, ok := idset[qs.ID]; ok {
+					matches = append(matches, batchMatch{Chapter: ch, Quest: qs})
+				}
+			}
+		}
+		return matches, nil
+	}
+
+	noTitle := query.Has("no_title")
+	noSubtitle := query.Has("no_subtitle")
+	noDesc := query.Has("no_desc")
+	opts := searchOptionsFrom(query)
+	scope := colorScope(qb, strings.TrimSpace(query.Get("cg")))
+	// A query matches when all query terms appear as substrings in any of the quest fields.
+	// Terms are whitespace-split, unless the query is a regular expression.
+	// Reward filters are taken out of the query first.
+	text, rewardFilters := splitSearchFilters(strings.TrimSpace(query.Get("q")))
+	matchers, err := searchMatchers(text, opts)
+	if err != nil {
+		return nil, err
+	}
+	done := timeOp(opSearch)
+	defer done()
+	idx := qb.textIndex()
+	cand := idx.candidates(matchers)
+	for _, ch := range qb.Chapters {
+		if len(scope) > 0 && !scope[ch.Name] {
+			continue
+		}
+		for _, qs := range ch.Quests {
+			if cand != nil && !cand[qs.ID] {
+				continue
+			}
+			if noTitle && qs.Title != "" {
+				continue
+			}
+			if noSubtitle && qs.Subtitle != "" {
+				continue
+			}
+			if noDesc && qs.Description != "" {
+				continue
+			}
+			if !idx.quest(qs).match(matchers, opts.Fields) || !matchRewards(qs, rewardFilters) {
+				continue
+			}
+			matches = append(matches, batchMatch{Chapter: ch, Quest: qs})
+		}
+	}
+	return matches, nil
+}
+
 // batchEdit performs the search and displays results in the normal layout, using
 // the site's left pane to render the search result tree instead of the global chapters.
 func (a *App) batchEdit(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
 	q := strings.TrimSpace(r.URL.Query().Get("q"))
 	cg := strings.TrimSpace(r.URL.Query().Get("cg"))
 	noTitle := r.URL.Query().Has("no_title")
 	noSubtitle := r.URL.Query().Has("no_subtitle")
 	noDesc := r.URL.Query().Has("no_desc")
-	caseSensitive := r.URL.Query().Has("case")
+	opts := searchOptionsFrom(r.URL.Query())
 	idsParam := strings.TrimSpace(r.URL.Query().Get("ids"))
 	perPage := 5
 	if n := strings.TrimSpace(r.URL.Query().Get("n")); n != "" {
@@ -237,78 +804,12 @@ func (a *App) batchEdit(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Scope by Chapter/Group
-	scope := make(map[string]bool)
-	if cg != "" {
-		lc := strings.ToLower(cg)
-		for _, g := range a.QB.Groups {
-			if strings.Contains(strings.ToLower(g.Title), lc) || strings.EqualFold(g.ID, cg) {
-				for _, ch := range g.Chapters {
-					scope[ch.Name] = true
-				}
-			}
-		}
-		for _, ch := range a.QB.Chapters {
-			if strings.Contains(strings.ToLower(ch.Title), lc) || strings.EqualFold(ch.Name, cg) {
-				scope[ch.Name] = true
-			}
-		}
-	}
-
-	// Collect matches
-	type QRef struct {
-		Chapter *Chapter
-		Quest   *Quest
-	}
-	var matches []QRef
-	// A query matches when all query terms appear as substrings in any of the quest fields.
-	// Terms are whitespace-split.
-	terms := []string{}
-	for _, part := range strings.Fields(q) {
-		p := strings.TrimSpace(part)
-		if !caseSensitive {
-			p = strings.ToLower(p)
-		}
-		if p != "" {
-			terms = append(terms, p)
-		}
-	}
-	if idsParam != "" {
-		idset := make(map[string]struct{})
-		for _, s := range strings.Split(idsParam, ",") {
-			s = strings.TrimSpace(s)
-			if s != "" {
-				idset[s] = struct{}{}
-			}
-		}
-		for _, ch := range a.QB.Chapters {
-			for _, qs := range ch.Quests {
-				if _, ok := idset[qs.ID]; ok {
-					matches = append(matches, QRef{Chapter: ch, Quest: qs})
-				}
-			}
-		}
-	} else {
-		for _, ch := range a.QB.Chapters {
-			if len(scope) > 0 && !scope[ch.Name] {
-				continue
-			}
-			for _, qs := range ch.Quests {
-				if noTitle && qs.Title != "" {
-					continue
-				}
-				if noSubtitle && qs.Subtitle != "" {
-					continue
-				}
-				if noDesc && qs.Description != "" {
-					continue
-				}
-				if !matchQuest(qs, terms, caseSensitive) {
-					continue
-				}
-				matches = append(matches, QRef{Chapter: ch, Quest: qs})
-			}
-		}
+	matches, err := batchMatches(qb, r.URL.Query())
+	if err != nil {
+		qs := r.URL.Query()
+		qs.Set("msg", "Invalid regular expression: "+err.Error())
+		http.Redirect(w, r, "/batch/?"+qs.Encode(), http.StatusSeeOther)
+		return
 	}
 	if len(matches) == 0 {
 		// Redirect back to /batch/ with a message
@@ -335,7 +836,7 @@ func (a *App) batchEdit(w http.ResponseWriter, r *http.Request) {
 		title := mr.Quest.GetTitle()
 		byChapter[mr.Chapter.Name] = append(byChapter[mr.Chapter.Name], SideQuest{ID: mr.Quest.ID, Title: title})
 	}
-	for _, g := range a.QB.Groups {
+	for _, g := range qb.Groups {
 		var sc []SideChapter
 		for _, ch := range g.Chapters {
 			if qs, ok := byChapter[ch.Name]; ok && len(qs) > 0 {
@@ -349,7 +850,7 @@ func (a *App) batchEdit(w http.ResponseWriter, r *http.Request) {
 	}
 	if len(byChapter) > 0 {
 		var sc []SideChapter
-		for _, ch := range a.QB.Chapters {
+		for _, ch := range qb.Chapters {
 			if ch.GroupID != "" {
 				continue
 			}
@@ -380,21 +881,49 @@ func (a *App) batchEdit(w http.ResponseWriter, r *http.Request) {
 	data["BatchTotal"] = total
 	data["BatchPerPage"] = perPage
 	data["BatchPage"] = page
+	data["Transforms"] = transforms
 	data["Form"] = map[string]any{
 		"cg": cg, "q": q,
 		"no_title": noTitle, "no_subtitle": noSubtitle, "no_desc": noDesc,
-		"case": caseSensitive,
-		"ids":  idsParam,
-		"n":    perPage,
+		"case": opts.CaseSensitive, "regex": opts.Regex, "word": opts.WholeWord, "in": opts.Fields,
+		"ids": idsParam,
+		"n":   perPage,
 	}
 	a.render(w, "batch_edit.gohtml", data)
 }
 
+// colorScope returns the names of the chapters matching the Color Manager's
+// chapter/group filter cg: chapters and groups whose title contains it, or
+// whose name or id is it. An empty cg is the whole book, and returns an empty
+// set.
+func colorScope(qb *QuestBook, cg string) map[string]bool {
+	scope := make(map[string]bool)
+	if cg == "" {
+		return scope
+	}
+	lc := strings.ToLower(cg)
+	for _, g := range qb.Groups {
+		if strings.Contains(strings.ToLower(g.Title), lc) || strings.EqualFold(g.ID, cg) {
+			for _, ch := range g.Chapters {
+				scope[ch.Name] = true
+			}
+		}
+	}
+	for _, ch := range qb.Chapters {
+		if strings.Contains(strings.ToLower(ch.Title), lc) || strings.EqualFold(ch.Name, cg) {
+			scope[ch.Name] = true
+		}
+	}
+	return scope
+}
+
 // colors handles GET "/colors/" — Color Manager base with an inconsistency finder.
 func (a *App) colors(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
 	term := strings.TrimSpace(r.URL.Query().Get("q"))
 	cg := strings.TrimSpace(r.URL.Query().Get("cg"))
 	ci := r.URL.Query().Has("ci") // case-insensitive if present
+	regex := r.URL.Query().Has("regex")
 	// Per-page selector for visual consistency (not used for aggregation yet)
 	perPage := 5
 	if n := strings.TrimSpace(r.URL.Query().Get("n")); n != "" {
@@ -409,50 +938,36 @@ func (a *App) colors(w http.ResponseWriter, r *http.Request) {
 	data := a.baseData(r, "Color Manager")
 	// Datalist options
 	var cgOptions []string
-	for _, g := range a.QB.Groups {
+	for _, g := range qb.Groups {
 		if g.Title != "" {
 			cgOptions = append(cgOptions, g.Title)
 		}
 	}
-	for _, ch := range a.QB.Chapters {
+	for _, ch := range qb.Chapters {
 		if ch.Title != "" {
 			cgOptions = append(cgOptions, ch.Title)
 		}
 	}
 	data["CGOptions"] = cgOptions
-	data["Form"] = map[string]any{"cg": cg, "q": term, "ci": ci, "n": perPage}
+	data["Form"] = map[string]any{"cg": cg, "q": term, "ci": ci, "regex": regex, "n": perPage}
+	data["ColorsMsg"] = r.URL.Query().Get("msg")
 
 	if term == "" {
+		data["Signs"] = signStats(qb)
 		a.render(w, "colors.gohtml", data)
 		return
 	}
-
-	// Scope selection
-	scope := make(map[string]bool)
-	if cg != "" {
-		lc := strings.ToLower(cg)
-		for _, g := range a.QB.Groups {
-			if strings.Contains(strings.ToLower(g.Title), lc) || strings.EqualFold(g.ID, cg) {
-				for _, ch := range g.Chapters {
-					scope[ch.Name] = true
-				}
-			}
-		}
-		for _, ch := range a.QB.Chapters {
-			if strings.Contains(strings.ToLower(ch.Title), lc) || strings.EqualFold(ch.Name, cg) {
-				scope[ch.Name] = true
-			}
-		}
+	m, err := newMatcher(term, regex, ci)
+	if err != nil {
+		data["ColorsErr"] = "Invalid regular expression: " + err.Error()
+		a.render(w, "colors.gohtml", data)
+		return
 	}
 
-	// Normalization
-	matchTerm := term
-	if ci {
-		matchTerm = strings.ToLower(term)
-	}
+	scope := colorScope(qb, cg)
 
 	// Count colors and capture quest ids for linking
-	counts := make(map[string]int)                     // code -> count (code like "c6", "ca", empty for none)
+	counts := make(map[string]int)                     // code -> count (code like "c6", "ca", "#ffaa00", empty for none)
 	idsByColor := make(map[string]map[string]struct{}) // code -> set of quest IDs
 	// Per-quest aggregated matches with highlighted segment text
 	type TermHit struct {
@@ -479,6 +994,11 @@ func (a *App) colors(w http.ResponseWriter, r *http.Request) {
 		i := 0
 		for i < len(rs) {
 			rch := rs[i]
+			if hex, n := mcformat.HexCode(rs, i); n > 0 {
+				cur = hex
+				i += n
+				continue
+			}
 			if rch == '&' || rch == '\u00A7' {
 				if i+1 < len(rs) {
 					code := rs[i+1]
@@ -500,21 +1020,8 @@ func (a *App) colors(w http.ResponseWriter, r *http.Request) {
 			i++
 		}
 		text := string(stripped)
-		hay := text
-		needle := matchTerm
-		if ci {
-			hay = strings.ToLower(text)
-		}
-		if len(needle) == 0 {
-			return
-		}
-		start := 0
-		for start <= len(hay)-len(needle) {
-			idx := strings.Index(hay[start:], needle)
-			if idx < 0 {
-				break
-			}
-			pos := start + idx
+		for _, loc := range m.index(text) {
+			pos, end := loc[0], loc[1]
 			if pos < len(colors) {
 				c := colors[pos]
 				counts[c]++
@@ -594,7 +1101,7 @@ func (a *App) colors(w http.ResponseWriter, r *http.Request) {
 						}
 					}
 					// Right bound starting after the needle
-					right := pos + len(needle)
+					right := end
 					words = 0
 					for right < len(bt) && words < 3 {
 						// skip spaces to the right
@@ -638,36 +1145,34 @@ func (a *App) colors(w http.ResponseWriter, r *http.Request) {
 				}
 				qh.Hits = append(qh.Hits, TermHit{Code: c, Seg: seg, Field: field, DIdx: didx, Pos: pos})
 			}
-			start = pos + len(needle)
 		}
 	}
 
-	for _, ch := range a.QB.Chapters {
+	done := timeOp(opSearch)
+	// the index narrows down the quests for a literal term
+	idx := qb.textIndex()
+	cand := idx.candidates([]*matcher{m})
+	for _, ch := range qb.Chapters {
 		if len(scope) > 0 && !scope[ch.Name] {
 			continue
 		}
 		for _, qs := range ch.Quests {
+			// hex colors are stripped differently here, and can hide a word
+			if cand != nil && !cand[qs.ID] && !idx.quest(qs).hex {
+				continue
+			}
 			ttl := qs.GetTitle()
 			process(ch.Name, qs.ID, ttl, qs.Title, "title", -1)
 			process(ch.Name, qs.ID, ttl, qs.Subtitle, "subtitle", -1)
-			// Handle description per raw line when available for precise targeting
-			var qm = qs.raw
-			if dl, ok := qm["description"].([]any); ok {
-				for di := range dl {
-					if s, ok := dl[di].(string); ok {
-						process(ch.Name, qs.ID, ttl, s, "description", di)
-					}
-				}
-			} else if s, ok := qm["description"].(string); ok {
-				process(ch.Name, qs.ID, ttl, s, "description", -1)
-			} else {
-				// fallback to joined string held in struct
-				if qs.Description != "" {
-					process(ch.Name, qs.ID, ttl, qs.Description, "description", -1)
+			// description lines are numbered as recolorQuests numbers them
+			if qs.Description != "" {
+				for di, s := range strings.Split(qs.Description, "\n") {
+					process(ch.Name, qs.ID, ttl, s, "description", di)
 				}
 			}
 		}
 	}
+	done()
 
 	type ColorCount struct {
 		Code  string
@@ -679,7 +1184,7 @@ func (a *App) colors(w http.ResponseWriter, r *http.Request) {
 		// Flatten ids in chapter order
 		var ids []string
 		if set := idsByColor[code]; set != nil {
-			for _, ch := range a.QB.Chapters {
+			for _, ch := range qb.Chapters {
 				for j := range ch.Quests {
 					if _, ok := set[ch.Quests[j].ID]; ok {
 						ids = append(ids, ch.Quests[j].ID)
@@ -689,7 +1194,13 @@ func (a *App) colors(w http.ResponseWriter, r *http.Request) {
 		}
 		res = append(res, ColorCount{Code: code, Count: n, IDs: strings.Join(ids, ",")})
 	}
-	sort.Slice(res, func(i, j int) bool { return res[i].Count > res[j].Count })
+	// most used first; ties in code order so the page is the same every time
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Code < res[j].Code
+	})
 	data["ColorResults"] = res
 	data["Term"] = term
 	// Build ordered per-quest results (one line per quest, dedup hits per quest)
@@ -698,7 +1209,7 @@ func (a *App) colors(w http.ResponseWriter, r *http.Request) {
 		Hits                []TermHit
 	}
 	var qlines []QuestLine
-	for _, ch := range a.QB.Chapters {
+	for _, ch := range qb.Chapters {
 		if len(scope) > 0 && !scope[ch.Name] {
 			continue
 		}
@@ -730,7 +1241,7 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 
 func writeError(w http.ResponseWriter, isAjax bool, msg string, code int) {
 	if isAjax {
-		writeJSON(w, code, map[string]any{"ok": false, "erorr": msg})
+		writeJSON(w, code, map[string]any{"ok": false, "error": msg})
 		return
 	}
 	http.Error(w, msg, code)
@@ -739,6 +1250,7 @@ func writeError(w http.ResponseWriter, isAjax bool, msg string, code int) {
 // colorsRecolor handles POST /colors/recolor. It applies a color code to all
 // occurrences of a term within the specified quest IDs, then rescans data.
 func (a *App) colorsRecolor(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
 	isAjax := strings.Contains(r.Header.Get("Accept"), "application/json") || r.Header.Get("X-Requested-With") == "XMLHttpRequest"
 	if err := r.ParseMultipartForm(32 << 20); err != nil {
 		writeError(w, isAjax, "invalid form", http.StatusBadRequest)
@@ -748,17 +1260,19 @@ func (a *App) colorsRecolor(w http.ResponseWriter, r *http.Request) {
 	idsParam := strings.TrimSpace(r.Form.Get("ids"))
 	color := strings.TrimSpace(r.Form.Get("color"))
 	ci := r.Form.Get("ci") == "1" || strings.EqualFold(r.Form.Get("ci"), "true")
-	if term == "" || idsParam == "" || len(color) != 1 {
+	if term == "" || idsParam == "" || color == "" {
 		writeError(w, isAjax, "missing term/ids/color", http.StatusBadRequest)
 		return
 	}
-	c := color[0]
-	if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
-		writeError(w, isAjax, "invalid color", http.StatusBadRequest)
+	tm, err := newMatcher(term, r.Form.Get("regex") == "1", ci)
+	if err != nil {
+		writeError(w, isAjax, "invalid regular expression: "+err.Error(), http.StatusBadRequest)
 		return
 	}
-	if c >= 'A' && c <= 'F' {
-		c = c - 'A' + 'a'
+	c, ok := parseColor(color)
+	if !ok {
+		writeError(w, isAjax, "invalid color", http.StatusBadRequest)
+		return
 	}
 
 	// Build index questID -> chapter name
@@ -775,7 +1289,7 @@ func (a *App) colorsRecolor(w http.ResponseWriter, r *http.Request) {
 		}
 		idset[id] = struct{}{}
 	}
-	for _, ch := range a.QB.Chapters {
+	for _, ch := range qb.Chapters {
 		for _, qs := range ch.Quests {
 			if _, ok := idset[qs.ID]; ok {
 				targets = append(targets, target{Chapter: ch.Name, ID: qs.ID})
@@ -787,91 +1301,98 @@ func (a *App) colorsRecolor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Group targets by chapter and update files
+	// Group targets by chapter
 	byChapter := make(map[string]map[string]struct{})
+	var names []string
 	for _, t := range targets {
 		if byChapter[t.Chapter] == nil {
 			byChapter[t.Chapter] = make(map[string]struct{})
+			names = append(names, t.Chapter)
 		}
 		byChapter[t.Chapter][t.ID] = struct{}{}
 	}
 
-	for cname, qids := range byChapter {
-		path := filepath.Join(a.Root, "quests", "chapters", cname+".snbt")
-		f, err := os.Open(path)
-		if err != nil {
-			writeError(w, isAjax, "open: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		v, err := snbt.Decode(f)
-		f.Close()
-		if err != nil {
-			writeError(w, isAjax, "decode: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		m, ok := v.(map[string]any)
-		if !ok {
-			writeError(w, isAjax, "chapter not a compound", http.StatusInternalServerError)
-			return
-		}
-		arr, ok := m["quests"].([]any)
-		if !ok {
-			writeError(w, isAjax, "chapter missing quests", http.StatusInternalServerError)
-			return
-		}
-		// update any matching quests
-		for i := range arr {
-			qm, ok := arr[i].(map[string]any)
-			if !ok {
-				continue
-			}
-			id, _ := qm["id"].(string)
-			if _, ok := qids[id]; !ok {
+	count := 0
+	edited, err := editChapters(qb, names, func(ch *Chapter) ([]string, error) {
+		n, ids := recolorChapter(ch, byChapter[ch.Name], tm, c)
+		count += n
+		return ids, nil
+	})
+	if err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.auditEdits(r, "recolor", term, edited)
+
+	// refresh in-memory data
+	a.reload()
+	if isAjax {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "count": count})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recolorQuests applies fix to the text of the quests of ch, opened by
+// editChapters, as the colors page shows it: with translation keys resolved,
+// and descriptions split into lines numbered from 0. fix is given the field,
+// the description line or -1, the text and the sign new codes should use, and
+// returns the new text. It returns the number of fields and lines changed,
+// and the ids of the quests they belong to.
+func recolorQuests(ch *Chapter, fix func(q *Quest, field string, line int, s string, sign rune) string) (int, []string) {
+	// new codes follow the field's convention, or the chapter's
+	var texts []string
+	for _, q := range ch.Quests {
+		texts = append(texts, q.Title, q.Subtitle, q.Description)
+	}
+	sign := codeSign(signAmp, texts...)
+	n := 0
+	var ids []string
+	for _, q := range ch.Quests {
+		before := n
+		for field, f := range map[string]*string{"title": &q.Title, "subtitle": &q.Subtitle} {
+			if *f == "" {
 				continue
 			}
-			// fields: title, subtitle, description (list of strings or string)
-			if s, ok := qm["title"].(string); ok {
-				qm["title"] = recolorString(s, term, c, ci)
-			}
-			if s, ok := qm["subtitle"].(string); ok {
-				qm["subtitle"] = recolorString(s, term, c, ci)
+			if s := fix(q, field, -1, *f, codeSign(sign, *f)); s != *f {
+				*f, n = s, n+1
 			}
-			if dl, ok := qm["description"].([]any); ok {
-				for j := range dl {
-					if s, ok2 := dl[j].(string); ok2 {
-						dl[j] = recolorString(s, term, c, ci)
-					}
+		}
+		if q.Description != "" {
+			lines := strings.Split(q.Description, "\n")
+			dsign := codeSign(sign, lines...)
+			changed := false
+			for i, line := range lines {
+				if s := fix(q, "description", i, line, dsign); s != line {
+					lines[i], changed, n = s, true, n+1
 				}
-				qm["description"] = dl
-			} else if s, ok := qm["description"].(string); ok {
-				qm["description"] = recolorString(s, term, c, ci)
 			}
-			arr[i] = qm
-		}
-		m["quests"] = arr
-		var buf bytes.Buffer
-		if err := snbt.Encode(&buf, m); err != nil {
-			writeError(w, isAjax, "encode: "+err.Error(), http.StatusInternalServerError)
-			return
+			if changed {
+				q.Description = strings.Join(lines, "\n")
+			}
 		}
-		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
-			writeError(w, isAjax, "write: "+err.Error(), http.StatusInternalServerError)
-			return
+		if n > before {
+			ids = append(ids, q.ID)
 		}
 	}
+	return n, ids
+}
 
-	// refresh in-memory data
-	a.reload()
-	if isAjax {
-		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
-		return
-	}
-	w.WriteHeader(http.StatusNoContent)
+// recolorChapter applies color to the occurrences of tm in the quests qids of
+// ch, as colorsRecolor does; see recolorQuests.
+func recolorChapter(ch *Chapter, qids map[string]struct{}, tm *matcher, c string) (int, []string) {
+	return recolorQuests(ch, func(q *Quest, _ string, _ int, s string, sign rune) string {
+		if _, ok := qids[q.ID]; !ok {
+			return s
+		}
+		return recolorString(s, tm, c, sign)
+	})
 }
 
 // colorsRecolorOne handles POST /colors/recolor_one to recolor a single occurrence
 // of a term in a specific quest field.
 func (a *App) colorsRecolorOne(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
 	isAjax := strings.Contains(r.Header.Get("Accept"), "application/json") || r.Header.Get("X-Requested-With") == "XMLHttpRequest"
 
 	if err := r.ParseMultipartForm(32 << 20); err != nil {
@@ -888,15 +1409,17 @@ func (a *App) colorsRecolorOne(w http.ResponseWriter, r *http.Request) {
 	color := strings.TrimSpace(r.Form.Get("color"))
 	ci := r.Form.Get("ci") == "1" || strings.EqualFold(r.Form.Get("ci"), "true")
 
-	if qid == "" || term == "" || field == "" || posStr == "" || len(color) != 1 {
+	if qid == "" || term == "" || field == "" || posStr == "" || color == "" {
 		writeError(w, isAjax, "missing params", http.StatusBadRequest)
 		return
 	}
-	c := color[0]
-	if c >= 'A' && c <= 'F' {
-		c = c - 'A' + 'a'
+	tm, err := newMatcher(term, r.Form.Get("regex") == "1", ci)
+	if err != nil {
+		writeError(w, isAjax, "invalid regular expression: "+err.Error(), http.StatusBadRequest)
+		return
 	}
-	if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')) {
+	c, ok := parseColor(color)
+	if !ok {
 		writeError(w, isAjax, "invalid color", http.StatusBadRequest)
 		return
 	}
@@ -914,7 +1437,7 @@ func (a *App) colorsRecolorOne(w http.ResponseWriter, r *http.Request) {
 
 	// locate quest and chapter
 	var ch *Chapter
-	for _, c := range a.QB.Chapters {
+	for _, c := range qb.Chapters {
 		for j := range c.Quests {
 			if c.Quests[j].ID == qid {
 				ch = c
@@ -930,263 +1453,278 @@ func (a *App) colorsRecolorOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := filepath.Join(a.Root, "quests", "chapters", ch.Name+".snbt")
-	f, err := os.Open(path)
+	edited, err := editChapters(qb, []string{ch.Name}, func(ch *Chapter) ([]string, error) {
+		_, ids := recolorQuests(ch, func(q *Quest, f string, line int, s string, sign rune) string {
+			if q.ID != qid || f != field || f == "description" && line != didx {
+				return s
+			}
+			return recolorOne(s, tm, c, pos, sign)
+		})
+		return ids, nil
+	})
 	if err != nil {
-		writeError(w, isAjax, "open: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, isAjax, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	v, err := snbt.Decode(f)
-	f.Close()
-	if err != nil {
-		writeError(w, isAjax, "decode: "+err.Error(), http.StatusInternalServerError)
+	if len(edited) == 0 {
+		writeError(w, isAjax, "the text has changed; search again", http.StatusConflict)
 		return
 	}
-	m, ok := v.(map[string]any)
-	if !ok {
-		writeError(w, isAjax, "chapter not a compound", http.StatusInternalServerError)
+	a.auditEdits(r, "recolor", term, edited)
+	a.reload()
+	if isAjax {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 		return
 	}
-	arr, ok := m["quests"].([]any)
-	if !ok {
-		writeError(w, isAjax, "chapter missing quests", http.StatusInternalServerError)
-		return
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recolorTarget is an occurrence of a term picked on the colors page: the
+// position of its match in a field of a quest, and for descriptions the line.
+type recolorTarget struct {
+	QID, Field string
+	DIdx, Pos  int
+}
+
+// parseRecolorTarget parses "qid:field:didx:pos", as the colors page's
+// checkboxes send them.
+func parseRecolorTarget(s string) (recolorTarget, bool) {
+	f := strings.Split(s, ":")
+	if len(f) != 4 || f[0] == "" {
+		return recolorTarget{}, false
 	}
+	didx, err1 := strconv.Atoi(f[2])
+	pos, err2 := strconv.Atoi(f[3])
+	if err1 != nil || err2 != nil {
+		return recolorTarget{}, false
+	}
+	switch f[1] {
+	case "title", "subtitle", "description":
+	default:
+		return recolorTarget{}, false
+	}
+	return recolorTarget{QID: f[0], Field: f[1], DIdx: didx, Pos: pos}, true
+}
 
-	// update one quest/field occurrence
-	for i := range arr {
-		qm, ok := arr[i].(map[string]any)
-		if !ok {
-			continue
-		}
-		if id, _ := qm["id"].(string); id != qid {
-			continue
-		}
-		// helper to update a string field in qm
-		updateField := func(key string, s string) {
-			if s == "" {
-				return
-			}
-			qm[key] = recolorOne(s, term, c, ci, pos)
-		}
-		switch field {
-		case "title":
-			if s, ok := qm["title"].(string); ok {
-				updateField("title", s)
-			}
-		case "subtitle":
-			if s, ok := qm["subtitle"].(string); ok {
-				updateField("subtitle", s)
-			}
-		case "description":
-			if dl, ok := qm["description"].([]any); ok {
-				// Operate across the joined string; but apply to the one line where the match was detected if didx >= 0
-				if didx >= 0 && didx < len(dl) {
-					if s, ok := dl[didx].(string); ok {
-						dl[didx] = recolorOne(s, term, c, ci, pos)
-					}
-					qm["description"] = dl
-				} else {
-					// fallback: join all lines and operate once (rare)
-					// Not ideal, but keeps behavior consistent if we didn't track didx
-				}
-			} else if s, ok := qm["description"].(string); ok {
-				updateField("description", s)
-			}
-		}
-		arr[i] = qm
-		break
+// colorsRecolorMany handles POST /colors/recolor_many, which recolors the
+// occurrences of a term picked on the colors page: one "target" per
+// occurrence, written as parseRecolorTarget reads them. Each chapter file is
+// changed in one pass, and all of them are written together.
+func (a *App) colorsRecolorMany(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	isAjax := strings.Contains(r.Header.Get("Accept"), "application/json") || r.Header.Get("X-Requested-With") == "XMLHttpRequest"
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(w, isAjax, "invalid form", http.StatusBadRequest)
+		return
 	}
-	m["quests"] = arr
-	var buf bytes.Buffer
-	if err := snbt.Encode(&buf, m); err != nil {
-		writeError(w, isAjax, "encode: "+err.Error(), http.StatusInternalServerError)
+	term := strings.TrimSpace(r.Form.Get("term"))
+	color := strings.TrimSpace(r.Form.Get("color"))
+	ci := r.Form.Get("ci") == "1" || strings.EqualFold(r.Form.Get("ci"), "true")
+	if term == "" || color == "" || len(r.Form["target"]) == 0 {
+		writeError(w, isAjax, "missing term/target/color", http.StatusBadRequest)
 		return
 	}
-	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
-		writeError(w, isAjax, "write: "+err.Error(), http.StatusInternalServerError)
+	tm, err := newMatcher(term, r.Form.Get("regex") == "1", ci)
+	if err != nil {
+		writeError(w, isAjax, "invalid regular expression: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, ok := parseColor(color)
+	if !ok {
+		writeError(w, isAjax, "invalid color", http.StatusBadRequest)
 		return
 	}
+
+	byChapter := make(map[string][]recolorTarget)
+	for _, s := range r.Form["target"] {
+		tg, ok := parseRecolorTarget(s)
+		if !ok {
+			writeError(w, isAjax, fmt.Sprintf("invalid target %q", s), http.StatusBadRequest)
+			return
+		}
+		q, ok := qb.questMap[tg.QID]
+		if !ok {
+			writeError(w, isAjax, "quest not found: "+tg.QID, http.StatusNotFound)
+			return
+		}
+		byChapter[q.Chapter.Name] = append(byChapter[q.Chapter.Name], tg)
+	}
+
+	count := 0
+	edited, err := editChapters(qb, slices.Collect(maps.Keys(byChapter)), func(ch *Chapter) ([]string, error) {
+		n, ids := recolorPicked(ch, byChapter[ch.Name], tm, c)
+		count += n
+		return ids, nil
+	})
+	if err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.auditEdits(r, "recolor", term, edited)
 	a.reload()
 	if isAjax {
-		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "count": count})
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// recolorOne modifies only the specific match at targetPos (in stripped text index).
-// If a color is active for that match, it replaces the color code as in recolorString.
-// If no color is active, wraps the term in &<color> and &r.
-func recolorOne(s, term string, color byte, ci bool, targetPos int) string {
-	if s == "" || term == "" {
-		return s
-	}
-	rs := []rune(s)
-	var stripped []rune
-	var colorsAt []string
-	var srcIdx []int
-	var codeIdxAt []int // index of color code char after '&' if active
-	cur := ""
-	lastCodeIdx := -1
-	for i := 0; i < len(rs); i++ {
-		r := rs[i]
-		if r == '&' || r == '\u00A7' {
-			if i+1 < len(rs) {
-				code := rs[i+1]
-				if (code >= '0' && code <= '9') || (code >= 'a' && code <= 'f') || (code >= 'A' && code <= 'F') {
-					if code >= 'A' && code <= 'F' {
-						code = code - 'A' + 'a'
-					}
-					cur = "c" + string(code)
-					lastCodeIdx = i + 1
-				} else if code == 'r' || code == 'R' {
-					cur = ""
-					lastCodeIdx = -1
-				}
-				i++
-				continue
-			}
+// recolorPicked applies color to the targets in ch, which all belong to it.
+// The picked positions of each line are recolored together, so that earlier
+// ones don't move later ones; see recolorQuests.
+func recolorPicked(ch *Chapter, targets []recolorTarget, tm *matcher, c string) (int, []string) {
+	// the positions to recolor in each line, by quest, field and line
+	type line struct {
+		qid, field string
+		didx       int
+	}
+	picked := make(map[line]map[int]bool)
+	for _, tg := range targets {
+		l := line{tg.QID, tg.Field, tg.DIdx}
+		if tg.Field != "description" {
+			l.didx = -1
 		}
-		stripped = append(stripped, r)
-		colorsAt = append(colorsAt, cur)
-		srcIdx = append(srcIdx, i)
-		codeIdxAt = append(codeIdxAt, lastCodeIdx)
-	}
-	hay := string(stripped)
-	needle := term
-	if ci {
-		hay = strings.ToLower(hay)
-		needle = strings.ToLower(term)
+		if picked[l] == nil {
+			picked[l] = make(map[int]bool)
+		}
+		picked[l][tg.Pos] = true
 	}
-	if len(needle) == 0 || len(hay) < len(needle) {
+	return recolorQuests(ch, func(q *Quest, field string, didx int, s string, sign rune) string {
+		if only := picked[line{q.ID, field, didx}]; only != nil {
+			return recolorMatches(s, tm, c, sign, only)
+		}
+		return s
+	})
+}
+
+// recolorOne modifies only the specific match at targetPos (in stripped text index).
+// If a color is active for that match, it replaces the color code as in recolorString.
+// If no color is active, wraps the term in the color code and <sign>r.
+func recolorOne(s string, m *matcher, color string, targetPos int, sign rune) string {
+	if s == "" {
 		return s
 	}
-	start := 0
-	for start <= len(hay)-len(needle) {
-		idx := strings.Index(hay[start:], needle)
-		if idx < 0 {
-			break
-		}
-		pos := start + idx
+	rs, stripped, srcIdx, colorAt := scanColors(s)
+	for _, loc := range m.index(string(stripped)) {
+		pos, end := loc[0], loc[1]
 		if pos == targetPos {
-			// perform change
-			if colorsAt[pos] != "" {
+			if codeIdx := colorAt[pos]; codeIdx >= 0 {
 				// replace existing color code
-				codeIdx := codeIdxAt[pos]
-				if codeIdx >= 0 && codeIdx < len(rs) {
-					rs[codeIdx] = rune(color)
-				}
-				return string(rs)
+				return spliceColors(rs, map[int]bool{codeIdx: true}, color, nil, nil)
 			}
 			// no active color: wrap the term only
-			startSrc := srcIdx[pos]
-			endSrc := srcIdx[pos+len(needle)-1]
-			injectBefore := map[int]string{startSrc: "&" + string(color)}
-			injectAfter := map[int]string{endSrc: "&r"}
-			var out []rune
-			for i := 0; i < len(rs); i++ {
-				if code, ok := injectBefore[i]; ok {
-					out = append(out, []rune(code)...)
-				}
-				out = append(out, rs[i])
-				if code, ok := injectAfter[i]; ok {
-					out = append(out, []rune(code)...)
-				}
-			}
-			return string(out)
+			before := map[int]string{srcIdx[pos]: colorCode(color, sign)}
+			after := map[int]string{srcIdx[end-1]: string(sign) + "r"}
+			return spliceColors(rs, nil, color, before, after)
 		}
-		start = pos + len(needle)
 	}
 	return s
 }
 
 // recolorString replaces the color code that applies to each occurrence of term
-// with the new color. It does not insert surrounding color/reset codes.
-// If no color code is active for a matched term, the string is left unchanged
-// for that occurrence (to avoid coloring unintended spans).
-func recolorString(s, term string, color byte, ci bool) string {
-	if s == "" || term == "" {
-		return s
-	}
-	rs := []rune(s)
-	// Build stripped text and mappings
-	var stripped []rune
-	var srcIdx []int
-	var colorCodeIdxAt []int
-	lastColorIdx := -1
-	for i := 0; i < len(rs); i++ {
-		r := rs[i]
-		if r == '&' || r == '\u00A7' {
-			if i+1 < len(rs) {
-				code := rs[i+1]
-				if (code >= '0' && code <= '9') || (code >= 'a' && code <= 'f') || (code >= 'A' && code <= 'F') {
-					if code >= 'A' && code <= 'F' {
-						code = code - 'A' + 'a'
-					}
-					lastColorIdx = i + 1
-				} else if code == 'r' || code == 'R' {
-					lastColorIdx = -1
-				}
-				i++
-				continue
-			}
-		}
-		stripped = append(stripped, r)
-		srcIdx = append(srcIdx, i)
-		colorCodeIdxAt = append(colorCodeIdxAt, lastColorIdx)
-	}
-	hay := string(stripped)
-	needle := term
-	if ci {
-		hay = strings.ToLower(hay)
-		needle = strings.ToLower(term)
-	}
-	if len(needle) == 0 || len(hay) < len(needle) {
+// with the new color, which may be a code or a #rrggbb hex color. Occurrences
+// with no color code active are wrapped in the color and a reset, written
+// with sign.
+func recolorString(s string, m *matcher, color string, sign rune) string {
+	return recolorMatches(s, m, color, sign, nil)
+}
+
+// recolorMatches recolors the occurrences of term like recolorString, but
+// only those starting at the positions in only, indexes into the text without
+// its codes, unless only is nil.
+func recolorMatches(s string, m *matcher, color string, sign rune, only map[int]bool) string {
+	if s == "" {
 		return s
 	}
-	injectBefore := make(map[int]string)
-	injectAfter := make(map[int]string)
+	rs, stripped, srcIdx, colorAt := scanColors(s)
+	codes := make(map[int]bool)
+	before := make(map[int]string)
+	after := make(map[int]string)
 	modified := false
-	for start := 0; start <= len(hay)-len(needle); {
-		idx := strings.Index(hay[start:], needle)
-		if idx < 0 {
-			break
+	for _, loc := range m.index(string(stripped)) {
+		pos, end := loc[0], loc[1]-1
+		if only != nil && !only[pos] {
+			continue
 		}
-		pos := start + idx
-		end := pos + len(needle) - 1
-		if pos < len(srcIdx) {
-			if codeIdx := colorCodeIdxAt[pos]; codeIdx >= 0 {
-				rs[codeIdx] = rune(color)
-				modified = true
+		if pos < len(srcIdx) && end < len(srcIdx) {
+			if codeIdx := colorAt[pos]; codeIdx >= 0 {
+				codes[codeIdx] = true
 			} else {
-				injectBefore[srcIdx[pos]] = "&" + string(color)
-				injectAfter[srcIdx[end]] = "&r"
-				modified = true
+				before[srcIdx[pos]] = colorCode(color, sign)
+				after[srcIdx[end]] = string(sign) + "r"
 			}
+			modified = true
 		}
-		start = pos + len(needle)
 	}
 	if !modified {
 		return s
 	}
+	return spliceColors(rs, codes, color, before, after)
+}
+
+// scanColors splits s into its visible text and, for each visible rune, its
+// index in s and the index of the color code that applies to it, or -1.
+func scanColors(s string) (rs, stripped []rune, srcIdx, colorAt []int) {
+	rs = []rune(s)
+	active := -1
+	for i := 0; i < len(rs); i++ {
+		if n := colorCodeLen(rs, i); n > 0 {
+			active = i
+			i += n - 1
+			continue
+		}
+		if (rs[i] == '&' || rs[i] == '\u00A7') && i+1 < len(rs) {
+			if code := rs[i+1]; code == 'r' || code == 'R' {
+				active = -1
+			}
+			i++
+			continue
+		}
+		stripped = append(stripped, rs[i])
+		srcIdx = append(srcIdx, i)
+		colorAt = append(colorAt, active)
+	}
+	return rs, stripped, srcIdx, colorAt
+}
+
+// spliceColors returns rs with the color codes starting at the indexes in
+// codes replaced by color, keeping each code's sign, and with the codes in
+// before and after added around the runes at their indexes.
+func spliceColors(rs []rune, codes map[int]bool, color string, before, after map[int]string) string {
 	var out []rune
 	for i := 0; i < len(rs); i++ {
-		if code, ok := injectBefore[i]; ok {
+		if codes[i] {
+			out = append(out, []rune(colorCode(color, rs[i]))...)
+			i += colorCodeLen(rs, i) - 1
+			continue
+		}
+		if code, ok := before[i]; ok {
 			out = append(out, []rune(code)...)
 		}
 		out = append(out, rs[i])
-		if code, ok := injectAfter[i]; ok {
+		if code, ok := after[i]; ok {
 			out = append(out, []rune(code)...)
 		}
 	}
 	return string(out)
 }
 
+// questRedirect handles GET "/q/{quest}", a stable link to a quest that
+// resolves to its row on its chapter's page, wherever the quest has moved.
+func (a *App) questRedirect(w http.ResponseWriter, r *http.Request) {
+	q, ok := a.QB().questMap[chi.URLParam(r, "quest")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, "/chapter/"+q.Chapter.Name+"#q-"+q.ID, http.StatusFound)
+}
+
 // chapterDetail handles GET "/chapter/{chapter}".
 func (a *App) chapterDetail(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
 	name := chi.URLParam(r, "chapter")
-	ch, _ := a.QB.chapterMap[name]
+	ch, _ := qb.chapterMap[name]
 	if ch == nil {
 		http.NotFound(w, r)
 		return
@@ -1194,46 +1732,553 @@ func (a *App) chapterDetail(w http.ResponseWriter, r *http.Request) {
 	data := a.baseData(r, ch.Title)
 	data["Chapter"] = ch
 	data["SelectedChapter"] = ch.Name
+	data["Links"] = qb.chapterLinks(ch)
+	data["AllQuests"] = qb.Quests
+	data["Msg"] = r.URL.Query().Get("msg")
 	a.render(w, "chapter.gohtml", data)
 }
 
+// compare handles GET "/compare" and reports divergences between this book
+// and CompareRoot. The other book is only ever the one qbedit was started
+// with, so a request can't read an arbitrary dir.
+func (a *App) compare(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	root := a.CompareRoot
+	data := a.baseData(r, "Compare Books")
+	data["CompareRoot"] = root
+	if root != "" {
+		// load the other book on each request so edits to it are picked up
+		other, err := NewQuestBook(root)
+		if err != nil {
+			data["CompareErr"] = err.Error()
+		} else {
+			data["Comparison"] = compareBooks(qb, other)
+		}
+	}
+	a.render(w, "compare.gohtml", data)
+}
+
+// questCompare handles GET "/compare/quest", a side by side editor for two
+// quests: ?a= and ?b= are quest ids, and b defaults to a. With ?other=1 quest
+// b is read from CompareRoot and only quest a can be edited.
+func (a *App) questCompare(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	idA := strings.TrimSpace(r.URL.Query().Get("a"))
+	idB := strings.TrimSpace(r.URL.Query().Get("b"))
+	fromOther := r.URL.Query().Get("other") != "" && a.CompareRoot != ""
+	if idB == "" {
+		idB = idA
+	}
+
+	data := a.baseData(r, "Compare Quests")
+	data["IDA"], data["IDB"], data["Other"] = idA, idB, fromOther
+	data["CompareRoot"] = a.CompareRoot
+	data["AllQuests"] = qb.Quests
+
+	other := qb
+	if fromOther {
+		var err error
+		if other, err = NewQuestBook(a.CompareRoot); err != nil {
+			data["CompareErr"] = "could not load " + a.CompareRoot + ": " + err.Error()
+			a.render(w, "quest_compare.gohtml", data)
+			return
+		}
+	}
+	qa, qbq := qb.questMap[idA], other.questMap[idB]
+	switch {
+	case idA == "":
+	case qa == nil:
+		data["CompareErr"] = "quest " + idA + " not found"
+	case qbq == nil:
+		data["CompareErr"] = "quest " + idB + " not found"
+	case qa == qbq:
+		data["CompareErr"] = "choose two different quests"
+	default:
+		data["A"], data["B"] = qa, qbq
+		data["EditableB"] = !fromOther
+		data["Fields"] = questFieldDiffs(qa, qbq)
+	}
+	a.render(w, "quest_compare.gohtml", data)
+}
+
+// graphScope returns the chapters selected by the "chapter" query param, or
+// every chapter if it is empty. ok is false if the chapter doesn't exist.
+func (a *App) graphScope(r *http.Request) (chapters []*Chapter, selected *Chapter, ok bool) {
+	qb := a.QB()
+	name := strings.TrimSpace(r.URL.Query().Get("chapter"))
+	if name == "" {
+		return qb.Chapters, nil, true
+	}
+	ch, ok := qb.chapterMap[name]
+	if !ok {
+		return nil, nil, false
+	}
+	return []*Chapter{ch}, ch, true
+}
+
+// graph handles GET "/graph" and renders the quest dependency graph for one
+// chapter (?chapter=name) or the whole book.
+func (a *App) graph(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	chapters, ch, ok := a.graphScope(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	title := "Dependency Graph"
+	data := a.baseData(r, title)
+	if ch != nil {
+		data["Chapter"] = ch
+		data["SelectedChapter"] = ch.Name
+	}
+	data["Graph"] = buildGraph(qb, chapters)
+	data["NodeW"] = graphNodeW
+	data["NodeH"] = graphNodeH
+	a.render(w, "graph.gohtml", data)
+}
+
+// graphJSON handles GET "/graph.json" and returns the laid out graph for
+// client-side rendering or external tools.
+func (a *App) graphJSON(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	chapters, _, ok := a.graphScope(r)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": "chapter not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, buildGraph(qb, chapters))
+}
+
+// chapterCreate handles POST "/chapters/new" and creates an empty chapter.
+func (a *App) chapterCreate(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	title := strings.TrimSpace(r.FormValue("title"))
+	name := strings.TrimSpace(r.FormValue("name"))
+	group := strings.TrimSpace(r.FormValue("group"))
+	name, err := qb.CreateChapter(name, title, group)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "create chapter", name, nil, "")
+	a.reload()
+	http.Redirect(w, r, "/chapter/"+name, http.StatusSeeOther)
+}
+
+// chapterOrder handles GET "/chapters/order" and shows the drag and drop
+// chapter ordering page.
+func (a *App) chapterOrder(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Chapter order")
+	a.render(w, "chapter_order.gohtml", data)
+}
+
+// chapterReorder handles POST "/chapters/order". The form lists every chapter
+// of one group (or of the ungrouped chapters if "group" is empty) in their new
+// order as repeated "chapter" values.
+func (a *App) chapterReorder(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	isAjax := r.Header.Get("X-Requested-With") == "XMLHttpRequest" || strings.Contains(r.Header.Get("Accept"), "application/json")
+	if err := r.ParseForm(); err != nil {
+		writeError(w, isAjax, "invalid form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := qb.ReorderChapters(r.Form.Get("group"), r.Form["chapter"]); err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "reorder chapters", "", nil, strings.Join(r.Form["chapter"], ", "))
+	a.reload()
+	if isAjax {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		return
+	}
+	http.Redirect(w, r, "/chapters/order", http.StatusSeeOther)
+}
+
+// chapterRename handles POST "/chapter/{chapter}/rename", which changes the
+// chapter's file name and optionally its title.
+func (a *App) chapterRename(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cname := chi.URLParam(r, "chapter")
+	if _, ok := qb.chapterMap[cname]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		name = cname
+	}
+	title := strings.TrimSpace(r.FormValue("title"))
+	if key := qb.chapterMap[cname].titleKey; key != "" {
+		// the title is translated; change the text and keep the key
+		if l := qb.Lang.clone(); l.Set(key, title) {
+			if err := l.Save(); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		title = "{" + key + "}"
+	}
+	if err := qb.RenameChapter(cname, name, title); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "rename chapter", name, nil, cname+" → "+name)
+	a.reload()
+	http.Redirect(w, r, "/chapter/"+name, http.StatusSeeOther)
+}
+
+// chapterDelete handles POST "/chapter/{chapter}/delete". The form must repeat
+// the chapter's name in "confirm" so a stray request can't delete a chapter.
+func (a *App) chapterDelete(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cname := chi.URLParam(r, "chapter")
+	if _, ok := qb.chapterMap[cname]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.FormValue("confirm") != cname {
+		http.Error(w, "type the chapter name to confirm deletion", http.StatusBadRequest)
+		return
+	}
+	if err := qb.DeleteChapter(cname); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.audit(r, "delete chapter", cname, nil, "")
+	a.reload()
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
 // errors handles GET "/errors".
 func (a *App) errors(w http.ResponseWriter, r *http.Request) {
 	data := a.baseData(r, "Errors")
-	data["Failures"] = nil
+	data["Failures"] = a.QB().Failures
+	data["Msg"] = r.URL.Query().Get("msg")
 	a.render(w, "errors.gohtml", data)
 }
 
+// errorsRetry handles POST "/errors/retry", which loads the book again after
+// the files that failed were fixed outside qbedit.
+func (a *App) errorsRetry(w http.ResponseWriter, r *http.Request) {
+	before := len(a.QB().Failures)
+	a.reload()
+	after := len(a.QB().Failures)
+	msg := "Reloaded the book: no errors."
+	if after > 0 {
+		msg = fmt.Sprintf("Reloaded the book: %d of %d errors remain.", after, before)
+	}
+	http.Redirect(w, r, "/errors?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// status handles GET "/status" and shows the largest and slowest to parse
+// files from the last load, the slowest routes and the recent scheduled
+// backups.
+func (a *App) status(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	data := a.baseData(r, "Status")
+	data["Stats"] = qb.Stats
+	data["LangFile"] = qb.Lang
+	data["Backups"] = a.Backups
+	data["Routes"] = a.requestTimes.Routes()
+	data["Reloads"], data["ReloadLoads"] = a.reloads.counts()
+	a.render(w, "status.gohtml", data)
+}
+
+// snippetForm is a snippet as the snippet_form template edits it, with the
+// page's UI language.
+type snippetForm struct {
+	Snippet
+	Lang string
+}
+
+// snippets handles GET "/snippets", where the pack's snippets are managed.
+func (a *App) snippets(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Snippets")
+	lang := a.lang(r)
+	var forms []snippetForm
+	for _, s := range a.Snippets.List() {
+		forms = append(forms, snippetForm{s, lang})
+	}
+	data["Snippets"] = forms
+	data["NewSnippet"] = snippetForm{Lang: lang}
+	data["Edit"] = r.URL.Query().Get("edit")
+	if msg := r.URL.Query().Get("msg"); msg != "" {
+		data["SnippetMsg"] = msg
+	}
+	a.render(w, "snippets.gohtml", data)
+}
+
+// snippetSave handles POST "/snippets", adding or replacing a snippet.
+func (a *App) snippetSave(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	sn := Snippet{
+		Name:     strings.TrimSpace(r.Form.Get("name")),
+		Title:    strings.TrimSpace(r.Form.Get("title")),
+		Body:     strings.ReplaceAll(r.Form.Get("body"), "\r\n", "\n"),
+		Defaults: parseDefaults(r.Form.Get("defaults")),
+	}
+	if err := a.Snippets.Put(sn); err != nil {
+		http.Redirect(w, r, "/snippets?msg="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/snippets", http.StatusSeeOther)
+}
+
+// snippetDelete handles POST "/snippets/{snippet}/delete".
+func (a *App) snippetDelete(w http.ResponseWriter, r *http.Request) {
+	if err := a.Snippets.Delete(chi.URLParam(r, "snippet")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/snippets", http.StatusSeeOther)
+}
+
+// snippetRender handles GET "/snippets/{snippet}/render". Query parameters
+// fill in the snippet's ${name} parameters; the text is returned as JSON for
+// the editor to insert at the cursor.
+func (a *App) snippetRender(w http.ResponseWriter, r *http.Request) {
+	sn, ok := a.Snippets.Get(chi.URLParam(r, "snippet"))
+	if !ok {
+		writeError(w, true, "snippet not found", http.StatusNotFound)
+		return
+	}
+	params := make(map[string]string)
+	for k, v := range r.URL.Query() {
+		params[k] = v[0]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "text": sn.Render(params)})
+}
+
 // chapterRaw handles GET "/chapter/{chapter}/raw".
+//
+// The file can be shown as-is (view=raw), re-indented (view=pretty), which is
+// useful for files that were written on a single line, or as just its text
+// fields with their line numbers (view=strings). view=edit opens it in an
+// editor that saves through chapterRawSave. wrap=1 soft-wraps long lines and
+// mono=0 uses a proportional font.
 func (a *App) chapterRaw(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
 	name := chi.URLParam(r, "chapter")
 
-	ch, _ := a.QB.chapterMap[name]
+	ch, _ := qb.chapterMap[name]
 	if ch == nil {
 		http.NotFound(w, r)
 		return
 	}
 
+	q := r.URL.Query()
+	view := q.Get("view")
+	if view != "pretty" && view != "strings" && view != "edit" {
+		view = "raw"
+	}
+	wrap := q.Get("wrap") == "1"
+	mono := q.Get("mono") != "0"
+
+	// link builds a url to this page with one parameter changed
+	link := func(key, value string) string {
+		v := r.URL.Query()
+		v.Set(key, value)
+		return a.Base + "/chapter/" + ch.Name + "/raw?" + v.Encode()
+	}
+	toggle := func(key string, on bool) string {
+		if on {
+			return link(key, "0")
+		}
+		return link(key, "1")
+	}
+
 	// Read raw file contents
-	path := filepath.Join(a.Root, "quests", "chapters", ch.Name+".snbt")
+	path := qb.chapterPath(ch.Name)
 	data := a.baseData(r, "Raw: "+ch.Title)
 	data["Chapter"] = ch
 	data["SelectedChapter"] = ch.Name
-	if b, err := os.ReadFile(path); err == nil {
-		data["Raw"] = string(b)
-	} else {
+	data["View"] = view
+	data["Wrap"] = wrap
+	data["Mono"] = mono
+	data["Links"] = map[string]string{
+		"raw":     link("view", "raw"),
+		"pretty":  link("view", "pretty"),
+		"strings": link("view", "strings"),
+		"edit":    link("view", "edit"),
+		"wrap":    toggle("wrap", wrap),
+		"mono":    toggle("mono", mono),
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
 		data["Raw"] = fmt.Sprintf("(error reading %s: %v)", path, err)
+		a.render(w, "chapter_raw.gohtml", data)
+		return
+	}
+	data["Raw"] = string(b)
+	switch view {
+	case "edit":
+		// files written on a single line are opened indented, unless they
+		// don't parse and have to be fixed as they are
+		if bytes.Count(b, []byte("\n")) > 1 {
+			break
+		}
+		if v, err := snbt.Decode(bytes.NewReader(b)); err == nil {
+			var buf bytes.Buffer
+			if snbt.EncodeIndent(&buf, v) == nil {
+				data["Raw"] = buf.String()
+			}
+		}
+	case "pretty":
+		v, err := snbt.Decode(bytes.NewReader(b))
+		if err != nil {
+			data["Raw"] = fmt.Sprintf("(error parsing %s: %v)", path, err)
+			break
+		}
+		var buf bytes.Buffer
+		if err := snbt.EncodeIndent(&buf, v); err != nil {
+			data["Raw"] = fmt.Sprintf("(error formatting %s: %v)", path, err)
+			break
+		}
+		data["Raw"] = buf.String()
+	case "strings":
+		data["Strings"] = scanRawStrings(string(b))
 	}
 	a.render(w, "chapter_raw.gohtml", data)
 }
 
+// questDependencies handles POST "/chapter/{chapter}/{quest}/dependencies".
+// It replaces the quest's dependencies with the ordered "dependency" fields
+// and sets "min_required", responding with the saved values as JSON.
+func (a *App) questDependencies(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	if err := r.ParseForm(); err != nil {
+		writeError(w, true, "invalid form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	cname := chi.URLParam(r, "chapter")
+	qid := chi.URLParam(r, "quest")
+
+	path := qb.chapterPath(cname)
+	chapter, err := NewChapterFromPath(path)
+	if err != nil {
+		writeError(w, true, "open chapter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	quest, ok := chapter.questMap[qid]
+	if !ok {
+		writeError(w, true, "quest not found", http.StatusNotFound)
+		return
+	}
+	if err := dependenciesFromForm(qb, quest, r.Form); err != nil {
+		writeError(w, true, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := chapter.Save(path); err != nil {
+		writeError(w, true, "saving chapter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.audit(r, "edit dependencies", cname, []string{qid}, "")
+	a.reload()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"ok":           true,
+		"dependencies": quest.Dependencies,
+		"min_required": quest.MinRequired,
+	})
+}
+
+// questDeps handles GET "/chapter/{chapter}/{quest}/deps", an HTML fragment
+// listing the quest's dependencies and the quests that depend on it. The
+// batch editor shows it when hovering a result's dependency count.
+func (a *App) questDeps(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	q, ok := qb.questMap[chi.URLParam(r, "quest")]
+	if !ok || q.Chapter.Name != chi.URLParam(r, "chapter") {
+		http.NotFound(w, r)
+		return
+	}
+	var deps []*Quest
+	for _, id := range q.Dependencies {
+		if d, ok := qb.questMap[id]; ok {
+			deps = append(deps, d)
+		}
+	}
+	a.render(w, "dep_preview.gohtml", map[string]any{
+		"Quest":        q,
+		"Dependencies": deps,
+		"Missing":      len(q.Dependencies) - len(deps),
+		"Dependents":   qb.dependents(q.ID),
+		"Lang":         a.lang(r),
+	})
+}
+
+// chapterText serves the chapter as plain text for text-to-speech review.
+// With ?download=1 it is sent as an attachment.
+func (a *App) chapterText(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	ch := qb.chapterMap[chi.URLParam(r, "chapter")]
+	if ch == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if r.URL.Query().Get("download") == "1" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ch.Name+".txt"))
+	}
+	io.WriteString(w, speechText(ch))
+}
+
+// chapterScript serves the chapter as a narration script, its quests in
+// dependency order with speaker markers. "speaker" names the narrator, and
+// ?download=1 sends it as an attachment.
+func (a *App) chapterScript(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	ch := qb.chapterMap[chi.URLParam(r, "chapter")]
+	if ch == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if r.URL.Query().Get("download") == "1" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ch.Name+"-script.txt"))
+	}
+	io.WriteString(w, narrationScript(ch, strings.TrimSpace(r.URL.Query().Get("speaker"))))
+}
+
+// chapterTOC handles POST "/chapter/{chapter}/toc", creating or regenerating
+// the chapter's table of contents quest. With scope=book the quest lists every
+// chapter in the book instead of the chapter's own quests.
+func (a *App) chapterTOC(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cname := chi.URLParam(r, "chapter")
+	if _, ok := qb.chapterMap[cname]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := qb.chapterPath(cname)
+	chapter, err := NewChapterFromPath(path)
+	if err != nil {
+		http.Error(w, "open chapter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	q := GenerateTOC(qb, chapter, r.FormValue("scope") == "book")
+	if err := chapter.Save(path); err != nil {
+		http.Error(w, "saving chapter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.audit(r, "generate table of contents", cname, []string{q.ID}, "")
+	a.reload()
+	http.Redirect(w, r, "/chapter/"+cname+"/"+q.ID, http.StatusSeeOther)
+}
+
 // questDetail handles GET "/chapter/{chapter}/{quest}".
 func (a *App) questDetail(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
 	cname := chi.URLParam(r, "chapter")
 	qid := chi.URLParam(r, "quest")
 
-	ch, _ := a.QB.chapterMap[cname]
-	q, _ := a.QB.questMap[qid]
+	ch, _ := qb.chapterMap[cname]
+	q, _ := qb.questMap[qid]
 	if ch == nil || q == nil {
 		http.NotFound(w, r)
 		return
@@ -1247,11 +2292,147 @@ func (a *App) questDetail(w http.ResponseWriter, r *http.Request) {
 	data["SelectedChapter"] = ch.Name
 	data["Chapter"] = ch
 	data["Quest"] = q
+	data["AllQuests"] = qb.Quests
+	data["DepSuggestions"] = suggestDependencies(qb, q, a.Items)
+	data["TaskTypes"] = TaskTypes
+	data["RewardTypes"] = RewardTypes
+	data["Cosmetic"] = cosmeticValues(q)
+	data["IDRefs"] = qb.questIDRefs(q.ID)
+	data["NewID"] = qb.idSource().Next()
+	data["IsStarred"] = slices.Contains(a.Prefs.Get(userID(r)).Starred, q.ID)
+	data["Snippets"] = a.Snippets.List()
+	data["CanShare"] = a.ShareAddr != ""
 	a.render(w, "quest.gohtml", data)
 }
 
+// activity handles GET "/activity", a heatmap of recent edits by chapter and
+// quest built from the audit log.
+func (a *App) activity(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	entries, err := a.Audit.Entries(a.Root())
+	if err != nil {
+		http.Error(w, "reading audit log: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := a.baseData(r, "Activity")
+	data["Activity"] = buildActivity(qb, entries, time.Now())
+	data["Name"] = a.Prefs.Get(userID(r)).Name
+	a.render(w, "activity.gohtml", data)
+}
+
+// prefsName handles POST "/prefs/name" and sets the name the current user's
+// edits are recorded under.
+func (a *App) prefsName(w http.ResponseWriter, r *http.Request) {
+	if err := a.Prefs.SetName(userID(r), strings.TrimSpace(r.FormValue("name"))); err != nil {
+		http.Error(w, "saving preferences: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	back := r.FormValue("next")
+	if !strings.HasPrefix(back, "/") || strings.HasPrefix(back, "//") {
+		back = "/activity"
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// questStar handles POST "/chapter/{chapter}/{quest}/star", which stars the
+// quest for the current user, or unstars it if "star" is "0".
+func (a *App) questStar(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	isAjax := r.Header.Get("X-Requested-With") == "XMLHttpRequest" || strings.Contains(r.Header.Get("Accept"), "application/json")
+	cname, qid := chi.URLParam(r, "chapter"), chi.URLParam(r, "quest")
+	if _, ok := qb.questMap[qid]; !ok {
+		writeError(w, isAjax, "quest not found", http.StatusNotFound)
+		return
+	}
+	star := r.FormValue("star") != "0"
+	if err := a.Prefs.Star(userID(r), qid, star); err != nil {
+		writeError(w, isAjax, "saving preferences: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if isAjax {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "starred": star})
+		return
+	}
+	http.Redirect(w, r, "/chapter/"+cname+"/"+qid, http.StatusSeeOther)
+}
+
+// questShare handles POST "/chapter/{chapter}/{quest}/share" and creates a
+// read-only link to the quest that expires after "ttl" (default 7d).
+func (a *App) questShare(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	isAjax := r.Header.Get("X-Requested-With") == "XMLHttpRequest" || strings.Contains(r.Header.Get("Accept"), "application/json")
+	qid := chi.URLParam(r, "quest")
+	if _, ok := qb.questMap[qid]; !ok {
+		writeError(w, isAjax, "quest not found", http.StatusNotFound)
+		return
+	}
+	if a.ShareAddr == "" {
+		writeError(w, isAjax, "start qbedit with --share-addr to share quests", http.StatusBadRequest)
+		return
+	}
+	ttl := r.FormValue("ttl")
+	if ttl == "" {
+		ttl = "7d"
+	}
+	d, err := parseTTL(ttl)
+	if err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exp := time.Now().Add(d)
+	link := a.shareLink(r, a.shareURL(qid, exp))
+	if isAjax {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "url": link, "expires": exp.UTC().Format(time.RFC3339)})
+		return
+	}
+	http.Redirect(w, r, link, http.StatusSeeOther)
+}
+
+// sharedQuest handles GET "/share/{quest}" on ShareRouter, the read-only
+// view of a quest reached through a signed share link.
+func (a *App) sharedQuest(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	qid := chi.URLParam(r, "quest")
+	lang := a.lang(r)
+	data := map[string]any{"Lang": lang, "Title": "qbedit"}
+
+	err := a.verifyShare(qid, r.URL.Query().Get("exp"), r.URL.Query().Get("sig"), time.Now())
+	q, ok := qb.questMap[qid]
+	if err == nil && !ok {
+		err = errors.New("this quest no longer exists")
+	}
+	if err != nil {
+		data["Error"] = err.Error()
+		a.renderStatus(w, http.StatusForbidden, "share.gohtml", data)
+		return
+	}
+
+	var tasks, rewards []string
+	for _, t := range q.Tasks {
+		tasks = append(tasks, taskSummary(t))
+	}
+	for _, rw := range q.Rewards {
+		rewards = append(rewards, rewardSummary(rw))
+	}
+	exp, _ := strconv.ParseInt(r.URL.Query().Get("exp"), 10, 64)
+	data["Title"] = stripCodes(q.GetTitle())
+	data["Quest"] = q
+	data["Description"] = splitMultistring(q.Description)
+	data["Tasks"] = tasks
+	data["Rewards"] = rewards
+	data["Expires"] = time.Unix(exp, 0).UTC().Format("2006-01-02 15:04 MST")
+	if q.Chapter != nil {
+		data["ChapterTitle"] = q.Chapter.Title
+	}
+	// the link is meant for one reader; keep it out of caches and indexes
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	a.render(w, "share.gohtml", data)
+}
+
 // questSave handles POST "/chapter/{chapter}/{quest}/save" to persist edits.
 func (a *App) questSave(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
 	isAjax := r.Header.Get("X-Requested-With") == "XMLHttpRequest" || strings.Contains(r.Header.Get("Accept"), "application/json")
 
 	if err := r.ParseMultipartForm(2 << 20); err != nil {
@@ -1268,22 +2449,20 @@ func (a *App) questSave(w http.ResponseWriter, r *http.Request) {
 
 	cname := chi.URLParam(r, "chapter")
 	qid := chi.URLParam(r, "quest")
-	title := strings.TrimSpace(r.Form.Get("title"))
-	subtitle := strings.TrimSpace(r.Form.Get("subtitle"))
-	desc := r.Form.Get("description")
 
 	slog.Debug("saving quest", "chapter", cname, "quest", qid,
-		"title", title, "subtitle", subtitle, "desc", desc)
+		"title", r.Form.Get("title"), "subtitle", r.Form.Get("subtitle"), "desc", r.Form.Get("description"))
 
 	// it makes sense to re-read the chapter from disk before saving as
 	// edits to other quests from elsewhere could be lost if we don't
-	path := filepath.Join(a.Root, "quests", "chapters", cname+".snbt")
+	path := qb.chapterPath(cname)
 
 	chapter, err := NewChapterFromPath(path)
 	if err != nil {
 		writeError(w, isAjax, "open chapter: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	chapter.resolveLang(qb.Lang)
 
 	quest, ok := chapter.questMap[qid]
 	if !ok {
@@ -1291,14 +2470,49 @@ func (a *App) questSave(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	quest.Title = title
-	quest.Subtitle = subtitle
-	quest.Description = desc
+	// the quest changed on disk since the form was rendered; unless our edit
+	// happens to agree with the other one, let the editor merge them
+	if h := r.Form.Get("hash"); h != "" && h != questHash(quest) {
+		conflicts, err := questConflicts(qb, path, qid, r.Form)
+		if err != nil {
+			writeError(w, isAjax, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(conflicts) > 0 {
+			if isAjax {
+				writeJSON(w, http.StatusConflict, map[string]any{"ok": false, "conflict": true, "error": "quest changed on disk since it was loaded"})
+				return
+			}
+			data := a.baseData(r, "Merge changes")
+			data["SelectedChapter"] = cname
+			data["Chapter"] = qb.chapterMap[cname]
+			data["Quest"] = quest
+			data["Hash"] = questHash(quest)
+			data["Conflicts"] = conflicts
+			data["Hidden"] = mergeHidden(r.Form, conflicts)
+			a.renderStatus(w, http.StatusConflict, "quest_merge.gohtml", data)
+			return
+		}
+	}
 
-	if err := chapter.Save(path); err != nil {
+	before := *quest
+	if err := applyQuestForm(qb, quest, r.Form); err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.Pack.Get().onSave(quest)
+	var fields []string
+	for _, f := range searchFields {
+		if questField(&before, f) != questField(quest, f) {
+			fields = append(fields, f)
+		}
+	}
+
+	if err := saveChapter(chapter, path, qb.Lang); err != nil {
 		writeError(w, isAjax, "saving chapter: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	a.audit(r, "edit quest", cname, []string{qid}, strings.Join(fields, ", "))
 
 	// Refresh in-memory data
 	a.reload()
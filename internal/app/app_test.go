@@ -0,0 +1,296 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testApp returns an App for a temporary copy of the test chapter.
+func testApp(t *testing.T) *App {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", "..", "snbt", "test_chapter.snbt"))
+	if err != nil {
+		t.Skip("test_chapter.snbt not present; skipping")
+	}
+	root := t.TempDir()
+	chapters := filepath.Join(root, "quests", "chapters")
+	if err := os.MkdirAll(chapters, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "quests", "chapter_groups.snbt"), []byte("{ chapter_groups: [ ] }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(chapters, "test.snbt"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(root, "1.20.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.QB() == nil || len(a.QB().Chapters) != 1 {
+		t.Fatal("test book did not load")
+	}
+	return a
+}
+
+// TestConcurrentSaves saves different quests of one chapter in parallel while
+// pages are being read; run with -race to check QuestBook access.
+func TestConcurrentSaves(t *testing.T) {
+	a := testApp(t)
+	h := a.Router()
+	quests := a.QB().Chapters[0].Quests
+	if len(quests) > 8 {
+		quests = quests[:8]
+	}
+
+	var wg sync.WaitGroup
+	for i, q := range quests {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			form := url.Values{"title": {"Edited " + q.ID}, "description": {q.Description}}
+			req := httptest.NewRequest("POST", "/chapter/test/"+q.ID+"/save", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("save %d: %d %s", i, rec.Code, rec.Body)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/chapter/test", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("read %d: %d %s", i, rec.Code, rec.Body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, q := range quests {
+		if got := a.QB().questMap[q.ID].Title; got != "Edited "+q.ID {
+			t.Errorf("quest %s title = %q; a concurrent save was lost", q.ID, got)
+		}
+	}
+}
+func TestReloadFlight(t *testing.T) {
+	var f reloadFlight
+	started := make(chan int, 10)
+	release := make(chan bool)
+	n := 0
+	load := func() {
+		n++
+		started <- n
+		<-release
+	}
+
+	// the first reload runs; the rest arrive during it and share the next
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); f.do(load) }()
+	if got := <-started; got != 1 {
+		t.Fatalf("first load %d", got)
+	}
+	for range 5 {
+		wg.Add(1)
+		go func() { defer wg.Done(); f.do(load) }()
+	}
+	for {
+		if requests, _ := f.counts(); requests == 6 {
+			break
+		}
+		runtime.Gosched()
+	}
+	release <- true
+	if got := <-started; got != 2 {
+		t.Fatalf("second load %d", got)
+	}
+	release <- true
+	wg.Wait()
+	if requests, loads := f.counts(); requests != 6 || loads != 2 {
+		t.Errorf("%d requests took %d loads", requests, loads)
+	}
+
+	// every reload returns with a book loaded after it was called
+	a := testApp(t)
+	chapters := filepath.Join(a.Root(), "quests", "chapters")
+	b, err := os.ReadFile(filepath.Join(chapters, "test.snbt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("copy%d", i)
+			if err := os.WriteFile(filepath.Join(chapters, name+".snbt"), b, 0644); err != nil {
+				t.Error(err)
+				return
+			}
+			a.reload()
+			if a.QB().chapterMap[name] == nil {
+				t.Errorf("%s not loaded after its reload", name)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestQuestDeps(t *testing.T) {
+	a := testApp(t)
+	qb := a.QB()
+	var q *Quest
+	for _, qq := range qb.Quests {
+		if len(qq.Dependencies) > 0 {
+			q = qq
+			break
+		}
+	}
+	if q == nil {
+		t.Skip("no quest with dependencies")
+	}
+	dep := qb.questMap[q.Dependencies[0]]
+	if !slices.Contains(qb.dependents(dep.ID), q) {
+		t.Errorf("%s is not a dependent of %s", q.ID, dep.ID)
+	}
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/chapter/test/"+q.ID+"/deps", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/chapter/test/"+dep.ID) {
+		t.Errorf("deps fragment: %d %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "<html") {
+		t.Error("deps fragment includes the page layout")
+	}
+}
+
+func TestChapterMinimap(t *testing.T) {
+	a := testApp(t)
+	q := a.QB().Chapters[0].Quests[0]
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/chapter/test/map.svg?q="+q.ID, nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(body, "<svg") {
+		t.Fatalf("map.svg: %d %.80s", rec.Code, body)
+	}
+	if strings.Count(body, "<circle") != len(a.QB().Chapters[0].Quests) || strings.Count(body, "mm-current") != 1 {
+		t.Errorf("map has wrong quests: %s", body)
+	}
+	if !strings.Contains(body, `href="/chapter/test/`+q.ID+`"`) {
+		t.Error("quests don't link to their editor")
+	}
+}
+
+// TestColorsStableOrder checks that the Color Manager lists colors in the
+// same order on every request, including colors used equally often.
+func TestColorsStableOrder(t *testing.T) {
+	a := testApp(t)
+	get := func() string {
+		req := httptest.NewRequest("GET", "/colors/?q=a&ci=on", nil)
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /colors/: %d", rec.Code)
+		}
+		return rec.Body.String()
+	}
+	first := get()
+	if !strings.Contains(first, "color-line") {
+		t.Fatal("no color results")
+	}
+	for range 20 {
+		if get() != first {
+			t.Fatal("color results differ between requests")
+		}
+	}
+}
+
+func TestQuestRedirect(t *testing.T) {
+	a := testApp(t)
+	q := a.QB().Quests[0]
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/q/"+q.ID, nil))
+	if want := "/chapter/test#q-" + q.ID; rec.Code != http.StatusFound || rec.Header().Get("Location") != want {
+		t.Errorf("GET /q/%s: %d %s, want %s", q.ID, rec.Code, rec.Header().Get("Location"), want)
+	}
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/chapter/test", nil))
+	if !strings.Contains(rec.Body.String(), `<li id="q-`+q.ID+`">`) {
+		t.Error("chapter page has no anchor for the quest")
+	}
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/q/NOPE", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /q/NOPE: %d", rec.Code)
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	a := testApp(t)
+	a.Base = "/b/one"
+	if _, err := a.tpl.New("broken.gohtml").Parse(`<p>started</p>{{ .Quest.Title }}`); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	a.render(rec, "broken.gohtml", map[string]any{"Quest": 7})
+	body := rec.Body.String()
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("code = %d", rec.Code)
+	}
+	if strings.Contains(body, "started") {
+		t.Error("half of the page was sent")
+	}
+	if !strings.Contains(body, `href="/b/one/errors"`) || !strings.Contains(body, "can&#39;t evaluate field Title") {
+		t.Errorf("error page:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	a.renderStatus(rec, http.StatusForbidden, "broken.gohtml", map[string]any{"Quest": map[string]string{"Title": "fine"}})
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "<p>started</p>fine") {
+		t.Errorf("render = %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestTemplateMessages checks that every message the templates look up is in
+// the English catalog, which other languages fall back to.
+func TestTemplateMessages(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("i18n", "locales", "en.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var en map[string]string
+	if err := json.Unmarshal(b, &en); err != nil {
+		t.Fatal(err)
+	}
+	uses := regexp.MustCompile(`\{\{ th? \$?\.Lang "([^"]+)"`)
+	paths, _ := filepath.Glob(filepath.Join("templates", "*.gohtml"))
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range uses.FindAllStringSubmatch(string(b), -1) {
+			if _, ok := en[m[1]]; !ok {
+				t.Errorf("%s: no message %q", filepath.Base(path), m[1])
+			}
+		}
+	}
+	// field labels are looked up by name
+	for _, f := range paletteFields {
+		if _, ok := en["fields."+f]; !ok {
+			t.Errorf("no message %q", "fields."+f)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AuditEntry is one recorded edit.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// User is the editing browser's id (see prefs.go); Name is the display
+	// name its user chose, if any.
+	User    string   `json:"user"`
+	Name    string   `json:"name,omitempty"`
+	Book    string   `json:"book"`
+	Action  string   `json:"action"`
+	Chapter string   `json:"chapter,omitempty"`
+	Quests  []string `json:"quests,omitempty"`
+	Detail  string   `json:"detail,omitempty"`
+}
+
+// Who returns a name for the entry's editor.
+func (e AuditEntry) Who() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	if len(e.User) > 6 {
+		return "anon-" + e.User[:6]
+	}
+	return "anon"
+}
+
+// AuditLog is an append-only log of edits: when, by whom, to which chapter
+// and quests. It is a file of JSON lines shared by all of the books a user
+// edits, so each entry names its book. With no path it is kept in memory.
+type AuditLog struct {
+	path    string
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// DefaultAuditPath returns the audit log in the user's config dir.
+func DefaultAuditPath() string {
+	return filepath.Join(filepath.Dir(DefaultPrefsPath()), "audit.jsonl")
+}
+
+// OpenAuditLog returns the audit log at path, which is created on first use.
+func OpenAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+// Append records e.
+func (l *AuditLog) Append(e AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == "" {
+		l.entries = append(l.entries, e)
+		return nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries returns the entries recorded for book, oldest first. Lines that
+// can't be decoded are skipped.
+func (l *AuditLog) Entries(book string) ([]AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var all []AuditEntry
+	if l.path == "" {
+		all = l.entries
+	} else {
+		f, err := os.Open(l.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 1<<20)
+		for sc.Scan() {
+			var e AuditEntry
+			if json.Unmarshal(sc.Bytes(), &e) == nil {
+				all = append(all, e)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+	var res []AuditEntry
+	for _, e := range all {
+		if e.Book == book {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+// audit records an edit made by r's user, and commits it when git is
+// enabled. Failures are logged rather than returned: the edit itself has
+// already been written.
+func (a *App) audit(r *http.Request, action, chapter string, quests []string, detail string) {
+	// edits in a sandbox are recorded when it is applied
+	if a.Audit == nil || a.sandbox.Load() != nil {
+		return
+	}
+	user := userID(r)
+	e := AuditEntry{
+		Time:    time.Now().UTC(),
+		User:    user,
+		Name:    a.Prefs.Get(user).Name,
+		Book:    a.Root(),
+		Action:  action,
+		Chapter: chapter,
+		Quests:  quests,
+		Detail:  detail,
+	}
+	if err := a.Audit.Append(e); err != nil {
+		slog.Error("writing audit log", "error", err)
+	}
+	if err := a.gitCommit(e); err != nil {
+		slog.Error("committing edit", "error", err)
+	}
+}
+
+// auditEdits records the chapters a bulk edit changed, as editChapters
+// returns them, in chapter order, and returns how many quests it changed.
+func (a *App) auditEdits(r *http.Request, action, detail string, edited map[string][]string) int {
+	n := 0
+	for _, name := range slices.Sorted(maps.Keys(edited)) {
+		a.audit(r, action, name, edited[name], detail)
+		n += len(edited[name])
+	}
+	return n
+}
+
+// activityDays is how many days the activity heatmap covers.
+const activityDays = 28
+
+// ActivityRow is a chapter's (or quest's) recent edit activity.
+type ActivityRow struct {
+	Chapter *Chapter
+	Quest   *Quest
+	// Days counts edits per day, oldest first, ending today.
+	Days  []int
+	Total int
+	// Last is the most recent edit ever recorded, if any.
+	Last *AuditEntry
+	// Editors are the names of everyone who edited in the window, most
+	// active first.
+	Editors []string
+}
+
+// Stale returns true if the row has had no edits within the window.
+func (r ActivityRow) Stale() bool { return r.Total == 0 }
+
+// heatLevel maps a day's edit count to a heatmap shade from 0 to 4.
+func heatLevel(n int) int {
+	switch {
+	case n == 0:
+		return 0
+	case n < 3:
+		return 1
+	case n < 6:
+		return 2
+	case n < 12:
+		return 3
+	}
+	return 4
+}
+
+// Activity summarizes the audit log for a quest book.
+type Activity struct {
+	Start    time.Time
+	Chapters []*ActivityRow // busiest first, then stale chapters by age
+	Quests   []*ActivityRow // most recently edited quests
+	Recent   []AuditEntry   // newest first
+}
+
+// calendarDays returns how many calendar days to's date is after from's,
+// each in its own location. Dates are compared in UTC, where every day is 24
+// hours long, so a daylight saving change between them doesn't shift it.
+func calendarDays(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// buildActivity buckets entries by the calendar day, in now's location, over
+// the activityDays up to now.
+func buildActivity(qb *QuestBook, entries []AuditEntry, now time.Time) *Activity {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := today.AddDate(0, 0, -(activityDays - 1))
+	act := &Activity{Start: start}
+
+	chapters := make(map[string]*ActivityRow)
+	for _, ch := range qb.Chapters {
+		row := &ActivityRow{Chapter: ch, Days: make([]int, activityDays)}
+		chapters[ch.Name] = row
+		act.Chapters = append(act.Chapters, row)
+	}
+	quests := make(map[string]*ActivityRow)
+	editors := make(map[*ActivityRow]map[string]int)
+
+	add := func(row *ActivityRow, e *AuditEntry, day int) {
+		row.Last = e
+		if day < 0 || day >= activityDays {
+			return
+		}
+		row.Days[day]++
+		row.Total++
+		if editors[row] == nil {
+			editors[row] = make(map[string]int)
+		}
+		editors[row][e.Who()]++
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		day := calendarDays(start, e.Time.In(now.Location()))
+		if row, ok := chapters[e.Chapter]; ok {
+			add(row, e, day)
+		}
+		for _, id := range e.Quests {
+			q, ok := qb.questMap[id]
+			if !ok {
+				continue
+			}
+			row, ok := quests[id]
+			if !ok {
+				row = &ActivityRow{Chapter: q.Chapter, Quest: q, Days: make([]int, activityDays)}
+				quests[id] = row
+				act.Quests = append(act.Quests, row)
+			}
+			add(row, e, day)
+		}
+	}
+
+	for row, counts := range editors {
+		for name := range counts {
+			row.Editors = append(row.Editors, name)
+		}
+		sort.Slice(row.Editors, func(i, j int) bool {
+			ci, cj := counts[row.Editors[i]], counts[row.Editors[j]]
+			if ci != cj {
+				return ci > cj
+			}
+			return row.Editors[i] < row.Editors[j]
+		})
+	}
+
+	lastTime := func(r *ActivityRow) time.Time {
+		if r.Last == nil {
+			return time.Time{}
+		}
+		return r.Last.Time
+	}
+	sort.SliceStable(act.Chapters, func(i, j int) bool {
+		a, b := act.Chapters[i], act.Chapters[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		// among equally busy (or stale) chapters, the longest untouched last
+		return lastTime(a).After(lastTime(b))
+	})
+	sort.SliceStable(act.Quests, func(i, j int) bool {
+		return lastTime(act.Quests[i]).After(lastTime(act.Quests[j]))
+	})
+	if len(act.Quests) > 50 {
+		act.Quests = act.Quests[:50]
+	}
+
+	for i := len(entries) - 1; i >= 0 && len(act.Recent) < 50; i-- {
+		act.Recent = append(act.Recent, entries[i])
+	}
+	return act
+}
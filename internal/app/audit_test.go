@@ -0,0 +1,92 @@
+package app
+
+import (
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata" // for America/New_York on hosts without zoneinfo
+)
+
+func TestBuildActivity(t *testing.T) {
+	busy := &Chapter{Name: "busy"}
+	quiet := &Chapter{Name: "quiet"}
+	old := &Chapter{Name: "old"}
+	q := &Quest{ID: "Q1", Chapter: busy}
+	busy.Quests = []*Quest{q}
+	qb := &QuestBook{
+		Chapters: []*Chapter{old, quiet, busy},
+		questMap: map[string]*Quest{"Q1": q},
+	}
+
+	now := time.Date(2025, 3, 30, 12, 0, 0, 0, time.UTC)
+	entries := []AuditEntry{
+		{Time: now.AddDate(0, 0, -100), User: "u1", Action: "edit quest", Chapter: "old"},
+		{Time: now.AddDate(0, 0, -40), User: "u1", Action: "edit quest", Chapter: "quiet"},
+		{Time: now.AddDate(0, 0, -1), User: "u1", Name: "Ann", Action: "edit quest", Chapter: "busy", Quests: []string{"Q1"}},
+		{Time: now, User: "u2", Action: "edit quest", Chapter: "busy", Quests: []string{"Q1"}},
+		{Time: now, User: "u1", Name: "Ann", Action: "recolor", Chapter: "busy", Quests: []string{"Q1", "GONE"}},
+	}
+	act := buildActivity(qb, entries, now)
+
+	var order []string
+	for _, row := range act.Chapters {
+		order = append(order, row.Chapter.Name)
+	}
+	if want := []string{"busy", "quiet", "old"}; !slices.Equal(order, want) {
+		t.Errorf("chapter order = %v, want %v", order, want)
+	}
+	b := act.Chapters[0]
+	if b.Total != 3 || b.Days[activityDays-1] != 2 || b.Days[activityDays-2] != 1 {
+		t.Errorf("busy days = %v (total %d)", b.Days, b.Total)
+	}
+	if want := []string{"Ann", "anon"}; !slices.Equal(b.Editors, want) {
+		t.Errorf("editors = %v, want %v", b.Editors, want)
+	}
+	if !act.Chapters[1].Stale() || act.Chapters[1].Last == nil {
+		t.Errorf("quiet chapter should be stale with a last edit")
+	}
+	if len(act.Quests) != 1 || act.Quests[0].Total != 3 {
+		t.Errorf("quest rows = %+v", act.Quests)
+	}
+	if len(act.Recent) != 5 || act.Recent[0].Action != "recolor" {
+		t.Errorf("recent = %+v", act.Recent)
+	}
+}
+
+func TestBuildActivityDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	ch := &Chapter{Name: "ch"}
+	qb := &QuestBook{Chapters: []*Chapter{ch}}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		edit time.Time
+		day  int // -1 if outside the window
+	}{
+		// clocks went forward on March 8th, so the window is an hour short
+		{"spring today", time.Date(2026, 3, 20, 9, 0, 0, 0, ny), time.Date(2026, 3, 20, 0, 30, 0, 0, ny), activityDays - 1},
+		{"spring first day", time.Date(2026, 3, 20, 9, 0, 0, 0, ny), time.Date(2026, 2, 21, 0, 30, 0, 0, ny), 0},
+		{"spring before", time.Date(2026, 3, 20, 9, 0, 0, 0, ny), time.Date(2026, 2, 20, 23, 30, 0, 0, ny), -1},
+		// clocks went back on November 1st, so the window is an hour long
+		{"fall today", time.Date(2026, 11, 10, 9, 0, 0, 0, ny), time.Date(2026, 11, 10, 0, 30, 0, 0, ny), activityDays - 1},
+		{"fall yesterday", time.Date(2026, 11, 10, 9, 0, 0, 0, ny), time.Date(2026, 11, 9, 23, 30, 0, 0, ny), activityDays - 2},
+		{"fall before", time.Date(2026, 11, 10, 9, 0, 0, 0, ny), time.Date(2026, 10, 13, 23, 30, 0, 0, ny), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := []AuditEntry{{Time: tt.edit.UTC(), User: "u1", Action: "edit quest", Chapter: "ch"}}
+			days := buildActivity(qb, entries, tt.now).Chapters[0].Days
+			want := make([]int, activityDays)
+			if tt.day >= 0 {
+				want[tt.day] = 1
+			}
+			if !slices.Equal(days, want) {
+				t.Errorf("days = %v, want %v", days, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// backupLayout names snapshot directories; they sort by time.
+const backupLayout = "20060102-150405"
+
+// maxBackupRuns is how many runs the status page lists.
+const maxBackupRuns = 20
+
+// BackupRun is the result of one scheduled backup.
+type BackupRun struct {
+	Time time.Time
+	// Dir is the snapshot's directory, or "" if the book hadn't changed
+	// since the last snapshot and none was made.
+	Dir   string
+	Files int
+	Bytes int64
+	// Broken are the files of the snapshot that don't decode, by their path
+	// in it, with their errors.
+	Broken []string
+	// Issues is how many structural issues the book had.
+	Issues int
+	Took   time.Duration
+	Err    string
+}
+
+// Name is the name of the run's snapshot directory.
+func (r BackupRun) Name() string { return filepath.Base(r.Dir) }
+
+// Backups snapshots the book into Dir.
+type Backups struct {
+	Dir string
+	// Keep is how many snapshots are kept; older ones are removed. 0 keeps
+	// them all.
+	Keep     int
+	Interval time.Duration
+
+	mu   sync.Mutex
+	runs []BackupRun
+	// sum identifies the contents of the last snapshot.
+	sum [sha256.Size]byte
+}
+
+// DefaultBackupDir is the backup area of the book at root.
+func DefaultBackupDir(root string) string {
+	return filepath.Join(packDir(root), "backups")
+}
+
+// Runs returns the recent runs, newest first.
+func (b *Backups) Runs() []BackupRun {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.runs)
+}
+
+// record adds run to the recent runs.
+func (b *Backups) record(run BackupRun) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.runs = append([]BackupRun{run}, b.runs...)
+	if len(b.runs) > maxBackupRuns {
+		b.runs = b.runs[:maxBackupRuns]
+	}
+}
+
+// RunBackups backs up the book now and then every a.Backups.Interval until
+// ctx is done.
+//
+// Hosted instances run for weeks with several people editing, and a bad bulk
+// edit or a crashed game can go unnoticed for days. Each run copies the
+// quests directory and the lang file into a timestamped directory of the
+// backup area, checks that every file of the copy decodes and counts the
+// book's structural issues (see validate.go). A run is skipped when nothing
+// changed since the last snapshot, and only the newest snapshots are kept.
+func (a *App) RunBackups(ctx context.Context) {
+	t := time.NewTicker(a.Backups.Interval)
+	defer t.Stop()
+	for {
+		run := a.backup(time.Now())
+		switch {
+		case run.Err != "":
+			slog.Error("backup", "error", run.Err)
+		case len(run.Broken) > 0:
+			slog.Error("backup: files don't decode", "dir", run.Dir, "files", run.Broken)
+		case run.Dir != "":
+			slog.Info("backup", "dir", run.Dir, "files", run.Files, "issues", run.Issues)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// backup snapshots the real book, not a sandbox's copy, and records the run.
+func (a *App) backup(now time.Time) BackupRun {
+	b := a.Backups
+	run := BackupRun{Time: now}
+	defer func() {
+		run.Took = time.Since(now)
+		b.record(run)
+	}()
+
+	// edits don't write while the files are read, so the snapshot is of
+	// one version of the book
+	a.writeMu.Lock()
+	root, langPath := a.Root(), a.LangPath()
+	if s := a.sandbox.Load(); s != nil {
+		root, langPath = s.root, s.langPath
+	}
+	files, err := readBookFiles(root, langPath)
+	a.writeMu.Unlock()
+	if err != nil {
+		run.Err = err.Error()
+		return run
+	}
+	run.Issues = len(validateBook(a.QB()))
+
+	h := sha256.New()
+	for _, f := range files {
+		fmt.Fprintf(h, "%s\x00%d\x00", f.rel, len(f.b))
+		h.Write(f.b)
+		run.Files++
+		run.Bytes += int64(len(f.b))
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	b.mu.Lock()
+	unchanged := sum == b.sum
+	b.mu.Unlock()
+	if unchanged {
+		return run
+	}
+
+	dir := filepath.Join(b.Dir, now.Format(backupLayout))
+	for _, f := range files {
+		dst := filepath.Join(dir, f.rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			run.Err = err.Error()
+			return run
+		}
+		if err := writeFile(dst, f.b, 0644); err != nil {
+			run.Err = err.Error()
+			return run
+		}
+		if strings.HasSuffix(f.rel, ".snbt") {
+			if _, err := snbt.Decode(bytes.NewReader(f.b)); err != nil {
+				run.Broken = append(run.Broken, fmt.Sprintf("%s: %v", f.rel, err))
+			}
+		}
+	}
+	run.Dir = dir
+	b.mu.Lock()
+	b.sum = sum
+	b.mu.Unlock()
+	if b.Keep <= 0 {
+		return run
+	}
+	if err := pruneBackups(b.Dir, b.Keep); err != nil {
+		run.Err = fmt.Sprintf("removing old snapshots: %v", err)
+	}
+	return run
+}
+
+// bookFile is a file of the book and its path in a snapshot.
+type bookFile struct {
+	rel string
+	b   []byte
+}
+
+// readBookFiles reads the quests directory of the book at root and the lang
+// file, which is kept in a snapshot's top directory.
+func readBookFiles(root, langPath string) ([]bookFile, error) {
+	var files []bookFile
+	err := filepath.WalkDir(filepath.Join(root, "quests"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, tmpSuffix) {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		files = append(files, bookFile{rel: rel, b: b})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if langPath != "" {
+		b, err := os.ReadFile(langPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			files = append(files, bookFile{rel: filepath.Base(langPath), b: b})
+		}
+	}
+	return files, nil
+}
+
+// pruneBackups removes all but the newest keep snapshots in dir. Other
+// files in dir are left alone.
+func pruneBackups(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var snaps []string
+	for _, e := range entries {
+		if _, err := time.Parse(backupLayout, e.Name()); err == nil && e.IsDir() {
+			snaps = append(snaps, e.Name())
+		}
+	}
+	slices.Sort(snaps)
+	var errs []error
+	for len(snaps) > keep {
+		errs = append(errs, os.RemoveAll(filepath.Join(dir, snaps[0])))
+		snaps = snaps[1:]
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackup(t *testing.T) {
+	a := testApp(t)
+	a.Backups = &Backups{Dir: DefaultBackupDir(a.Root()), Keep: 2, Interval: time.Hour}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+
+	run := a.backup(now)
+	if run.Err != "" || run.Dir == "" || len(run.Broken) != 0 {
+		t.Fatalf("first run = %+v", run)
+	}
+	if _, err := os.Stat(filepath.Join(run.Dir, "quests", "chapters", "test.snbt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing changed, so no snapshot is made
+	if run := a.backup(now.Add(time.Hour)); run.Dir != "" || run.Err != "" {
+		t.Errorf("unchanged run = %+v", run)
+	}
+
+	// a broken file is still copied, and reported
+	chapter := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	if err := os.WriteFile(chapter, []byte("{ title: "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run = a.backup(now.Add(2 * time.Hour))
+	if run.Dir == "" || len(run.Broken) != 1 || !strings.Contains(run.Broken[0], "test.snbt") {
+		t.Errorf("broken run = %+v", run)
+	}
+
+	// only the newest two snapshots are kept
+	if err := os.WriteFile(chapter, []byte("{ }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.backup(now.Add(3 * time.Hour))
+	snaps, _ := os.ReadDir(a.Backups.Dir)
+	if len(snaps) != 2 || snaps[0].Name() != now.Add(2*time.Hour).Format(backupLayout) {
+		t.Errorf("snapshots = %v", snaps)
+	}
+	if runs := a.Backups.Runs(); len(runs) != 4 || !runs[0].Time.Equal(now.Add(3*time.Hour)) {
+		t.Errorf("runs = %+v", runs)
+	}
+
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "Backups") || !strings.Contains(body, "quests/chapters/test.snbt: snbt: syntax error") {
+		t.Errorf("status page: %d %s", rec.Code, body)
+	}
+}
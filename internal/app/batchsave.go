@@ -0,0 +1,261 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// questEdit is one quest's edited fields from a batch save.
+type questEdit struct {
+	Chapter string
+	ID      string
+	// Form holds the quest's fields without the id prefix, as the quest
+	// editor would post them.
+	Form url.Values
+}
+
+// batchSaveFields are the quest fields the batch editor edits.
+var batchSaveFields = []string{"hash", "title", "subtitle", "description"}
+
+// questEditsFromForm reads the edits in a batch save form.
+func questEditsFromForm(form url.Values) ([]questEdit, error) {
+	var edits []questEdit
+	for _, ref := range form["quest"] {
+		cname, qid, ok := strings.Cut(ref, "/")
+		if !ok || cname == "" || qid == "" {
+			return nil, fmt.Errorf("invalid quest %q", ref)
+		}
+		e := questEdit{Chapter: cname, ID: qid, Form: url.Values{}}
+		for _, f := range batchSaveFields {
+			if v, ok := form[qid+"."+f]; ok {
+				e.Form[f] = v
+			}
+		}
+		edits = append(edits, e)
+	}
+	return edits, nil
+}
+
+// batchSaveResult reports what a batch save did with each quest.
+type batchSaveResult struct {
+	Saved []string `json:"saved"`
+	// Conflicts are quests that changed on disk since they were loaded and
+	// whose edits disagree with that change; they are not saved.
+	Conflicts []string `json:"conflicts"`
+	// Hashes are the new hashes of the saved quests, for further edits.
+	Hashes map[string]string `json:"hashes"`
+}
+
+// saveQuestEdits applies edits to the chapters of qb, the book they were made
+// against, writing the chapters together so that an error leaves all of them
+// unchanged. cfg's on-save rules are applied to each edited quest.
+func saveQuestEdits(qb *QuestBook, cfg PackConfig, edits []questEdit) (*batchSaveResult, error) {
+	byChapter := make(map[string][]questEdit)
+	for _, e := range edits {
+		if qb.chapterMap[e.Chapter] == nil {
+			return nil, fmt.Errorf("unknown chapter %q", e.Chapter)
+		}
+		byChapter[e.Chapter] = append(byChapter[e.Chapter], e)
+	}
+	names := make([]string, 0, len(byChapter))
+	for name := range byChapter {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := &batchSaveResult{Saved: []string{}, Conflicts: []string{}, Hashes: make(map[string]string)}
+	t := newBookWrite(qb.Lang)
+	var saved []*Quest
+	for _, name := range names {
+		path := qb.chapterPath(name)
+		chapter, err := NewChapterFromPath(path)
+		if err != nil {
+			return res, fmt.Errorf("open chapter %s: %w", name, err)
+		}
+		chapter.resolveLang(qb.Lang)
+		n := len(saved)
+		for _, e := range byChapter[name] {
+			quest, ok := chapter.questMap[e.ID]
+			if !ok {
+				return res, fmt.Errorf("quest %s not found in %s", e.ID, name)
+			}
+			if h := e.Form.Get("hash"); h != "" && h != questHash(quest) {
+				conflicts, err := questConflicts(qb, path, e.ID, e.Form)
+				if err != nil {
+					return res, err
+				}
+				if len(conflicts) > 0 {
+					res.Conflicts = append(res.Conflicts, e.ID)
+					continue
+				}
+			}
+			if err := applyQuestForm(qb, quest, e.Form); err != nil {
+				return res, err
+			}
+			cfg.onSave(quest)
+			saved = append(saved, quest)
+		}
+		if len(saved) == n {
+			continue
+		}
+		if err := t.stageChapter(chapter, path); err != nil {
+			return res, fmt.Errorf("saving chapter %s: %w", name, err)
+		}
+	}
+	if err := t.commit(); err != nil {
+		return res, fmt.Errorf("saving chapters: %w", err)
+	}
+	for _, q := range saved {
+		res.Saved = append(res.Saved, q.ID)
+		res.Hashes[q.ID] = questHash(q)
+	}
+	return res, nil
+}
+
+// batchSave handles POST "/batch/save", the batch editor's "Save All", which
+// posts every modified quest at once. Each quest is named by a
+// quest=<chapter>/<id> value, and its fields are sent prefixed with its id,
+// eg. <id>.title. Edits are grouped by chapter so that each chapter file is
+// read and written only once.
+func (a *App) batchSave(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	isAjax := r.Header.Get("X-Requested-With") == "XMLHttpRequest" || strings.Contains(r.Header.Get("Accept"), "application/json")
+	if err := r.ParseForm(); err != nil {
+		writeError(w, isAjax, "invalid form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	edits, err := questEditsFromForm(r.Form)
+	if err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := saveQuestEdits(qb, a.Pack.Get(), edits)
+	if res != nil && len(res.Saved) > 0 {
+		byChapter := make(map[string][]string)
+		for _, e := range edits {
+			if _, ok := res.Hashes[e.ID]; ok {
+				byChapter[e.Chapter] = append(byChapter[e.Chapter], e.ID)
+			}
+		}
+		names := make([]string, 0, len(byChapter))
+		for name := range byChapter {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, cname := range names {
+			a.audit(r, "batch edit", cname, byChapter[cname], "")
+		}
+		a.reload()
+	}
+	if err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if isAjax {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "saved": res.Saved, "conflicts": res.Conflicts, "hashes": res.Hashes})
+		return
+	}
+	back := r.Referer()
+	if back == "" {
+		back = "/batch/"
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// setQuestDependency adds the quest dep as the last dependency of each of the
+// quests named by refs, or with remove, removes it from them, writing their
+// chapters together. Quests that already have (or lack) it are left alone.
+// Adding is refused if it would make a quest depend on itself, ie. if dep
+// already depends on one of the quests. It returns the changed quests' ids
+// by chapter.
+func setQuestDependency(qb *QuestBook, refs []questEdit, dep string, remove bool) (map[string][]string, error) {
+	if _, ok := qb.questMap[dep]; !ok {
+		return nil, fmt.Errorf("unknown dependency %s", dep)
+	}
+	byChapter := make(map[string][]string)
+	for _, e := range refs {
+		if qb.chapterMap[e.Chapter] == nil {
+			return nil, fmt.Errorf("unknown chapter %q", e.Chapter)
+		}
+		if !remove {
+			if path := dependencyPath(qb, dep, e.ID); path != nil {
+				return nil, fmt.Errorf("quest %s cannot depend on %s, which already depends on it: %s", e.ID, dep, strings.Join(append(path, dep), " → "))
+			}
+		}
+		byChapter[e.Chapter] = append(byChapter[e.Chapter], e.ID)
+	}
+	return editChapters(qb, slices.Collect(maps.Keys(byChapter)), func(ch *Chapter) ([]string, error) {
+		var changed []string
+		for _, id := range byChapter[ch.Name] {
+			quest, ok := ch.questMap[id]
+			if !ok {
+				return nil, fmt.Errorf("quest %s not found", id)
+			}
+			i := slices.Index(quest.Dependencies, dep)
+			switch {
+			case remove && i >= 0:
+				quest.Dependencies = slices.Delete(quest.Dependencies, i, i+1)
+				quest.MinRequired = min(quest.MinRequired, len(quest.Dependencies))
+			case !remove && i < 0:
+				quest.Dependencies = append(quest.Dependencies, dep)
+			default:
+				continue
+			}
+			changed = append(changed, id)
+		}
+		return changed, nil
+	})
+}
+
+// batchDependency handles POST "/batch/dependency", which adds the quest
+// "dependency" as a dependency of every quest=<chapter>/<id> selected in the
+// batch editor, or with op=remove removes it from them.
+func (a *App) batchDependency(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	isAjax := r.Header.Get("X-Requested-With") == "XMLHttpRequest" || strings.Contains(r.Header.Get("Accept"), "application/json")
+	if err := r.ParseForm(); err != nil {
+		writeError(w, isAjax, "invalid form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	refs, err := questEditsFromForm(r.Form)
+	if err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(refs) == 0 {
+		writeError(w, isAjax, "no quests selected", http.StatusBadRequest)
+		return
+	}
+	dep := strings.TrimSpace(r.FormValue("dependency"))
+	remove := r.FormValue("op") == "remove"
+	changed, err := setQuestDependency(qb, refs, dep, remove)
+	if err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := "batch add dependency"
+	if remove {
+		action = "batch remove dependency"
+	}
+	ids := []string{}
+	for _, name := range slices.Sorted(maps.Keys(changed)) {
+		ids = append(ids, changed[name]...)
+	}
+	if a.auditEdits(r, action, dep, changed) > 0 {
+		a.reload()
+	}
+	if isAjax {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "changed": ids})
+		return
+	}
+	back := r.Referer()
+	if back == "" {
+		back = "/batch/"
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
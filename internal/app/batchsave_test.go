@@ -0,0 +1,146 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBatchSave(t *testing.T) {
+	a := testApp(t)
+	h := a.Router()
+	quests := a.QB().Chapters[0].Quests[:3]
+
+	// change the third quest on disk after the page was "loaded"
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.questMap[quests[2].ID].Title = "Changed elsewhere"
+	if err := ch.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{}
+	for _, q := range quests {
+		form.Add("quest", "test/"+q.ID)
+		form.Set(q.ID+".hash", questHash(q))
+		form.Set(q.ID+".title", "Batch "+q.ID)
+		form.Set(q.ID+".description", q.Description)
+	}
+	req := httptest.NewRequest("POST", "/batch/save", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("save: %d %s", rec.Code, rec.Body)
+	}
+	var res batchSaveResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(res.Saved, []string{quests[0].ID, quests[1].ID}) || !slices.Equal(res.Conflicts, []string{quests[2].ID}) {
+		t.Fatalf("saved %v, conflicts %v", res.Saved, res.Conflicts)
+	}
+
+	qb := a.QB()
+	for _, q := range quests[:2] {
+		if got := qb.questMap[q.ID]; got.Title != "Batch "+q.ID || res.Hashes[q.ID] != questHash(got) {
+			t.Errorf("quest %s: title %q, hash %s", q.ID, got.Title, res.Hashes[q.ID])
+		}
+	}
+	if got := qb.questMap[quests[2].ID].Title; got != "Changed elsewhere" {
+		t.Errorf("conflicting quest was overwritten: %q", got)
+	}
+}
+
+func TestQuestEditsFromForm(t *testing.T) {
+	form := url.Values{"quest": {"a/1", "b/2"}, "1.title": {"One"}, "2.description": {"Two"}, "3.title": {"x"}}
+	edits, err := questEditsFromForm(form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 2 || edits[0].Chapter != "a" || edits[0].Form.Get("title") != "One" || edits[1].Form.Get("description") != "Two" || edits[1].Form.Has("title") {
+		t.Errorf("got %+v", edits)
+	}
+	if _, err := questEditsFromForm(url.Values{"quest": {"nochapter"}}); err == nil {
+		t.Error("expected an error for a quest without a chapter")
+	}
+}
+
+func TestBatchDependency(t *testing.T) {
+	a := testApp(t)
+	h := a.Router()
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/batch/dependency", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// a gate for two quests that doesn't already depend on either
+	qb := a.QB()
+	picked := qb.Chapters[0].Quests[:2]
+	var gate *Quest
+	for _, q := range qb.Quests {
+		if q != picked[0] && q != picked[1] && dependencyPath(qb, q.ID, picked[0].ID) == nil && dependencyPath(qb, q.ID, picked[1].ID) == nil {
+			gate = q
+			break
+		}
+	}
+	if gate == nil {
+		t.Fatal("no quest to use as a gate")
+	}
+	form := url.Values{"dependency": {gate.ID}}
+	for _, q := range picked {
+		form.Add("quest", "test/"+q.ID)
+	}
+	if rec := post(form); rec.Code != http.StatusOK {
+		t.Fatalf("add: %d %s", rec.Code, rec.Body)
+	}
+	for _, q := range picked {
+		deps := a.QB().questMap[q.ID].Dependencies
+		if len(deps) == 0 || deps[len(deps)-1] != gate.ID || !slices.Equal(deps[:len(deps)-1], q.Dependencies) {
+			t.Errorf("quest %s dependencies %v", q.ID, deps)
+		}
+	}
+
+	form.Set("op", "remove")
+	if rec := post(form); rec.Code != http.StatusOK {
+		t.Fatalf("remove: %d %s", rec.Code, rec.Body)
+	}
+	for _, q := range picked {
+		if deps := a.QB().questMap[q.ID].Dependencies; !slices.Equal(deps, q.Dependencies) {
+			t.Errorf("quest %s dependencies %v after removing", q.ID, deps)
+		}
+	}
+
+	// making a quest's dependency depend on it is a cycle
+	var dependent *Quest
+	for _, q := range a.QB().Quests {
+		if len(q.Dependencies) > 0 {
+			dependent = q
+			break
+		}
+	}
+	before := a.QB().questMap[dependent.Dependencies[0]].Dependencies
+	rec := post(url.Values{"quest": {"test/" + dependent.Dependencies[0]}, "dependency": {dependent.ID}})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "already depends on it") {
+		t.Errorf("cycle: %d %s", rec.Code, rec.Body)
+	}
+	if got := a.QB().questMap[dependent.Dependencies[0]].Dependencies; !slices.Equal(got, before) {
+		t.Errorf("cycle was written: %v", got)
+	}
+	if rec := post(url.Values{"quest": {"test/" + gate.ID}, "dependency": {gate.ID}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("self dependency: %d %s", rec.Code, rec.Body)
+	}
+}
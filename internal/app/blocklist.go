@@ -0,0 +1,227 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// BlockList is the pack's blocked terms and the text allowed despite them.
+// Blocked terms are the words a pack must not use in its quest text, such as
+// profanity or a trademark the pack has agreed to avoid, and are reported on
+// the lint page and by `qbedit check`. They are matched like the preferred
+// terms of terms.go: as whole words, in any case, with formatting codes
+// ignored. Text that is fine can be allowed in two ways: phrases allowed
+// everywhere, eg. a mod's name that contains a blocked word, and exceptions
+// for a term in one quest.
+type BlockList struct {
+	Terms []BlockedTerm `json:"terms,omitempty"`
+	// Allowed are phrases never reported, even where they contain a term.
+	Allowed []string `json:"allowed,omitempty"`
+	// Exceptions allow a term in one quest.
+	Exceptions []BlockException `json:"exceptions,omitempty"`
+}
+
+// BlockedTerm is a term the pack must not use, and why.
+type BlockedTerm struct {
+	Term   string `json:"term"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// BlockException allows Term, as written in the blocklist, in a quest.
+type BlockException struct {
+	Quest string `json:"quest"`
+	Term  string `json:"term"`
+}
+
+// parseBlockedTerms reads one term per line, written "term" or "term =
+// reason".
+func parseBlockedTerms(s string) ([]BlockedTerm, error) {
+	var terms []BlockedTerm
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		term, reason, _ := strings.Cut(line, "=")
+		t := BlockedTerm{Term: strings.TrimSpace(term), Reason: strings.TrimSpace(reason)}
+		if t.Term == "" {
+			return nil, fmt.Errorf("line %d: no term", i+1)
+		}
+		if slices.ContainsFunc(terms, func(o BlockedTerm) bool { return strings.EqualFold(o.Term, t.Term) }) {
+			continue
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+// formatBlockedTerms writes terms in the form parseBlockedTerms reads.
+func formatBlockedTerms(terms []BlockedTerm) string {
+	var b strings.Builder
+	for _, t := range terms {
+		b.WriteString(t.Term)
+		if t.Reason != "" {
+			b.WriteString(" = ")
+			b.WriteString(t.Reason)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// parseLines returns the non-empty lines of s, trimmed, without repeats.
+func parseLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !slices.Contains(lines, line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// excepted reports whether term is allowed in the quest id.
+func (bl BlockList) excepted(id, term string) bool {
+	return slices.ContainsFunc(bl.Exceptions, func(e BlockException) bool {
+		return e.Quest == id && strings.EqualFold(e.Term, term)
+	})
+}
+
+// BlockHit is a use of a blocked term in a line of quest text.
+type BlockHit struct {
+	Chapter *Chapter
+	Quest   *Quest
+	// Field is title, subtitle or description; Line is the description line.
+	Field  string
+	Line   int
+	Term   string
+	Reason string
+	// Found is the term as written in the text.
+	Found string
+	Text  string
+	// at is the byte offset of Found in Text.
+	at int
+}
+
+// blockedIn returns the blocked terms used in line, outside the allowed
+// phrases, in order.
+func (bl BlockList) blockedIn(line string) []BlockHit {
+	allowed := make([]bool, len(line))
+	for _, p := range bl.Allowed {
+		for _, i := range findTerm(line, p) {
+			for j := i; j < i+len(p); j++ {
+				allowed[j] = true
+			}
+		}
+	}
+	var hits []BlockHit
+	for _, t := range bl.Terms {
+	next:
+		for _, i := range findTerm(line, t.Term) {
+			for j := i; j < i+len(t.Term); j++ {
+				if allowed[j] {
+					continue next
+				}
+			}
+			hits = append(hits, BlockHit{Term: t.Term, Reason: t.Reason, Found: line[i : i+len(t.Term)], at: i})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].at < hits[j].at })
+	return hits
+}
+
+// blockedBook returns the uses of blocked terms in every quest of qb, in
+// chapter order, leaving out the quests' exceptions.
+func blockedBook(bl BlockList, qb *QuestBook) []BlockHit {
+	if len(bl.Terms) == 0 {
+		return nil
+	}
+	var hits []BlockHit
+	for _, ch := range qb.Chapters {
+		for _, q := range ch.Quests {
+			check := func(field string, line int, text string) {
+				for _, h := range bl.blockedIn(text) {
+					if bl.excepted(q.ID, h.Term) {
+						continue
+					}
+					h.Chapter, h.Quest, h.Field, h.Line, h.Text = ch, q, field, line, text
+					hits = append(hits, h)
+				}
+			}
+			check("title", 0, q.Title)
+			check("subtitle", 0, q.Subtitle)
+			if q.Description != "" {
+				for i, line := range strings.Split(q.Description, "\n") {
+					check("description", i, line)
+				}
+			}
+		}
+	}
+	return hits
+}
+
+// lintBlocklist handles POST "/lint/blocklist", saving the pack's blocked
+// terms and allowed phrases.
+func (a *App) lintBlocklist(w http.ResponseWriter, r *http.Request) {
+	terms, err := parseBlockedTerms(r.FormValue("terms"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	allowed := parseLines(r.FormValue("allowed"))
+	err = a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Blocklist.Terms, cfg.Blocklist.Allowed = terms, allowed
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/lint", http.StatusSeeOther)
+}
+
+// lintAllow handles POST "/lint/allow", which allows the blocked "term" in
+// the quest "quest", or with "remove=1" takes the exception back.
+func (a *App) lintAllow(w http.ResponseWriter, r *http.Request) {
+	id, term := r.FormValue("quest"), r.FormValue("term")
+	remove := r.FormValue("remove") == "1"
+	if _, ok := a.QB().questMap[id]; !ok && !remove {
+		http.Error(w, "unknown quest "+id, http.StatusBadRequest)
+		return
+	}
+	// rejected is why the request can't be applied to the current settings
+	var rejected error
+	err := a.Pack.Update(func(cfg *PackConfig) error {
+		bl := &cfg.Blocklist
+		switch {
+		case remove && !bl.excepted(id, term):
+			rejected = fmt.Errorf("%q isn't allowed in %s", term, id)
+		case remove:
+			bl.Exceptions = slices.DeleteFunc(slices.Clone(bl.Exceptions), func(e BlockException) bool {
+				return e.Quest == id && strings.EqualFold(e.Term, term)
+			})
+		case !slices.ContainsFunc(bl.Terms, func(t BlockedTerm) bool { return t.Term == term }):
+			rejected = fmt.Errorf("%q isn't blocked", term)
+		case !bl.excepted(id, term):
+			bl.Exceptions = append(slices.Clone(bl.Exceptions), BlockException{Quest: id, Term: term})
+		}
+		return rejected
+	})
+	if rejected != nil {
+		http.Error(w, rejected.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	msg := fmt.Sprintf("%q is allowed in %s.", term, id)
+	if remove {
+		msg = fmt.Sprintf("%q is blocked in %s again.", term, id)
+	}
+	http.Redirect(w, r, "/lint?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBlocklist(t *testing.T) {
+	terms, err := parseBlockedTerms("Darn = keep it family friendly\n# trademarks\nMegaCorp = trademark\ndarn\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(terms) != 2 || terms[1].Reason != "trademark" {
+		t.Fatalf("terms = %+v", terms)
+	}
+	if got, err := parseBlockedTerms(formatBlockedTerms(terms)); err != nil || formatBlockedTerms(got) != formatBlockedTerms(terms) {
+		t.Errorf("round trip = %+v, %v", got, err)
+	}
+	if _, err := parseBlockedTerms("= no term"); err == nil {
+		t.Error("a line without a term should be an error")
+	}
+
+	bl := BlockList{Terms: terms, Allowed: []string{"Darn Tough Boots"}}
+	for _, c := range []struct {
+		in   string
+		want []string
+	}{
+		{"Oh &cDARN&r, it's the megacorp drill", []string{"DARN", "megacorp"}},
+		{"darned socks and MegaCorporation", nil},
+		{"Craft the Darn Tough Boots, darn it", []string{"darn"}},
+	} {
+		var got []string
+		for _, h := range bl.blockedIn(c.in) {
+			got = append(got, h.Found)
+		}
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("blockedIn(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	a := testApp(t)
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := ch.Quests[0]
+	q.Title = "MegaCorp Drill"
+	q.Description = "Darn.\nA fine drill."
+	if err := ch.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	cfg := a.Pack.Get()
+	cfg.Blocklist = BlockList{Terms: terms}
+	if err := a.Pack.Set(cfg); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	hits := blockedBook(a.Pack.Get().Blocklist, a.QB())
+	if len(hits) != 2 || hits[0].Field != "title" || hits[1].Line != 0 {
+		t.Fatalf("hits = %+v", hits)
+	}
+	var out strings.Builder
+	if n := a.Check(&out); n != 2 || !strings.Contains(out.String(), `test/`+q.ID+` description line 1: blocked: "Darn" (keep it family friendly)`) {
+		t.Errorf("check found %d:\n%s", n, out.String())
+	}
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/lint/allow", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post(url.Values{"quest": {q.ID}, "term": {"MegaCorp"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("allow: %d %s", rec.Code, rec.Body)
+	}
+	if hits := blockedBook(a.Pack.Get().Blocklist, a.QB()); len(hits) != 1 || hits[0].Term != "Darn" {
+		t.Errorf("after allowing, hits = %+v", hits)
+	}
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/lint", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, "Block again") || !strings.Contains(body, "keep it family friendly") {
+		t.Errorf("lint page: %d", rec.Code)
+	}
+	if rec := post(url.Values{"quest": {q.ID}, "term": {"Heck"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("allowing a term that isn't blocked: %d", rec.Code)
+	}
+	if rec := post(url.Values{"quest": {q.ID}, "term": {"MegaCorp"}, "remove": {"1"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("remove: %d %s", rec.Code, rec.Body)
+	}
+	if len(a.Pack.Get().Blocklist.Exceptions) != 0 {
+		t.Error("the exception wasn't removed")
+	}
+}
@@ -0,0 +1,414 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// parseCacheVersion is bumped when the encoding or the decoder's values
+// change, so older files are ignored.
+const parseCacheVersion = 1
+
+// parseCacheMagic starts every cache file.
+var parseCacheMagic = []byte("qbedit parse cache\n")
+
+// parseCache caches decoded SNBT by the sha256 of the source. Parsing is most
+// of the time it takes to load a book, and the book is loaded again after
+// every edit, so decoded files are kept in memory, for a reload to only parse
+// the files that changed, and on disk in .qbedit/cache, for a restart on an
+// unchanged pack to parse nothing. After each load the values it didn't use,
+// eg. of chapters since edited, are dropped from both. Cached values are kept
+// pristine and each load gets a deep copy, which is much cheaper than
+// parsing.
+type parseCache struct {
+	// dir is where cached values are written, or "" to keep them in memory
+	dir string
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]any
+	// used are the entries used since the last prune, ie. by the load in
+	// progress
+	used map[[sha256.Size]byte]bool
+}
+
+// openParseCache returns a cache kept in dir, which is created when the
+// first value is written.
+func openParseCache(dir string) *parseCache {
+	return &parseCache{dir: dir, entries: make(map[[sha256.Size]byte]any), used: make(map[[sha256.Size]byte]bool)}
+}
+
+// file returns the path of the cache file for the source hash h.
+func (c *parseCache) file(h [sha256.Size]byte) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s.v%d", hex.EncodeToString(h[:]), parseCacheVersion))
+}
+
+// decode decodes the SNBT source b as snbt.DecodeLenient does, from the
+// cache if it has been decoded before. Sources with errors are not cached.
+// It reports whether the value came from the cache. A nil cache decodes.
+func (c *parseCache) decode(b []byte) (v any, skipped []*snbt.ParseError, cached bool, err error) {
+	if c == nil {
+		v, skipped, err = snbt.DecodeLenient(bytes.NewReader(b))
+		return v, skipped, false, err
+	}
+	h := sha256.Sum256(b)
+	c.mu.Lock()
+	v, ok := c.entries[h]
+	c.mu.Unlock()
+	if !ok && c.dir != "" {
+		v, ok = c.read(h)
+	}
+	if ok {
+		c.mu.Lock()
+		c.entries[h] = v
+		c.used[h] = true
+		c.mu.Unlock()
+		return copyValue(v), nil, true, nil
+	}
+
+	v, skipped, err = snbt.DecodeLenient(bytes.NewReader(b))
+	if err != nil || len(skipped) > 0 {
+		return v, skipped, false, err
+	}
+	c.mu.Lock()
+	c.entries[h] = v
+	c.used[h] = true
+	c.mu.Unlock()
+	if c.dir != "" {
+		if err := c.write(h, v); err != nil {
+			slog.Warn("writing parse cache", "error", err)
+		}
+	}
+	return copyValue(v), nil, false, nil
+}
+
+// read reads the cached value of the source hash h from disk.
+func (c *parseCache) read(h [sha256.Size]byte) (any, bool) {
+	b, err := os.ReadFile(c.file(h))
+	if err != nil {
+		return nil, false
+	}
+	if !bytes.HasPrefix(b, parseCacheMagic) {
+		return nil, false
+	}
+	d := cacheDecoder{b: b[len(parseCacheMagic):]}
+	v := d.value()
+	if d.err != nil || len(d.b) != 0 {
+		slog.Warn("ignoring broken parse cache file", "path", c.file(h), "error", d.err)
+		return nil, false
+	}
+	return v, true
+}
+
+// write writes the value v of the source hash h to disk.
+func (c *parseCache) write(h [sha256.Size]byte, v any) error {
+	defer timeOp(opWrite)()
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	// the cache is rebuilt as needed, and shouldn't be committed with the
+	// book by --git
+	ignore := filepath.Join(c.dir, ".gitignore")
+	if _, err := os.Stat(ignore); errors.Is(err, fs.ErrNotExist) {
+		if err := writeFile(ignore, []byte("*\n"), 0644); err != nil {
+			return err
+		}
+	}
+	b, err := appendCacheValue(append([]byte(nil), parseCacheMagic...), v)
+	if err != nil {
+		return err
+	}
+	return writeFile(c.file(h), b, 0644)
+}
+
+// prune drops the entries and removes the cache files not used since the
+// last prune, so that values for old versions of chapters don't pile up. It
+// is called after loading a book, when every current file has been used.
+func (c *parseCache) prune() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	used := c.used
+	c.used = make(map[[sha256.Size]byte]bool, len(used))
+	for h := range c.entries {
+		if !used[h] {
+			delete(c.entries, h)
+		}
+	}
+	c.mu.Unlock()
+	if c.dir == "" {
+		return nil
+	}
+	keep := make(map[string]bool, len(used))
+	for h := range used {
+		keep[filepath.Base(c.file(h))] = true
+	}
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() && !keep[e.Name()] && e.Name() != ".gitignore" && !strings.HasSuffix(e.Name(), tmpSuffix) {
+			if err := os.Remove(filepath.Join(c.dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Tags of the values in the cache encoding. Each value is its tag followed
+// by its contents: lengths and integers are varints, strings are a length
+// and bytes, and compounds and lists are a length and that many keys and
+// values or values.
+const (
+	tagNil byte = iota
+	tagCompound
+	tagList
+	tagString
+	tagInt
+	tagFloat
+	tagBool
+	tagByte
+	tagShort
+	tagLong
+	tagFloatNum
+	tagDecimal
+	tagByteArray
+	tagIntArray
+	tagLongArray
+)
+
+// appendCacheValue appends the encoding of the SNBT value v to b. Cache files
+// use this small binary encoding as gob spends most of its time naming the
+// type of every value in an SNBT tree. A file that doesn't decode, eg. from
+// an older version, is parsed again and replaced.
+func appendCacheValue(b []byte, v any) ([]byte, error) {
+	str := func(b []byte, s string) []byte {
+		return append(binary.AppendUvarint(b, uint64(len(s))), s...)
+	}
+	// the suffixed numbers keep their digits and suffix as written
+	number := func(b []byte, tag byte, sign int, digits, frac string, suffix byte) []byte {
+		b = append(b, tag)
+		b = binary.AppendVarint(b, int64(sign))
+		b = str(b, digits)
+		if tag == tagFloatNum || tag == tagDecimal {
+			b = str(b, frac)
+		}
+		return append(b, suffix)
+	}
+	var err error
+	switch x := v.(type) {
+	case nil:
+		b = append(b, tagNil)
+	case map[string]any:
+		b = binary.AppendUvarint(append(b, tagCompound), uint64(len(x)))
+		for k, e := range x {
+			b = str(b, k)
+			if b, err = appendCacheValue(b, e); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		b = binary.AppendUvarint(append(b, tagList), uint64(len(x)))
+		for _, e := range x {
+			if b, err = appendCacheValue(b, e); err != nil {
+				return nil, err
+			}
+		}
+	case string:
+		b = str(append(b, tagString), x)
+	case int64:
+		b = binary.AppendVarint(append(b, tagInt), x)
+	case float64:
+		b = binary.LittleEndian.AppendUint64(append(b, tagFloat), math.Float64bits(x))
+	case bool:
+		b = append(b, tagBool)
+		if x {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+	case snbt.Byte:
+		b = number(b, tagByte, x.Sign, x.Digits, "", x.Suffix)
+	case snbt.Short:
+		b = number(b, tagShort, x.Sign, x.Digits, "", x.Suffix)
+	case snbt.Long:
+		b = number(b, tagLong, x.Sign, x.Digits, "", x.Suffix)
+	case snbt.FloatNum:
+		b = number(b, tagFloatNum, x.Sign, x.Int, x.Frac, x.Suffix)
+	case snbt.Decimal:
+		b = number(b, tagDecimal, x.Sign, x.Int, x.Frac, x.Suffix)
+	case snbt.ByteArray:
+		b = binary.AppendUvarint(append(b, tagByteArray), uint64(len(x)))
+		for _, e := range x {
+			b = append(b, byte(e))
+		}
+	case snbt.IntArray:
+		b = binary.AppendUvarint(append(b, tagIntArray), uint64(len(x)))
+		for _, e := range x {
+			b = binary.AppendVarint(b, int64(e))
+		}
+	case snbt.LongArray:
+		b = binary.AppendUvarint(append(b, tagLongArray), uint64(len(x)))
+		for _, e := range x {
+			b = binary.AppendVarint(b, e)
+		}
+	default:
+		return nil, fmt.Errorf("can't cache a %T", v)
+	}
+	return b, nil
+}
+
+// cacheDecoder decodes values from the cache encoding in b. The first error
+// stops it, and is kept in err.
+type cacheDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *cacheDecoder) fail() {
+	if d.err == nil {
+		d.err = errors.New("truncated or corrupt value")
+	}
+	d.b = nil
+}
+
+func (d *cacheDecoder) byte() byte {
+	if len(d.b) == 0 {
+		d.fail()
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+func (d *cacheDecoder) uvarint() uint64 {
+	n, size := binary.Uvarint(d.b)
+	if size <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[size:]
+	return n
+}
+
+func (d *cacheDecoder) varint() int64 {
+	n, size := binary.Varint(d.b)
+	if size <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[size:]
+	return n
+}
+
+// length reads a length of things at least min bytes long each, failing if
+// there aren't enough bytes left for them.
+func (d *cacheDecoder) length(min int) int {
+	n := d.uvarint()
+	if n > uint64(len(d.b)/min) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (d *cacheDecoder) string() string {
+	n := d.length(1)
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+func (d *cacheDecoder) value() any {
+	switch tag := d.byte(); tag {
+	case tagNil:
+		return nil
+	case tagCompound:
+		n := d.length(2)
+		m := make(map[string]any, n)
+		for range n {
+			k := d.string()
+			m[k] = d.value()
+		}
+		return m
+	case tagList:
+		n := d.length(1)
+		l := make([]any, n)
+		for i := range l {
+			l[i] = d.value()
+		}
+		return l
+	case tagString:
+		return d.string()
+	case tagInt:
+		return d.varint()
+	case tagFloat:
+		if len(d.b) < 8 {
+			d.fail()
+			return nil
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(d.b))
+		d.b = d.b[8:]
+		return f
+	case tagBool:
+		return d.byte() != 0
+	case tagByte:
+		sign, digits := int(d.varint()), d.string()
+		return snbt.Byte{Sign: sign, Digits: digits, Suffix: d.byte()}
+	case tagShort:
+		sign, digits := int(d.varint()), d.string()
+		return snbt.Short{Sign: sign, Digits: digits, Suffix: d.byte()}
+	case tagLong:
+		sign, digits := int(d.varint()), d.string()
+		return snbt.Long{Sign: sign, Digits: digits, Suffix: d.byte()}
+	case tagFloatNum:
+		sign, i, frac := int(d.varint()), d.string(), d.string()
+		return snbt.FloatNum{Sign: sign, Int: i, Frac: frac, Suffix: d.byte()}
+	case tagDecimal:
+		sign, i, frac := int(d.varint()), d.string(), d.string()
+		return snbt.Decimal{Sign: sign, Int: i, Frac: frac, Suffix: d.byte()}
+	case tagByteArray:
+		n := d.length(1)
+		a := make(snbt.ByteArray, n)
+		for i := range a {
+			a[i] = int8(d.byte())
+		}
+		return a
+	case tagIntArray:
+		n := d.length(1)
+		a := make(snbt.IntArray, n)
+		for i := range a {
+			a[i] = int32(d.varint())
+		}
+		return a
+	case tagLongArray:
+		n := d.length(1)
+		a := make(snbt.LongArray, n)
+		for i := range a {
+			a[i] = d.varint()
+		}
+		return a
+	default:
+		d.err = fmt.Errorf("unknown tag %d", tag)
+		d.b = nil
+		return nil
+	}
+}
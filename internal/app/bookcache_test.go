@@ -0,0 +1,129 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+func TestCacheEncoding(t *testing.T) {
+	chapter, err := os.ReadFile(filepath.Join("..", "..", "snbt", "test_chapter.snbt"))
+	if err != nil {
+		t.Skip("test_chapter.snbt not present; skipping")
+	}
+	for _, src := range []string{
+		string(chapter),
+		`{ a: 1b, b: -2s, c: 3L, d: 1.5f, e: -2.25d, f: [B; 1b, -2b], g: [I; 1, -2], h: [L; 1L], i: true, j: "s", k: [ ], l: 12, m: { } }`,
+	} {
+		v, err := snbt.Decode(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := appendCacheValue(nil, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := cacheDecoder{b: b}
+		got := d.value()
+		if d.err != nil || len(d.b) != 0 {
+			t.Fatalf("decoding: %v, %d bytes left", d.err, len(d.b))
+		}
+		var want, have bytes.Buffer
+		if err := snbt.Encode(&want, v); err != nil {
+			t.Fatal(err)
+		}
+		if err := snbt.Encode(&have, got); err != nil {
+			t.Fatal(err)
+		}
+		if want.String() != have.String() {
+			t.Errorf("round trip changed the value:\n%s\nwant:\n%s", have.String(), want.String())
+		}
+		// a truncated file is an error, not a panic
+		for _, n := range []int{0, 1, len(b) / 2, len(b) - 1} {
+			d := cacheDecoder{b: b[:n]}
+			if d.value(); d.err == nil {
+				t.Errorf("no error for %d of %d bytes", n, len(b))
+			}
+		}
+	}
+}
+
+func TestParseCache(t *testing.T) {
+	a := testApp(t)
+	dir := filepath.Join(packDir(a.Root()), "cache")
+	files := func() []string {
+		entries, _ := os.ReadDir(dir)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+	if got := files(); len(got) != 2 || got[0] != ".gitignore" {
+		t.Fatalf("cache files after the first load: %v", got)
+	}
+	if n := a.QB().Stats.Cached; n != 0 {
+		t.Errorf("first load had %d cached files", n)
+	}
+	title := a.QB().Quests[0].Title
+
+	// a restart reads the chapter from the cache
+	b, err := New(a.Root(), "1.20.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := b.QB().Stats.Cached; n != 1 {
+		t.Errorf("restart had %d cached files, want 1", n)
+	}
+	if got := b.QB().Quests[0].Title; got != title {
+		t.Errorf("cached title %q, want %q", got, title)
+	}
+	// changing the loaded book doesn't change the cache
+	b.QB().Quests[0].raw["title"] = "Changed in memory"
+	b.reload()
+	if got := b.QB().Quests[0].raw["title"]; got != title {
+		t.Errorf("reloaded title %q, want %q", got, title)
+	}
+
+	// an edited chapter is parsed again, and its old entry pruned from
+	// memory and disk
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.Quests[0].Title = "Edited"
+	if err := ch.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	b.reload()
+	if got, n := b.QB().Quests[0].Title, b.QB().Stats.Cached; got != "Edited" || n != 0 {
+		t.Errorf("after editing: title %q, %d cached", got, n)
+	}
+	if got := files(); len(got) != 2 {
+		t.Errorf("cache files after editing: %v", got)
+	}
+	if n := len(b.cache.entries); n != 1 {
+		t.Errorf("%d cached values after editing, want 1", n)
+	}
+
+	// a broken cache file is parsed again
+	for _, name := range files() {
+		if name != ".gitignore" {
+			if err := os.WriteFile(filepath.Join(dir, name), parseCacheMagic, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	c, err := New(a.Root(), "1.20.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, n := c.QB().Quests[0].Title, c.QB().Stats.Cached; got != "Edited" || n != 0 {
+		t.Errorf("with a broken cache file: title %q, %d cached", got, n)
+	}
+}
@@ -0,0 +1,289 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+// Canvas layout constants, in SVG user units.
+const (
+	canvasUnit = 40
+	canvasPad  = 40
+)
+
+// CanvasNode is a quest, or a link to one, placed on the canvas.
+type CanvasNode struct {
+	ID      string
+	Chapter string
+	Title   string
+	// Class is the minecraft.css color class of the title, eg. "c6"
+	Class string
+	X, Y  float64
+	// R is half the quest's width
+	R float64
+	// Path draws the quest's shape centered on 0,0
+	Path string
+	// Icon is the item shown on the quest, if it has a texture
+	Icon string
+	// Link is the id of the quest link this node is drawn for, if any.
+	Link string
+}
+
+// Key identifies the node when saving positions: the quest's id, or the
+// link's.
+func (n CanvasNode) Key() string {
+	if n.Link != "" {
+		return n.Link
+	}
+	return n.ID
+}
+
+// IconSize is the width of the quest's icon, which is drawn centered.
+func (n CanvasNode) IconSize() float64 { return math.Round(n.R * 1.2) }
+
+// IconOffset is where the icon's top left corner is drawn.
+func (n CanvasNode) IconOffset() float64 { return -n.IconSize() / 2 }
+
+// LabelY is where the quest's title is drawn, below it.
+func (n CanvasNode) LabelY() float64 { return n.R + 12 }
+
+// CanvasEdge is a line from a dependency to the quest that requires it.
+type CanvasEdge struct {
+	From, To       string
+	X1, Y1, X2, Y2 float64
+	// Hidden lines aren't drawn in game
+	Hidden bool
+}
+
+// Canvas is a chapter laid out as in game.
+type Canvas struct {
+	Nodes         []CanvasNode
+	Edges         []CanvasEdge
+	Width, Height float64
+	// OffsetX and OffsetY are where the chapter's 0,0 is on the canvas.
+	OffsetX, OffsetY float64
+}
+
+// polygonPath returns a regular polygon with n corners of radius r, the
+// first at angle start (in degrees, clockwise from the right).
+func polygonPath(n int, r, start float64) string {
+	var b strings.Builder
+	for i := range n {
+		a := (start + float64(i)*360/float64(n)) * math.Pi / 180
+		cmd := "L"
+		if i == 0 {
+			cmd = "M"
+		}
+		fmt.Fprintf(&b, "%s %.2f %.2f ", cmd, r*math.Cos(a), r*math.Sin(a))
+	}
+	b.WriteString("Z")
+	return b.String()
+}
+
+// shapePath returns an SVG path of the quest shape with radius r centered on
+// 0,0. Unknown shapes are drawn as circles.
+func shapePath(shape string, r float64) string {
+	switch shape {
+	case "square":
+		return fmt.Sprintf("M %.2f %.2f H %.2f V %.2f H %.2f Z", -r, -r, r, r, -r)
+	case "rsquare":
+		c := r * 0.3
+		return fmt.Sprintf("M %.2f %.2f H %.2f A %.2f %.2f 0 0 1 %.2f %.2f V %.2f A %.2f %.2f 0 0 1 %.2f %.2f H %.2f A %.2f %.2f 0 0 1 %.2f %.2f V %.2f A %.2f %.2f 0 0 1 %.2f %.2f Z",
+			-r+c, -r, r-c, c, c, r, -r+c, r-c, c, c, r-c, r, -r+c, c, c, -r, r-c, -r+c, c, c, -r+c, -r)
+	case "diamond":
+		return polygonPath(4, r, -90)
+	case "pentagon":
+		return polygonPath(5, r, -90)
+	case "hexagon":
+		return polygonPath(6, r, -90)
+	case "octagon":
+		return polygonPath(8, r, 22.5)
+	case "heart":
+		return fmt.Sprintf("M 0 %.2f C %.2f %.2f %.2f %.2f 0 %.2f C %.2f %.2f %.2f %.2f 0 %.2f Z",
+			r*0.9, -r*1.2, 0.0, -r*0.6, -r*1.1, -r*0.45, r*0.6, -r*1.1, r*1.2, 0.0, r*0.9)
+	case "gear":
+		// teeth alternate between the outer and an inner radius
+		var b strings.Builder
+		for i := range 32 {
+			rr := r
+			if i%4 >= 2 {
+				rr = r * 0.78
+			}
+			a := float64(i) * 2 * math.Pi / 32
+			cmd := "L"
+			if i == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(&b, "%s %.2f %.2f ", cmd, rr*math.Cos(a), rr*math.Sin(a))
+		}
+		b.WriteString("Z")
+		return b.String()
+	}
+	return fmt.Sprintf("M %.2f 0 A %.2f %.2f 0 1 1 %.2f 0 A %.2f %.2f 0 1 1 %.2f 0 Z", -r, r, r, r, r, r, -r)
+}
+
+// buildCanvas lays out ch's quests and quest links at their grid positions,
+// to be drawn as the quest book does in game: each quest with its shape, size
+// and icon, and lines to the quests it depends on. Quest links, which show a
+// quest from another chapter, are drawn dashed.
+func buildCanvas(qb *QuestBook, ch *Chapter, icons *IconSet) *Canvas {
+	c := &Canvas{}
+	pos := make(map[string]int, len(ch.Quests))
+	add := func(q *Quest, x, y float64, link string) {
+		n := CanvasNode{
+			ID:    q.ID,
+			Title: stripCodes(q.GetTitle()),
+			X:     x,
+			Y:     y,
+			R:     0.5 * q.Size * canvasUnit,
+			Path:  shapePath(q.DisplayShape(), 0.5*q.Size*canvasUnit),
+			Link:  link,
+		}
+		if q.Chapter != nil {
+			n.Chapter = q.Chapter.Name
+		}
+		if n.Title == "" {
+			n.Title = q.ID
+		}
+		if code := mcformat.FirstColorCode(q.GetTitle()); code != 0 {
+			n.Class = "c" + string(code)
+		}
+		if id := q.IconItem(); icons.Has(id) {
+			n.Icon = id
+		}
+		c.Nodes = append(c.Nodes, n)
+	}
+	for _, q := range ch.Quests {
+		pos[q.ID] = len(c.Nodes)
+		add(q, q.X, q.Y, "")
+	}
+	for _, l := range ch.QuestLinks {
+		m, ok := l.(map[string]any)
+		if !ok {
+			continue
+		}
+		if q, ok := qb.questMap[M(m).GetString("linked_quest")]; ok {
+			add(q, M(m).GetFloat("x"), M(m).GetFloat("y"), M(m).GetString("id"))
+		}
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i := range c.Nodes {
+		n := &c.Nodes[i]
+		n.X, n.Y = n.X*canvasUnit, n.Y*canvasUnit
+		minX, minY = min(minX, n.X-n.R), min(minY, n.Y-n.R)
+		maxX, maxY = max(maxX, n.X+n.R), max(maxY, n.Y+n.R)
+	}
+	if len(c.Nodes) == 0 {
+		minX, minY, maxX, maxY = 0, 0, 0, 0
+	}
+	for i := range c.Nodes {
+		c.Nodes[i].X += canvasPad - minX
+		c.Nodes[i].Y += canvasPad - minY
+	}
+	c.Width, c.Height = maxX-minX+2*canvasPad, maxY-minY+2*canvasPad
+	c.OffsetX, c.OffsetY = canvasPad-minX, canvasPad-minY
+
+	for _, q := range ch.Quests {
+		to := c.Nodes[pos[q.ID]]
+		hidden := M(q.raw).GetBool("hide_dependency_lines")
+		for _, d := range q.Dependencies {
+			// dependencies in other chapters aren't on the canvas
+			i, ok := pos[d]
+			if !ok {
+				continue
+			}
+			from := c.Nodes[i]
+			c.Edges = append(c.Edges, CanvasEdge{From: d, To: q.ID, X1: from.X, Y1: from.Y, X2: to.X, Y2: to.Y, Hidden: hidden})
+		}
+	}
+	return c
+}
+
+// chapterCanvas handles GET "/chapter/{chapter}/canvas", the chapter drawn as
+// it is in game.
+func (a *App) chapterCanvas(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	ch, ok := qb.chapterMap[chi.URLParam(r, "chapter")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data := a.baseData(r, ch.Title)
+	data["Chapter"] = ch
+	data["SelectedChapter"] = ch.Name
+	data["Canvas"] = buildCanvas(qb, ch, a.Icons)
+	a.render(w, "canvas.gohtml", data)
+}
+
+// setPosition moves the quest or quest link id in ch to x, y.
+func setPosition(ch *Chapter, id string, x, y float64) error {
+	if q, ok := ch.questMap[id]; ok {
+		q.X, q.Y = x, y
+		return nil
+	}
+	for _, l := range ch.QuestLinks {
+		if m, ok := l.(map[string]any); ok && M(m).GetString("id") == id {
+			if M(m).GetFloat("x") != x {
+				m["x"] = floatLike(m["x"], x)
+			}
+			if M(m).GetFloat("y") != y {
+				m["y"] = floatLike(m["y"], y)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no quest or quest link %s in this chapter", id)
+}
+
+// chapterPositions handles POST "/chapter/{chapter}/positions", which moves
+// the quests and quest links given by the "id", "x" and "y" form fields, as
+// dragged on the canvas, together.
+func (a *App) chapterPositions(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, true, "invalid form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	cname := chi.URLParam(r, "chapter")
+	ids, xs, ys := r.Form["id"], r.Form["x"], r.Form["y"]
+	if len(xs) != len(ids) || len(ys) != len(ids) {
+		writeError(w, true, "each id needs an x and a y", http.StatusBadRequest)
+		return
+	}
+	path := a.QB().chapterPath(cname)
+	chapter, err := NewChapterFromPath(path)
+	if err != nil {
+		writeError(w, true, "open chapter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i, id := range ids {
+		x, err := strconv.ParseFloat(xs[i], 64)
+		if err != nil {
+			writeError(w, true, fmt.Sprintf("invalid x %q", xs[i]), http.StatusBadRequest)
+			return
+		}
+		y, err := strconv.ParseFloat(ys[i], 64)
+		if err != nil {
+			writeError(w, true, fmt.Sprintf("invalid y %q", ys[i]), http.StatusBadRequest)
+			return
+		}
+		if err := setPosition(chapter, id, x, y); err != nil {
+			writeError(w, true, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := chapter.Save(path); err != nil {
+		writeError(w, true, "saving chapter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.audit(r, "move quests", cname, ids, "")
+	a.reload()
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "moved": len(ids)})
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"bytes"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+func TestCanvas(t *testing.T) {
+	a := testApp(t)
+	qb := a.QB()
+	ch := qb.Chapters[0]
+	c := buildCanvas(qb, ch, nil)
+	if len(c.Nodes) != len(ch.Quests) {
+		t.Fatalf("%d nodes for %d quests", len(c.Nodes), len(ch.Quests))
+	}
+	for i, n := range c.Nodes {
+		q := ch.Quests[i]
+		if n.R != 0.5*q.Size*canvasUnit || n.X-n.R < canvasPad-0.01 || n.Y-n.R < canvasPad-0.01 ||
+			n.X+n.R > c.Width-canvasPad+0.01 || n.Y+n.R > c.Height-canvasPad+0.01 {
+			t.Errorf("quest %s at %v,%v r %v outside %vx%v", q.ID, n.X, n.Y, n.R, c.Width, c.Height)
+		}
+		// the chapter's default shape is a rounded square
+		if want := shapePath(q.DisplayShape(), n.R); n.Path != want || (q.Shape == "" && q.DisplayShape() != "rsquare") {
+			t.Errorf("quest %s shape %q", q.ID, q.DisplayShape())
+		}
+	}
+	for _, e := range c.Edges {
+		q := qb.questMap[e.To]
+		if q == nil || !strings.Contains(strings.Join(q.Dependencies, " "), e.From) {
+			t.Errorf("edge %s → %s isn't a dependency", e.From, e.To)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/chapter/test/canvas", nil))
+	if rec.Code != http.StatusOK || strings.Count(rec.Body.String(), `class="canvas-node`) != len(ch.Quests) {
+		t.Errorf("GET canvas: %d", rec.Code)
+	}
+}
+
+func TestChapterPositions(t *testing.T) {
+	a := testApp(t)
+	q := a.QB().Chapters[0].Quests[0]
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/chapter/test/positions", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post(url.Values{"id": {"0000000000000000"}, "x": {"1"}, "y": {"1"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("moving an unknown quest: %d", rec.Code)
+	}
+	if rec := post(url.Values{"id": {q.ID}, "x": {"2.5"}, "y": {"-3"}}); rec.Code != http.StatusOK {
+		t.Fatalf("POST positions: %d %s", rec.Code, rec.Body)
+	}
+	moved := a.QB().questMap[q.ID]
+	if moved.X != 2.5 || moved.Y != -3 {
+		t.Errorf("quest at %v,%v", moved.X, moved.Y)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the moved quest's position changes, and stays a double
+	positions := func(b []byte) map[string]string {
+		v, err := snbt.Decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pos := make(map[string]string)
+		for _, q := range M(v.(map[string]any)).GetAnys("quests") {
+			m := M(q.(map[string]any))
+			pos[m.GetString("id")] = m["x"].(snbt.Decimal).SNBT() + " " + m["y"].(snbt.Decimal).SNBT()
+		}
+		return pos
+	}
+	want := positions(before)
+	want[q.ID] = "2.5d -3.0d"
+	if got := positions(after); !maps.Equal(got, want) {
+		t.Errorf("positions = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,244 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// validChapterName matches the file names FTB Quests generates for chapters.
+var validChapterName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// chapterName derives a chapter file name from a title, eg. "&6Ore Processing"
+// becomes "ore_processing".
+func chapterName(title string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(stripCodes(title)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+// chapterPath returns the path of the chapter file called name.
+func (q *QuestBook) chapterPath(name string) string {
+	return filepath.Join(q.root, "quests", "chapters", name+".snbt")
+}
+
+// CreateChapter writes a new, empty chapter file. If name is empty it is
+// derived from the title. The chapter is placed last in its group, or last
+// among the ungrouped chapters.
+func (q *QuestBook) CreateChapter(name, title, groupID string) (string, error) {
+	if strings.TrimSpace(title) == "" {
+		return "", errors.New("chapter title is required")
+	}
+	if name == "" {
+		name = chapterName(title)
+	}
+	if !validChapterName.MatchString(name) {
+		return "", fmt.Errorf("invalid chapter name %q: use lowercase letters, digits and _", name)
+	}
+	if groupID != "" {
+		if _, ok := q.groupMap[groupID]; !ok {
+			return "", fmt.Errorf("unknown group %s", groupID)
+		}
+	}
+
+	path := q.chapterPath(name)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("chapter %s already exists", name)
+	}
+	return name, writeSNBT(path, newChapterRaw(q.idSource().Next(), name, title, groupID, q.nextChapterOrder(groupID)))
+}
+
+// nextChapterOrder returns the order_index placing a chapter last in the
+// group groupID, or last among the ungrouped chapters.
+func (q *QuestBook) nextChapterOrder(groupID string) int {
+	order := 0
+	for _, c := range q.Chapters {
+		if c.GroupID == groupID {
+			order = max(order, c.OrderIndex+1)
+		}
+	}
+	return order
+}
+
+// newChapterRaw returns the compound of an empty chapter with the given id.
+func newChapterRaw(id, name, title, groupID string, order int) map[string]any {
+	return map[string]any{
+		"default_hide_dependency_lines": false,
+		"default_quest_shape":           "",
+		"filename":                      name,
+		"group":                         groupID,
+		"icon":                          "",
+		"id":                            id,
+		"order_index":                   int64(order),
+		"quest_links":                   []any{},
+		"quests":                        []any{},
+		"title":                         title,
+	}
+}
+
+// RenameChapter moves the chapter file called name to newName and updates its
+// filename field. If title is not empty the chapter's title is changed too.
+func (q *QuestBook) RenameChapter(name, newName, title string) error {
+	if !validChapterName.MatchString(newName) {
+		return fmt.Errorf("invalid chapter name %q: use lowercase letters, digits and _", newName)
+	}
+	path, newPath := q.chapterPath(name), q.chapterPath(newName)
+	if newName != name {
+		if _, err := os.Stat(newPath); err == nil {
+			return fmt.Errorf("chapter %s already exists", newName)
+		}
+	}
+
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		return err
+	}
+	ch.raw["filename"] = newName
+	if title != "" {
+		ch.raw["title"] = title
+	}
+	if newName != name {
+		if err := checkRemove(path); err != nil {
+			return err
+		}
+	}
+	// write the new file before removing the old one so a failure can't
+	// lose the chapter
+	if err := ch.Save(newPath); err != nil {
+		return err
+	}
+	if newName != name {
+		recordRemove(path)
+		return os.Remove(path)
+	}
+	return nil
+}
+
+// DeleteChapter removes the chapter file called name. Quests in other
+// chapters lose their dependencies on its quests, which would otherwise be
+// left dangling; their chapters are written together before the file is
+// removed.
+func (q *QuestBook) DeleteChapter(name string) error {
+	c, ok := q.chapterMap[name]
+	if !ok {
+		return fmt.Errorf("unknown chapter %s", name)
+	}
+	path := q.chapterPath(name)
+	if err := checkRemove(path); err != nil {
+		return err
+	}
+
+	gone := make(map[string]bool, len(c.Quests))
+	for _, quest := range c.Quests {
+		gone[quest.ID] = true
+	}
+	dangling := func(id string) bool { return gone[id] }
+	t := newBookWrite(q.Lang)
+	for _, other := range q.Chapters {
+		if other == c || !slices.ContainsFunc(other.Quests, func(quest *Quest) bool {
+			return slices.ContainsFunc(quest.Dependencies, dangling)
+		}) {
+			continue
+		}
+		otherPath := q.chapterPath(other.Name)
+		ch, err := NewChapterFromPath(otherPath)
+		if err != nil {
+			return fmt.Errorf("open chapter %s: %w", other.Name, err)
+		}
+		ch.resolveLang(q.Lang)
+		for _, quest := range ch.Quests {
+			if slices.ContainsFunc(quest.Dependencies, dangling) {
+				quest.Dependencies = slices.DeleteFunc(quest.Dependencies, dangling)
+				quest.MinRequired = min(quest.MinRequired, len(quest.Dependencies))
+			}
+		}
+		if err := t.stageChapter(ch, otherPath); err != nil {
+			return fmt.Errorf("saving chapter %s: %w", other.Name, err)
+		}
+	}
+	if t.Len() > 0 {
+		if err := t.commit(); err != nil {
+			return fmt.Errorf("saving chapters: %w", err)
+		}
+	}
+	recordRemove(path)
+	return os.Remove(path)
+}
+
+// ReorderChapters rewrites the order_index of the chapters in a group (or of
+// the ungrouped chapters when groupID is "") so that they appear in the order
+// given by names, which must list every chapter in the group exactly once.
+//
+// Ungrouped chapters share their order_index space with the groups they are
+// interleaved with (see buildTopItems), so the chapters are given the indexes
+// they already occupy in the new order rather than being renumbered from 0.
+// Only files whose index changes are written, and they are written together
+// as one bookWrite, so a failure part way leaves the old order in place.
+func (q *QuestBook) ReorderChapters(groupID string, names []string) error {
+	var current []*Chapter
+	for _, c := range q.Chapters {
+		if c.GroupID == groupID {
+			current = append(current, c)
+		}
+	}
+	if len(names) != len(current) {
+		return fmt.Errorf("expected %d chapters, got %d", len(current), len(names))
+	}
+
+	slots := make([]int, 0, len(current))
+	seen := make(map[string]bool, len(names))
+	for _, c := range current {
+		slots = append(slots, c.OrderIndex)
+	}
+	for _, name := range names {
+		c, ok := q.chapterMap[name]
+		if !ok || c.GroupID != groupID {
+			return fmt.Errorf("chapter %s is not in this group", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("chapter %s listed twice", name)
+		}
+		seen[name] = true
+	}
+	slices.Sort(slots)
+	if len(slices.Compact(slices.Clone(slots))) != len(slots) {
+		// duplicate indexes can't express an order; renumber from the first
+		for i := range slots {
+			slots[i] = slots[0] + i
+		}
+	}
+
+	t := newBookWrite(nil)
+	for i, name := range names {
+		if q.chapterMap[name].OrderIndex == slots[i] {
+			continue
+		}
+		path := q.chapterPath(name)
+		ch, err := NewChapterFromPath(path)
+		if err != nil {
+			return err
+		}
+		M(ch.raw).SetInt("order_index", slots[i])
+		ch.Sync()
+		if err := t.stageSNBT(path, ch.raw); err != nil {
+			return err
+		}
+	}
+	if t.Len() == 0 {
+		return nil
+	}
+	return t.commit()
+}
@@ -0,0 +1,288 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// chapterApp returns a test app whose book has the ungrouped chapters test,
+// alpha, beta and gamma, in that order.
+func chapterApp(t *testing.T) *App {
+	t.Helper()
+	a := testApp(t)
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		if _, err := a.QB().CreateChapter(name, strings.ToUpper(name[:1])+name[1:], ""); err != nil {
+			t.Fatal(err)
+		}
+		a.reload()
+	}
+	return a
+}
+
+// chapterOrder returns the names of the book's ungrouped chapters sorted by
+// order_index.
+func chapterOrder(qb *QuestBook) []string {
+	var cs []*Chapter
+	for _, c := range qb.Chapters {
+		if c.GroupID == "" {
+			cs = append(cs, c)
+		}
+	}
+	slices.SortStableFunc(cs, func(a, b *Chapter) int { return a.OrderIndex - b.OrderIndex })
+	var names []string
+	for _, c := range cs {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestReorderChapters(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []string
+		err   string
+		// written lists the chapters whose files should change
+		written []string
+	}{
+		{"unchanged", []string{"test", "alpha", "beta", "gamma"}, "", nil},
+		{"swap", []string{"test", "beta", "alpha", "gamma"}, "", []string{"alpha", "beta"}},
+		{"reverse", []string{"gamma", "beta", "alpha", "test"}, "", []string{"test", "alpha", "beta", "gamma"}},
+		{"too few", []string{"test", "alpha", "beta"}, "expected 4 chapters, got 3", nil},
+		{"unknown", []string{"test", "alpha", "beta", "delta"}, "chapter delta is not in this group", nil},
+		{"twice", []string{"test", "alpha", "beta", "beta"}, "chapter beta listed twice", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := chapterApp(t)
+			qb := a.QB()
+			before := make(map[string][]byte)
+			for _, c := range qb.Chapters {
+				before[c.Name], _ = os.ReadFile(qb.chapterPath(c.Name))
+			}
+
+			err := qb.ReorderChapters("", tt.order)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("ReorderChapters = %v, want %q", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			var written []string
+			for _, c := range qb.Chapters {
+				b, _ := os.ReadFile(qb.chapterPath(c.Name))
+				if string(b) != string(before[c.Name]) {
+					written = append(written, c.Name)
+				}
+			}
+			slices.Sort(written)
+			want := slices.Sorted(slices.Values(tt.written))
+			if !slices.Equal(written, want) {
+				t.Errorf("wrote %v, want %v", written, want)
+			}
+
+			a.reload()
+			got := chapterOrder(a.QB())
+			wantOrder := tt.order
+			if tt.err != "" {
+				wantOrder = []string{"test", "alpha", "beta", "gamma"}
+			}
+			if !slices.Equal(got, wantOrder) {
+				t.Errorf("order = %v, want %v", got, wantOrder)
+			}
+		})
+	}
+}
+
+// TestReorderChaptersDuplicateIndexes checks that chapters sharing an
+// order_index are renumbered so that the new order holds.
+func TestReorderChaptersDuplicateIndexes(t *testing.T) {
+	a := chapterApp(t)
+	qb := a.QB()
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		ch, err := NewChapterFromPath(qb.chapterPath(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		M(ch.raw).SetInt("order_index", 1)
+		if err := ch.Save(qb.chapterPath(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.reload()
+
+	order := []string{"gamma", "test", "alpha", "beta"}
+	if err := a.QB().ReorderChapters("", order); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	if got := chapterOrder(a.QB()); !slices.Equal(got, order) {
+		t.Errorf("order = %v, want %v", got, order)
+	}
+}
+
+func TestCreateChapter(t *testing.T) {
+	tests := []struct {
+		name, chapter, title, group string
+		// want is the file name created, or "" if creation fails
+		want, err string
+	}{
+		{"derived", "", "&6Ore Processing", "", "ore_processing", ""},
+		{"named", "ores", "Ores", "", "ores", ""},
+		{"grouped", "early", "Early", "0000000000000AB1", "early", ""},
+		{"no title", "ores", " ", "", "", "chapter title is required"},
+		{"invalid name", "Ores!", "Ores", "", "", `invalid chapter name "Ores!": use lowercase letters, digits and _`},
+		{"unknown group", "ores", "Ores", "0000000000000AB2", "", "unknown group 0000000000000AB2"},
+		{"exists", "test", "Test", "", "", "chapter test already exists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := testApp(t)
+			groups := `{ chapter_groups: [ { id: "0000000000000AB1", title: "Early" } ] }`
+			if err := os.WriteFile(filepath.Join(a.Root(), "quests", "chapter_groups.snbt"), []byte(groups), 0644); err != nil {
+				t.Fatal(err)
+			}
+			a.reload()
+
+			name, err := a.QB().CreateChapter(tt.chapter, tt.title, tt.group)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("CreateChapter = %q, %v, want %q", name, err, tt.err)
+				}
+				return
+			}
+			if err != nil || name != tt.want {
+				t.Fatalf("CreateChapter = %q, %v, want %q", name, err, tt.want)
+			}
+			// a chapter goes last in its group, or after test when ungrouped
+			order := 0
+			if tt.group == "" {
+				order = a.QB().chapterMap["test"].OrderIndex + 1
+			}
+			a.reload()
+			c := a.QB().chapterMap[name]
+			if c == nil {
+				t.Fatalf("chapter %s did not load", name)
+			}
+			if c.Title != tt.title || c.Filename != name || c.GroupID != tt.group || c.OrderIndex != order || len(c.Quests) != 0 {
+				t.Errorf("chapter = %q %q %q %d, %d quests", c.Title, c.Filename, c.GroupID, c.OrderIndex, len(c.Quests))
+			}
+			if !validQuestID.MatchString(c.ID) || c.ID == a.QB().chapterMap["test"].ID {
+				t.Errorf("chapter id %q", c.ID)
+			}
+		})
+	}
+}
+
+func TestRenameChapter(t *testing.T) {
+	tests := []struct {
+		name, newName, title string
+		// wantTitle is the title afterwards; "" keeps test's title
+		wantTitle, err string
+	}{
+		{"move", "renamed", "", "", ""},
+		{"move and retitle", "renamed", "Renamed", "Renamed", ""},
+		{"retitle", "test", "Retitled", "Retitled", ""},
+		{"exists", "alpha", "", "", "chapter alpha already exists"},
+		{"invalid", "Renamed", "", "", `invalid chapter name "Renamed": use lowercase letters, digits and _`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := chapterApp(t)
+			qb := a.QB()
+			old := qb.chapterMap["test"]
+
+			err := qb.RenameChapter("test", tt.newName, tt.title)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("RenameChapter = %v, want %q", err, tt.err)
+				}
+				if _, err := os.Stat(qb.chapterPath("test")); err != nil {
+					t.Errorf("test.snbt is gone after a failed rename: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.newName != "test" {
+				if _, err := os.Stat(qb.chapterPath("test")); !os.IsNotExist(err) {
+					t.Errorf("test.snbt still exists: %v", err)
+				}
+			}
+			a.reload()
+			c := a.QB().chapterMap[tt.newName]
+			if c == nil {
+				t.Fatalf("chapter %s did not load", tt.newName)
+			}
+			wantTitle := tt.wantTitle
+			if wantTitle == "" {
+				wantTitle = old.Title
+			}
+			if c.Filename != tt.newName || c.Title != wantTitle || c.ID != old.ID || len(c.Quests) != len(old.Quests) {
+				t.Errorf("chapter = %q %q %q, %d quests", c.Filename, c.Title, c.ID, len(c.Quests))
+			}
+		})
+	}
+}
+
+func TestDeleteChapter(t *testing.T) {
+	a := testApp(t)
+	test := a.QB().chapterMap["test"]
+	if len(test.Quests) == 0 {
+		t.Skip("test chapter has no quests")
+	}
+	dep := test.Quests[0].ID
+	other := `{
+	id: "00000000000000C1"
+	filename: "other"
+	order_index: 1
+	title: "Other"
+	quests: [
+		{ id: "00000000000000D1", x: 0.0d, y: 0.0d }
+		{ dependencies: ["` + dep + `", "00000000000000D1"], id: "00000000000000D2", min_required_dependencies: 2, x: 1.0d, y: 0.0d }
+		{ dependencies: ["` + dep + `"], id: "00000000000000D3", x: 2.0d, y: 0.0d }
+	]
+}
+`
+	if err := os.WriteFile(a.QB().chapterPath("other"), []byte(other), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+
+	if err := a.QB().DeleteChapter("missing"); err == nil || err.Error() != "unknown chapter missing" {
+		t.Errorf("DeleteChapter(missing) = %v", err)
+	}
+	if err := a.QB().DeleteChapter("test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(a.QB().chapterPath("test")); !os.IsNotExist(err) {
+		t.Errorf("test.snbt still exists: %v", err)
+	}
+	a.reload()
+	qb := a.QB()
+	if qb.chapterMap["test"] != nil {
+		t.Fatal("test chapter still loads")
+	}
+	want := map[string][]string{
+		"00000000000000D1": nil,
+		"00000000000000D2": {"00000000000000D1"},
+		"00000000000000D3": nil,
+	}
+	for id, deps := range want {
+		q := qb.questMap[id]
+		if q == nil {
+			t.Fatalf("quest %s is missing", id)
+		}
+		if !slices.Equal(q.Dependencies, deps) {
+			t.Errorf("%s dependencies = %v, want %v", id, q.Dependencies, deps)
+		}
+	}
+	if q := qb.questMap["00000000000000D2"]; q.MinRequired > len(q.Dependencies) {
+		t.Errorf("D2 requires %d of %d dependencies", q.MinRequired, len(q.Dependencies))
+	}
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+)
+
+// claimFlagRatio is how many times the median chapter's items or XP a
+// chapter can give before it is flagged.
+const claimFlagRatio = 3
+
+// claimTableDepth bounds how deep tables rolling other tables are followed.
+const claimTableDepth = 8
+
+// ClaimTotals are the expected rewards of a set of quests.
+type ClaimTotals struct {
+	Quests int
+	// Items are the expected count of each item.
+	Items map[string]float64
+	// XP and Levels are experience points and levels.
+	XP, Levels float64
+	// Commands are the command rewards, which can't be estimated.
+	Commands float64
+}
+
+// ItemAmount is an expected count of an item.
+type ItemAmount struct {
+	Item  string
+	Count float64
+}
+
+// ItemCount returns the expected number of items of every kind.
+func (t *ClaimTotals) ItemCount() float64 {
+	var n float64
+	for _, c := range t.Items {
+		n += c
+	}
+	return n
+}
+
+// TopItems returns the n items with the largest counts, largest first.
+func (t *ClaimTotals) TopItems(n int) []ItemAmount {
+	var items []ItemAmount
+	for id, c := range t.Items {
+		items = append(items, ItemAmount{id, c})
+	}
+	slices.SortFunc(items, func(a, b ItemAmount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Item, b.Item))
+	})
+	return items[:min(n, len(items))]
+}
+
+// add adds r, given times times, to t.
+func (t *ClaimTotals) add(qb *QuestBook, r Reward, times float64, depth int) {
+	if t.Items == nil {
+		t.Items = make(map[string]float64)
+	}
+	switch r := r.(type) {
+	case *ItemReward:
+		t.Items[r.Item] += times * float64(max(r.Count, 1))
+	case *XPReward:
+		if r.Type == "xp_levels" {
+			t.Levels += times * float64(r.Amount)
+		} else {
+			t.XP += times * float64(r.Amount)
+		}
+	case *CommandReward:
+		t.Commands += times
+	case *LootReward:
+		tb := qb.RewardTable(r)
+		if tb == nil || depth >= claimTableDepth || len(tb.Entries) == 0 {
+			return
+		}
+		if r.Type == "choice" {
+			for _, e := range tb.Entries {
+				t.add(qb, e.Reward, times/float64(len(tb.Entries)), depth+1)
+			}
+			return
+		}
+		rolls := 1.0
+		if r.Type == "loot" {
+			rolls = float64(tb.LootSize)
+		}
+		total := tb.TotalWeight()
+		if total <= 0 {
+			return
+		}
+		for _, e := range tb.Entries {
+			t.add(qb, e.Reward, times*rolls*e.Weight/total, depth+1)
+		}
+	}
+}
+
+// claimQuests returns the rewards of quests.
+func claimQuests(qb *QuestBook, quests []*Quest) ClaimTotals {
+	t := ClaimTotals{Items: make(map[string]float64)}
+	for _, q := range quests {
+		t.Quests++
+		for _, r := range q.Rewards {
+			t.add(qb, r, 1, 0)
+		}
+	}
+	return t
+}
+
+// withDependencies returns quests and every quest they depend on, directly
+// or not, in book order.
+func withDependencies(qb *QuestBook, quests []*Quest) []*Quest {
+	ids := make(map[string]bool)
+	next := func(id string) []string {
+		if q, ok := qb.questMap[id]; ok {
+			return q.Dependencies
+		}
+		return nil
+	}
+	for _, q := range quests {
+		ids[q.ID] = true
+		for id := range dependencyClosure(q.ID, next) {
+			ids[id] = true
+		}
+	}
+	var res []*Quest
+	for _, q := range qb.Quests {
+		if ids[q.ID] {
+			res = append(res, q)
+		}
+	}
+	return res
+}
+
+// ChapterClaim is what finishing a chapter gives.
+type ChapterClaim struct {
+	Chapter *Chapter
+	// Own are the rewards of the chapter's quests, and Total adds those of
+	// the quests in other chapters they depend on.
+	Own, Total ClaimTotals
+	// Ratio is how many times the median chapter's items or XP, whichever
+	// is more, the chapter's quests give.
+	Ratio float64
+}
+
+// Flagged reports whether the chapter gives far more than the others.
+func (c ChapterClaim) Flagged() bool { return c.Ratio >= claimFlagRatio }
+
+// chapterClaims returns what finishing each chapter of qb gives, in chapter
+// order, as if a player claimed every reward at once: the rewards of the
+// chapter's quests and of every quest they depend on, in any chapter. Reward
+// tables count at their expected value: a random reward is one roll, a loot
+// crate its table's loot size, and a choice the average of its entries.
+// Chapters giving several times what the median chapter gives are flagged, as
+// that is usually a balance problem rather than a plan.
+func chapterClaims(qb *QuestBook) []ChapterClaim {
+	defer timeOp(opSearch)()
+	claims := make([]ChapterClaim, len(qb.Chapters))
+	var items, xp []float64
+	for i, ch := range qb.Chapters {
+		c := ChapterClaim{Chapter: ch, Own: claimQuests(qb, ch.Quests)}
+		c.Total = claimQuests(qb, withDependencies(qb, ch.Quests))
+		if n := c.Own.ItemCount(); n > 0 {
+			items = append(items, n)
+		}
+		if c.Own.XP > 0 {
+			xp = append(xp, c.Own.XP)
+		}
+		claims[i] = c
+	}
+	mi, mx := median(items), median(xp)
+	for i := range claims {
+		c := &claims[i]
+		if mi > 0 {
+			c.Ratio = c.Own.ItemCount() / mi
+		}
+		if mx > 0 {
+			c.Ratio = max(c.Ratio, c.Own.XP/mx)
+		}
+	}
+	return claims
+}
+
+// median returns the median of vs, or 0 if there are none.
+func median(vs []float64) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	vs = slices.Sorted(slices.Values(vs))
+	if n := len(vs); n%2 == 0 {
+		return (vs[n/2-1] + vs[n/2]) / 2
+	}
+	return vs[len(vs)/2]
+}
+
+// QuestClaim is what completing a quest gives, alone and with everything it
+// depends on.
+type QuestClaim struct {
+	Quest     *Quest
+	Own, Path ClaimTotals
+}
+
+// questClaims returns what completing each quest of ch gives, in chapter
+// order.
+func questClaims(qb *QuestBook, ch *Chapter) []QuestClaim {
+	defer timeOp(opSearch)()
+	claims := make([]QuestClaim, len(ch.Quests))
+	for i, q := range ch.Quests {
+		claims[i] = QuestClaim{
+			Quest: q,
+			Own:   claimQuests(qb, []*Quest{q}),
+			Path:  claimQuests(qb, withDependencies(qb, []*Quest{q})),
+		}
+	}
+	return claims
+}
+
+// claims handles GET "/claims", and "?chapter=" for a chapter's quests.
+func (a *App) claims(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	data := a.baseData(r, "Claims")
+	data["Chapters"] = chapterClaims(qb)
+	data["FlagRatio"] = claimFlagRatio
+	if name := r.URL.Query().Get("chapter"); name != "" {
+		ch, ok := qb.chapterMap[name]
+		if !ok {
+			http.Error(w, "unknown chapter "+name, http.StatusNotFound)
+			return
+		}
+		data["Chapter"] = ch
+		data["Quests"] = questClaims(qb, ch)
+	}
+	a.render(w, "claims.gohtml", data)
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClaims(t *testing.T) {
+	item := func(id string, n int) Reward {
+		return &ItemReward{RewardBase: RewardBase{Type: "item"}, Item: id, Count: n}
+	}
+	xp := func(n int) Reward { return &XPReward{RewardBase: RewardBase{Type: "xp"}, Amount: n} }
+	tb := &RewardTable{key: "1", LootSize: 2, EmptyWeight: 2, Entries: []*TableEntry{
+		{Reward: item("minecraft:diamond", 1), Weight: 1},
+		{Reward: item("minecraft:coal", 8), Weight: 1},
+	}}
+	roll := func(typ string) Reward { return &LootReward{RewardBase: RewardBase{Type: typ}, TableID: "1"} }
+
+	early := &Chapter{Name: "early"}
+	late := &Chapter{Name: "late"}
+	big := &Chapter{Name: "big"}
+	a1 := &Quest{ID: "A1", Chapter: early, Rewards: []Reward{item("minecraft:coal", 4), xp(10)}}
+	b1 := &Quest{ID: "B1", Chapter: late, Dependencies: []string{"A1"}, Rewards: []Reward{roll("random"), roll("loot"), roll("choice")}}
+	c1 := &Quest{ID: "C1", Chapter: big, Rewards: []Reward{item("minecraft:diamond", 64), xp(10)}}
+	early.Quests, late.Quests, big.Quests = []*Quest{a1}, []*Quest{b1}, []*Quest{c1}
+	qb := &QuestBook{
+		Chapters: []*Chapter{early, late, big},
+		Quests:   []*Quest{a1, b1, c1},
+		questMap: map[string]*Quest{"A1": a1, "B1": b1, "C1": c1},
+		tableMap: map[string]*RewardTable{"1": tb},
+	}
+
+	claims := chapterClaims(qb)
+	// random: one roll at 1/4 each, loot: two rolls, choice: half of each
+	own := claims[1].Own
+	if own.Items["minecraft:diamond"] != 0.25+0.5+0.5 || own.Items["minecraft:coal"] != 8*(0.25+0.5+0.5) {
+		t.Errorf("late items = %v", own.Items)
+	}
+	if total := claims[1].Total; total.Quests != 2 || total.Items["minecraft:coal"] != 14 || total.XP != 10 {
+		t.Errorf("late with prerequisites = %+v", total)
+	}
+	if claims[0].Flagged() || claims[1].Flagged() || !claims[2].Flagged() {
+		t.Errorf("ratios = %v, %v, %v", claims[0].Ratio, claims[1].Ratio, claims[2].Ratio)
+	}
+	if top := claims[2].Own.TopItems(3); len(top) != 1 || top[0].Item != "minecraft:diamond" {
+		t.Errorf("top items = %v", top)
+	}
+	if qs := questClaims(qb, late); qs[0].Path.Quests != 2 || qs[0].Own.Quests != 1 {
+		t.Errorf("quest claims = %+v", qs)
+	}
+
+	a := testApp(t)
+	for _, path := range []string{"/claims", "/claims?chapter=test"} {
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "With prerequisites") {
+			t.Errorf("%s: %d", path, rec.Code)
+		}
+	}
+}
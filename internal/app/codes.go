@@ -0,0 +1,345 @@
+package app
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+// The signs a formatting code can be written with: an ampersand (&a), or the
+// section sign Minecraft itself uses (§a). FTB Quests accepts both.
+const (
+	signAmp     = '&'
+	signSection = '§'
+)
+
+// countCodes returns how many formatting codes in s use & and §.
+func countCodes(s string) (amp, sect int) {
+	rs := []rune(s)
+	for i := 0; i+1 < len(rs); i++ {
+		if !isFormatCode(rs[i+1]) {
+			continue
+		}
+		switch rs[i] {
+		case signAmp:
+			amp++
+			i++
+		case signSection:
+			sect++
+			i++
+		}
+	}
+	return amp, sect
+}
+
+// codeSign returns the sign most codes in texts use, or def if they have
+// none. Ties go to def.
+//
+// Packs tend to pick one sign, often per chapter when chapters come from
+// different authors, so codes qbedit inserts use whichever sign the text
+// around them already uses: the field's own if it has codes, otherwise its
+// chapter's, otherwise &.
+func codeSign(def rune, texts ...string) rune {
+	amp, sect := 0, 0
+	for _, s := range texts {
+		a, s := countCodes(s)
+		amp, sect = amp+a, sect+s
+	}
+	switch {
+	case amp > sect:
+		return signAmp
+	case sect > amp:
+		return signSection
+	}
+	return def
+}
+
+// rawQuestTexts returns the text fields of the quests in a decoded chapter
+// compound.
+func rawQuestTexts(chapter map[string]any) []string {
+	var texts []string
+	for _, qv := range M(chapter).GetAnys("quests") {
+		qm, ok := qv.(map[string]any)
+		if !ok {
+			continue
+		}
+		texts = append(texts, M(qm).GetString("title"), M(qm).GetString("subtitle"))
+		if s := M(qm).GetString("description"); s != "" {
+			texts = append(texts, s)
+		}
+		texts = append(texts, M(qm).GetStrings("description")...)
+	}
+	return texts
+}
+
+// parseColor reads a color to apply: a color code, with or without its sign,
+// or a #rrggbb hex color. It returns the code or the lowercased hex color.
+func parseColor(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimLeft(strings.TrimSpace(s), "&§"))
+	if len(s) == 1 && isColorCode(rune(s[0])) || mcformat.IsHexColor(s) {
+		return s, true
+	}
+	return "", false
+}
+
+// colorCode returns the codes that set color, as returned by parseColor,
+// written with sign.
+func colorCode(color string, sign rune) string {
+	if mcformat.IsHexColor(color) {
+		return mcformat.HexSequence(color, sign)
+	}
+	return string(sign) + color
+}
+
+// colorCodeLen returns the length in runes of the color code at rs[i], a
+// single code or a hex sequence, or 0 if there isn't one.
+func colorCodeLen(rs []rune, i int) int {
+	if _, n := mcformat.HexCode(rs, i); n > 0 {
+		return n
+	}
+	if i+1 < len(rs) && (rs[i] == signAmp || rs[i] == signSection) && isColorCode(rs[i+1]) {
+		return 2
+	}
+	return 0
+}
+
+// colorArg returns the color to post to the recolor endpoints for a Color
+// Manager color key: "c6" for a color code, a #rrggbb color, or "" for text
+// with no color.
+func colorArg(key string) string {
+	return strings.TrimPrefix(key, "c")
+}
+
+// colorLabel returns how a Color Manager color key is written in text.
+func colorLabel(key string) string {
+	if key == "" || mcformat.IsHexColor(key) {
+		return key
+	}
+	return "&" + colorArg(key)
+}
+
+// colorSwatch returns a swatch showing a Color Manager color key.
+func colorSwatch(key string) template.HTML {
+	switch {
+	case key == "":
+		return `<span class="mc-swatch" style="background:transparent;"></span>`
+	case mcformat.IsHexColor(key):
+		return template.HTML(`<span class="mc-swatch" style="background:` + key + `;"></span>`)
+	}
+	return template.HTML(`<span class="mc-swatch mc-b-` + template.HTMLEscapeString(key) + `"></span>`)
+}
+
+// convertSigns rewrites every formatting code in s to use sign.
+func convertSigns(s string, sign rune) string {
+	other := signAmp
+	if sign == signAmp {
+		other = signSection
+	}
+	if !strings.ContainsRune(s, other) {
+		return s
+	}
+	rs := []rune(s)
+	for i := 0; i+1 < len(rs); i++ {
+		if rs[i] == other && isFormatCode(rs[i+1]) {
+			rs[i] = sign
+			i++
+		}
+	}
+	return string(rs)
+}
+
+// SignStat counts a chapter's codes by sign for the Color Manager.
+type SignStat struct {
+	Chapter      *Chapter
+	Amp, Section int
+}
+
+// Mixed reports whether the chapter uses both signs.
+func (s SignStat) Mixed() bool { return s.Amp > 0 && s.Section > 0 }
+
+// signStats counts the codes of every chapter that has any.
+func signStats(qb *QuestBook) []SignStat {
+	var stats []SignStat
+	for _, ch := range qb.Chapters {
+		st := SignStat{Chapter: ch}
+		for _, q := range ch.Quests {
+			for _, f := range searchFields {
+				a, s := countCodes(questField(q, f))
+				st.Amp, st.Section = st.Amp+a, st.Section+s
+			}
+		}
+		if st.Amp+st.Section > 0 {
+			stats = append(stats, st)
+		}
+	}
+	return stats
+}
+
+// normalizeChapter rewrites the codes in the quests of ch, opened by
+// editChapters, to use sign, and returns the ids of the quests it changed.
+func normalizeChapter(ch *Chapter, sign rune) []string {
+	var ids []string
+	for _, q := range ch.Quests {
+		changed := false
+		for _, f := range searchFields {
+			if s := questField(q, f); s != "" {
+				if c := convertSigns(s, sign); c != s {
+					setQuestField(q, f, c)
+					changed = true
+				}
+			}
+		}
+		if changed {
+			ids = append(ids, q.ID)
+		}
+	}
+	return ids
+}
+
+// colorsNormalize handles POST "/colors/normalize", rewriting the codes of
+// one chapter ("chapter") or of every chapter to use "sign" (& or §).
+func (a *App) colorsNormalize(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	sign := []rune(r.Form.Get("sign"))
+	if len(sign) != 1 || (sign[0] != signAmp && sign[0] != signSection) {
+		http.Error(w, "sign must be & or §", http.StatusBadRequest)
+		return
+	}
+	var names []string
+	if name := r.Form.Get("chapter"); name != "" {
+		if _, ok := qb.chapterMap[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		names = []string{name}
+	} else {
+		for _, st := range signStats(qb) {
+			names = append(names, st.Chapter.Name)
+		}
+	}
+
+	edited, err := editChapters(qb, names, func(ch *Chapter) ([]string, error) {
+		return normalizeChapter(ch, sign[0]), nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	converted := a.auditEdits(r, "normalize codes", "to "+string(sign), edited)
+	if converted > 0 {
+		a.reload()
+	}
+	http.Redirect(w, r, "/colors/?msg="+url.QueryEscape(fmt.Sprintf("Converted codes in %d quests to %s", converted, string(sign))), http.StatusSeeOther)
+}
+
+// trailingCodes returns the index in rs of the run of codes (and spaces) that
+// ends it with no text after, or -1 if rs ends with text.
+func trailingCodes(rs []rune) int {
+	start := -1
+	for i := 0; i < len(rs); i++ {
+		if (rs[i] == signAmp || rs[i] == signSection) && i+1 < len(rs) && isFormatCode(rs[i+1]) {
+			if start < 0 {
+				start = i
+			}
+			i++
+			continue
+		}
+		if !unicode.IsSpace(rs[i]) {
+			start = -1
+		}
+	}
+	return start
+}
+
+// stripTrailingCodes removes the codes at the end of line that style no
+// text. A reset among them is kept, since it closes the text before it;
+// a run of resets alone is left as it is.
+func stripTrailingCodes(line string) string {
+	rs := []rune(line)
+	start := trailingCodes(rs)
+	if start < 0 {
+		return line
+	}
+	var reset, spaces []rune
+	styles := false
+	for i := start; i < len(rs); i++ {
+		if unicode.IsSpace(rs[i]) {
+			spaces = append(spaces, rs[i])
+			continue
+		}
+		if unicode.ToLower(rs[i+1]) == 'r' {
+			if reset == nil {
+				reset = rs[i : i+2]
+			}
+		} else {
+			styles = true
+		}
+		i++
+	}
+	if !styles {
+		return line
+	}
+	return string(rs[:start]) + string(reset) + string(spaces)
+}
+
+// stripOrphanSigns removes § signs that don't start a code, and an & that
+// ends the line. An & elsewhere is text, as in "salt & pepper".
+func stripOrphanSigns(line string) string {
+	if !strings.ContainsAny(line, "&§") {
+		return line
+	}
+	rs := []rune(line)
+	out := make([]rune, 0, len(rs))
+	for i := 0; i < len(rs); i++ {
+		if (rs[i] == signAmp || rs[i] == signSection) && i+1 < len(rs) && isFormatCode(rs[i+1]) {
+			out = append(out, rs[i], rs[i+1])
+			i++
+			continue
+		}
+		if rs[i] == signSection || (rs[i] == signAmp && i == len(rs)-1) {
+			continue
+		}
+		out = append(out, rs[i])
+	}
+	return string(out)
+}
+
+// apiConvert handles GET and POST "/api/convert", converting "text" between
+// formatting codes, MiniMessage tags and JSON text components for other
+// tools. "to" is legacy (codes written with "sign", & by default),
+// minimessage or json; the text may be written in any of the three.
+func (a *App) apiConvert(w http.ResponseWriter, r *http.Request) {
+	text := r.FormValue("text")
+	res := map[string]any{"ok": true}
+	switch r.FormValue("to") {
+	case "legacy":
+		sign := signAmp
+		if r.FormValue("sign") == string(signSection) {
+			sign = signSection
+		}
+		res["text"] = mcformat.ToLegacy(text, sign)
+	case "minimessage":
+		res["text"] = mcformat.ToMiniMessage(text)
+	case "json":
+		list := tellrawText(text)
+		b, err := marshalTellraw(list, "")
+		if err != nil {
+			writeError(w, true, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res["text"], res["components"] = string(b), list
+	default:
+		writeError(w, true, "to must be legacy, minimessage or json", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,237 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+func TestCodeSign(t *testing.T) {
+	if amp, sect := countCodes("&aR & D §lx §"); amp != 1 || sect != 1 {
+		t.Errorf("countCodes = %d, %d", amp, sect)
+	}
+	cases := []struct {
+		def   rune
+		texts []string
+		want  rune
+	}{
+		{'&', nil, '&'},
+		{'§', []string{"plain"}, '§'},
+		{'&', []string{"§aone §btwo", "&cthree"}, '§'},
+		{'§', []string{"&aone", "&btwo §r"}, '&'},
+		// ties keep the default
+		{'§', []string{"&a", "§b"}, '§'},
+	}
+	for _, c := range cases {
+		if got := codeSign(c.def, c.texts...); got != c.want {
+			t.Errorf("codeSign(%c, %q) = %c, want %c", c.def, c.texts, got, c.want)
+		}
+	}
+
+	m, _ := newMatcher("ingot", false, false)
+	if got := recolorString("an ingot", m, "c", codeSign('&', "§eGold")); got != "an §cingot§r" {
+		t.Errorf("recolorString = %q", got)
+	}
+}
+
+func TestConvertSigns(t *testing.T) {
+	cases := []struct {
+		in   string
+		sign rune
+		want string
+	}{
+		{"§aR & D &lbold§r", '&', "&aR & D &lbold&r"},
+		{"&aR & D &lbold§r", '§', "§aR & D §lbold§r"},
+		{"no codes & such", '§', "no codes & such"},
+	}
+	for _, c := range cases {
+		if got := convertSigns(c.in, c.sign); got != c.want {
+			t.Errorf("convertSigns(%q, %c) = %q, want %q", c.in, c.sign, got, c.want)
+		}
+	}
+}
+
+func TestBrokenCodes(t *testing.T) {
+	cases := []struct {
+		fix      func(string) string
+		in, want string
+	}{
+		{stripTrailingCodes, "&6Gold", "&6Gold"},
+		{stripTrailingCodes, "&6Gold&r", "&6Gold&r"},
+		{stripTrailingCodes, "&6Gold &r&l", "&6Gold &r"},
+		{stripTrailingCodes, "Gold §6 ", "Gold  "},
+		{stripTrailingCodes, "&l", ""},
+		{stripOrphanSigns, "salt & pepper", "salt & pepper"},
+		{stripOrphanSigns, "Gold &", "Gold "},
+		{stripOrphanSigns, "§zGold §6ingot", "zGold §6ingot"},
+	}
+	for _, c := range cases {
+		if got := c.fix(c.in); got != c.want {
+			t.Errorf("fix(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestHexColors(t *testing.T) {
+	for _, c := range []struct{ in, want string }{
+		{"6", "6"}, {"&E", "e"}, {"#FFaa00", "#ffaa00"},
+	} {
+		if got, ok := parseColor(c.in); !ok || got != c.want {
+			t.Errorf("parseColor(%q) = %q, %t", c.in, got, ok)
+		}
+	}
+	for _, in := range []string{"", "g", "&l", "#ffaa0", "ffaa00"} {
+		if _, ok := parseColor(in); ok {
+			t.Errorf("parseColor(%q) accepted", in)
+		}
+	}
+
+	m, _ := newMatcher("ingot", false, false)
+	cases := []struct{ in, color, want string }{
+		{"an ingot", "#ffaa00", "an &x&f&f&a&a&0&0ingot&r"},
+		{"§ean ingot", "#ffaa00", "§x§f§f§a§a§0§0an ingot"},
+		{"&x&f&f&a&a&0&0an ingot", "c", "&can ingot"},
+		{"§x§f§f§a§a§0§0an ingot", "#00ff00", "§x§0§0§f§f§0§0an ingot"},
+	}
+	for _, c := range cases {
+		if got := recolorString(c.in, m, c.color, '&'); got != c.want {
+			t.Errorf("recolorString(%q, %s) = %q, want %q", c.in, c.color, got, c.want)
+		}
+	}
+	if got := recolorOne("ingot and ingot", m, "#123456", 10, '§'); got != "ingot and §x§1§2§3§4§5§6ingot§r" {
+		t.Errorf("recolorOne = %q", got)
+	}
+
+	html := string(mcformat.Format("&x&f&f&a&a&0&0Gold&r plain"))
+	if !strings.Contains(html, `style="color:#ffaa00">Gold`) || strings.Contains(html, "&amp;x") {
+		t.Errorf("Format hex = %s", html)
+	}
+	html = string(mcformat.Format(`{"text":"Gold","color":"#ffaa00","extra":[{"text":" ingot","bold":true}]}`))
+	if !strings.Contains(html, `style="color:#ffaa00">Gold`) || !strings.Contains(html, `mc-bold" style="color:#ffaa00"> ingot`) {
+		t.Errorf("Format component = %s", html)
+	}
+	html = string(mcformat.Format(`["",{"text":"red","color":"red"},"plain"]`))
+	if !strings.Contains(html, `mc-cc">red`) || !strings.Contains(html, `mc-text">plain`) {
+		t.Errorf("Format component list = %s", html)
+	}
+	if cs := mcformat.Components("§x§1§2§3§4§5§6hex"); len(cs) != 1 || cs[0].Color != "#123456" {
+		t.Errorf("Components = %+v", cs)
+	}
+}
+
+func TestRecolorMany(t *testing.T) {
+	m, _ := newMatcher("ingot", false, false)
+	if got := recolorMatches("ingot, ingot and ingot", m, "c", '&', map[int]bool{0: true, 17: true}); got != "&cingot&r, ingot and &cingot&r" {
+		t.Errorf("recolorMatches = %q", got)
+	}
+
+	a := testApp(t)
+	qs := a.QB().Chapters[0].Quests
+	q0, q1 := qs[0], qs[1]
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range map[string]string{"term": "Classico|suspected", "regex": "1", "color": "c"} {
+		mw.WriteField(k, v)
+	}
+	mw.WriteField("target", q0.ID+":title:-1:3")
+	mw.WriteField("target", q1.ID+":description:0:3")
+	mw.Close()
+	req := httptest.NewRequest("POST", "/colors/recolor_many", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("recolor_many: %d %s", rec.Code, rec.Body)
+	}
+	qm := a.QB().questMap
+	if got := qm[q0.ID].Title; got != "El &cClassico&r" {
+		t.Errorf("title = %q", got)
+	}
+	if got := strings.Split(qm[q1.ID].Description, "\n")[0]; got != "As &csuspected&r!" {
+		t.Errorf("description = %q", got)
+	}
+}
+
+// TestRecolorKeyed recolors text that comes from a lang file, in a quest
+// whose description is a single string.
+func TestRecolorKeyed(t *testing.T) {
+	a := testApp(t)
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := ch.Quests[0]
+	ch.raw["quests"].([]any)[0].(map[string]any)["title"] = "{quest.x.title}"
+	ch.raw["quests"].([]any)[0].(map[string]any)["description"] = "{quest.x.desc}"
+	if err := writeSNBT(path, ch.raw); err != nil {
+		t.Fatal(err)
+	}
+	lang := filepath.Join(t.TempDir(), "en_us.json")
+	if err := os.WriteFile(lang, []byte(`{"quest.x.title": "Iron Age", "quest.x.desc": "Smelt iron.\nThen more iron."}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetLangFile(lang); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(target string, fields map[string][]string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for k, vs := range fields {
+			for _, v := range vs {
+				mw.WriteField(k, v)
+			}
+		}
+		mw.Close()
+		req := httptest.NewRequest("POST", target, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	rec := post("/colors/recolor_many", map[string][]string{
+		"term": {"iron"}, "ci": {"1"}, "color": {"c"},
+		"target": {q.ID + ":title:-1:0", q.ID + ":description:1:10"},
+	})
+	var res struct{ Count int }
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK || res.Count != 2 {
+		t.Fatalf("recolor_many: %d %s", rec.Code, rec.Body)
+	}
+	if rec := post("/colors/recolor_one", map[string][]string{
+		"term": {"iron"}, "ci": {"1"}, "color": {"6"}, "qid": {q.ID}, "field": {"description"}, "didx": {"0"}, "pos": {"6"},
+	}); rec.Code != http.StatusOK {
+		t.Fatalf("recolor_one: %d %s", rec.Code, rec.Body)
+	}
+	if rec := post("/colors/recolor_one", map[string][]string{
+		"term": {"iron"}, "ci": {"1"}, "color": {"6"}, "qid": {q.ID}, "field": {"title"}, "pos": {"9"},
+	}); rec.Code != http.StatusConflict {
+		t.Errorf("recolor_one of no match: %d %s", rec.Code, rec.Body)
+	}
+
+	got := a.QB().questMap[q.ID]
+	if got.Title != "&cIron&r Age" || got.Description != "Smelt &6iron&r.\nThen more &ciron&r." {
+		t.Errorf("recolored %q, %q", got.Title, got.Description)
+	}
+	// the text went to the lang file, and the chapter keeps the keys
+	l, err := LoadLangFile(lang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := l.Get("quest.x.title"); v != "&cIron&r Age" {
+		t.Errorf("lang title %q", v)
+	}
+	if b, _ := os.ReadFile(path); !strings.Contains(string(b), "{quest.x.desc}") {
+		t.Errorf("chapter lost the description key:\n%s", b)
+	}
+}
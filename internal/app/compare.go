@@ -0,0 +1,162 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// QuestPair is a quest from the primary book and its counterpart in the other.
+type QuestPair struct {
+	A, B *Quest
+	// MatchedBy is "id" or "title"
+	MatchedBy string
+	// Fields lists the text fields that differ (title, subtitle, description)
+	Fields []string
+	// RewardsA and RewardsB summarize rewards when they differ
+	RewardsA, RewardsB []string
+}
+
+// BookComparison is the result of comparing two quest books.
+type BookComparison struct {
+	Matched int
+	Pairs   []QuestPair // only pairs that diverge
+	OnlyA   []*Quest
+	OnlyB   []*Quest
+}
+
+// rewardSummary returns a short, comparable description of a reward.
+func rewardSummary(r Reward) string {
+	s := r.Base().Type
+	if v := r.FormValue(); v != "" {
+		s += " " + v
+	}
+	if n := r.FormCount(); n > 1 {
+		s += fmt.Sprintf(" x%d", n)
+	}
+	return s
+}
+
+func rewardSummaries(q *Quest) []string {
+	ss := make([]string, 0, len(q.Rewards))
+	for _, r := range q.Rewards {
+		ss = append(ss, rewardSummary(r))
+	}
+	slices.Sort(ss)
+	return ss
+}
+
+// titleKey normalizes a quest title for matching across books.
+func titleKey(q *Quest) string {
+	return strings.ToLower(strings.TrimSpace(stripCodes(q.GetTitle())))
+}
+
+// compareBooks aligns the quests of a and b by id, falling back to their
+// title, and reports where text or rewards have drifted apart. Packs with a
+// normal and an expert (or hard) mode often keep two quest books that are
+// meant to stay in step.
+func compareBooks(a, b *QuestBook) *BookComparison {
+	res := &BookComparison{}
+
+	byTitle := make(map[string][]*Quest)
+	for _, q := range b.Quests {
+		if k := titleKey(q); k != "" {
+			byTitle[k] = append(byTitle[k], q)
+		}
+	}
+
+	used := make(map[*Quest]bool)
+	for _, qa := range a.Quests {
+		qb, by := b.questMap[qa.ID], "id"
+		if qb == nil || used[qb] {
+			qb, by = nil, "title"
+			for _, cand := range byTitle[titleKey(qa)] {
+				if !used[cand] {
+					qb = cand
+					break
+				}
+			}
+		}
+		if qb == nil {
+			res.OnlyA = append(res.OnlyA, qa)
+			continue
+		}
+		used[qb] = true
+		res.Matched++
+
+		p := QuestPair{A: qa, B: qb, MatchedBy: by}
+		if qa.Title != qb.Title {
+			p.Fields = append(p.Fields, "title")
+		}
+		if qa.Subtitle != qb.Subtitle {
+			p.Fields = append(p.Fields, "subtitle")
+		}
+		if qa.Description != qb.Description {
+			p.Fields = append(p.Fields, "description")
+		}
+		ra, rb := rewardSummaries(qa), rewardSummaries(qb)
+		if !slices.Equal(ra, rb) {
+			p.RewardsA, p.RewardsB = ra, rb
+		}
+		if len(p.Fields) > 0 || p.RewardsA != nil || p.RewardsB != nil {
+			res.Pairs = append(res.Pairs, p)
+		}
+	}
+
+	for _, q := range b.Quests {
+		if !used[q] {
+			res.OnlyB = append(res.OnlyB, q)
+		}
+	}
+	return res
+}
+
+// FieldDiff compares one field of two quests for the side by side editor.
+type FieldDiff struct {
+	Name  string
+	Label string
+	A, B  string
+	Same  bool
+	// Editable fields can be copied from one side to the other.
+	Editable bool
+	// Lines is a line diff from A to B for multi-line fields that differ.
+	Lines []diffOp
+}
+
+// questFieldDiffs compares the fields of a and b.
+func questFieldDiffs(a, b *Quest) []FieldDiff {
+	field := func(name, label, va, vb string, editable bool) FieldDiff {
+		d := FieldDiff{Name: name, Label: label, A: va, B: vb, Same: va == vb, Editable: editable}
+		if !d.Same && (strings.Contains(va, "\n") || strings.Contains(vb, "\n")) {
+			d.Lines = diffLines(splitLines(va), splitLines(vb))
+		}
+		return d
+	}
+	return []FieldDiff{
+		field("title", "Title", a.Title, b.Title, true),
+		field("subtitle", "Subtitle", a.Subtitle, b.Subtitle, true),
+		field("description", "Description", a.Description, b.Description, true),
+		field("tasks", "Tasks", strings.Join(taskSummaries(a), "\n"), strings.Join(taskSummaries(b), "\n"), false),
+		field("rewards", "Rewards", strings.Join(rewardSummaries(a), "\n"), strings.Join(rewardSummaries(b), "\n"), false),
+	}
+}
+
+// taskSummary returns a short description of a task, like rewardSummary.
+func taskSummary(t Task) string {
+	s := t.Base().Type
+	if v := t.FormValue(); v != "" {
+		s += " " + v
+	}
+	if n := t.FormCount(); n > 1 {
+		s += fmt.Sprintf(" x%d", n)
+	}
+	return s
+}
+
+func taskSummaries(q *Quest) []string {
+	ss := make([]string, 0, len(q.Tasks))
+	for _, t := range q.Tasks {
+		ss = append(ss, taskSummary(t))
+	}
+	return ss
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompareRoot(t *testing.T) {
+	a, other := testApp(t), testApp(t)
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", target, rec.Code, rec.Body)
+		}
+		return rec.Body.String()
+	}
+	if body := get("/compare?root=" + other.Root()); strings.Contains(body, "quests matched") {
+		t.Error("?root= was compared")
+	}
+	a.CompareRoot = other.Root()
+	if body := get("/compare"); !strings.Contains(body, "quests matched") {
+		t.Error("CompareRoot was not compared")
+	}
+}
+
+func TestQuestCompareOther(t *testing.T) {
+	a, other := testApp(t), testApp(t)
+	q := a.QB().Quests[0]
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", target, rec.Code, rec.Body)
+		}
+		return rec.Body.String()
+	}
+	if body := get("/compare/quest?a=" + q.ID + "&root=" + other.Root()); !strings.Contains(body, "choose two different quests") {
+		t.Error("?root= was read")
+	}
+	if body := get("/compare/quest?a=" + q.ID + "&other=1"); !strings.Contains(body, "choose two different quests") {
+		t.Error("?other=1 without CompareRoot was read")
+	}
+	a.CompareRoot = other.Root()
+	if body := get("/compare/quest?a=" + q.ID + "&other=1"); strings.Contains(body, "choose two different quests") || !strings.Contains(body, "cmp-form-a") {
+		t.Error("quest was not compared with CompareRoot's")
+	}
+}
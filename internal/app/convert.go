@@ -0,0 +1,58 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// convertSNBT returns s, SNBT, as JSON.
+func convertSNBT(s string) (string, error) {
+	v, err := snbt.Decode(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	b, err := snbt.ToJSON(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// convertJSON returns s, JSON, as SNBT in the style FTB Quests writes.
+func convertJSON(s string) (string, error) {
+	v, err := snbt.FromJSON([]byte(s))
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := snbt.EncodeIndent(&buf, v); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// convert handles GET and POST "/convert", which turns SNBT, such as an
+// item's NBT from a reward, into indented JSON and back, for reading it or
+// editing it with JSON tools. A POST converts "snbt" to JSON, or "json" to
+// SNBT with "to=snbt". The numbers' types survive the round trip as tags; see
+// snbt.ToJSON.
+func (a *App) convert(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Convert")
+	in, out := r.FormValue("snbt"), r.FormValue("json")
+	if r.Method == http.MethodPost {
+		var err error
+		if r.FormValue("to") == "snbt" {
+			in, err = convertJSON(out)
+		} else {
+			out, err = convertSNBT(in)
+		}
+		if err != nil {
+			data["Error"] = err.Error()
+		}
+	}
+	data["SNBT"], data["JSON"] = in, out
+	a.render(w, "convert.gohtml", data)
+}
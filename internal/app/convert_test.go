@@ -0,0 +1,38 @@
+package app
+
+import (
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	a := testApp(t)
+	post := func(form url.Values) string {
+		req := httptest.NewRequest("POST", "/convert", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("convert: %d %s", rec.Code, rec.Body)
+		}
+		return html.UnescapeString(rec.Body.String())
+	}
+
+	body := post(url.Values{"snbt": {`{ id: "minecraft:diamond_sword", Count: 1b, tag: { Damage: 5, Unbreakable: 1b } }`}, "to": {"json"}})
+	if !strings.Contains(body, `"Unbreakable": {`+"\n") || !strings.Contains(body, `"$b": 1`) {
+		t.Errorf("to json:\n%s", body)
+	}
+	body = post(url.Values{"json": {`{"Count": {"$b": 1}, "x": 1.5, "$$k": "v"}`}, "to": {"snbt"}})
+	for _, want := range []string{"Count: 1b", "x: 1.5d", `"$k": "v"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("to snbt lacks %s:\n%s", want, body)
+		}
+	}
+	if body := post(url.Values{"snbt": {`{ broken`}, "to": {"json"}}); !strings.Contains(body, "Error:") {
+		t.Error("a parse error isn't shown")
+	}
+}
@@ -0,0 +1,230 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// Kinds of cosmetic field values.
+const (
+	kindBool   = "bool"
+	kindInt    = "int"
+	kindDouble = "double"
+	kindString = "string"
+)
+
+// cosmeticField is a known cosmetic field. Bools default to false and are
+// removed rather than set false; the others are removed when cleared.
+type cosmeticField struct {
+	Key, Label, Kind string
+	// Choices are the allowed values of a string field, if limited.
+	Choices []string
+}
+
+// cosmeticFields are the known cosmetic quest fields: shape, size, whether
+// dependency lines are drawn and so on, which don't change what a quest asks
+// for or gives. FTB Quests keeps adding them, so rather than a struct field
+// each they are edited directly in the quest's raw compound, and any other
+// scalar the editor doesn't model is shown too, typed by the kind of value it
+// holds.
+var cosmeticFields = []cosmeticField{
+	{"shape", "Shape", kindString, []string{"circle", "square", "rsquare", "diamond", "pentagon", "hexagon", "octagon", "heart", "gear"}},
+	{"size", "Size", kindDouble, nil},
+	{"icon_scale", "Icon scale", kindDouble, nil},
+	{"min_width", "Min width", kindInt, nil},
+	{"text_shadow", "Text shadow", kindBool, nil},
+	{"hide_dependency_lines", "Hide dependency lines", kindBool, nil},
+	{"hide_dependent_lines", "Hide dependent lines", kindBool, nil},
+	{"hide_lock_icon", "Hide lock icon", kindBool, nil},
+	{"hide_text_until_complete", "Hide text until complete", kindBool, nil},
+	{"hide_until_deps_visible", "Hide until dependencies are visible", kindBool, nil},
+	{"hide_details_until_startable", "Hide details until startable", kindBool, nil},
+	{"disable_toast", "Disable toast", kindBool, nil},
+	{"invisible", "Invisible", kindBool, nil},
+	{"invisible_until_tasks", "Invisible until tasks done", kindInt, nil},
+	{"optional", "Optional", kindBool, nil},
+	{"guide_page", "Guide page", kindString, nil},
+}
+
+// modeledKeys are quest keys edited elsewhere in the editor, or positions,
+// which aren't edited as numbers.
+var modeledKeys = []string{
+	"id", "title", "subtitle", "description", "dependencies", "min_required_dependencies",
+	"can_repeat", "repeat_cooldown", "tasks", "rewards", "x", "y",
+}
+
+// CosmeticValue is a cosmetic field of a quest as shown in the editor.
+type CosmeticValue struct {
+	cosmeticField
+	// Value is the field's value as text; Set is whether the quest has it.
+	Value string
+	Set   bool
+}
+
+// Checked reports whether a bool field is on.
+func (v CosmeticValue) Checked() bool { return v.Value == "true" }
+
+// valueKind returns the kind of a decoded SNBT value, or "" if it isn't a
+// scalar.
+func valueKind(v any) string {
+	switch v.(type) {
+	case bool, snbt.Byte:
+		// bytes are almost always flags, eg. 1b
+		return kindBool
+	case string:
+		return kindString
+	case int, int64, snbt.Long, snbt.Short:
+		return kindInt
+	case float64, snbt.Decimal, snbt.FloatNum:
+		return kindDouble
+	}
+	return ""
+}
+
+// formatScalar renders a scalar value as it is edited.
+func formatScalar(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case float64, snbt.Decimal, snbt.FloatNum:
+		return strconv.FormatFloat(M{"v": x}.GetFloat("v"), 'f', -1, 64)
+	}
+	return strconv.Itoa(anyToInt(v))
+}
+
+// cosmeticFieldFor returns the field for key: a known one, or one typed by the
+// quest's value for key. ok is false if key is modeled elsewhere or isn't a
+// scalar.
+func cosmeticFieldFor(raw map[string]any, key string) (cosmeticField, bool) {
+	if i := slices.IndexFunc(cosmeticFields, func(f cosmeticField) bool { return f.Key == key }); i >= 0 {
+		return cosmeticFields[i], true
+	}
+	if slices.Contains(modeledKeys, key) {
+		return cosmeticField{}, false
+	}
+	kind := valueKind(raw[key])
+	if kind == "" {
+		return cosmeticField{}, false
+	}
+	return cosmeticField{Key: key, Label: strings.ReplaceAll(key, "_", " "), Kind: kind}, true
+}
+
+// cosmeticKeys returns the keys of the known cosmetic fields followed by
+// the other unmodeled scalars in any of raws, sorted.
+func cosmeticKeys(raws ...map[string]any) []string {
+	keys := make([]string, 0, len(cosmeticFields))
+	for _, f := range cosmeticFields {
+		keys = append(keys, f.Key)
+	}
+	var extra []string
+	for _, raw := range raws {
+		for k := range raw {
+			if f, ok := cosmeticFieldFor(raw, k); ok && !slices.Contains(keys, f.Key) && !slices.Contains(extra, k) {
+				extra = append(extra, k)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(keys, extra...)
+}
+
+// cosmeticValues returns q's cosmetic fields for the editor.
+func cosmeticValues(q *Quest) []CosmeticValue {
+	var vs []CosmeticValue
+	for _, k := range cosmeticKeys(q.raw) {
+		f, _ := cosmeticFieldFor(q.raw, k)
+		v := CosmeticValue{cosmeticField: f}
+		if x, ok := q.raw[k]; ok {
+			v.Value, v.Set = formatScalar(x), true
+			if f.Kind == kindBool {
+				// older files write flags as bytes, eg. 1b
+				v.Value = strconv.FormatBool(M(q.raw).GetBool(k))
+			}
+		}
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+// cosmeticFromForm updates q's cosmetic fields from the "cf.<key>" form
+// fields of the keys listed in "cf_key". Numbers keep the SNBT type they
+// were written with; new doubles are written as doubles (eg. 1.5d), which is
+// what FTB Quests writes.
+func cosmeticFromForm(q *Quest, form url.Values) error {
+	for _, key := range form["cf_key"] {
+		f, ok := cosmeticFieldFor(q.raw, key)
+		if !ok {
+			continue
+		}
+		old, had := q.raw[key]
+		value := strings.TrimSpace(form.Get("cf." + key))
+		if f.Kind == kindBool {
+			switch {
+			case value != "":
+				if !M(q.raw).GetBool(key) {
+					M(q.raw).SetBool(key, true)
+				}
+			case !had:
+			case slices.ContainsFunc(cosmeticFields, func(c cosmeticField) bool { return c.Key == key }):
+				delete(q.raw, key)
+			default:
+				// we don't know this field's default, so say false explicitly
+				M(q.raw).SetBool(key, false)
+			}
+			continue
+		}
+		if value == "" {
+			delete(q.raw, key)
+			continue
+		}
+		switch f.Kind {
+		case kindInt:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a whole number", f.Label, value)
+			}
+			if had && anyToInt(old) == n {
+				continue
+			}
+			M(q.raw).SetInt(key, n)
+		case kindDouble:
+			x, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", f.Label, value)
+			}
+			if had && M(q.raw).GetFloat(key) == x {
+				continue
+			}
+			q.raw[key] = floatLike(old, x)
+		case kindString:
+			if len(f.Choices) > 0 && !slices.Contains(f.Choices, value) && value != formatScalar(old) {
+				return fmt.Errorf("%s: unknown value %q", f.Label, value)
+			}
+			q.raw[key] = value
+		}
+	}
+	return nil
+}
+
+// floatLike returns x as the same kind of SNBT number as old, or as a
+// double if old isn't a float.
+func floatLike(old any, x float64) any {
+	d := decimalValue(x)
+	switch o := old.(type) {
+	case float64:
+		return x
+	case snbt.FloatNum:
+		return snbt.FloatNum{Sign: d.Sign, Int: d.Int, Frac: d.Frac, Suffix: o.Suffix}
+	case snbt.Decimal:
+		d.Suffix = o.Suffix
+	}
+	return d
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+func TestCosmeticFromForm(t *testing.T) {
+	v, err := snbt.Decode(strings.NewReader(`{id: "A", x: 1.0d, size: 1.5d, icon_scale: 2.0f, min_width: 3L, hide_lock_icon: 1b, glow: true, custom_level: 4, custom: "x", custom_flag: 0b}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := NewQuest(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals := cosmeticValues(q)
+	byKey := make(map[string]CosmeticValue)
+	for _, cv := range vals {
+		byKey[cv.Key] = cv
+	}
+	if _, ok := byKey["x"]; ok {
+		t.Error("positions shouldn't be cosmetic fields")
+	}
+	if cv := byKey["custom_level"]; cv.Kind != kindInt || cv.Value != "4" {
+		t.Errorf("custom_level: %+v", cv)
+	}
+	if !byKey["hide_lock_icon"].Checked() || !byKey["glow"].Checked() {
+		t.Error("flags should be checked")
+	}
+
+	form := url.Values{"cf_key": cosmeticKeys(q.raw)}
+	form.Set("cf.size", "2")
+	form.Set("cf.icon_scale", "0.5")
+	form.Set("cf.min_width", "3")
+	form.Set("cf.shape", "heart")
+	form.Set("cf.custom_level", "")
+	form.Set("cf.custom", "y")
+	form.Set("cf.optional", "1")
+	form.Set("cf.custom_flag", "1")
+	// hide_lock_icon and glow are unchecked
+	if err := cosmeticFromForm(q, form); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := snbt.Encode(&buf, q.raw); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{"size: 2.0d", "icon_scale: 0.5f", "min_width: 3l", `shape: "heart"`, `custom: "y"`, "optional: true", "glow: false", "custom_flag: 1b"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %s", want, got)
+		}
+	}
+	for _, gone := range []string{"custom_level", "hide_lock_icon"} {
+		if strings.Contains(got, gone) {
+			t.Errorf("%s should be removed: %s", gone, got)
+		}
+	}
+
+	form.Set("cf.shape", "blob")
+	if err := cosmeticFromForm(q, form); err == nil {
+		t.Error("expected an error for an unknown shape")
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+// cvdMode returns the deficiency previewed for r: ?cvd= for this render,
+// then the cvd cookie set by the sidebar picker, or "" for none.
+func cvdMode(r *http.Request) mcformat.Deficiency {
+	v := r.URL.Query().Get("cvd")
+	if v == "" {
+		if c, err := r.Cookie("cvd"); err == nil {
+			v = c.Value
+		}
+	}
+	d, _ := mcformat.ParseDeficiency(v)
+	return d
+}
+
+// hexColorRe matches the inline colors of formatted text.
+var hexColorRe = regexp.MustCompile(`color:(#[0-9a-fA-F]{6})`)
+
+// bookHexColors returns the hex colors used in the text of qb, lowercased
+// and sorted.
+func bookHexColors(qb *QuestBook) []string {
+	seen := make(map[string]bool)
+	add := func(s string) {
+		// only formatted text can set a hex color
+		if !strings.ContainsAny(s, "§&<{") {
+			return
+		}
+		for _, m := range hexColorRe.FindAllStringSubmatch(string(mcformat.Format(s)), -1) {
+			seen[strings.ToLower(m[1])] = true
+		}
+	}
+	for _, ch := range qb.Chapters {
+		add(ch.Title)
+		for _, q := range ch.Quests {
+			add(q.Title)
+			add(q.Subtitle)
+			add(q.Description)
+		}
+	}
+	var colors []string
+	for c := range seen {
+		colors = append(colors, c)
+	}
+	slices.Sort(colors)
+	return colors
+}
+
+// cvdCSS handles GET "/cvd.css?type=", the stylesheet that previews text as
+// it appears with the color vision deficiency type, with which a red "danger"
+// and a green "safe" can look alike. It restyles the color code classes and
+// every hex color the book's text uses, which are rendered as inline styles
+// and so are overridden by attribute.
+func (a *App) cvdCSS(w http.ResponseWriter, r *http.Request) {
+	d, ok := mcformat.ParseDeficiency(r.URL.Query().Get("type"))
+	if !ok {
+		http.Error(w, "unknown color vision deficiency", http.StatusBadRequest)
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "/* colors as they appear with %s */\n", d)
+	palette := mcformat.Palette(d)
+	classes := make([]string, 0, len(palette))
+	for class := range palette {
+		classes = append(classes, class)
+	}
+	slices.Sort(classes)
+	for _, class := range classes {
+		fmt.Fprintf(&b, ".mc-%s { color: %s; }\n", class, palette[class])
+		fmt.Fprintf(&b, ".mc-b-%s { background-color: %s; }\n", class, palette[class])
+	}
+	for _, hex := range bookHexColors(a.QB()) {
+		fmt.Fprintf(&b, ".mc-text[style=\"color:%s\" i] { color: %s !important; }\n", hex, mcformat.Simulate(hex, d))
+		fmt.Fprintf(&b, ".mc-swatch[style=\"background:%s;\" i] { background: %s !important; }\n", hex, mcformat.Simulate(hex, d))
+	}
+	// the book's colors change as it is edited
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
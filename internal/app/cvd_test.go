@@ -0,0 +1,60 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+func TestCVDPreview(t *testing.T) {
+	a := testApp(t)
+	q := a.QB().Chapters[0].Quests[0]
+	q.Subtitle = "&x&F&F&0&0&0&0Danger"
+	q.Description = "<gradient:#00ff00:#0000ff>ok</gradient>"
+	h := a.Router()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/cvd.css?type=deuteranopia", nil))
+	css := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("status %d, %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		".mc-cc { color: " + mcformat.Simulate("#ff5555", mcformat.Deuteranopia) + "; }",
+		`.mc-text[style="color:#ff0000" i] { color: ` + mcformat.Simulate("#ff0000", mcformat.Deuteranopia) + " !important; }",
+		`.mc-text[style="color:#00ff00" i]`,
+		`.mc-text[style="color:#0000ff" i]`,
+	} {
+		if !strings.Contains(css, want) {
+			t.Errorf("missing %s in:\n%s", want, css)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/cvd.css?type=blurry", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown type: status %d", rec.Code)
+	}
+
+	// pages link the stylesheet for the mode in the cookie
+	page := func(cookie string) string {
+		req := httptest.NewRequest("GET", "/chapter/test/"+q.ID, nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "cvd", Value: cookie})
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+	if body := page("tritanopia"); !strings.Contains(body, `href="/cvd.css?type=tritanopia"`) || !strings.Contains(body, "cvd-banner") {
+		t.Error("tritanopia page doesn't link the stylesheet")
+	}
+	for _, cookie := range []string{"", "nonsense"} {
+		if body := page(cookie); strings.Contains(body, "/cvd.css") || strings.Contains(body, "cvd-banner") {
+			t.Errorf("cookie %q: page links the stylesheet", cookie)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// setDependencies replaces q's dependencies with ids, in order, and sets its
+// min_required_dependencies. Dependency order matters to FTB Quests, so ids
+// are kept as given apart from dropping blanks and duplicates. Every id added
+// must be a quest in qb other than q itself; ids q already depends on may be
+// kept even if their quest is gone, so a dangling dependency doesn't stop
+// the quest from being saved. minRequired must be between 0 (all
+// dependencies) and the number of dependencies.
+func setDependencies(qb *QuestBook, q *Quest, ids []string, minRequired int) error {
+	deps := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if id == q.ID {
+			return fmt.Errorf("quest %s cannot depend on itself", q.ID)
+		}
+		if _, ok := qb.questMap[id]; !ok && !slices.Contains(q.Dependencies, id) {
+			return fmt.Errorf("unknown dependency %s", id)
+		}
+		seen[id] = true
+		deps = append(deps, id)
+	}
+	if minRequired < 0 || minRequired > len(deps) {
+		return fmt.Errorf("min required must be between 0 and %d, got %d", len(deps), minRequired)
+	}
+	q.Dependencies = deps
+	q.MinRequired = minRequired
+	return nil
+}
+
+// dependenciesFromForm applies the ordered "dependency" and "min_required"
+// form fields to q.
+func dependenciesFromForm(qb *QuestBook, q *Quest, form url.Values) error {
+	minRequired := 0
+	if s := strings.TrimSpace(form.Get("min_required")); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid min required %q", s)
+		}
+		minRequired = n
+	}
+	return setDependencies(qb, q, form["dependency"], minRequired)
+}
+
+// dependents returns the quests that depend on the quest id, in book order.
+func (qb *QuestBook) dependents(id string) []*Quest {
+	var qs []*Quest
+	for _, q := range qb.Quests {
+		if slices.Contains(q.Dependencies, id) {
+			qs = append(qs, q)
+		}
+	}
+	return qs
+}
+
+// dependencyPath returns the ids of a chain of dependencies leading from the
+// quest from to the quest to, both included, or nil if to is not among from's
+// dependencies, direct or not. The chain is one of the shortest.
+func dependencyPath(qb *QuestBook, from, to string) []string {
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == to {
+			var path []string
+			for ; id != ""; id = prev[id] {
+				path = append(path, id)
+			}
+			slices.Reverse(path)
+			return path
+		}
+		q, ok := qb.questMap[id]
+		if !ok {
+			continue
+		}
+		for _, d := range q.Dependencies {
+			if _, seen := prev[d]; !seen {
+				prev[d] = id
+				queue = append(queue, d)
+			}
+		}
+	}
+	return nil
+}
+
+// requiredDependencies returns how many of q's dependencies must be
+// completed before it can be started: all of them, min_required_dependencies
+// of them, or one if its dependency_requirement is one_completed or
+// one_started.
+func requiredDependencies(q *Quest) int {
+	n := len(q.Dependencies)
+	if strings.HasPrefix(M(q.raw).GetString("dependency_requirement"), "one_") {
+		return min(n, 1)
+	}
+	if q.MinRequired > 0 {
+		return min(n, q.MinRequired)
+	}
+	return n
+}
+
+// unreachableQuests returns the quests of qb that can never be started, by
+// id, with the reason: they depend on a quest that doesn't exist, are in a
+// dependency cycle, or depend on other unreachable quests. Quests without
+// dependencies are where a player starts, and a quest is reachable once
+// enough of its dependencies are.
+func unreachableQuests(qb *QuestBook) map[string]string {
+	dependents := make(map[string][]*Quest)
+	have := make(map[string]int)
+	reached := make(map[string]bool)
+	var queue []*Quest
+	for _, q := range qb.Quests {
+		if requiredDependencies(q) == 0 {
+			reached[q.ID] = true
+			queue = append(queue, q)
+		}
+		for _, d := range slices.Compact(slices.Sorted(slices.Values(q.Dependencies))) {
+			dependents[d] = append(dependents[d], q)
+		}
+	}
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		for _, d := range dependents[q.ID] {
+			if have[d.ID]++; !reached[d.ID] && have[d.ID] >= requiredDependencies(d) {
+				reached[d.ID] = true
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	reasons := make(map[string]string)
+	for _, q := range qb.Quests {
+		if reached[q.ID] {
+			continue
+		}
+		var unknown, blocked []string
+		for _, d := range q.Dependencies {
+			if _, ok := qb.questMap[d]; !ok {
+				unknown = append(unknown, d)
+			} else if !reached[d] {
+				blocked = append(blocked, d)
+			}
+		}
+		switch {
+		case len(unknown) > 0:
+			reasons[q.ID] = "depends on unknown quest " + strings.Join(unknown, ", ")
+		case slices.ContainsFunc(blocked, func(d string) bool { return dependencyPath(qb, d, q.ID) != nil }):
+			for _, d := range blocked {
+				if path := dependencyPath(qb, d, q.ID); path != nil {
+					reasons[q.ID] = "is in a dependency cycle: " + strings.Join(questTitles(qb, append([]string{q.ID}, path...)), " → ")
+					break
+				}
+			}
+		default:
+			reasons[q.ID] = "depends on unreachable quest " + strings.Join(questTitles(qb, blocked), ", ")
+		}
+	}
+	return reasons
+}
+
+// questTitles returns the titles of the quests ids, or the id of one
+// without a title.
+func questTitles(qb *QuestBook, ids []string) []string {
+	titles := make([]string, len(ids))
+	for i, id := range ids {
+		titles[i] = id
+		if q, ok := qb.questMap[id]; ok && q.GetTitle() != "" {
+			titles[i] = stripCodes(q.GetTitle())
+		}
+	}
+	return titles
+}
+
+// DependencySuggestion is a quest suggested as a dependency, and why.
+type DependencySuggestion struct {
+	Quest   *Quest
+	Reasons []string
+	score   int
+}
+
+// maxDependencySuggestions is how many suggestions are made for a quest.
+const maxDependencySuggestions = 5
+
+// shapeWords are item name words for shapes and forms rather than materials.
+var shapeWords = map[string]bool{
+	"ingot": true, "ingots": true, "nugget": true, "block": true, "dust": true, "plate": true,
+	"gear": true, "rod": true, "wire": true, "ore": true, "raw": true, "sheet": true,
+	"tiny": true, "small": true, "large": true, "casing": true, "deepslate": true,
+	"sword": true, "pickaxe": true, "axe": true, "shovel": true, "hoe": true,
+	"helmet": true, "chestplate": true, "leggings": true, "boots": true,
+	"stairs": true, "slab": true, "wall": true, "fence": true, "gate": true, "door": true,
+	"bucket": true, "item": true, "upgrade": true, "tier": true, "basic": true, "advanced": true,
+}
+
+// baseShapes are the shapes a material usually comes in before it is made
+// into anything.
+var baseShapes = []string{"ingot", "ingots", "nugget", "dust", "raw", "ore"}
+
+// plainerItem reports whether the item id is likely had before the item
+// than: it is a base shape of its material and than isn't, or its name has
+// fewer words.
+func plainerItem(id, than string) bool {
+	words := func(id string) []string {
+		_, name, ok := strings.Cut(strings.ToLower(id), ":")
+		if !ok {
+			name = strings.ToLower(id)
+		}
+		return strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '/' || r == '.' || r == '-' })
+	}
+	a, b := words(id), words(than)
+	base := func(ws []string) bool {
+		return slices.ContainsFunc(ws, func(w string) bool { return slices.Contains(baseShapes, w) })
+	}
+	if base(a) != base(b) {
+		return base(a)
+	}
+	return len(a) < len(b)
+}
+
+// itemWords returns the words of the name of the item id, without its
+// namespace, that could name a material.
+func itemWords(id string) []string {
+	_, name, ok := strings.Cut(strings.ToLower(id), ":")
+	if !ok {
+		name = strings.ToLower(id)
+	}
+	var words []string
+	for _, w := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '/' || r == '.' || r == '-' }) {
+		if len(w) < 3 || shapeWords[w] || strings.Trim(w, "0123456789") == "" || slices.Contains(words, w) {
+			continue
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+// questItems returns the ids of the items q's tasks ask for and its rewards
+// give.
+func questItems(q *Quest) (tasks, rewards []string) {
+	for _, t := range q.Tasks {
+		if it, ok := t.(*ItemTask); ok && it.Item != "" {
+			tasks = append(tasks, it.Item)
+		}
+	}
+	for _, r := range q.Rewards {
+		if it, ok := r.(*ItemReward); ok && it.Item != "" {
+			rewards = append(rewards, it.Item)
+		}
+	}
+	return tasks, rewards
+}
+
+// dependencyClosure returns the ids of the quests reachable from id by next,
+// not including id.
+func dependencyClosure(id string, next func(id string) []string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := []string{id}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, n := range next(cur) {
+			if !seen[n] && n != id {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return seen
+}
+
+// suggestDependencies returns the quests that q probably depends on but
+// doesn't, best first: quests rewarding an item q asks for and, more loosely,
+// quests asking for or giving a plainer item of the same material, eg.
+// mekanism:ingot_steel for mekanism:steel_casing. Materials are the words of
+// item names that few items share, counted over items, the item registry, or
+// over the book's items when it is nil. Quests q already depends on, directly
+// or not, and quests depending on q are never suggested.
+func suggestDependencies(qb *QuestBook, q *Quest, items *ItemRegistry) []DependencySuggestion {
+	defer timeOp(opSearch)()
+	wanted, _ := questItems(q)
+	if len(wanted) == 0 {
+		return nil
+	}
+	depsOf := func(id string) []string {
+		if d, ok := qb.questMap[id]; ok {
+			return d.Dependencies
+		}
+		return nil
+	}
+	ancestors := dependencyClosure(q.ID, depsOf)
+	descendants := dependencyClosure(q.ID, func(id string) []string {
+		var ids []string
+		for _, d := range qb.dependents(id) {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	})
+
+	// a word is a material if few items have it
+	words, total := make(map[string]int), 0
+	if items != nil && items.Len() > 0 {
+		words, total = items.words, items.Len()
+	} else {
+		seen := make(map[string]bool)
+		for _, other := range qb.Quests {
+			ts, rs := questItems(other)
+			for _, id := range append(ts, rs...) {
+				if !seen[id] {
+					seen[id] = true
+					total++
+					for _, w := range itemWords(id) {
+						words[w]++
+					}
+				}
+			}
+		}
+	}
+	material := func(w string) bool { return words[w] > 0 && words[w] <= max(3, total/20) }
+	wantedWords := make(map[string]string)
+	for _, id := range wanted {
+		for _, w := range itemWords(id) {
+			if material(w) {
+				wantedWords[w] = id
+			}
+		}
+	}
+
+	var out []DependencySuggestion
+	for _, other := range qb.Quests {
+		if other == q || ancestors[other.ID] || descendants[other.ID] {
+			continue
+		}
+		s := DependencySuggestion{Quest: other}
+		ts, rs := questItems(other)
+		for _, id := range rs {
+			if slices.Contains(wanted, id) {
+				s.score += 3
+				s.Reasons = append(s.Reasons, "rewards "+id)
+			}
+		}
+		// loose matches, on items the registry knows
+		for i, id := range append(rs, ts...) {
+			if slices.Contains(wanted, id) || !items.Known(id) {
+				continue
+			}
+			verb := "rewards "
+			if i >= len(rs) {
+				verb = "asks for "
+			}
+			for _, w := range itemWords(id) {
+				// a related item comes first if it is the plainer one
+				if want, ok := wantedWords[w]; ok && plainerItem(id, want) {
+					s.score++
+					s.Reasons = append(s.Reasons, verb+id+" ("+w+")")
+					break
+				}
+			}
+		}
+		if s.score > 0 {
+			out = append(out, s)
+		}
+	}
+	// the best first, then those in q's chapter and those earlier on
+	depth := make(map[*Quest]int, len(out))
+	for _, s := range out {
+		depth[s.Quest] = len(dependencyClosure(s.Quest.ID, depsOf))
+	}
+	slices.SortStableFunc(out, func(a, b DependencySuggestion) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		if ina, inb := a.Quest.Chapter == q.Chapter, b.Quest.Chapter == q.Chapter; ina != inb {
+			if ina {
+				return -1
+			}
+			return 1
+		}
+		return depth[a.Quest] - depth[b.Quest]
+	})
+	return out[:min(len(out), maxDependencySuggestions)]
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffOp is a single line of an edit script.
+type diffOp struct {
+	Kind byte // ' ', '-' or '+'
+	Line string
+	// A and B are the 0-based line numbers in the old and new text
+	A, B int
+}
+
+// diffLines returns a minimal edit script turning a into b, using Myers'
+// O((N+M)D) algorithm.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	total := n + m
+	if total == 0 {
+		return nil
+	}
+	v := make([]int, 2*total+3)
+	// trace[d] is v before step d, which only reads and needs the diagonals
+	// -d-1 to d+1: trace[d][d+1+k] is v[off+k]. Keeping just those makes the
+	// trace O(D²) rather than O((N+M)D).
+	var trace [][]int
+	off := total + 1
+
+search:
+	for d := 0; d <= total; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// walk the trace backwards to recover the script
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var pk int
+		if k == -d || (k != d && v[d+k] < v[d+k+2]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := v[d+1+pk]
+		py := px - pk
+		for x > px && y > py {
+			x--
+			y--
+			ops = append(ops, diffOp{Kind: ' ', Line: a[x], A: x, B: y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == px {
+			y--
+			ops = append(ops, diffOp{Kind: '+', Line: b[y], A: x, B: y})
+		} else {
+			x--
+			ops = append(ops, diffOp{Kind: '-', Line: a[x], A: x, B: y})
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// unifiedDiff renders the differences between a and b as a unified diff with
+// the given number of context lines. It returns "" if they are equal.
+func unifiedDiff(nameA, nameB, a, b string, context int) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	for i := 0; i < len(ops); {
+		// find the next change
+		for i < len(ops) && ops[i].Kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		// extend the hunk while changes are within 2*context of each other
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			j := end
+			for j < len(ops) && ops[j].Kind == ' ' {
+				j++
+			}
+			if j == len(ops) || j-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = j
+		}
+
+		var countA, countB int
+		for _, op := range ops[start:end] {
+			if op.Kind != '+' {
+				countA++
+			}
+			if op.Kind != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", ops[start].A+1, countA, ops[start].B+1, countB)
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.Kind)
+			sb.WriteString(op.Line)
+			sb.WriteByte('\n')
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+	got := unifiedDiff("a/f", "b/f", a, b, 1)
+	want := "--- a/f\n+++ b/f\n" +
+		"@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n" +
+		"@@ -10,1 +10,2 @@\n ten\n+eleven\n"
+	if got != want {
+		t.Fatalf("diff mismatch:\n%s\nwant:\n%s", got, want)
+	}
+	if d := unifiedDiff("a", "b", a, a, 3); d != "" {
+		t.Fatalf("expected no diff for equal input, got:\n%s", d)
+	}
+	if d := unifiedDiff("a", "b", "", "x\n", 3); d != "--- a\n+++ b\n@@ -1,0 +1,1 @@\n+x\n" {
+		t.Fatalf("unexpected diff from empty: %q", d)
+	}
+}
+
+// TestDiffLines checks diffLines' scripts against the longest common
+// subsequence of random texts.
+func TestDiffLines(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	text := func() []string {
+		l := make([]string, rnd.IntN(30))
+		for i := range l {
+			l[i] = string(rune('a' + rnd.IntN(4)))
+		}
+		return l
+	}
+	for range 500 {
+		a, b := text(), text()
+		// lcs[i][j] is the LCS of a[i:] and b[j:]
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		var gotA, gotB []string
+		same := 0
+		for _, op := range diffLines(a, b) {
+			if op.Kind != '+' {
+				gotA = append(gotA, op.Line)
+			}
+			if op.Kind != '-' {
+				gotB = append(gotB, op.Line)
+			}
+			if op.Kind == ' ' {
+				same++
+			}
+		}
+		if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) || same != lcs[0][0] {
+			t.Fatalf("diffLines(%q, %q) keeps %d lines, want %d", a, b, same, lcs[0][0])
+		}
+	}
+}
+
+func TestQuestFieldDiffs(t *testing.T) {
+	a := &Quest{Title: "Stone", Description: "one\ntwo"}
+	b := &Quest{Title: "Stone", Subtitle: "rocks", Description: "one\n2"}
+	fields := questFieldDiffs(a, b)
+	same := map[string]bool{}
+	for _, f := range fields {
+		same[f.Name] = f.Same
+	}
+	if !same["title"] || same["subtitle"] || same["description"] || !same["tasks"] {
+		t.Fatalf("unexpected same flags: %v", same)
+	}
+	if d := fields[2]; len(d.Lines) != 3 || d.Lines[1].Kind != '-' || d.Lines[2].Kind != '+' {
+		t.Fatalf("unexpected description diff: %+v", d.Lines)
+	}
+}
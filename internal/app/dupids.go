@@ -0,0 +1,170 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// idPlace is where an id was seen.
+type idPlace struct {
+	// what is the kind of object, eg. "quest", and where the file it is in,
+	// eg. "chapter mining"
+	what, where string
+}
+
+// duplicateIDs returns an IssueDuplicateID issue for every object in qb with
+// the id of one before it. FTB Quests ids are random hex, but files copied
+// between packs or merged by hand can bring two objects with the same id, and
+// the game then loads only one of them without saying so.
+//
+// The whole quests directory is checked, in order: the groups, then the
+// reward tables, then each chapter with its quests, their tasks and rewards,
+// and its quest links. The later of two objects is reported, as groups and
+// reward tables are referred to by id and the chapters' contents are easier
+// to give a new one.
+func duplicateIDs(qb *QuestBook) []BookIssue {
+	var issues []BookIssue
+	seen := make(map[string]idPlace)
+	use := func(id, what, file, where string, ch *Chapter, q *Quest) {
+		if id == "" {
+			return
+		}
+		if p, ok := seen[id]; ok {
+			issues = append(issues, BookIssue{
+				Kind: IssueDuplicateID, Chapter: ch, Quest: q, File: file, ID: id,
+				Message: fmt.Sprintf("%s %s has the same id as %s in %s", what, id, p.what, p.where),
+			})
+			return
+		}
+		seen[id] = idPlace{what, where}
+	}
+
+	for _, g := range qb.Groups {
+		use(g.ID, "group", "chapter_groups.snbt", "chapter_groups.snbt", nil, nil)
+	}
+	tables := slices.Clone(qb.Tables)
+	slices.SortFunc(tables, func(a, b *RewardTable) int { return strings.Compare(a.Name, b.Name) })
+	for _, t := range tables {
+		file, where := "reward_tables/"+t.Name+".snbt", "reward table "+t.Name
+		use(t.ID, "reward table", file, where, nil, nil)
+		for _, e := range t.Entries {
+			use(e.Reward.Base().ID, "table reward", file, where, nil, nil)
+		}
+	}
+	for _, ch := range qb.Chapters {
+		file, where := "chapters/"+ch.Name+".snbt", "chapter "+ch.Name
+		use(ch.ID, "chapter", file, where, ch, nil)
+		for _, q := range ch.Quests {
+			use(q.ID, "quest", file, where, ch, q)
+			for _, t := range q.Tasks {
+				use(t.Base().ID, "task", file, where, ch, q)
+			}
+			for _, r := range q.Rewards {
+				use(r.Base().ID, "reward", file, where, ch, q)
+			}
+		}
+		for _, l := range ch.QuestLinks {
+			if m, ok := l.(map[string]any); ok {
+				use(M(m).GetString("id"), "quest link", file, where, ch, nil)
+			}
+		}
+	}
+	return issues
+}
+
+// idCompounds returns the compounds with ids in v, the decoded file rel, in
+// the order duplicateIDs looks at them.
+func idCompounds(rel string, v map[string]any) []map[string]any {
+	var ms []map[string]any
+	list := func(m map[string]any, key string) []map[string]any {
+		var res []map[string]any
+		for _, e := range M(m).GetAnys(key) {
+			if c, ok := e.(map[string]any); ok {
+				res = append(res, c)
+			}
+		}
+		return res
+	}
+	switch {
+	case rel == "chapter_groups.snbt":
+		ms = list(v, "chapter_groups")
+	case strings.HasPrefix(rel, "reward_tables/"):
+		ms = append([]map[string]any{v}, list(v, "rewards")...)
+	case strings.HasPrefix(rel, "chapters/"):
+		ms = append(ms, v)
+		for _, q := range list(v, "quests") {
+			ms = append(ms, q)
+			ms = append(ms, list(q, "tasks")...)
+			ms = append(ms, list(q, "rewards")...)
+		}
+		ms = append(ms, list(v, "quest_links")...)
+	}
+	return ms
+}
+
+// RegenerateID gives the last object with the id in the file rel, relative to
+// the quests directory, a new id, which it returns. Nothing else is changed:
+// anything referring to the id, like a dependency on a quest, keeps referring
+// to the first object.
+func (qb *QuestBook) RegenerateID(rel, id string) (string, error) {
+	rel = filepath.ToSlash(rel)
+	if !knownFile(rel) || strings.Contains(rel, "..") || strings.HasPrefix(rel, "lang/") {
+		return "", fmt.Errorf("not a book file: %s", rel)
+	}
+	path := filepath.Join(qb.root, "quests", filepath.FromSlash(rel))
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	v, err := snbt.Decode(f)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", rel, err)
+	}
+	root, ok := v.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("%s is not a compound", rel)
+	}
+	var last map[string]any
+	for _, m := range idCompounds(rel, root) {
+		if numberString(m["id"]) == id {
+			last = m
+		}
+	}
+	if last == nil {
+		return "", fmt.Errorf("no id %s in %s", id, rel)
+	}
+	newID := qb.idSource().Next()
+	last["id"] = newID
+	return newID, writeSNBT(path, root)
+}
+
+// warnDuplicateIDs logs the duplicate ids of qb, so they are seen on startup.
+func warnDuplicateIDs(qb *QuestBook) {
+	for _, is := range duplicateIDs(qb) {
+		slog.Warn("duplicate id", "file", is.File, "id", is.ID, "issue", is.Message)
+	}
+}
+
+// issuesRegenerate handles POST "/issues/regenerate", which gives the
+// duplicate "id" in "file" a new id.
+func (a *App) issuesRegenerate(w http.ResponseWriter, r *http.Request) {
+	file, id := r.FormValue("file"), r.FormValue("id")
+	newID, err := a.QB().RegenerateID(file, id)
+	if err != nil {
+		http.Error(w, "regenerate id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "regenerate id", file, nil, fmt.Sprintf("%s → %s", id, newID))
+	a.reload()
+	msg := fmt.Sprintf("Gave %s in %s the new id %s.", id, file, newID)
+	http.Redirect(w, r, "/issues?msg="+url.QueryEscape(msg)+"#"+IssueDuplicateID, http.StatusSeeOther)
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDuplicateIDs(t *testing.T) {
+	root := t.TempDir()
+	chapters := filepath.Join(root, "quests", "chapters")
+	if err := os.MkdirAll(chapters, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"../chapter_groups.snbt": `{ chapter_groups: [ { id: "00000000000000C0", title: "G" } ] }`,
+		"a.snbt": `{ id: "00000000000000C0", group: "00000000000000C0", title: "A", quests: [
+			{ id: "00000000000000A1", tasks: [{ id: "00000000000000A2", type: "checkmark" }] }
+		] }`,
+		"b.snbt": `{ id: "00000000000000B0", title: "B", quests: [
+			{ id: "00000000000000A1", tasks: [{ id: "00000000000000B2", type: "checkmark" }] }
+		], quest_links: [ { id: "00000000000000B2", linked_quest: "00000000000000A1" } ] }`,
+	}
+	for name, s := range files {
+		if err := os.WriteFile(filepath.Join(chapters, name), []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	qb, err := NewQuestBook(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := duplicateIDs(qb)
+	if len(issues) != 3 {
+		t.Fatalf("got %d issues: %+v", len(issues), issues)
+	}
+	if is := issues[0]; is.File != "chapters/a.snbt" || is.ID != "00000000000000C0" || is.Message != "chapter 00000000000000C0 has the same id as group in chapter_groups.snbt" {
+		t.Errorf("first issue = %+v", is)
+	}
+	if is := issues[2]; is.File != "chapters/b.snbt" || is.ID != "00000000000000B2" || is.Quest != nil {
+		t.Errorf("link issue = %+v", is)
+	}
+
+	// the quest link is the later object with B2 in b.snbt, not the task
+	newID, err := qb.RegenerateID("chapters/b.snbt", "00000000000000B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qb, err = NewQuestBook(root); err != nil {
+		t.Fatal(err)
+	}
+	links := qb.chapterLinks(qb.chapterMap["b"])
+	if len(links) != 1 || links[0].ID != newID || qb.questMap["00000000000000A1"] == nil {
+		t.Errorf("links = %+v", links)
+	}
+	if n := len(duplicateIDs(qb)); n != 2 {
+		t.Errorf("%d issues after regenerating", n)
+	}
+	if _, err := qb.RegenerateID("../../etc/passwd", "00000000000000B2"); err == nil {
+		t.Error("RegenerateID outside the book should fail")
+	}
+}
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// Ways of setting a duplicated quest's dependencies.
+const (
+	// copyDepsKeep keeps the original's dependencies.
+	copyDepsKeep = "keep"
+	// copyDepsClear leaves the copy without dependencies.
+	copyDepsClear = "clear"
+	// copyDepsOriginal makes the copy depend on the original only, for the
+	// next tier of a pattern.
+	copyDepsOriginal = "original"
+)
+
+// copyValue returns a deep copy of the SNBT value v.
+func copyValue(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(x))
+		for k, e := range x {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []any:
+		l := make([]any, len(x))
+		for i, e := range x {
+			l[i] = copyValue(e)
+		}
+		return l
+	case snbt.ByteArray:
+		return slices.Clone(x)
+	case snbt.IntArray:
+		return slices.Clone(x)
+	case snbt.LongArray:
+		return slices.Clone(x)
+	}
+	return v
+}
+
+// DuplicateQuest copies the quest id to the end of the chapter named to, with
+// its dependencies set by deps, one of the copyDeps ways. A copy in the
+// original's chapter is placed beside it; in another chapter it keeps its
+// position. The chapter is written with the book's lang file, and the copy is
+// returned.
+//
+// The quest's compound is deep-copied, keeping every field the editor doesn't
+// know, with new ids for it and its tasks and rewards, so a quest pattern can
+// be repeated across progression tiers. Text kept in the lang file is copied
+// as literal text, so the copy doesn't share translation keys with the
+// original.
+func (qb *QuestBook) DuplicateQuest(id, to, deps string) (*Quest, error) {
+	orig, ok := qb.questMap[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown quest %s", id)
+	}
+	if qb.chapterMap[to] == nil {
+		return nil, fmt.Errorf("unknown chapter %q", to)
+	}
+	if !slices.Contains([]string{copyDepsKeep, copyDepsClear, copyDepsOriginal}, deps) {
+		return nil, fmt.Errorf("invalid dependencies %q: want keep, clear or original", deps)
+	}
+
+	// the copy is made from the files, not the loaded book, in case they
+	// changed since
+	t := newBookWrite(qb.Lang)
+	src, err := NewChapterFromPath(qb.chapterPath(orig.Chapter.Name))
+	if err != nil {
+		return nil, fmt.Errorf("open chapter %s: %w", orig.Chapter.Name, err)
+	}
+	src.resolveLang(qb.Lang)
+	from, ok := src.questMap[id]
+	if !ok {
+		return nil, fmt.Errorf("quest %s not found in %s", id, src.Name)
+	}
+	dst := src
+	if to != src.Name {
+		if dst, err = NewChapterFromPath(qb.chapterPath(to)); err != nil {
+			return nil, fmt.Errorf("open chapter %s: %w", to, err)
+		}
+		dst.resolveLang(qb.Lang)
+	}
+
+	q, err := NewQuest(copyValue(from.raw))
+	if err != nil {
+		return nil, err
+	}
+	ids := qb.idSource()
+	q.ID = ids.Next()
+	q.raw["id"] = q.ID
+	for _, task := range q.Tasks {
+		task.Base().ID = ids.Next()
+		task.Base().raw["id"] = task.Base().ID
+	}
+	for _, r := range q.Rewards {
+		r.Base().ID = ids.Next()
+		r.Base().raw["id"] = r.Base().ID
+	}
+	q.Title, q.Subtitle, q.Description = from.Title, from.Subtitle, from.Description
+	switch deps {
+	case copyDepsClear:
+		q.Dependencies, q.MinRequired = nil, 0
+	case copyDepsOriginal:
+		q.Dependencies, q.MinRequired = []string{id}, 0
+	}
+	if dst == src {
+		q.X += from.Size
+	}
+	q.Chapter = dst
+	dst.Quests = append(dst.Quests, q)
+	dst.questMap[q.ID] = q
+
+	if err := t.stageChapter(dst, qb.chapterPath(dst.Name)); err != nil {
+		return nil, err
+	}
+	if err := t.commit(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// questDuplicate handles POST "/chapter/{chapter}/{quest}/duplicate", which
+// copies the quest to the chapter "to" (by default its own), with its
+// dependencies set by "deps", and opens the copy.
+func (a *App) questDuplicate(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cname := chi.URLParam(r, "chapter")
+	qid := chi.URLParam(r, "quest")
+	orig, ok := qb.questMap[qid]
+	if !ok || orig.Chapter.Name != cname {
+		http.NotFound(w, r)
+		return
+	}
+	to := strings.TrimSpace(r.FormValue("to"))
+	if to == "" {
+		to = cname
+	}
+	deps := r.FormValue("deps")
+	if deps == "" {
+		deps = copyDepsKeep
+	}
+	q, err := qb.DuplicateQuest(qid, to, deps)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "duplicate quest", to, []string{q.ID}, "copy of "+qid)
+	a.reload()
+	msg := fmt.Sprintf("Copied %s to %s as %s.", qid, to, q.ID)
+	http.Redirect(w, r, "/chapter/"+to+"/"+q.ID+"?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateQuest(t *testing.T) {
+	a := testApp(t)
+	other := `{ id: "00000000000000AA", title: "Other", quests: [ ] }`
+	if err := os.WriteFile(filepath.Join(a.Root(), "quests", "chapters", "other.snbt"), []byte(other), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	h := a.Router()
+	orig := a.QB().questMap[a.QB().chapterMap["test"].Quests[0].ID]
+
+	dup := func(form url.Values) *Quest {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/chapter/test/"+orig.ID+"/duplicate", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("duplicate %v: %d %s", form, rec.Code, rec.Body)
+		}
+		loc, _ := url.Parse(rec.Header().Get("Location"))
+		id := filepath.Base(loc.Path)
+		q, ok := a.QB().questMap[id]
+		if !ok {
+			t.Fatalf("copy %s not loaded (redirected to %s)", id, loc)
+		}
+		return q
+	}
+
+	// beside the original, depending on it
+	q := dup(url.Values{"deps": {"original"}})
+	if q.ID == orig.ID || q.Chapter.Name != "test" || q.Title != orig.Title || q.Description != orig.Description {
+		t.Errorf("copy %s in %s: %q", q.ID, q.Chapter.Name, q.Title)
+	}
+	if !slices.Equal(q.Dependencies, []string{orig.ID}) || q.X != orig.X+orig.Size || q.Y != orig.Y {
+		t.Errorf("copy deps %v at %v,%v", q.Dependencies, q.X, q.Y)
+	}
+	if len(q.Tasks) != len(orig.Tasks) || len(q.Rewards) != len(orig.Rewards) {
+		t.Fatalf("copy has %d tasks and %d rewards", len(q.Tasks), len(q.Rewards))
+	}
+	for i, task := range q.Tasks {
+		if task.Base().ID == orig.Tasks[i].Base().ID || task.Base().Type != orig.Tasks[i].Base().Type {
+			t.Errorf("task %d: %s %s", i, task.Base().ID, task.Base().Type)
+		}
+	}
+	if got := a.QB().questMap[orig.ID]; !slices.Equal(got.Dependencies, orig.Dependencies) || got.Tasks[0].Base().ID != orig.Tasks[0].Base().ID {
+		t.Errorf("original changed: %+v", got)
+	}
+
+	// to another chapter, keeping dependencies and position
+	q = dup(url.Values{"to": {"other"}})
+	if q.Chapter.Name != "other" || !slices.Equal(q.Dependencies, orig.Dependencies) || q.X != orig.X {
+		t.Errorf("copy in %s with deps %v at %v", q.Chapter.Name, q.Dependencies, q.X)
+	}
+	q = dup(url.Values{"to": {"other"}, "deps": {"clear"}})
+	if len(q.Dependencies) != 0 || len(a.QB().chapterMap["other"].Quests) != 2 {
+		t.Errorf("cleared copy has deps %v", q.Dependencies)
+	}
+}
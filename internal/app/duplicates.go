@@ -0,0 +1,198 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultDuplicateSimilarity is the text similarity pairs need by default.
+const defaultDuplicateSimilarity = 0.8
+
+// DuplicatePair is two quests that look like copies of each other.
+type DuplicatePair struct {
+	A, B *Quest
+	// Items are the items both quests' item tasks ask for.
+	Items []string
+	// Similarity is how alike their text is, from 0 to 1.
+	Similarity float64
+}
+
+// Percent is the similarity as a whole percentage.
+func (p DuplicatePair) Percent() int { return int(p.Similarity*100 + 0.5) }
+
+// taskItems returns the distinct items q's item tasks ask for, sorted.
+func taskItems(q *Quest) []string {
+	var items []string
+	for _, t := range q.Tasks {
+		if it, ok := t.(*ItemTask); ok && !slices.Contains(items, it.Item) {
+			items = append(items, it.Item)
+		}
+	}
+	slices.Sort(items)
+	return items
+}
+
+// questWords returns q's title, subtitle and description as lower case words
+// without formatting codes.
+func questWords(q *Quest) []string {
+	text := strings.Join([]string{q.GetTitle(), q.Subtitle, q.Description}, " ")
+	return strings.Fields(strings.ToLower(stripCodes(text)))
+}
+
+// textSimilarity compares two texts by the words they have in common, in
+// order: 1 means they're the same, 0 that they share nothing.
+func textSimilarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	same := 0
+	for _, op := range diffLines(a, b) {
+		if op.Kind == ' ' {
+			same++
+		}
+	}
+	return 2 * float64(same) / float64(len(a)+len(b))
+}
+
+// findDuplicates returns the pairs of quests in different chapters that ask
+// for the same items and whose text is at least threshold similar, most
+// similar first; splitting or reorganizing chapters often leaves a quest
+// behind in both places. Quests without item tasks are left out, since
+// checkmark quests would all match each other.
+func findDuplicates(qb *QuestBook, threshold float64) []DuplicatePair {
+	byItems := make(map[string][]*Quest)
+	for _, q := range qb.Quests {
+		if items := taskItems(q); len(items) > 0 {
+			key := strings.Join(items, " ")
+			byItems[key] = append(byItems[key], q)
+		}
+	}
+	var pairs []DuplicatePair
+	for _, qs := range byItems {
+		if len(qs) < 2 {
+			continue
+		}
+		words := make([][]string, len(qs))
+		for i, q := range qs {
+			words[i] = questWords(q)
+		}
+		for i := range qs {
+			for j := i + 1; j < len(qs); j++ {
+				if qs[i].Chapter == qs[j].Chapter {
+					continue
+				}
+				if s := textSimilarity(words[i], words[j]); s >= threshold {
+					pairs = append(pairs, DuplicatePair{A: qs[i], B: qs[j], Items: taskItems(qs[i]), Similarity: s})
+				}
+			}
+		}
+	}
+	slices.SortFunc(pairs, func(a, b DuplicatePair) int {
+		if a.Similarity != b.Similarity {
+			if a.Similarity > b.Similarity {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.A.ID+a.B.ID, b.A.ID+b.B.ID)
+	})
+	return pairs
+}
+
+// MergeDuplicate removes the quest drop from the book in favor of keep:
+// quests that depended on drop depend on keep instead, and links to drop
+// link to keep. The changed chapters are written together. It returns the
+// number of references moved to keep.
+func (qb *QuestBook) MergeDuplicate(keep, drop string) (int, error) {
+	kq, ok := qb.questMap[keep]
+	if !ok {
+		return 0, fmt.Errorf("unknown quest %s", keep)
+	}
+	dq, ok := qb.questMap[drop]
+	if !ok {
+		return 0, fmt.Errorf("unknown quest %s", drop)
+	}
+	if kq == dq {
+		return 0, fmt.Errorf("can't merge quest %s with itself", keep)
+	}
+
+	refs := qb.questIDRefs(drop)
+	names := map[string]bool{dq.Chapter.Name: true}
+	for _, d := range refs.Dependents {
+		names[d.Chapter.Name] = true
+	}
+	for c := range refs.Links {
+		names[c.Name] = true
+	}
+	moved := 0
+	files := make(map[string]any)
+	for name := range names {
+		path := qb.chapterPath(name)
+		ch, err := NewChapterFromPath(path)
+		if err != nil {
+			return 0, err
+		}
+		ch.Quests = slices.DeleteFunc(ch.Quests, func(q *Quest) bool { return q.ID == drop })
+		for _, q := range ch.Quests {
+			if !slices.Contains(q.Dependencies, drop) {
+				continue
+			}
+			q.Dependencies = slices.DeleteFunc(q.Dependencies, func(d string) bool { return d == drop })
+			if q.ID != keep && !slices.Contains(q.Dependencies, keep) {
+				q.Dependencies = append(q.Dependencies, keep)
+				moved++
+			}
+		}
+		for _, l := range ch.QuestLinks {
+			if m, ok := l.(map[string]any); ok && M(m).GetString("linked_quest") == drop {
+				m["linked_quest"] = keep
+				moved++
+			}
+		}
+		ch.Sync()
+		files[path] = ch.raw
+	}
+	if err := writeSNBTFiles(files); err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+// duplicates handles GET "/duplicates". "min" is the text similarity pairs
+// need, as a percentage.
+func (a *App) duplicates(w http.ResponseWriter, r *http.Request) {
+	threshold := defaultDuplicateSimilarity
+	if n, err := strconv.Atoi(r.URL.Query().Get("min")); err == nil && n >= 0 && n <= 100 {
+		threshold = float64(n) / 100
+	}
+	data := a.baseData(r, "Duplicates")
+	data["Pairs"] = findDuplicates(a.QB(), threshold)
+	data["Min"] = int(threshold*100 + 0.5)
+	data["Msg"] = r.URL.Query().Get("msg")
+	a.render(w, "duplicates.gohtml", data)
+}
+
+// duplicatesMerge handles POST "/duplicates/merge", which removes the quest
+// "drop" in favor of "keep"; see MergeDuplicate.
+func (a *App) duplicatesMerge(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	keep, drop := r.FormValue("keep"), r.FormValue("drop")
+	dq, ok := qb.questMap[drop]
+	if !ok {
+		http.Error(w, "unknown quest "+drop, http.StatusBadRequest)
+		return
+	}
+	moved, err := qb.MergeDuplicate(keep, drop)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "merge duplicate", dq.Chapter.Name, []string{drop, keep}, fmt.Sprintf("removed %s, kept %s", drop, keep))
+	a.reload()
+	msg := fmt.Sprintf("Removed %s; %d references now point to %s.", drop, moved, keep)
+	http.Redirect(w, r, "/duplicates?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	if s := textSimilarity(strings.Fields("craft a furnace"), strings.Fields("craft a furnace now")); s < 0.85 || s > 0.86 {
+		t.Errorf("similarity = %v", s)
+	}
+
+	a := testApp(t)
+	qb := a.QB()
+	var orig *Quest
+	for _, q := range qb.Quests {
+		if len(taskItems(q)) > 0 && len(qb.dependents(q.ID)) > 0 {
+			orig = q
+			break
+		}
+	}
+	if orig == nil {
+		t.Skip("no quest with item tasks and dependents")
+	}
+	dependents := qb.dependents(orig.ID)
+
+	// copy the quest into a new chapter, with a slightly different text
+	ch, err := NewChapterFromPath(filepath.Join(a.Root(), "quests", "chapters", "test.snbt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := ch.questMap[orig.ID]
+	cp.ID, cp.raw["id"] = "00000000000C0002", "00000000000C0002"
+	cp.Dependencies = nil
+	cp.Description += " Copied."
+	ch.Quests = []*Quest{cp}
+	ch.QuestLinks = nil
+	ch.raw["id"], ch.raw["filename"], ch.raw["quest_links"] = "00000000000C0001", "copy", []any{}
+	if err := ch.Save(filepath.Join(a.Root(), "quests", "chapters", "copy.snbt")); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+
+	pairs := findDuplicates(a.QB(), 0.5)
+	if !slices.ContainsFunc(pairs, func(p DuplicatePair) bool {
+		return (p.A.ID == orig.ID && p.B.ID == cp.ID) || (p.A.ID == cp.ID && p.B.ID == orig.ID)
+	}) {
+		t.Fatalf("copy not found in %d pairs", len(pairs))
+	}
+	if got := findDuplicates(a.QB(), 1); len(got) != 0 {
+		t.Errorf("%d identical pairs", len(got))
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/duplicates/merge", strings.NewReader(url.Values{"keep": {cp.ID}, "drop": {orig.ID}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	a.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("merge: %d %s", rec.Code, rec.Body)
+	}
+	qb = a.QB()
+	if _, ok := qb.questMap[orig.ID]; ok {
+		t.Error("dropped quest still loaded")
+	}
+	for _, d := range dependents {
+		if q := qb.questMap[d.ID]; !slices.Contains(q.Dependencies, cp.ID) || slices.Contains(q.Dependencies, orig.ID) {
+			t.Errorf("%s dependencies = %v", d.ID, q.Dependencies)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ExportVersion is the version of the quest export's shape. The export is a
+// normalized JSON form of the book for wiki generators and pack websites:
+// tasks and rewards are typed, text is resolved from the lang file and also
+// given without formatting codes, and positions and dependencies are
+// included. Fields are only ever added within a version; removing or changing
+// the meaning of a field bumps it.
+const ExportVersion = 1
+
+// ExportBook is the whole book, or the chapters asked for.
+type ExportBook struct {
+	Version  int             `json:"version"`
+	Chapters []ExportChapter `json:"chapters"`
+}
+
+// ExportChapter is a chapter and its quests.
+type ExportChapter struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Title  ExportText    `json:"title"`
+	Group  string        `json:"group,omitempty"`
+	Quests []ExportQuest `json:"quests"`
+}
+
+// ExportText is text as the quest book shows it, with formatting codes, and
+// as plain text.
+type ExportText struct {
+	Text  string `json:"text"`
+	Plain string `json:"plain"`
+}
+
+// ExportQuest is a quest. Single quests are exported with the version too.
+type ExportQuest struct {
+	Version      int          `json:"version,omitempty"`
+	ID           string       `json:"id"`
+	Chapter      string       `json:"chapter"`
+	Title        ExportText   `json:"title"`
+	Subtitle     *ExportText  `json:"subtitle,omitempty"`
+	Description  []ExportText `json:"description"`
+	Icon         string       `json:"icon,omitempty"`
+	X            float64      `json:"x"`
+	Y            float64      `json:"y"`
+	Size         float64      `json:"size"`
+	Shape        string       `json:"shape"`
+	Dependencies []string     `json:"dependencies"`
+	// MinRequired is how many dependencies must be completed; 0 means all.
+	MinRequired int            `json:"min_required,omitempty"`
+	Repeatable  bool           `json:"repeatable,omitempty"`
+	Tasks       []ExportTask   `json:"tasks"`
+	Rewards     []ExportReward `json:"rewards"`
+}
+
+// ExportTask is a task. Which fields are set depends on its type; unknown
+// task types only have an id and type.
+type ExportTask struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Item is set for item tasks, with Count.
+	Item  string `json:"item,omitempty"`
+	Count int    `json:"count,omitempty"`
+	// Entity is set for kill tasks, with Count.
+	Entity string `json:"entity,omitempty"`
+	// Value is the advancement, dimension, biome or structure to reach.
+	Value string `json:"value,omitempty"`
+	// Amount is the experience an xp task takes.
+	Amount int `json:"amount,omitempty"`
+}
+
+// ExportReward is a reward. Which fields are set depends on its type;
+// unknown reward types only have an id and type.
+type ExportReward struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Item is set for item rewards, with Count.
+	Item  string `json:"item,omitempty"`
+	Count int    `json:"count,omitempty"`
+	// Amount is the experience, or levels, an xp reward gives.
+	Amount int `json:"amount,omitempty"`
+	// TableID is the reward table of loot, random and choice rewards.
+	TableID string `json:"table_id,omitempty"`
+	Command string `json:"command,omitempty"`
+}
+
+func newExportText(s string) ExportText {
+	return ExportText{Text: s, Plain: stripCodes(s)}
+}
+
+func exportTask(t Task) ExportTask {
+	e := ExportTask{ID: t.Base().ID, Type: t.Base().Type}
+	switch t := t.(type) {
+	case *ItemTask:
+		e.Item, e.Count = t.Item, t.Count
+	case *KillTask:
+		e.Entity, e.Count = t.Entity, t.Count
+	case *StringTask:
+		e.Value = t.Value
+	case *XPTask:
+		e.Amount = t.Amount
+	}
+	return e
+}
+
+func exportReward(r Reward) ExportReward {
+	e := ExportReward{ID: r.Base().ID, Type: r.Base().Type}
+	switch r := r.(type) {
+	case *ItemReward:
+		e.Item, e.Count = r.Item, r.Count
+	case *XPReward:
+		e.Amount = r.Amount
+	case *LootReward:
+		e.TableID = r.TableID
+	case *CommandReward:
+		e.Command = r.Command
+	}
+	return e
+}
+
+// exportQuest returns q in its exported form. Lists are never null, so
+// consumers don't have to check for both.
+func exportQuest(q *Quest) ExportQuest {
+	e := ExportQuest{
+		ID:           q.ID,
+		Title:        newExportText(q.GetTitle()),
+		Description:  []ExportText{},
+		Icon:         q.IconItem(),
+		X:            q.X,
+		Y:            q.Y,
+		Size:         q.Size,
+		Shape:        q.DisplayShape(),
+		Dependencies: append([]string{}, q.Dependencies...),
+		MinRequired:  q.MinRequired,
+		Repeatable:   q.Repeatable,
+		Tasks:        make([]ExportTask, 0, len(q.Tasks)),
+		Rewards:      make([]ExportReward, 0, len(q.Rewards)),
+	}
+	if q.Chapter != nil {
+		e.Chapter = q.Chapter.Name
+	}
+	if q.Subtitle != "" {
+		s := newExportText(q.Subtitle)
+		e.Subtitle = &s
+	}
+	if q.Description != "" {
+		for _, line := range strings.Split(q.Description, "\n") {
+			e.Description = append(e.Description, newExportText(line))
+		}
+	}
+	for _, t := range q.Tasks {
+		e.Tasks = append(e.Tasks, exportTask(t))
+	}
+	for _, r := range q.Rewards {
+		e.Rewards = append(e.Rewards, exportReward(r))
+	}
+	return e
+}
+
+// exportBook returns the chapters in their exported form, in book order.
+func exportBook(chapters []*Chapter) ExportBook {
+	b := ExportBook{Version: ExportVersion, Chapters: make([]ExportChapter, 0, len(chapters))}
+	for _, ch := range chapters {
+		c := ExportChapter{
+			ID:     ch.ID,
+			Name:   ch.Name,
+			Title:  newExportText(ch.Title),
+			Group:  ch.GroupID,
+			Quests: make([]ExportQuest, 0, len(ch.Quests)),
+		}
+		for _, q := range ch.Quests {
+			c.Quests = append(c.Quests, exportQuest(q))
+		}
+		b.Chapters = append(b.Chapters, c)
+	}
+	return b
+}
+
+// questExport handles GET "/export/quests.json", every chapter or those
+// named by "chapter" parameters. download=1 saves it as a file.
+func (a *App) questExport(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	chapters := qb.Chapters
+	if names := r.URL.Query()["chapter"]; len(names) > 0 {
+		chapters = nil
+		for _, name := range names {
+			ch, ok := qb.chapterMap[name]
+			if !ok {
+				http.Error(w, fmt.Sprintf("no chapter %q", name), http.StatusNotFound)
+				return
+			}
+			chapters = append(chapters, ch)
+		}
+	}
+	writeExport(w, r, "quests", exportBook(chapters))
+}
+
+// questExportOne handles GET "/chapter/{chapter}/{quest}/json", a single
+// quest in the export's form.
+func (a *App) questExportOne(w http.ResponseWriter, r *http.Request) {
+	q, ok := a.QB().questMap[chi.URLParam(r, "quest")]
+	if !ok || q.Chapter.Name != chi.URLParam(r, "chapter") {
+		http.NotFound(w, r)
+		return
+	}
+	e := exportQuest(q)
+	e.Version = ExportVersion
+	writeExport(w, r, q.ID, e)
+}
+
+func writeExport(w http.ResponseWriter, r *http.Request, name string, v any) {
+	b, err := marshalTellraw(v, "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if r.URL.Query().Get("download") == "1" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".json"))
+	}
+	w.Write(b)
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQuestExport(t *testing.T) {
+	a := testApp(t)
+	ch := a.QB().Chapters[0]
+
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/export/quests.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET export: %d %s", rec.Code, rec.Body)
+	}
+	var book ExportBook
+	if err := json.Unmarshal(rec.Body.Bytes(), &book); err != nil {
+		t.Fatal(err)
+	}
+	if book.Version != ExportVersion || len(book.Chapters) != 1 || len(book.Chapters[0].Quests) != len(ch.Quests) {
+		t.Fatalf("export = version %d, %d chapters", book.Version, len(book.Chapters))
+	}
+	for i, e := range book.Chapters[0].Quests {
+		q := ch.Quests[i]
+		if e.ID != q.ID || e.Chapter != "test" || e.Title.Text != q.GetTitle() || e.Title.Plain != stripCodes(q.GetTitle()) ||
+			len(e.Tasks) != len(q.Tasks) || len(e.Rewards) != len(q.Rewards) || len(e.Dependencies) != len(q.Dependencies) {
+			t.Errorf("quest %s exported as %+v", q.ID, e)
+		}
+		for j, et := range e.Tasks {
+			if it, ok := q.Tasks[j].(*ItemTask); ok && (et.Item != it.Item || et.Count != it.Count) {
+				t.Errorf("task %s exported as %+v", it.ID, et)
+			}
+		}
+		if e.Tasks == nil || e.Rewards == nil || e.Dependencies == nil || e.Description == nil {
+			t.Errorf("quest %s has null lists", q.ID)
+		}
+	}
+
+	q := ch.Quests[0]
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/chapter/test/"+q.ID+"/json", nil))
+	var one ExportQuest
+	if err := json.Unmarshal(rec.Body.Bytes(), &one); err != nil || one.Version != ExportVersion || one.ID != q.ID || one.X != q.X {
+		t.Errorf("GET quest json: %+v, %v", one, err)
+	}
+
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/export/quests.json?chapter=nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown chapter: %d", rec.Code)
+	}
+}
@@ -0,0 +1,52 @@
+// Package fbtid generates ids in the style FTB Quests uses for its groups,
+// chapters, quests, tasks, rewards and reward tables: 64 random bits written
+// as 16 upper case hex digits.
+//
+// The game doesn't check that ids are unique, and loads only one of the
+// objects sharing an id, so a Source hands out ids that are checked against
+// those already in use.
+package fbtid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// New returns a random id. It isn't checked against any book; see Source.
+func New() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return strings.ToUpper(hex.EncodeToString(b[:]))
+}
+
+// Source hands out random ids that aren't in use, and never the same one
+// twice, so that the ids of objects created together don't collide before
+// they are written. It is safe for concurrent use.
+type Source struct {
+	inUse func(id string) bool
+
+	mu    sync.Mutex
+	taken map[string]bool
+}
+
+// NewSource returns a Source for ids that inUse reports unused. inUse may be
+// nil.
+func NewSource(inUse func(id string) bool) *Source {
+	return &Source{inUse: inUse, taken: make(map[string]bool)}
+}
+
+// Next returns an id that isn't in use and hasn't been returned before.
+func (s *Source) Next() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		id := New()
+		if s.taken[id] || (s.inUse != nil && s.inUse(id)) {
+			continue
+		}
+		s.taken[id] = true
+		return id
+	}
+}
@@ -0,0 +1,34 @@
+package fbtid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	id := New()
+	if len(id) != 16 || strings.Trim(id, "0123456789ABCDEF") != "" {
+		t.Errorf("New() = %q", id)
+	}
+}
+
+func TestSource(t *testing.T) {
+	// an inUse rejecting everything but every third id it is asked about
+	// makes Next skip ids
+	asked := 0
+	s := NewSource(func(string) bool {
+		asked++
+		return asked%3 != 0
+	})
+	seen := make(map[string]bool)
+	for range 10 {
+		id := s.Next()
+		if seen[id] {
+			t.Fatalf("%s returned twice", id)
+		}
+		seen[id] = true
+	}
+	if asked != 30 {
+		t.Errorf("inUse asked %d times, want 30", asked)
+	}
+}
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitRepo commits to the repository containing a book. With --git every edit
+// recorded in the audit log is also committed, so that the history of the
+// book can be browsed and individual edits reverted from the /git page. Only
+// the ftbquests dir (and the lang file, if it is in the same repository) is
+// staged; anything else in the working tree is left for the user to commit.
+// git is run as a command rather than through a library so that it behaves
+// exactly like the user's own git, hooks and config included.
+type GitRepo struct {
+	// dir is the ftbquests dir; top is the repository's working tree.
+	dir, top string
+	// identity are -c options for repositories without a configured user,
+	// where git would refuse to commit.
+	identity []string
+	mu       sync.Mutex
+}
+
+// gitHash matches full or abbreviated commit hashes.
+var gitHash = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// GitCommit is an entry of the /git page's history.
+type GitCommit struct {
+	Hash, Short, Author, Subject string
+	Time                         time.Time
+}
+
+// OpenGitRepo returns the repository dir is in, or an error if it isn't in
+// one or git isn't installed.
+func OpenGitRepo(dir string) (*GitRepo, error) {
+	g := &GitRepo{dir: dir}
+	top, err := g.run("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", dir, err)
+	}
+	g.top = strings.TrimSpace(top)
+	if email, _ := g.run("config", "user.email"); strings.TrimSpace(email) == "" {
+		g.identity = []string{"-c", "user.name=qbedit", "-c", "user.email=qbedit@localhost"}
+	}
+	return g, nil
+}
+
+// run runs git in the book's dir and returns its output. The error includes
+// whatever git printed to stderr.
+func (g *GitRepo) run(args ...string) (string, error) {
+	cmd := exec.Command("git", append(append([]string(nil), g.identity...), args...)...)
+	cmd.Dir = g.dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// inRepo reports whether path is inside the repository's working tree.
+func (g *GitRepo) inRepo(path string) bool {
+	rel, err := filepath.Rel(g.top, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Commit stages the book (and extra paths inside the repository) and
+// commits them with msg. author, if not empty, is recorded as the commit's
+// author. Nothing is committed if nothing changed.
+func (g *GitRepo) Commit(msg, author string, extra ...string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	paths := []string{"."}
+	for _, p := range extra {
+		if p != "" && g.inRepo(p) {
+			paths = append(paths, p)
+		}
+	}
+	if _, err := g.run(append([]string{"add", "-A", "--"}, paths...)...); err != nil {
+		return err
+	}
+	// diff --quiet exits 1 when there are staged changes
+	if _, err := g.run("diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	args := []string{"commit", "-q", "-m", msg}
+	if author != "" {
+		args = append(args, "--author", author+" <qbedit@localhost>")
+	}
+	_, err := g.run(args...)
+	return err
+}
+
+// Log returns the last n commits that touched the book, newest first.
+func (g *GitRepo) Log(n int) ([]GitCommit, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out, err := g.run("log", "-n", strconv.Itoa(n), "--format=%H%x1f%h%x1f%an%x1f%at%x1f%s", "--", ".")
+	if err != nil {
+		// a repository without commits has no log
+		if strings.Contains(err.Error(), "does not have any commits") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var commits []GitCommit
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		f := strings.Split(line, "\x1f")
+		if len(f) != 5 {
+			continue
+		}
+		ts, _ := strconv.ParseInt(f[3], 10, 64)
+		commits = append(commits, GitCommit{Hash: f[0], Short: f[1], Author: f[2], Time: time.Unix(ts, 0), Subject: f[4]})
+	}
+	return commits, nil
+}
+
+// Revert commits the inverse of the commit hash, with author as its author
+// if it is not empty. If it doesn't apply cleanly the revert is abandoned
+// and the working tree left as it was.
+func (g *GitRepo) Revert(hash, author string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !gitHash.MatchString(hash) {
+		return fmt.Errorf("invalid commit %q", hash)
+	}
+	if _, err := g.run("rev-parse", "--verify", "--quiet", hash+"^{commit}"); err != nil {
+		return fmt.Errorf("unknown commit %s", hash)
+	}
+	if _, err := g.run("revert", "--no-commit", hash); err != nil {
+		g.run("revert", "--abort")
+		return err
+	}
+	subject, _ := g.run("log", "-1", "--format=%s", hash)
+	args := []string{"commit", "-q", "-m", fmt.Sprintf("Revert %q\n\nThis reverts commit %s.", strings.TrimSpace(subject), hash)}
+	if author != "" {
+		args = append(args, "--author", author+" <qbedit@localhost>")
+	}
+	if _, err := g.run(args...); err != nil {
+		g.run("revert", "--abort")
+		return err
+	}
+	return nil
+}
+
+// gitMessage is the commit message for an audited edit, eg.
+// "edit quest 1A2B3C4D5E6F7A8B (title, description) in chapter start".
+func gitMessage(e AuditEntry) string {
+	var b strings.Builder
+	b.WriteString(e.Action)
+	switch n := len(e.Quests); {
+	case n > 3:
+		fmt.Fprintf(&b, " %s and %d more", strings.Join(e.Quests[:3], ", "), n-3)
+	case n > 0:
+		b.WriteString(" " + strings.Join(e.Quests, ", "))
+	}
+	if e.Detail != "" {
+		fmt.Fprintf(&b, " (%s)", e.Detail)
+	}
+	if e.Chapter != "" {
+		b.WriteString(" in chapter " + e.Chapter)
+	}
+	return b.String()
+}
+
+// gitCommit commits the edit recorded by e. Failures are logged by the
+// caller; the edit itself has already been written.
+func (a *App) gitCommit(e AuditEntry) error {
+	if a.Git == nil {
+		return nil
+	}
+	return a.Git.Commit(gitMessage(e), e.Name, a.LangPath())
+}
+
+// gitLog handles GET "/git", the book's recent commits.
+func (a *App) gitLog(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "History")
+	data["GitEnabled"] = a.Git != nil
+	if msg := r.URL.Query().Get("msg"); msg != "" {
+		data["GitMsg"] = msg
+	}
+	if a.Git != nil {
+		commits, err := a.Git.Log(50)
+		if err != nil {
+			data["GitErr"] = err.Error()
+		}
+		data["Commits"] = commits
+	}
+	a.render(w, "git.gohtml", data)
+}
+
+// gitRevert handles POST "/git/revert", reverting the commit "hash".
+func (a *App) gitRevert(w http.ResponseWriter, r *http.Request) {
+	if a.Git == nil {
+		http.Error(w, "git is not enabled; start qbedit with --git", http.StatusBadRequest)
+		return
+	}
+	hash := strings.TrimSpace(r.FormValue("hash"))
+	if err := a.Git.Revert(hash, a.Prefs.Get(userID(r)).Name); err != nil {
+		http.Redirect(w, r, "/git?msg="+url.QueryEscape("Revert failed: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+	a.audit(r, "revert", "", nil, hash)
+	a.reload()
+	http.Redirect(w, r, "/git?msg="+url.QueryEscape("Reverted "+hash), http.StatusSeeOther)
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestGitCommitAndRevert(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	a := testApp(t)
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}, {"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-qm", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = a.Root()
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	g, err := OpenGitRepo(a.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Git = g
+
+	q := a.QB().Chapters[0].Quests[0]
+	title := q.Title
+	form := url.Values{"title": {"Committed"}, "subtitle": {q.Subtitle}, "description": {q.Description}}
+	req := httptest.NewRequest("POST", "/chapter/test/"+q.ID+"/save", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("save: %d %s", rec.Code, rec.Body)
+	}
+
+	commits, err := g.Log(10)
+	if err != nil || len(commits) != 2 {
+		t.Fatalf("log: %v %+v", err, commits)
+	}
+	if want := "edit quest " + q.ID + " (title) in chapter test"; commits[0].Subject != want {
+		t.Errorf("commit message %q, want %q", commits[0].Subject, want)
+	}
+
+	req = httptest.NewRequest("POST", "/git/revert", strings.NewReader(url.Values{"hash": {commits[0].Hash}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	if loc := rec.Header().Get("Location"); !strings.Contains(loc, "Reverted") {
+		t.Fatalf("revert: %s", loc)
+	}
+	if got := a.QB().questMap[q.ID].Title; got != title {
+		t.Errorf("title after revert is %q, want %q", got, title)
+	}
+	if commits, _ := g.Log(10); len(commits) != 3 || !strings.HasPrefix(commits[0].Subject, "Revert ") {
+		t.Errorf("after revert: %+v", commits)
+	}
+	if err := g.Revert("--help", ""); err == nil {
+		t.Error("expected an error for an invalid hash")
+	}
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"sort"
+
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+// Graph layout constants, in SVG user units.
+const (
+	graphNodeW = 200
+	graphNodeH = 28
+	graphColW  = 260
+	graphRowH  = 40
+	graphPad   = 20
+)
+
+// GraphNode is a quest placed in the dependency graph. External nodes are
+// dependencies from chapters outside of the graph's scope.
+type GraphNode struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Color    string `json:"color,omitempty"`
+	Chapter  string `json:"chapter"`
+	External bool   `json:"external,omitempty"`
+	Layer    int    `json:"layer"`
+	Row      int    `json:"row"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+
+	// Class is the minecraft.css color class for Color, eg. "c6"
+	Class string `json:"-"`
+}
+
+// GraphEdge points from a dependency to the quest that requires it.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	X1   int    `json:"x1"`
+	Y1   int    `json:"y1"`
+	X2   int    `json:"x2"`
+	Y2   int    `json:"y2"`
+}
+
+// Graph is a laid out dependency DAG.
+type Graph struct {
+	Nodes  []*GraphNode `json:"nodes"`
+	Edges  []GraphEdge  `json:"edges"`
+	Width  int          `json:"width"`
+	Height int          `json:"height"`
+}
+
+// buildGraph lays out the dependency graph of the quests in chapters. Quests
+// are placed in columns by the length of their longest dependency chain, so
+// progression reads left to right, and ordered within a column by the average
+// row of their dependencies to keep edges short.
+func buildGraph(qb *QuestBook, chapters []*Chapter) *Graph {
+	g := &Graph{}
+	nodes := make(map[string]*GraphNode)
+	deps := make(map[string][]string)
+
+	addNode := func(q *Quest, external bool) *GraphNode {
+		n := &GraphNode{
+			ID:       q.ID,
+			Title:    stripCodes(q.GetTitle()),
+			Color:    mcformat.FirstColor(q.GetTitle()),
+			External: external,
+		}
+		if c := mcformat.FirstColorCode(q.GetTitle()); c != 0 {
+			n.Class = "c" + string(c)
+		}
+		if q.Chapter != nil {
+			n.Chapter = q.Chapter.Name
+		}
+		if n.Title == "" {
+			n.Title = q.ID
+		}
+		nodes[q.ID] = n
+		g.Nodes = append(g.Nodes, n)
+		return n
+	}
+
+	for _, ch := range chapters {
+		for _, q := range ch.Quests {
+			addNode(q, false)
+		}
+	}
+	for _, ch := range chapters {
+		for _, q := range ch.Quests {
+			for _, d := range q.Dependencies {
+				if _, ok := nodes[d]; !ok {
+					dq, ok := qb.questMap[d]
+					if !ok {
+						// dangling dependency; nothing to draw
+						continue
+					}
+					addNode(dq, true)
+				}
+				deps[q.ID] = append(deps[q.ID], d)
+			}
+		}
+	}
+
+	// longest path layering; cycles are broken by treating an in-progress
+	// node as a root
+	const (
+		visiting = -1
+		unset    = -2
+	)
+	layer := make(map[string]int, len(nodes))
+	for id := range nodes {
+		layer[id] = unset
+	}
+	var visit func(id string) int
+	visit = func(id string) int {
+		switch l := layer[id]; l {
+		case visiting:
+			return 0
+		case unset:
+		default:
+			return l
+		}
+		layer[id] = visiting
+		l := 0
+		for _, d := range deps[id] {
+			l = max(l, visit(d)+1)
+		}
+		layer[id] = l
+		return l
+	}
+
+	var columns [][]*GraphNode
+	for _, n := range g.Nodes {
+		n.Layer = visit(n.ID)
+		for len(columns) <= n.Layer {
+			columns = append(columns, nil)
+		}
+		columns[n.Layer] = append(columns[n.Layer], n)
+	}
+
+	for _, col := range columns {
+		// barycenter of dependency rows from earlier columns; roots keep
+		// their file order
+		center := make(map[string]float64, len(col))
+		for i, n := range col {
+			ds := deps[n.ID]
+			if len(ds) == 0 {
+				center[n.ID] = float64(i)
+				continue
+			}
+			var sum float64
+			for _, d := range ds {
+				sum += float64(nodes[d].Row)
+			}
+			center[n.ID] = sum / float64(len(ds))
+		}
+		sort.SliceStable(col, func(i, j int) bool { return center[col[i].ID] < center[col[j].ID] })
+		for i, n := range col {
+			n.Row = i
+			n.X = graphPad + n.Layer*graphColW
+			n.Y = graphPad + i*graphRowH
+			g.Width = max(g.Width, n.X+graphNodeW+graphPad)
+			g.Height = max(g.Height, n.Y+graphNodeH+graphPad)
+		}
+	}
+
+	for _, n := range g.Nodes {
+		for _, d := range deps[n.ID] {
+			from := nodes[d]
+			g.Edges = append(g.Edges, GraphEdge{
+				From: d,
+				To:   n.ID,
+				X1:   from.X + graphNodeW,
+				Y1:   from.Y + graphNodeH/2,
+				X2:   n.X,
+				Y2:   n.Y + graphNodeH/2,
+			})
+		}
+	}
+	return g
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// graphBook returns a book of the chapters, each given as quests "ID:DEP,DEP".
+func graphBook(chapters map[string][]string) *QuestBook {
+	qb := &QuestBook{questMap: map[string]*Quest{}, chapterMap: map[string]*Chapter{}}
+	for _, name := range []string{"a", "b"} {
+		ch := &Chapter{Name: name}
+		for _, spec := range chapters[name] {
+			id, deps, _ := strings.Cut(spec, ":")
+			q := &Quest{ID: id, Title: "&6" + id, Chapter: ch}
+			if deps != "" {
+				q.Dependencies = strings.Split(deps, ",")
+			}
+			ch.Quests = append(ch.Quests, q)
+			qb.questMap[id] = q
+		}
+		qb.Chapters = append(qb.Chapters, ch)
+		qb.chapterMap[name] = ch
+	}
+	return qb
+}
+
+func TestBuildGraph(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		chapters map[string][]string
+		// nodes are "ID layer row", edges "FROM>TO"
+		nodes, edges []string
+	}{
+		{
+			name:     "chain",
+			chapters: map[string][]string{"a": {"C:B", "B:A", "A"}},
+			nodes:    []string{"C 2 0", "B 1 0", "A 0 0"},
+			edges:    []string{"B>C", "A>B"},
+		},
+		{
+			name:     "longest path",
+			chapters: map[string][]string{"a": {"A", "B:A", "C:A,B"}},
+			nodes:    []string{"A 0 0", "B 1 0", "C 2 0"},
+			edges:    []string{"A>B", "A>C", "B>C"},
+		},
+		{
+			// D depends on the second root, so it sorts after C
+			name:     "barycenter",
+			chapters: map[string][]string{"a": {"R1", "R2", "D:R2", "C:R1"}},
+			nodes:    []string{"R1 0 0", "R2 0 1", "D 1 1", "C 1 0"},
+			edges:    []string{"R2>D", "R1>C"},
+		},
+		{
+			name:     "dangling",
+			chapters: map[string][]string{"a": {"A:GONE"}},
+			nodes:    []string{"A 0 0"},
+		},
+		{
+			name:     "cycle",
+			chapters: map[string][]string{"a": {"A:B", "B:A"}},
+			nodes:    []string{"A 2 0", "B 1 0"},
+			edges:    []string{"B>A", "A>B"},
+		},
+		{
+			name:     "external",
+			chapters: map[string][]string{"a": {"A:X"}, "b": {"X", "Y"}},
+			nodes:    []string{"A 1 0", "X* 0 0"},
+			edges:    []string{"X>A"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			qb := graphBook(tc.chapters)
+			g := buildGraph(qb, qb.Chapters[:1])
+			var nodes, edges []string
+			for _, n := range g.Nodes {
+				ext := ""
+				if n.External {
+					ext = "*"
+				}
+				nodes = append(nodes, fmt.Sprintf("%s%s %d %d", n.ID, ext, n.Layer, n.Row))
+				if n.Title != n.ID || n.Class != "c6" || n.X != graphPad+n.Layer*graphColW || n.Y != graphPad+n.Row*graphRowH {
+					t.Errorf("node %+v", n)
+				}
+				if n.X+graphNodeW+graphPad > g.Width || n.Y+graphNodeH+graphPad > g.Height {
+					t.Errorf("node %s outside the graph's %dx%d", n.ID, g.Width, g.Height)
+				}
+			}
+			for _, e := range g.Edges {
+				edges = append(edges, e.From+">"+e.To)
+			}
+			if got, want := strings.Join(nodes, ", "), strings.Join(tc.nodes, ", "); got != want {
+				t.Errorf("nodes %s, want %s", got, want)
+			}
+			if got, want := strings.Join(edges, ", "), strings.Join(tc.edges, ", "); got != want {
+				t.Errorf("edges %s, want %s", got, want)
+			}
+		})
+	}
+}
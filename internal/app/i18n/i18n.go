@@ -0,0 +1,159 @@
+// Package i18n is a small message catalog for qbedit's own user interface.
+//
+// Messages are flat JSON objects mapping a key (eg. "nav.chapters") to its
+// text, one file per language named after its tag (eg. "de.json", "pt-BR.json").
+// English is embedded and is the fallback for missing keys; further languages
+// can be embedded in locales/ or loaded at startup from a translations
+// directory, whose files override embedded messages key by key.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fallback is the language every key is expected to exist in.
+const Fallback = "en"
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+// Catalog holds translated messages for one or more languages.
+type Catalog struct {
+	// Default is the language used when a request doesn't pick one.
+	Default string
+	msgs    map[string]map[string]string
+}
+
+// New returns a catalog with the embedded languages loaded.
+func New(def string) (*Catalog, error) {
+	c := &Catalog{Default: Fallback, msgs: make(map[string]map[string]string)}
+	sub, _ := fs.Sub(localesFS, "locales")
+	if err := c.load(sub); err != nil {
+		return nil, err
+	}
+	if def != "" {
+		c.Default = normalize(def)
+	}
+	return c, nil
+}
+
+// LoadDir loads every <lang>.json file in dir.
+func (c *Catalog) LoadDir(dir string) error {
+	return c.load(os.DirFS(dir))
+}
+
+func (c *Catalog) load(fsys fs.FS) error {
+	paths, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		var m map[string]string
+		if err := json.Unmarshal(b, &m); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		lang := normalize(strings.TrimSuffix(filepath.Base(p), ".json"))
+		if c.msgs[lang] == nil {
+			c.msgs[lang] = make(map[string]string, len(m))
+		}
+		for k, v := range m {
+			c.msgs[lang][k] = v
+		}
+	}
+	return nil
+}
+
+// Langs returns the available languages, sorted.
+func (c *Catalog) Langs() []string {
+	langs := make([]string, 0, len(c.msgs))
+	for l := range c.msgs {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// T returns the message for key in lang. A regional language falls back to
+// its base language (pt-BR to pt), then to the default language and English.
+// If the key is missing everywhere, the key itself is returned so untranslated
+// strings are easy to spot. With args, the message is a fmt format string.
+func (c *Catalog) T(lang, key string, args ...any) string {
+	msg, ok := c.lookup(lang, key)
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+func (c *Catalog) lookup(lang, key string) (string, bool) {
+	lang = normalize(lang)
+	for _, l := range []string{lang, base(lang), c.Default, base(c.Default), Fallback} {
+		if m, ok := c.msgs[l][key]; ok {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// Match picks the best available language for an Accept-Language header,
+// returning the default language if none match.
+func (c *Catalog) Match(accept string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		tag = normalize(tag)
+		for _, l := range []string{tag, base(tag)} {
+			if _, ok := c.msgs[l]; ok && q > bestQ {
+				best, bestQ = l, q
+				break
+			}
+		}
+	}
+	if best == "" {
+		return c.Default
+	}
+	return best
+}
+
+// Has returns true if lang has any messages.
+func (c *Catalog) Has(lang string) bool {
+	_, ok := c.msgs[normalize(lang)]
+	return ok
+}
+
+// normalize canonicalizes a language tag, eg. "pt_br" to "pt-BR".
+func normalize(tag string) string {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	lang, region, ok := strings.Cut(tag, "-")
+	if !ok {
+		return strings.ToLower(lang)
+	}
+	return strings.ToLower(lang) + "-" + strings.ToUpper(region)
+}
+
+// base returns the language of a regional tag, eg. "pt" for "pt-BR".
+func base(tag string) string {
+	l, _, _ := strings.Cut(tag, "-")
+	return l
+}
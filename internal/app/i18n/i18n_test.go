@@ -0,0 +1,48 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	c, err := New(Fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pt.json"), []byte(`{"nav.chapters": "Capítulos"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct{ lang, key, want string }{
+		{"en", "nav.chapters", "Chapters"},
+		{"pt", "nav.chapters", "Capítulos"},
+		{"pt_br", "nav.chapters", "Capítulos"},
+		{"pt", "nav.theme", "Theme:"},
+		{"xx", "nav.chapters", "Chapters"},
+		{"en", "no.such.key", "no.such.key"},
+	} {
+		if got := c.T(tc.lang, tc.key); got != tc.want {
+			t.Errorf("T(%q, %q) = %q, want %q", tc.lang, tc.key, got, tc.want)
+		}
+	}
+	if got := c.T("en", "nav.failed", 3); got != "3 failed" {
+		t.Errorf("T with args = %q", got)
+	}
+
+	for accept, want := range map[string]string{
+		"":                        "en",
+		"pt-BR,pt;q=0.9,en;q=0.8": "pt",
+		"fr-FR,en;q=0.5,pt;q=0.4": "en",
+		"de":                      "en",
+	} {
+		if got := c.Match(accept); got != want {
+			t.Errorf("Match(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package app
+
+import (
+	"archive/zip"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// IconSet serves item textures found in resource packs and mod jars, so that
+// items are shown next to the quests and tasks that name them. Its directory
+// (--assets) can hold resource packs, either unpacked or zipped, and mod
+// jars, which carry their textures the same way; it can also be a resource
+// pack itself. Only the index of textures is built up front, and images are
+// read when they are first asked for.
+type IconSet struct {
+	// sources maps item ids to where their texture is
+	sources map[string]iconSource
+	mu      sync.Mutex
+	cache   map[string][]byte
+}
+
+// iconSource is a texture, either a file or an entry in the zip at archive.
+type iconSource struct {
+	archive string
+	name    string
+	// block is set for block textures, which are used for items without
+	// their own
+	block bool
+}
+
+// textureID returns the item id for an asset path like
+// assets/minecraft/textures/item/stick.png and whether it is a block
+// texture, or "" for other files.
+func textureID(name string) (string, bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 5 || parts[0] != "assets" || parts[2] != "textures" || !strings.HasSuffix(parts[4], ".png") {
+		return "", false
+	}
+	var block bool
+	switch parts[3] {
+	case "item", "items":
+	case "block", "blocks":
+		block = true
+	default:
+		return "", false
+	}
+	return parts[1] + ":" + strings.TrimSuffix(parts[4], ".png"), block
+}
+
+// OpenIcons indexes the textures of the resource packs and jars in dir.
+func OpenIcons(dir string) (*IconSet, error) {
+	s := &IconSet{sources: make(map[string]iconSource), cache: make(map[string][]byte)}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.addDir(dir); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		switch ext := strings.ToLower(filepath.Ext(e.Name())); {
+		case e.IsDir() && e.Name() != "assets":
+			err = s.addDir(p)
+		case ext == ".jar" || ext == ".zip":
+			err = s.addZip(p)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+	}
+	return s, nil
+}
+
+// add records src as the texture for id unless an item texture is already
+// known; the first pack or jar to provide a texture wins.
+func (s *IconSet) add(id string, src iconSource) {
+	if cur, ok := s.sources[id]; ok && (!cur.block || src.block) {
+		return
+	}
+	s.sources[id] = src
+}
+
+// addDir indexes an unpacked resource pack, if dir is one.
+func (s *IconSet) addDir(dir string) error {
+	assets := filepath.Join(dir, "assets")
+	if _, err := os.Stat(assets); err != nil {
+		return nil
+	}
+	return filepath.WalkDir(assets, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if id, block := textureID(filepath.ToSlash(rel)); id != "" {
+			s.add(id, iconSource{name: p, block: block})
+		}
+		return nil
+	})
+}
+
+// addZip indexes a zipped resource pack or a mod jar.
+func (s *IconSet) addZip(p string) error {
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if id, block := textureID(f.Name); id != "" {
+			s.add(id, iconSource{archive: p, name: f.Name, block: block})
+		}
+	}
+	return nil
+}
+
+// Len returns how many items have textures.
+func (s *IconSet) Len() int { return len(s.sources) }
+
+// IDs returns the ids of the items with textures.
+func (s *IconSet) IDs() []string {
+	ids := make([]string, 0, len(s.sources))
+	for id := range s.sources {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Has reports whether there is a texture for the item id.
+func (s *IconSet) Has(id string) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.sources[id]
+	return ok
+}
+
+// PNG returns the texture for the item id.
+func (s *IconSet) PNG(id string) ([]byte, error) {
+	src, ok := s.sources[id]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.cache[id]; ok {
+		return b, nil
+	}
+	var b []byte
+	var err error
+	if src.archive == "" {
+		b, err = os.ReadFile(src.name)
+	} else {
+		b, err = readZipFile(src.archive, src.name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cache[id] = b
+	return b, nil
+}
+
+// readZipFile returns the contents of the file name in the zip at archive.
+func readZipFile(archive, name string) ([]byte, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// itemIcon returns an image of the item id, or nothing if there is no
+// texture for it.
+func (a *App) itemIcon(id string) template.HTML {
+	if !a.Icons.Has(id) {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf(`<img class="item-icon" src="%s/items/%s.png" alt="" title="%s" />`,
+		a.Base, template.HTMLEscapeString(id), template.HTMLEscapeString(id)))
+}
+
+// itemPNG handles GET "/items/{id}.png", the texture of an item.
+func (a *App) itemPNG(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(chi.URLParam(r, "id"), ".png")
+	if !a.Icons.Has(id) {
+		http.NotFound(w, r)
+		return
+	}
+	b, err := a.Icons.PNG(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "max-age=3600")
+	w.Write(b)
+}
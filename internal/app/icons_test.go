@@ -0,0 +1,77 @@
+package app
+
+import (
+	"archive/zip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIcons(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("pack/assets/minecraft/textures/block/oak_log.png", "log")
+	write("pack/assets/minecraft/textures/item/stick.png", "stick")
+	write("pack/assets/minecraft/textures/item/tools/nested.png", "nested")
+
+	f, err := os.Create(filepath.Join(dir, "mod.jar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range map[string]string{
+		"assets/mymod/textures/item/gear.png":       "gear",
+		"assets/minecraft/textures/block/stick.png": "block stick",
+		"assets/mymod/lang/en_us.json":              "{}",
+	} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(body))
+	}
+	zw.Close()
+	f.Close()
+
+	icons, err := OpenIcons(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if icons.Len() != 3 || icons.Has("minecraft:nested") {
+		t.Errorf("indexed %d icons: %v", icons.Len(), icons.sources)
+	}
+	for id, want := range map[string]string{
+		"minecraft:oak_log": "log",
+		"minecraft:stick":   "stick",
+		"mymod:gear":        "gear",
+	} {
+		if b, err := icons.PNG(id); err != nil || string(b) != want {
+			t.Errorf("PNG(%s) = %q, %v", id, b, err)
+		}
+	}
+
+	a := testApp(t)
+	a.Icons = icons
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+	if rec := get("/items/mymod:gear.png"); rec.Code != http.StatusOK || rec.Body.String() != "gear" {
+		t.Errorf("GET gear: %d %q", rec.Code, rec.Body)
+	}
+	if rec := get("/items/mymod:missing.png"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET missing: %d", rec.Code)
+	}
+	if body := get("/chapter/test").Body.String(); !strings.Contains(body, `src="/items/minecraft:oak_log.png"`) {
+		t.Error("chapter page has no oak log icon")
+	}
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ItemRegistry is the set of item ids known to exist, either from a JSON dump
+// (--items, eg. from KubeJS or a registry export) or taken from the textures
+// in --assets. Item ids in tasks and rewards are typed by hand, and a typo
+// only shows up in game, so the editor suggests known ids as they are typed
+// and marks ones it doesn't know.
+type ItemRegistry struct {
+	// ids are sorted
+	ids []string
+	set map[string]bool
+	// words counts the ids whose names have each word, see itemWords
+	words map[string]int
+}
+
+// NewItemRegistry returns a registry of ids.
+func NewItemRegistry(ids []string) *ItemRegistry {
+	r := &ItemRegistry{set: make(map[string]bool, len(ids)), words: make(map[string]int)}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || r.set[id] {
+			continue
+		}
+		r.set[id] = true
+		r.ids = append(r.ids, id)
+		for _, w := range itemWords(id) {
+			r.words[w]++
+		}
+	}
+	slices.Sort(r.ids)
+	return r
+}
+
+// LoadItemRegistry reads a JSON dump of item ids: either a list of ids or an
+// object keyed by them, as registry exports write.
+func LoadItemRegistry(path string) (*ItemRegistry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err == nil {
+		return NewItemRegistry(list), nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, fmt.Errorf("%s: want a list of item ids or an object keyed by them", path)
+	}
+	for id := range obj {
+		list = append(list, id)
+	}
+	return NewItemRegistry(list), nil
+}
+
+// Len returns how many items are known.
+func (r *ItemRegistry) Len() int { return len(r.ids) }
+
+// Known reports whether id is a known item. Without a registry every id is.
+func (r *ItemRegistry) Known(id string) bool {
+	return r == nil || r.set[id]
+}
+
+// Search returns up to limit ids matching q: those whose id or name (the
+// part after the namespace) starts with q first, then those containing it.
+func (r *ItemRegistry) Search(q string, limit int) []string {
+	if r == nil {
+		return nil
+	}
+	q = strings.ToLower(strings.TrimSpace(q))
+	var prefix, within []string
+	for _, id := range r.ids {
+		_, name, _ := strings.Cut(id, ":")
+		switch {
+		case strings.HasPrefix(id, q) || strings.HasPrefix(name, q):
+			prefix = append(prefix, id)
+		case strings.Contains(id, q):
+			within = append(within, id)
+		}
+		if len(prefix) >= limit {
+			break
+		}
+	}
+	res := append(prefix, within...)
+	return res[:min(len(res), limit)]
+}
+
+// unknownItem reports whether id names an item missing from the registry.
+func (a *App) unknownItem(id string) bool {
+	return id != "" && !a.Items.Known(id)
+}
+
+// apiItems handles GET "/api/items?q=", item ids for autocomplete. known is
+// whether q itself is an item, and is left out when there is no registry.
+func (a *App) apiItems(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 || n > 100 {
+		n = 20
+	}
+	res := map[string]any{"ok": true, "items": []string{}}
+	if a.Items != nil {
+		if items := a.Items.Search(q, n); items != nil {
+			res["items"] = items
+		}
+		res["known"] = a.Items.Known(strings.TrimSpace(q))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
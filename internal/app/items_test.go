@@ -0,0 +1,61 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestItemRegistry(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"list.json": `["minecraft:stick", "minecraft:oak_log", "mymod:stick_bundle", "minecraft:stick"]`,
+		"obj.json":  `{"minecraft:stick": {}, "minecraft:oak_log": {"id": 1}, "mymod:stick_bundle": {}}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		reg, err := LoadItemRegistry(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reg.Len() != 3 || !reg.Known("minecraft:oak_log") || reg.Known("minecraft:oak_logs") {
+			t.Errorf("%s: %v", name, reg.ids)
+		}
+		// name prefix matches come before ids only containing q
+		if got := reg.Search("stick", 10); !slices.Equal(got, []string{"minecraft:stick", "mymod:stick_bundle"}) {
+			t.Errorf("%s: Search(stick) = %v", name, got)
+		}
+		if got := reg.Search("log", 10); !slices.Equal(got, []string{"minecraft:oak_log"}) {
+			t.Errorf("%s: Search(log) = %v", name, got)
+		}
+	}
+
+	a := testApp(t)
+	a.Items = NewItemRegistry([]string{"minecraft:crafting_table", "minecraft:string"})
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/api/items?q=minecraft:str", nil))
+	var res struct {
+		Items []string
+		Known *bool
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(res.Items, []string{"minecraft:string"}) || res.Known == nil || *res.Known {
+		t.Errorf("GET /api/items = %s", rec.Body)
+	}
+
+	// the quest's other item tasks aren't in the registry
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/chapter/test/"+a.QB().Chapters[0].Quests[0].ID, nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, `value="minecraft:crafting_table" placeholder="item, entity, advancement, dimension or amount" />`) ||
+		!strings.Contains(body, `class="unknown-item"`) {
+		t.Error("quest page doesn't mark unknown items")
+	}
+}
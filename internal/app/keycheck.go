@@ -0,0 +1,225 @@
+package app
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+//go:embed samples/*.snbt
+var samplesFS embed.FS
+
+// keySet holds the known keys of each kind of compound: "chapter", "quest",
+// "task", "reward", "image" and "quest link".
+type keySet map[string]map[string]bool
+
+// add adds the keys of the chapter compound ch and the compounds in it.
+func (ks keySet) add(ch map[string]any) {
+	addKeys := func(kind string, m map[string]any) {
+		if ks[kind] == nil {
+			ks[kind] = make(map[string]bool)
+		}
+		for k := range m {
+			ks[kind][k] = true
+		}
+	}
+	addKeys("chapter", ch)
+	for _, q := range compounds(ch["quests"]) {
+		addKeys("quest", q)
+		for _, t := range compounds(q["tasks"]) {
+			addKeys("task", t)
+		}
+		for _, r := range compounds(q["rewards"]) {
+			addKeys("reward", r)
+		}
+	}
+	for _, im := range compounds(ch["images"]) {
+		addKeys("image", im)
+	}
+	for _, l := range compounds(ch["quest_links"]) {
+		addKeys("quest link", l)
+	}
+}
+
+// compounds returns the compounds in the list v.
+func compounds(v any) []map[string]any {
+	list, _ := v.([]any)
+	var ms []map[string]any
+	for _, e := range list {
+		if m, ok := e.(map[string]any); ok {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+var (
+	referenceMu   sync.RWMutex
+	referenceKeys keySet
+)
+
+// knownKeys returns the known keys, reading the shipped samples the first
+// time.
+func knownKeys() keySet {
+	referenceMu.RLock()
+	ks := referenceKeys
+	referenceMu.RUnlock()
+	if ks != nil {
+		return ks
+	}
+	referenceMu.Lock()
+	defer referenceMu.Unlock()
+	if referenceKeys == nil {
+		referenceKeys = make(keySet)
+		if err := addReference(referenceKeys, samplesFS, "samples"); err != nil {
+			panic(fmt.Sprintf("reading sample chapters: %v", err))
+		}
+	}
+	return referenceKeys
+}
+
+// AddReference adds the keys of the chapter files at path, a file or a
+// directory such as another pack's ftbquests dir, to the known keys. Files
+// without quests, eg. chapter groups, are skipped.
+func AddReference(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	knownKeys()
+	referenceMu.Lock()
+	defer referenceMu.Unlock()
+	// copied, so that checks already running see a consistent set
+	ks := make(keySet, len(referenceKeys))
+	for kind, keys := range referenceKeys {
+		ks[kind] = make(map[string]bool, len(keys))
+		for k := range keys {
+			ks[kind][k] = true
+		}
+	}
+	if fi.IsDir() {
+		err = addReference(ks, os.DirFS(path), ".")
+	} else {
+		err = addReference(ks, os.DirFS(filepath.Dir(path)), filepath.Base(path))
+	}
+	if err != nil {
+		return err
+	}
+	referenceKeys = ks
+	return nil
+}
+
+// addReference adds the keys of the chapter files at root in fsys to ks.
+func addReference(ks keySet, fsys fs.FS, root string) error {
+	return fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".snbt") {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		v, err := snbt.Decode(bytes.NewReader(b))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if ch, ok := v.(map[string]any); ok && ch["quests"] != nil {
+			ks.add(ch)
+		}
+		return nil
+	})
+}
+
+// misspelledKey returns the known key of kind that key is probably a
+// misspelling of, or "" if key is known or not close to any known key.
+func misspelledKey(ks keySet, kind, key string) string {
+	known := ks[kind]
+	if known[key] {
+		return ""
+	}
+	// short keys are close to too much to allow two edits
+	limit := 2
+	if len(key) <= 5 {
+		limit = 1
+	}
+	best, bestDist := "", limit+1
+	for _, k := range slices.Sorted(maps.Keys(known)) {
+		if d := editDistance(key, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and swaps of adjacent letters that turn a into b.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	// three rows of the distance matrix: two rows up, the row above and
+	// this one
+	prev2 := make([]int, len(br)+1)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(br)]
+}
+
+// misspelledKeys reports the keys of ch and the compounds in it that are
+// probably misspelled, with the quest they are in or nil. FTB Quests ignores
+// keys it doesn't know, so a misspelled one such as "dependancies" fails
+// silently: the quest just has no dependencies. Keys are compared with those
+// of known-good chapters, the samples shipped in samples/ and any given with
+// --reference, and unknown keys a letter or two from a known one are
+// reported. Other unknown keys, eg. of addon task types, are left alone.
+func misspelledKeys(ch *Chapter, report func(q *Quest, msg string)) {
+	ks := knownKeys()
+	check := func(q *Quest, kind, what string, m map[string]any) {
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			if k := misspelledKey(ks, kind, key); k != "" {
+				report(q, fmt.Sprintf("%s key %q is probably %q; FTB Quests ignores it", what, key, k))
+			}
+		}
+	}
+	check(nil, "chapter", "chapter", ch.raw)
+	for i, im := range compounds(ch.raw["images"]) {
+		check(nil, "image", fmt.Sprintf("image %d", i+1), im)
+	}
+	for _, l := range compounds(ch.raw["quest_links"]) {
+		id, _ := l["id"].(string)
+		check(nil, "quest link", "quest link "+id, l)
+	}
+	for _, q := range ch.Quests {
+		check(q, "quest", "quest", q.raw)
+		for _, t := range q.Tasks {
+			check(q, "task", "task "+t.Base().ID, t.Base().raw)
+		}
+		for _, r := range q.Rewards {
+			check(q, "reward", "reward "+r.Base().ID, r.Base().raw)
+		}
+	}
+}
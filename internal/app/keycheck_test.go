@@ -0,0 +1,85 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"dependencies", "dependencies", 0},
+		{"dependancies", "dependencies", 1},
+		{"depnedencies", "dependencies", 1},
+		{"titel", "title", 1},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+	}
+	for _, c := range cases {
+		if got := editDistance(c.a, c.b); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestMisspelledKeys(t *testing.T) {
+	a := testApp(t)
+	issuesOf := func() []BookIssue {
+		var found []BookIssue
+		for _, is := range validateBook(a.QB()) {
+			if is.Kind == IssueMisspelled {
+				found = append(found, is)
+			}
+		}
+		return found
+	}
+	if found := issuesOf(); len(found) != 0 {
+		t.Fatalf("known-good test chapter has misspelled keys: %+v", found)
+	}
+
+	q := a.QB().Chapters[0].Quests[0]
+	q.raw["dependancies"] = []any{"0000000000000001"}
+	q.raw["hide_until_dep_visible"] = true
+	q.raw["some_addon_key"] = true
+	q.Tasks[0].Base().raw["cout"] = 2
+	found := issuesOf()
+	var msgs []string
+	for _, is := range found {
+		if is.Quest != q {
+			t.Errorf("issue on the wrong quest: %+v", is)
+		}
+		msgs = append(msgs, is.Message)
+	}
+	got := strings.Join(msgs, "\n")
+	for _, want := range []string{`"dependancies" is probably "dependencies"`, `"hide_until_dep_visible" is probably "hide_until_deps_visible"`, `"cout" is probably "count"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in:\n%s", want, got)
+		}
+	}
+	if len(found) != 3 {
+		t.Errorf("got %d issues:\n%s", len(found), got)
+	}
+
+	// a reference chapter using the key makes it known
+	dir := t.TempDir()
+	ref := `{ id: "1", quests: [ { id: "2", dependancies: [ ] } ] }`
+	if err := os.WriteFile(filepath.Join(dir, "ref.snbt"), []byte(ref), 0644); err != nil {
+		t.Fatal(err)
+	}
+	saved := knownKeys()
+	defer func() {
+		referenceMu.Lock()
+		referenceKeys = saved
+		referenceMu.Unlock()
+	}()
+	if err := AddReference(dir); err != nil {
+		t.Fatal(err)
+	}
+	if found := issuesOf(); len(found) != 2 {
+		t.Errorf("with the reference: %+v", found)
+	}
+}
@@ -0,0 +1,301 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// langKeyPattern matches a field that is exactly a translation key. The
+// description placeholders FTB Quests uses ({@pagebreak}, {image:...}) don't
+// match.
+var langKeyPattern = regexp.MustCompile(`^\{([A-Za-z0-9_\-]+(?:\.[A-Za-z0-9_\-]+)+)\}$`)
+
+// langKey returns the key s refers to, if it is a translation key.
+func langKey(s string) (string, bool) {
+	m := langKeyPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// findLangFile looks for the en_us.json of a KubeJS setup next to the
+// ftbquests dir root (which is normally <instance>/config/ftbquests), and
+// returns "" if there is none.
+func findLangFile(root string) string {
+	matches, _ := filepath.Glob(filepath.Join(root, "..", "..", "kubejs", "assets", "*", "lang", "en_us.json"))
+	sort.Strings(matches)
+	if len(matches) == 0 {
+		return ""
+	}
+	return filepath.Clean(matches[0])
+}
+
+// SetLangFile resolves the book's translation keys from the lang file at
+// path instead of the one found next to the ftbquests dir.
+func (a *App) SetLangFile(path string) error {
+	if _, err := LoadLangFile(path); err != nil {
+		return err
+	}
+	a.setLangPath(path)
+	a.reload()
+	return nil
+}
+
+// LangFile is a Minecraft JSON lang file. The order of its keys is kept so
+// that saving it doesn't reorder a file people also edit by hand.
+//
+// Many packs keep quest text out of the SNBT files: a title of
+// "{quest.mypack.start.title}" is a translation key, and the text is in a
+// lang file, usually provided by KubeJS at
+// kubejs/assets/<namespace>/lang/en_us.json. When a book has one, keyed
+// fields are resolved as the book is loaded (see resolveLang), so everything
+// in qbedit shows and edits the text, and edited text is written back to the
+// lang file when a quest is saved (see unresolveLang).
+type LangFile struct {
+	Path   string
+	keys   []string
+	values map[string]string
+}
+
+// LoadLangFile reads the lang file at path.
+func LoadLangFile(path string) (*LangFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &LangFile{Path: path, values: make(map[string]string)}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, fmt.Errorf("%s: expected a JSON object", path)
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		key, _ := t.(string)
+		var v string
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+		if _, ok := l.values[key]; !ok {
+			l.keys = append(l.keys, key)
+		}
+		l.values[key] = v
+	}
+	return l, nil
+}
+
+// Get returns the text for key.
+func (l *LangFile) Get(key string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	v, ok := l.values[key]
+	return v, ok
+}
+
+// Set sets the text for key, adding it at the end if it is new, and reports
+// whether anything changed.
+func (l *LangFile) Set(key, value string) bool {
+	old, ok := l.values[key]
+	if ok && old == value {
+		return false
+	}
+	if !ok {
+		l.keys = append(l.keys, key)
+	}
+	l.values[key] = value
+	return true
+}
+
+// Len returns the number of keys in the file.
+func (l *LangFile) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.keys)
+}
+
+// clone returns a copy of l that can be modified without affecting l.
+func (l *LangFile) clone() *LangFile {
+	if l == nil {
+		return nil
+	}
+	c := &LangFile{Path: l.Path, keys: append([]string(nil), l.keys...), values: make(map[string]string, len(l.values))}
+	for k, v := range l.values {
+		c.values[k] = v
+	}
+	return c
+}
+
+// Save writes the file back to its path.
+func (l *LangFile) Save() error {
+	return writeFile(l.Path, l.encode(), 0644)
+}
+
+// encode returns the file's JSON, with its keys in order.
+func (l *LangFile) encode() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for i, k := range l.keys {
+		kb, _ := marshalNoEscape(k)
+		vb, _ := marshalNoEscape(l.values[k])
+		fmt.Fprintf(&buf, "  %s: %s", kb, vb)
+		if i < len(l.keys)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// marshalNoEscape encodes v as JSON without escaping &, < and >, which are
+// common in quest text.
+func marshalNoEscape(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// questLangKeys records which of a quest's text fields were translation keys
+// when it was loaded.
+type questLangKeys struct {
+	title, subtitle string
+	// desc has an entry for each line of the SNBT description: the line's
+	// key, or "" for literal text, and how many lines of Description the
+	// line became (a key's text may have several).
+	desc []descLine
+}
+
+type descLine struct {
+	key   string
+	lines int
+}
+
+// resolveLang resolves the chapter's title and its quests' text from l.
+func (ch *Chapter) resolveLang(l *LangFile) {
+	if l == nil {
+		return
+	}
+	if k, ok := langKey(ch.Title); ok {
+		if v, ok := l.Get(k); ok {
+			ch.titleKey, ch.Title = k, v
+		}
+	}
+	for _, q := range ch.Quests {
+		q.resolveLang(l)
+	}
+}
+
+// saveChapter writes ch to path. The text of fields that were translation
+// keys, including the chapter title, is written to a copy of lang instead,
+// which is saved with the chapter if it changed; lang itself may belong to a
+// loaded book and is not modified.
+func saveChapter(ch *Chapter, path string, lang *LangFile) error {
+	t := newBookWrite(lang)
+	if err := t.stageChapter(ch, path); err != nil {
+		return err
+	}
+	return t.commit()
+}
+
+// resolveLang replaces q's keyed text fields with their text from l.
+// Keys missing from l are left as they are.
+func (q *Quest) resolveLang(l *LangFile) {
+	if l == nil {
+		return
+	}
+	var keys questLangKeys
+	found := false
+	if k, ok := langKey(q.Title); ok {
+		if v, ok := l.Get(k); ok {
+			keys.title, q.Title, found = k, v, true
+		}
+	}
+	if k, ok := langKey(q.Subtitle); ok {
+		if v, ok := l.Get(k); ok {
+			keys.subtitle, q.Subtitle, found = k, v, true
+		}
+	}
+	if q.Description != "" {
+		var lines []string
+		for _, line := range strings.Split(q.Description, "\n") {
+			dl := descLine{lines: 1}
+			if k, ok := langKey(line); ok {
+				if v, ok := l.Get(k); ok {
+					dl = descLine{key: k, lines: strings.Count(v, "\n") + 1}
+					line, found = v, true
+				}
+			}
+			keys.desc = append(keys.desc, dl)
+			lines = append(lines, line)
+		}
+		if found {
+			q.Description = strings.Join(lines, "\n")
+		}
+	}
+	if found {
+		q.langKeys = &keys
+	}
+}
+
+// unresolveLang is the inverse of resolveLang: text in q's keyed fields is
+// written to l, and the fields are set back to their keys so that the SNBT
+// keeps them. It reports whether l changed.
+func (q *Quest) unresolveLang(l *LangFile) (bool, error) {
+	keys := q.langKeys
+	if keys == nil || l == nil {
+		return false, nil
+	}
+	changed := false
+	if keys.title != "" {
+		changed = l.Set(keys.title, q.Title) || changed
+		q.Title = "{" + keys.title + "}"
+	}
+	if keys.subtitle != "" {
+		changed = l.Set(keys.subtitle, q.Subtitle) || changed
+		q.Subtitle = "{" + keys.subtitle + "}"
+	}
+	if len(keys.desc) > 0 {
+		lines := strings.Split(q.Description, "\n")
+		want := 0
+		for _, dl := range keys.desc {
+			want += dl.lines
+		}
+		keyed := slices.ContainsFunc(keys.desc, func(dl descLine) bool { return dl.key != "" })
+		if keyed && len(lines) != want {
+			return changed, fmt.Errorf("quest %s: its description uses translation keys, so it must keep %d lines (it has %d); edit %s to change its length", q.ID, want, len(lines), filepath.Base(l.Path))
+		}
+		if keyed {
+			var out []string
+			i := 0
+			for _, dl := range keys.desc {
+				text := strings.Join(lines[i:i+dl.lines], "\n")
+				i += dl.lines
+				if dl.key == "" {
+					out = append(out, text)
+					continue
+				}
+				changed = l.Set(dl.key, text) || changed
+				out = append(out, "{"+dl.key+"}")
+			}
+			q.Description = strings.Join(out, "\n")
+		}
+	}
+	q.langKeys = nil
+	return changed, nil
+}
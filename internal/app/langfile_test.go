@@ -0,0 +1,94 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLangFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "en_us.json")
+	src := "{\n  \"z.title\": \"Start & Go\",\n  \"a.desc\": \"One\\nTwo\",\n  \"m.other\": \"x\"\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := LoadLangFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := &Quest{ID: "0000000000000001", Title: "{z.title}", Description: "Intro\n{a.desc}\n{@pagebreak}"}
+	q.resolveLang(l)
+	if q.Title != "Start & Go" || q.Description != "Intro\nOne\nTwo\n{@pagebreak}" {
+		t.Fatalf("resolved to %q, %q", q.Title, q.Description)
+	}
+
+	q.Title = "Start & Run"
+	q.Description = "Intro!\nUno\nDos\n{@pagebreak}"
+	c := l.clone()
+	changed, err := q.unresolveLang(c)
+	if err != nil || !changed {
+		t.Fatalf("unresolve: %v, %v", changed, err)
+	}
+	if q.Title != "{z.title}" || q.Description != "Intro!\n{a.desc}\n{@pagebreak}" {
+		t.Errorf("unresolved to %q, %q", q.Title, q.Description)
+	}
+	if v, _ := l.Get("z.title"); v != "Start & Go" {
+		t.Errorf("the original lang file changed: %q", v)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(path)
+	want := "{\n  \"z.title\": \"Start & Run\",\n  \"a.desc\": \"Uno\\nDos\",\n  \"m.other\": \"x\"\n}\n"
+	if string(b) != want {
+		t.Errorf("saved:\n%s\nwant:\n%s", b, want)
+	}
+}
+
+func TestLangFileLineCount(t *testing.T) {
+	l := &LangFile{values: map[string]string{}}
+	l.Set("a.desc", "One\nTwo")
+	q := &Quest{Description: "{a.desc}"}
+	q.resolveLang(l)
+	q.Description = "One\nTwo\nThree"
+	if _, err := q.unresolveLang(l); err == nil {
+		t.Error("expected an error when a keyed description changes length")
+	}
+
+	// quests without keys are left alone
+	q = &Quest{Title: "{no.such.key}"}
+	q.resolveLang(l)
+	if changed, err := q.unresolveLang(l); changed || err != nil || q.Title != "{no.such.key}" {
+		t.Errorf("got %v, %v, %q", changed, err, q.Title)
+	}
+}
+
+func TestLocalizeChapter(t *testing.T) {
+	ch := NewChapter(map[string]any{"title": "Start", "quests": []any{
+		map[string]any{"id": "00000000000000AB", "title": "First", "subtitle": "{done.already}",
+			"description": []any{"Hello", "", "{@pagebreak}", "&eWorld"}},
+	}})
+	ch.Name = "start"
+	l := &LangFile{values: map[string]string{}}
+	if n := localizeChapter(ch, "pack", l); n != 4 {
+		t.Fatalf("added %d keys, want 4", n)
+	}
+	q := ch.Quests[0]
+	if q.Title != "{pack.quest.00000000000000ab.title}" || q.Subtitle != "{done.already}" {
+		t.Errorf("got %q, %q", q.Title, q.Subtitle)
+	}
+	want := "{pack.quest.00000000000000ab.description.0}\n\n{@pagebreak}\n{pack.quest.00000000000000ab.description.3}"
+	if q.Description != want {
+		t.Errorf("description is %q", q.Description)
+	}
+	if v, _ := l.Get("pack.chapter.start.title"); v != "Start" || ch.raw["title"] != "{pack.chapter.start.title}" {
+		t.Errorf("chapter title %q, %v", v, ch.raw["title"])
+	}
+	if v, _ := l.Get("pack.quest.00000000000000ab.description.3"); v != "&eWorld" {
+		t.Errorf("got %q", v)
+	}
+	if n := localizeChapter(ch, "pack", l); n != 0 {
+		t.Errorf("localizing again added %d keys", n)
+	}
+}
@@ -0,0 +1,258 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// lintRule is a check on a line of quest text.
+type lintRule struct {
+	Name        string
+	Description string
+	// enabled reports whether the pack uses the rule.
+	enabled func(cfg PackConfig) bool
+	// fix returns the line as the rule wants it; a line that is already
+	// fine is returned unchanged.
+	fix func(cfg PackConfig, line string) string
+}
+
+var lintRules = []lintRule{
+	{
+		Name:        "orphan",
+		Description: "A § that doesn't start a code, or an & ending a line, is removed.",
+		enabled:     func(PackConfig) bool { return true },
+		fix:         func(_ PackConfig, line string) string { return skipJSON(line, stripOrphanSigns) },
+	},
+	{
+		Name:        "trailing",
+		Description: "Codes at the end of a line style no text and are removed.",
+		enabled:     func(PackConfig) bool { return true },
+		fix:         func(_ PackConfig, line string) string { return skipJSON(line, stripTrailingCodes) },
+	},
+	{
+		Name:        "reset",
+		Description: "Styled text must be closed with &r (see the pack's reset policy).",
+		enabled:     func(cfg PackConfig) bool { return cfg.Reset.Mode != ResetOff },
+		fix:         func(cfg PackConfig, line string) string { return cfg.Reset.applyLine(line) },
+	},
+}
+
+// On reports whether the pack uses the rule.
+func (r lintRule) On(cfg PackConfig) bool { return r.enabled(cfg) }
+
+// skipJSON returns fix(line), or line if it holds JSON text components.
+func skipJSON(line string, fix func(string) string) string {
+	if t := strings.TrimSpace(line); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		return line
+	}
+	return fix(line)
+}
+
+// LintIssue is a line of quest text that breaks one of the pack's rules.
+type LintIssue struct {
+	Chapter *Chapter
+	Quest   *Quest
+	// Field is title, subtitle or description; Line is the description line.
+	Field string
+	Line  int
+	Rule  string
+	Text  string
+	Fixed string
+}
+
+// lintQuest returns the issues in q's title, subtitle and description.
+func lintQuest(cfg PackConfig, q *Quest) []LintIssue {
+	var issues []LintIssue
+	check := func(field string, line int, text string) {
+		for _, rule := range lintRules {
+			if !rule.enabled(cfg) {
+				continue
+			}
+			if fixed := rule.fix(cfg, text); fixed != text {
+				issues = append(issues, LintIssue{Chapter: q.Chapter, Quest: q, Field: field, Line: line, Rule: rule.Name, Text: text, Fixed: fixed})
+			}
+		}
+	}
+	check("title", 0, q.Title)
+	check("subtitle", 0, q.Subtitle)
+	if q.Description != "" {
+		for i, line := range strings.Split(q.Description, "\n") {
+			check("description", i, line)
+		}
+	}
+	return issues
+}
+
+// lintBook returns the issues in every quest of qb, in chapter order.
+func lintBook(cfg PackConfig, qb *QuestBook) []LintIssue {
+	var issues []LintIssue
+	for _, ch := range qb.Chapters {
+		for _, q := range ch.Quests {
+			issues = append(issues, lintQuest(cfg, q)...)
+		}
+	}
+	return issues
+}
+
+// fixText applies every enabled rule to each line of s.
+func fixText(cfg PackConfig, s string) string {
+	if s == "" {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		for _, rule := range lintRules {
+			if rule.enabled(cfg) {
+				line = rule.fix(cfg, line)
+			}
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fixQuestText fixes q's text fields and reports whether any changed.
+func fixQuestText(cfg PackConfig, q *Quest) bool {
+	changed := false
+	for _, f := range []*string{&q.Title, &q.Subtitle, &q.Description} {
+		if fixed := fixText(cfg, *f); fixed != *f {
+			*f = fixed
+			changed = true
+		}
+	}
+	return changed
+}
+
+// onSave applies the rules the pack wants enforced whenever a quest is saved.
+func (cfg PackConfig) onSave(q *Quest) {
+	if cfg.Reset.OnSave {
+		// only the reset rule, even if other rules are enabled
+		fixQuestText(PackConfig{Reset: cfg.Reset}, q)
+	}
+}
+
+// Check writes the book's lint issues and uses of blocked terms to out, one
+// per line, and returns how many it found. It is `qbedit check`.
+func (a *App) Check(out io.Writer) int {
+	qb := a.QB()
+	cfg := a.Pack.Get()
+	where := func(ch *Chapter, q *Quest, field string, line int) string {
+		s := fmt.Sprintf("%s/%s %s", ch.Name, q.ID, field)
+		if field == "description" {
+			s += fmt.Sprintf(" line %d", line+1)
+		}
+		return s
+	}
+	issues := lintBook(cfg, qb)
+	for _, is := range issues {
+		fmt.Fprintf(out, "%s: %s: %q should be %q\n", where(is.Chapter, is.Quest, is.Field, is.Line), is.Rule, is.Text, is.Fixed)
+	}
+	hits := blockedBook(cfg.Blocklist, qb)
+	for _, h := range hits {
+		reason := ""
+		if h.Reason != "" {
+			reason = " (" + h.Reason + ")"
+		}
+		fmt.Fprintf(out, "%s: blocked: %q%s in %q\n", where(h.Chapter, h.Quest, h.Field, h.Line), h.Found, reason, h.Text)
+	}
+	return len(issues) + len(hits)
+}
+
+// lint handles GET "/lint", which checks quest text against the pack's
+// formatting rules and fixes what it can.
+func (a *App) lint(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cfg := a.Pack.Get()
+	data := a.baseData(r, "Lint")
+	data["Pack"] = cfg
+	data["Rules"] = lintRules
+	data["Issues"] = lintBook(cfg, qb)
+	data["Blocked"] = blockedBook(cfg.Blocklist, qb)
+	data["BlockedTerms"] = formatBlockedTerms(cfg.Blocklist.Terms)
+	data["Allowed"] = strings.Join(cfg.Blocklist.Allowed, "\n")
+	data["Exceptions"] = cfg.Blocklist.Exceptions
+	data["Msg"] = r.URL.Query().Get("msg")
+	a.render(w, "lint.gohtml", data)
+}
+
+// lintPolicy handles POST "/lint/policy", saving the pack's rules.
+func (a *App) lintPolicy(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	mode := r.Form.Get("reset_mode")
+	switch mode {
+	case ResetOff, ResetLineEnd, ResetPunct:
+	default:
+		http.Error(w, "unknown reset mode "+mode, http.StatusBadRequest)
+		return
+	}
+	err := a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Reset.Mode = mode
+		cfg.Reset.OnSave = r.Form.Get("reset_on_save") == "1"
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/lint", http.StatusSeeOther)
+}
+
+// lintFix handles POST "/lint/fix", fixing the quests in ids (comma
+// separated), or every quest with an issue if ids is "all".
+func (a *App) lintFix(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	isAjax := r.Header.Get("X-Requested-With") == "XMLHttpRequest" || strings.Contains(r.Header.Get("Accept"), "application/json")
+	if err := r.ParseForm(); err != nil {
+		writeError(w, isAjax, "invalid form", http.StatusBadRequest)
+		return
+	}
+	cfg := a.Pack.Get()
+	ids := make(map[string]bool)
+	if p := r.Form.Get("ids"); p == "all" {
+		for _, is := range lintBook(cfg, qb) {
+			ids[is.Quest.ID] = true
+		}
+	} else {
+		for _, id := range strings.Split(p, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids[id] = true
+			}
+		}
+	}
+
+	byChapter := make(map[string][]string)
+	for id := range ids {
+		if q, ok := qb.questMap[id]; ok {
+			byChapter[q.Chapter.Name] = append(byChapter[q.Chapter.Name], id)
+		}
+	}
+	edited, err := editChapters(qb, slices.Collect(maps.Keys(byChapter)), func(ch *Chapter) ([]string, error) {
+		var changed []string
+		for _, id := range byChapter[ch.Name] {
+			if q, ok := ch.questMap[id]; ok && fixQuestText(cfg, q) {
+				changed = append(changed, id)
+			}
+		}
+		return changed, nil
+	})
+	if err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fixed := a.auditEdits(r, "lint fix", "", edited)
+	if fixed > 0 {
+		a.reload()
+	}
+	if isAjax {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "fixed": fixed})
+		return
+	}
+	http.Redirect(w, r, "/lint", http.StatusSeeOther)
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// loadStatsTop is how many files the status page lists in each table.
+const loadStatsTop = 10
+
+// FileStat is the size of a quest file and how long it took to parse.
+type FileStat struct {
+	// Name is the path relative to the quests directory.
+	Name  string
+	Size  int64
+	Parse time.Duration
+	// Cached is whether the file's parse was cached; see bookcache.go
+	Cached bool
+}
+
+// LoadStats summarizes loading a QuestBook, so that pack authors can see
+// which chapters are slowing startup down and might need splitting.
+type LoadStats struct {
+	Files int
+	// Cached is how many of the files were decoded from the parse cache
+	Cached  int
+	Bytes   int64
+	Total   time.Duration
+	Largest []FileStat
+	Slowest []FileStat
+
+	largest, slowest *topFiles
+}
+
+func newLoadStats(n int) *LoadStats {
+	return &LoadStats{
+		largest: newTopFiles(n, func(a, b FileStat) bool { return a.Size < b.Size }),
+		slowest: newTopFiles(n, func(a, b FileStat) bool { return a.Parse < b.Parse }),
+	}
+}
+
+// add records a loaded file.
+func (s *LoadStats) add(fs FileStat) {
+	s.Files++
+	if fs.Cached {
+		s.Cached++
+	}
+	s.Bytes += fs.Size
+	s.largest.add(fs)
+	s.slowest.add(fs)
+}
+
+// done finalizes the Largest and Slowest lists.
+func (s *LoadStats) done(total time.Duration) {
+	s.Total = total
+	s.Largest = s.largest.sorted()
+	s.Slowest = s.slowest.sorted()
+}
+
+// topFiles keeps the n greatest stats seen by less. They are kept in a
+// min-heap, so the least of them is the one evicted by a greater stat.
+type topFiles struct {
+	n int
+	h statHeap
+}
+
+func newTopFiles(n int, less func(a, b FileStat) bool) *topFiles {
+	return &topFiles{n: n, h: statHeap{less: less}}
+}
+
+func (t *topFiles) add(fs FileStat) {
+	if t.h.Len() < t.n {
+		heap.Push(&t.h, fs)
+		return
+	}
+	if t.n > 0 && t.h.less(t.h.stats[0], fs) {
+		t.h.stats[0] = fs
+		heap.Fix(&t.h, 0)
+	}
+}
+
+// sorted returns the kept stats, greatest first.
+func (t *topFiles) sorted() []FileStat {
+	h := statHeap{stats: append([]FileStat(nil), t.h.stats...), less: t.h.less}
+	out := make([]FileStat, h.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(&h).(FileStat)
+	}
+	return out
+}
+
+// statHeap implements heap.Interface.
+type statHeap struct {
+	stats []FileStat
+	less  func(a, b FileStat) bool
+}
+
+func (h statHeap) Len() int           { return len(h.stats) }
+func (h statHeap) Less(i, j int) bool { return h.less(h.stats[i], h.stats[j]) }
+func (h statHeap) Swap(i, j int)      { h.stats[i], h.stats[j] = h.stats[j], h.stats[i] }
+func (h *statHeap) Push(x any)        { h.stats = append(h.stats, x.(FileStat)) }
+func (h *statHeap) Pop() any {
+	n := len(h.stats)
+	x := h.stats[n-1]
+	h.stats = h.stats[:n-1]
+	return x
+}
+
+// humanBytes formats n as a size in B, KiB or MiB.
+func humanBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// millis formats d in milliseconds.
+func millis(d time.Duration) string {
+	return fmt.Sprintf("%.2f ms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadStatsTop(t *testing.T) {
+	s := newLoadStats(3)
+	for i, size := range []int64{5, 1, 9, 3, 7, 2} {
+		s.add(FileStat{Name: string(rune('a' + i)), Size: size, Parse: time.Duration(10 - size)})
+	}
+	s.done(time.Second)
+	if s.Files != 6 || s.Bytes != 27 {
+		t.Fatalf("got %d files, %d bytes", s.Files, s.Bytes)
+	}
+	var sizes []int64
+	for _, fs := range s.Largest {
+		sizes = append(sizes, fs.Size)
+	}
+	if len(sizes) != 3 || sizes[0] != 9 || sizes[1] != 7 || sizes[2] != 5 {
+		t.Errorf("largest: got %v", sizes)
+	}
+	if got := s.Slowest[0]; got.Size != 1 || len(s.Slowest) != 3 {
+		t.Errorf("slowest: got %v", s.Slowest)
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	for n, want := range map[int64]string{12: "12 B", 2048: "2.0 KiB", 3 << 20: "3.0 MiB"} {
+		if got := humanBytes(n); got != want {
+			t.Errorf("humanBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
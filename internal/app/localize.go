@@ -0,0 +1,179 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// validNamespace matches namespaces for generated keys; it is also the
+// KubeJS assets directory the lang file goes in by default.
+var validNamespace = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// localizeChapter replaces the literal text in ch with keys in namespace ns,
+// adding the text to l. It returns the number of keys added.
+//
+// Every chapter title and quest title, subtitle and description line is
+// localized, so translators then only need to provide other <lang>.json
+// files. Text that is already a key, empty description lines and placeholders
+// such as {@pagebreak} are left alone, so localizing is safe to repeat after
+// new quests are added.
+func localizeChapter(ch *Chapter, ns string, l *LangFile) int {
+	n := 0
+	key := func(text string, parts ...string) string {
+		k := ns + "." + strings.Join(parts, ".")
+		l.Set(k, text)
+		n++
+		return "{" + k + "}"
+	}
+	if t := ch.Title; t != "" && !isLangKey(t) {
+		ch.Title = key(t, "chapter", ch.Name, "title")
+		ch.raw["title"] = ch.Title
+	}
+	for _, q := range ch.Quests {
+		id := strings.ToLower(q.ID)
+		if q.Title != "" && !isLangKey(q.Title) {
+			q.Title = key(q.Title, "quest", id, "title")
+		}
+		if q.Subtitle != "" && !isLangKey(q.Subtitle) {
+			q.Subtitle = key(q.Subtitle, "quest", id, "subtitle")
+		}
+		if q.Description == "" {
+			continue
+		}
+		lines := strings.Split(q.Description, "\n")
+		for i, line := range lines {
+			t := strings.TrimSpace(line)
+			if t == "" || isLangKey(t) || t == "{@pagebreak}" || strings.HasPrefix(t, "{image:") {
+				continue
+			}
+			lines[i] = key(line, "quest", id, "description", fmt.Sprint(i))
+		}
+		q.Description = strings.Join(lines, "\n")
+	}
+	return n
+}
+
+func isLangKey(s string) bool {
+	_, ok := langKey(s)
+	return ok
+}
+
+// openLangFile loads the lang file at path, or returns an empty one if it
+// doesn't exist yet.
+func openLangFile(path string) (*LangFile, error) {
+	l, err := LoadLangFile(path)
+	if os.IsNotExist(err) {
+		return &LangFile{Path: path, values: make(map[string]string)}, nil
+	}
+	return l, err
+}
+
+// localizePath returns the lang file a book localized into namespace ns is
+// written to: the book's current lang file if it has one, otherwise the
+// en_us.json of ns in the instance's kubejs dir.
+func (a *App) localizePath(ns string) string {
+	if a.LangPath() != "" {
+		return a.LangPath()
+	}
+	return filepath.Join(filepath.Dir(filepath.Dir(a.Root())), "kubejs", "assets", ns, "lang", "en_us.json")
+}
+
+// localize handles GET "/localize", which explains what localizing does
+// and where the lang file will be written.
+func (a *App) localize(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	data := a.baseData(r, "Localize")
+	ns := r.URL.Query().Get("ns")
+	if !validNamespace.MatchString(ns) {
+		ns = "quests"
+	}
+	literal := 0
+	for _, ch := range qb.Chapters {
+		if ch.titleKey == "" && ch.Title != "" {
+			literal++
+		}
+		for _, q := range ch.Quests {
+			if q.langKeys == nil && (q.Title != "" || q.Subtitle != "" || q.Description != "") {
+				literal++
+			}
+		}
+	}
+	data["Namespace"] = ns
+	data["LangPath"] = a.localizePath(ns)
+	data["Literal"] = literal
+	data["LocalizeMsg"] = r.URL.Query().Get("msg")
+	a.render(w, "localize.gohtml", data)
+}
+
+// localizeApply handles POST "/localize", moving the text of every chapter
+// into the lang file. The lang file is written first; the chapters are then
+// written together so a failure can't leave some of them localized.
+func (a *App) localizeApply(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	ns := strings.TrimSpace(r.Form.Get("ns"))
+	if !validNamespace.MatchString(ns) {
+		http.Error(w, "invalid namespace: use lowercase letters, digits and _", http.StatusBadRequest)
+		return
+	}
+	path := a.localizePath(ns)
+	l, err := openLangFile(path)
+	if err != nil {
+		http.Error(w, "lang file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	files := make(map[string]any)
+	var names []string
+	added := 0
+	for _, c := range qb.Chapters {
+		cpath := qb.chapterPath(c.Name)
+		ch, err := NewChapterFromPath(cpath)
+		if err != nil {
+			http.Error(w, "open chapter: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if n := localizeChapter(ch, ns, l); n > 0 {
+			ch.Sync()
+			files[cpath] = ch.raw
+			names = append(names, ch.Name)
+			added += n
+		}
+	}
+	if added > 0 {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// the lang file and chapters are written together
+		t := newBookWrite(nil)
+		t.stageLang(l)
+		for path, v := range files {
+			if err := t.stageSNBT(path, v); err != nil {
+				http.Error(w, "saving chapters: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := t.commit(); err != nil {
+			http.Error(w, "saving chapters: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			a.audit(r, "localize", name, nil, path)
+		}
+		a.setLangPath(path)
+		a.reload()
+	}
+	msg := fmt.Sprintf("Moved %d strings from %d chapters into %s.", added, len(names), path)
+	http.Redirect(w, r, "/localize?ns="+url.QueryEscape(ns)+"&msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalizeApply(t *testing.T) {
+	a := testApp(t)
+	a.setLangPath(filepath.Join(t.TempDir(), "en_us.json"))
+	elsewhere := filepath.Join(t.TempDir(), "elsewhere.json")
+	form := url.Values{"ns": {"pack"}, "path": {elsewhere}}
+	req := httptest.NewRequest("POST", "/localize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("localize: %d %s", rec.Code, rec.Body)
+	}
+	if _, err := os.Stat(elsewhere); err == nil {
+		t.Error("the lang file was written to the posted path")
+	}
+	l, err := LoadLangFile(a.LangPath())
+	if err != nil || len(l.values) == 0 {
+		t.Fatalf("lang file: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/localize?ns=../x", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), a.LangPath()) {
+		t.Errorf("page: %d", rec.Code)
+	}
+	form.Set("ns", "../x")
+	req = httptest.NewRequest("POST", "/localize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid namespace: %d", rec.Code)
+	}
+}
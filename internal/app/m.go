@@ -1,5 +1,12 @@
 package app
 
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
 // Mis a map[string]any with some extra methods
 type M map[string]any
 
@@ -35,3 +42,112 @@ func (m M) GetStrings(key string) []string {
 	}
 	return ss
 }
+
+// GetBool returns the value for key as a bool. Older files may store flags
+// as numbers (eg. 1b), which are true when non-zero.
+func (m M) GetBool(key string) bool {
+	if b, ok := m[key].(bool); ok {
+		return b
+	}
+	return anyToInt(m[key]) != 0
+}
+
+// SetBool sets key to v, keeping a flag written as a byte (eg. 1b) a byte.
+func (m M) SetBool(key string, v bool) {
+	if b, ok := m[key].(snbt.Byte); ok {
+		b.Sign, b.Digits = 1, "0"
+		if v {
+			b.Digits = "1"
+		}
+		m[key] = b
+		return
+	}
+	m[key] = v
+}
+
+// GetInt returns the value for key as an int, or 0. SNBT numbers may decode
+// as int64, float64 or one of the suffixed snbt number types.
+func (m M) GetInt(key string) int {
+	return anyToInt(m[key])
+}
+
+// SetInt sets key to n, preserving the SNBT number type of any existing value
+// so that eg. "count: 4L" stays a long when it is rewritten.
+func (m M) SetInt(key string, n int) {
+	sign, digits := 1, strconv.Itoa(n)
+	if n < 0 {
+		sign, digits = -1, strconv.Itoa(-n)
+	}
+	switch v := m[key].(type) {
+	case snbt.Long:
+		v.Sign, v.Digits = sign, digits
+		m[key] = v
+	case snbt.Byte:
+		v.Sign, v.Digits = sign, digits
+		m[key] = v
+	case snbt.Short:
+		v.Sign, v.Digits = sign, digits
+		m[key] = v
+	default:
+		m[key] = int64(n)
+	}
+}
+
+func anyToInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case snbt.Long:
+		i, _ := strconv.Atoi(n.Digits)
+		return i * signOf(n.Sign)
+	case snbt.Short:
+		i, _ := strconv.Atoi(n.Digits)
+		return i * signOf(n.Sign)
+	case snbt.Byte:
+		i, _ := strconv.Atoi(n.Digits)
+		return i * signOf(n.Sign)
+	case snbt.Decimal:
+		return int(n.Float())
+	case snbt.FloatNum:
+		return int(n.Float())
+	}
+	return 0
+}
+
+func signOf(s int) int {
+	if s < 0 {
+		return -1
+	}
+	return 1
+}
+
+// GetFloat returns the value for key as a float64, or 0.
+func (m M) GetFloat(key string) float64 {
+	switch n := m[key].(type) {
+	case float64:
+		return n
+	case snbt.Decimal:
+		return n.Float()
+	case snbt.FloatNum:
+		return n.Float()
+	}
+	return float64(anyToInt(m[key]))
+}
+
+// decimalValue returns f as an SNBT double (eg. "1.5d").
+func decimalValue(f float64) snbt.Decimal {
+	d := snbt.Decimal{Sign: 1, Suffix: 'd'}
+	if f < 0 {
+		d.Sign, f = -1, -f
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	d.Int, d.Frac, _ = strings.Cut(s, ".")
+	if d.Frac == "" {
+		d.Frac = "0"
+	}
+	return d
+}
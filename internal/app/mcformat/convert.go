@@ -0,0 +1,268 @@
+package mcformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// run is text in a single style. Quest text is written with formatting codes,
+// as JSON text components or with MiniMessage tags, and other tools each want
+// one of them: chat plugins and Discord bots take MiniMessage, commands take
+// JSON. The converters read any of the three into runs and write them in
+// another.
+type run struct {
+	text string
+	st   style
+}
+
+// runsOf returns the styled runs of s, which is a JSON text component,
+// MiniMessage or text with formatting codes.
+func runsOf(s string) []run {
+	t := strings.TrimSpace(s)
+	if t != "" && (t[0] == '{' || t[0] == '[') {
+		var v any
+		if json.Unmarshal([]byte(t), &v) == nil {
+			var rs []run
+			componentRuns(&rs, v, style{})
+			return rs
+		}
+	}
+	if IsMiniMessage(s) {
+		var rs []run
+		for _, c := range miniRunes(s) {
+			rs = appendRun(rs, string(c.r), c.st)
+		}
+		return rs
+	}
+	return legacyRuns(nil, s, style{})
+}
+
+// appendRun appends text in st to rs, extending the last run if it has the
+// same style.
+func appendRun(rs []run, text string, st style) []run {
+	if text == "" {
+		return rs
+	}
+	if n := len(rs); n > 0 && rs[n-1].st == st {
+		rs[n-1].text += text
+		return rs
+	}
+	return append(rs, run{text: text, st: st})
+}
+
+// legacyRuns appends the runs of s, written with formatting codes, to rs.
+// Text starts in the style base, which a reset returns to. As in the game, a
+// color code also clears the formats before it.
+func legacyRuns(rs []run, s string, base style) []run {
+	st := base
+	var text strings.Builder
+	flush := func() {
+		rs = appendRun(rs, text.String(), st)
+		text.Reset()
+	}
+	chars := []rune(s)
+	for i := 0; i < len(chars); i++ {
+		if hex, n := HexCode(chars, i); n > 0 {
+			flush()
+			st = style{hex: hex}
+			i += n - 1
+			continue
+		}
+		if !isSign(chars[i]) || i+1 >= len(chars) {
+			text.WriteRune(chars[i])
+			continue
+		}
+		next := st
+		switch code := unicode.ToLower(chars[i+1]); code {
+		case 'k':
+			next.obf = true
+		case 'l':
+			next.bold = true
+		case 'm':
+			next.strike = true
+		case 'n':
+			next.underline = true
+		case 'o':
+			next.italic = true
+		case 'r':
+			next = base
+		default:
+			if ColorName(code) == "" {
+				// not a code, eg. "salt & pepper"
+				text.WriteRune(chars[i])
+				continue
+			}
+			next = style{color: "c" + string(code)}
+		}
+		flush()
+		st = next
+		i++
+	}
+	flush()
+	return rs
+}
+
+// componentRuns appends the runs of the JSON text component v, which
+// inherits the style parent, to rs, styling it as formatComponent does.
+func componentRuns(rs *[]run, v any, parent style) {
+	switch c := v.(type) {
+	case string:
+		*rs = legacyRuns(*rs, c, parent)
+	case float64, bool:
+		*rs = appendRun(*rs, fmt.Sprint(c), parent)
+	case []any:
+		if len(c) == 0 {
+			return
+		}
+		componentRuns(rs, c[0], parent)
+		st := componentStyle(c[0], parent)
+		for _, e := range c[1:] {
+			componentRuns(rs, e, st)
+		}
+	case map[string]any:
+		st := componentStyle(c, parent)
+		for _, key := range []string{"text", "translate", "keybind"} {
+			if s, ok := c[key].(string); ok {
+				*rs = legacyRuns(*rs, s, st)
+				break
+			}
+		}
+		if extra, ok := c["extra"].([]any); ok {
+			for _, e := range extra {
+				componentRuns(rs, e, st)
+			}
+		}
+	}
+}
+
+// colorOf returns the color of st as a JSON text component color: a name, a
+// #rrggbb color or "".
+func colorOf(st style) string {
+	if st.hex != "" {
+		return st.hex
+	}
+	if st.color != "" {
+		return ColorName(rune(st.color[1]))
+	}
+	return ""
+}
+
+// miniTags returns the MiniMessage tags for st, color first.
+func miniTags(st style) []string {
+	var tags []string
+	if c := colorOf(st); c != "" {
+		tags = append(tags, c)
+	}
+	for _, d := range []struct {
+		on  bool
+		tag string
+	}{{st.bold, "bold"}, {st.italic, "italic"}, {st.underline, "underlined"}, {st.strike, "strikethrough"}, {st.obf, "obfuscated"}} {
+		if d.on {
+			tags = append(tags, d.tag)
+		}
+	}
+	return tags
+}
+
+// ToMiniMessage converts s, written with formatting codes or as a JSON text
+// component, to MiniMessage tags, eg. "&6Gold &lbold" is
+// "<gold>Gold <bold>bold</bold></gold>". Text that is already MiniMessage
+// is returned as it is.
+func ToMiniMessage(s string) string {
+	if IsMiniMessage(s) {
+		return s
+	}
+	var b strings.Builder
+	var open []string
+	for _, r := range runsOf(s) {
+		tags := miniTags(r.st)
+		// keep the tags the run shares with the open ones, in order
+		keep := 0
+		for keep < len(open) && keep < len(tags) && open[keep] == tags[keep] {
+			keep++
+		}
+		for len(open) > keep {
+			b.WriteString("</" + open[len(open)-1] + ">")
+			open = open[:len(open)-1]
+		}
+		for _, t := range tags[keep:] {
+			b.WriteString("<" + t + ">")
+			open = append(open, t)
+		}
+		b.WriteString(strings.NewReplacer(`\`, `\\`, "<", `\<`).Replace(r.text))
+	}
+	for len(open) > 0 {
+		b.WriteString("</" + open[len(open)-1] + ">")
+		open = open[:len(open)-1]
+	}
+	return b.String()
+}
+
+// ToLegacy converts s, written with MiniMessage tags or as a JSON text
+// component, to formatting codes written with sign, eg. "<gold>Gold" is
+// "&6Gold". Colors that aren't one of the game's sixteen are written as hex
+// codes, so gradients color each character.
+func ToLegacy(s string, sign rune) string {
+	var b strings.Builder
+	var cur style
+	for _, r := range runsOf(s) {
+		st := r.st
+		if st != cur {
+			lost := (cur.bold && !st.bold) || (cur.italic && !st.italic) || (cur.underline && !st.underline) ||
+				(cur.strike && !st.strike) || (cur.obf && !st.obf)
+			colored := st.color != "" || st.hex != ""
+			if lost || (!colored && (cur.color != "" || cur.hex != "")) {
+				b.WriteString(string(sign) + "r")
+				cur = style{}
+			}
+			if colored && (st.color != cur.color || st.hex != cur.hex) {
+				if st.hex != "" {
+					b.WriteString(HexSequence(st.hex, sign))
+				} else {
+					b.WriteString(string(sign) + st.color[1:])
+				}
+				// a color clears the formats
+				cur = style{color: st.color, hex: st.hex}
+			}
+			for _, f := range []struct {
+				on, was bool
+				code    string
+			}{{st.obf, cur.obf, "k"}, {st.bold, cur.bold, "l"}, {st.strike, cur.strike, "m"}, {st.underline, cur.underline, "n"}, {st.italic, cur.italic, "o"}} {
+				if f.on && !f.was {
+					b.WriteString(string(sign) + f.code)
+				}
+			}
+			cur = st
+		}
+		b.WriteString(r.text)
+	}
+	return b.String()
+}
+
+// ToComponents converts s, written with formatting codes, MiniMessage tags
+// or as a JSON text component, to text components, one per run of text with
+// the same style.
+func ToComponents(s string) []Component {
+	var cs []Component
+	for _, r := range runsOf(s) {
+		cs = append(cs, Component{
+			Text:          r.text,
+			Color:         colorOf(r.st),
+			Bold:          r.st.bold,
+			Italic:        r.st.italic,
+			Underlined:    r.st.underline,
+			Strikethrough: r.st.strike,
+			Obfuscated:    r.st.obf,
+		})
+	}
+	return cs
+}
+
+// SameLook reports whether a and b are the same text in the same style,
+// however each is written.
+func SameLook(a, b string) bool {
+	return reflect.DeepEqual(runsOf(a), runsOf(b))
+}
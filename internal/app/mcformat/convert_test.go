@@ -0,0 +1,61 @@
+package mcformat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToMiniMessage(t *testing.T) {
+	cases := map[string]string{
+		"plain":                        "plain",
+		"&6Gold &lbold&r plain":        "<gold>Gold <bold>bold</bold></gold> plain",
+		"&lBold &cred":                 "<bold>Bold </bold><red>red</red>",
+		"§x§f§f§0§0§0§0Hot":            "<#ff0000>Hot</#ff0000>",
+		"5 <3 salt & pepper":           `5 \<3 salt & pepper`,
+		`{"text":"Hi","color":"aqua"}`: "<aqua>Hi</aqua>",
+		"<red>already</red>":           "<red>already</red>",
+	}
+	for in, want := range cases {
+		if got := ToMiniMessage(in); got != want {
+			t.Errorf("ToMiniMessage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestToLegacy(t *testing.T) {
+	cases := map[string]string{
+		"plain":                                 "plain",
+		"<gold>Gold <bold>bold</bold></gold> x": "&6Gold &lbold&r x",
+		"<bold>Bold</bold> <red>red":            "&lBold&r &cred",
+		"<#ff0000>Hot":                          "&x&f&f&0&0&0&0Hot",
+		`\<3 <i>it`:                             "<3 &oit",
+		`["",{"text":"A","color":"green","bold":true}]`: "&a&lA",
+	}
+	for in, want := range cases {
+		if got := ToLegacy(in, '&'); got != want {
+			t.Errorf("ToLegacy(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := ToLegacy("<gold>x", '§'); got != "§6x" {
+		t.Errorf("ToLegacy with § = %q", got)
+	}
+
+	// converting to MiniMessage and back keeps the text's look
+	for _, in := range []string{"&6Gold &lbold&r plain", "&lBold &cred", "&a&nunder&r and &o&9blue"} {
+		if got := ToLegacy(ToMiniMessage(in), '&'); !reflect.DeepEqual(runsOf(got), runsOf(in)) {
+			t.Errorf("round trip of %q = %q", in, got)
+		}
+	}
+}
+
+func TestToComponents(t *testing.T) {
+	got := ToComponents("<gold>Gold <b>bold</b></gold> x")
+	want := []Component{
+		{Text: "Gold ", Color: "gold"},
+		{Text: "bold", Color: "gold", Bold: true},
+		{Text: " x"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToComponents = %+v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package mcformat
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Deficiency is a color vision deficiency that colors can be simulated for.
+type Deficiency string
+
+// The dichromacies, where one kind of cone is missing. They are the extreme
+// of the common deficiencies, so text that reads under them reads under the
+// milder ones too.
+const (
+	// Protanopia is missing red cones.
+	Protanopia Deficiency = "protanopia"
+	// Deuteranopia is missing green cones.
+	Deuteranopia Deficiency = "deuteranopia"
+	// Tritanopia is missing blue cones.
+	Tritanopia Deficiency = "tritanopia"
+)
+
+// Deficiencies lists the deficiencies that can be simulated.
+var Deficiencies = []Deficiency{Protanopia, Deuteranopia, Tritanopia}
+
+// ParseDeficiency returns the deficiency named s, and whether there is one.
+func ParseDeficiency(s string) (Deficiency, bool) {
+	for _, d := range Deficiencies {
+		if string(d) == strings.ToLower(s) {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+// cvdMatrices simulate each deficiency at full severity in linear RGB, from
+// Machado, Oliveira and Fernandes (2009).
+var cvdMatrices = map[Deficiency][3][3]float64{
+	Protanopia: {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	Deuteranopia: {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	Tritanopia: {
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	},
+}
+
+// Simulate returns the #rrggbb color hex as it appears with the deficiency
+// d. Colors it can't parse are returned as they are.
+func Simulate(hex string, d Deficiency) string {
+	m, ok := cvdMatrices[d]
+	n, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if !ok || err != nil || len(hex) != 7 || hex[0] != '#' {
+		return hex
+	}
+	var lin [3]float64
+	for i := range lin {
+		lin[i] = toLinear(float64(n>>(16-8*i)&0xff) / 255)
+	}
+	var out [3]float64
+	for i := range out {
+		v := m[i][0]*lin[0] + m[i][1]*lin[1] + m[i][2]*lin[2]
+		out[i] = 255 * fromLinear(min(max(v, 0), 1))
+	}
+	return rgbHex(out)
+}
+
+// Palette returns the colors of the color code classes, eg. "c4", as they
+// appear with the deficiency d.
+func Palette(d Deficiency) map[string]string {
+	p := make(map[string]string, len(classHex))
+	for class, hex := range classHex {
+		p[class] = Simulate(hex, d)
+	}
+	return p
+}
+
+// toLinear converts an sRGB component in [0, 1] to linear light.
+func toLinear(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// fromLinear converts a linear light component in [0, 1] to sRGB.
+func fromLinear(c float64) float64 {
+	if c <= 0.0031308 {
+		return c * 12.92
+	}
+	return 1.055*math.Pow(c, 1/2.4) - 0.055
+}
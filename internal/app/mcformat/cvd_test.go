@@ -0,0 +1,56 @@
+package mcformat
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	// distance is the sum of the channel differences of two colors
+	distance := func(a, b string) int {
+		x, _ := strconv.ParseUint(a[1:], 16, 32)
+		y, _ := strconv.ParseUint(b[1:], 16, 32)
+		d := 0
+		for shift := 0; shift <= 16; shift += 8 {
+			d += max(int(x>>shift&0xff)-int(y>>shift&0xff), int(y>>shift&0xff)-int(x>>shift&0xff))
+		}
+		return d
+	}
+	for _, d := range Deficiencies {
+		for _, grey := range []string{"#000000", "#555555", "#ffffff"} {
+			if got := Simulate(grey, d); distance(got, grey) > 3 {
+				t.Errorf("Simulate(%s, %s) = %s, want about the same grey", grey, d, got)
+			}
+		}
+	}
+
+	// green and yellow, told apart by red cones, come close without red or
+	// green cones but not without blue ones; green and blue come close
+	// without blue cones
+	green, yellow, blue := classHex["ca"], classHex["ce"], classHex["c9"]
+	for _, d := range []Deficiency{Protanopia, Deuteranopia} {
+		if got, normal := distance(Simulate(green, d), Simulate(yellow, d)), distance(green, yellow); got > normal/3 {
+			t.Errorf("%s: green and yellow %d apart, normally %d", d, got, normal)
+		}
+	}
+	if got, normal := distance(Simulate(green, Tritanopia), Simulate(yellow, Tritanopia)), distance(green, yellow); got < normal/2 {
+		t.Errorf("tritanopia: green and yellow %d apart, normally %d", got, normal)
+	}
+	darkGreen := classHex["c2"]
+	if got, normal := distance(Simulate(darkGreen, Tritanopia), Simulate(blue, Tritanopia)), distance(darkGreen, blue); got > normal/3 {
+		t.Errorf("tritanopia: dark green and blue %d apart, normally %d", got, normal)
+	}
+
+	if got := Simulate("red", Protanopia); got != "red" {
+		t.Errorf("Simulate(red) = %q", got)
+	}
+	if got := Simulate("#ff0000", "achromatopsia"); got != "#ff0000" {
+		t.Errorf("unknown deficiency = %q", got)
+	}
+	if p := Palette(Deuteranopia); len(p) != 16 || p["ca"] != Simulate(green, Deuteranopia) {
+		t.Errorf("Palette = %v", p)
+	}
+	if d, ok := ParseDeficiency("Tritanopia"); !ok || d != Tritanopia {
+		t.Errorf("ParseDeficiency = %q, %v", d, ok)
+	}
+}
@@ -1,50 +1,128 @@
 package mcformat
 
 import (
+	"encoding/json"
+	"fmt"
 	"html/template"
 	"strings"
+	"unicode"
 )
 
+// HexCode returns the #rrggbb color of the §x§R§R§G§G§B§B sequence starting
+// at rs[i] and its length in runes, or "" and 0 if there isn't one there.
+// Either sign may be used for each code.
+func HexCode(rs []rune, i int) (string, int) {
+	const n = 14
+	if i+n > len(rs) || !isSign(rs[i]) || unicode.ToLower(rs[i+1]) != 'x' {
+		return "", 0
+	}
+	hex := []rune{'#'}
+	for j := i + 2; j < i+n; j += 2 {
+		d := unicode.ToLower(rs[j+1])
+		if !isSign(rs[j]) || !((d >= '0' && d <= '9') || (d >= 'a' && d <= 'f')) {
+			return "", 0
+		}
+		hex = append(hex, d)
+	}
+	return string(hex), n
+}
+
+// HexSequence returns the codes that set the #rrggbb color hex, written with
+// sign.
+func HexSequence(hex string, sign rune) string {
+	var b strings.Builder
+	b.WriteRune(sign)
+	b.WriteByte('x')
+	for _, d := range strings.ToLower(strings.TrimPrefix(hex, "#")) {
+		b.WriteRune(sign)
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+// IsHexColor reports whether s is a #rrggbb color.
+func IsHexColor(s string) bool {
+	if len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	for _, d := range strings.ToLower(s[1:]) {
+		if !((d >= '0' && d <= '9') || (d >= 'a' && d <= 'f')) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSign(r rune) bool { return r == '§' || r == '&' }
+
 // Format converts Minecraft color/format codes to HTML using CSS classes.
 // Supports both '§' and '&' prefixes.
 // Color codes: 0-9, a-f. Formats: k (obfuscated), l (bold), m (strikethrough), n (underline), o (italic), r (reset).
+// Hex colors (§x§R§R§G§G§B§B) are written as an inline style. Text that is a
+// JSON text component, or a list of them, is rendered as the game would, and
+// text with MiniMessage tags as FormatMiniMessage does.
 // Returns a template.HTML with spans carrying classes like `mc-color-red`, `mc-bold`, etc.
 func Format(s string) template.HTML {
-	type state struct {
-		color     string
-		bold      bool
-		italic    bool
-		underline bool
-		strike    bool
-		obf       bool
-	}
-	st := state{}
+	if h, ok := formatJSON(s); ok {
+		return h
+	}
+	if IsMiniMessage(s) {
+		return FormatMiniMessage(s)
+	}
 	var b strings.Builder
-	writeSpanOpen := func() {
-		classes := make([]string, 0, 6)
-		classes = append(classes, "mc-text")
-		if st.color != "" {
-			classes = append(classes, "mc-"+st.color)
-		}
-		if st.bold {
-			classes = append(classes, "mc-bold")
-		}
-		if st.italic {
-			classes = append(classes, "mc-italic")
-		}
-		if st.underline {
-			classes = append(classes, "mc-underline")
-		}
-		if st.strike {
-			classes = append(classes, "mc-strike")
-		}
-		if st.obf {
-			classes = append(classes, "mc-obf")
-		}
-		b.WriteString("<span class=\"")
-		b.WriteString(strings.Join(classes, " "))
-		b.WriteString("\">")
+	formatLegacy(&b, s, style{})
+	return template.HTML(b.String())
+}
+
+// style is the formatting applied to a run of text. color is a color code
+// class like "c6"; hex is a #rrggbb color used instead when set.
+type style struct {
+	color     string
+	hex       string
+	bold      bool
+	italic    bool
+	underline bool
+	strike    bool
+	obf       bool
+}
+
+// writeSpanOpen writes a span opening tag for st.
+func writeSpanOpen(b *strings.Builder, st style) {
+	classes := make([]string, 0, 6)
+	classes = append(classes, "mc-text")
+	if st.color != "" {
+		classes = append(classes, "mc-"+st.color)
+	}
+	if st.bold {
+		classes = append(classes, "mc-bold")
+	}
+	if st.italic {
+		classes = append(classes, "mc-italic")
+	}
+	if st.underline {
+		classes = append(classes, "mc-underline")
+	}
+	if st.strike {
+		classes = append(classes, "mc-strike")
 	}
+	if st.obf {
+		classes = append(classes, "mc-obf")
+	}
+	b.WriteString("<span class=\"")
+	b.WriteString(strings.Join(classes, " "))
+	b.WriteString("\"")
+	if st.hex != "" {
+		b.WriteString(" style=\"color:")
+		b.WriteString(st.hex)
+		b.WriteString("\"")
+	}
+	b.WriteString(">")
+}
+
+// formatLegacy writes s, which may contain formatting codes, to b as spans.
+// Text starts in the style base, which a reset returns to.
+func formatLegacy(b *strings.Builder, s string, base style) {
+	st := base
 	open := false
 	closeSpan := func() {
 		if open {
@@ -52,116 +130,256 @@ func Format(s string) template.HTML {
 			open = false
 		}
 	}
-	reset := func() {
-		st = state{}
-	}
-	setColor := func(code rune) {
-		switch code {
-		case '0':
-			st.color = "c0"
-		case '1':
-			st.color = "c1"
-		case '2':
-			st.color = "c2"
-		case '3':
-			st.color = "c3"
-		case '4':
-			st.color = "c4"
-		case '5':
-			st.color = "c5"
-		case '6':
-			st.color = "c6"
-		case '7':
-			st.color = "c7"
-		case '8':
-			st.color = "c8"
-		case '9':
-			st.color = "c9"
-		case 'a', 'A':
-			st.color = "ca"
-		case 'b', 'B':
-			st.color = "cb"
-		case 'c', 'C':
-			st.color = "cc"
-		case 'd', 'D':
-			st.color = "cd"
-		case 'e', 'E':
-			st.color = "ce"
-		case 'f', 'F':
-			st.color = "cf"
-		default:
-			// unknown, ignore
-		}
-	}
-	esc := func(r rune) {
-		switch r {
-		case '&':
-			b.WriteString("&amp;")
-		case '<':
-			b.WriteString("&lt;")
-		case '>':
-			b.WriteString("&gt;")
-		case '"':
-			b.WriteString("&quot;")
-		case '\'':
-			b.WriteString("&#39;")
-		default:
-			b.WriteRune(r)
-		}
+	restyle := func() {
+		closeSpan()
+		writeSpanOpen(b, st)
+		open = true
 	}
 	i := 0
 	rs := []rune(s)
 	for i < len(rs) {
 		r := rs[i]
-		if (r == '§' || r == '&') && i+1 < len(rs) {
-			code := rs[i+1]
+		if hex, n := HexCode(rs, i); n > 0 {
+			st.color, st.hex = "", hex
+			restyle()
+			i += n
+			continue
+		}
+		if isSign(r) && i+1 < len(rs) {
+			code := unicode.ToLower(rs[i+1])
 			// formatting or color codes
 			switch code {
-			case 'k', 'K': // obfuscated
-				closeSpan()
+			case 'k': // obfuscated
 				st.obf = true
-				writeSpanOpen()
-				open = true
-			case 'l', 'L':
-				closeSpan()
+				restyle()
+			case 'l':
 				st.bold = true
-				writeSpanOpen()
-				open = true
-			case 'm', 'M':
-				closeSpan()
+				restyle()
+			case 'm':
 				st.strike = true
-				writeSpanOpen()
-				open = true
-			case 'n', 'N':
-				closeSpan()
+				restyle()
+			case 'n':
 				st.underline = true
-				writeSpanOpen()
-				open = true
-			case 'o', 'O':
-				closeSpan()
+				restyle()
+			case 'o':
 				st.italic = true
-				writeSpanOpen()
-				open = true
-			case 'r', 'R':
+				restyle()
+			case 'r':
 				closeSpan()
-				reset()
+				st = base
 			default:
-				// color
-				closeSpan()
-				setColor(code)
-				writeSpanOpen()
-				open = true
+				// color; unknown codes keep the current color
+				if ColorName(code) != "" {
+					st.color, st.hex = "c"+string(code), ""
+				}
+				restyle()
 			}
 			i += 2
 			continue
 		}
 		if !open {
-			writeSpanOpen()
+			writeSpanOpen(b, st)
 			open = true
 		}
-		esc(r)
+		b.WriteString(template.HTMLEscapeString(string(r)))
 		i++
 	}
 	closeSpan()
-	return template.HTML(b.String())
+}
+
+// formatJSON renders s if it is a JSON text component or a list of them.
+func formatJSON(s string) (template.HTML, bool) {
+	t := strings.TrimSpace(s)
+	if t == "" || (t[0] != '{' && t[0] != '[') {
+		return "", false
+	}
+	var v any
+	if err := json.Unmarshal([]byte(t), &v); err != nil {
+		return "", false
+	}
+	var b strings.Builder
+	formatComponent(&b, v, style{})
+	return template.HTML(b.String()), true
+}
+
+// formatComponent writes the JSON text component v, which inherits the style
+// parent. As in the game, the later elements of a list are styled like the
+// first, and a component's extra children like the component.
+func formatComponent(b *strings.Builder, v any, parent style) {
+	switch c := v.(type) {
+	case string:
+		formatLegacy(b, c, parent)
+	case float64, bool:
+		formatLegacy(b, fmt.Sprint(c), parent)
+	case []any:
+		if len(c) == 0 {
+			return
+		}
+		formatComponent(b, c[0], parent)
+		st := componentStyle(c[0], parent)
+		for _, e := range c[1:] {
+			formatComponent(b, e, st)
+		}
+	case map[string]any:
+		st := componentStyle(c, parent)
+		// translated text can't be looked up here, so show its key
+		for _, key := range []string{"text", "translate", "keybind"} {
+			if s, ok := c[key].(string); ok {
+				formatLegacy(b, s, st)
+				break
+			}
+		}
+		if extra, ok := c["extra"].([]any); ok {
+			for _, e := range extra {
+				formatComponent(b, e, st)
+			}
+		}
+	}
+}
+
+// componentStyle returns the style of the component v inheriting parent.
+func componentStyle(v any, parent style) style {
+	c, ok := v.(map[string]any)
+	if !ok {
+		return parent
+	}
+	st := parent
+	if color, ok := c["color"].(string); ok {
+		color = strings.ToLower(color)
+		if IsHexColor(color) {
+			st.color, st.hex = "", color
+		} else if code := colorCodes[color]; code != 0 {
+			st.color, st.hex = "c"+string(code), ""
+		}
+	}
+	for key, f := range map[string]*bool{
+		"bold":          &st.bold,
+		"italic":        &st.italic,
+		"underlined":    &st.underline,
+		"strikethrough": &st.strike,
+		"obfuscated":    &st.obf,
+	} {
+		if on, ok := c[key].(bool); ok {
+			*f = on
+		}
+	}
+	return st
+}
+
+// colorNames maps color codes to the names used by JSON text components.
+var colorNames = map[rune]string{
+	'0': "black", '1': "dark_blue", '2': "dark_green", '3': "dark_aqua",
+	'4': "dark_red", '5': "dark_purple", '6': "gold", '7': "gray",
+	'8': "dark_gray", '9': "blue", 'a': "green", 'b': "aqua",
+	'c': "red", 'd': "light_purple", 'e': "yellow", 'f': "white",
+}
+
+// colorCodes maps JSON text component color names to color codes.
+var colorCodes = func() map[string]rune {
+	m := make(map[string]rune, len(colorNames))
+	for code, name := range colorNames {
+		m[name] = code
+	}
+	return m
+}()
+
+// ColorName returns the JSON text component color name for a color code
+// (eg. 'e' is "yellow"), or "" if code is not a color.
+func ColorName(code rune) string {
+	if code >= 'A' && code <= 'F' {
+		code = code - 'A' + 'a'
+	}
+	return colorNames[code]
+}
+
+// FirstColor returns the color name of the first color code in s, or "".
+func FirstColor(s string) string {
+	return ColorName(FirstColorCode(s))
+}
+
+// FirstColorCode returns the lowercased first color code in s, or 0.
+func FirstColorCode(s string) rune {
+	rs := []rune(s)
+	for i := 0; i+1 < len(rs); i++ {
+		if rs[i] == '&' || rs[i] == '§' {
+			if c := ColorName(rs[i+1]); c != "" {
+				return unicode.ToLower(rs[i+1])
+			}
+		}
+	}
+	return 0
+}
+
+// Component is a JSON text component, as used by /tellraw.
+type Component struct {
+	Text          string `json:"text"`
+	Color         string `json:"color,omitempty"`
+	Bold          bool   `json:"bold,omitempty"`
+	Italic        bool   `json:"italic,omitempty"`
+	Underlined    bool   `json:"underlined,omitempty"`
+	Strikethrough bool   `json:"strikethrough,omitempty"`
+	Obfuscated    bool   `json:"obfuscated,omitempty"`
+}
+
+// Components splits s into text components, one per run of text with the same
+// style. As in the game, a color code also clears the formats before it. Hex
+// colors are given as #rrggbb.
+func Components(s string) []Component {
+	var cs []Component
+	var st Component
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			c := st
+			c.Text = text.String()
+			cs = append(cs, c)
+			text.Reset()
+		}
+	}
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		if hex, n := HexCode(rs, i); n > 0 {
+			if next := (Component{Color: hex}); next != st {
+				flush()
+				st = next
+			}
+			i += n - 1
+			continue
+		}
+		if (rs[i] == '§' || rs[i] == '&') && i+1 < len(rs) {
+			code := unicode.ToLower(rs[i+1])
+			next := st
+			switch code {
+			case 'k':
+				next.Obfuscated = true
+			case 'l':
+				next.Bold = true
+			case 'm':
+				next.Strikethrough = true
+			case 'n':
+				next.Underlined = true
+			case 'o':
+				next.Italic = true
+			case 'r':
+				next = Component{}
+			default:
+				name := ColorName(code)
+				if name == "" {
+					// not a code, eg. "salt & pepper"
+					text.WriteRune(rs[i])
+					continue
+				}
+				next = Component{Color: name}
+			}
+			if next != st {
+				flush()
+				st = next
+			}
+			i++
+			continue
+		}
+		text.WriteRune(rs[i])
+	}
+	flush()
+	return cs
 }
@@ -0,0 +1,337 @@
+package mcformat
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// miniColors are the color names MiniMessage accepts besides those of JSON
+// text components.
+var miniColors = map[string]rune{"grey": '7', "dark_grey": '8'}
+
+// miniDecorations maps MiniMessage decoration tags and their aliases to the
+// style field they set.
+var miniDecorations = map[string]func(*style) *bool{
+	"bold":          func(s *style) *bool { return &s.bold },
+	"b":             func(s *style) *bool { return &s.bold },
+	"italic":        func(s *style) *bool { return &s.italic },
+	"i":             func(s *style) *bool { return &s.italic },
+	"em":            func(s *style) *bool { return &s.italic },
+	"underlined":    func(s *style) *bool { return &s.underline },
+	"u":             func(s *style) *bool { return &s.underline },
+	"strikethrough": func(s *style) *bool { return &s.strike },
+	"st":            func(s *style) *bool { return &s.strike },
+	"obfuscated":    func(s *style) *bool { return &s.obf },
+	"obf":           func(s *style) *bool { return &s.obf },
+}
+
+// miniColor returns the #rrggbb or color code class for a MiniMessage color
+// argument, a name or a hex color.
+func miniColor(arg string) (hex, class string, ok bool) {
+	arg = strings.ToLower(arg)
+	if IsHexColor(arg) {
+		return arg, "", true
+	}
+	if code, ok := colorCodes[arg]; ok {
+		return "", "c" + string(code), true
+	}
+	if code, ok := miniColors[arg]; ok {
+		return "", "c" + string(code), true
+	}
+	return "", "", false
+}
+
+// miniTag is a parsed opening tag: its name and arguments.
+type miniTag struct {
+	name string
+	args []string
+}
+
+// parseMiniTag parses the inside of <...>. <#ff0000> is short for
+// <color:#ff0000> and a bare color name for <color:name>.
+func parseMiniTag(s string) (miniTag, bool) {
+	if s == "" {
+		return miniTag{}, false
+	}
+	parts := strings.Split(s, ":")
+	t := miniTag{name: strings.ToLower(parts[0]), args: parts[1:]}
+	switch t.name {
+	case "color", "colour", "c":
+		if len(t.args) != 1 {
+			return t, false
+		}
+		_, _, ok := miniColor(t.args[0])
+		return miniTag{name: "color", args: t.args}, ok
+	case "gradient", "rainbow", "reset", "newline", "br":
+		return t, true
+	}
+	if _, _, ok := miniColor(t.name); ok && len(t.args) == 0 {
+		return miniTag{name: "color", args: []string{t.name}}, true
+	}
+	if _, ok := miniDecorations[t.name]; ok {
+		return t, true
+	}
+	// decorations can be turned off with <!bold> or <bold:false>
+	if _, ok := miniDecorations[strings.TrimPrefix(t.name, "!")]; ok {
+		return t, true
+	}
+	return t, false
+}
+
+// IsMiniMessage reports whether s contains any MiniMessage tags.
+func IsMiniMessage(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '<' {
+			continue
+		}
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			return false
+		}
+		if _, ok := parseMiniTag(strings.TrimPrefix(s[i+1:i+end], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// miniGradient colors the characters of a gradient or rainbow tag, whose
+// length is only known once the tag is closed.
+type miniGradient struct {
+	stops   [][3]float64
+	rainbow bool
+	n       int
+}
+
+// color returns the color of the i'th of the gradient's characters.
+func (g *miniGradient) color(i int) string {
+	t := 0.0
+	if g.n > 1 {
+		t = float64(i) / float64(g.n-1)
+	}
+	if g.rainbow {
+		return hslHex(t*300, 1, 0.5)
+	}
+	if len(g.stops) == 1 {
+		return rgbHex(g.stops[0])
+	}
+	pos := t * float64(len(g.stops)-1)
+	k := min(int(pos), len(g.stops)-2)
+	f := pos - float64(k)
+	a, b := g.stops[k], g.stops[k+1]
+	return rgbHex([3]float64{a[0] + (b[0]-a[0])*f, a[1] + (b[1]-a[1])*f, a[2] + (b[2]-a[2])*f})
+}
+
+// newGradient reads a gradient's colors, which default to white to black.
+func newGradient(args []string) *miniGradient {
+	g := &miniGradient{}
+	for _, arg := range args {
+		hex, class, ok := miniColor(arg)
+		if !ok {
+			// eg. the phase argument
+			continue
+		}
+		if hex == "" {
+			hex = classHex[class]
+		}
+		r, _ := strconv.ParseUint(hex[1:3], 16, 8)
+		gr, _ := strconv.ParseUint(hex[3:5], 16, 8)
+		b, _ := strconv.ParseUint(hex[5:7], 16, 8)
+		g.stops = append(g.stops, [3]float64{float64(r), float64(gr), float64(b)})
+	}
+	if len(g.stops) == 0 {
+		g.stops = [][3]float64{{255, 255, 255}, {0, 0, 0}}
+	}
+	return g
+}
+
+func rgbHex(c [3]float64) string {
+	return fmt.Sprintf("#%02x%02x%02x", int(math.Round(c[0])), int(math.Round(c[1])), int(math.Round(c[2])))
+}
+
+// hslHex converts a hue in degrees, saturation and lightness to #rrggbb.
+func hslHex(h, s, l float64) string {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g = c, x
+	case h < 120:
+		r, g = x, c
+	case h < 180:
+		g, b = c, x
+	case h < 240:
+		g, b = x, c
+	case h < 300:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := l - c/2
+	return rgbHex([3]float64{(r + m) * 255, (g + m) * 255, (b + m) * 255})
+}
+
+// classHex are the game's colors for the color code classes, used where a
+// gradient names a color.
+var classHex = map[string]string{
+	"c0": "#000000", "c1": "#0000aa", "c2": "#00aa00", "c3": "#00aaaa",
+	"c4": "#aa0000", "c5": "#aa00aa", "c6": "#ffaa00", "c7": "#aaaaaa",
+	"c8": "#555555", "c9": "#5555ff", "ca": "#55ff55", "cb": "#55ffff",
+	"cc": "#ff5555", "cd": "#ff55ff", "ce": "#ffff55", "cf": "#ffffff",
+}
+
+// styledRune is a character of text and its style.
+type styledRune struct {
+	r  rune
+	st style
+}
+
+// miniRunes returns the characters of text written with MiniMessage tags,
+// styled by the tags around them.
+func miniRunes(s string) []styledRune {
+	type frame struct {
+		name string
+		st   style
+		g    *miniGradient
+	}
+	type char struct {
+		r  rune
+		st style
+		g  *miniGradient
+		gi int
+	}
+	var (
+		chars []char
+		stack []frame
+		st    style
+		g     *miniGradient
+	)
+	text := func(t string) {
+		for _, r := range t {
+			c := char{r: r, st: st, g: g}
+			if g != nil {
+				c.gi = g.n
+				g.n++
+			}
+			chars = append(chars, c)
+		}
+	}
+	for len(s) > 0 {
+		i := strings.IndexAny(s, "<\\")
+		if i < 0 {
+			text(s)
+			break
+		}
+		text(s[:i])
+		s = s[i:]
+		if s[0] == '\\' {
+			// \< writes a literal <
+			if len(s) > 1 && (s[1] == '<' || s[1] == '\\') {
+				text(s[1:2])
+				s = s[2:]
+			} else {
+				text(`\`)
+				s = s[1:]
+			}
+			continue
+		}
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			text(s)
+			break
+		}
+		inner := s[1:end]
+		if name, ok := strings.CutPrefix(inner, "/"); ok {
+			tag, known := parseMiniTag(name)
+			if !known && name != "" {
+				text("<")
+				s = s[1:]
+				continue
+			}
+			// close the innermost matching tag and everything inside it
+			for j := len(stack) - 1; j >= 0; j-- {
+				if name == "" || stack[j].name == tag.name {
+					st, g = stack[j].st, stack[j].g
+					stack = stack[:j]
+					break
+				}
+			}
+			s = s[end+1:]
+			continue
+		}
+		tag, known := parseMiniTag(inner)
+		if !known {
+			// not a tag; what follows the < may still hold one
+			text("<")
+			s = s[1:]
+			continue
+		}
+		s = s[end+1:]
+		switch tag.name {
+		case "newline", "br":
+			text("\n")
+			continue
+		case "reset":
+			st, g, stack = style{}, nil, nil
+			continue
+		}
+		stack = append(stack, frame{name: tag.name, st: st, g: g})
+		switch tag.name {
+		case "color":
+			st.hex, st.color, _ = miniColor(tag.args[0])
+			g = nil
+		case "gradient":
+			g = newGradient(tag.args)
+		case "rainbow":
+			g = &miniGradient{rainbow: true}
+		default:
+			name, on := tag.name, true
+			if n, ok := strings.CutPrefix(name, "!"); ok {
+				name, on = n, false
+			}
+			if len(tag.args) > 0 && tag.args[0] == "false" {
+				on = false
+			}
+			*miniDecorations[name](&st) = on
+		}
+	}
+
+	rs := make([]styledRune, len(chars))
+	for i, c := range chars {
+		rs[i] = styledRune{r: c.r, st: c.st}
+		if c.g != nil {
+			rs[i].st.color, rs[i].st.hex = "", c.g.color(c.gi)
+		}
+	}
+	return rs
+}
+
+// FormatMiniMessage renders text written with MiniMessage tags, eg. <gold>,
+// <bold> or <gradient:#ff0000:#0000ff>, as HTML, using the same spans as
+// Format. Some packs write quest text with them, and a mod turns them into
+// components in game; this is an approximation: colors and decorations are
+// applied, gradients and rainbows color each character, and tags that aren't
+// understood are shown as written.
+func FormatMiniMessage(s string) template.HTML {
+	var b strings.Builder
+	open := false
+	var cur style
+	for _, c := range miniRunes(s) {
+		if !open || c.st != cur {
+			if open {
+				b.WriteString("</span>")
+			}
+			writeSpanOpen(&b, c.st)
+			open, cur = true, c.st
+		}
+		b.WriteString(template.HTMLEscapeString(string(c.r)))
+	}
+	if open {
+		b.WriteString("</span>")
+	}
+	return template.HTML(b.String())
+}
@@ -0,0 +1,37 @@
+package mcformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatMiniMessage(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"<gold>Gold</gold> plain", []string{`mc-c6">Gold</span>`, `<span class="mc-text"> plain</span>`}},
+		{"<#ff0000><b>Hot</b> red", []string{`mc-bold" style="color:#ff0000">Hot</span>`, `style="color:#ff0000"> red`}},
+		{"<gradient:#ff0000:#0000ff>abc</gradient>", []string{
+			`style="color:#ff0000">a</span>`, `style="color:#800080">b</span>`, `style="color:#0000ff">c</span>`,
+		}},
+		{"<rainbow>ab</rainbow>", []string{`style="color:#ff0000">a`, `style="color:#ff00ff">b`}},
+		{"<red>a<reset>b", []string{`mc-cc">a`, `<span class="mc-text">b`}},
+		{"<red>5 <3 \\<b> & <wat>", []string{`mc-cc">5 &lt;3 &lt;b&gt; &amp; &lt;wat&gt;</span>`}},
+	}
+	for _, c := range cases {
+		got := string(FormatMiniMessage(c.in))
+		for _, w := range c.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("FormatMiniMessage(%q) = %s, missing %s", c.in, got, w)
+			}
+		}
+	}
+
+	if !IsMiniMessage("a <bold>b") || IsMiniMessage("1 < 2 > 0") || IsMiniMessage("&6plain") {
+		t.Error("IsMiniMessage")
+	}
+	if got := string(Format("<gold>Gold")); !strings.Contains(got, `mc-c6">Gold`) {
+		t.Errorf("Format = %s", got)
+	}
+}
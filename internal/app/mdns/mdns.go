@@ -0,0 +1,437 @@
+// Package mdns advertises an HTTP service on the local network with
+// multicast DNS (RFC 6762) and DNS service discovery (RFC 6763), so service
+// browsers find it without being told its address and port.
+//
+// It's a responder for a single service only: it answers queries for the
+// service type, its instance and its host name, and announces them when it
+// starts and withdraws them when it's closed. It doesn't probe for name
+// conflicts, and only advertises IPv4 addresses.
+package mdns
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	typeA   = 1
+	typePTR = 12
+	typeTXT = 16
+	typeSRV = 33
+	typeANY = 255
+
+	classIN = 1
+	// cacheFlush marks a record as the only one of its name and type.
+	cacheFlush = 0x8000
+
+	// TTLs recommended by RFC 6762 section 10.
+	hostTTL    = 120
+	serviceTTL = 4500
+	// legacyTTL caps TTLs in replies to plain DNS resolvers.
+	legacyTTL = 10
+
+	maxLabel = 63
+)
+
+var group = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+var (
+	serviceType = []string{"_http", "_tcp", "local"}
+	metaQuery   = []string{"_services", "_dns-sd", "_udp", "local"}
+)
+
+// Service is an HTTP service to advertise.
+type Service struct {
+	// Instance is the friendly name service browsers show.
+	Instance string
+	// Host is the host name to advertise, without ".local". It defaults to
+	// the machine's host name.
+	Host string
+	Port int
+	// IPs are the IPv4 addresses the service is reachable at.
+	IPs []net.IP
+	// Text is the key=value pairs of the service's TXT record.
+	Text []string
+}
+
+// Server answers queries for a Service until it's closed.
+type Server struct {
+	svc  Service
+	conn *net.UDPConn
+	done chan struct{}
+	once sync.Once
+}
+
+// Advertise announces s on the local network and answers queries for it
+// until the returned Server is closed.
+func Advertise(s Service) (*Server, error) {
+	if s.Host == "" {
+		s.Host = hostLabel()
+	}
+	s.Instance = truncLabel(s.Instance)
+	s.Host = truncLabel(s.Host)
+	if s.Instance == "" {
+		return nil, errors.New("mdns: no instance name")
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return nil, err
+	}
+	srv := &Server{svc: s, conn: conn, done: make(chan struct{})}
+	go srv.serve()
+	go srv.announce()
+	return srv, nil
+}
+
+// Close withdraws the service and stops answering queries for it.
+func (srv *Server) Close() error {
+	var err error
+	srv.once.Do(func() {
+		close(srv.done)
+		srv.conn.WriteToUDP(srv.svc.announcement(0), group)
+		err = srv.conn.Close()
+	})
+	return err
+}
+
+// announce sends the service's records twice, a second apart, as RFC 6762
+// asks of a responder that starts up.
+func (srv *Server) announce() {
+	for i := range 2 {
+		if i > 0 {
+			select {
+			case <-srv.done:
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		srv.conn.WriteToUDP(srv.svc.announcement(1), group)
+	}
+}
+
+func (srv *Server) serve() {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := srv.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-srv.done:
+				return
+			default:
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return
+		}
+		// queries from other ports come from plain DNS resolvers, which
+		// expect a unicast reply
+		legacy := from.Port != group.Port
+		resp := srv.svc.respond(buf[:n], legacy)
+		if resp == nil {
+			continue
+		}
+		to := group
+		if legacy {
+			to = from
+		}
+		srv.conn.WriteToUDP(resp, to)
+	}
+}
+
+type record struct {
+	name  []string
+	typ   uint16
+	flush bool
+	ttl   uint32
+	data  []byte
+}
+
+func (s *Service) instanceName() []string {
+	return append([]string{s.Instance}, serviceType...)
+}
+
+func (s *Service) hostName() []string {
+	return []string{s.Host, "local"}
+}
+
+func (s *Service) ptr() record {
+	return record{name: serviceType, typ: typePTR, ttl: serviceTTL, data: appendName(nil, s.instanceName())}
+}
+
+func (s *Service) srv() record {
+	data := binary.BigEndian.AppendUint16(nil, 0) // priority
+	data = binary.BigEndian.AppendUint16(data, 0) // weight
+	data = binary.BigEndian.AppendUint16(data, uint16(s.Port))
+	data = appendName(data, s.hostName())
+	return record{name: s.instanceName(), typ: typeSRV, flush: true, ttl: hostTTL, data: data}
+}
+
+func (s *Service) txt() record {
+	var data []byte
+	for _, t := range s.Text {
+		if len(t) > 255 {
+			t = t[:255]
+		}
+		data = append(data, byte(len(t)))
+		data = append(data, t...)
+	}
+	if len(data) == 0 {
+		data = []byte{0}
+	}
+	return record{name: s.instanceName(), typ: typeTXT, flush: true, ttl: serviceTTL, data: data}
+}
+
+func (s *Service) addrs() []record {
+	var rs []record
+	for _, ip := range s.IPs {
+		if ip4 := ip.To4(); ip4 != nil {
+			rs = append(rs, record{name: s.hostName(), typ: typeA, flush: true, ttl: hostTTL, data: ip4})
+		}
+	}
+	return rs
+}
+
+// announcement is a response with all of the service's records, with their
+// TTLs multiplied by ttl: 1 to announce them and 0 to withdraw them.
+func (s *Service) announcement(ttl uint32) []byte {
+	rs := append([]record{s.ptr(), s.srv(), s.txt()}, s.addrs()...)
+	for i := range rs {
+		rs[i].ttl *= ttl
+	}
+	return appendMessage(nil, 0, nil, rs, nil)
+}
+
+type question struct {
+	name []string
+	typ  uint16
+}
+
+// respond returns the reply to the query msg, or nil if there's nothing to
+// answer. legacy replies are for plain DNS resolvers: they repeat the query's
+// id and questions and have short TTLs.
+func (s *Service) respond(msg []byte, legacy bool) []byte {
+	if len(msg) < 12 {
+		return nil
+	}
+	id := binary.BigEndian.Uint16(msg)
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 != 0 || flags>>11&0xf != 0 {
+		// a response, or not a standard query
+		return nil
+	}
+	var qs []question
+	off := 12
+	for range binary.BigEndian.Uint16(msg[4:]) {
+		name, n, err := readName(msg, off)
+		if err != nil || n+4 > len(msg) {
+			return nil
+		}
+		qs = append(qs, question{name: name, typ: binary.BigEndian.Uint16(msg[n:])})
+		off = n + 4
+	}
+
+	var answers, extra []record
+	seen := make(map[string]bool)
+	add := func(to *[]record, rs ...record) {
+		for _, r := range rs {
+			key := strings.ToLower(strings.Join(r.name, ".")) + "/" + string(r.data) + "/" + string(rune(r.typ))
+			if !seen[key] {
+				seen[key] = true
+				*to = append(*to, r)
+			}
+		}
+	}
+	want := func(q question, typ uint16) bool { return q.typ == typ || q.typ == typeANY }
+	for _, q := range qs {
+		switch {
+		case sameName(q.name, metaQuery) && want(q, typePTR):
+			add(&answers, record{name: metaQuery, typ: typePTR, ttl: serviceTTL, data: appendName(nil, serviceType)})
+		case sameName(q.name, serviceType) && want(q, typePTR):
+			add(&answers, s.ptr())
+		case sameName(q.name, s.instanceName()):
+			if want(q, typeSRV) {
+				add(&answers, s.srv())
+			}
+			if want(q, typeTXT) {
+				add(&answers, s.txt())
+			}
+		case sameName(q.name, s.hostName()) && want(q, typeA):
+			add(&answers, s.addrs()...)
+		}
+	}
+	if len(answers) == 0 {
+		return nil
+	}
+	// save a round trip with the records the answers point to
+	for _, r := range answers {
+		switch r.typ {
+		case typePTR:
+			if sameName(r.name, serviceType) {
+				add(&extra, s.srv(), s.txt())
+				add(&extra, s.addrs()...)
+			}
+		case typeSRV:
+			add(&extra, s.addrs()...)
+		}
+	}
+	if !legacy {
+		return appendMessage(nil, 0, nil, answers, extra)
+	}
+	for _, rs := range [][]record{answers, extra} {
+		for i := range rs {
+			rs[i].ttl = min(rs[i].ttl, legacyTTL)
+			rs[i].flush = false
+		}
+	}
+	return appendMessage(nil, id, qs, answers, extra)
+}
+
+func appendMessage(b []byte, id uint16, qs []question, answers, extra []record) []byte {
+	b = binary.BigEndian.AppendUint16(b, id)
+	b = binary.BigEndian.AppendUint16(b, 0x8400) // response, authoritative
+	b = binary.BigEndian.AppendUint16(b, uint16(len(qs)))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(answers)))
+	b = binary.BigEndian.AppendUint16(b, 0)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(extra)))
+	for _, q := range qs {
+		b = appendName(b, q.name)
+		b = binary.BigEndian.AppendUint16(b, q.typ)
+		b = binary.BigEndian.AppendUint16(b, classIN)
+	}
+	for _, rs := range [][]record{answers, extra} {
+		for _, r := range rs {
+			b = appendName(b, r.name)
+			b = binary.BigEndian.AppendUint16(b, r.typ)
+			class := uint16(classIN)
+			if r.flush {
+				class |= cacheFlush
+			}
+			b = binary.BigEndian.AppendUint16(b, class)
+			b = binary.BigEndian.AppendUint32(b, r.ttl)
+			b = binary.BigEndian.AppendUint16(b, uint16(len(r.data)))
+			b = append(b, r.data...)
+		}
+	}
+	return b
+}
+
+func appendName(b []byte, labels []string) []byte {
+	for _, l := range labels {
+		b = append(b, byte(len(l)))
+		b = append(b, l...)
+	}
+	return append(b, 0)
+}
+
+var errName = errors.New("mdns: invalid name")
+
+// readName reads the name at off in msg, following compression pointers, and
+// returns it with the offset just past it.
+func readName(msg []byte, off int) ([]string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return nil, 0, errName
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return labels, end, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) || jumps > 10 {
+				return nil, 0, errName
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			jumps++
+		case n > maxLabel || off+1+n > len(msg):
+			return nil, 0, errName
+		default:
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+func sameName(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// truncLabel shortens s to fit in a DNS label without splitting a rune.
+func truncLabel(s string) string {
+	for len(s) > maxLabel {
+		_, n := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-n]
+	}
+	return s
+}
+
+// hostLabel is the machine's host name as a single DNS label.
+func hostLabel() string {
+	h, _ := os.Hostname()
+	h, _, _ = strings.Cut(h, ".")
+	h = strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '-'
+	}, h)
+	if h = strings.Trim(h, "-"); h == "" {
+		return "qbedit"
+	}
+	return h
+}
+
+// LocalIPs returns the IPv4 addresses a server listening on ip can be reached
+// at from the local network: ip itself, or the addresses of the machine's
+// network interfaces if it's unspecified.
+func LocalIPs(ip net.IP) []net.IP {
+	if ip != nil && !ip.IsUnspecified() {
+		if ip.To4() == nil {
+			return nil
+		}
+		return []net.IP{ip}
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagLoopback != 0 || ifi.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && n.IP.To4() != nil {
+				ips = append(ips, n.IP.To4())
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,166 @@
+package mdns
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+var testService = Service{
+	Instance: "qbedit on pack.dev",
+	Host:     "pack",
+	Port:     8222,
+	IPs:      []net.IP{net.IPv4(192, 168, 1, 20)},
+	Text:     []string{"path=/"},
+}
+
+func query(id uint16, name []string, typ uint16) []byte {
+	b := binary.BigEndian.AppendUint16(nil, id)
+	b = append(b, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0)
+	b = appendName(b, name)
+	b = binary.BigEndian.AppendUint16(b, typ)
+	return binary.BigEndian.AppendUint16(b, classIN)
+}
+
+// parse returns the records of a response, and its id.
+func parse(t *testing.T, msg []byte) (uint16, []record) {
+	t.Helper()
+	off := 12
+	for range binary.BigEndian.Uint16(msg[4:]) {
+		_, n, err := readName(msg, off)
+		if err != nil {
+			t.Fatal(err)
+		}
+		off = n + 4
+	}
+	count := int(binary.BigEndian.Uint16(msg[6:]) + binary.BigEndian.Uint16(msg[8:]) + binary.BigEndian.Uint16(msg[10:]))
+	var rs []record
+	for range count {
+		name, n, err := readName(msg, off)
+		if err != nil {
+			t.Fatal(err)
+		}
+		class := binary.BigEndian.Uint16(msg[n+2:])
+		size := int(binary.BigEndian.Uint16(msg[n+8:]))
+		rs = append(rs, record{
+			name:  name,
+			typ:   binary.BigEndian.Uint16(msg[n:]),
+			flush: class&cacheFlush != 0,
+			ttl:   binary.BigEndian.Uint32(msg[n+4:]),
+			data:  msg[n+10 : n+10+size],
+		})
+		off = n + 10 + size
+	}
+	if off != len(msg) {
+		t.Fatalf("%d bytes left over", len(msg)-off)
+	}
+	return binary.BigEndian.Uint16(msg), rs
+}
+
+func find(rs []record, typ uint16) *record {
+	for i := range rs {
+		if rs[i].typ == typ {
+			return &rs[i]
+		}
+	}
+	return nil
+}
+
+func TestRespond(t *testing.T) {
+	s := testService
+
+	resp := s.respond(query(7, serviceType, typePTR), false)
+	if resp == nil {
+		t.Fatal("no reply to a browse query")
+	}
+	id, rs := parse(t, resp)
+	if id != 0 {
+		t.Errorf("multicast reply has id %d", id)
+	}
+	ptr := find(rs, typePTR)
+	if ptr == nil {
+		t.Fatalf("no PTR in %v", rs)
+	}
+	if target, _, _ := readName(ptr.data, 0); strings.Join(target, ".") != "qbedit on pack.dev._http._tcp.local" {
+		t.Errorf("PTR target = %q", target)
+	}
+	srv := find(rs, typeSRV)
+	if srv == nil || binary.BigEndian.Uint16(srv.data[4:]) != 8222 || !srv.flush {
+		t.Fatalf("SRV = %+v", srv)
+	}
+	if host, _, _ := readName(srv.data, 6); strings.Join(host, ".") != "pack.local" {
+		t.Errorf("SRV host = %q", host)
+	}
+	if txt := find(rs, typeTXT); txt == nil || string(txt.data) != "\x06path=/" {
+		t.Errorf("TXT = %+v", txt)
+	}
+	if a := find(rs, typeA); a == nil || !net.IP(a.data).Equal(net.IPv4(192, 168, 1, 20)) {
+		t.Errorf("A = %+v", a)
+	}
+
+	// the host name, case insensitively
+	if _, rs := parse(t, s.respond(query(0, []string{"PACK", "local"}, typeA), false)); len(rs) != 1 || rs[0].typ != typeA {
+		t.Errorf("host query: %+v", rs)
+	}
+	// service type enumeration
+	if _, rs := parse(t, s.respond(query(0, metaQuery, typePTR), false)); len(rs) != 1 || rs[0].typ != typePTR {
+		t.Errorf("meta query: %+v", rs)
+	}
+	// plain DNS resolvers get their id back and short TTLs
+	id, rs = parse(t, s.respond(query(7, s.instanceName(), typeANY), true))
+	if id != 7 || find(rs, typeSRV) == nil || find(rs, typeTXT) == nil {
+		t.Errorf("legacy reply: %d %+v", id, rs)
+	}
+	for _, r := range rs {
+		if r.ttl > legacyTTL || r.flush {
+			t.Errorf("legacy record %+v", r)
+		}
+	}
+
+	// nothing to say about other names, or to responses
+	if resp := s.respond(query(0, []string{"_ipp", "_tcp", "local"}, typePTR), false); resp != nil {
+		t.Errorf("answered another service: %q", resp)
+	}
+	if resp := s.respond(s.announcement(1), false); resp != nil {
+		t.Errorf("answered a response: %q", resp)
+	}
+	if resp := s.respond([]byte{0, 0, 0}, false); resp != nil {
+		t.Errorf("answered a short message: %q", resp)
+	}
+}
+
+func TestAnnouncement(t *testing.T) {
+	s := testService
+	_, rs := parse(t, s.announcement(1))
+	if len(rs) != 4 {
+		t.Fatalf("announced %d records", len(rs))
+	}
+	_, rs = parse(t, s.announcement(0))
+	for _, r := range rs {
+		if r.ttl != 0 {
+			t.Errorf("goodbye record has ttl %d", r.ttl)
+		}
+	}
+}
+
+func TestReadNameCompressed(t *testing.T) {
+	msg := appendName(make([]byte, 12), []string{"pack", "local"})
+	off := len(msg)
+	msg = append(msg, 3, 'w', 'w', 'w', 0xc0, 12)
+	name, end, err := readName(msg, off)
+	if err != nil || strings.Join(name, ".") != "www.pack.local" || end != len(msg) {
+		t.Errorf("readName = %q, %d, %v", name, end, err)
+	}
+	// pointer loops end
+	if _, _, err := readName([]byte{0xc0, 0}, 0); err == nil {
+		t.Error("no error for a pointer loop")
+	}
+}
+
+func TestTruncLabel(t *testing.T) {
+	s := truncLabel(strings.Repeat("é", 40))
+	if len(s) > maxLabel || !strings.HasPrefix(strings.Repeat("é", 40), s) || len(s) != 62 {
+		t.Errorf("truncLabel = %q (%d bytes)", s, len(s))
+	}
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// questHash returns a short hash of a quest's compound. Edit forms carry the
+// hash of the quest as it was rendered: if the quest has changed on disk by
+// the time the form is saved, somebody else (another browser, the game, a
+// text editor) edited it in the meantime, and rather than overwrite their
+// work the editor is shown a merge screen to pick between their version and
+// ours, field by field.
+func questHash(q *Quest) string {
+	var buf bytes.Buffer
+	if err := snbt.Encode(&buf, q.raw); err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:8])
+}
+
+// mergeSections are the parts of a quest that are merged as a whole. Each
+// section's form marker (see applyQuestForm) doubles as its name, and a
+// "merge_<section>" value of "theirs" skips it so the version on disk stays.
+var mergeSections = []struct {
+	Name, Label string
+	Keys        []string
+}{
+	{"dependencies", "Dependencies", []string{"dependencies", "min_required_dependencies"}},
+	{"repeat", "Repeat", []string{"can_repeat", "repeat_cooldown"}},
+	{"tasks", "Tasks", []string{"tasks"}},
+	{"rewards", "Rewards", []string{"rewards"}},
+	// the cosmetic keys depend on the quest; see cosmeticKeys
+	{"cosmetic", "Advanced", nil},
+}
+
+// applyQuestForm updates q from a quest edit form. Title, subtitle and
+// description are always present; the other sections are only edited by the
+// quest page, which includes a marker field for each, so the batch editor
+// leaves them alone.
+func applyQuestForm(qb *QuestBook, q *Quest, form url.Values) error {
+	q.Title = strings.TrimSpace(form.Get("title"))
+	q.Subtitle = strings.TrimSpace(form.Get("subtitle"))
+	q.Description = form.Get("description")
+
+	edit := func(section string) bool {
+		return form.Has(section) && form.Get("merge_"+section) != "theirs"
+	}
+	if edit("dependencies") {
+		if err := dependenciesFromForm(qb, q, form); err != nil {
+			return err
+		}
+	}
+	if edit("repeat") {
+		if err := repeatFromForm(q, form); err != nil {
+			return err
+		}
+	}
+	// new tasks and rewards share a source so their ids can't collide
+	ids := qb.idSource()
+	if edit("tasks") {
+		tasks, err := tasksFromForm(q.Tasks, form, ids)
+		if err != nil {
+			return err
+		}
+		q.Tasks = tasks
+	}
+	if edit("rewards") {
+		rewards, err := rewardsFromForm(q.Rewards, form, ids)
+		if err != nil {
+			return err
+		}
+		q.Rewards = rewards
+	}
+	if edit("cosmetic") {
+		if err := cosmeticFromForm(q, form); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergeField is a part of a quest where our edit and the version on disk
+// disagree. Text fields are picked by value; sections by "ours" or "theirs".
+type MergeField struct {
+	Name   string
+	Label  string
+	Text   bool
+	Ours   string
+	Theirs string
+}
+
+// questConflicts compares the quest qid in the chapter at path with the result
+// of applying form to it, and returns the fields that differ.
+func questConflicts(qb *QuestBook, path, qid string, form url.Values) ([]MergeField, error) {
+	load := func() (*Quest, error) {
+		ch, err := NewChapterFromPath(path)
+		if err != nil {
+			return nil, err
+		}
+		ch.resolveLang(qb.Lang)
+		return ch.questMap[qid], nil
+	}
+	theirs, err := load()
+	if err != nil {
+		return nil, err
+	}
+	ours, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if err := applyQuestForm(qb, ours, form); err != nil {
+		return nil, err
+	}
+	theirs.Sync()
+	ours.Sync()
+
+	var fields []MergeField
+	text := func(name, label, o, t string) {
+		if o != t {
+			fields = append(fields, MergeField{Name: name, Label: label, Text: true, Ours: o, Theirs: t})
+		}
+	}
+	text("title", "Title", ours.Title, theirs.Title)
+	text("subtitle", "Subtitle", ours.Subtitle, theirs.Subtitle)
+	text("description", "Description",
+		strings.Join(splitMultistring(ours.Description), "\n"),
+		strings.Join(splitMultistring(theirs.Description), "\n"))
+
+	for _, s := range mergeSections {
+		if !form.Has(s.Name) {
+			continue
+		}
+		keys := s.Keys
+		if s.Name == "cosmetic" {
+			keys = cosmeticKeys(ours.raw, theirs.raw)
+		}
+		o, t := sectionString(ours.raw, keys), sectionString(theirs.raw, keys)
+		if o != t {
+			fields = append(fields, MergeField{Name: s.Name, Label: s.Label, Ours: o, Theirs: t})
+		}
+	}
+	return fields, nil
+}
+
+// sectionString renders the keys of raw for display and comparison.
+func sectionString(raw map[string]any, keys []string) string {
+	var buf bytes.Buffer
+	for _, k := range keys {
+		v, ok := raw[k]
+		if !ok {
+			continue
+		}
+		buf.WriteString(k + ": ")
+		if err := snbt.EncodeIndent(&buf, v); err != nil {
+			return ""
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// mergeHidden returns the form values to carry through the merge screen
+// unchanged: everything except the hash, which is replaced with the current
+// one, and the text fields being picked.
+func mergeHidden(form url.Values, fields []MergeField) [][2]string {
+	skip := map[string]bool{"hash": true}
+	for _, f := range fields {
+		if f.Text {
+			skip[f.Name] = true
+		}
+		skip["merge_"+f.Name] = true
+	}
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		if !skip[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var pairs [][2]string
+	for _, k := range keys {
+		for _, v := range form[k] {
+			pairs = append(pairs, [2]string{k, v})
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuestConflicts(t *testing.T) {
+	src := filepath.Join("..", "..", "snbt", "test_chapter.snbt")
+	b, err := os.ReadFile(src)
+	if err != nil {
+		t.Skip("test_chapter.snbt not present; skipping")
+	}
+	path := filepath.Join(t.TempDir(), "chapter.snbt")
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		t.Fatalf("load chapter: %v", err)
+	}
+	q := ch.Quests[0]
+	qb := &QuestBook{questMap: ch.questMap}
+
+	// a form that leaves everything as it is has no conflicts
+	form := url.Values{
+		"title":       {q.Title},
+		"subtitle":    {q.Subtitle},
+		"description": {q.Description},
+	}
+	fields, err := questConflicts(qb, path, q.ID, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 0 {
+		t.Errorf("unchanged form: got conflicts %+v", fields)
+	}
+
+	form.Set("title", "Something else")
+	form.Set("repeat", "1")
+	form.Set("can_repeat", "1")
+	fields, err = questConflicts(qb, path, q.ID, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 || fields[0].Name != "title" || !fields[0].Text || fields[1].Name != "repeat" || fields[1].Text {
+		t.Fatalf("got conflicts %+v", fields)
+	}
+	if fields[0].Ours != "Something else" || fields[0].Theirs != q.Title {
+		t.Errorf("title conflict = %+v", fields[0])
+	}
+
+	hidden := mergeHidden(form, fields)
+	for _, kv := range hidden {
+		if kv[0] == "title" || kv[0] == "hash" {
+			t.Errorf("hidden fields include %s", kv[0])
+		}
+	}
+	if len(hidden) != 4 {
+		t.Errorf("hidden = %v", hidden)
+	}
+
+	// picking "theirs" for a section leaves it alone
+	form.Set("merge_repeat", "theirs")
+	if err := applyQuestForm(qb, q, form); err != nil {
+		t.Fatal(err)
+	}
+	if q.Repeatable {
+		t.Errorf("merge_repeat=theirs still applied the repeat section")
+	}
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// minimapPad is the margin around the quests, in grid units.
+const minimapPad = 1.0
+
+// chapterMinimap returns an SVG of ch's quests and the dependencies between
+// them, with the quest current highlighted, for the sidebar to show the open
+// quest in context. It is drawn in grid units from the quests' positions,
+// like the in-game quest map, and scaled to fit by the browser. Each quest
+// links to its editor, under base.
+func chapterMinimap(ch *Chapter, current, base string) string {
+	type node struct {
+		q       *Quest
+		x, y, r float64
+	}
+	nodes := make(map[string]node, len(ch.Quests))
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, q := range ch.Quests {
+		n := node{q: q, x: q.X, y: q.Y, r: 0.5 * q.Size}
+		nodes[q.ID] = n
+		minX, minY = math.Min(minX, n.x-n.r), math.Min(minY, n.y-n.r)
+		maxX, maxY = math.Max(maxX, n.x+n.r), math.Max(maxY, n.y+n.r)
+	}
+	if len(nodes) == 0 {
+		minX, minY, maxX, maxY = 0, 0, 0, 0
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" class="minimap-svg" viewBox="%g %g %g %g">`,
+		minX-minimapPad, minY-minimapPad, maxX-minX+2*minimapPad, maxY-minY+2*minimapPad)
+	b.WriteString(`<g class="mm-edges">`)
+	for _, q := range ch.Quests {
+		to := nodes[q.ID]
+		for _, dep := range q.Dependencies {
+			// dependencies in other chapters aren't on this map
+			if from, ok := nodes[dep]; ok {
+				fmt.Fprintf(&b, `<line x1="%g" y1="%g" x2="%g" y2="%g"/>`, from.x, from.y, to.x, to.y)
+			}
+		}
+	}
+	b.WriteString(`</g>`)
+	for _, q := range ch.Quests {
+		n := nodes[q.ID]
+		class := "mm-quest"
+		if q.ID == current {
+			class += " mm-current"
+		}
+		title := stripCodes(q.GetTitle())
+		if title == "" {
+			title = q.ID
+		}
+		fmt.Fprintf(&b, `<a href="%s/chapter/%s/%s"><circle class="%s" cx="%g" cy="%g" r="%g"><title>%s</title></circle></a>`,
+			base, ch.Name, q.ID, class, n.x, n.y, n.r, html.EscapeString(title))
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// chapterMinimapSVG handles GET "/chapter/{chapter}/map.svg", the sidebar's
+// overview of the chapter. ?q highlights a quest.
+func (a *App) chapterMinimapSVG(w http.ResponseWriter, r *http.Request) {
+	ch, ok := a.QB().chapterMap[chi.URLParam(r, "chapter")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	w.Write([]byte(chapterMinimap(ch, r.URL.Query().Get("q"), a.Base)))
+}
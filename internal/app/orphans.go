@@ -0,0 +1,325 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// OrphanKind is why something is reported as orphaned.
+type OrphanKind string
+
+const (
+	// OrphanTemp is a temporary file left behind by a write.
+	OrphanTemp OrphanKind = "temp"
+	// OrphanMisnamed is a file in the chapters or reward_tables directory
+	// whose name doesn't end in .snbt.
+	OrphanMisnamed OrphanKind = "misnamed"
+	// OrphanMisplaced is an .snbt file in a directory the loader doesn't read.
+	OrphanMisplaced OrphanKind = "misplaced"
+	// OrphanQuest is a quest with no dependencies, no dependents and no
+	// links, in a chapter with other quests.
+	OrphanQuest OrphanKind = "quest"
+	// OrphanTable is a reward table no reward rolls.
+	OrphanTable OrphanKind = "table"
+)
+
+// staleTemp is how old a temporary file must be before it is reported; a
+// younger one may belong to a write in progress.
+const staleTemp = time.Minute
+
+// Orphan is one orphaned file, quest or reward table.
+type Orphan struct {
+	Kind OrphanKind
+	// Path is an orphaned file's path, relative to the quests directory and
+	// with forward slashes.
+	Path string
+	// Target is where the file belongs, relative like Path, or "" if that
+	// isn't known or is taken.
+	Target string
+	// Detail says what the file holds.
+	Detail string
+	Quest  *Quest
+	Table  *RewardTable
+}
+
+// knownFile reports whether the loader (or FTB Quests itself) reads the file
+// at rel, a path relative to the quests directory.
+func knownFile(rel string) bool {
+	dir, name := path.Split(rel)
+	switch dir {
+	case "":
+		return name == "data.snbt" || name == "chapter_groups.snbt"
+	case "chapters/", "reward_tables/":
+		return strings.HasSuffix(name, ".snbt")
+	}
+	// newer versions of FTB Quests keep their text in quests/lang
+	return strings.HasPrefix(rel, "lang/")
+}
+
+// fileTarget returns where the file at rel with contents b belongs, and a
+// description of what it holds. The target is "" if b isn't a chapter or a
+// reward table, or if no file name can be made from rel.
+func fileTarget(rel string, b []byte) (target, detail string) {
+	v, err := snbt.Decode(bytes.NewReader(b))
+	if err != nil {
+		return "", "doesn't parse"
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return "", "not a compound"
+	}
+	var dir string
+	switch {
+	case M(m).Has("quests") || M(m).Has("filename"):
+		dir, detail = "chapters", "a chapter"
+	case M(m).Has("rewards"):
+		dir, detail = "reward_tables", "a reward table"
+	default:
+		return "", "not a chapter or reward table"
+	}
+	name := strings.ToLower(path.Base(rel))
+	if i := strings.Index(name, ".snbt"); i >= 0 {
+		name = name[:i]
+	}
+	if !validChapterName.MatchString(name) {
+		return "", detail
+	}
+	return dir + "/" + name + ".snbt", detail
+}
+
+// findOrphans returns the orphaned files, quests and reward tables of qb: the
+// parts of the book nothing uses. Orphaned files are those under the quests
+// directory the loader doesn't read, such as a chapter saved with the wrong
+// extension, a reward table in the chapters directory or a temporary file
+// left by an interrupted write.
+func findOrphans(qb *QuestBook) ([]Orphan, error) {
+	var orphans []Orphan
+	quests := filepath.Join(qb.root, "quests")
+	err := filepath.WalkDir(quests, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(quests, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasSuffix(rel, tmpSuffix) {
+			if info, err := d.Info(); err == nil && time.Since(info.ModTime()) >= staleTemp {
+				orphans = append(orphans, Orphan{Kind: OrphanTemp, Path: rel, Detail: "left by an interrupted write"})
+			}
+			return nil
+		}
+		if knownFile(rel) || !strings.Contains(strings.ToLower(rel), ".snbt") {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		o := Orphan{Kind: OrphanMisplaced, Path: rel}
+		if dir := path.Dir(rel); dir == "chapters" || dir == "reward_tables" {
+			o.Kind = OrphanMisnamed
+		}
+		o.Target, o.Detail = fileTarget(rel, b)
+		if o.Target != "" {
+			if _, err := os.Stat(filepath.Join(quests, filepath.FromSlash(o.Target))); err == nil {
+				o.Detail += ", but " + o.Target + " exists"
+				o.Target = ""
+			}
+		}
+		orphans = append(orphans, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range qb.Chapters {
+		if len(c.Quests) < 2 {
+			continue
+		}
+		for _, q := range c.Quests {
+			if len(q.Dependencies) > 0 {
+				continue
+			}
+			if refs := qb.questIDRefs(q.ID); len(refs.Dependents) == 0 && refs.LinkCount() == 0 {
+				orphans = append(orphans, Orphan{Kind: OrphanQuest, Quest: q})
+			}
+		}
+	}
+	for _, t := range qb.Tables {
+		if len(qb.tableUsers(t)) == 0 {
+			orphans = append(orphans, Orphan{Kind: OrphanTable, Path: "reward_tables/" + t.Name + ".snbt", Table: t})
+		}
+	}
+	return orphans, nil
+}
+
+// orphanFile returns the orphaned file at rel, which must be one
+// findOrphans reports so that only those can be moved or deleted.
+func (qb *QuestBook) orphanFile(rel string) (Orphan, error) {
+	orphans, err := findOrphans(qb)
+	if err != nil {
+		return Orphan{}, err
+	}
+	for _, o := range orphans {
+		if o.Path == rel && o.Quest == nil {
+			return o, nil
+		}
+	}
+	return Orphan{}, fmt.Errorf("%s isn't an orphaned file", rel)
+}
+
+// RelocateOrphan moves the orphaned file at rel to where it belongs.
+func (qb *QuestBook) RelocateOrphan(rel string) (string, error) {
+	o, err := qb.orphanFile(rel)
+	if err != nil {
+		return "", err
+	}
+	if o.Target == "" {
+		return "", fmt.Errorf("it isn't known where %s belongs", rel)
+	}
+	from := filepath.Join(qb.root, "quests", filepath.FromSlash(rel))
+	to := filepath.Join(qb.root, "quests", filepath.FromSlash(o.Target))
+	b, err := os.ReadFile(from)
+	if err != nil {
+		return "", err
+	}
+	if err := checkRemove(from); err != nil {
+		return "", err
+	}
+	if err := checkProtected(to, nil, b); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return "", err
+	}
+	// write the new file before removing the old one so a failure can't
+	// lose it
+	recordWrite(to, b)
+	if err := writeFile(to, b, 0644); err != nil {
+		return "", err
+	}
+	recordRemove(from)
+	return o.Target, os.Remove(from)
+}
+
+// DeleteOrphanFile removes the orphaned file at rel.
+func (qb *QuestBook) DeleteOrphanFile(rel string) error {
+	if _, err := qb.orphanFile(rel); err != nil {
+		return err
+	}
+	p := filepath.Join(qb.root, "quests", filepath.FromSlash(rel))
+	if err := checkRemove(p); err != nil {
+		return err
+	}
+	recordRemove(p)
+	return os.Remove(p)
+}
+
+// DeleteOrphanQuest removes the quest id, which must be one findOrphans
+// reports, from its chapter.
+func (qb *QuestBook) DeleteOrphanQuest(id string) error {
+	orphans, err := findOrphans(qb)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(orphans, func(o Orphan) bool { return o.Quest != nil && o.Quest.ID == id })
+	if i < 0 {
+		return fmt.Errorf("%s isn't an orphaned quest", id)
+	}
+	name := orphans[i].Quest.Chapter.Name
+	ch, err := NewChapterFromPath(qb.chapterPath(name))
+	if err != nil {
+		return fmt.Errorf("open chapter %s: %w", name, err)
+	}
+	ch.resolveLang(qb.Lang)
+	ch.Quests = slices.DeleteFunc(ch.Quests, func(q *Quest) bool { return q.ID == id })
+	return saveChapter(ch, qb.chapterPath(name), qb.Lang)
+}
+
+// orphans handles GET "/orphans".
+func (a *App) orphans(w http.ResponseWriter, r *http.Request) {
+	orphans, err := findOrphans(a.QB())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := a.baseData(r, "Orphans")
+	var files, quests, tables []Orphan
+	for _, o := range orphans {
+		switch o.Kind {
+		case OrphanQuest:
+			quests = append(quests, o)
+		case OrphanTable:
+			tables = append(tables, o)
+		default:
+			files = append(files, o)
+		}
+	}
+	data["Files"], data["Quests"], data["UnusedTables"] = files, quests, tables
+	data["Msg"] = r.URL.Query().Get("msg")
+	a.render(w, "orphans.gohtml", data)
+}
+
+// orphansRelocate handles POST "/orphans/relocate", which moves the
+// orphaned file "path" to where it belongs.
+func (a *App) orphansRelocate(w http.ResponseWriter, r *http.Request) {
+	rel := r.FormValue("path")
+	target, err := a.QB().RelocateOrphan(rel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "relocate orphan", "", nil, rel+" to "+target)
+	a.reload()
+	msg := fmt.Sprintf("Moved %s to %s.", rel, target)
+	http.Redirect(w, r, "/orphans?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// orphansDelete handles POST "/orphans/delete", which removes the orphaned
+// file "path", or the orphaned quest "quest" from its chapter.
+func (a *App) orphansDelete(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	var msg string
+	if id := r.FormValue("quest"); id != "" {
+		q, ok := qb.questMap[id]
+		if !ok {
+			http.Error(w, "unknown quest "+id, http.StatusBadRequest)
+			return
+		}
+		if err := qb.DeleteOrphanQuest(id); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.audit(r, "delete orphan", q.Chapter.Name, []string{id}, "")
+		msg = fmt.Sprintf("Removed %s from %s.", id, q.Chapter.Name)
+	} else {
+		rel := r.FormValue("path")
+		err := qb.DeleteOrphanFile(rel)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = fmt.Errorf("%s no longer exists", rel)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.audit(r, "delete orphan", "", nil, rel)
+		msg = fmt.Sprintf("Deleted %s.", rel)
+	}
+	a.reload()
+	http.Redirect(w, r, "/orphans?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrphans(t *testing.T) {
+	a := testApp(t)
+	quests := filepath.Join(a.Root(), "quests")
+	chapter, err := os.ReadFile(filepath.Join(quests, "chapters", "test.snbt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lonely := `{
+	id: "00000000000000C1"
+	filename: "lonely"
+	order_index: 1
+	title: "Lonely"
+	quests: [
+		{ id: "00000000000000D1", x: 0.0d, y: 0.0d }
+		{ dependencies: ["00000000000000D1"], id: "00000000000000D2", x: 1.0d, y: 0.0d }
+		{ id: "00000000000000D3", x: 2.0d, y: 0.0d }
+	]
+}
+`
+	old := time.Now().Add(-time.Hour)
+	files := map[string]string{
+		"chapters/lonely.snbt":             lonely,
+		"stray.snbt":                       string(chapter),
+		"chapters/backup/test.snbt":        string(chapter),
+		"chapters/Lonely.SNBT.bak":         lonely,
+		"chapters/test.snbt.1" + tmpSuffix: "{}",
+		"chapters/test.snbt.2" + tmpSuffix: "{}",
+		"reward_tables/unused.snbt":        `{ id: "00000000000000E1", rewards: [ { item: "minecraft:stone" } ] }`,
+		"lang/en_us.snbt":                  "{}",
+		"chapters/notes.txt":               "not a chapter",
+	}
+	for name, s := range files {
+		p := filepath.Join(quests, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// only temporary files old enough not to belong to a write are reported
+	if err := os.Chtimes(filepath.Join(quests, "chapters", "test.snbt.1"+tmpSuffix), old, old); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+
+	orphans, err := findOrphans(a.QB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, o := range orphans {
+		key := o.Path
+		if o.Quest != nil {
+			key = o.Quest.ID
+		}
+		got[key] = string(o.Kind) + " " + o.Target
+	}
+	want := map[string]string{
+		"stray.snbt":                       "misplaced chapters/stray.snbt",
+		"chapters/backup/test.snbt":        "misplaced ",
+		"chapters/Lonely.SNBT.bak":         "misnamed ",
+		"chapters/test.snbt.1" + tmpSuffix: "temp ",
+		"reward_tables/unused.snbt":        "table ",
+		"00000000000000D3":                 "quest ",
+	}
+	if len(got) != len(want) {
+		t.Errorf("orphans = %v", got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/orphans", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, "Move to <code>chapters/stray.snbt</code>") {
+		t.Errorf("orphans page: %d %s", rec.Code, body)
+	}
+
+	if rec := post("/orphans/relocate", url.Values{"path": {"stray.snbt"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("relocate: %d %s", rec.Code, rec.Body)
+	}
+	if _, err := os.Stat(filepath.Join(quests, "stray.snbt")); !os.IsNotExist(err) {
+		t.Error("stray.snbt is still there")
+	}
+	if a.QB().chapterMap["stray"] == nil {
+		t.Error("the moved chapter isn't loaded")
+	}
+	if rec := post("/orphans/delete", url.Values{"path": {"chapters/test.snbt.1" + tmpSuffix}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+	}
+	if rec := post("/orphans/delete", url.Values{"quest": {"00000000000000D3"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("delete quest: %d %s", rec.Code, rec.Body)
+	}
+	if c := a.QB().chapterMap["lonely"]; c == nil || len(c.Quests) != 2 {
+		t.Errorf("lonely = %+v", c)
+	}
+
+	// only what is reported can be moved or deleted
+	for _, bad := range []url.Values{
+		{"path": {"chapters/test.snbt"}},
+		{"path": {"../../etc/passwd"}},
+		{"path": {"chapters/test.snbt.2" + tmpSuffix}},
+		{"quest": {"00000000000000D1"}},
+	} {
+		if rec := post("/orphans/delete", bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("delete %v: %d", bad, rec.Code)
+		}
+	}
+	if rec := post("/orphans/relocate", url.Values{"path": {"chapters/Lonely.SNBT.bak"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("relocate onto an existing chapter: %d", rec.Code)
+	}
+}
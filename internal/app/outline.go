@@ -0,0 +1,416 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/qbedit/snbt"
+	"gopkg.in/yaml.v3"
+)
+
+const outlineHeader = `# The structure of the quest book. Reorder the entries to reorder groups and
+# chapters, move chapters between groups, or change titles, then import the
+# file at /outline. Add a group with an entry without a group id; groups left
+# out of the outline are removed. Every chapter must stay in the outline.
+# Quests are listed for reference and changes to them are ignored.
+`
+
+// Outline is the book's structure, in sidebar order: its groups and ungrouped
+// chapters, the chapters of each group and the quests of each chapter. It is
+// exported as YAML for authors to reorganize the book in a text editor:
+// importing it again reorders and regroups chapters as the entries were
+// moved, and retitles chapters and groups. Quests are listed for reference
+// only and are not imported.
+type Outline struct {
+	Book []*OutlineEntry `yaml:"book"`
+}
+
+// OutlineEntry is a group, which has chapters, or a chapter. Quests are
+// "ID title" strings.
+type OutlineEntry struct {
+	Group    string          `yaml:"group,omitempty"`
+	Chapter  string          `yaml:"chapter,omitempty"`
+	Title    string          `yaml:"title,omitempty"`
+	Chapters []*OutlineEntry `yaml:"chapters,omitempty"`
+	Quests   []string        `yaml:"quests,omitempty"`
+}
+
+// bookOutline returns the outline of qb.
+func bookOutline(qb *QuestBook) *Outline {
+	chapter := func(c *Chapter) *OutlineEntry {
+		e := &OutlineEntry{Chapter: c.Name, Title: c.Title}
+		for _, q := range c.Quests {
+			e.Quests = append(e.Quests, strings.TrimSpace(q.ID+" "+q.GetTitle()))
+		}
+		return e
+	}
+	o := &Outline{}
+	for _, it := range qb.TopItems() {
+		if it.Kind == "chapter" {
+			o.Book = append(o.Book, chapter(it.Chapter))
+			continue
+		}
+		g := &OutlineEntry{Group: it.Group.ID, Title: it.Group.Title}
+		for _, c := range it.Group.Chapters {
+			g.Chapters = append(g.Chapters, chapter(c))
+		}
+		o.Book = append(o.Book, g)
+	}
+	return o
+}
+
+// writeOutline writes o as YAML after a comment explaining how to edit it.
+func writeOutline(w io.Writer, o *Outline) error {
+	if _, err := io.WriteString(w, outlineHeader); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(o); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// readOutline parses an outline, refusing fields it doesn't know so that a
+// misspelt key isn't silently ignored.
+func readOutline(s string) (*Outline, error) {
+	dec := yaml.NewDecoder(strings.NewReader(s))
+	dec.KnownFields(true)
+	var o Outline
+	if err := dec.Decode(&o); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the outline is empty")
+		}
+		return nil, fmt.Errorf("invalid outline: %w", err)
+	}
+	return &o, nil
+}
+
+// placement is where a chapter goes in the book.
+type placement struct {
+	group string
+	order int
+	title string
+}
+
+// outlinePlan is the changes importing an outline makes to the book.
+type outlinePlan struct {
+	// Changes describes each change.
+	Changes []string
+	// groups is the new chapter_groups list, in order, or nil if the groups
+	// don't change; new groups have "new:" ids until they are written.
+	groups   []*Group
+	chapters map[string]placement
+}
+
+// planOutline checks o against qb and returns the changes it makes.
+func planOutline(qb *QuestBook, o *Outline) (*outlinePlan, error) {
+	plan := &outlinePlan{chapters: make(map[string]placement)}
+	var groups []*Group
+	seenGroup := make(map[string]bool)
+	// the entries of the top level and the chapters of each group, to keep
+	// the order_index of chapters whose order doesn't change
+	var top []string
+	lists := make(map[string][]string)
+	place := func(e *OutlineEntry, group string, order int) error {
+		if e.Group != "" || len(e.Chapters) > 0 {
+			return fmt.Errorf("chapter %s can't have a group or chapters of its own", e.Chapter)
+		}
+		if _, ok := qb.chapterMap[e.Chapter]; !ok {
+			return fmt.Errorf("unknown chapter %s", e.Chapter)
+		}
+		if _, ok := plan.chapters[e.Chapter]; ok {
+			return fmt.Errorf("chapter %s is listed twice", e.Chapter)
+		}
+		plan.chapters[e.Chapter] = placement{group: group, order: order, title: e.Title}
+		lists[group] = append(lists[group], e.Chapter)
+		return nil
+	}
+	for i, e := range o.Book {
+		if e.Chapter != "" {
+			// ungrouped chapters share their order_index space with the
+			// groups, see buildTopItems
+			if err := place(e, "", i); err != nil {
+				return nil, err
+			}
+			top = append(top, "chapter "+e.Chapter)
+			continue
+		}
+		g := &Group{ID: strings.ToUpper(e.Group), Title: e.Title}
+		if g.ID == "" {
+			if strings.TrimSpace(g.Title) == "" {
+				return nil, fmt.Errorf("entry %d needs a chapter, a group id or the title of a new group", i+1)
+			}
+			// new groups are given ids when the outline is applied, so
+			// their chapters are placed by index for now
+			g.ID = fmt.Sprintf("new:%d", i)
+		} else if old, ok := qb.groupMap[g.ID]; !ok {
+			return nil, fmt.Errorf("unknown group %s", e.Group)
+		} else if g.Title == "" {
+			g.Title = old.Title
+		}
+		if seenGroup[g.ID] {
+			return nil, fmt.Errorf("group %s is listed twice", g.ID)
+		}
+		seenGroup[g.ID] = true
+		top = append(top, "group "+g.ID)
+		for j, ce := range e.Chapters {
+			if ce.Chapter == "" {
+				return nil, fmt.Errorf("entry %d of group %s needs a chapter", j+1, g.Title)
+			}
+			if err := place(ce, g.ID, j); err != nil {
+				return nil, err
+			}
+		}
+		groups = append(groups, g)
+	}
+	var oldTop []string
+	oldLists := make(map[string][]string)
+	for _, it := range qb.TopItems() {
+		if it.Kind == "chapter" {
+			oldTop = append(oldTop, "chapter "+it.Chapter.Name)
+			oldLists[""] = append(oldLists[""], it.Chapter.Name)
+			continue
+		}
+		oldTop = append(oldTop, "group "+it.Group.ID)
+		for _, c := range it.Group.Chapters {
+			oldLists[it.Group.ID] = append(oldLists[it.Group.ID], c.Name)
+		}
+	}
+	for _, names := range oldLists {
+		for _, name := range names {
+			if _, ok := plan.chapters[name]; !ok {
+				return nil, fmt.Errorf("chapter %s is missing from the outline", name)
+			}
+		}
+	}
+	// chapters in the same order as before keep their indexes, which can
+	// have gaps; otherwise the chapters are renumbered
+	for group, names := range lists {
+		if !slices.Equal(names, oldLists[group]) || group == "" && !slices.Equal(top, oldTop) {
+			continue
+		}
+		for _, name := range names {
+			p := plan.chapters[name]
+			p.order = qb.chapterMap[name].OrderIndex
+			plan.chapters[name] = p
+		}
+	}
+
+	groupTitle := func(id string) string {
+		if id == "" {
+			return "the top level"
+		}
+		for _, g := range groups {
+			if g.ID == id {
+				return "group " + stripCodes(g.Title)
+			}
+		}
+		return "group " + stripCodes(qb.groupMap[id].Title)
+	}
+	var ids []string
+	for _, g := range groups {
+		ids = append(ids, g.ID)
+		if old, ok := qb.groupMap[g.ID]; !ok {
+			plan.Changes = append(plan.Changes, fmt.Sprintf("Add group %s", stripCodes(g.Title)))
+		} else if old.Title != g.Title {
+			plan.Changes = append(plan.Changes, fmt.Sprintf("Retitle group %s to %s", stripCodes(old.Title), stripCodes(g.Title)))
+		}
+	}
+	var oldIDs []string
+	for _, g := range qb.Groups {
+		oldIDs = append(oldIDs, g.ID)
+		if !seenGroup[g.ID] {
+			plan.Changes = append(plan.Changes, fmt.Sprintf("Remove group %s", stripCodes(g.Title)))
+		}
+	}
+	changed := len(plan.Changes) > 0
+	kept := slices.DeleteFunc(slices.Clone(ids), func(id string) bool { return qb.groupMap[id] == nil })
+	if !slices.Equal(kept, slices.DeleteFunc(oldIDs, func(id string) bool { return !seenGroup[id] })) {
+		plan.Changes = append(plan.Changes, "Reorder the groups")
+		changed = true
+	}
+	if changed {
+		plan.groups = groups
+	}
+
+	reordered := make(map[string]bool)
+	var retitled []string
+	for _, c := range qb.Chapters {
+		p, ok := plan.chapters[c.Name]
+		if !ok {
+			continue
+		}
+		if p.group != c.GroupID {
+			from := "the top level"
+			if c.GroupID != "" {
+				from = "group " + c.GroupID
+				if g, ok := qb.groupMap[c.GroupID]; ok {
+					from = "group " + stripCodes(g.Title)
+				}
+			}
+			plan.Changes = append(plan.Changes, fmt.Sprintf("Move chapter %s from %s to %s", c.Name, from, groupTitle(p.group)))
+		} else if p.order != c.OrderIndex {
+			reordered[p.group] = true
+		}
+		if p.title != "" && p.title != c.Title {
+			retitled = append(retitled, fmt.Sprintf("Retitle chapter %s to %s", c.Name, stripCodes(p.title)))
+		}
+	}
+	for _, id := range append([]string{""}, ids...) {
+		if reordered[id] {
+			plan.Changes = append(plan.Changes, "Reorder the chapters of "+groupTitle(id))
+		}
+	}
+	slices.Sort(retitled)
+	plan.Changes = append(plan.Changes, retitled...)
+	return plan, nil
+}
+
+// ApplyOutline writes the changes of plan to the book: the chapters whose
+// group, order or title changes, and chapter_groups.snbt if the groups do,
+// together in one write.
+func (qb *QuestBook) ApplyOutline(plan *outlinePlan) error {
+	t := newBookWrite(qb.Lang)
+	newIDs := make(map[string]string)
+	if plan.groups != nil {
+		path := filepath.Join(qb.root, "quests", "chapter_groups.snbt")
+		file := map[string]any{}
+		if b, err := os.ReadFile(path); err == nil {
+			v, err := snbt.Decode(bytes.NewReader(b))
+			if err != nil {
+				return fmt.Errorf("chapter_groups: %w", err)
+			}
+			if m, ok := v.(map[string]any); ok {
+				file = m
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		// keep the fields of existing groups we don't know about
+		existing := make(map[string]map[string]any)
+		for _, g := range M(file).GetAnys("chapter_groups") {
+			if m, ok := g.(map[string]any); ok {
+				existing[M(m).GetString("id")] = m
+			}
+		}
+		ids := qb.idSource()
+		var list []any
+		for _, g := range plan.groups {
+			m, ok := existing[g.ID]
+			if !ok {
+				id := ids.Next()
+				newIDs[g.ID] = id
+				m = map[string]any{"id": id}
+			}
+			m["title"] = g.Title
+			list = append(list, m)
+		}
+		file["chapter_groups"] = list
+		if err := t.stageSNBT(path, file); err != nil {
+			return err
+		}
+	}
+
+	for name, p := range plan.chapters {
+		c := qb.chapterMap[name]
+		group := p.group
+		if id, ok := newIDs[group]; ok {
+			group = id
+		}
+		if group == c.GroupID && p.order == c.OrderIndex && (p.title == "" || p.title == c.Title) {
+			continue
+		}
+		path := qb.chapterPath(name)
+		ch, err := NewChapterFromPath(path)
+		if err != nil {
+			return fmt.Errorf("open chapter %s: %w", name, err)
+		}
+		ch.resolveLang(qb.Lang)
+		ch.raw["group"] = group
+		M(ch.raw).SetInt("order_index", p.order)
+		if p.title != "" {
+			ch.Title = p.title
+			if ch.titleKey == "" {
+				// a keyed title is written to the lang file by stageChapter
+				ch.raw["title"] = p.title
+			}
+		}
+		if err := t.stageChapter(ch, path); err != nil {
+			return err
+		}
+	}
+	return t.commit()
+}
+
+// outline handles GET "/outline", the outline import form, and POST
+// "/outline", which shows the changes an edited outline makes without
+// applying them.
+func (a *App) outline(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Outline")
+	data["Msg"] = r.URL.Query().Get("msg")
+	if r.Method == http.MethodPost {
+		s, err := readUploadedText(r)
+		var plan *outlinePlan
+		if err == nil {
+			var o *Outline
+			if o, err = readOutline(s); err == nil {
+				plan, err = planOutline(a.QB(), o)
+			}
+		}
+		if err != nil {
+			data["OutlineErr"] = err.Error()
+		} else {
+			data["Plan"] = plan
+			data["Outline"] = s
+		}
+	}
+	a.render(w, "outline.gohtml", data)
+}
+
+// outlineExport handles GET "/outline.yaml", downloading the book's outline.
+func (a *App) outlineExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Disposition", `attachment; filename="outline.yaml"`)
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	if err := writeOutline(w, bookOutline(a.QB())); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// outlineApply handles POST "/outline/apply", which applies the outline in
+// "text". The outline is checked again against the book as it is now.
+func (a *App) outlineApply(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	o, err := readOutline(r.FormValue("text"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	plan, err := planOutline(qb, o)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(plan.Changes) == 0 {
+		http.Redirect(w, r, "/outline?msg="+url.QueryEscape("The outline matches the book; nothing changed."), http.StatusSeeOther)
+		return
+	}
+	if err := qb.ApplyOutline(plan); err != nil {
+		http.Error(w, "apply outline: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.audit(r, "import outline", "", nil, strings.Join(plan.Changes, "; "))
+	a.reload()
+	msg := fmt.Sprintf("Applied %d changes from the outline.", len(plan.Changes))
+	http.Redirect(w, r, "/outline?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestOutline(t *testing.T) {
+	a := testApp(t)
+	for _, name := range []string{"alpha", "beta"} {
+		if _, err := a.QB().CreateChapter(name, strings.ToUpper(name[:1])+name[1:], ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.reload()
+
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/outline.yaml", nil))
+	exported := rec.Body.String()
+	q := a.QB().chapterMap["test"].Quests[0]
+	if !strings.Contains(exported, "chapter: alpha") || !strings.Contains(exported, q.ID) {
+		t.Fatalf("outline:\n%s", exported)
+	}
+	o, err := readOutline(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan, err := planOutline(a.QB(), o); err != nil || len(plan.Changes) != 0 {
+		t.Fatalf("unedited outline: %v %v", plan, err)
+	}
+
+	post := func(path, text string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(url.Values{"text": {text}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	edited := `book:
+  - chapter: alpha
+    title: "&6First Steps"
+  - title: Early Game
+    chapters:
+      - chapter: beta
+      - chapter: test
+        quests: [ignored]
+`
+	rec = post("/outline", edited)
+	for _, want := range []string{"Add group Early Game", "Move chapter beta from the top level to group Early Game", "Retitle chapter alpha to First Steps"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("preview is missing %q", want)
+		}
+	}
+	if rec := post("/outline/apply", edited); rec.Code != http.StatusSeeOther {
+		t.Fatalf("apply: %d %s", rec.Code, rec.Body)
+	}
+	qb := a.QB()
+	if len(qb.Groups) != 1 || qb.Groups[0].Title != "Early Game" || len(qb.Groups[0].Chapters) != 2 {
+		t.Fatalf("groups = %+v", qb.Groups)
+	}
+	if g := qb.Groups[0]; g.Chapters[0].Name != "beta" || g.Chapters[1].Name != "test" {
+		t.Errorf("group chapters = %s, %s", g.Chapters[0].Name, g.Chapters[1].Name)
+	}
+	if top := qb.TopItems(); len(top) != 2 || top[0].Chapter == nil || top[0].Chapter.Title != "&6First Steps" {
+		t.Errorf("top items = %+v", top)
+	}
+
+	// moving the group first and dropping it again
+	id := qb.Groups[0].ID
+	o, _ = readOutline("book:\n  - group: " + id + "\n    chapters: [{chapter: test}, {chapter: beta}]\n  - chapter: alpha\n")
+	plan, err := planOutline(qb, o)
+	if err != nil || strings.Join(plan.Changes, "; ") != "Reorder the chapters of the top level; Reorder the chapters of group Early Game" {
+		t.Errorf("changes = %v, %v", plan, err)
+	}
+
+	for _, bad := range []string{
+		"book:\n  - chapter: alpha\n  - chapter: beta\n",
+		"book:\n  - chapter: alpha\n  - chapter: alpha\n",
+		"book:\n  - chapter: nope\n",
+		"book:\n  - group: 0000000000000BAD\n",
+		"book:\n  - chapters: [{chapter: test}]\n",
+		"book:\n  - chapter: alpha\n    color: red\n",
+		"",
+	} {
+		if rec := post("/outline/apply", bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("apply %q: %d", bad, rec.Code)
+		}
+	}
+}
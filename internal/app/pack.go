@@ -0,0 +1,121 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// packDir returns qbedit's directory for the ftbquests dir root, where it
+// keeps a few per-pack files of its own. It is next to quests/ so that the
+// game never reads them and they travel with the pack.
+func packDir(root string) string {
+	return filepath.Join(root, ".qbedit")
+}
+
+// setAside renames the file at path, which couldn't be read, out of the way
+// so that saving the defaults used in its place doesn't lose it. It returns
+// the file's new path, or "" if it wasn't moved.
+func setAside(path string) string {
+	to := path + ".broken"
+	if err := os.Rename(path, to); err != nil {
+		return ""
+	}
+	return to
+}
+
+// PackConfig holds settings that apply to everyone editing a pack.
+type PackConfig struct {
+	// Reset is the pack's policy for closing styled text; see reset.go
+	Reset ResetPolicy `json:"reset"`
+	// Palettes are the pack's named color palettes, and Applied the one
+	// the book was last restyled with; see palette.go
+	Palettes []Palette `json:"palettes,omitempty"`
+	Applied  *Palette  `json:"applied_palette,omitempty"`
+	// Recipes are saved bulk operations; see recipes.go
+	Recipes []Recipe `json:"recipes,omitempty"`
+	// Terms are the pack's preferred terms; see terms.go
+	Terms []TermRule `json:"terms,omitempty"`
+	// Blocklist is the terms quest text must not use; see blocklist.go
+	Blocklist BlockList `json:"blocklist"`
+	// SpellIgnored are suspected typos ignored in one quest; see spelling.go
+	SpellIgnored []SpellIgnore `json:"spelling_ignored,omitempty"`
+	// Protected are patterns of chapters and fields qbedit won't change;
+	// see protect.go
+	Protected []string `json:"protected,omitempty"`
+}
+
+// PackSettings is a pack's file backed PackConfig.
+type PackSettings struct {
+	path string
+	mu   sync.Mutex
+	cfg  PackConfig
+}
+
+// OpenPackSettings loads the settings at path; a missing file is the zero
+// PackConfig.
+func OpenPackSettings(path string) (*PackSettings, error) {
+	p := &PackSettings{path: path}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &p.cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Get returns the current settings.
+func (p *PackSettings) Get() PackConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cfg
+}
+
+// Set replaces the settings and saves them.
+func (p *PackSettings) Set(cfg PackConfig) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.save(cfg)
+}
+
+// Update changes the settings with fn and saves them, holding the settings
+// for the whole change so that concurrent updates can't undo each other. If
+// fn returns an error the settings are left alone and the error returned.
+//
+// fn is given a copy of the settings; slices in it are shared with the
+// current settings and must be cloned before they are changed in place.
+func (p *PackSettings) Update(fn func(cfg *PackConfig) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cfg := p.cfg
+	if err := fn(&cfg); err != nil {
+		return err
+	}
+	return p.save(cfg)
+}
+
+// save makes cfg the settings and writes it to the settings file; p.mu must
+// be held.
+func (p *PackSettings) save(cfg PackConfig) error {
+	p.cfg = cfg
+	if p.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return err
+	}
+	return writeFile(p.path, b, 0644)
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// TestPackSettingsUpdate checks that concurrent updates each see the others'
+// changes, and that a failed update changes nothing.
+func TestPackSettingsUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pack.json")
+	p, err := OpenPackSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := p.Update(func(cfg *PackConfig) error {
+				cfg.Protected = append(slices.Clone(cfg.Protected), fmt.Sprintf("chapter%d", i))
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := len(p.Get().Protected); got != n {
+		t.Fatalf("%d patterns after %d updates", got, n)
+	}
+
+	failed := errors.New("failed")
+	err = p.Update(func(cfg *PackConfig) error {
+		cfg.Protected = nil
+		return failed
+	})
+	if err != failed || len(p.Get().Protected) != n {
+		t.Errorf("failed update = %v, left %d patterns", err, len(p.Get().Protected))
+	}
+
+	reopened, err := OpenPackSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reopened.Get().Protected); got != n {
+		t.Errorf("%d patterns saved, want %d", got, n)
+	}
+}
+
+// TestNewBrokenSettings checks that a book whose settings files can't be
+// read still opens, with the defaults, and that the files are kept.
+func TestNewBrokenSettings(t *testing.T) {
+	root := testApp(t).Root()
+	broken := []string{filepath.Join(packDir(root), "pack.json"), snippetsPath(root)}
+	for _, path := range broken {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{ not json"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := New(root, "1.20.1", 0)
+	if err != nil {
+		t.Fatalf("New = %v", err)
+	}
+	if len(a.Snippets.List()) != len(defaultSnippets) || len(a.Pack.Get().Palettes) != 0 {
+		t.Errorf("settings weren't the defaults: %d snippets, %+v", len(a.Snippets.List()), a.Pack.Get())
+	}
+	for _, path := range broken {
+		if b, err := os.ReadFile(path + ".broken"); err != nil || string(b) != "{ not json" {
+			t.Errorf("%s wasn't kept: %q %v", path, b, err)
+		}
+	}
+	if err := a.Pack.Update(func(cfg *PackConfig) error { return nil }); err != nil {
+		t.Errorf("saving settings: %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// PaletteRole is the color code (0-9, a-f) a palette gives a role.
+type PaletteRole struct {
+	Role string `json:"role"`
+	Code string `json:"code"`
+}
+
+// Palette is a named set of role colors, eg. gold for item names and red for
+// warnings. A pack can be restyled by editing its palette and applying it
+// again (see paletteApply); the first palette applied only records the colors
+// the book already uses.
+type Palette struct {
+	Name  string        `json:"name"`
+	Roles []PaletteRole `json:"roles"`
+}
+
+// defaultRoles are offered for new palettes.
+var defaultRoles = []string{"item", "machine", "warning"}
+
+// isColorCode reports whether c is a color (rather than format) code.
+func isColorCode(c rune) bool {
+	c = unicode.ToLower(c)
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')
+}
+
+// code returns the code p gives role, or 0.
+func (p Palette) code(role string) rune {
+	for _, r := range p.Roles {
+		if r.Role == role && r.Code != "" {
+			return rune(r.Code[0])
+		}
+	}
+	return 0
+}
+
+// validate checks that every role has a single color code and appears once.
+func (p Palette) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("palette needs a name")
+	}
+	seen := make(map[string]bool)
+	for _, r := range p.Roles {
+		if r.Role == "" {
+			return fmt.Errorf("palette %s: a role has no name", p.Name)
+		}
+		if seen[r.Role] {
+			return fmt.Errorf("palette %s: role %s is listed twice", p.Name, r.Role)
+		}
+		seen[r.Role] = true
+		if len(r.Code) != 1 || !isColorCode(rune(r.Code[0])) {
+			return fmt.Errorf("palette %s: %q is not a color code for %s", p.Name, r.Code, r.Role)
+		}
+	}
+	return nil
+}
+
+// paletteMapping returns the codes to replace when restyling text colored
+// with from to use to. Roles missing from either palette are left alone. It
+// is an error for from to give roles that to colors differently the same
+// code, as text in that code can't be told apart.
+func paletteMapping(from, to Palette) (map[rune]rune, error) {
+	mapping := make(map[rune]rune)
+	role := make(map[rune]string)
+	for _, r := range from.Roles {
+		o, n := from.code(r.Role), to.code(r.Role)
+		if o == 0 || n == 0 {
+			continue
+		}
+		if m, ok := mapping[o]; ok && m != n {
+			return nil, fmt.Errorf("%s and %s are both &%c in %s but differ in %s", role[o], r.Role, o, from.Name, to.Name)
+		}
+		mapping[o], role[o] = n, r.Role
+	}
+	for o, n := range mapping {
+		if o == n {
+			delete(mapping, o)
+		}
+	}
+	return mapping, nil
+}
+
+// recodeColors replaces the color codes in s that are in mapping, keeping
+// each code's sign. Every code is replaced at once, so palettes that swap
+// two colors work.
+func recodeColors(s string, mapping map[rune]rune) string {
+	if len(mapping) == 0 || !strings.ContainsAny(s, "&§") {
+		return s
+	}
+	rs := []rune(s)
+	for i := 0; i+1 < len(rs); i++ {
+		if (rs[i] == signAmp || rs[i] == signSection) && isFormatCode(rs[i+1]) {
+			if c, ok := mapping[unicode.ToLower(rs[i+1])]; ok {
+				rs[i+1] = c
+			}
+			i++
+		}
+	}
+	return string(rs)
+}
+
+// paletteFromForm reads a palette from the "name", "role" and "code" form
+// fields. Rows without a role or a code are dropped, so clearing either
+// removes a role; codes may be written as &6 or 6.
+func paletteFromForm(form url.Values) Palette {
+	p := Palette{Name: strings.TrimSpace(form.Get("name"))}
+	roles, codes := form["role"], form["code"]
+	for i, role := range roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if i >= len(codes) {
+			break
+		}
+		code := strings.ToLower(strings.TrimLeft(strings.TrimSpace(codes[i]), "&§"))
+		if role == "" || code == "" {
+			continue
+		}
+		p.Roles = append(p.Roles, PaletteRole{Role: role, Code: code})
+	}
+	return p
+}
+
+// palettes handles GET "/colors/palettes".
+func (a *App) palettes(w http.ResponseWriter, r *http.Request) {
+	cfg := a.Pack.Get()
+	data := a.baseData(r, "Palettes")
+	data["Palettes"] = cfg.Palettes
+	data["Applied"] = cfg.Applied
+	data["DefaultRoles"] = defaultRoles
+	data["Fields"] = paletteFields
+	data["PaletteMsg"] = r.URL.Query().Get("msg")
+	a.render(w, "palettes.gohtml", data)
+}
+
+// paletteRedirect returns to the palettes page with msg.
+func paletteRedirect(w http.ResponseWriter, r *http.Request, msg string) {
+	http.Redirect(w, r, "/colors/palettes?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// paletteSave handles POST "/colors/palettes", adding or replacing a palette.
+func (a *App) paletteSave(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	p := paletteFromForm(r.Form)
+	if err := p.validate(); err != nil {
+		paletteRedirect(w, r, err.Error())
+		return
+	}
+	err := a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Palettes = slices.Clone(cfg.Palettes)
+		if i := slices.IndexFunc(cfg.Palettes, func(o Palette) bool { return o.Name == p.Name }); i >= 0 {
+			cfg.Palettes[i] = p
+		} else {
+			cfg.Palettes = append(cfg.Palettes, p)
+		}
+		sort.Slice(cfg.Palettes, func(i, j int) bool { return cfg.Palettes[i].Name < cfg.Palettes[j].Name })
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	paletteRedirect(w, r, "Saved palette "+p.Name+".")
+}
+
+// paletteDelete handles POST "/colors/palettes/delete".
+func (a *App) paletteDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	err := a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Palettes = slices.DeleteFunc(slices.Clone(cfg.Palettes), func(p Palette) bool { return p.Name == name })
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	paletteRedirect(w, r, "Deleted palette "+name+".")
+}
+
+// paletteFields are the parts of the book a palette can be applied to: the
+// quest fields, and chapter titles as "chapter".
+var paletteFields = append(slices.Clone(searchFields), "chapter")
+
+// paletteFieldsFrom returns the paletteFields chosen in form's "field"s.
+func paletteFieldsFrom(form url.Values) []string {
+	var fields []string
+	for _, f := range paletteFields {
+		if slices.Contains(form["field"], f) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// recodeChapter returns what recoding the fields of ch with mapping would
+// change, without changing ch. A change to the chapter's title comes first,
+// as a result without a quest.
+func recodeChapter(ch *Chapter, mapping map[rune]rune, fields []string) []TransformResult {
+	var results []TransformResult
+	if slices.Contains(fields, "chapter") {
+		if t := recodeColors(ch.Title, mapping); t != ch.Title {
+			results = append(results, TransformResult{Chapter: ch, Changes: []TransformChange{{Field: "chapter title", Before: ch.Title, After: t}}})
+		}
+	}
+	for _, q := range ch.Quests {
+		var changes []TransformChange
+		for _, f := range fields {
+			if f == "chapter" {
+				continue
+			}
+			before := questField(q, f)
+			if after := recodeColors(before, mapping); after != before {
+				changes = append(changes, TransformChange{Field: f, Before: before, After: after})
+			}
+		}
+		if len(changes) > 0 {
+			results = append(results, TransformResult{Chapter: ch, Quest: q, Changes: changes})
+		}
+	}
+	return results
+}
+
+// paletteApply handles GET "/colors/palettes/apply", a preview of restyling
+// the "field"s of the book from the colors of the last applied palette to
+// those of the palette "name", and POST "/colors/palettes/apply", which
+// applies it. Every code the last palette gives a role is replaced, whatever
+// the text in it is, so the preview shows each change before it's made.
+func (a *App) paletteApply(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	name := r.Form.Get("name")
+	cfg := a.Pack.Get()
+	i := slices.IndexFunc(cfg.Palettes, func(p Palette) bool { return p.Name == name })
+	if i < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	to := cfg.Palettes[i]
+	var mapping map[rune]rune
+	if cfg.Applied != nil {
+		var err error
+		if mapping, err = paletteMapping(*cfg.Applied, to); err != nil {
+			paletteRedirect(w, r, "Can't apply "+name+": "+err.Error())
+			return
+		}
+	}
+	fields := paletteFieldsFrom(r.Form)
+	if len(fields) == 0 {
+		paletteRedirect(w, r, "Choose the parts of the book to apply "+name+" to.")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		var results []TransformResult
+		for _, c := range qb.Chapters {
+			results = append(results, recodeChapter(c, mapping, fields)...)
+		}
+		data := a.baseData(r, "Apply palette")
+		data["Palette"], data["From"], data["Fields"] = to, cfg.Applied, fields
+		data["Results"] = results
+		a.render(w, "palette_apply.gohtml", data)
+		return
+	}
+
+	var names []string
+	if len(mapping) > 0 {
+		for _, c := range qb.Chapters {
+			names = append(names, c.Name)
+		}
+	}
+	edited, err := editChapters(qb, names, func(ch *Chapter) ([]string, error) {
+		var ids []string
+		for _, res := range recodeChapter(ch, mapping, fields) {
+			if res.Quest == nil {
+				ch.Title = res.Changes[0].After
+				if ch.titleKey == "" {
+					ch.raw["title"] = ch.Title
+				}
+				ids = []string{}
+				continue
+			}
+			for _, c := range res.Changes {
+				setQuestField(res.Quest, c.Field, c.After)
+			}
+			ids = append(ids, res.Quest.ID)
+		}
+		return ids, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	changed := a.auditEdits(r, "apply palette", name, edited)
+
+	err = a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Applied = &to
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(edited) > 0 {
+		a.reload()
+	}
+	paletteRedirect(w, r, fmt.Sprintf("Applied palette %s; recolored %d quests.", name, changed))
+}
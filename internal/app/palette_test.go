@@ -0,0 +1,117 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPaletteMapping(t *testing.T) {
+	old := Palette{Name: "old", Roles: []PaletteRole{{"item", "6"}, {"machine", "b"}, {"warning", "c"}}}
+	swap := Palette{Name: "new", Roles: []PaletteRole{{"item", "b"}, {"machine", "6"}, {"warning", "c"}, {"fluid", "3"}}}
+	mapping, err := paletteMapping(old, swap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mapping) != 2 || mapping['6'] != 'b' || mapping['b'] != '6' {
+		t.Fatalf("mapping = %q", mapping)
+	}
+	if got := recodeColors("&6Gold&r in a §Bfurnace, &cnot &lhot", mapping); got != "&bGold&r in a §6furnace, &cnot &lhot" {
+		t.Errorf("recodeColors = %q", got)
+	}
+
+	shared := Palette{Name: "shared", Roles: []PaletteRole{{"item", "6"}, {"machine", "6"}}}
+	if _, err := paletteMapping(shared, swap); err == nil {
+		t.Error("expected an error mapping one code to two colors")
+	}
+}
+
+func TestPaletteFromForm(t *testing.T) {
+	form := url.Values{
+		"name": {"Default"},
+		"role": {"Item", "machine", "", "warning"},
+		"code": {"&6", "§B", "3", ""},
+	}
+	p := paletteFromForm(form)
+	if err := p.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Roles) != 2 || p.code("item") != '6' || p.code("machine") != 'b' {
+		t.Errorf("got %+v", p)
+	}
+	p.Roles = append(p.Roles, PaletteRole{"warning", "l"})
+	if p.validate() == nil {
+		t.Error("&l is a format code, not a color")
+	}
+}
+
+func TestPaletteApply(t *testing.T) {
+	a := testApp(t)
+	chapter := `{
+	id: "00000000000000C1"
+	filename: "colors"
+	order_index: 1
+	title: "&6Colors"
+	quests: [
+		{ id: "00000000000000D1", title: "&6Iron", description: ["Smelt &6Iron&r."], x: 0.0d, y: 0.0d }
+	]
+}
+`
+	if err := os.WriteFile(a.QB().chapterPath("colors"), []byte(chapter), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	gold := Palette{Name: "gold", Roles: []PaletteRole{{"item", "6"}}}
+	yellow := Palette{Name: "yellow", Roles: []PaletteRole{{"item", "e"}}}
+	err := a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Palettes, cfg.Applied = []Palette{gold, yellow}, &gold
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(method string, form url.Values) *httptest.ResponseRecorder {
+		var req *http.Request
+		if method == http.MethodGet {
+			req = httptest.NewRequest(method, "/colors/palettes/apply?"+form.Encode(), nil)
+		} else {
+			req = httptest.NewRequest(method, "/colors/palettes/apply", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// the preview shows every change but writes nothing
+	before, _ := os.ReadFile(a.QB().chapterPath("colors"))
+	rec := serve(http.MethodGet, url.Values{"name": {"yellow"}, "field": paletteFields})
+	for _, want := range []string{"&amp;eColors", "&amp;eIron", "Smelt &amp;eIron&amp;r."} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("preview is missing %q", want)
+		}
+	}
+	if after, _ := os.ReadFile(a.QB().chapterPath("colors")); string(after) != string(before) {
+		t.Error("preview wrote the chapter")
+	}
+
+	// only the chosen fields are recolored
+	if rec := serve(http.MethodPost, url.Values{"name": {"yellow"}, "field": {"description"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("apply: %d %s", rec.Code, rec.Body)
+	}
+	ch := a.QB().chapterMap["colors"]
+	q := a.QB().questMap["00000000000000D1"]
+	if ch.Title != "&6Colors" || q.Title != "&6Iron" || q.Description != "Smelt &eIron&r." {
+		t.Errorf("after apply: %q %q %q", ch.Title, q.Title, q.Description)
+	}
+	if applied := a.Pack.Get().Applied; applied == nil || applied.Name != "yellow" {
+		t.Errorf("applied palette = %v", applied)
+	}
+
+	if rec := serve(http.MethodGet, url.Values{"name": {"gold"}}); rec.Code != http.StatusSeeOther {
+		t.Errorf("preview without fields: %d", rec.Code)
+	}
+}
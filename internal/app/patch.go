@@ -0,0 +1,249 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// plainHeader starts the line naming each entry of the plain text export:
+// "=== <chapter> title" for a chapter title, and
+// "=== <chapter> <quest> <field>" for a quest's text. Lines of text that
+// would look like a header, or that start with a backslash, are escaped with
+// a backslash.
+const plainHeader = "=== "
+
+// writeTextPlain writes entries in the plain text format, each followed by an
+// empty line.
+func writeTextPlain(w io.Writer, entries []TextEntry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		if e.Quest == "" {
+			fmt.Fprintf(bw, "%s%s %s\n", plainHeader, e.Chapter, e.Field)
+		} else {
+			fmt.Fprintf(bw, "%s%s %s %s\n", plainHeader, e.Chapter, e.Quest, e.Field)
+		}
+		for _, line := range strings.Split(e.Text, "\n") {
+			if strings.HasPrefix(line, plainHeader) || strings.HasPrefix(line, `\`) {
+				line = `\` + line
+			}
+			bw.WriteString(line + "\n")
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// readTextPlain reads entries written by writeTextPlain.
+func readTextPlain(s string) ([]TextEntry, error) {
+	var (
+		entries []TextEntry
+		lines   []string
+	)
+	flush := func() {
+		if len(entries) == 0 {
+			return
+		}
+		// the empty line after each entry isn't part of its text
+		if n := len(lines); n > 0 && lines[n-1] == "" {
+			lines = lines[:n-1]
+		}
+		entries[len(entries)-1].Text = strings.Join(lines, "\n")
+		lines = nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	for i, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		if strings.HasPrefix(line, plainHeader) {
+			flush()
+			f := strings.Fields(strings.TrimPrefix(line, plainHeader))
+			switch len(f) {
+			case 2:
+				entries = append(entries, TextEntry{Chapter: f[0], Field: f[1]})
+			case 3:
+				entries = append(entries, TextEntry{Chapter: f[0], Quest: f[1], Field: f[2]})
+			default:
+				return nil, fmt.Errorf("line %d: invalid entry header %q", i+1, line)
+			}
+			continue
+		}
+		if len(entries) == 0 {
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("line %d: text before the first %q header", i+1, strings.TrimSpace(plainHeader))
+			}
+			continue
+		}
+		lines = append(lines, strings.TrimPrefix(line, `\`))
+	}
+	flush()
+	return entries, nil
+}
+
+// isPatch reports whether s looks like a unified diff.
+func isPatch(s string) bool {
+	for _, line := range strings.SplitN(s, "\n", 50) {
+		if strings.HasPrefix(line, "@@ -") {
+			return true
+		}
+	}
+	return false
+}
+
+// hunk is one change of a unified diff.
+type hunk struct {
+	header string
+	// start is the 1-based line of the old text the hunk starts at.
+	start    int
+	old, new []string
+}
+
+// parsePatch returns the hunks of the unified diff s. File headers and any
+// text outside hunks, such as a commit message, are ignored.
+func parsePatch(s string) ([]hunk, error) {
+	var hunks []hunk
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "@@ -") {
+			continue
+		}
+		h := hunk{header: lines[i]}
+		oldLen, newLen, err := parseHunkHeader(lines[i], &h.start)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		for len(h.old) < oldLen || len(h.new) < newLen {
+			i++
+			if i >= len(lines) {
+				return nil, fmt.Errorf("hunk %q: patch ends early", h.header)
+			}
+			line := lines[i]
+			if line == "" {
+				// editors and mail clients strip the space of empty context
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				h.old = append(h.old, line[1:])
+				h.new = append(h.new, line[1:])
+			case '-':
+				h.old = append(h.old, line[1:])
+			case '+':
+				h.new = append(h.new, line[1:])
+			case '\\':
+				// "\ No newline at end of file"
+			default:
+				return nil, fmt.Errorf("line %d: unexpected %q in hunk %q", i+1, line, h.header)
+			}
+		}
+		if len(h.old) != oldLen || len(h.new) != newLen {
+			return nil, fmt.Errorf("hunk %q: line counts don't match its header", h.header)
+		}
+		hunks = append(hunks, h)
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("the patch has no changes")
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader parses "@@ -start,len +start,len @@", setting start to the
+// old start and returning both lengths. A missing length is 1.
+func parseHunkHeader(header string, start *int) (oldLen, newLen int, err error) {
+	f := strings.Fields(header)
+	if len(f) < 4 || f[3] != "@@" || !strings.HasPrefix(f[1], "-") || !strings.HasPrefix(f[2], "+") {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+	rangeOf := func(s string) (int, int, error) {
+		from, n, ok := strings.Cut(s, ",")
+		if !ok {
+			n = "1"
+		}
+		a, err1 := strconv.Atoi(from)
+		b, err2 := strconv.Atoi(n)
+		if err1 != nil || err2 != nil || a < 0 || b < 0 {
+			return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+		}
+		return a, b, nil
+	}
+	if *start, oldLen, err = rangeOf(f[1][1:]); err != nil {
+		return 0, 0, err
+	}
+	_, newLen, err = rangeOf(f[2][1:])
+	return oldLen, newLen, err
+}
+
+// applyHunks applies hunks to lines in order. Like patch, a hunk whose lines
+// have moved since the diff was made is applied where they are now; hunks
+// whose lines can't be found are left out and returned as rejected.
+func applyHunks(lines []string, hunks []hunk) (out []string, rejected []hunk) {
+	out = append([]string(nil), lines...)
+	delta, from := 0, 0
+	for _, h := range hunks {
+		want := h.start - 1 + delta
+		if len(h.old) == 0 {
+			// a pure addition's start is the line it goes after
+			want++
+		}
+		at := findLines(out, h.old, want, from)
+		if at < 0 {
+			rejected = append(rejected, h)
+			continue
+		}
+		out = append(out[:at], append(append([]string(nil), h.new...), out[at+len(h.old):]...)...)
+		delta += len(h.new) - len(h.old)
+		from = at + len(h.new)
+	}
+	return out, rejected
+}
+
+// findLines returns the index at or after from of lines' run of want nearest
+// to at, or -1.
+func findLines(lines, want []string, at, from int) int {
+	matches := func(i int) bool {
+		if i < from || i+len(want) > len(lines) {
+			return false
+		}
+		for j, w := range want {
+			if lines[i+j] != w {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; at-d >= from || at+d <= len(lines); d++ {
+		if matches(at - d) {
+			return at - d
+		}
+		if matches(at + d) {
+			return at + d
+		}
+	}
+	return -1
+}
+
+// patchText applies the unified diff s to the book's plain text export and
+// returns the entries of the result, and the headers of the hunks that
+// couldn't be applied. Reviewers can propose text corrections this way
+// instead of editing SNBT: they edit the export and send the diff, eg. from
+// "diff -u" or a pull request, which is applied to the text as it is now.
+func patchText(qb *QuestBook, s string) ([]TextEntry, []string, error) {
+	hunks, err := parsePatch(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	var b strings.Builder
+	if err := writeTextPlain(&b, exportText(qb)); err != nil {
+		return nil, nil, err
+	}
+	lines, rejected := applyHunks(strings.Split(b.String(), "\n"), hunks)
+	entries, err := readTextPlain(strings.Join(lines, "\n"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("patched text: %w", err)
+	}
+	var headers []string
+	for _, h := range rejected {
+		headers = append(headers, h.header)
+	}
+	return entries, headers, nil
+}
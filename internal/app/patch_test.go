@@ -0,0 +1,84 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTextPlainRoundTrip(t *testing.T) {
+	entries := []TextEntry{
+		{Chapter: "intro", Field: "title", Text: "Intro"},
+		{Chapter: "intro", Quest: "0A", Field: "description", Text: "first\n\n=== not a header\n\\escaped\nlast\n"},
+		{Chapter: "intro", Quest: "0B", Field: "title", Text: "&6Gold"},
+	}
+	var buf bytes.Buffer
+	if err := writeTextPlain(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readText(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("round trip:\n%q\n%+v", buf.String(), got)
+	}
+	if _, err := readTextPlain("stray\n=== intro title\nx\n"); err == nil {
+		t.Error("no error for text before the first header")
+	}
+}
+
+func TestPatchText(t *testing.T) {
+	a := testApp(t)
+	qb := a.QB()
+	q := qb.Chapters[0].Quests[0]
+	var buf bytes.Buffer
+	if err := writeTextPlain(&buf, exportText(qb)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	header := fmt.Sprintf("%s%s %s title", plainHeader, q.Chapter.Name, q.ID)
+	at := slices.Index(lines, header)
+	if at < 0 || lines[at+1] != q.Title {
+		t.Fatalf("%q not in export:\n%s", header, buf.String())
+	}
+	hunk := func(start int, old string) string {
+		return fmt.Sprintf("@@ -%d,2 +%d,2 @@\n %s\n-%s\n+Corrected\n", start, start, header, old)
+	}
+
+	// the line numbers are off, as if the book changed since the diff
+	patch := "--- a/quest-text.txt\n+++ b/quest-text.txt\n" + hunk(at+6, q.Title) + hunk(1, "not the title")
+	if !isPatch(patch) {
+		t.Fatal("isPatch = false")
+	}
+	entries, rejected, err := patchText(qb, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 1 {
+		t.Errorf("rejected = %q", rejected)
+	}
+	changes := diffText(qb, entries)
+	if len(changes) != 1 || changes[0].Quest != q.ID || changes[0].Old != q.Title || changes[0].Text != "Corrected" {
+		t.Fatalf("changes = %+v", changes)
+	}
+
+	// pasted into the import page it is previewed like a file
+	form := url.Values{"text": {patch}}
+	req := httptest.NewRequest("POST", "/import", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	if body := rec.Body.String(); !strings.Contains(body, "Corrected") || !strings.Contains(body, "left out") {
+		t.Errorf("preview: %d %s", rec.Code, body)
+	}
+
+	if _, _, err := patchText(qb, "@@ -1,3 +1,3 @@\n a\n"); err == nil {
+		t.Error("no error for a truncated hunk")
+	}
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// userCookie names the cookie holding the browser's user id.
+const userCookie = "qbedit_user"
+
+// UserPrefs are one user's preferences.
+type UserPrefs struct {
+	// Name is shown as the author of the user's edits on the activity page.
+	Name string `json:"name,omitempty"`
+	// Starred are the ids of quests the user has starred, most recent first.
+	Starred []string `json:"starred,omitempty"`
+}
+
+// Prefs is a file backed store of UserPrefs, keyed by user id. There are no
+// accounts in qbedit, so each browser is a user, identified by a random id in
+// a long lived cookie (see ensureUser). The file is kept outside of the quest
+// book so it is never mistaken for pack data.
+type Prefs struct {
+	path  string
+	mu    sync.Mutex
+	users map[string]*UserPrefs
+}
+
+// DefaultPrefsPath returns the preferences file in the user's config dir.
+func DefaultPrefsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "qbedit", "prefs.json")
+}
+
+// OpenPrefs loads the preferences at path; a missing file is empty.
+func OpenPrefs(path string) (*Prefs, error) {
+	p := &Prefs{path: path, users: make(map[string]*UserPrefs)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &p.users); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Get returns a copy of the preferences of user.
+func (p *Prefs) Get(user string) UserPrefs {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	u, ok := p.users[user]
+	if !ok {
+		return UserPrefs{}
+	}
+	return UserPrefs{Name: u.Name, Starred: slices.Clone(u.Starred)}
+}
+
+// SetName sets user's display name and saves the preferences.
+func (p *Prefs) SetName(user, name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user(user).Name = name
+	return p.save()
+}
+
+// Star stars or unstars questID for user and saves the preferences.
+func (p *Prefs) Star(user, questID string, star bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	u := p.user(user)
+	u.Starred = slices.DeleteFunc(u.Starred, func(id string) bool { return id == questID })
+	if star {
+		u.Starred = slices.Insert(u.Starred, 0, questID)
+	}
+	return p.save()
+}
+
+// user returns user's preferences, creating them if needed; p.mu must be held.
+func (p *Prefs) user(user string) *UserPrefs {
+	u, ok := p.users[user]
+	if !ok {
+		u = &UserPrefs{}
+		p.users[user] = u
+	}
+	return u
+}
+
+// save writes the preferences file; p.mu must be held.
+func (p *Prefs) save() error {
+	if p.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(p.users, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return err
+	}
+	return writeFile(p.path, b, 0644)
+}
+
+// userID returns the user id of the browser making r, or "".
+func userID(r *http.Request) string {
+	if c, err := r.Cookie(userCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// ensureUser is middleware that gives browsers without a user id a new one.
+// The cookie is only set when the request has none, and never on static
+// assets, which don't need a user and are often fetched without cookies.
+// It is also added to the request so handlers see it right away.
+func ensureUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID(r) == "" && !strings.HasPrefix(r.URL.Path, "/static/") {
+			var b [12]byte
+			_, _ = rand.Read(b[:])
+			c := &http.Cookie{
+				Name:     userCookie,
+				Value:    hex.EncodeToString(b[:]),
+				Path:     "/",
+				MaxAge:   10 * 365 * 24 * 60 * 60,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			}
+			http.SetCookie(w, c)
+			r.AddCookie(c)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// starredQuests returns the quests r's user has starred that exist in the
+// current quest book.
+func (a *App) starredQuests(r *http.Request) []*Quest {
+	if a.Prefs == nil {
+		return nil
+	}
+	var qs []*Quest
+	for _, id := range a.Prefs.Get(userID(r)).Starred {
+		if q, ok := a.QB().questMap[id]; ok {
+			qs = append(qs, q)
+		}
+	}
+	return qs
+}
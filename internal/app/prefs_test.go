@@ -0,0 +1,74 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPrefsStar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qbedit", "prefs.json")
+	p, err := OpenPrefs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"A", "B", "A"} {
+		if err := p.Star("u1", id, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Star("u1", "B", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Star("u2", "C", true); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = OpenPrefs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Get("u1").Starred; !slices.Equal(got, []string{"A"}) {
+		t.Errorf("u1 starred = %v", got)
+	}
+	if got := p.Get("u2").Starred; !slices.Equal(got, []string{"C"}) {
+		t.Errorf("u2 starred = %v", got)
+	}
+	if got := p.Get("nobody").Starred; got != nil {
+		t.Errorf("unknown user starred = %v", got)
+	}
+}
+
+func TestEnsureUser(t *testing.T) {
+	a := testApp(t)
+	h := a.Router()
+	tests := []struct {
+		name   string
+		path   string
+		cookie string
+		set    bool
+	}{
+		{"new browser", "/", "", true},
+		{"known browser", "/", "abc123", false},
+		{"static asset", "/static/app.css", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: userCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			set := slices.ContainsFunc(rec.Result().Cookies(), func(c *http.Cookie) bool { return c.Name == userCookie })
+			if set != tt.set {
+				t.Errorf("cookie set = %v, want %v", set, tt.set)
+			}
+		})
+	}
+}
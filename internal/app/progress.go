@@ -0,0 +1,251 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// TeamProgress is a team's progress file. FTB Quests keeps one per team in
+// <world>/ftbquests, with the ids of the quests, tasks and chapters the team
+// has started and completed, the time in milliseconds, and the progress of
+// unfinished tasks. qbedit only reads them, to show what a team has done.
+type TeamProgress struct {
+	File string
+	UUID string
+	Name string
+	// Started and Completed map quest, task and chapter ids to when they
+	// were started or completed
+	Started   map[string]time.Time
+	Completed map[string]time.Time
+	// Tasks is the progress of unfinished tasks
+	Tasks map[string]int
+}
+
+// Label returns the team's name, or its uuid or file if it has none.
+func (t *TeamProgress) Label() string {
+	return cmp.Or(t.Name, t.UUID, t.FileName())
+}
+
+// FileName returns the name of the team's progress file.
+func (t *TeamProgress) FileName() string { return filepath.Base(t.File) }
+
+// readTeamProgress reads the progress file at path.
+func readTeamProgress(path string) (*TeamProgress, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	v, err := snbt.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok || (!M(m).Has("completed") && !M(m).Has("started")) {
+		return nil, fmt.Errorf("not a quest progress file")
+	}
+	times := func(key string) map[string]time.Time {
+		res := make(map[string]time.Time)
+		if c, ok := m[key].(map[string]any); ok {
+			for id, ms := range c {
+				res[strings.ToUpper(id)] = time.UnixMilli(int64(anyToInt(ms)))
+			}
+		}
+		return res
+	}
+	t := &TeamProgress{
+		File:      path,
+		UUID:      M(m).GetString("uuid"),
+		Name:      M(m).GetString("name"),
+		Started:   times("started"),
+		Completed: times("completed"),
+		Tasks:     make(map[string]int),
+	}
+	if c, ok := m["task_progress"].(map[string]any); ok {
+		for id, n := range c {
+			t.Tasks[strings.ToUpper(id)] = anyToInt(n)
+		}
+	}
+	return t, nil
+}
+
+// readProgressDir reads the progress files in dir, sorted by team, and
+// the errors of the files that couldn't be read.
+func readProgressDir(dir string) ([]*TeamProgress, []string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.snbt"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("no progress files in %s", dir)
+	}
+	var teams []*TeamProgress
+	var errs []string
+	for _, p := range paths {
+		t, err := readTeamProgress(p)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", filepath.Base(p), err))
+			continue
+		}
+		teams = append(teams, t)
+	}
+	slices.SortFunc(teams, func(a, b *TeamProgress) int {
+		return strings.Compare(strings.ToLower(a.Label()), strings.ToLower(b.Label()))
+	})
+	return teams, errs, nil
+}
+
+// findProgressDirs returns the directories near the ftbquests dir root
+// that hold progress files: those of the instance's singleplayer worlds,
+// of a server's world, and the data dir of older versions.
+func findProgressDirs(root string) []string {
+	instance := filepath.Join(root, "..", "..")
+	var dirs []string
+	for _, pattern := range []string{
+		filepath.Join(instance, "saves", "*", "ftbquests"),
+		filepath.Join(instance, "*", "ftbquests"),
+		filepath.Join(root, "data"),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		slices.Sort(matches)
+		for _, dir := range matches {
+			dir = filepath.Clean(dir)
+			if dir == filepath.Clean(root) || slices.Contains(dirs, dir) {
+				continue
+			}
+			if snbts, _ := filepath.Glob(filepath.Join(dir, "*.snbt")); len(snbts) > 0 {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// QuestProgress is a quest's state in a team's progress.
+type QuestProgress struct {
+	Quest *Quest
+	// State is "completed", "started" or ""
+	State string
+	At    time.Time
+	// TasksDone counts the quest's completed tasks, and Partial describes
+	// the progress of the others, eg. "item 12"
+	TasksDone int
+	Partial   []string
+	// Missing are the dependencies the team hasn't completed, for a
+	// completed quest; usually from dependencies added after it was done
+	Missing []string
+}
+
+// ChapterProgress is the progress of a team in a chapter.
+type ChapterProgress struct {
+	Chapter *Chapter
+	Done    int
+	Quests  []QuestProgress
+}
+
+// ProgressReport is a team's progress through the book.
+type ProgressReport struct {
+	Team     *TeamProgress
+	Done     int
+	Chapters []ChapterProgress
+	// Unknown are the completed ids that aren't in the book, eg. of quests
+	// that were deleted or given new ids
+	Unknown []string
+}
+
+// progressReport compares t with the quests of qb.
+func progressReport(qb *QuestBook, t *TeamProgress) *ProgressReport {
+	rep := &ProgressReport{Team: t}
+	known := make(map[string]bool)
+	for _, ch := range qb.Chapters {
+		known[ch.ID] = true
+		cp := ChapterProgress{Chapter: ch}
+		for _, q := range ch.Quests {
+			known[q.ID] = true
+			p := QuestProgress{Quest: q}
+			for _, task := range q.Tasks {
+				known[task.Base().ID] = true
+				if _, ok := t.Completed[task.Base().ID]; ok {
+					p.TasksDone++
+				} else if n := t.Tasks[task.Base().ID]; n > 0 {
+					p.Partial = append(p.Partial, fmt.Sprintf("%s %d", task.Base().Type, n))
+				}
+			}
+			if at, ok := t.Completed[q.ID]; ok {
+				p.State, p.At = "completed", at
+				for _, dep := range q.Dependencies {
+					if _, ok := t.Completed[dep]; !ok {
+						p.Missing = append(p.Missing, dep)
+					}
+				}
+				cp.Done++
+			} else if at, ok := t.Started[q.ID]; ok {
+				p.State, p.At = "started", at
+			}
+			cp.Quests = append(cp.Quests, p)
+		}
+		rep.Done += cp.Done
+		rep.Chapters = append(rep.Chapters, cp)
+	}
+	for id := range t.Completed {
+		if !known[id] {
+			rep.Unknown = append(rep.Unknown, id)
+		}
+	}
+	slices.Sort(rep.Unknown)
+	return rep
+}
+
+// progress handles GET "/progress", the progress of the teams in the
+// progress dir ?dir=, defaulting to ProgressDir or the first one found near
+// the book; ?team= is the uuid or file of the team to show. ?dir= can only
+// be ProgressDir or one of the dirs found, so a request can't read any dir.
+func (a *App) progress(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	data := a.baseData(r, "Progress")
+	found := findProgressDirs(a.bookRoot())
+	if a.ProgressDir != "" && !slices.Contains(found, a.ProgressDir) {
+		found = append([]string{a.ProgressDir}, found...)
+	}
+	dir := strings.TrimSpace(r.URL.Query().Get("dir"))
+	if dir != "" && !slices.Contains(found, dir) {
+		http.Error(w, "unknown progress dir "+dir, http.StatusBadRequest)
+		return
+	}
+	if dir == "" && len(found) > 0 {
+		dir = found[0]
+	}
+	data["Dir"], data["Found"] = dir, found
+	if dir == "" {
+		a.render(w, "progress.gohtml", data)
+		return
+	}
+	teams, errs, err := readProgressDir(dir)
+	if err != nil {
+		data["ProgressErr"] = err.Error()
+		a.render(w, "progress.gohtml", data)
+		return
+	}
+	data["FileErrs"] = errs
+	var reports []*ProgressReport
+	for _, t := range teams {
+		reports = append(reports, progressReport(qb, t))
+	}
+	data["Reports"], data["QuestCount"] = reports, len(qb.Quests)
+	team := r.URL.Query().Get("team")
+	for _, rep := range reports {
+		if team == "" || rep.Team.UUID == team || rep.Team.FileName() == team {
+			data["Report"] = rep
+			break
+		}
+	}
+	a.render(w, "progress.gohtml", data)
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	a := testApp(t)
+	var q, dep *Quest
+	for _, cq := range a.QB().Quests {
+		if len(cq.Dependencies) > 0 && len(cq.Tasks) > 0 {
+			q, dep = cq, a.QB().questMap[cq.Dependencies[0]]
+			break
+		}
+	}
+	if q == nil || dep == nil {
+		t.Skip("test chapter needs a quest with a dependency")
+	}
+
+	inst := t.TempDir()
+	root := filepath.Join(inst, "config", "ftbquests")
+	dir := filepath.Join(inst, "saves", "World", "ftbquests")
+	for _, d := range []string{root, dir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	team := fmt.Sprintf(`{
+	version: 1
+	uuid: "6f2a"
+	name: "Builders"
+	started: { %[1]s: 1700000000000L }
+	completed: { %[1]s: 1700000100000L, %[2]s: 1700000050000L, DEADBEEFDEADBEEF: 1L }
+	task_progress: { }
+}`, q.ID, q.Tasks[0].Base().ID)
+	if err := os.WriteFile(filepath.Join(dir, "6f2a.snbt"), []byte(team), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.snbt"), []byte(`{ a: 1 }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if dirs := findProgressDirs(root); !slices.Equal(dirs, []string{dir}) {
+		t.Fatalf("found %q", dirs)
+	}
+
+	teams, errs, err := readProgressDir(dir)
+	if err != nil || len(teams) != 1 || len(errs) != 1 {
+		t.Fatalf("teams %v, errors %q, %v", teams, errs, err)
+	}
+	rep := progressReport(a.QB(), teams[0])
+	if rep.Done != 1 || !slices.Equal(rep.Unknown, []string{"DEADBEEFDEADBEEF"}) {
+		t.Errorf("report: %d done, unknown %q", rep.Done, rep.Unknown)
+	}
+	for _, cp := range rep.Chapters {
+		for _, p := range cp.Quests {
+			if p.Quest == q && (p.State != "completed" || p.TasksDone != 1 || !slices.Equal(p.Missing, []string{dep.ID})) {
+				t.Errorf("quest progress = %+v", p)
+			}
+		}
+	}
+
+	a.ProgressDir = dir
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/progress", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, "Builders") || !strings.Contains(body, "completed without") {
+		t.Errorf("progress page: %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/progress?dir="+url.QueryEscape(inst), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("progress page for another dir: %d", rec.Code)
+	}
+}
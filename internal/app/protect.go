@@ -0,0 +1,235 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// protections are what the write functions check, by the root of the book
+// they protect; the app sets them from the pack's settings. A sandbox's root
+// shares the rules of its book.
+//
+// Packs often include chapters maintained upstream, eg. by a mod's own quest
+// book, that local edits would be lost from or conflict with on the next
+// update. They are protected with glob patterns of chapter names:
+// "upstream_*" protects whole chapters, and "upstream_*:rewards" only the
+// rewards field of those chapters and their quests. A protection holds until
+// it is unlocked, which lasts until it is locked again or qbedit restarts.
+var protections sync.Map // root -> *atomic.Pointer[protectionRules]
+
+type protectionRules struct {
+	patterns []string
+	unlocked bool
+}
+
+// protectionOf returns the rules of the book at root.
+func protectionOf(root string) *atomic.Pointer[protectionRules] {
+	v, _ := protections.LoadOrStore(filepath.Clean(root), new(atomic.Pointer[protectionRules]))
+	return v.(*atomic.Pointer[protectionRules])
+}
+
+// setProtection sets the patterns writes to the book at root are checked
+// against and whether they are unlocked.
+func setProtection(root string, patterns []string, unlocked bool) {
+	protectionOf(root).Store(&protectionRules{patterns: patterns, unlocked: unlocked})
+}
+
+// shareProtection makes writes under alias, a sandbox of the book at root,
+// checked against the book's rules until dropProtection.
+func shareProtection(alias, root string) {
+	protections.Store(filepath.Clean(alias), protectionOf(root))
+}
+
+// dropProtection forgets the rules of alias.
+func dropProtection(alias string) {
+	protections.Delete(filepath.Clean(alias))
+}
+
+// protectionUnlocked reports whether protected content of the book at root
+// can be edited.
+func protectionUnlocked(root string) bool {
+	p := protectionOf(root).Load()
+	return p != nil && p.unlocked
+}
+
+// ProtectedError is returned for writes that would change protected content.
+type ProtectedError struct {
+	Chapter string
+	Pattern string
+	// Field is the protected field that would change, or "" when the whole
+	// chapter is protected.
+	Field string
+}
+
+func (e *ProtectedError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s of chapter %s is protected by %q; unlock protected content to edit it", e.Field, e.Chapter, e.Pattern)
+	}
+	return fmt.Sprintf("chapter %s is protected by %q; unlock protected content to edit it", e.Chapter, e.Pattern)
+}
+
+// validProtectPattern reports whether p is a valid pattern.
+func validProtectPattern(p string) error {
+	glob, field, _ := strings.Cut(p, ":")
+	if glob == "" {
+		return fmt.Errorf("%q: no chapter pattern", p)
+	}
+	if _, err := path.Match(glob, ""); err != nil {
+		return fmt.Errorf("%q: %w", p, err)
+	}
+	if strings.Contains(p, ":") && field == "" {
+		return fmt.Errorf("%q: no field after the colon", p)
+	}
+	return nil
+}
+
+// checkProtected returns a *ProtectedError if writing b to the file at path,
+// which held old, changes protected content. A nil b removes the file, and a
+// nil old creates it. It is called where files are written, so it covers
+// every edit; text kept in a lang file is checked with the chapter that uses
+// it only when the chapter file changes too.
+func checkProtected(file string, old, b []byte) error {
+	if filepath.Base(filepath.Dir(file)) != "chapters" || filepath.Ext(file) != ".snbt" {
+		return nil
+	}
+	// chapter files are in <root>/quests/chapters
+	v, ok := protections.Load(filepath.Dir(filepath.Dir(filepath.Dir(file))))
+	if !ok {
+		return nil
+	}
+	p := v.(*atomic.Pointer[protectionRules]).Load()
+	if p == nil || p.unlocked || len(p.patterns) == 0 {
+		return nil
+	}
+	name := strings.TrimSuffix(filepath.Base(file), ".snbt")
+	var ov, nv map[string]any
+	decoded := false
+	for _, pat := range p.patterns {
+		glob, field, _ := strings.Cut(pat, ":")
+		if ok, _ := path.Match(glob, name); !ok {
+			continue
+		}
+		if !decoded {
+			ov, nv = decodeCompound(old), decodeCompound(b)
+			decoded = true
+		}
+		if field == "" {
+			if (old == nil) != (b == nil) || !reflect.DeepEqual(ov, nv) {
+				return &ProtectedError{Chapter: name, Pattern: pat}
+			}
+			continue
+		}
+		if protectedFieldChanged(ov, nv, field) {
+			return &ProtectedError{Chapter: name, Pattern: pat, Field: field}
+		}
+	}
+	return nil
+}
+
+// checkRemove is checkProtected for removing the file at path.
+func checkRemove(path string) error {
+	old, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return checkProtected(path, old, nil)
+}
+
+// decodeCompound decodes b, or returns nil if it isn't an SNBT compound.
+func decodeCompound(b []byte) map[string]any {
+	if b == nil {
+		return nil
+	}
+	v, err := snbt.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// protectedFieldChanged reports whether field differs between the chapters
+// ov and nv, on the chapter or on any quest ov has.
+func protectedFieldChanged(ov, nv map[string]any, field string) bool {
+	if !reflect.DeepEqual(ov[field], nv[field]) {
+		return true
+	}
+	quests := func(m map[string]any) map[string]map[string]any {
+		byID := make(map[string]map[string]any)
+		for _, q := range M(m).GetAnys("quests") {
+			if qm, ok := q.(map[string]any); ok {
+				byID[M(qm).GetString("id")] = qm
+			}
+		}
+		return byID
+	}
+	nq := quests(nv)
+	for id, q := range quests(ov) {
+		if !reflect.DeepEqual(q[field], nq[id][field]) {
+			return true
+		}
+	}
+	return false
+}
+
+// protectPage handles GET "/protect".
+func (a *App) protectPage(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Protected content")
+	data["Patterns"] = strings.Join(a.Pack.Get().Protected, "\n")
+	data["Unlocked"] = protectionUnlocked(a.bookRoot())
+	data["Msg"] = r.URL.Query().Get("msg")
+	a.render(w, "protect.gohtml", data)
+}
+
+// protectPatterns handles POST "/protect", saving the pack's patterns, one
+// per line.
+func (a *App) protectPatterns(w http.ResponseWriter, r *http.Request) {
+	var patterns []string
+	for _, p := range strings.Split(r.FormValue("patterns"), "\n") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if err := validProtectPattern(p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		patterns = append(patterns, p)
+	}
+	err := a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Protected = patterns
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	setProtection(a.bookRoot(), patterns, protectionUnlocked(a.bookRoot()))
+	a.audit(r, "protect", "", nil, strings.Join(patterns, ", "))
+	http.Redirect(w, r, "/protect", http.StatusSeeOther)
+}
+
+// protectLock handles POST "/protect/lock". "unlock=1" allows edits to
+// protected content until it is locked again.
+func (a *App) protectLock(w http.ResponseWriter, r *http.Request) {
+	unlock := r.FormValue("unlock") == "1"
+	setProtection(a.bookRoot(), a.Pack.Get().Protected, unlock)
+	msg := "Protected content is locked."
+	if unlock {
+		msg = "Protected content is unlocked: it can be edited until it is locked again."
+		a.audit(r, "unlock protected", "", nil, "")
+	} else {
+		a.audit(r, "lock protected", "", nil, "")
+	}
+	http.Redirect(w, r, "/protect?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
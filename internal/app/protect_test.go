@@ -0,0 +1,78 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProtect(t *testing.T) {
+	a := testApp(t)
+	t.Cleanup(func() { setProtection(a.Root(), nil, false) })
+	post := func(path string, form url.Values) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		a.Router().ServeHTTP(rec, req)
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("%s: %d %s", path, rec.Code, rec.Body)
+		}
+	}
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	open := func() *Chapter {
+		t.Helper()
+		ch, err := NewChapterFromPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ch
+	}
+	var pe *ProtectedError
+
+	post("/protect", url.Values{"patterns": {"te*:rewards\n"}})
+	if got := a.Pack.Get().Protected; len(got) != 1 || got[0] != "te*:rewards" {
+		t.Fatalf("patterns = %v", got)
+	}
+	ch := open()
+	ch.Quests[0].Title = "Renamed"
+	if err := ch.Save(path); err != nil {
+		t.Errorf("saving an unprotected field: %v", err)
+	}
+	ch = open()
+	var q *Quest
+	for _, cq := range ch.Quests {
+		if len(cq.Rewards) > 0 {
+			q = cq
+			break
+		}
+	}
+	if q == nil {
+		t.Skip("no quest with rewards")
+	}
+	q.Rewards = nil
+	if err := ch.Save(path); !errors.As(err, &pe) || pe.Field != "rewards" {
+		t.Errorf("saving protected rewards: %v", err)
+	}
+
+	post("/protect", url.Values{"patterns": {"test"}})
+	if err := open().Save(path); err != nil {
+		t.Errorf("saving an unchanged protected chapter: %v", err)
+	}
+	if err := a.QB().DeleteChapter("test"); !errors.As(err, &pe) || pe.Chapter != "test" {
+		t.Errorf("deleting a protected chapter: %v", err)
+	}
+
+	post("/protect/lock", url.Values{"unlock": {"1"}})
+	if err := ch.Save(path); err != nil {
+		t.Errorf("saving while unlocked: %v", err)
+	}
+	post("/protect/lock", nil)
+	if !strings.Contains(a.Pack.Get().Protected[0], "test") || protectionUnlocked(a.Root()) {
+		t.Error("still unlocked")
+	}
+}
@@ -0,0 +1,968 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxQuery is the longest query the console runs.
+const maxQuery = 4000
+
+// qexpr is a compiled query: it evaluates against the root and the current
+// value.
+type qexpr func(root, cur any) (any, error)
+
+// qtoken is a lexical token of a query.
+type qtoken struct {
+	kind byte // 'i'dent, 'n'umber, 's'tring, 'o'perator or 0 at the end
+	text string
+	num  float64
+	pos  int
+}
+
+// lexQuery splits s into tokens.
+func lexQuery(s string) ([]qtoken, error) {
+	var toks []qtoken
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += n
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for j < len(s) {
+				r, n := utf8.DecodeRuneInString(s[j:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += n
+			}
+			toks = append(toks, qtoken{kind: 'i', text: s[i:j], pos: i})
+			i = j
+		case r >= '0' && r <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.' || s[j] == 'e' || s[j] == 'E' ||
+				(s[j] == '-' || s[j] == '+') && (s[j-1] == 'e' || s[j-1] == 'E')) {
+				j++
+			}
+			f, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("column %d: bad number %s", i+1, s[i:j])
+			}
+			toks = append(toks, qtoken{kind: 'n', text: s[i:j], num: f, pos: i})
+			i = j
+		case r == '\'' || r == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s) && rune(s[j]) != r; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("column %d: unterminated string", i+1)
+			}
+			toks = append(toks, qtoken{kind: 's', text: b.String(), pos: i})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range []string{"==", "!=", "<=", ">=", "&&", "||", "[?", "[]", "[*]"} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune(".[](){},:<>!@$-|", r) {
+					return nil, fmt.Errorf("column %d: unexpected %q", i+1, r)
+				}
+				op = string(r)
+			}
+			toks = append(toks, qtoken{kind: 'o', text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, qtoken{pos: len(s)}), nil
+}
+
+// qparser is a recursive descent parser for queries.
+type qparser struct {
+	toks []qtoken
+	i    int
+}
+
+func (p *qparser) peek() qtoken { return p.toks[p.i] }
+
+func (p *qparser) next() qtoken {
+	t := p.toks[p.i]
+	if t.kind != 0 {
+		p.i++
+	}
+	return t
+}
+
+// is reports whether the next token is the operator op, and takes it if so.
+func (p *qparser) is(op string) bool {
+	if t := p.peek(); t.kind == 'o' && t.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *qparser) errorf(format string, args ...any) error {
+	return fmt.Errorf("column %d: %s", p.peek().pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *qparser) expect(op string) error {
+	if !p.is(op) {
+		if t := p.peek(); t.kind != 0 {
+			return p.errorf("expected %s, found %s", op, t.text)
+		}
+		return p.errorf("expected %s at the end", op)
+	}
+	return nil
+}
+
+// compileQuery parses the query s, for the console's read-only questions such
+// as how many quests have an item as their first task. Queries work on the
+// book in the quest export's shape (see export.go), as JSON, under a root of:
+//
+//	chapters  the export's chapters, with their quests
+//	quests    every quest
+//	groups    chapter groups: id, title and chapters (their names)
+//	tables    reward tables: id, name, title, loot_size, empty_weight and
+//	          entries, each a weight and a reward
+//
+// The language is a small relative of JMESPath:
+//
+//	quests[0].title.plain         fields and indexes; negative indexes count
+//	                              from the end
+//	quests[?repeatable]           filters keep the elements the condition
+//	                              holds for, with fields read from each
+//	quests[*].id, chapters[].quests  projections apply the rest of the path to
+//	                              each element; [] also flattens a level
+//	quests[*].{id: id, n: count(tasks)}  objects build new values
+//	== != < <= > >= && || !       compare and combine; strings are quoted
+//	                              with ' or "
+//	quests[].tasks[] | [?count]   a pipe runs the right side on the result of
+//	                              the left, ending projections
+//	@ and $                       the current value and the root
+//
+// with the functions listed in queryFuncs. Missing fields are null, and
+// projections leave out nulls.
+func compileQuery(s string) (qexpr, error) {
+	if len(s) > maxQuery {
+		return nil, fmt.Errorf("the query is longer than %d characters", maxQuery)
+	}
+	toks, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &qparser{toks: toks}
+	if p.peek().kind == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != 0 {
+		return nil, p.errorf("unexpected %s", t.text)
+	}
+	return e, nil
+}
+
+// expr parses a pipe, which evaluates its right side on the value of its
+// left, ending any projections.
+func (p *qparser) expr() (qexpr, error) {
+	l, err := p.or()
+	for err == nil && p.is("|") {
+		var r qexpr
+		if r, err = p.or(); err == nil {
+			l = then(l, r)
+		}
+	}
+	return l, err
+}
+
+func (p *qparser) or() (qexpr, error) {
+	l, err := p.and()
+	for err == nil && p.is("||") {
+		var r qexpr
+		if r, err = p.and(); err == nil {
+			l = orExpr(l, r)
+		}
+	}
+	return l, err
+}
+
+func orExpr(l, r qexpr) qexpr {
+	return func(root, cur any) (any, error) {
+		v, err := l(root, cur)
+		if err != nil || truthy(v) {
+			return v, err
+		}
+		return r(root, cur)
+	}
+}
+
+func (p *qparser) and() (qexpr, error) {
+	l, err := p.compare()
+	for err == nil && p.is("&&") {
+		var r qexpr
+		if r, err = p.compare(); err == nil {
+			l = andExpr(l, r)
+		}
+	}
+	return l, err
+}
+
+func andExpr(l, r qexpr) qexpr {
+	return func(root, cur any) (any, error) {
+		v, err := l(root, cur)
+		if err != nil || !truthy(v) {
+			return v, err
+		}
+		return r(root, cur)
+	}
+}
+
+func (p *qparser) compare() (qexpr, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.is(op) {
+			r, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return func(root, cur any) (any, error) {
+				a, err := l(root, cur)
+				if err != nil {
+					return nil, err
+				}
+				b, err := r(root, cur)
+				if err != nil {
+					return nil, err
+				}
+				return compareValues(op, a, b), nil
+			}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *qparser) unary() (qexpr, error) {
+	if p.is("!") {
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(root, cur any) (any, error) {
+			v, err := e(root, cur)
+			return !truthy(v), err
+		}, nil
+	}
+	if p.is("-") {
+		t := p.next()
+		if t.kind != 'n' {
+			return nil, p.errorf("expected a number after -")
+		}
+		return p.chain(literal(-t.num), false)
+	}
+	e, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	return p.chain(e, false)
+}
+
+func literal(v any) qexpr { return func(any, any) (any, error) { return v, nil } }
+
+func current(_, cur any) (any, error) { return cur, nil }
+
+func (p *qparser) primary() (qexpr, error) {
+	t := p.peek()
+	switch t.kind {
+	case 'n':
+		p.next()
+		return literal(t.num), nil
+	case 's':
+		p.next()
+		return literal(t.text), nil
+	case 'i':
+		p.next()
+		switch t.text {
+		case "true", "false":
+			return literal(t.text == "true"), nil
+		case "null":
+			return literal(nil), nil
+		}
+		if p.is("(") {
+			return p.call(t)
+		}
+		return field(current, t.text), nil
+	case 'o':
+		switch t.text {
+		case "@":
+			p.next()
+			return current, nil
+		case "$":
+			p.next()
+			return func(root, _ any) (any, error) { return root, nil }, nil
+		case "(":
+			p.next()
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		case "{":
+			p.next()
+			return p.object(current)
+		case "[", "[?", "[*]", "[]":
+			// a filter or projection of the current value
+			return current, nil
+		}
+		return nil, p.errorf("unexpected %s", t.text)
+	}
+	return nil, p.errorf("unexpected end of the query")
+}
+
+// chain parses the fields, indexes, filters and projections after e. In a
+// projection, inner is true and chain stops at a [], which flattens the
+// whole projection.
+func (p *qparser) chain(e qexpr, inner bool) (qexpr, error) {
+	for {
+		switch {
+		case p.is("."):
+			if p.is("{") {
+				obj, err := p.object(current)
+				if err != nil {
+					return nil, err
+				}
+				e = then(e, obj)
+				continue
+			}
+			t := p.next()
+			if t.kind != 'i' && t.kind != 's' {
+				return nil, p.errorf("expected a field name after .")
+			}
+			e = field(e, t.text)
+		case p.is("["):
+			neg := p.is("-")
+			t := p.next()
+			if t.kind != 'n' || t.num != math.Trunc(t.num) {
+				return nil, p.errorf("expected an index")
+			}
+			n := int(t.num)
+			if neg {
+				n = -n
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			e = index(e, n)
+		case p.is("[?"):
+			cond, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			rest, err := p.chain(current, true)
+			if err != nil {
+				return nil, err
+			}
+			e = project(filter(e, cond), rest)
+		case p.is("[*]"):
+			rest, err := p.chain(current, true)
+			if err != nil {
+				return nil, err
+			}
+			e = project(values(e), rest)
+		case !inner && p.is("[]"):
+			rest, err := p.chain(current, true)
+			if err != nil {
+				return nil, err
+			}
+			e = project(flatten(e), rest)
+		default:
+			return e, nil
+		}
+	}
+}
+
+// object parses the entries of an object after its {, built from the value
+// of e.
+func (p *qparser) object(e qexpr) (qexpr, error) {
+	var keys []string
+	var vals []qexpr
+	for !p.is("}") {
+		if len(keys) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		t := p.next()
+		if t.kind != 'i' && t.kind != 's' {
+			return nil, p.errorf("expected a key")
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		keys, vals = append(keys, t.text), append(vals, v)
+	}
+	return then(e, func(root, cur any) (any, error) {
+		m := make(map[string]any, len(keys))
+		for i, k := range keys {
+			v, err := vals[i](root, cur)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	}), nil
+}
+
+// call parses the arguments of the function named by t.
+func (p *qparser) call(t qtoken) (qexpr, error) {
+	fn, ok := queryFuncs[t.text]
+	if !ok {
+		return nil, fmt.Errorf("column %d: unknown function %s", t.pos+1, t.text)
+	}
+	var args []qexpr
+	for !p.is(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		a, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	if len(args) != fn.args {
+		return nil, fmt.Errorf("column %d: %s takes %d arguments, not %d", t.pos+1, t.text, fn.args, len(args))
+	}
+	return func(root, cur any) (any, error) {
+		vals := make([]any, len(args))
+		for i, a := range args {
+			v, err := a(root, cur)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+		}
+		v, err := fn.call(vals)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.text, err)
+		}
+		return v, nil
+	}, nil
+}
+
+// then evaluates next on the value of e.
+func then(e, next qexpr) qexpr {
+	return func(root, cur any) (any, error) {
+		v, err := e(root, cur)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		return next(root, v)
+	}
+}
+
+func field(e qexpr, name string) qexpr {
+	return func(root, cur any) (any, error) {
+		v, err := e(root, cur)
+		if m, ok := v.(map[string]any); ok {
+			return m[name], err
+		}
+		return nil, err
+	}
+}
+
+func index(e qexpr, n int) qexpr {
+	return func(root, cur any) (any, error) {
+		v, err := e(root, cur)
+		l, ok := v.([]any)
+		if !ok {
+			return nil, err
+		}
+		i := n
+		if i < 0 {
+			i += len(l)
+		}
+		if i < 0 || i >= len(l) {
+			return nil, err
+		}
+		return l[i], err
+	}
+}
+
+func filter(e, cond qexpr) qexpr {
+	return func(root, cur any) (any, error) {
+		v, err := e(root, cur)
+		l, ok := v.([]any)
+		if !ok {
+			return nil, err
+		}
+		kept := []any{}
+		for _, el := range l {
+			c, err := cond(root, el)
+			if err != nil {
+				return nil, err
+			}
+			if truthy(c) {
+				kept = append(kept, el)
+			}
+		}
+		return kept, nil
+	}
+}
+
+// values returns a list, or an object's values in key order.
+func values(e qexpr) qexpr {
+	return func(root, cur any) (any, error) {
+		v, err := e(root, cur)
+		switch v := v.(type) {
+		case []any:
+			return v, err
+		case map[string]any:
+			keys := sortedKeys(v)
+			l := make([]any, len(keys))
+			for i, k := range keys {
+				l[i] = v[k]
+			}
+			return l, err
+		}
+		return nil, err
+	}
+}
+
+func flatten(e qexpr) qexpr {
+	return func(root, cur any) (any, error) {
+		v, err := e(root, cur)
+		l, ok := v.([]any)
+		if !ok {
+			return nil, err
+		}
+		flat := []any{}
+		for _, el := range l {
+			if sub, ok := el.([]any); ok {
+				flat = append(flat, sub...)
+			} else {
+				flat = append(flat, el)
+			}
+		}
+		return flat, nil
+	}
+}
+
+// project evaluates rest on each element of the list e, leaving out nulls.
+func project(e, rest qexpr) qexpr {
+	return func(root, cur any) (any, error) {
+		v, err := e(root, cur)
+		l, ok := v.([]any)
+		if !ok {
+			return nil, err
+		}
+		out := []any{}
+		for _, el := range l {
+			r, err := rest(root, el)
+			if err != nil {
+				return nil, err
+			}
+			if r != nil {
+				out = append(out, r)
+			}
+		}
+		return out, nil
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// truthy reports whether v counts as true: anything but null, false and
+// empty strings, lists and objects.
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	}
+	return true
+}
+
+// compareValues applies the comparison op to a and b. Ordering only holds
+// between two numbers or two strings.
+func compareValues(op string, a, b any) bool {
+	switch op {
+	case "==":
+		return reflect.DeepEqual(a, b)
+	case "!=":
+		return !reflect.DeepEqual(a, b)
+	}
+	var c int
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return false
+		}
+		c = cmpFloat(a, b)
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(a, b)
+	default:
+		return false
+	}
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// queryFunc is a function queries can call.
+type queryFunc struct {
+	args int
+	call func(args []any) (any, error)
+	// Doc describes the function on the console page.
+	Doc string
+}
+
+// queryFuncs are the functions of the query language, by name.
+var queryFuncs map[string]queryFunc
+
+func init() {
+	numbers := func(v any) ([]float64, error) {
+		l, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("want a list")
+		}
+		ns := make([]float64, 0, len(l))
+		for _, el := range l {
+			n, ok := el.(float64)
+			if !ok {
+				return nil, fmt.Errorf("want a list of numbers")
+			}
+			ns = append(ns, n)
+		}
+		return ns, nil
+	}
+	// extreme returns the least or greatest of a list of numbers or of
+	// strings, or null for an empty list.
+	extreme := func(v any, sign int) (any, error) {
+		l, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("want a list")
+		}
+		var best any
+		for _, el := range l {
+			switch el.(type) {
+			case float64, string:
+			default:
+				return nil, fmt.Errorf("want a list of numbers or strings")
+			}
+			if best == nil || sign < 0 && compareValues("<", el, best) || sign > 0 && compareValues(">", el, best) {
+				best = el
+			}
+		}
+		return best, nil
+	}
+	str := func(v any) (string, error) {
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("want a string")
+		}
+		return s, nil
+	}
+	strs := func(f func(a, b string) bool) func(args []any) (any, error) {
+		return func(args []any) (any, error) {
+			a, err := str(args[0])
+			if err != nil {
+				return nil, err
+			}
+			b, err := str(args[1])
+			if err != nil {
+				return nil, err
+			}
+			return f(a, b), nil
+		}
+	}
+	queryFuncs = map[string]queryFunc{
+		"count": {1, func(args []any) (any, error) {
+			switch v := args[0].(type) {
+			case []any:
+				return float64(len(v)), nil
+			case map[string]any:
+				return float64(len(v)), nil
+			case string:
+				return float64(utf8.RuneCountInString(v)), nil
+			case nil:
+				return 0.0, nil
+			}
+			return nil, fmt.Errorf("want a list, object or string")
+		}, "the length of a list, object or string"},
+		"sum": {1, func(args []any) (any, error) {
+			ns, err := numbers(args[0])
+			total := 0.0
+			for _, n := range ns {
+				total += n
+			}
+			return total, err
+		}, "the sum of a list of numbers"},
+		"avg": {1, func(args []any) (any, error) {
+			ns, err := numbers(args[0])
+			if err != nil || len(ns) == 0 {
+				return nil, err
+			}
+			total := 0.0
+			for _, n := range ns {
+				total += n
+			}
+			return total / float64(len(ns)), nil
+		}, "the mean of a list of numbers"},
+		"min": {1, func(args []any) (any, error) { return extreme(args[0], -1) }, "the least of a list of numbers or strings"},
+		"max": {1, func(args []any) (any, error) { return extreme(args[0], 1) }, "the greatest of a list of numbers or strings"},
+		"keys": {1, func(args []any) (any, error) {
+			m, ok := args[0].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("want an object")
+			}
+			keys := []any{}
+			for _, k := range sortedKeys(m) {
+				keys = append(keys, k)
+			}
+			return keys, nil
+		}, "an object's keys, sorted"},
+		"unique": {1, func(args []any) (any, error) {
+			l, ok := args[0].([]any)
+			if !ok {
+				return nil, fmt.Errorf("want a list")
+			}
+			out := []any{}
+			for _, el := range l {
+				if !containsValue(out, el) {
+					out = append(out, el)
+				}
+			}
+			return out, nil
+		}, "a list without repeats, in order"},
+		"sort": {1, func(args []any) (any, error) {
+			l, ok := args[0].([]any)
+			if !ok {
+				return nil, fmt.Errorf("want a list")
+			}
+			out := append([]any{}, l...)
+			for _, el := range out {
+				if !isOrdered(el) || reflect.TypeOf(el) != reflect.TypeOf(out[0]) {
+					return nil, fmt.Errorf("want a list of numbers or of strings")
+				}
+			}
+			sort.SliceStable(out, func(i, j int) bool { return compareValues("<", out[i], out[j]) })
+			return out, nil
+		}, "a list of numbers or of strings, sorted"},
+		"tally": {1, func(args []any) (any, error) {
+			l, ok := args[0].([]any)
+			if !ok {
+				return nil, fmt.Errorf("want a list")
+			}
+			m := make(map[string]any)
+			for _, el := range l {
+				k, ok := el.(string)
+				if !ok {
+					b, _ := json.Marshal(el)
+					k = string(b)
+				}
+				n, _ := m[k].(float64)
+				m[k] = n + 1
+			}
+			return m, nil
+		}, "how often each value of a list occurs, as an object"},
+		"contains": {2, func(args []any) (any, error) {
+			switch v := args[0].(type) {
+			case []any:
+				return containsValue(v, args[1]), nil
+			case string:
+				s, err := str(args[1])
+				return strings.Contains(v, s), err
+			}
+			return nil, fmt.Errorf("want a list or string")
+		}, "whether a list holds a value, or a string another string"},
+		"starts_with": {2, strs(strings.HasPrefix), "whether a string starts with another"},
+		"ends_with":   {2, strs(strings.HasSuffix), "whether a string ends with another"},
+		"lower": {1, func(args []any) (any, error) {
+			s, err := str(args[0])
+			return strings.ToLower(s), err
+		}, "a string in lower case"},
+	}
+}
+
+func isOrdered(v any) bool {
+	switch v.(type) {
+	case float64, string:
+		return true
+	}
+	return false
+}
+
+func containsValue(l []any, v any) bool {
+	for _, el := range l {
+		if reflect.DeepEqual(el, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryTable is a reward table in the console's root.
+type queryTable struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Title       string            `json:"title"`
+	LootSize    int               `json:"loot_size"`
+	EmptyWeight float64           `json:"empty_weight"`
+	Entries     []queryTableEntry `json:"entries"`
+}
+
+type queryTableEntry struct {
+	Weight float64      `json:"weight"`
+	Reward ExportReward `json:"reward"`
+}
+
+type queryGroup struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Chapters []string `json:"chapters"`
+}
+
+// queryRoot returns qb as the console's root value.
+func queryRoot(qb *QuestBook) (any, error) {
+	book := exportBook(qb.Chapters)
+	quests := []ExportQuest{}
+	for _, c := range book.Chapters {
+		quests = append(quests, c.Quests...)
+	}
+	groups := []queryGroup{}
+	for _, g := range qb.Groups {
+		qg := queryGroup{ID: g.ID, Title: g.Title, Chapters: []string{}}
+		for _, ch := range g.Chapters {
+			qg.Chapters = append(qg.Chapters, ch.Name)
+		}
+		groups = append(groups, qg)
+	}
+	tables := []queryTable{}
+	for _, t := range qb.Tables {
+		qt := queryTable{ID: t.ID, Name: t.Name, Title: t.Title, LootSize: t.LootSize, EmptyWeight: t.EmptyWeight, Entries: []queryTableEntry{}}
+		for _, e := range t.Entries {
+			qt.Entries = append(qt.Entries, queryTableEntry{Weight: e.Weight, Reward: exportReward(e.Reward)})
+		}
+		tables = append(tables, qt)
+	}
+	b, err := json.Marshal(map[string]any{"chapters": book.Chapters, "quests": quests, "groups": groups, "tables": tables})
+	if err != nil {
+		return nil, err
+	}
+	var root any
+	return root, json.Unmarshal(b, &root)
+}
+
+// Eval runs the query s against the book and returns its result as indented
+// JSON. It is `qbedit eval`.
+func (a *App) Eval(s string) ([]byte, error) {
+	e, err := compileQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	root, err := queryRoot(a.QB())
+	if err != nil {
+		return nil, err
+	}
+	v, err := e(root, root)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// console handles GET "/console", which runs the query "q" if given.
+func (a *App) console(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Console")
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q != "" {
+		if b, err := a.Eval(q); err != nil {
+			data["Error"] = err.Error()
+		} else {
+			data["Result"] = string(b)
+		}
+	}
+	data["Query"] = q
+	data["Funcs"] = queryFuncs
+	a.render(w, "console.gohtml", data)
+}
+
+// consoleJSON handles GET "/console.json", the result of the query "q" as
+// JSON for scripts.
+func (a *App) consoleJSON(w http.ResponseWriter, r *http.Request) {
+	b, err := a.Eval(r.URL.Query().Get("q"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(b)
+}
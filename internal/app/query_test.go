@@ -0,0 +1,89 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	var root any
+	if err := json.Unmarshal([]byte(`{
+		"quests": [
+			{"id": "a", "repeatable": true, "tasks": [{"type": "item", "item": "minecraft:stone", "count": 4}], "dependencies": []},
+			{"id": "b", "tasks": [{"type": "kill"}, {"type": "item", "item": "minecraft:dirt"}], "dependencies": ["a"]},
+			{"id": "c", "tasks": [], "dependencies": ["a", "b"]}
+		],
+		"$weird": 1
+	}`), &root); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ q, want string }{
+		{`count(quests[?tasks[0].type=='item'])`, `1`},
+		{`quests[?repeatable].id`, `["a"]`},
+		{`quests[*].id`, `["a","b","c"]`},
+		{`quests[-1].id`, `"c"`},
+		{`quests[9].id`, `null`},
+		{`quests[].tasks[].item`, `["minecraft:stone","minecraft:dirt"]`},
+		{`quests[?count(dependencies) >= 1 && !contains(dependencies, 'b')].id`, `["b"]`},
+		{`quests[?id == "a" || id == "c"].{id: id, n: count(tasks)}`, `[{"id":"a","n":1},{"id":"c","n":0}]`},
+		{`sum(quests[].tasks[] | [?count].count)`, `4`},
+		{`max(quests[*].id)`, `"c"`},
+		{`sort(unique(quests[].dependencies[]))`, `["a","b"]`},
+		{`tally(quests[].tasks[].type)`, `{"item":2,"kill":1}`},
+		{`quests[0].tasks[0].count > 3`, `true`},
+		{`quests[?starts_with(id, 'b')].id`, `["b"]`},
+		{`keys($)`, `["$weird","quests"]`},
+		{`quests[?tasks[?type=='kill']].id`, `["b"]`},
+		{`quests[0].{first: tasks[0].item, all: $.quests[*].id}`, `{"all":["a","b","c"],"first":"minecraft:stone"}`},
+		{`-1 < 0`, `true`},
+		{`quests[*].tasks[*].type`, `[["item"],["kill","item"],[]]`},
+	} {
+		e, err := compileQuery(c.q)
+		if err != nil {
+			t.Errorf("%s: %v", c.q, err)
+			continue
+		}
+		v, err := e(root, root)
+		if err != nil {
+			t.Errorf("%s: %v", c.q, err)
+			continue
+		}
+		if b, _ := json.Marshal(v); string(b) != c.want {
+			t.Errorf("%s = %s, want %s", c.q, b, c.want)
+		}
+	}
+	for _, bad := range []string{``, `quests[`, `quests[?id == ]`, `nope(1)`, `count(1, 2)`, `quests.#`, `'open`, `a b`} {
+		if _, err := compileQuery(bad); err == nil {
+			t.Errorf("%q should not compile", bad)
+		}
+	}
+	if e, err := compileQuery(`sum(quests[*].id)`); err != nil {
+		t.Error(err)
+	} else if _, err := e(root, root); err == nil {
+		t.Error("summing strings should be an error")
+	}
+
+	a := testApp(t)
+	if _, err := a.QB().CreateChapter("second", "Second", ""); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	b, err := a.Eval(`chapters[*].name`)
+	if err != nil || !strings.Contains(string(b), `"test"`) || !strings.Contains(string(b), `"second"`) {
+		t.Errorf("eval = %s, %v", b, err)
+	}
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/console.json?q="+url.QueryEscape(`count(quests) == count(chapters[].quests[])`), nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "true" {
+		t.Errorf("console.json: %d %s", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/console?q="+url.QueryEscape(`quests[`), nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Error:") {
+		t.Errorf("console: %d", rec.Code)
+	}
+}
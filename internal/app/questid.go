@@ -0,0 +1,285 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/qbedit/internal/app/fbtid"
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// validQuestID matches the hex ids FTB Quests uses.
+var validQuestID = regexp.MustCompile(`^[0-9A-F]{1,16}$`)
+
+// QuestIDRefs are the references to a quest id.
+type QuestIDRefs struct {
+	// Dependents are the quests that depend on it.
+	Dependents []*Quest
+	// Links are the quest links to it, by chapter.
+	Links map[*Chapter]int
+}
+
+// LinkCount returns the number of quest links to the quest.
+func (r QuestIDRefs) LinkCount() int {
+	n := 0
+	for _, c := range r.Links {
+		n += c
+	}
+	return n
+}
+
+// LinkChapters returns the chapters with quest links to the quest, by title.
+func (r QuestIDRefs) LinkChapters() []*Chapter {
+	chapters := slices.Collect(maps.Keys(r.Links))
+	slices.SortFunc(chapters, func(a, b *Chapter) int { return strings.Compare(a.Title, b.Title) })
+	return chapters
+}
+
+// questIDRefs returns the references to the quest id in the loaded book.
+// Reward tables aren't loaded and are only looked at when renaming.
+func (qb *QuestBook) questIDRefs(id string) QuestIDRefs {
+	refs := QuestIDRefs{Dependents: qb.dependents(id), Links: make(map[*Chapter]int)}
+	for _, c := range qb.Chapters {
+		for _, l := range c.QuestLinks {
+			if m, ok := l.(map[string]any); ok && M(m).GetString("linked_quest") == id {
+				refs.Links[c]++
+			}
+		}
+	}
+	return refs
+}
+
+// idSource returns a source of ids that aren't in use in qb.
+func (qb *QuestBook) idSource() *fbtid.Source {
+	return fbtid.NewSource(qb.idInUse)
+}
+
+// idInUse reports whether id is already used by a quest, chapter, group,
+// task, reward, quest link or reward table.
+func (qb *QuestBook) idInUse(id string) bool {
+	if _, ok := qb.questMap[id]; ok {
+		return true
+	}
+	if _, ok := qb.groupMap[id]; ok {
+		return true
+	}
+	for _, c := range qb.Chapters {
+		if c.ID == id {
+			return true
+		}
+		for _, l := range c.QuestLinks {
+			if m, ok := l.(map[string]any); ok && M(m).GetString("id") == id {
+				return true
+			}
+		}
+	}
+	for _, q := range qb.Quests {
+		for _, t := range q.Tasks {
+			if t.Base().ID == id {
+				return true
+			}
+		}
+		for _, r := range q.Rewards {
+			if r.Base().ID == id {
+				return true
+			}
+		}
+	}
+	for _, t := range qb.Tables {
+		if t.ID == id {
+			return true
+		}
+		for _, e := range t.Entries {
+			if e.Reward.Base().ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// replaceString replaces every string in v equal to old with new, returning
+// the updated value and how many were replaced.
+func replaceString(v any, old, new string) (any, int) {
+	switch x := v.(type) {
+	case string:
+		if x == old {
+			return new, 1
+		}
+	case map[string]any:
+		n := 0
+		for k, e := range x {
+			var c int
+			x[k], c = replaceString(e, old, new)
+			n += c
+		}
+		return x, n
+	case []any:
+		n := 0
+		for i, e := range x {
+			var c int
+			x[i], c = replaceString(e, old, new)
+			n += c
+		}
+		return x, n
+	}
+	return v, 0
+}
+
+// RenameQuestID changes the id of the quest old to new, along with every
+// dependency on it, quest link to it and mention of it in the reward tables,
+// so the book never has a dangling reference. The changed files are written
+// together. It returns the paths written.
+func (qb *QuestBook) RenameQuestID(old, new string) ([]string, error) {
+	q, ok := qb.questMap[old]
+	if !ok {
+		return nil, fmt.Errorf("unknown quest %s", old)
+	}
+	if !validQuestID.MatchString(new) {
+		return nil, fmt.Errorf("invalid id %q: use up to 16 hex digits", new)
+	}
+	if new == old {
+		return nil, fmt.Errorf("quest already has id %s", new)
+	}
+	if qb.idInUse(new) {
+		return nil, fmt.Errorf("id %s is already in use", new)
+	}
+
+	refs := qb.questIDRefs(old)
+	names := map[string]bool{q.Chapter.Name: true}
+	for _, d := range refs.Dependents {
+		names[d.Chapter.Name] = true
+	}
+	for c := range refs.Links {
+		names[c.Name] = true
+	}
+	files := make(map[string]any)
+	for name := range names {
+		path := qb.chapterPath(name)
+		ch, err := NewChapterFromPath(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, cq := range ch.Quests {
+			if cq.ID == old {
+				cq.ID = new
+				cq.raw["id"] = new
+			}
+			for i, d := range cq.Dependencies {
+				if d == old {
+					cq.Dependencies[i] = new
+				}
+			}
+		}
+		for _, l := range ch.QuestLinks {
+			if m, ok := l.(map[string]any); ok && M(m).GetString("linked_quest") == old {
+				m["linked_quest"] = new
+			}
+		}
+		ch.Sync()
+		files[path] = ch.raw
+	}
+
+	// reward table entries can hold any reward, so look at every value
+	tables, _ := filepath.Glob(filepath.Join(qb.root, "quests", "reward_tables", "*.snbt"))
+	for _, path := range tables {
+		// most tables don't mention the quest; only decode those that do
+		found, err := fileHasString(path, old)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if !found {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		v, err := snbt.Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if v, n := replaceString(v, old, new); n > 0 {
+			files[path] = v
+		}
+	}
+
+	if err := writeSNBTFiles(files); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// questRenameID handles POST "/chapter/{chapter}/{quest}/rename-id".
+func (a *App) questRenameID(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cname := chi.URLParam(r, "chapter")
+	qid := chi.URLParam(r, "quest")
+	q, ok := qb.questMap[qid]
+	if !ok || q.Chapter.Name != cname {
+		http.NotFound(w, r)
+		return
+	}
+	id := strings.ToUpper(strings.TrimSpace(r.FormValue("id")))
+	paths, err := qb.RenameQuestID(qid, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "rename quest id", cname, []string{id}, fmt.Sprintf("%s → %s (%d files)", qid, id, len(paths)))
+	a.reload()
+	msg := fmt.Sprintf("Renamed %s to %s in %d files.", qid, id, len(paths))
+	http.Redirect(w, r, "/chapter/"+cname+"/"+id+"?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// fileHasString reports whether the SNBT file at path has the string value s,
+// reading it a token at a time.
+func fileHasString(path, s string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	d := snbt.NewDecoder(f)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if v, ok := tok.(string); ok && v == s {
+			return true, nil
+		}
+	}
+}
+
+// apiNewID handles GET "/api/newid", ids for new objects that aren't in use
+// in the book; ?n= asks for up to 100 at once.
+func (a *App) apiNewID(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 || n > 100 {
+		n = 1
+	}
+	src := a.QB().idSource()
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = src.Next()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "id": ids[0], "ids": ids})
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRenameQuestID(t *testing.T) {
+	a := testApp(t)
+	qb := a.QB()
+	var dep *Quest
+	for _, q := range qb.Quests {
+		if len(qb.dependents(q.ID)) > 0 {
+			dep = q
+			break
+		}
+	}
+	if dep == nil {
+		t.Skip("no quest with dependents")
+	}
+	old := dep.ID
+	dependents := qb.dependents(old)
+
+	tables := filepath.Join(a.Root(), "quests", "reward_tables")
+	if err := os.MkdirAll(tables, 0755); err != nil {
+		t.Fatal(err)
+	}
+	table := filepath.Join(tables, "loot.snbt")
+	if err := os.WriteFile(table, []byte(`{ id: "0000000000000ABC", rewards: [ { note: "`+old+`" } ] }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/chapter/test/"+old+"/rename-id", strings.NewReader(url.Values{"id": {id}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	for _, bad := range []string{"not hex", old, dependents[0].ID} {
+		if rec := post(bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("rename to %q: %d", bad, rec.Code)
+		}
+	}
+	if rec := post("abcdef0123456789"); rec.Code != http.StatusSeeOther {
+		t.Fatalf("rename: %d %s", rec.Code, rec.Body)
+	}
+
+	qb = a.QB()
+	if _, ok := qb.questMap[old]; ok {
+		t.Error("old id still loaded")
+	}
+	if _, ok := qb.questMap["ABCDEF0123456789"]; !ok {
+		t.Fatal("renamed quest not loaded")
+	}
+	for _, d := range dependents {
+		if q := qb.questMap[d.ID]; !slices.Contains(q.Dependencies, "ABCDEF0123456789") || slices.Contains(q.Dependencies, old) {
+			t.Errorf("%s dependencies = %v", d.ID, q.Dependencies)
+		}
+	}
+	b, err := os.ReadFile(table)
+	if err != nil || !strings.Contains(string(b), "ABCDEF0123456789") || strings.Contains(string(b), old) {
+		t.Errorf("reward table = %s, %v", b, err)
+	}
+}
+
+func TestAPINewID(t *testing.T) {
+	a := testApp(t)
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/api/newid?n=5", nil))
+	var res struct {
+		ID  string
+		IDs []string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("%d %s: %v", rec.Code, rec.Body, err)
+	}
+	if len(res.IDs) != 5 || res.ID != res.IDs[0] {
+		t.Fatalf("got %+v", res)
+	}
+	for _, id := range res.IDs {
+		if !validQuestID.MatchString(id) || len(id) != 16 || a.QB().idInUse(id) {
+			t.Errorf("id %q", id)
+		}
+	}
+	if id := a.QB().Quests[0].ID; !a.QB().idInUse(id) {
+		t.Errorf("quest id %s not in use", id)
+	}
+}
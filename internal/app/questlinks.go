@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// QuestLink is a quest link in a chapter: a compound in its quest_links list,
+// with its own id, the linked_quest and a position. A link shows a quest of
+// another chapter at a position of its own, so a quest that belongs in
+// several places can be reached from each without copying it.
+type QuestLink struct {
+	ID string
+	// QuestID is the linked quest's id, and Quest the quest, or nil if it
+	// isn't in the book.
+	QuestID string
+	Quest   *Quest
+	X, Y    float64
+}
+
+// chapterLinks returns the quest links of ch, with their quests.
+func (qb *QuestBook) chapterLinks(ch *Chapter) []QuestLink {
+	var links []QuestLink
+	for _, l := range ch.QuestLinks {
+		m, ok := l.(map[string]any)
+		if !ok {
+			continue
+		}
+		link := QuestLink{ID: M(m).GetString("id"), QuestID: M(m).GetString("linked_quest"), X: M(m).GetFloat("x"), Y: M(m).GetFloat("y")}
+		link.Quest = qb.questMap[link.QuestID]
+		links = append(links, link)
+	}
+	return links
+}
+
+// linkPosition returns where a new link goes in ch: right of everything
+// already in it, level with its topmost quest.
+func linkPosition(ch *Chapter) (x, y float64) {
+	maxX, minY := math.Inf(-1), math.Inf(1)
+	for _, q := range ch.Quests {
+		maxX, minY = max(maxX, q.X), min(minY, q.Y)
+	}
+	for _, l := range ch.QuestLinks {
+		if m, ok := l.(map[string]any); ok {
+			maxX, minY = max(maxX, M(m).GetFloat("x")), min(minY, M(m).GetFloat("y"))
+		}
+	}
+	if math.IsInf(maxX, -1) {
+		return 0, 0
+	}
+	return maxX + 2, minY
+}
+
+// AddQuestLink links the quest id into the chapter named chapter at x, y, or
+// next to the chapter's quests if at is false, and returns the link's id.
+func (qb *QuestBook) AddQuestLink(chapter, id string, x, y float64, at bool) (string, error) {
+	q, ok := qb.questMap[id]
+	if !ok {
+		return "", fmt.Errorf("unknown quest %s", id)
+	}
+	if qb.chapterMap[chapter] == nil {
+		return "", fmt.Errorf("unknown chapter %q", chapter)
+	}
+	if q.Chapter.Name == chapter {
+		return "", fmt.Errorf("quest %s is already in %s", id, chapter)
+	}
+	ch, err := NewChapterFromPath(qb.chapterPath(chapter))
+	if err != nil {
+		return "", fmt.Errorf("open chapter %s: %w", chapter, err)
+	}
+	ch.resolveLang(qb.Lang)
+	for _, l := range qb.chapterLinks(ch) {
+		if l.QuestID == id {
+			return "", fmt.Errorf("quest %s is already linked in %s", id, chapter)
+		}
+	}
+	if !at {
+		x, y = linkPosition(ch)
+	}
+	link := map[string]any{
+		"id":           qb.idSource().Next(),
+		"linked_quest": id,
+		"x":            decimalValue(x),
+		"y":            decimalValue(y),
+	}
+	ch.QuestLinks = append(ch.QuestLinks, link)
+	ch.raw["quest_links"] = ch.QuestLinks
+	return link["id"].(string), saveChapter(ch, qb.chapterPath(chapter), qb.Lang)
+}
+
+// RemoveQuestLink removes the quest link id from the chapter named chapter.
+func (qb *QuestBook) RemoveQuestLink(chapter, id string) error {
+	if qb.chapterMap[chapter] == nil {
+		return fmt.Errorf("unknown chapter %q", chapter)
+	}
+	ch, err := NewChapterFromPath(qb.chapterPath(chapter))
+	if err != nil {
+		return fmt.Errorf("open chapter %s: %w", chapter, err)
+	}
+	ch.resolveLang(qb.Lang)
+	n := len(ch.QuestLinks)
+	ch.QuestLinks = slices.DeleteFunc(ch.QuestLinks, func(l any) bool {
+		m, ok := l.(map[string]any)
+		return ok && M(m).GetString("id") == id
+	})
+	if len(ch.QuestLinks) == n {
+		return fmt.Errorf("no quest link %s in %s", id, chapter)
+	}
+	ch.raw["quest_links"] = ch.QuestLinks
+	return saveChapter(ch, qb.chapterPath(chapter), qb.Lang)
+}
+
+// chapterLinkAdd handles POST "/chapter/{chapter}/links", which links the
+// quest "quest" into the chapter, at "x" and "y" if they are given.
+func (a *App) chapterLinkAdd(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cname := chi.URLParam(r, "chapter")
+	id := strings.ToUpper(strings.TrimSpace(r.FormValue("quest")))
+	var x, y float64
+	at := false
+	if xs, ys := strings.TrimSpace(r.FormValue("x")), strings.TrimSpace(r.FormValue("y")); xs != "" || ys != "" {
+		var errX, errY error
+		x, errX = strconv.ParseFloat(xs, 64)
+		y, errY = strconv.ParseFloat(ys, 64)
+		if errX != nil || errY != nil {
+			http.Error(w, "the position needs a number for both x and y", http.StatusBadRequest)
+			return
+		}
+		at = true
+	}
+	link, err := qb.AddQuestLink(cname, id, x, y, at)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "add quest link", cname, []string{id}, "link "+link)
+	a.reload()
+	msg := fmt.Sprintf("Linked %s into this chapter.", id)
+	http.Redirect(w, r, "/chapter/"+cname+"?msg="+url.QueryEscape(msg)+"#links", http.StatusSeeOther)
+}
+
+// chapterLinkRemove handles POST "/chapter/{chapter}/links/{link}/delete".
+func (a *App) chapterLinkRemove(w http.ResponseWriter, r *http.Request) {
+	cname := chi.URLParam(r, "chapter")
+	link := chi.URLParam(r, "link")
+	if err := a.QB().RemoveQuestLink(cname, link); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "remove quest link", cname, nil, "link "+link)
+	a.reload()
+	http.Redirect(w, r, "/chapter/"+cname+"?msg="+url.QueryEscape("Removed the quest link.")+"#links", http.StatusSeeOther)
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestQuestLinks(t *testing.T) {
+	a := testApp(t)
+	if _, err := a.QB().CreateChapter("other", "Other", ""); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	q := a.QB().chapterMap["test"].Quests[0]
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	get := func(path string) string {
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Body.String()
+	}
+
+	if rec := post("/chapter/other/links", url.Values{"quest": {q.ID}, "x": {"3.5"}, "y": {"-1"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("add: %d %s", rec.Code, rec.Body)
+	}
+	links := a.QB().chapterLinks(a.QB().chapterMap["other"])
+	if len(links) != 1 || links[0].Quest == nil || links[0].Quest.ID != q.ID || links[0].X != 3.5 || links[0].Y != -1 {
+		t.Fatalf("links = %+v", links)
+	}
+	if refs := a.QB().questIDRefs(q.ID); refs.LinkCount() != 1 {
+		t.Errorf("link count = %d", refs.LinkCount())
+	}
+	if body := get("/chapter/other"); !strings.Contains(body, `/links/`+links[0].ID+`/delete`) || !strings.Contains(body, `href="/chapter/test/`+q.ID+`"`) {
+		t.Error("chapter page doesn't list the link")
+	}
+	if body := get("/chapter/test/" + q.ID); !strings.Contains(body, `href="/chapter/other#links"`) {
+		t.Error("quest page doesn't show where it is linked")
+	}
+
+	for _, bad := range []url.Values{
+		{"quest": {q.ID}},
+		{"quest": {"0000000000000BAD"}},
+		{"quest": {q.ID}, "x": {"1"}},
+	} {
+		if rec := post("/chapter/other/links", bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("add %v: %d", bad, rec.Code)
+		}
+	}
+	if rec := post("/chapter/test/links", url.Values{"quest": {q.ID}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("link into its own chapter: %d", rec.Code)
+	}
+
+	// without a position it goes right of the chapter's contents
+	other := a.QB().chapterMap["test"].Quests[1]
+	if rec := post("/chapter/other/links", url.Values{"quest": {other.ID}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("add: %d %s", rec.Code, rec.Body)
+	}
+	links = a.QB().chapterLinks(a.QB().chapterMap["other"])
+	if len(links) != 2 || links[1].X != 5.5 || links[1].Y != -1 {
+		t.Errorf("links = %+v", links)
+	}
+
+	if rec := post("/chapter/other/links/"+links[0].ID+"/delete", nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("remove: %d %s", rec.Code, rec.Body)
+	}
+	if rec := post("/chapter/other/links/"+links[0].ID+"/delete", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("remove again: %d", rec.Code)
+	}
+	if links := a.QB().chapterLinks(a.QB().chapterMap["other"]); len(links) != 1 || links[0].QuestID != other.ID {
+		t.Errorf("after removing: %+v", links)
+	}
+}
@@ -1,7 +1,7 @@
 package app
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/qbedit/snbt"
 )
@@ -21,6 +22,8 @@ type QuestBook struct {
 	Quests   []*Quest
 	Chapters []*Chapter
 	Groups   []*Group
+	// Tables are the reward tables, by title; see rewardtables.go
+	Tables []*RewardTable
 
 	// questMap maps a quest ID to a quest
 	questMap map[string]*Quest
@@ -28,15 +31,42 @@ type QuestBook struct {
 	chapterMap map[string]*Chapter
 	// groupMap maps a group "ID" to a group
 	groupMap map[string]*Group
+	// tableMap maps a reward table's Key to the table
+	tableMap map[string]*RewardTable
+
+	// Failures are the parts of the book that couldn't be loaded.
+	Failures []Failure
+
+	// Stats records file sizes and parse times from loading.
+	Stats *LoadStats
+	// Lang is the book's lang file, if its text uses translation keys; see
+	// langfile.go
+	Lang *LangFile
+
+	// cache decodes chapter files, or is nil
+	cache *parseCache
+	// quick indexes the titles for quick open; see quickopen.go
+	quick *quickIndex
+	// text indexes the quests' text for searching; see textindex.go
+	text *textIndex
 }
 
 // NewQuestBook instantiates a questbook from a path.
 func NewQuestBook(path string) (*QuestBook, error) {
+	return loadQuestBook(path, nil)
+}
+
+// loadQuestBook loads the questbook at path, decoding its chapters through
+// cache, which may be nil; see bookcache.go.
+func loadQuestBook(path string, cache *parseCache) (*QuestBook, error) {
+	start := time.Now()
 	qb := &QuestBook{
 		root:       path,
+		cache:      cache,
 		questMap:   make(map[string]*Quest),
 		chapterMap: make(map[string]*Chapter),
 		groupMap:   make(map[string]*Group),
+		Stats:      newLoadStats(loadStatsTop),
 	}
 
 	// Load group definitions if present
@@ -48,6 +78,9 @@ func NewQuestBook(path string) (*QuestBook, error) {
 	if err := qb.loadChapters(); err != nil {
 		return nil, err
 	}
+	if err := qb.loadTables(); err != nil {
+		return nil, err
+	}
 
 	// add global accounting for quests and chapters
 	// XXX: should we order the chapters first?
@@ -81,9 +114,24 @@ func NewQuestBook(path string) (*QuestBook, error) {
 	// XXX: chapters could be sorted by their appearance in the quest book but
 	// that's a bit tricky
 	sort.Slice(qb.Chapters, func(i, j int) bool { return qb.Chapters[i].Title < qb.Chapters[j].Title })
+	qb.Stats.done(time.Since(start))
 	return qb, nil
 }
 
+// loadLang loads the lang file at path and resolves the translation keys in
+// the book's chapter titles and quest text.
+func (q *QuestBook) loadLang(path string) error {
+	l, err := LoadLangFile(path)
+	if err != nil {
+		return err
+	}
+	q.Lang = l
+	for _, ch := range q.Chapters {
+		ch.resolveLang(l)
+	}
+	return nil
+}
+
 func (q *QuestBook) TopItems() []*TopItem {
 	// Convert pointers to value slices for existing builder
 	return buildTopItems(q.Groups, q.Chapters)
@@ -97,11 +145,17 @@ func (q *QuestBook) loadGroups() error {
 	}
 	defer f.Close()
 
+	start := time.Now()
 	groups, err := scanGroups(f)
 	if err != nil {
 		return err
 	}
 	q.Groups = groups
+	fs := FileStat{Name: "chapter_groups.snbt", Parse: time.Since(start)}
+	if info, err := f.Stat(); err == nil {
+		fs.Size = info.Size()
+	}
+	q.Stats.add(fs)
 
 	groupMap := make(map[string]*Group)
 	for _, g := range q.Groups {
@@ -126,10 +180,29 @@ func (q *QuestBook) loadChapters() error {
 		if e.IsDir() || !strings.HasSuffix(e.Name(), ".snbt") {
 			continue
 		}
-		c, err := NewChapterFromPath(filepath.Join(dir, e.Name()))
+		start := time.Now()
+		path := filepath.Join(dir, e.Name())
+		c, skipped, cached, err := loadChapter(path, q.cache)
 		if err != nil {
-			return err
+			// the rest of the book still loads; the failure is shown on
+			// the errors page
+			slog.Warn("loading chapter", "path", path, "error", err)
+			f := Failure{Name: "chapters/" + e.Name(), Path: path, Err: err.Error()}
+			var pe *snbt.ParseError
+			if errors.As(err, &pe) {
+				f.Line, f.Snippet = pe.Line, pe.Snippet
+			}
+			q.Failures = append(q.Failures, f)
+			continue
+		}
+		for _, pe := range skipped {
+			q.Failures = append(q.Failures, Failure{Name: "chapters/" + e.Name(), Path: path, Err: pe.Error(), Chapter: c.Name, Line: pe.Line, Snippet: pe.Snippet})
+		}
+		fs := FileStat{Name: "chapters/" + e.Name(), Parse: time.Since(start), Cached: cached}
+		if info, err := e.Info(); err == nil {
+			fs.Size = info.Size()
 		}
+		q.Stats.add(fs)
 		chapters = append(chapters, c)
 		chapterMap[c.Name] = c
 	}
@@ -141,24 +214,50 @@ func (q *QuestBook) loadChapters() error {
 
 // Quest represents a single quest entry within a Chapter.
 //
-// For now, we leave quests unmodeled since different quest types carry
-// different fields. This gives us flexibility to iterate on the exact
-// structure once we begin editing/creating quests. The rendering layer
-// can use type assertions or helpers to access common fields like
-// "title" and "id" when present.
-//
-// Future: define a concrete struct per quest type (e.g., CheckmarkTask,
-// ItemTask, etc.) and decode into the appropriate type based on the
-// "type" field.
+// Only the fields we edit are modeled; everything else stays in raw so that
+// saving a quest doesn't drop data we don't understand. Tasks and rewards are
+// decoded into concrete types based on their "type" field (see tasks.go and
+// rewards.go).
 type Quest struct {
 	raw         map[string]any
 	ID          string
 	Title       string
 	Subtitle    string
 	Description string
+	// Dependencies are the ids of quests that must be completed first.
+	Dependencies []string
+	// MinRequired is how many Dependencies must be completed; 0 means all.
+	MinRequired int
+	// Repeatable quests can be completed again Cooldown ticks after their
+	// rewards are claimed (see repeat.go).
+	Repeatable bool
+	Cooldown   int
+	Tasks      []Task
+	Rewards    []Reward
+	// X and Y are the quest's position on its chapter's grid. Size scales
+	// the quest (1 when unset) and Shape is "" for the chapter's default.
+	X, Y  float64
+	Size  float64
+	Shape string
 
 	// Backlink to this quest's Chapter for sync/saving
 	Chapter *Chapter
+
+	// langKeys are the translation keys of text fields resolved from the
+	// book's lang file, or nil.
+	langKeys *questLangKeys
+}
+
+// DisplayShape returns the shape the quest is drawn with in game: its own,
+// its chapter's default or the book's, a circle.
+func (q Quest) DisplayShape() string {
+	switch {
+	case q.Shape != "":
+		return q.Shape
+	case q.Chapter != nil && q.Chapter.DefaultShape != "" && q.Chapter.DefaultShape != "default":
+		return q.Chapter.DefaultShape
+	}
+	return "circle"
 }
 
 // GetTitle returns the preferred display title for the quest.
@@ -192,6 +291,20 @@ func (q Quest) GetTitle() string {
 	return ""
 }
 
+// IconItem returns the id of the item shown as the quest's icon: its icon if
+// it has one, otherwise the item of its first item task, as in game.
+func (q Quest) IconItem() string {
+	if s := itemToString(q.raw["icon"]); s != "" {
+		return s
+	}
+	for _, t := range q.Tasks {
+		if it, ok := t.(*ItemTask); ok {
+			return it.Item
+		}
+	}
+	return ""
+}
+
 func itemToString(v any) string {
 	switch x := v.(type) {
 	case string:
@@ -253,6 +366,37 @@ func NewQuest(raw any) (*Quest, error) {
 		q.Description = strings.Join(ss, "\n")
 	}
 
+	q.Dependencies = m.GetStrings("dependencies")
+	q.MinRequired = m.GetInt("min_required_dependencies")
+	q.Repeatable = m.GetBool("can_repeat")
+	q.Cooldown = m.GetInt("repeat_cooldown")
+	q.X, q.Y = m.GetFloat("x"), m.GetFloat("y")
+	q.Size = m.GetFloat("size")
+	if q.Size <= 0 {
+		q.Size = 1
+	}
+	if q.Shape = m.GetString("shape"); q.Shape == "default" {
+		q.Shape = ""
+	}
+
+	for _, tv := range m.GetAnys("tasks") {
+		t, err := NewTask(tv)
+		if err != nil {
+			slog.Error("error loading task", "quest", q.ID, "task", tv)
+			continue
+		}
+		q.Tasks = append(q.Tasks, t)
+	}
+
+	for _, rv := range m.GetAnys("rewards") {
+		r, err := NewReward(rv)
+		if err != nil {
+			slog.Error("error loading reward", "quest", q.ID, "reward", rv)
+			continue
+		}
+		q.Rewards = append(q.Rewards, r)
+	}
+
 	return q, nil
 }
 
@@ -276,6 +420,54 @@ func (q *Quest) Sync() {
 	} else {
 		delete(q.raw, "description")
 	}
+	// dependencies, tasks and rewards are kept as (possibly empty) lists if
+	// they were present
+	if _, ok := q.raw["dependencies"]; ok || len(q.Dependencies) > 0 {
+		q.raw["dependencies"] = stringsToAnySlice(q.Dependencies)
+	}
+	// positions keep their SNBT number type, and aren't rewritten unless moved
+	for key, v := range map[string]float64{"x": q.X, "y": q.Y} {
+		if old, ok := q.raw[key]; (ok || v != 0) && M(q.raw).GetFloat(key) != v {
+			q.raw[key] = floatLike(old, v)
+		}
+	}
+	if q.MinRequired > 0 {
+		if M(q.raw).GetInt("min_required_dependencies") != q.MinRequired {
+			M(q.raw).SetInt("min_required_dependencies", q.MinRequired)
+		}
+	} else {
+		delete(q.raw, "min_required_dependencies")
+	}
+	if q.Repeatable {
+		if !M(q.raw).GetBool("can_repeat") {
+			M(q.raw).SetBool("can_repeat", true)
+		}
+	} else {
+		delete(q.raw, "can_repeat")
+	}
+	if q.Cooldown > 0 {
+		if M(q.raw).GetInt("repeat_cooldown") != q.Cooldown {
+			M(q.raw).SetInt("repeat_cooldown", q.Cooldown)
+		}
+	} else {
+		delete(q.raw, "repeat_cooldown")
+	}
+	if _, ok := q.raw["tasks"]; ok || len(q.Tasks) > 0 {
+		tasks := make([]any, 0, len(q.Tasks))
+		for _, t := range q.Tasks {
+			t.Sync()
+			tasks = append(tasks, t.Base().raw)
+		}
+		q.raw["tasks"] = tasks
+	}
+	if _, ok := q.raw["rewards"]; ok || len(q.Rewards) > 0 {
+		rewards := make([]any, 0, len(q.Rewards))
+		for _, r := range q.Rewards {
+			r.Sync()
+			rewards = append(rewards, r.Base().raw)
+		}
+		q.raw["rewards"] = rewards
+	}
 }
 
 // Chapter models a quest chapter file.
@@ -290,6 +482,8 @@ type Chapter struct {
 	Icon       string
 	Subtitle   []string
 	QuestLinks []any
+	// DefaultShape is the shape of quests without their own, or "".
+	DefaultShape string
 	// Group and ordering
 	GroupID    string
 	OrderIndex int
@@ -300,6 +494,13 @@ type Chapter struct {
 
 	// map of quest id -> quest
 	questMap map[string]*Quest
+
+	// titleKey is the translation key Title was resolved from, if any.
+	titleKey string
+
+	// Skipped are the quests and other list entries of the file that didn't
+	// parse and were left out when it was loaded.
+	Skipped []*snbt.ParseError
 }
 
 // TODO: clean up the constructors of Chapter
@@ -319,6 +520,7 @@ func NewChapter(rm map[string]any) *Chapter {
 	ch.Filename = m.GetString("filename")
 	ch.Icon = m.GetString("icon")
 	ch.GroupID = m.GetString("group")
+	ch.DefaultShape = m.GetString("default_quest_shape")
 
 	if oi, ok := m["order_index"]; ok {
 		switch n := oi.(type) {
@@ -350,7 +552,6 @@ func NewChapter(rm map[string]any) *Chapter {
 
 // NewChapterFromPath creates a new chapter from the snbt file at path.
 func NewChapterFromPath(path string) (*Chapter, error) {
-	fallback := strings.TrimSuffix(filepath.Base(path), ".snbt")
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -360,6 +561,33 @@ func NewChapterFromPath(path string) (*Chapter, error) {
 	if err != nil {
 		return nil, err
 	}
+	return chapterFromValue(path, v)
+}
+
+// loadChapter loads the chapter file at path for viewing, decoding it
+// through cache, which may be nil, and reports whether it was cached. Quests
+// that don't parse are left out and returned, rather than hiding the whole
+// chapter. Edits read the file again with NewChapterFromPath, which refuses
+// it until it is fixed, so saving can't drop the skipped quests.
+func loadChapter(path string, cache *parseCache) (*Chapter, []*snbt.ParseError, bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	v, skipped, cached, err := cache.decode(b)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	ch, err := chapterFromValue(path, v)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	ch.Skipped = skipped
+	return ch, skipped, cached, nil
+}
+
+func chapterFromValue(path string, v any) (*Chapter, error) {
+	fallback := strings.TrimSuffix(filepath.Base(path), ".snbt")
 	m, ok := v.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("chapter at %s: expected compound, got %T", path, v)
@@ -401,21 +629,14 @@ func (ch *Chapter) Sync() {
 // Save writes this chapter to path. The Chapter is sync'd first.
 func (ch *Chapter) Save(path string) error {
 	ch.Sync()
-
-	var buf bytes.Buffer
-	if err := snbt.Encode(&buf, ch.raw); err != nil {
-		return err
-	}
-
-	// TODO: preserve permissions?
-	return os.WriteFile(path, buf.Bytes(), 0644)
+	return writeSNBT(path, ch.raw)
 }
 
 // Group organizes chapters under a heading.
 type Group struct {
-	ID       string
-	Title    string
-	Chapters []*Chapter
+	ID       string     `snbt:"id"`
+	Title    string     `snbt:"title"`
+	Chapters []*Chapter `snbt:"-"`
 }
 
 type ItemType int
@@ -435,29 +656,21 @@ type TopItem struct {
 
 // scanGroups decodes a chapter_groups.snbt stream and returns groups in file order.
 func scanGroups(r io.Reader) ([]*Group, error) {
-	v, err := snbt.Decode(r)
+	b, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
-	rm, ok := v.(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf("chapter_groups: expecting a compound, found %v", v)
+	var file struct {
+		Groups []*Group `snbt:"chapter_groups"`
 	}
-
-	m := M(rm)
-	arr := m.GetAnys("chapter_groups")
-	groups := make([]*Group, 0, len(arr))
-
-	for _, it := range arr {
-		mm, ok := it.(map[string]any)
-		if !ok {
-			continue
-		}
-		g := &Group{ID: M(mm).GetString("id"), Title: M(mm).GetString("title")}
-		if g.ID == "" {
-			continue
+	if err := snbt.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("chapter_groups: %w", err)
+	}
+	groups := make([]*Group, 0, len(file.Groups))
+	for _, g := range file.Groups {
+		if g.ID != "" {
+			groups = append(groups, g)
 		}
-		groups = append(groups, g)
 	}
 	return groups, nil
 }
@@ -483,7 +696,9 @@ func buildTopItems(groups []*Group, chapters []*Chapter) []*TopItem {
 	items := make([]*TopItem, len(ungrouped)+len(groups))
 
 	for i := range len(items) {
-		if len(ungrouped) > 0 && ungrouped[0].OrderIndex == i {
+		// chapters are also emitted once the groups run out, as their
+		// order_index values can leave gaps
+		if len(ungrouped) > 0 && (ungrouped[0].OrderIndex == i || len(groups) == 0) {
 			items[i] = &TopItem{
 				Kind:    "chapter",
 				Chapter: ungrouped[0],
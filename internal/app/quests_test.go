@@ -2,6 +2,12 @@ package app
 
 import (
 	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jmoiron/qbedit/snbt"
@@ -187,3 +193,201 @@ func TestQuestSyncRoundTrip(t *testing.T) {
 		t.Fatalf("description mismatch: got %q want %q", q2.Description, q1.Description)
 	}
 }
+
+func TestChapterSyncKeepsTasksAndRewards(t *testing.T) {
+	path := filepath.Join("..", "..", "snbt", "test_chapter.snbt")
+	if _, err := os.Stat(path); err != nil {
+		t.Skip("test_chapter.snbt not present; skipping")
+	}
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		t.Fatalf("load chapter: %v", err)
+	}
+	encode := func(q *Quest) string {
+		var buf bytes.Buffer
+		for _, k := range []string{"tasks", "rewards"} {
+			if v, ok := q.raw[k]; ok {
+				if err := snbt.Encode(&buf, v); err != nil {
+					t.Fatalf("encode %s: %v", k, err)
+				}
+			}
+		}
+		return buf.String()
+	}
+	before := make(map[string]string)
+	for _, q := range ch.Quests {
+		before[q.ID] = encode(q)
+	}
+	ch.Sync()
+	for _, q := range ch.Quests {
+		if got := encode(q); got != before[q.ID] {
+			t.Errorf("quest %s: sync changed tasks/rewards\nbefore: %s\nafter:  %s", q.ID, before[q.ID], got)
+		}
+	}
+}
+
+func TestSetDependencies(t *testing.T) {
+	qb := &QuestBook{questMap: map[string]*Quest{
+		"A": {ID: "A", raw: map[string]any{}},
+		"B": {ID: "B", raw: map[string]any{}},
+		"C": {ID: "C", raw: map[string]any{}},
+	}}
+	q := qb.questMap["C"]
+	if err := setDependencies(qb, q, []string{"B", " A ", "B", ""}, 1); err != nil {
+		t.Fatalf("setDependencies: %v", err)
+	}
+	if got := strings.Join(q.Dependencies, ","); got != "B,A" {
+		t.Fatalf("dependencies: got %s want B,A", got)
+	}
+	q.Sync()
+	if got := M(q.raw).GetInt("min_required_dependencies"); got != 1 {
+		t.Fatalf("min_required_dependencies: got %d want 1", got)
+	}
+
+	for _, tc := range []struct {
+		ids []string
+		min int
+	}{
+		{[]string{"A"}, 2},
+		{[]string{"C"}, 0},
+		{[]string{"Z"}, 0},
+		{[]string{"A"}, -1},
+	} {
+		if err := setDependencies(qb, q, tc.ids, tc.min); err == nil {
+			t.Errorf("expected error for %v min %d", tc.ids, tc.min)
+		}
+	}
+}
+
+func TestSetDependenciesDangling(t *testing.T) {
+	qb := &QuestBook{questMap: map[string]*Quest{
+		"A": {ID: "A", raw: map[string]any{}},
+		"B": {ID: "B", raw: map[string]any{}, Dependencies: []string{"GONE", "A"}},
+	}}
+	q := qb.questMap["B"]
+	// the quest page posts every dependency back with the rest of the form
+	form := url.Values{"title": {"Renamed"}, "dependencies": {"1"}, "dependency": {"GONE", "A"}}
+	if err := applyQuestForm(qb, q, form); err != nil {
+		t.Fatalf("saving a quest with a dangling dependency: %v", err)
+	}
+	if q.Title != "Renamed" || strings.Join(q.Dependencies, ",") != "GONE,A" {
+		t.Errorf("quest = %q, %v", q.Title, q.Dependencies)
+	}
+	if err := setDependencies(qb, q, []string{"GONE", "A", "NEW"}, 0); err == nil {
+		t.Error("an unknown dependency can be added")
+	}
+	if err := setDependencies(qb, q, []string{"A"}, 0); err != nil || strings.Join(q.Dependencies, ",") != "A" {
+		t.Errorf("dropping the dangling dependency: %v, %v", err, q.Dependencies)
+	}
+	if err := setDependencies(qb, q, []string{"GONE"}, 0); err == nil {
+		t.Error("a dropped dangling dependency can be added back")
+	}
+}
+
+func TestChapterName(t *testing.T) {
+	for title, want := range map[string]string{
+		"&6Ore Processing":     "ore_processing",
+		"  The Age of  Tech! ": "the_age_of_tech",
+		"Chapter 2: Power":     "chapter_2_power",
+	} {
+		if got := chapterName(title); got != want {
+			t.Errorf("chapterName(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestLoadChapterLenient(t *testing.T) {
+	a := testApp(t)
+	path := filepath.Join(a.Root(), "quests", "chapters", "broken.snbt")
+	src := "{\n\tid: \"00000000000B0001\"\n\tfilename: \"broken\"\n\ttitle: \"Broken\"\n\tquests: [\n" +
+		"\t\t{ id: \"00000000000B0002\", title: \"Fine\" }\n" +
+		"\t\t{ id: \"00000000000B0003\", title: \"Broken }\n" +
+		"\t]\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	qb := a.QB()
+	ch := qb.chapterMap["broken"]
+	if ch == nil || len(ch.Quests) != 1 || ch.Quests[0].Title != "Fine" {
+		t.Fatalf("broken chapter = %+v", ch)
+	}
+	if len(qb.Failures) != 1 || qb.Failures[0].Chapter != "broken" || qb.Failures[0].Line != 7 {
+		t.Errorf("failures = %+v", qb.Failures)
+	}
+	// edits read the file strictly, so they can't drop the broken quest
+	if _, err := NewChapterFromPath(path); err == nil {
+		t.Error("strict load of a broken chapter succeeded")
+	}
+}
+
+func TestLoadChapterFailure(t *testing.T) {
+	a := testApp(t)
+	path := filepath.Join(a.Root(), "quests", "chapters", "bad.snbt")
+	if err := os.WriteFile(path, []byte("{\n\tid: \"00000000000B0001\"\n\ttitle: }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	qb := a.QB()
+	if qb.chapterMap["test"] == nil || qb.chapterMap["bad"] != nil {
+		t.Fatalf("chapters = %v", qb.Chapters)
+	}
+	if len(qb.Failures) != 1 || qb.Failures[0].Path != path || qb.Failures[0].Line != 3 || qb.Failures[0].Snippet == "" {
+		t.Fatalf("failures = %+v", qb.Failures)
+	}
+
+	if err := os.WriteFile(path, []byte("{\n\tid: \"00000000000B0001\"\n\ttitle: \"Bad\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("POST", "/errors/retry", nil))
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("retry: %d %s", rec.Code, rec.Body)
+	}
+	if qb := a.QB(); len(qb.Failures) != 0 || qb.chapterMap["bad"] == nil {
+		t.Errorf("after retry: failures = %+v", qb.Failures)
+	}
+}
+
+func TestSuggestDependencies(t *testing.T) {
+	quest := func(id string, tasks []string, rewards []string, deps ...string) *Quest {
+		q := &Quest{ID: id, Title: id, Dependencies: deps, raw: map[string]any{}}
+		for _, it := range tasks {
+			q.Tasks = append(q.Tasks, &ItemTask{Item: it, Count: 1})
+		}
+		for _, it := range rewards {
+			q.Rewards = append(q.Rewards, &ItemReward{Item: it, Count: 1})
+		}
+		return q
+	}
+	qb := &QuestBook{Quests: []*Quest{
+		quest("casing", []string{"mek:steel_casing"}, nil, "start"),
+		quest("start", nil, []string{"minecraft:stick"}),
+		quest("furnace", nil, []string{"mek:steel_casing"}),
+		quest("ingot", []string{"mek:ingot_steel"}, nil),
+		quest("armor", []string{"mek:steel_armor_plating"}, nil),
+		quest("later", []string{"mek:ingot_steel"}, nil, "casing"),
+		quest("wood", []string{"minecraft:oak_planks"}, nil),
+	}}
+	qb.questMap = make(map[string]*Quest)
+	for _, q := range qb.Quests {
+		qb.questMap[q.ID] = q
+	}
+	var got []string
+	for _, s := range suggestDependencies(qb, qb.questMap["casing"], nil) {
+		got = append(got, s.Quest.ID+": "+strings.Join(s.Reasons, "; "))
+	}
+	want := []string{"furnace: rewards mek:steel_casing", "ingot: asks for mek:ingot_steel (steel)"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// with a registry, items it doesn't know aren't used
+	items := NewItemRegistry([]string{"mek:steel_casing", "mek:steel_armor_plating", "minecraft:stick"})
+	if ss := suggestDependencies(qb, qb.questMap["casing"], items); len(ss) != 1 || ss[0].Quest.ID != "furnace" {
+		t.Errorf("with a registry: %+v", ss)
+	}
+	if ss := suggestDependencies(qb, qb.questMap["start"], nil); len(ss) != 0 {
+		t.Errorf("quest without item tasks: %+v", ss)
+	}
+}
@@ -0,0 +1,348 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// questTemplatesDir returns the quest templates dir for the ftbquests dir
+// root.
+func questTemplatesDir(root string) string {
+	return filepath.Join(packDir(root), "templates")
+}
+
+// templateParam matches a {name} placeholder. Translation keys have dots, so
+// they aren't placeholders.
+var templateParam = regexp.MustCompile(`\{([a-z][a-z0-9_]*)\}`)
+
+// validTemplateName is the form of a template's name, which is its file
+// name and is used in urls.
+var validTemplateName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// QuestTemplate is a saved quest template, a quest saved to be created again,
+// like the gating quest every chapter of a pack starts with. Each is an SNBT
+// file in .qbedit/templates holding a quest without its id, position and
+// dependencies, and without the ids of its tasks and rewards. Its text and
+// values may contain {name} placeholders, eg. {item} and {count}, which are
+// filled in when a quest is created from it; a value that is only a
+// placeholder becomes a number when it's given one, so that counts stay
+// numbers.
+type QuestTemplate struct {
+	Name string
+	raw  map[string]any
+	// Params are the template's placeholders in order of first use
+	Params []string
+}
+
+// Title returns the template's quest title.
+func (t *QuestTemplate) Title() string {
+	return M(t.raw).GetString("title")
+}
+
+// Tasks returns the types of the template's tasks.
+func (t *QuestTemplate) Tasks() []string {
+	var types []string
+	for _, v := range M(t.raw).GetAnys("tasks") {
+		if m, ok := v.(map[string]any); ok {
+			types = append(types, M(m).GetString("type"))
+		}
+	}
+	return types
+}
+
+// templateParams appends the placeholders in the strings of v to names.
+func templateParams(v any, names []string) []string {
+	switch x := v.(type) {
+	case string:
+		for _, m := range templateParam.FindAllStringSubmatch(x, -1) {
+			if !slices.Contains(names, m[1]) {
+				names = append(names, m[1])
+			}
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		// the text fields first, so their placeholders are listed first
+		slices.SortFunc(keys, func(a, b string) int {
+			return strings.Compare(templateKeyOrder(a), templateKeyOrder(b))
+		})
+		for _, k := range keys {
+			names = templateParams(x[k], names)
+		}
+	case []any:
+		for _, e := range x {
+			names = templateParams(e, names)
+		}
+	}
+	return names
+}
+
+// templateKeyOrder sorts the text fields of a quest before its other keys.
+func templateKeyOrder(key string) string {
+	if i := slices.Index(searchFields, key); i >= 0 {
+		return strconv.Itoa(i)
+	}
+	return "~" + key
+}
+
+// fillTemplate returns a copy of v with its placeholders replaced by their
+// values in params. Values that are only a placeholder given a number become
+// that number, except in the text fields.
+func fillTemplate(v any, params map[string]string, numbers bool) any {
+	switch x := v.(type) {
+	case string:
+		if m := templateParam.FindStringSubmatch(x); numbers && m != nil && m[0] == x {
+			if n, err := strconv.ParseInt(params[m[1]], 10, 64); err == nil {
+				return n
+			}
+		}
+		return templateParam.ReplaceAllStringFunc(x, func(m string) string {
+			return params[m[1:len(m)-1]]
+		})
+	case map[string]any:
+		c := make(map[string]any, len(x))
+		for k, e := range x {
+			c[k] = fillTemplate(e, params, numbers && !slices.Contains(searchFields, k))
+		}
+		return c
+	case []any:
+		l := make([]any, len(x))
+		for i, e := range x {
+			l[i] = fillTemplate(e, params, numbers)
+		}
+		return l
+	}
+	return copyValue(v)
+}
+
+// readQuestTemplate reads the template called name from dir.
+func readQuestTemplate(dir, name string) (*QuestTemplate, error) {
+	if !validTemplateName.MatchString(name) {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	f, err := os.Open(filepath.Join(dir, name+".snbt"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	v, err := snbt.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("template %s is not a compound", name)
+	}
+	return &QuestTemplate{Name: name, raw: m, Params: templateParams(m, nil)}, nil
+}
+
+// listQuestTemplates reads the templates in dir, sorted by name, and the
+// errors of those that couldn't be read.
+func listQuestTemplates(dir string) ([]*QuestTemplate, []string) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.snbt"))
+	slices.Sort(paths)
+	var list []*QuestTemplate
+	var errs []string
+	for _, p := range paths {
+		t, err := readQuestTemplate(dir, strings.TrimSuffix(filepath.Base(p), ".snbt"))
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		list = append(list, t)
+	}
+	return list, errs
+}
+
+// SaveQuestTemplate saves the quest id as the template called name in dir,
+// replacing any template of that name. With placeholders, the item and count
+// of its first item task become {item} and {count}.
+func (qb *QuestBook) SaveQuestTemplate(dir, name, id string, placeholders bool) error {
+	if !validTemplateName.MatchString(name) {
+		return fmt.Errorf("invalid template name %q: use lowercase letters, digits, - and _", name)
+	}
+	orig, ok := qb.questMap[id]
+	if !ok {
+		return fmt.Errorf("unknown quest %s", id)
+	}
+	// the template is made from the file, with the text of the lang file
+	// if the book has one, so that it doesn't depend on the quest's keys
+	ch, err := NewChapterFromPath(qb.chapterPath(orig.Chapter.Name))
+	if err != nil {
+		return fmt.Errorf("open chapter %s: %w", orig.Chapter.Name, err)
+	}
+	ch.resolveLang(qb.Lang)
+	from, ok := ch.questMap[id]
+	if !ok {
+		return fmt.Errorf("quest %s not found in %s", id, ch.Name)
+	}
+	q, err := NewQuest(copyValue(from.raw))
+	if err != nil {
+		return err
+	}
+	q.Title, q.Subtitle, q.Description = from.Title, from.Subtitle, from.Description
+	q.Sync()
+	for _, key := range []string{"id", "x", "y", "dependencies", "min_required_dependencies"} {
+		delete(q.raw, key)
+	}
+	for _, t := range q.Tasks {
+		delete(t.Base().raw, "id")
+	}
+	for _, r := range q.Rewards {
+		delete(r.Base().raw, "id")
+	}
+	if placeholders {
+		i := slices.IndexFunc(q.Tasks, func(t Task) bool { _, ok := t.(*ItemTask); return ok })
+		if i < 0 {
+			return fmt.Errorf("quest %s has no item task to make placeholders of", id)
+		}
+		raw := q.Tasks[i].Base().raw
+		if m, ok := raw["item"].(map[string]any); ok {
+			m["id"] = "{item}"
+		} else {
+			raw["item"] = "{item}"
+		}
+		raw["count"] = "{count}"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeSNBT(filepath.Join(dir, name+".snbt"), q.raw)
+}
+
+// CreateFromTemplate adds a quest made from t to the end of the chapter
+// named to, with its placeholders filled from params, and returns it. Every
+// placeholder needs a value.
+func (qb *QuestBook) CreateFromTemplate(t *QuestTemplate, to string, params map[string]string) (*Quest, error) {
+	if qb.chapterMap[to] == nil {
+		return nil, fmt.Errorf("unknown chapter %q", to)
+	}
+	var missing []string
+	for _, p := range t.Params {
+		if strings.TrimSpace(params[p]) == "" {
+			missing = append(missing, "{"+p+"}")
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("template %s needs a value for %s", t.Name, strings.Join(missing, ", "))
+	}
+	ch, err := NewChapterFromPath(qb.chapterPath(to))
+	if err != nil {
+		return nil, fmt.Errorf("open chapter %s: %w", to, err)
+	}
+	ch.resolveLang(qb.Lang)
+
+	raw := fillTemplate(t.raw, params, true).(map[string]any)
+	ids := qb.idSource()
+	raw["id"] = ids.Next()
+	x, y := linkPosition(ch)
+	raw["x"], raw["y"] = decimalValue(x), decimalValue(y)
+	for _, key := range []string{"tasks", "rewards"} {
+		for _, v := range M(raw).GetAnys(key) {
+			if m, ok := v.(map[string]any); ok {
+				m["id"] = ids.Next()
+			}
+		}
+	}
+	q, err := NewQuest(raw)
+	if err != nil {
+		return nil, err
+	}
+	q.Chapter = ch
+	ch.Quests = append(ch.Quests, q)
+	ch.questMap[q.ID] = q
+
+	w := newBookWrite(qb.Lang)
+	if err := w.stageChapter(ch, qb.chapterPath(ch.Name)); err != nil {
+		return nil, err
+	}
+	return q, w.commit()
+}
+
+// questTemplates handles GET "/templates", the pack's quest templates.
+func (a *App) questTemplates(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Quest templates")
+	data["Msg"] = r.URL.Query().Get("msg")
+	data["Templates"], data["TemplateErrs"] = listQuestTemplates(questTemplatesDir(a.bookRoot()))
+	data["To"] = r.URL.Query().Get("to")
+	a.render(w, "templates.gohtml", data)
+}
+
+// questTemplateSave handles POST "/chapter/{chapter}/{quest}/template",
+// which saves the quest as the template "name", with {item} and {count}
+// placeholders if "placeholders" is set.
+func (a *App) questTemplateSave(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	qid := chi.URLParam(r, "quest")
+	if q, ok := qb.questMap[qid]; !ok || q.Chapter.Name != chi.URLParam(r, "chapter") {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if err := qb.SaveQuestTemplate(questTemplatesDir(a.bookRoot()), name, qid, r.FormValue("placeholders") != ""); err != nil {
+		http.Error(w, "save template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "save quest template", name, []string{qid}, "")
+	msg := fmt.Sprintf("Saved %s as the template %s.", qid, name)
+	http.Redirect(w, r, "/templates?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// questTemplateCreate handles POST "/templates/{template}/create", which
+// creates a quest from the template in the chapter "to". The placeholders
+// are filled from the "param_<name>" fields.
+func (a *App) questTemplateCreate(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	t, err := readQuestTemplate(questTemplatesDir(a.bookRoot()), chi.URLParam(r, "template"))
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := make(map[string]string)
+	for _, p := range t.Params {
+		params[p] = strings.TrimSpace(r.FormValue("param_" + p))
+	}
+	to := r.FormValue("to")
+	q, err := qb.CreateFromTemplate(t, to, params)
+	if err != nil {
+		http.Error(w, "create quest: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "create quest from template", to, []string{q.ID}, t.Name)
+	a.reload()
+	msg := fmt.Sprintf("Created %s from the template %s.", q.ID, t.Name)
+	http.Redirect(w, r, "/chapter/"+to+"/"+q.ID+"?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// questTemplateDelete handles POST "/templates/{template}/delete".
+func (a *App) questTemplateDelete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "template")
+	if !validTemplateName.MatchString(name) {
+		http.NotFound(w, r)
+		return
+	}
+	if err := os.Remove(filepath.Join(questTemplatesDir(a.bookRoot()), name+".snbt")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.audit(r, "delete quest template", name, nil, "")
+	http.Redirect(w, r, "/templates?msg="+url.QueryEscape("Deleted the template "+name+"."), http.StatusSeeOther)
+}
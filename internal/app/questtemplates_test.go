@@ -0,0 +1,78 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestQuestTemplates(t *testing.T) {
+	a := testApp(t)
+	var src *Quest
+	for _, q := range a.QB().Quests {
+		if slices.ContainsFunc(q.Tasks, func(t Task) bool { _, ok := t.(*ItemTask); return ok }) {
+			src = q
+			break
+		}
+	}
+	if src == nil {
+		t.Skip("test chapter needs a quest with an item task")
+	}
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post("/chapter/test/"+src.ID+"/template", url.Values{"name": {"gate"}, "placeholders": {"1"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("save: %d %s", rec.Code, rec.Body)
+	}
+	dir := questTemplatesDir(a.Root())
+	b, err := os.ReadFile(filepath.Join(dir, "gate.snbt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), src.ID) || !strings.Contains(string(b), `"{count}"`) {
+		t.Errorf("template:\n%s", b)
+	}
+	tpl, err := readQuestTemplate(dir, "gate")
+	if err != nil || !slices.Equal(tpl.Params, []string{"count", "item"}) {
+		t.Fatalf("params %q, %v", tpl.Params, err)
+	}
+
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/templates", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "param_item") {
+		t.Fatalf("templates page: %d", rec.Code)
+	}
+	if rec := post("/templates/gate/create", url.Values{"to": {"test"}, "param_item": {"minecraft:iron_ingot"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("create without a count: %d", rec.Code)
+	}
+	before := len(a.QB().Quests)
+	if rec := post("/templates/gate/create", url.Values{"to": {"test"}, "param_item": {"minecraft:iron_ingot"}, "param_count": {"16"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	qs := a.QB().chapterMap["test"].Quests
+	q := qs[len(qs)-1]
+	if len(a.QB().Quests) != before+1 || q.ID == src.ID || q.GetTitle() != src.GetTitle() || len(q.Dependencies) != 0 {
+		t.Fatalf("created %+v", q)
+	}
+	i := slices.IndexFunc(q.Tasks, func(t Task) bool { _, ok := t.(*ItemTask); return ok })
+	if it := q.Tasks[i].(*ItemTask); it.Item != "minecraft:iron_ingot" || it.Count != 16 || it.ID == "" {
+		t.Errorf("item task = %+v", it)
+	}
+
+	if rec := post("/templates/gate/delete", nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("delete: %d", rec.Code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gate.snbt")); err == nil {
+		t.Error("template not deleted")
+	}
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// quickEntry is a chapter or quest in the quick open index.
+type quickEntry struct {
+	Kind         string `json:"kind"`
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Chapter      string `json:"chapter"`
+	ChapterTitle string `json:"chapter_title,omitempty"`
+	URL          string `json:"url"`
+	// text is the lower cased title, and words its words
+	text  string
+	words []string
+}
+
+// quickIndex is the book's chapters and quests for quick open, chapters
+// first, each in book order. The quick open palette asks /api/search on each
+// keystroke, so the titles are indexed once when the book is loaded rather
+// than read from the quests every time.
+type quickIndex struct {
+	entries []*quickEntry
+}
+
+// newQuickIndex indexes the chapters and quests of qb.
+func newQuickIndex(qb *QuestBook) *quickIndex {
+	idx := &quickIndex{}
+	add := func(e *quickEntry) {
+		e.text = strings.ToLower(e.Title)
+		e.words = strings.FieldsFunc(e.text, func(r rune) bool { return !isWordRune(r) })
+		idx.entries = append(idx.entries, e)
+	}
+	for _, ch := range qb.Chapters {
+		add(&quickEntry{Kind: "chapter", ID: ch.ID, Title: stripCodes(ch.Title), Chapter: ch.Name, URL: "/chapter/" + ch.Name})
+	}
+	for _, ch := range qb.Chapters {
+		title := stripCodes(ch.Title)
+		for _, q := range ch.Quests {
+			add(&quickEntry{Kind: "quest", ID: q.ID, Title: stripCodes(q.GetTitle()), Chapter: ch.Name, ChapterTitle: title, URL: "/chapter/" + ch.Name + "/" + q.ID})
+		}
+	}
+	return idx
+}
+
+// quickIndex returns the book's quick open index, building it if the book
+// wasn't loaded with one.
+func (q *QuestBook) quickIndex() *quickIndex {
+	if q.quick != nil {
+		return q.quick
+	}
+	return newQuickIndex(q)
+}
+
+// rank returns how well e matches the lower cased query q and its words, or
+// -1 if it doesn't. Lower is better: a title starting with q, then a word
+// of it starting with q, then every query word starting a title word, then q
+// anywhere in the title. An id always matches exactly.
+func (e *quickEntry) rank(q string, qwords []string) int {
+	switch {
+	case strings.EqualFold(e.ID, q):
+		return 0
+	case strings.HasPrefix(e.text, q):
+		return 1
+	}
+	for _, w := range e.words {
+		if strings.HasPrefix(w, q) {
+			return 2
+		}
+	}
+	all := len(qwords) > 0
+	for _, qw := range qwords {
+		if !slices.ContainsFunc(e.words, func(w string) bool { return strings.HasPrefix(w, qw) }) {
+			all = false
+			break
+		}
+	}
+	if all {
+		return 3
+	}
+	if strings.Contains(e.text, q) {
+		return 4
+	}
+	return -1
+}
+
+// search returns up to limit entries matching q, best first; chapters come
+// before quests that match as well.
+func (idx *quickIndex) search(q string, limit int) []*quickEntry {
+	q = strings.ToLower(strings.TrimSpace(q))
+	if q == "" {
+		return nil
+	}
+	qwords := strings.FieldsFunc(q, func(r rune) bool { return !isWordRune(r) })
+	type hit struct {
+		e    *quickEntry
+		rank int
+	}
+	var hits []hit
+	for _, e := range idx.entries {
+		if r := e.rank(q, qwords); r >= 0 {
+			hits = append(hits, hit{e, r})
+		}
+	}
+	// stable, so that ties stay in index order
+	slices.SortStableFunc(hits, func(a, b hit) int { return a.rank - b.rank })
+	res := make([]*quickEntry, 0, min(len(hits), limit))
+	for _, h := range hits[:min(len(hits), limit)] {
+		res = append(res, h.e)
+	}
+	return res
+}
+
+// apiSearch handles GET "/api/search?q=", the chapters and quests whose
+// titles match q, for the quick open palette. n limits the results.
+func (a *App) apiSearch(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 || n > 50 {
+		n = 20
+	}
+	results := []quickEntry{}
+	for _, e := range a.QB().quickIndex().search(r.URL.Query().Get("q"), n) {
+		res := *e
+		res.URL = a.Base + e.URL
+		results = append(results, res)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "results": results})
+}
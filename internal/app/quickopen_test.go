@@ -0,0 +1,72 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestQuickOpen(t *testing.T) {
+	ch := &Chapter{ID: "00000000000000C1", Name: "tech", Title: "&6Tech"}
+	ch.Quests = []*Quest{
+		{ID: "00000000000000A1", Title: "Iron Furnace", Chapter: ch},
+		{ID: "00000000000000A2", Title: "Furnace Upgrades", Chapter: ch},
+		{ID: "00000000000000A3", Title: "Upgraded Tech Furnace", Chapter: ch},
+		{ID: "00000000000000A4", Title: "Smeltery", Chapter: ch},
+	}
+	idx := newQuickIndex(&QuestBook{Chapters: []*Chapter{ch}})
+	titles := func(q string) []string {
+		var res []string
+		for _, e := range idx.search(q, 10) {
+			res = append(res, e.Title)
+		}
+		return res
+	}
+	for _, c := range []struct {
+		q    string
+		want []string
+	}{
+		{"furn", []string{"Furnace Upgrades", "Iron Furnace", "Upgraded Tech Furnace"}},
+		{"tech", []string{"Tech", "Upgraded Tech Furnace"}},
+		{"up fur", []string{"Furnace Upgrades", "Upgraded Tech Furnace"}},
+		{"elter", []string{"Smeltery"}},
+		{"00000000000000a4", []string{"Smeltery"}},
+		{"  ", nil},
+		{"nothing", nil},
+	} {
+		got := titles(c.q)
+		if len(got) != len(c.want) {
+			t.Errorf("search(%q) = %q, want %q", c.q, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("search(%q) = %q, want %q", c.q, got, c.want)
+				break
+			}
+		}
+	}
+	if got := idx.search("furnace", 2); len(got) != 2 {
+		t.Errorf("limit: %d results", len(got))
+	}
+
+	a := testApp(t)
+	a.Base = "/b/one"
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/api/search?q="+url.QueryEscape(stripCodes(a.QB().Chapters[0].Title)), nil))
+	var res struct {
+		OK      bool         `json:"ok"`
+		Results []quickEntry `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("api: %d %s", rec.Code, rec.Body)
+	}
+	if !res.OK || len(res.Results) == 0 || res.Results[0].Kind != "chapter" || res.Results[0].URL != "/b/one/chapter/test" {
+		t.Errorf("results = %+v", res.Results)
+	}
+	if a.QB().quick == nil {
+		t.Error("the loaded book has no index")
+	}
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// RawString is a text field found in a raw SNBT file.
+type RawString struct {
+	Line int    // 1-based line in the file
+	Key  string // title, subtitle or description
+	Text string
+}
+
+// textKeys are the keys whose string values are shown in the strings-only
+// raw view.
+var textKeys = map[string]bool{"title": true, "subtitle": true, "description": true}
+
+// scanRawStrings returns the text fields in src along with the line they
+// appear on. It only tokenizes the input, so it works on files that don't
+// parse and follows the file's own layout whether it is indented or written
+// on a single line. List items belong to the key that opened their list.
+func scanRawStrings(src string) []RawString {
+	type frame struct {
+		list bool
+		key  string
+	}
+	var (
+		res     []RawString
+		stack   []frame
+		pending string // key awaiting its value
+		line    = 1
+	)
+	// nextNonSpace returns the index of the next non-blank byte at or after i
+	nextNonSpace := func(i int) int {
+		for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+			i++
+		}
+		return i
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == '#' && (i == 0 || src[i-1] == '\n' || src[i-1] == ' ' || src[i-1] == '\t'):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			start, startLine := i, line
+			i++
+			for i < len(src) && src[i] != '"' {
+				if src[i] == '\\' {
+					i++
+				} else if src[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i++ // closing quote
+			lit := src[start:min(i, len(src))]
+			s, err := strconv.Unquote(lit)
+			if err != nil {
+				s = strings.Trim(lit, `"`)
+			}
+			if j := nextNonSpace(i); j < len(src) && src[j] == ':' {
+				pending, i = s, j+1
+				continue
+			}
+			owner := pending
+			if owner == "" && len(stack) > 0 && stack[len(stack)-1].list {
+				owner = stack[len(stack)-1].key
+			}
+			if textKeys[owner] {
+				res = append(res, RawString{Line: startLine, Key: owner, Text: s})
+			}
+			pending = ""
+		case c == '{' || c == '[':
+			stack = append(stack, frame{list: c == '[', key: pending})
+			pending = ""
+			i++
+		case c == '}' || c == ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			pending = ""
+			i++
+		case c == ':' || c == ',' || c == ' ' || c == '\t' || c == '\r':
+			i++
+		default:
+			// identifier, number or boolean
+			start := i
+			for i < len(src) && !strings.ContainsRune(" \t\r\n:,{}[]\"", rune(src[i])) {
+				i++
+			}
+			if j := nextNonSpace(i); j < len(src) && src[j] == ':' {
+				pending, i = src[start:i], j+1
+				continue
+			}
+			pending = ""
+		}
+	}
+	return res
+}
+
+// errorPosition returns the 1-based line and column of a parse error, or
+// 0, 0 if err doesn't carry one.
+func errorPosition(err error) (line, col int) {
+	var pe *snbt.ParseError
+	if errors.As(err, &pe) {
+		return pe.Line, pe.Col
+	}
+	return 0, 0
+}
+
+// validateChapterSNBT checks that src is a chapter file qbedit can load: it
+// has to parse, and be a compound with an id.
+func validateChapterSNBT(src string) error {
+	v, err := snbt.Decode(strings.NewReader(src))
+	if err != nil {
+		return err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("a chapter must be a compound {...}, not %T", v)
+	}
+	if M(m).GetString("id") == "" {
+		return errors.New("the chapter has no id")
+	}
+	return nil
+}
+
+// chapterRawSave handles POST "/chapter/{chapter}/raw", which replaces the
+// chapter's file with the "snbt" form field as written. The text is checked
+// to parse first; parse errors are returned with their line and column so the
+// editor can jump to them.
+func (a *App) chapterRawSave(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cname := chi.URLParam(r, "chapter")
+	ch, ok := qb.chapterMap[cname]
+	if !ok {
+		writeError(w, true, "unknown chapter "+cname, http.StatusNotFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, true, "invalid form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	src := r.PostForm.Get("snbt")
+	if err := validateChapterSNBT(src); err != nil {
+		line, col := errorPosition(err)
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"ok":    false,
+			"error": strings.TrimSpace(err.Error()),
+			"line":  line,
+			"col":   col,
+		})
+		return
+	}
+	path := qb.chapterPath(ch.Name)
+	t := newBookWrite(nil)
+	t.stage(path, []byte(src))
+	if err := t.commit(); err != nil {
+		writeError(w, true, "saving chapter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.audit(r, "edit raw", cname, nil, "")
+	a.reload()
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanRawStrings(t *testing.T) {
+	src := `{
+	title: "Chapter"
+	quests: [
+		{
+			description: [
+				"Line &aone"
+				""
+				"Say \"hi\""
+			]
+			tasks: [{ title: "Task", type: "item" }]
+			"subtitle": "Sub"
+		}
+	]
+}
+{ quests: [ { description: ["a", "b"], id: "X" } ] }
+`
+	got := scanRawStrings(src)
+	want := []RawString{
+		{2, "title", "Chapter"},
+		{6, "description", "Line &aone"},
+		{7, "description", ""},
+		{8, "description", `Say "hi"`},
+		{10, "title", "Task"},
+		{11, "subtitle", "Sub"},
+		{15, "description", "a"},
+		{15, "description", "b"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d strings, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%d: got %+v want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestChapterRawSave(t *testing.T) {
+	a := testApp(t)
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(src string) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/chapter/test/raw", strings.NewReader(url.Values{"snbt": {src}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		a.Router().ServeHTTP(rec, req)
+		var res map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s: %v", rec.Body, err)
+		}
+		return rec, res
+	}
+
+	rec, res := post("{\n\tid: \"0000000000000001\"\n\ttitle: \"Broken\n}\n")
+	if rec.Code != http.StatusBadRequest || res["line"] != float64(3) || res["col"] == float64(0) {
+		t.Errorf("unclosed string: %d %v", rec.Code, res)
+	}
+	if rec, _ := post(`["not", "a", "chapter"]`); rec.Code != http.StatusBadRequest {
+		t.Errorf("list: %d", rec.Code)
+	}
+	if b, _ := os.ReadFile(path); !bytes.Equal(b, before) {
+		t.Error("invalid text was written")
+	}
+
+	src := "{\n\tid: \"0000000000000001\"\n\tfilename: \"test\"\n\ttitle: \"Edited\"\n\tquests: [ ]\n}\n"
+	if rec, res := post(src); rec.Code != http.StatusOK {
+		t.Fatalf("save: %d %v", rec.Code, res)
+	}
+	if b, _ := os.ReadFile(path); string(b) != src {
+		t.Errorf("file = %q, want it as written", b)
+	}
+	if ch := a.QB().chapterMap["test"]; ch == nil || ch.Title != "Edited" {
+		t.Errorf("chapter not reloaded: %+v", ch)
+	}
+}
@@ -0,0 +1,233 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+// Recipe kinds.
+const (
+	// RecipeRecolor colors every occurrence of Term with Color.
+	RecipeRecolor = "recolor"
+	// RecipeNormalize rewrites every code to start with Sign.
+	RecipeNormalize = "normalize"
+)
+
+// Recipe is a saved bulk operation, like recoloring a term across a group of
+// chapters, so that it can be run again later, eg. after new chapters have
+// been imported. A recipe re-selects the quests it changes each time it runs
+// rather than remembering quest ids. Recipes are kept in the pack's settings,
+// shared by everyone editing it.
+type Recipe struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Scope is a chapter or group filter as used by the Color Manager;
+	// empty is the whole book.
+	Scope string `json:"scope,omitempty"`
+	Term  string `json:"term,omitempty"`
+	Regex bool   `json:"regex,omitempty"`
+	CI    bool   `json:"ci,omitempty"`
+	// Color is the color code or #rrggbb color a recolor applies; Sign is &
+	// or §.
+	Color string `json:"color,omitempty"`
+	Sign  string `json:"sign,omitempty"`
+}
+
+// Summary describes what the recipe does.
+func (rc Recipe) Summary() string {
+	var s string
+	switch rc.Kind {
+	case RecipeRecolor:
+		color := "&" + rc.Color
+		if mcformat.IsHexColor(rc.Color) {
+			color = rc.Color
+		}
+		s = fmt.Sprintf("color %q with %s", rc.Term, color)
+		if rc.Regex {
+			s += " (regular expression)"
+		}
+		if rc.CI {
+			s += " (ignoring case)"
+		}
+	case RecipeNormalize:
+		s = "write every code with " + rc.Sign
+	}
+	if rc.Scope != "" {
+		return s + " in " + rc.Scope
+	}
+	return s + " in the whole book"
+}
+
+// validate checks that the recipe has what its kind needs.
+func (rc Recipe) validate() error {
+	if strings.TrimSpace(rc.Name) == "" {
+		return fmt.Errorf("recipe needs a name")
+	}
+	switch rc.Kind {
+	case RecipeRecolor:
+		if rc.Term == "" {
+			return fmt.Errorf("recipe %s: missing term", rc.Name)
+		}
+		if c, ok := parseColor(rc.Color); !ok || c != rc.Color {
+			return fmt.Errorf("recipe %s: %q is not a color code or #rrggbb color", rc.Name, rc.Color)
+		}
+		if _, err := newMatcher(rc.Term, rc.Regex, rc.CI); err != nil {
+			return fmt.Errorf("recipe %s: %w", rc.Name, err)
+		}
+	case RecipeNormalize:
+		if rc.Sign != "&" && rc.Sign != "§" {
+			return fmt.Errorf("recipe %s: sign must be & or §", rc.Name)
+		}
+	default:
+		return fmt.Errorf("recipe %s: unknown kind %q", rc.Name, rc.Kind)
+	}
+	return nil
+}
+
+// recipeFromForm reads a recipe from the fields the Color Manager posts.
+func recipeFromForm(form url.Values) Recipe {
+	return Recipe{
+		Name:  strings.TrimSpace(form.Get("name")),
+		Kind:  form.Get("kind"),
+		Scope: strings.TrimSpace(form.Get("scope")),
+		Term:  strings.TrimSpace(form.Get("term")),
+		Regex: form.Get("regex") == "1",
+		CI:    form.Get("ci") == "1",
+		Color: strings.ToLower(strings.TrimLeft(strings.TrimSpace(form.Get("color")), "&§")),
+		Sign:  form.Get("sign"),
+	}
+}
+
+// recipeChapters returns the chapters in the recipe's scope.
+func recipeChapters(qb *QuestBook, rc Recipe) []*Chapter {
+	scope := colorScope(qb, rc.Scope)
+	var chapters []*Chapter
+	for _, ch := range qb.Chapters {
+		if len(scope) == 0 || scope[ch.Name] {
+			chapters = append(chapters, ch)
+		}
+	}
+	return chapters
+}
+
+// recolorTargets returns the ids of the quests in ch whose text contains tm.
+func recolorTargets(ch *Chapter, tm *matcher) map[string]struct{} {
+	ids := make(map[string]struct{})
+	for _, q := range ch.Quests {
+		for _, f := range searchFields {
+			if len(tm.index(stripCodes(questField(q, f)))) > 0 {
+				ids[q.ID] = struct{}{}
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// runRecipe runs rc against the book and returns how many quests it changed.
+func (a *App) runRecipe(r *http.Request, rc Recipe) (int, error) {
+	qb := a.QB()
+	if err := rc.validate(); err != nil {
+		return 0, err
+	}
+	var names []string
+	for _, ch := range recipeChapters(qb, rc) {
+		names = append(names, ch.Name)
+	}
+	edited, err := editChapters(qb, names, func(ch *Chapter) ([]string, error) {
+		switch rc.Kind {
+		case RecipeRecolor:
+			tm, _ := newMatcher(rc.Term, rc.Regex, rc.CI)
+			_, ids := recolorChapter(ch, recolorTargets(ch, tm), tm, rc.Color)
+			return ids, nil
+		case RecipeNormalize:
+			return normalizeChapter(ch, []rune(rc.Sign)[0]), nil
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	n := a.auditEdits(r, "recipe "+rc.Name, rc.Summary(), edited)
+	if n > 0 {
+		a.reload()
+	}
+	return n, nil
+}
+
+// recipes handles GET "/recipes".
+func (a *App) recipes(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Recipes")
+	data["Recipes"] = a.Pack.Get().Recipes
+	data["RecipeMsg"] = r.URL.Query().Get("msg")
+	a.render(w, "recipes.gohtml", data)
+}
+
+func recipeRedirect(w http.ResponseWriter, r *http.Request, msg string) {
+	http.Redirect(w, r, "/recipes?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// recipeSave handles POST "/recipes", adding or replacing a recipe.
+func (a *App) recipeSave(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	rc := recipeFromForm(r.Form)
+	if err := rc.validate(); err != nil {
+		recipeRedirect(w, r, err.Error())
+		return
+	}
+	err := a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Recipes = slices.Clone(cfg.Recipes)
+		if i := slices.IndexFunc(cfg.Recipes, func(o Recipe) bool { return o.Name == rc.Name }); i >= 0 {
+			cfg.Recipes[i] = rc
+		} else {
+			cfg.Recipes = append(cfg.Recipes, rc)
+		}
+		sort.Slice(cfg.Recipes, func(i, j int) bool { return cfg.Recipes[i].Name < cfg.Recipes[j].Name })
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recipeRedirect(w, r, "Saved recipe "+rc.Name+".")
+}
+
+// recipeDelete handles POST "/recipes/delete".
+func (a *App) recipeDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	err := a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Recipes = slices.DeleteFunc(slices.Clone(cfg.Recipes), func(rc Recipe) bool { return rc.Name == name })
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	recipeRedirect(w, r, "Deleted recipe "+name+".")
+}
+
+// recipeRun handles POST "/recipes/run", running the recipe "name".
+func (a *App) recipeRun(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	recipes := a.Pack.Get().Recipes
+	i := slices.IndexFunc(recipes, func(rc Recipe) bool { return rc.Name == name })
+	if i < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	n, err := a.runRecipe(r, recipes[i])
+	if err != nil {
+		recipeRedirect(w, r, "Recipe "+name+" failed: "+err.Error())
+		return
+	}
+	recipeRedirect(w, r, fmt.Sprintf("Ran recipe %s on %d quests.", name, n))
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRecipeRun(t *testing.T) {
+	a := testApp(t)
+	var q *Quest
+	for _, qq := range a.QB().Quests {
+		if qq.Title != "" && !strings.ContainsAny(qq.Title, "&§") {
+			q = qq
+			break
+		}
+	}
+	if q == nil {
+		t.Skip("no quest with a plain title")
+	}
+	term := strings.Fields(q.Title)[0]
+	post := func(path string, form url.Values) {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("%s: %d %s", path, rec.Code, rec.Body)
+		}
+		if msg := rec.Header().Get("Location"); strings.Contains(msg, "failed") || strings.Contains(msg, "not") {
+			t.Fatalf("%s: %s", path, msg)
+		}
+	}
+	post("/recipes", url.Values{"name": {"gold"}, "kind": {"recolor"}, "term": {term}, "color": {"&6"}})
+	if rs := a.Pack.Get().Recipes; len(rs) != 1 || rs[0].Color != "6" {
+		t.Fatalf("recipes = %+v", rs)
+	}
+	post("/recipes/run", url.Values{"name": {"gold"}})
+	if got := a.QB().questMap[q.ID].Title; !strings.Contains(got, "&6"+term+"&r") {
+		t.Errorf("title after recipe = %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ticksPerSecond is the rate of the minecraft game clock.
+const ticksPerSecond = 20
+
+// durationUnits maps a duration suffix to its length in ticks.
+var durationUnits = map[string]int{
+	"t": 1,
+	"s": ticksPerSecond,
+	"m": 60 * ticksPerSecond,
+	"h": 60 * 60 * ticksPerSecond,
+	"d": 24 * 60 * 60 * ticksPerSecond,
+}
+
+// parseTicks parses a human friendly duration like "1h30m", "2d" or "90s"
+// into game ticks. A bare number is taken as ticks, and "t" can be used as an
+// explicit tick suffix, eg. "1m10t".
+func parseTicks(s string) (int, error) {
+	s = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return n, nil
+	}
+
+	total, rest := 0, s
+	for rest != "" {
+		i := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, _ := strconv.Atoi(rest[:i])
+		unit, ok := durationUnits[rest[i:i+1]]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q: unknown unit %q", s, rest[i:i+1])
+		}
+		total += n * unit
+		rest = rest[i+1:]
+	}
+	return total, nil
+}
+
+// formatTicks renders ticks as a duration parseTicks understands, using the
+// largest units that divide it evenly, eg. 72000 is "1h".
+func formatTicks(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, u := range []string{"d", "h", "m", "s", "t"} {
+		size := durationUnits[u]
+		if n >= size {
+			fmt.Fprintf(&sb, "%d%s", n/size, u)
+			n %= size
+		}
+	}
+	return sb.String()
+}
+
+// repeatFromForm updates q's repeat settings from the "can_repeat" checkbox
+// and the "cooldown" duration field. A repeatable quest resets after it is
+// completed and its rewards claimed; FTB Quests stores the wait before it is
+// available again as "repeat_cooldown", in game ticks.
+func repeatFromForm(q *Quest, form url.Values) error {
+	ticks, err := parseTicks(form.Get("cooldown"))
+	if err != nil {
+		return err
+	}
+	q.Repeatable = form.Get("can_repeat") != ""
+	q.Cooldown = ticks
+	return nil
+}
@@ -0,0 +1,31 @@
+package app
+
+import "testing"
+
+func TestParseTicks(t *testing.T) {
+	for in, want := range map[string]int{
+		"":       0,
+		"1200":   1200,
+		"90s":    1800,
+		"1h30m":  108000,
+		"2d":     3456000,
+		"1m 10t": 1210,
+	} {
+		got, err := parseTicks(in)
+		if err != nil {
+			t.Errorf("parseTicks(%q): %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("parseTicks(%q) = %d, want %d", in, got, want)
+		}
+		if back, _ := parseTicks(formatTicks(got)); back != got {
+			t.Errorf("formatTicks(%d) = %q does not round-trip", got, formatTicks(got))
+		}
+	}
+	for _, in := range []string{"h", "10x", "-5", "1.5h"} {
+		if _, err := parseTicks(in); err == nil {
+			t.Errorf("parseTicks(%q): expected error", in)
+		}
+	}
+}
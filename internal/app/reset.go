@@ -0,0 +1,106 @@
+package app
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Reset policy modes.
+const (
+	// ResetOff doesn't require resets.
+	ResetOff = ""
+	// ResetLineEnd requires spans to be reset before the end of each line.
+	ResetLineEnd = "line"
+	// ResetPunct also requires a reset before punctuation that ends a span,
+	// eg. "&9Wooden Crook&r." rather than "&9Wooden Crook.".
+	ResetPunct = "punct"
+)
+
+// ResetPolicy is a pack's rule for ending styled spans with &r, so that
+// styles never leak into following punctuation or onto the next line when the
+// game wraps text. The lint page reports and fixes text that breaks it, and
+// it can be applied to quests as they are saved.
+type ResetPolicy struct {
+	Mode string `json:"mode,omitempty"`
+	// OnSave applies the policy to quests when they are saved.
+	OnSave bool `json:"on_save,omitempty"`
+}
+
+// isFormatCode reports whether c following & or § is a color or format code,
+// or the x that starts a hex color.
+func isFormatCode(c rune) bool {
+	c = unicode.ToLower(c)
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'k' && c <= 'o') || c == 'r' || c == 'x'
+}
+
+// Apply returns s with resets inserted where the policy requires them. Each
+// line is handled separately; lines holding JSON text components are left
+// alone.
+func (p ResetPolicy) Apply(s string) string {
+	if p.Mode == ResetOff || !strings.ContainsAny(s, "&§") {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = p.applyLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p ResetPolicy) applyLine(line string) string {
+	if t := strings.TrimSpace(line); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		return line
+	}
+	rs := []rune(line)
+	out := make([]rune, 0, len(rs)+4)
+	// styled is whether a color or format code is in effect, and shown
+	// whether any text has been written in it since; a reset is only needed
+	// when both are true. Resets use the sign of the code they close.
+	styled, shown := false, false
+	sign := '&'
+
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		if (r == '&' || r == '§') && i+1 < len(rs) && isFormatCode(rs[i+1]) {
+			if unicode.ToLower(rs[i+1]) == 'r' {
+				styled, shown = false, false
+			} else if !styled {
+				styled, shown, sign = true, false, r
+			}
+			out = append(out, r, rs[i+1])
+			i++
+			continue
+		}
+		if p.Mode == ResetPunct && styled && shown && endsSpan(rs, i) {
+			out = append(out, sign, 'r')
+			styled, shown = false, false
+		}
+		if !unicode.IsSpace(r) {
+			shown = true
+		}
+		out = append(out, r)
+	}
+	if styled && shown {
+		// trailing spaces stay outside of the span
+		end := len(out)
+		for end > 0 && out[end-1] == ' ' {
+			end--
+		}
+		out = append(out[:end], append([]rune{sign, 'r'}, out[end:]...)...)
+	}
+	return string(out)
+}
+
+// endsSpan reports whether rs[i] starts a run of punctuation that is followed
+// by whitespace or the end of the line, like the period ending a sentence.
+// Punctuation inside words (it's, R&D, x-ray) doesn't end a span.
+func endsSpan(rs []rune, i int) bool {
+	if !unicode.IsPunct(rs[i]) || rs[i] == '&' || rs[i] == '§' {
+		return false
+	}
+	j := i
+	for j < len(rs) && unicode.IsPunct(rs[j]) && rs[j] != '&' && rs[j] != '§' {
+		j++
+	}
+	return j == len(rs) || unicode.IsSpace(rs[j])
+}
@@ -0,0 +1,55 @@
+package app
+
+import "testing"
+
+func TestResetPolicy(t *testing.T) {
+	punct := ResetPolicy{Mode: ResetPunct}
+	line := ResetPolicy{Mode: ResetLineEnd}
+	cases := []struct {
+		p        ResetPolicy
+		in, want string
+	}{
+		{punct, "Make a &9Wooden Crook.", "Make a &9Wooden Crook&r."},
+		{punct, "Make a &9Wooden Crook&r.", "Make a &9Wooden Crook&r."},
+		{punct, "Get &9String", "Get &9String&r"},
+		{punct, "Get &9String  ", "Get &9String&r  "},
+		// nested codes are closed by a single reset
+		{punct, "&6&lTitle: more", "&6&lTitle&r: more"},
+		{punct, "&9Silk &lworms!", "&9Silk &lworms&r!"},
+		{punct, "&9a&cb, c", "&9a&cb&r, c"},
+		{punct, "&o&5isn't your world&r after all.", "&o&5isn't your world&r after all."},
+		// punctuation inside words doesn't end a span
+		{punct, "&eR&D x-ray", "&eR&D x-ray&r"},
+		{punct, "(&9Crook)", "(&9Crook&r)"},
+		{punct, "§9Crook.", "§9Crook§r."},
+		{punct, "trailing &9", "trailing &9"},
+		{line, "Make a &9Wooden Crook.", "Make a &9Wooden Crook.&r"},
+		{line, "&aone\n&btwo&r\nthree", "&aone&r\n&btwo&r\nthree"},
+		{punct, `{"text":"&9x."}`, `{"text":"&9x."}`},
+		{ResetPolicy{}, "&9x.", "&9x."},
+	}
+	for _, c := range cases {
+		if got := c.p.Apply(c.in); got != c.want {
+			t.Errorf("%s Apply(%q) = %q, want %q", c.p.Mode, c.in, got, c.want)
+		}
+		// applying a policy twice changes nothing more
+		if got := c.p.Apply(c.want); got != c.want {
+			t.Errorf("%s Apply(%q) is not idempotent: %q", c.p.Mode, c.want, got)
+		}
+	}
+}
+
+func TestLintQuest(t *testing.T) {
+	cfg := PackConfig{Reset: ResetPolicy{Mode: ResetPunct}}
+	q := &Quest{Title: "&6Gold", Subtitle: "fine", Description: "ok\nUse &9Crook.\n"}
+	issues := lintQuest(cfg, q)
+	if len(issues) != 2 || issues[0].Field != "title" || issues[1].Line != 1 || issues[1].Fixed != "Use &9Crook&r." {
+		t.Fatalf("got %+v", issues)
+	}
+	if !fixQuestText(cfg, q) || len(lintQuest(cfg, q)) != 0 || q.Title != "&6Gold&r" {
+		t.Fatalf("fix left %+v", q)
+	}
+	if len(lintQuest(PackConfig{}, &Quest{Title: "&6Gold"})) != 0 {
+		t.Fatal("expected no issues without a policy")
+	}
+}
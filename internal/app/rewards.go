@@ -0,0 +1,285 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/qbedit/internal/app/fbtid"
+)
+
+// Reward is a single entry in a quest's rewards list.
+//
+// Concrete reward types model the fields we know how to edit and keep the
+// decoded compound around so that anything we don't model (item NBT, team
+// reward flags, etc) survives a round-trip.
+type Reward interface {
+	// Base returns the fields common to every reward type.
+	Base() *RewardBase
+	// FormValue returns the reward's primary value as shown in the editor.
+	FormValue() string
+	// FormCount returns the reward's count, or 0 if it has none.
+	FormCount() int
+	// SetForm updates the reward from the editor's value and count fields.
+	SetForm(value string, count int) error
+	// Sync writes the reward's fields back into its raw compound.
+	Sync()
+}
+
+// RewardTypes are the reward types that can be created from the quest editor.
+var RewardTypes = []string{"item", "xp", "xp_levels", "loot", "random", "choice", "command"}
+
+// RewardBase holds the fields shared by all rewards.
+type RewardBase struct {
+	raw  map[string]any
+	ID   string
+	Type string
+}
+
+func (r *RewardBase) Base() *RewardBase { return r }
+
+// ItemReward gives the player Count of an item.
+type ItemReward struct {
+	RewardBase
+	Item  string
+	Count int
+}
+
+func (r *ItemReward) FormValue() string { return r.Item }
+func (r *ItemReward) FormCount() int    { return r.Count }
+
+func (r *ItemReward) SetForm(value string, count int) (err error) {
+	r.Item, r.Count, err = itemFromForm("item reward", r.ID, value, count)
+	return err
+}
+
+func (r *ItemReward) Sync() { syncItem(r.raw, r.Item, r.Count) }
+
+// itemFromRaw returns the item id and count of an item task or reward's
+// compound. Items can be compounds with NBT; only their id is returned.
+func itemFromRaw(raw map[string]any) (string, int) {
+	return itemToString(raw["item"]), max(M(raw).GetInt("count"), 1)
+}
+
+// itemFromForm checks the editor's value and count fields of the item task or
+// reward id, which kind names in errors, returning its item and count.
+func itemFromForm(kind, id, value string, count int) (string, int, error) {
+	if value == "" {
+		return "", 0, fmt.Errorf("%s %s: missing item id", kind, id)
+	}
+	return value, max(count, 1), nil
+}
+
+// syncItem writes item and count back into an item task or reward's compound.
+// An item compound keeps its NBT unless the id changes, and a count of 1 is
+// left out as FTB Quests does.
+func syncItem(raw map[string]any, item string, count int) {
+	if itemToString(raw["item"]) != item {
+		raw["item"] = item
+	}
+	if max(M(raw).GetInt("count"), 1) == count {
+		return
+	}
+	if count > 1 {
+		M(raw).SetInt("count", count)
+	} else {
+		delete(raw, "count")
+	}
+}
+
+// XPReward gives the player experience points, or levels for "xp_levels".
+type XPReward struct {
+	RewardBase
+	Amount int
+}
+
+func (r *XPReward) FormValue() string { return strconv.Itoa(r.Amount) }
+func (r *XPReward) FormCount() int    { return 0 }
+
+func (r *XPReward) SetForm(value string, count int) error {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("%s reward %s: invalid amount %q", r.Type, r.ID, value)
+	}
+	r.Amount = n
+	return nil
+}
+
+func (r *XPReward) Sync() {
+	key := r.key()
+	if M(r.raw).GetInt(key) != r.Amount || !M(r.raw).Has(key) {
+		M(r.raw).SetInt(key, r.Amount)
+	}
+}
+
+// key returns the compound key holding the amount, which matches the type.
+func (r *XPReward) key() string {
+	if r.Type == "xp_levels" {
+		return "xp_levels"
+	}
+	return "xp"
+}
+
+// LootReward rolls a reward table; "loot", "random" and "choice" rewards
+// all reference a table by its numeric id.
+type LootReward struct {
+	RewardBase
+	TableID string
+}
+
+func (r *LootReward) FormValue() string { return r.TableID }
+func (r *LootReward) FormCount() int    { return 0 }
+
+func (r *LootReward) SetForm(value string, count int) error {
+	value = strings.TrimSpace(value)
+	if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+		return fmt.Errorf("%s reward %s: invalid table id %q", r.Type, r.ID, value)
+	}
+	r.TableID = value
+	return nil
+}
+
+func (r *LootReward) Sync() {
+	if numberString(r.raw["table_id"]) == r.TableID {
+		return
+	}
+	n, _ := strconv.Atoi(r.TableID)
+	M(r.raw).SetInt("table_id", n)
+}
+
+// CommandReward runs a command when the reward is claimed.
+type CommandReward struct {
+	RewardBase
+	Command string
+}
+
+func (r *CommandReward) FormValue() string { return r.Command }
+func (r *CommandReward) FormCount() int    { return 0 }
+
+func (r *CommandReward) SetForm(value string, count int) error {
+	if value == "" {
+		return fmt.Errorf("command reward %s: missing command", r.ID)
+	}
+	r.Command = value
+	return nil
+}
+
+func (r *CommandReward) Sync() { r.raw["command"] = r.Command }
+
+// UnknownReward is any reward type we don't model; it is preserved as-is.
+type UnknownReward struct {
+	RewardBase
+}
+
+func (r *UnknownReward) FormValue() string { return "" }
+func (r *UnknownReward) FormCount() int    { return 0 }
+
+func (r *UnknownReward) SetForm(value string, count int) error { return nil }
+func (r *UnknownReward) Sync()                                 {}
+
+// NewReward decodes a reward from its raw compound based on its "type".
+func NewReward(raw any) (Reward, error) {
+	rm, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("new reward expected compound, but got %T", raw)
+	}
+	m := M(rm)
+	base := RewardBase{raw: rm, ID: m.GetString("id"), Type: m.GetString("type")}
+
+	switch base.Type {
+	case "item", "":
+		// FTB Quests omits the type for item rewards in some older packs
+		r := &ItemReward{RewardBase: base}
+		r.Item, r.Count = itemFromRaw(rm)
+		return r, nil
+	case "xp":
+		return &XPReward{RewardBase: base, Amount: m.GetInt("xp")}, nil
+	case "xp_levels":
+		return &XPReward{RewardBase: base, Amount: m.GetInt("xp_levels")}, nil
+	case "loot", "random", "choice", "all_table":
+		return &LootReward{RewardBase: base, TableID: numberString(rm["table_id"])}, nil
+	case "command":
+		return &CommandReward{RewardBase: base, Command: m.GetString("command")}, nil
+	}
+	return &UnknownReward{RewardBase: base}, nil
+}
+
+// NewRewardOfType creates an empty reward of type typ with the given id.
+func NewRewardOfType(typ, id string) (Reward, error) {
+	if !slices.Contains(RewardTypes, typ) {
+		return nil, fmt.Errorf("unknown reward type %q", typ)
+	}
+	return NewReward(map[string]any{"id": id, "type": typ})
+}
+
+// numberString formats a decoded SNBT integer as a plain decimal string.
+func numberString(v any) string {
+	switch n := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return n
+	}
+	return strconv.Itoa(anyToInt(v))
+}
+
+// rewardsFromForm rebuilds a quest's rewards from the parallel reward_id,
+// reward_type, reward_value and reward_count form fields; see entriesFromForm.
+func rewardsFromForm(existing []Reward, form url.Values, src *fbtid.Source) ([]Reward, error) {
+	return entriesFromForm("reward", existing, form, src, func(r Reward) (string, string) {
+		return r.Base().ID, r.Base().Type
+	}, NewRewardOfType)
+}
+
+// formEntry is a task or reward as the quest editor edits it.
+type formEntry interface {
+	SetForm(value string, count int) error
+}
+
+// entriesFromForm rebuilds a quest's tasks or rewards, as kind says, from the
+// parallel <kind>_id, <kind>_type, <kind>_value and <kind>_count form fields.
+// Entries that keep their id and type, as base reports them, are updated in
+// place so their unmodeled fields survive. Other entries are made with
+// newOfType, and new entries without an id get one from src.
+func entriesFromForm[T formEntry](kind string, existing []T, form url.Values, src *fbtid.Source, base func(T) (id, typ string), newOfType func(typ, id string) (T, error)) ([]T, error) {
+	ids := form[kind+"_id"]
+	types := form[kind+"_type"]
+	values := form[kind+"_value"]
+	counts := form[kind+"_count"]
+	if len(types) != len(ids) || len(values) != len(ids) || len(counts) != len(ids) {
+		return nil, fmt.Errorf("mismatched %s fields", kind)
+	}
+
+	byID := make(map[string]T, len(existing))
+	for _, e := range existing {
+		id, _ := base(e)
+		byID[id] = e
+	}
+
+	entries := make([]T, 0, len(ids))
+	for i, id := range ids {
+		typ := strings.TrimSpace(types[i])
+		e, ok := byID[id]
+		if ok {
+			_, etyp := base(e)
+			ok = etyp == typ
+		}
+		if !ok {
+			if id == "" {
+				id = src.Next()
+			}
+			var err error
+			if e, err = newOfType(typ, id); err != nil {
+				return nil, err
+			}
+		}
+		count, _ := strconv.Atoi(strings.TrimSpace(counts[i]))
+		if err := e.SetForm(strings.TrimSpace(values[i]), count); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
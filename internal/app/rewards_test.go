@@ -0,0 +1,84 @@
+package app
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jmoiron/qbedit/internal/app/fbtid"
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+func TestNewRewardTypes(t *testing.T) {
+	q, err := NewQuest(map[string]any{
+		"id": "Q1",
+		"rewards": []any{
+			map[string]any{"id": "R1", "type": "item", "item": "minecraft:stone", "count": snbt.Long{Sign: 1, Digits: "4", Suffix: 'L'}},
+			map[string]any{"id": "R2", "type": "xp_levels", "xp_levels": int64(5)},
+			map[string]any{"id": "R3", "type": "random", "table_id": int64(1234567890123)},
+			map[string]any{"id": "R4", "type": "command", "command": "/say hi"},
+			map[string]any{"id": "R5", "type": "toast", "title": "hi"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewQuest: %v", err)
+	}
+	if len(q.Rewards) != 5 {
+		t.Fatalf("expected 5 rewards, got %d", len(q.Rewards))
+	}
+	want := []struct {
+		value string
+		count int
+	}{
+		{"minecraft:stone", 4}, {"5", 0}, {"1234567890123", 0}, {"/say hi", 0}, {"", 0},
+	}
+	for i, r := range q.Rewards {
+		if r.FormValue() != want[i].value || r.FormCount() != want[i].count {
+			t.Errorf("reward %d: got (%q,%d) want (%q,%d)", i, r.FormValue(), r.FormCount(), want[i].value, want[i].count)
+		}
+	}
+	if _, ok := q.Rewards[4].(*UnknownReward); !ok {
+		t.Errorf("expected unknown reward for toast, got %T", q.Rewards[4])
+	}
+}
+
+func TestRewardsFromForm(t *testing.T) {
+	item := map[string]any{"id": "R1", "type": "item", "item": map[string]any{"id": "minecraft:stone", "Count": int64(1)}, "count": snbt.Long{Sign: 1, Digits: "4", Suffix: 'L'}}
+	q, err := NewQuest(map[string]any{"id": "Q1", "rewards": []any{item}})
+	if err != nil {
+		t.Fatalf("NewQuest: %v", err)
+	}
+
+	form := url.Values{
+		"reward_id":    {"R1", ""},
+		"reward_type":  {"item", "xp"},
+		"reward_value": {"minecraft:stone", "100"},
+		"reward_count": {"8", ""},
+	}
+	rewards, err := rewardsFromForm(q.Rewards, form, fbtid.NewSource(nil))
+	if err != nil {
+		t.Fatalf("rewardsFromForm: %v", err)
+	}
+	q.Rewards = rewards
+	q.Sync()
+
+	rl := M(q.raw).GetAnys("rewards")
+	if len(rl) != 2 {
+		t.Fatalf("expected 2 rewards, got %d", len(rl))
+	}
+	r1 := rl[0].(map[string]any)
+	// the item compound is unchanged, so it should be kept as-is
+	if _, ok := r1["item"].(map[string]any); !ok {
+		t.Errorf("item compound was replaced: %#v", r1["item"])
+	}
+	if c, ok := r1["count"].(snbt.Long); !ok || c.Digits != "8" {
+		t.Errorf("count: got %#v, want 8L", r1["count"])
+	}
+	r2 := M(rl[1].(map[string]any))
+	if r2.GetString("type") != "xp" || r2.GetInt("xp") != 100 || len(r2.GetString("id")) != 16 {
+		t.Errorf("new xp reward mismatch: %#v", r2)
+	}
+
+	if _, err := rewardsFromForm(nil, url.Values{"reward_id": {""}, "reward_type": {"xp"}, "reward_value": {"lots"}, "reward_count": {""}}, fbtid.NewSource(nil)); err == nil {
+		t.Errorf("expected error for invalid xp amount")
+	}
+}
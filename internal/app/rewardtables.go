@@ -0,0 +1,413 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// RewardTable is a reward table file, in quests/reward_tables, that loot,
+// random and choice rewards roll from. It holds a list of rewards with
+// weights; the rewards are the same compounds a quest's rewards are, without
+// ids. Rewards refer to a table by its id as a number, while the table's file
+// has it as hex, like quest ids.
+type RewardTable struct {
+	raw map[string]any
+	// Name is the base filename (without .snbt) used in URLs.
+	Name  string
+	ID    string
+	Title string
+	// LootSize is how many entries a loot crate of the table gives.
+	LootSize int
+	// EmptyWeight is the weight of rolling nothing.
+	EmptyWeight float64
+	Entries     []*TableEntry
+
+	// key is the id as rewards refer to it
+	key string
+}
+
+// TableEntry is a reward in a table and its weight.
+type TableEntry struct {
+	Reward Reward
+	// Weight is the entry's share of the rolls, 1 when unset.
+	Weight float64
+}
+
+// tableEntryWeight is the weight of entries without one.
+const tableEntryWeight = 1.0
+
+// NewRewardTable constructs a RewardTable from a decoded SNBT map.
+func NewRewardTable(rm map[string]any) (*RewardTable, error) {
+	m := M(rm)
+	t := &RewardTable{
+		raw:         rm,
+		ID:          m.GetString("id"),
+		Title:       m.GetString("title"),
+		LootSize:    max(m.GetInt("loot_size"), 1),
+		EmptyWeight: m.GetFloat("empty_weight"),
+	}
+	// current packs write the id as hex, older ones as a number
+	if n, err := strconv.ParseUint(t.ID, 16, 64); err == nil {
+		t.key = strconv.FormatInt(int64(n), 10)
+	} else {
+		t.ID = numberString(rm["id"])
+		t.key = t.ID
+	}
+	for _, v := range m.GetAnys("rewards") {
+		r, err := NewReward(v)
+		if err != nil {
+			return nil, err
+		}
+		e := &TableEntry{Reward: r, Weight: tableEntryWeight}
+		if M(r.Base().raw).Has("weight") {
+			e.Weight = M(r.Base().raw).GetFloat("weight")
+		}
+		t.Entries = append(t.Entries, e)
+	}
+	return t, nil
+}
+
+// DisplayTitle returns the table's title, or its name if it has none.
+func (t *RewardTable) DisplayTitle() string {
+	if t.Title != "" {
+		return t.Title
+	}
+	return t.Name
+}
+
+// Key returns the table's id as rewards refer to it: the hex id of current
+// packs as a signed decimal, or the number of older ones.
+func (t *RewardTable) Key() string {
+	return t.key
+}
+
+// TotalWeight is the sum of the weights of the entries and of rolling
+// nothing.
+func (t *RewardTable) TotalWeight() float64 {
+	total := t.EmptyWeight
+	for _, e := range t.Entries {
+		total += e.Weight
+	}
+	return total
+}
+
+// Chance returns the percentage chance of a roll giving e.
+func (t *RewardTable) Chance(e *TableEntry) float64 {
+	total := t.TotalWeight()
+	if total <= 0 {
+		return 0
+	}
+	return 100 * e.Weight / total
+}
+
+// Sync updates the table's raw map to match the struct state.
+func (t *RewardTable) Sync() {
+	m := M(t.raw)
+	if t.Title != "" {
+		t.raw["title"] = t.Title
+	} else {
+		delete(t.raw, "title")
+	}
+	if max(m.GetInt("loot_size"), 1) != t.LootSize {
+		m.SetInt("loot_size", t.LootSize)
+	}
+	if m.GetFloat("empty_weight") != t.EmptyWeight {
+		if t.EmptyWeight == 0 {
+			delete(t.raw, "empty_weight")
+		} else {
+			t.raw["empty_weight"] = floatValue(t.EmptyWeight)
+		}
+	}
+	rewards := make([]any, 0, len(t.Entries))
+	for _, e := range t.Entries {
+		e.Reward.Sync()
+		rm := e.Reward.Base().raw
+		old := tableEntryWeight
+		if M(rm).Has("weight") {
+			old = M(rm).GetFloat("weight")
+		}
+		switch {
+		case old == e.Weight:
+		case e.Weight == tableEntryWeight:
+			delete(rm, "weight")
+		default:
+			rm["weight"] = floatValue(e.Weight)
+		}
+		rewards = append(rewards, rm)
+	}
+	t.raw["rewards"] = rewards
+}
+
+// floatValue returns f as an SNBT float (eg. "1.5f").
+func floatValue(f float64) snbt.FloatNum {
+	d := decimalValue(f)
+	return snbt.FloatNum{Sign: d.Sign, Int: d.Int, Frac: d.Frac, Suffix: 'f'}
+}
+
+// tablesDir returns the reward tables directory of the book at root.
+func tablesDir(root string) string {
+	return filepath.Join(root, "quests", "reward_tables")
+}
+
+// loadTables loads the book's reward tables. Books without any are fine.
+func (q *QuestBook) loadTables() error {
+	dir := tablesDir(q.root)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	q.tableMap = make(map[string]*RewardTable)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".snbt") {
+			continue
+		}
+		start := time.Now()
+		path := filepath.Join(dir, e.Name())
+		t, cached, err := loadTable(path, q.cache)
+		if err != nil {
+			slog.Warn("loading reward table", "path", path, "error", err)
+			f := Failure{Name: "reward_tables/" + e.Name(), Path: path, Err: err.Error()}
+			var pe *snbt.ParseError
+			if errors.As(err, &pe) {
+				f.Line, f.Snippet = pe.Line, pe.Snippet
+			}
+			q.Failures = append(q.Failures, f)
+			continue
+		}
+		fs := FileStat{Name: "reward_tables/" + e.Name(), Parse: time.Since(start), Cached: cached}
+		if info, err := e.Info(); err == nil {
+			fs.Size = info.Size()
+		}
+		q.Stats.add(fs)
+		q.Tables = append(q.Tables, t)
+		q.tableMap[t.Key()] = t
+	}
+	sort.SliceStable(q.Tables, func(i, j int) bool {
+		return strings.ToLower(q.Tables[i].DisplayTitle()) < strings.ToLower(q.Tables[j].DisplayTitle())
+	})
+	return nil
+}
+
+// loadTable loads the reward table file at path, decoding it through cache,
+// which may be nil, and reports whether it was cached.
+func loadTable(path string, cache *parseCache) (*RewardTable, bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	v, skipped, cached, err := cache.decode(b)
+	if err == nil && len(skipped) > 0 {
+		err = skipped[0]
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	t, err := tableFromValue(path, v)
+	return t, cached, err
+}
+
+// NewRewardTableFromPath reads the reward table file at path.
+func NewRewardTableFromPath(path string) (*RewardTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	v, err := snbt.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return tableFromValue(path, v)
+}
+
+func tableFromValue(path string, v any) (*RewardTable, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("reward table at %s: expected compound, got %T", path, v)
+	}
+	t, err := NewRewardTable(m)
+	if err != nil {
+		return nil, fmt.Errorf("reward table at %s: %w", path, err)
+	}
+	t.Name = strings.TrimSuffix(filepath.Base(path), ".snbt")
+	return t, nil
+}
+
+// RewardTable returns the table a loot, random or choice reward rolls from,
+// or nil if r isn't one or the table isn't in the book.
+func (q *QuestBook) RewardTable(r Reward) *RewardTable {
+	lr, ok := r.(*LootReward)
+	if !ok {
+		return nil
+	}
+	return q.tableMap[lr.TableID]
+}
+
+// tableByName returns the table with the file name name, or nil.
+func (q *QuestBook) tableByName(name string) *RewardTable {
+	for _, t := range q.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// tableEntriesFromForm rebuilds a table's entries from the parallel
+// entry_index, entry_type, entry_value, entry_count and entry_weight form
+// fields. Entries that keep their index and type are updated in place so
+// their unmodeled fields survive; entry_index is empty for new ones.
+func tableEntriesFromForm(existing []*TableEntry, form url.Values) ([]*TableEntry, error) {
+	idxs := form["entry_index"]
+	types := form["entry_type"]
+	values := form["entry_value"]
+	counts := form["entry_count"]
+	weights := form["entry_weight"]
+	n := len(idxs)
+	if len(types) != n || len(values) != n || len(counts) != n || len(weights) != n {
+		return nil, fmt.Errorf("mismatched entry fields")
+	}
+
+	var entries []*TableEntry
+	for i := range idxs {
+		typ := strings.TrimSpace(types[i])
+		var e *TableEntry
+		if j, err := strconv.Atoi(idxs[i]); err == nil && j >= 0 && j < len(existing) && entryType(existing[j]) == typ {
+			e = existing[j]
+		} else {
+			r, err := NewRewardOfType(typ, "")
+			if err != nil {
+				return nil, err
+			}
+			// table entries have no ids, and items no type
+			delete(r.Base().raw, "id")
+			r.Base().ID = ""
+			if typ == "item" {
+				delete(r.Base().raw, "type")
+			}
+			e = &TableEntry{Reward: r, Weight: tableEntryWeight}
+		}
+		count, _ := strconv.Atoi(strings.TrimSpace(counts[i]))
+		if err := e.Reward.SetForm(strings.TrimSpace(values[i]), count); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		if w := strings.TrimSpace(weights[i]); w != "" {
+			f, err := strconv.ParseFloat(w, 64)
+			if err != nil || f < 0 {
+				return nil, fmt.Errorf("entry %d: invalid weight %q", i+1, w)
+			}
+			e.Weight = f
+		} else {
+			e.Weight = tableEntryWeight
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// entryType returns the type of e's reward, which is "item" when unset.
+func entryType(e *TableEntry) string {
+	if t := e.Reward.Base().Type; t != "" {
+		return t
+	}
+	return "item"
+}
+
+// SaveRewardTable writes the table named name with the title, loot size,
+// empty weight and entries of the form.
+func (q *QuestBook) SaveRewardTable(name string, form url.Values) error {
+	if q.tableByName(name) == nil {
+		return fmt.Errorf("unknown reward table %q", name)
+	}
+	// edits are made to the file, not the loaded book, in case it changed
+	path := filepath.Join(tablesDir(q.root), name+".snbt")
+	t, err := NewRewardTableFromPath(path)
+	if err != nil {
+		return err
+	}
+	t.Title = strings.TrimSpace(form.Get("title"))
+	if v := strings.TrimSpace(form.Get("loot_size")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid loot size %q", v)
+		}
+		t.LootSize = n
+	}
+	if v := strings.TrimSpace(form.Get("empty_weight")); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("invalid empty weight %q", v)
+		}
+		t.EmptyWeight = f
+	} else {
+		t.EmptyWeight = 0
+	}
+	if t.Entries, err = tableEntriesFromForm(t.Entries, form); err != nil {
+		return err
+	}
+	t.Sync()
+	w := newBookWrite(q.Lang)
+	if err := w.stageSNBT(path, t.raw); err != nil {
+		return err
+	}
+	return w.commit()
+}
+
+// rewardTable handles GET "/tables/{table}", the editor of a reward table.
+func (a *App) rewardTable(w http.ResponseWriter, r *http.Request) {
+	t := a.QB().tableByName(chi.URLParam(r, "table"))
+	if t == nil {
+		http.NotFound(w, r)
+		return
+	}
+	data := a.baseData(r, t.DisplayTitle())
+	data["Table"] = t
+	data["SelectedTable"] = t.Name
+	data["RewardTypes"] = RewardTypes
+	data["UsedBy"] = a.QB().tableUsers(t)
+	data["Msg"] = r.URL.Query().Get("msg")
+	a.render(w, "reward_table.gohtml", data)
+}
+
+// tableUsers returns the quests with rewards rolling from t.
+func (q *QuestBook) tableUsers(t *RewardTable) []*Quest {
+	var users []*Quest
+	for _, qu := range q.Quests {
+		if slices.ContainsFunc(qu.Rewards, func(r Reward) bool { return q.RewardTable(r) == t }) {
+			users = append(users, qu)
+		}
+	}
+	return users
+}
+
+// rewardTableSave handles POST "/tables/{table}/save".
+func (a *App) rewardTableSave(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "table")
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	if err := a.QB().SaveRewardTable(name, r.PostForm); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.audit(r, "edit reward table", "", nil, name)
+	a.reload()
+	http.Redirect(w, r, "/tables/"+name+"?msg="+url.QueryEscape("Saved."), http.StatusSeeOther)
+}
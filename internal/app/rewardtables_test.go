@@ -0,0 +1,124 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewardTables(t *testing.T) {
+	a := testApp(t)
+	dir := tablesDir(a.Root())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	table := `{
+	id: "00000000000000FF"
+	loot_size: 1
+	rewards: [
+		{ count: 16, item: "minecraft:iron_ingot" }
+		{ item: { Count: 1b, id: "minecraft:diamond_sword", tag: { Damage: 0 } }, weight: 3.0f }
+		{ type: "xp", xp: 50 }
+	]
+	title: "Iron Loot"
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "iron.snbt"), []byte(table), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	qb := a.QB()
+	if len(qb.Tables) != 1 {
+		t.Fatalf("tables = %v, failures = %v", qb.Tables, qb.Failures)
+	}
+	tb := qb.Tables[0]
+	if tb.Key() != "255" || tb.DisplayTitle() != "Iron Loot" || len(tb.Entries) != 3 {
+		t.Fatalf("table = %+v", tb)
+	}
+	if tb.Entries[1].Weight != 3 || tb.Chance(tb.Entries[0]) != 20 {
+		t.Errorf("weights %v, chance %v", tb.Entries[1].Weight, tb.Chance(tb.Entries[0]))
+	}
+
+	// rewards resolve the table by its number, and unknown tables are issues
+	q := qb.Chapters[0].Quests[0]
+	loot := &LootReward{RewardBase: RewardBase{raw: map[string]any{}, ID: "0000000000000AAA", Type: "loot"}, TableID: "255"}
+	missing := &LootReward{RewardBase: RewardBase{raw: map[string]any{}, ID: "0000000000000AAB", Type: "random"}, TableID: "256"}
+	if qb.RewardTable(loot) != tb || qb.RewardTable(missing) != nil || qb.RewardTable(&ItemReward{}) != nil {
+		t.Error("RewardTable")
+	}
+	q.Rewards = append(q.Rewards, loot, missing)
+	var found []string
+	for _, is := range validateBook(qb) {
+		if is.Kind == IssueMissingTable {
+			found = append(found, is.Message)
+		}
+	}
+	if len(found) != 1 || !strings.Contains(found[0], "unknown reward table 256") {
+		t.Errorf("issues = %v", found)
+	}
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/chapter/test/"+q.ID, nil))
+	if body := rec.Body.String(); !strings.Contains(body, `href="/tables/iron"`) || !strings.Contains(body, "(60%)") {
+		t.Error("quest page doesn't show the table")
+	}
+
+	// the sidebar lists the tables, and the table page edits them
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/tables/iron", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, `data-list="reward-tables"`) || !strings.Contains(body, "20.0%") {
+		t.Errorf("table page: %d", rec.Code)
+	}
+	form := url.Values{
+		"title":        {"Iron Loot"},
+		"loot_size":    {"2"},
+		"empty_weight": {""},
+		"entry_index":  {"1", "0", ""},
+		"entry_type":   {"item", "item", "command"},
+		"entry_value":  {"minecraft:diamond_sword", "minecraft:iron_ingot", "/say hi"},
+		"entry_count":  {"", "8", ""},
+		"entry_weight": {"2.5", "1", "1"},
+	}
+	req := httptest.NewRequest("POST", "/tables/iron/save", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("save: %d %s", rec.Code, rec.Body)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "iron.snbt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{"Damage: 0", "weight: 2.5f", "count: 8", `command: "/say hi"`, "loot_size: 2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in:\n%s", want, got)
+		}
+	}
+	// entries have no ids, and weights of 1 are left out
+	if strings.Contains(got, "xp: 50") || strings.Contains(got, "weight: 1") || strings.Count(got, "id:") != 2 {
+		t.Errorf("saved table:\n%s", got)
+	}
+	tb = a.QB().Tables[0]
+	if len(tb.Entries) != 3 || tb.LootSize != 2 || tb.Entries[0].Weight != 2.5 {
+		t.Errorf("reloaded table = %+v", tb)
+	}
+
+	for _, bad := range []url.Values{
+		{"loot_size": {"0"}},
+		{"entry_index": {""}, "entry_type": {"item"}, "entry_value": {"minecraft:stone"}, "entry_count": {""}, "entry_weight": {"heavy"}},
+		{"entry_index": {""}, "entry_type": {"item"}, "entry_value": {"minecraft:stone"}},
+	} {
+		req := httptest.NewRequest("POST", "/tables/iron/save", strings.NewReader(bad.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("save %v: %d", bad, rec.Code)
+		}
+	}
+}
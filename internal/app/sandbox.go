@@ -0,0 +1,371 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// Sandbox is an active sandbox: a scratch copy of the book to try out edits
+// on. Every edit in qbedit writes files, so the quest files and the lang file
+// are copied to a temporary directory and the book is loaded from there; all
+// of the editor's pages and bulk operations then work on the copy. The
+// changes can be reviewed as diffs against the real files, and then applied
+// to the book in one write or thrown away.
+//
+// Edits in a sandbox aren't recorded in the activity log or committed to git;
+// applying it records a single entry. Pack settings such as palettes and
+// snippets are shared with the real book.
+type Sandbox struct {
+	// Dir is the temporary directory holding the copy.
+	Dir     string
+	Started time.Time
+	// root and langPath are the real book's
+	root, langPath string
+	// base is the directory Dir mirrors; it contains the book and usually
+	// its lang file.
+	base string
+	// langCopy is where the lang file was copied when it is outside base.
+	langCopy string
+	// originals are the real files' contents when the sandbox started, by
+	// their real path, to detect changes made to the book in the meantime.
+	originals map[string][]byte
+}
+
+// SandboxChange is a file that differs between a sandbox and the book.
+type SandboxChange struct {
+	// Path is the file's path relative to the book's parent directories.
+	Path string
+	// Kind is "added", "changed" or "removed".
+	Kind string
+	// Conflict is set when the real file changed after the sandbox started.
+	Conflict bool
+	Lines    []diffOp
+	real     string
+	copy     string
+}
+
+// newSandbox copies the book at root and its lang file to a new temporary
+// directory.
+func newSandbox(root, langPath string) (*Sandbox, error) {
+	dir, err := os.MkdirTemp("", "qbedit-sandbox-")
+	if err != nil {
+		return nil, err
+	}
+	s := &Sandbox{
+		Dir:       dir,
+		Started:   time.Now(),
+		root:      root,
+		langPath:  langPath,
+		base:      filepath.Dir(filepath.Dir(root)),
+		originals: make(map[string][]byte),
+	}
+	if langPath != "" {
+		if rel, err := filepath.Rel(s.base, langPath); err != nil || strings.HasPrefix(rel, "..") {
+			s.langCopy = filepath.Join(dir, ".lang", filepath.Base(langPath))
+		}
+	}
+	copyFile := func(path string) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		s.originals[path] = b
+		dst := s.copyOf(path)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		return writeFile(dst, b, 0644)
+	}
+	err = filepath.WalkDir(filepath.Join(root, "quests"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, tmpSuffix) {
+			return err
+		}
+		return copyFile(path)
+	})
+	if err == nil && langPath != "" {
+		if err = copyFile(langPath); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("copying the book: %w", err)
+	}
+	return s, nil
+}
+
+// Root is the sandbox's copy of the book.
+func (s *Sandbox) Root() string { return s.copyOf(s.root) }
+
+// LangPath is the sandbox's copy of the lang file, if the book has one.
+func (s *Sandbox) LangPath() string {
+	if s.langPath == "" {
+		return ""
+	}
+	return s.copyOf(s.langPath)
+}
+
+// copyOf returns where the real file at path is in the sandbox.
+func (s *Sandbox) copyOf(path string) string {
+	if path == s.langPath && s.langCopy != "" {
+		return s.langCopy
+	}
+	rel, _ := filepath.Rel(s.base, path)
+	return filepath.Join(s.Dir, rel)
+}
+
+// realPath returns the real file for the path in the sandbox.
+func (s *Sandbox) realPath(path string) string {
+	if path == s.langCopy && s.langCopy != "" {
+		return s.langPath
+	}
+	rel, _ := filepath.Rel(s.Dir, path)
+	return filepath.Join(s.base, rel)
+}
+
+// Changes lists the files that differ between the sandbox and the book on
+// disk, by path.
+func (s *Sandbox) Changes() ([]SandboxChange, error) {
+	var changes []SandboxChange
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, tmpSuffix) {
+			return err
+		}
+		real := s.realPath(path)
+		seen[real] = true
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		cur, err := os.ReadFile(real)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if bytes.Equal(b, cur) && cur != nil {
+			return nil
+		}
+		c := s.change(real, path, cur, b)
+		c.Kind = "changed"
+		if cur == nil {
+			c.Kind = "added"
+		}
+		changes = append(changes, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for real := range s.originals {
+		if seen[real] {
+			continue
+		}
+		cur, err := os.ReadFile(real)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		c := s.change(real, s.copyOf(real), cur, nil)
+		c.Kind = "removed"
+		changes = append(changes, c)
+	}
+	slices.SortFunc(changes, func(a, b SandboxChange) int { return strings.Compare(a.Path, b.Path) })
+	return changes, nil
+}
+
+// change describes the change of the real file from cur to b.
+func (s *Sandbox) change(real, copy string, cur, b []byte) SandboxChange {
+	rel, _ := filepath.Rel(s.base, real)
+	if strings.HasPrefix(rel, "..") {
+		rel = real
+	}
+	c := SandboxChange{Path: rel, real: real, copy: copy}
+	c.Conflict = !bytes.Equal(s.originals[real], cur)
+	d := unifiedDiff("book/"+rel, "sandbox/"+rel, indentedText(real, cur), indentedText(real, b), 3)
+	for _, l := range splitLines(d) {
+		if strings.HasPrefix(l, "---") || strings.HasPrefix(l, "+++") {
+			continue
+		}
+		c.Lines = append(c.Lines, diffOp{Kind: l[0], Line: l[1:]})
+	}
+	return c
+}
+
+// indentedText returns the contents of a file to diff. SNBT files are indented
+// first, as they are usually written on a single line.
+func indentedText(path string, b []byte) string {
+	if filepath.Ext(path) != ".snbt" || b == nil {
+		return string(b)
+	}
+	v, err := snbt.Decode(bytes.NewReader(b))
+	if err != nil {
+		return string(b)
+	}
+	var buf bytes.Buffer
+	if err := snbt.EncodeIndent(&buf, v); err != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+// apply writes the sandbox's changes to the book. Nothing is written if a
+// file was changed outside the sandbox after it started.
+func (s *Sandbox) apply() ([]SandboxChange, error) {
+	changes, err := s.Changes()
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []string
+	for _, c := range changes {
+		if c.Conflict {
+			conflicts = append(conflicts, c.Path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("changed outside the sandbox since it started: %s", strings.Join(conflicts, ", "))
+	}
+	t := newBookWrite(nil)
+	for _, c := range changes {
+		if c.Kind == "removed" {
+			if err := checkRemove(c.real); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		b, err := os.ReadFile(c.copy)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(c.real), 0755); err != nil {
+			return nil, err
+		}
+		t.stage(c.real, b)
+	}
+	if err := t.commit(); err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		if c.Kind == "removed" {
+			recordRemove(c.real)
+			if err := os.Remove(c.real); err != nil {
+				return changes, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// startSandbox switches the app to a new sandbox. It is called with writeMu
+// held, so no edit is writing to the book while its root changes.
+func (a *App) startSandbox() error {
+	if a.sandbox.Load() != nil {
+		return errors.New("a sandbox is already active")
+	}
+	s, err := newSandbox(a.Root(), a.LangPath())
+	if err != nil {
+		return err
+	}
+	shareProtection(s.Root(), a.Root())
+	a.sandbox.Store(s)
+	a.setPaths(s.Root(), s.LangPath())
+	a.reload()
+	return nil
+}
+
+// bookRoot returns the root of the real book, which Root isn't while a
+// sandbox is active.
+func (a *App) bookRoot() string {
+	if s := a.sandbox.Load(); s != nil {
+		return s.root
+	}
+	return a.Root()
+}
+
+// endSandbox switches the app back to the real book and removes the sandbox.
+// If its changes were applied, a lang file the sandbox started using, eg.
+// after localizing the book, is used by the book too.
+func (a *App) endSandbox(s *Sandbox, applied bool) {
+	langPath := s.langPath
+	if applied && a.LangPath() != "" {
+		langPath = s.realPath(a.LangPath())
+	}
+	a.setPaths(s.root, langPath)
+	a.sandbox.Store(nil)
+	dropProtection(s.Root())
+	a.reload()
+	if err := os.RemoveAll(s.Dir); err != nil {
+		slog.Error("removing sandbox", "dir", s.Dir, "error", err)
+	}
+}
+
+// sandboxPage handles GET "/sandbox", which starts a sandbox or shows the
+// changes made in the active one.
+func (a *App) sandboxPage(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Sandbox")
+	data["Msg"] = r.URL.Query().Get("msg")
+	if s := a.sandbox.Load(); s != nil {
+		changes, err := s.Changes()
+		if err != nil {
+			data["Err"] = err.Error()
+		}
+		data["Sandbox"] = s
+		data["Changes"] = changes
+	}
+	a.render(w, "sandbox.gohtml", data)
+}
+
+// sandboxStart handles POST "/sandbox/start".
+func (a *App) sandboxStart(w http.ResponseWriter, r *http.Request) {
+	if err := a.startSandbox(); err != nil {
+		http.Error(w, "starting sandbox: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, "/sandbox?msg="+url.QueryEscape("Sandbox started: edits now go to a copy of the book."), http.StatusSeeOther)
+}
+
+// sandboxApply handles POST "/sandbox/apply", which writes the sandbox's
+// changes to the book and ends it.
+func (a *App) sandboxApply(w http.ResponseWriter, r *http.Request) {
+	s := a.sandbox.Load()
+	if s == nil {
+		http.Error(w, "no sandbox is active", http.StatusBadRequest)
+		return
+	}
+	changes, err := s.apply()
+	if err != nil {
+		http.Redirect(w, r, "/sandbox?msg="+url.QueryEscape("Not applied: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+	a.endSandbox(s, true)
+	var files []string
+	for _, c := range changes {
+		files = append(files, c.Path)
+	}
+	a.audit(r, "apply sandbox", "", nil, strings.Join(files, ", "))
+	http.Redirect(w, r, "/sandbox?msg="+url.QueryEscape(fmt.Sprintf("Applied %d changed files to the book.", len(changes))), http.StatusSeeOther)
+}
+
+// sandboxDiscard handles POST "/sandbox/discard", which ends the sandbox
+// without changing the book.
+func (a *App) sandboxDiscard(w http.ResponseWriter, r *http.Request) {
+	s := a.sandbox.Load()
+	if s == nil {
+		http.Error(w, "no sandbox is active", http.StatusBadRequest)
+		return
+	}
+	a.endSandbox(s, false)
+	http.Redirect(w, r, "/sandbox?msg="+url.QueryEscape("Sandbox discarded."), http.StatusSeeOther)
+}
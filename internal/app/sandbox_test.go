@@ -0,0 +1,118 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSandbox(t *testing.T) {
+	a := testApp(t)
+	root := a.Root()
+	path := filepath.Join(root, "quests", "chapters", "test.snbt")
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := a.QB().Chapters[0].Quests[0]
+	post := func(target string, form url.Values) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	move := func() {
+		t.Helper()
+		if rec := post("/chapter/test/positions", url.Values{"id": {q.ID}, "x": {"7"}, "y": {"8"}}); rec.Code != http.StatusOK {
+			t.Fatalf("move: %d %s", rec.Code, rec.Body)
+		}
+	}
+	page := func() string {
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/sandbox", nil))
+		return rec.Body.String()
+	}
+
+	// discarding leaves the book as it was
+	if rec := post("/sandbox/start", nil); rec.Code != http.StatusSeeOther {
+		t.Fatalf("start: %d %s", rec.Code, rec.Body)
+	}
+	move()
+	if a.QB().questMap[q.ID].X != 7 {
+		t.Error("edit not loaded from the sandbox")
+	}
+	if b, _ := os.ReadFile(path); !bytes.Equal(b, before) {
+		t.Error("sandboxed edit written to the book")
+	}
+	if p := page(); !strings.Contains(p, "chapters/test.snbt") || !strings.Contains(p, "7.0d") {
+		t.Errorf("sandbox page doesn't show the change:\n%s", p)
+	}
+	dir := a.sandbox.Load().Dir
+	post("/sandbox/discard", nil)
+	if a.Root() != root || a.QB().questMap[q.ID].X == 7 {
+		t.Errorf("after discard root = %s, quest at %v", a.Root(), a.QB().questMap[q.ID].X)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("sandbox dir left behind: %v", err)
+	}
+
+	// a file changed outside the sandbox can't be overwritten
+	post("/sandbox/start", nil)
+	move()
+	if err := os.WriteFile(path, append(before, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+	post("/sandbox/apply", nil)
+	if a.sandbox.Load() == nil {
+		t.Fatal("applied over a conflicting change")
+	}
+	if err := os.WriteFile(path, before, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// applying writes the changes to the book
+	post("/sandbox/apply", nil)
+	if a.sandbox.Load() != nil || a.Root() != root {
+		t.Fatal("sandbox still active after apply")
+	}
+	if a.QB().questMap[q.ID].X != 7 {
+		t.Error("applied change not loaded")
+	}
+	if b, _ := os.ReadFile(path); bytes.Equal(b, before) {
+		t.Error("applied change not written")
+	}
+}
+
+// TestSandboxSwitchRace reads the book while sandboxes start and end, for
+// go test -race.
+func TestSandboxSwitchRace(t *testing.T) {
+	a := testApp(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 20 {
+			rec := httptest.NewRecorder()
+			a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/chapter/test/raw", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("raw: %d", rec.Code)
+				return
+			}
+		}
+	}()
+	for range 5 {
+		for _, target := range []string{"/sandbox/start", "/sandbox/discard"} {
+			rec := httptest.NewRecorder()
+			a.Router().ServeHTTP(rec, httptest.NewRequest("POST", target, nil))
+			if rec.Code >= 400 {
+				t.Fatalf("%s: %d %s", target, rec.Code, rec.Body)
+			}
+		}
+	}
+	<-done
+}
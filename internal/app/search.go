@@ -1,6 +1,13 @@
 package app
 
-import "strings"
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 // stripCodes removes Minecraft color/format codes (eg, &a, §b, &r) from a string.
 // It preserves all other characters and does not alter case.
@@ -27,25 +34,260 @@ func stripCodes(s string) string {
 	return string(b)
 }
 
-// matchQuest reports whether all query terms appear as substrings in any of the
-// quest's text fields (title, subtitle, description, or GetTitle fallback).
-// Terms should be pre-split; case-insensitive mode lowercases the fields.
-func matchQuest(qs *Quest, terms []string, caseSensitive bool) bool {
-	if len(terms) == 0 {
-		return true
+// A matcher finds a search term in text, either as a literal substring or,
+// in regex mode, as a regular expression. Handlers build one per request with
+// newMatcher so that patterns are only compiled once.
+type matcher struct {
+	term string
+	ci   bool
+	re   *regexp.Regexp
+	// word only accepts matches that start and end on word boundaries.
+	word bool
+}
+
+// newMatcher returns a matcher for term. If regex is true term is compiled
+// as a regular expression, and a syntax error is returned as is.
+func newMatcher(term string, regex, ci bool) (*matcher, error) {
+	if !regex {
+		if ci {
+			term = strings.ToLower(term)
+		}
+		return &matcher{term: term, ci: ci}, nil
+	}
+	re, err := regexp.Compile(term)
+	if err != nil {
+		return nil, err
+	}
+	if ci {
+		re = regexp.MustCompile("(?i)" + term)
+	}
+	return &matcher{re: re}, nil
+}
+
+// index returns the [start, end) byte offsets of each non-overlapping match
+// in s. Empty regex matches are skipped. In case-insensitive literal mode the
+// offsets are into the lowercased s.
+func (m *matcher) index(s string) [][]int {
+	locs := m.find(s)
+	if !m.word {
+		return locs
+	}
+	if m.re == nil && m.ci {
+		s = strings.ToLower(s)
+	}
+	words := locs[:0]
+	for _, loc := range locs {
+		if wordBoundary(s, loc[0]) && wordBoundary(s, loc[1]) {
+			words = append(words, loc)
+		}
+	}
+	return words
+}
+
+// wordBoundary reports whether i in s is between a word and a non-word
+// character (or the start or end of s).
+func wordBoundary(s string, i int) bool {
+	before, _ := utf8.DecodeLastRuneInString(s[:i])
+	after, _ := utf8.DecodeRuneInString(s[i:])
+	return i == 0 || i == len(s) || isWordRune(before) != isWordRune(after)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (m *matcher) find(s string) [][]int {
+	if m.re != nil {
+		var locs [][]int
+		for _, loc := range m.re.FindAllStringIndex(s, -1) {
+			if loc[1] > loc[0] {
+				locs = append(locs, loc)
+			}
+		}
+		return locs
+	}
+	if m.term == "" {
+		return nil
+	}
+	if m.ci {
+		s = strings.ToLower(s)
+	}
+	var locs [][]int
+	for start := 0; start <= len(s)-len(m.term); {
+		idx := strings.Index(s[start:], m.term)
+		if idx < 0 {
+			break
+		}
+		pos := start + idx
+		locs = append(locs, []int{pos, pos + len(m.term)})
+		start = pos + len(m.term)
+	}
+	return locs
+}
+
+// match reports whether s contains a match.
+func (m *matcher) match(s string) bool {
+	if m.word {
+		return len(m.index(s)) > 0
+	}
+	if m.re != nil {
+		return m.re.MatchString(s)
+	}
+	if m.ci {
+		s = strings.ToLower(s)
+	}
+	return strings.Contains(s, m.term)
+}
+
+// searchFields are the quest fields a batch search can be limited to.
+var searchFields = []string{"title", "subtitle", "description"}
+
+// searchOptions are the qualifiers of a batch search.
+type searchOptions struct {
+	Regex         bool
+	CaseSensitive bool
+	WholeWord     bool
+	// Fields limits the search to some of searchFields; empty means all.
+	Fields []string
+}
+
+// searchOptionsFrom reads search qualifiers from a batch search query:
+// case, regex, word and any number of in=<field>.
+func searchOptionsFrom(v url.Values) searchOptions {
+	opts := searchOptions{
+		Regex:         v.Has("regex"),
+		CaseSensitive: v.Has("case"),
+		WholeWord:     v.Has("word"),
+	}
+	for _, f := range v["in"] {
+		if slices.Contains(searchFields, f) && !slices.Contains(opts.Fields, f) {
+			opts.Fields = append(opts.Fields, f)
+		}
 	}
-	t1 := stripCodes(qs.Title)
-	t2 := stripCodes(qs.Subtitle)
-	t3 := stripCodes(qs.Description)
-	t4 := stripCodes(qs.GetTitle())
-	if !caseSensitive {
-		t1 = strings.ToLower(t1)
-		t2 = strings.ToLower(t2)
-		t3 = strings.ToLower(t3)
-		t4 = strings.ToLower(t4)
+	return opts
+}
+
+// searchMatchers builds the matchers for a batch search query. Literal
+// queries are split into whitespace separated terms; a regex query is used
+// whole.
+func searchMatchers(q string, opts searchOptions) ([]*matcher, error) {
+	var terms []string
+	if opts.Regex {
+		if q != "" {
+			terms = []string{q}
+		}
+	} else {
+		terms = strings.Fields(q)
 	}
+	ms := make([]*matcher, 0, len(terms))
 	for _, term := range terms {
-		if !(strings.Contains(t1, term) || strings.Contains(t2, term) || strings.Contains(t3, term) || strings.Contains(t4, term)) {
+		m, err := newMatcher(term, opts.Regex, !opts.CaseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		m.word = opts.WholeWord
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// matchQuest reports whether every matcher matches any of the quest's text
+// fields (title, subtitle, description), or only those in fields if it is
+// not empty. An untitled quest's GetTitle fallback counts as its title.
+// Fields are searched with color codes stripped; regex matchers also see the
+// raw text, so that patterns for the codes themselves (eg. &[0-9a-f]{2}) work.
+func matchQuest(qs *Quest, ms []*matcher, fields []string) bool {
+	return newQuestText(qs).match(ms, fields)
+}
+
+// match is matchQuest on text that has already been stripped; see
+// textindex.go.
+func (t *questText) match(ms []*matcher, fields []string) bool {
+	for _, m := range ms {
+		found := false
+		for i, f := range t.fields {
+			if len(fields) > 0 && !slices.Contains(fields, searchFields[i]) {
+				continue
+			}
+			for j := range f.text {
+				if m.matchLower(f.text[j], f.lower[j]) {
+					found = true
+				}
+			}
+			if !found && m.re != nil && m.match(f.raw) {
+				found = true
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// A rewardFilter limits a batch search to quests with a matching reward. They
+// are written in the query as reward:<value> or reward-type:<type>, eg.
+// reward:minecraft:diamond or reward-type:xp, and several must all match.
+type rewardFilter struct {
+	// typ matches the reward type rather than its value.
+	typ   bool
+	value string
+}
+
+// splitSearchFilters removes reward filters from a search query, returning
+// the rest of the query and the filters. A query without filters is
+// returned unchanged.
+func splitSearchFilters(q string) (string, []rewardFilter) {
+	var rest []string
+	var filters []rewardFilter
+	for _, f := range strings.Fields(q) {
+		lf := strings.ToLower(f)
+		switch {
+		case strings.HasPrefix(lf, "reward-type:") && len(lf) > len("reward-type:"):
+			filters = append(filters, rewardFilter{typ: true, value: lf[len("reward-type:"):]})
+		case strings.HasPrefix(lf, "reward:") && len(lf) > len("reward:"):
+			filters = append(filters, rewardFilter{value: lf[len("reward:"):]})
+		default:
+			rest = append(rest, f)
+		}
+	}
+	if len(filters) == 0 {
+		return q, nil
+	}
+	return strings.Join(rest, " "), filters
+}
+
+// matchReward reports whether r matches f. Item rewards match their item
+// id, with or without its namespace; command rewards match any part of the
+// command; and table rewards match the table id.
+func (f rewardFilter) matchReward(r Reward) bool {
+	if f.typ {
+		typ := r.Base().Type
+		if typ == "" {
+			typ = "item"
+		}
+		return typ == f.value
+	}
+	v := strings.ToLower(r.FormValue())
+	switch r.(type) {
+	case *ItemReward:
+		_, path, _ := strings.Cut(v, ":")
+		return v == f.value || path == f.value
+	case *CommandReward:
+		return strings.Contains(v, f.value)
+	case *LootReward:
+		return v == f.value
+	}
+	return false
+}
+
+// matchRewards reports whether the quest has a reward matching each filter.
+func matchRewards(q *Quest, filters []rewardFilter) bool {
+	for _, f := range filters {
+		if !slices.ContainsFunc(q.Rewards, f.matchReward) {
 			return false
 		}
 	}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestMatcherIndex(t *testing.T) {
+	m, _ := newMatcher("ab", false, true)
+	if got := m.index("xABab"); !slices.EqualFunc(got, [][]int{{1, 3}, {3, 5}}, slices.Equal) {
+		t.Errorf("literal: got %v", got)
+	}
+	m, err := newMatcher(`ore_\w+`, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.index("iron ore_block, ORE_x"); !slices.EqualFunc(got, [][]int{{5, 14}}, slices.Equal) {
+		t.Errorf("regex: got %v", got)
+	}
+	m, _ = newMatcher(`x*`, true, false)
+	if got := m.index("abc"); len(got) != 0 {
+		t.Errorf("expected empty matches to be skipped, got %v", got)
+	}
+	if _, err := newMatcher(`(`, true, false); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestMatchQuestRegex(t *testing.T) {
+	q := &Quest{Title: "&6&lGold Ingot", Description: "Smelt minecraft:raw_gold"}
+	cases := []struct {
+		q     string
+		regex bool
+		want  bool
+	}{
+		{"gold smelt", false, true},
+		{"gold copper", false, false},
+		{`&[0-9a-f]&l`, true, true},
+		{`minecraft:raw_\w+`, true, true},
+		{`^gold`, true, true},
+		{`^Gold$`, true, false},
+	}
+	for _, c := range cases {
+		ms, err := searchMatchers(c.q, searchOptions{Regex: c.regex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matchQuest(q, ms, nil); got != c.want {
+			t.Errorf("matchQuest(%q, regex=%v) = %v, want %v", c.q, c.regex, got, c.want)
+		}
+	}
+}
+
+func TestMatchQuestQualifiers(t *testing.T) {
+	q := &Quest{Title: "Ironwood", Subtitle: "an iron tree", Description: "Grows &ebig&r."}
+	cases := []struct {
+		q    string
+		opts searchOptions
+		want bool
+	}{
+		{"iron", searchOptions{}, true},
+		{"iron", searchOptions{Fields: []string{"title"}}, true},
+		{"iron", searchOptions{Fields: []string{"description"}}, false},
+		{"iron", searchOptions{WholeWord: true}, true},
+		{"iron", searchOptions{WholeWord: true, Fields: []string{"title"}}, false},
+		{"IRON", searchOptions{WholeWord: true, CaseSensitive: true}, false},
+		{"big", searchOptions{WholeWord: true, Fields: []string{"description"}}, true},
+		{`gr\w+`, searchOptions{Regex: true, WholeWord: true}, true},
+		{`gr`, searchOptions{Regex: true, WholeWord: true}, false},
+	}
+	for _, c := range cases {
+		ms, err := searchMatchers(c.q, c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matchQuest(q, ms, c.opts.Fields); got != c.want {
+			t.Errorf("matchQuest(%q, %+v) = %v, want %v", c.q, c.opts, got, c.want)
+		}
+	}
+}
+
+func TestSearchOptionsFrom(t *testing.T) {
+	v, _ := url.ParseQuery("in=title&in=bogus&in=title&word=1&case")
+	opts := searchOptionsFrom(v)
+	if !opts.WholeWord || !opts.CaseSensitive || opts.Regex || !slices.Equal(opts.Fields, []string{"title"}) {
+		t.Errorf("got %+v", opts)
+	}
+}
+
+func TestRewardFilters(t *testing.T) {
+	q, err := NewQuest(map[string]any{"id": "0000000000000001", "rewards": []any{
+		map[string]any{"id": "1", "type": "item", "item": map[string]any{"id": "minecraft:diamond", "Count": 1}},
+		map[string]any{"id": "2", "type": "command", "command": "/gamestage add @p Iron"},
+		map[string]any{"id": "3", "type": "xp", "xp": 100},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		q, rest string
+		want    bool
+	}{
+		{"reward:minecraft:diamond", "", true},
+		{"reward:Diamond ingots", "ingots", true},
+		{"reward:minecraft:diamond_block", "", false},
+		{"reward:gamestage reward-type:XP", "", true},
+		{"reward-type:loot", "", false},
+		{"reward: plain", "reward: plain", true},
+	}
+	for _, c := range cases {
+		rest, fs := splitSearchFilters(c.q)
+		if rest != c.rest {
+			t.Errorf("splitSearchFilters(%q) left %q, want %q", c.q, rest, c.rest)
+		}
+		if got := matchRewards(q, fs); got != c.want {
+			t.Errorf("matchRewards(%q) = %v, want %v", c.q, got, c.want)
+		}
+	}
+}
+
+func TestRecolorStringRegex(t *testing.T) {
+	m, _ := newMatcher(`ingots?`, true, false)
+	if got := recolorString("an ingot and &eingots", m, "c", '&'); got != "an &cingot&r and &cingots" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// maxShareTTL bounds how long a share link can stay valid.
+const maxShareTTL = 90 * 24 * time.Hour
+
+var (
+	errShareExpired = errors.New("this link has expired")
+	errShareInvalid = errors.New("this link is not valid")
+)
+
+// SetShareSecret derives the share link signing key from secret. An empty
+// secret picks a random key, and links stop working when qbedit restarts.
+func (a *App) SetShareSecret(secret string) {
+	if secret == "" {
+		a.shareKey = make([]byte, 32)
+		_, _ = rand.Read(a.shareKey)
+		return
+	}
+	sum := sha256.Sum256([]byte("qbedit-share:" + secret))
+	a.shareKey = sum[:]
+}
+
+// shareSig signs a quest id and expiry.
+func (a *App) shareSig(questID string, exp int64) string {
+	mac := hmac.New(sha256.New, a.shareKey)
+	fmt.Fprintf(mac, "%s:%d", questID, exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// shareURL returns the path of a share link for questID that expires at exp.
+func (a *App) shareURL(questID string, exp time.Time) string {
+	v := url.Values{}
+	v.Set("exp", strconv.FormatInt(exp.Unix(), 10))
+	v.Set("sig", a.shareSig(questID, exp.Unix()))
+	return a.Base + "/share/" + url.PathEscape(questID) + "?" + v.Encode()
+}
+
+// verifyShare checks a share link's signature and expiry.
+func (a *App) verifyShare(questID, exp, sig string, now time.Time) error {
+	n, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return errShareInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(a.shareSig(questID, n))) {
+		return errShareInvalid
+	}
+	if now.Unix() > n {
+		return errShareExpired
+	}
+	return nil
+}
+
+// parseTTL parses a share link lifetime, either a Go duration ("12h") or a
+// number of days ("7d").
+func parseTTL(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ttl %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid ttl %q", s)
+		}
+	}
+	if d <= 0 || d > maxShareTTL {
+		return 0, fmt.Errorf("ttl must be between 1s and %dd", int(maxShareTTL.Hours()/24))
+	}
+	return d, nil
+}
+
+// shareLink turns a path on ShareRouter into a full URL. The host is
+// ShareAddr's, or r's if ShareAddr listens on every interface.
+func (a *App) shareLink(r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(a.ShareAddr)
+	if err != nil {
+		return scheme + "://" + a.ShareAddr + path
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(host, port) + path
+}
+
+// ShareRouter serves share links and nothing else: the quest a link is signed
+// for and the stylesheets its page uses. Every other path is not found, so it
+// can be served where the editor can't, letting playtesters read a single
+// quest without access to the editor. A link names the quest and an expiry
+// time and is signed with an HMAC key, so it can't be altered to show another
+// quest or to live longer.
+func (a *App) ShareRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RealIP)
+	r.Use(middleware.Recoverer)
+	staticFS, _ := fs.Sub(templatesFS, "static")
+	static := http.StripPrefix("/static/", http.FileServer(http.FS(staticFS)))
+	r.Handle("/static/app.css", static)
+	r.Handle("/static/minecraft.css", static)
+	r.Get("/share/{quest}", a.sharedQuest)
+	return r
+}
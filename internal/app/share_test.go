@@ -0,0 +1,102 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareLinks(t *testing.T) {
+	a := &App{}
+	a.SetShareSecret("hunter2")
+	now := time.Unix(1700000000, 0)
+	exp := now.Add(time.Hour).Unix()
+	sig := a.shareSig("ABCD", exp)
+	e := "1700003600"
+
+	if err := a.verifyShare("ABCD", e, sig, now); err != nil {
+		t.Errorf("valid link: %v", err)
+	}
+	if err := a.verifyShare("ABCE", e, sig, now); err != errShareInvalid {
+		t.Errorf("other quest: got %v", err)
+	}
+	if err := a.verifyShare("ABCD", "1700007200", sig, now); err != errShareInvalid {
+		t.Errorf("extended expiry: got %v", err)
+	}
+	if err := a.verifyShare("ABCD", e, sig, now.Add(2*time.Hour)); err != errShareExpired {
+		t.Errorf("expired link: got %v", err)
+	}
+
+	b := &App{}
+	b.SetShareSecret("")
+	if err := b.verifyShare("ABCD", e, sig, now); err != errShareInvalid {
+		t.Errorf("other key: got %v", err)
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	for in, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "12h": 12 * time.Hour} {
+		if got, err := parseTTL(in); err != nil || got != want {
+			t.Errorf("parseTTL(%q) = %v, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"", "0d", "-1h", "365d", "soon"} {
+		if _, err := parseTTL(in); err == nil {
+			t.Errorf("parseTTL(%q): expected error", in)
+		}
+	}
+}
+
+func TestShareRouter(t *testing.T) {
+	a := testApp(t)
+	q := a.QB().Quests[0]
+	share := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/chapter/test/"+q.ID+"/share", strings.NewReader("ttl=1d"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+		req.Host = "books.example:8222"
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := share(); rec.Code != http.StatusBadRequest {
+		t.Errorf("share without ShareAddr: %d", rec.Code)
+	}
+	a.ShareAddr = "0.0.0.0:8223"
+	rec := share()
+	var res struct{ URL string }
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("share: %d %s", rec.Code, rec.Body)
+	}
+	link, err := url.Parse(res.URL)
+	if err != nil || link.Host != "books.example:8223" {
+		t.Fatalf("link %q", res.URL)
+	}
+
+	get := func(h http.Handler, target string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		return rec.Code
+	}
+	h := a.ShareRouter()
+	for target, want := range map[string]int{
+		link.RequestURI():                 http.StatusOK,
+		"/share/" + q.ID:                  http.StatusForbidden,
+		"/static/app.css":                 http.StatusOK,
+		"/static/app.js":                  http.StatusNotFound,
+		"/":                               http.StatusNotFound,
+		"/chapter/test/" + q.ID:           http.StatusNotFound,
+		"/chapter/test/" + q.ID + "/json": http.StatusNotFound,
+	} {
+		if code := get(h, target); code != want {
+			t.Errorf("share router %s: %d, want %d", target, code, want)
+		}
+	}
+	if code := get(a.Router(), link.RequestURI()); code != http.StatusNotFound {
+		t.Errorf("editor served a share link: %d", code)
+	}
+}
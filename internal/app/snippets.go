@@ -0,0 +1,203 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// snippetsPath returns the snippets file for the ftbquests dir root.
+func snippetsPath(root string) string {
+	return filepath.Join(packDir(root), "snippets.json")
+}
+
+// snippetParam matches a ${name} parameter in a snippet body.
+var snippetParam = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// Snippet is a named piece of reusable description text, like a formatted
+// warning or a keybind callout, that can be inserted into a quest's
+// description. Its body may contain ${name} parameters, which are filled in
+// when it is rendered for insertion.
+type Snippet struct {
+	Name  string `json:"name"`
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body"`
+	// Defaults are parameter values used when a render doesn't provide one.
+	Defaults map[string]string `json:"defaults,omitempty"`
+}
+
+// Params returns the names of the snippet's parameters in order of first use.
+func (s Snippet) Params() []string {
+	var names []string
+	for _, m := range snippetParam.FindAllStringSubmatch(s.Body, -1) {
+		if !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// Render returns the body with its parameters replaced by their values in
+// params, or their defaults. Parameters with neither are left empty.
+func (s Snippet) Render(params map[string]string) string {
+	return snippetParam.ReplaceAllStringFunc(s.Body, func(m string) string {
+		name := m[2 : len(m)-1]
+		if v, ok := params[name]; ok && v != "" {
+			return v
+		}
+		return s.Defaults[name]
+	})
+}
+
+// defaultSnippets seed a pack's library until it has been saved.
+var defaultSnippets = []Snippet{
+	{Name: "warning", Title: "Warning", Body: "&c&lWarning:&r &c${text}&r", Defaults: map[string]string{"text": "This can't be undone."}},
+	{Name: "tip", Title: "Tip", Body: "&e&lTip:&r ${text}"},
+	{Name: "keybind", Title: "Keybind", Body: "Press &6[${key}]&r to ${action}.", Defaults: map[string]string{"key": "Shift"}},
+}
+
+// validSnippetName is the form of a snippet's name, which is used in urls.
+var validSnippetName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// SnippetStore is a pack's file backed snippet library. The file is kept with
+// the pack (see snippetsPath), so everyone editing it shares the snippets.
+type SnippetStore struct {
+	path     string
+	mu       sync.Mutex
+	snippets map[string]Snippet
+}
+
+// OpenSnippets loads the snippet library at path. A missing file holds the
+// default snippets.
+func OpenSnippets(path string) (*SnippetStore, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return newSnippetStore(path), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Snippet
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s := &SnippetStore{path: path, snippets: make(map[string]Snippet)}
+	for _, sn := range list {
+		s.snippets[sn.Name] = sn
+	}
+	return s, nil
+}
+
+// newSnippetStore returns a library of the default snippets saved to path.
+func newSnippetStore(path string) *SnippetStore {
+	s := &SnippetStore{path: path, snippets: make(map[string]Snippet)}
+	for _, sn := range defaultSnippets {
+		s.snippets[sn.Name] = sn
+	}
+	return s
+}
+
+// List returns the snippets sorted by name.
+func (s *SnippetStore) List() []Snippet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Snippet, 0, len(s.snippets))
+	for _, sn := range s.snippets {
+		list = append(list, sn)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns the snippet called name.
+func (s *SnippetStore) Get(name string) (Snippet, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sn, ok := s.snippets[name]
+	return sn, ok
+}
+
+// Put adds or replaces a snippet and saves the library.
+func (s *SnippetStore) Put(sn Snippet) error {
+	if !validSnippetName.MatchString(sn.Name) {
+		return fmt.Errorf("invalid snippet name %q: use lowercase letters, digits, - and _", sn.Name)
+	}
+	if strings.TrimSpace(sn.Body) == "" {
+		return errors.New("snippet body is empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snippets[sn.Name] = sn
+	return s.save()
+}
+
+// Delete removes the snippet called name and saves the library.
+func (s *SnippetStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.snippets, name)
+	return s.save()
+}
+
+// save writes the library; s.mu must be held.
+func (s *SnippetStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	list := make([]Snippet, 0, len(s.snippets))
+	for _, sn := range s.snippets {
+		list = append(list, sn)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	// the file is meant to be readable, so keep & and friends as they are
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(list); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return writeFile(s.path, buf.Bytes(), 0644)
+}
+
+// parseDefaults reads "name=value" lines into a map.
+func parseDefaults(s string) map[string]string {
+	var m map[string]string
+	for _, line := range strings.Split(s, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		if m == nil {
+			m = make(map[string]string)
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m
+}
+
+// formatDefaults is the inverse of parseDefaults.
+func formatDefaults(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, m[k])
+	}
+	return b.String()
+}
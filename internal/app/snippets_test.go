@@ -0,0 +1,51 @@
+package app
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSnippetRender(t *testing.T) {
+	sn := Snippet{Body: "Press ${key} to ${action}, ${key}!", Defaults: map[string]string{"key": "Shift"}}
+	if got := sn.Params(); !slices.Equal(got, []string{"key", "action"}) {
+		t.Errorf("params: got %v", got)
+	}
+	if got := sn.Render(map[string]string{"action": "sneak"}); got != "Press Shift to sneak, Shift!" {
+		t.Errorf("got %q", got)
+	}
+	if got := sn.Render(map[string]string{"key": "Q"}); got != "Press Q to , Q!" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSnippetStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".qbedit", "snippets.json")
+	s, err := OpenSnippets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.List()) != len(defaultSnippets) {
+		t.Fatalf("expected the default snippets, got %v", s.List())
+	}
+	if err := s.Put(Snippet{Name: "Bad Name", Body: "x"}); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+	if err := s.Put(Snippet{Name: "note", Body: "&7${text}"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("tip"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenSnippets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get("tip"); ok {
+		t.Error("deleted snippet was loaded")
+	}
+	if sn, ok := s.Get("note"); !ok || sn.Body != "&7${text}" {
+		t.Errorf("got %+v", sn)
+	}
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// speechSeparator goes between quests. Most readers pause on it without
+// reading it aloud.
+const speechSeparator = "* * *"
+
+// speechLine returns the readable text of one description line, or "" if
+// the line has nothing to read (images, page breaks).
+func speechLine(line string) string {
+	t := strings.TrimSpace(line)
+	if t == "{@pagebreak}" || strings.HasPrefix(t, "{image:") {
+		return ""
+	}
+	if strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		var v any
+		if json.Unmarshal([]byte(t), &v) == nil {
+			var b strings.Builder
+			componentText(&b, v)
+			t = b.String()
+		}
+	}
+	return strings.TrimSpace(stripCodes(t))
+}
+
+// componentText appends the text of a JSON text component (a string, a list
+// of components, or an object with text and extra) to b.
+func componentText(b *strings.Builder, v any) {
+	switch v := v.(type) {
+	case string:
+		b.WriteString(v)
+	case []any:
+		for _, c := range v {
+			componentText(b, c)
+		}
+	case map[string]any:
+		if s, ok := v["text"].(string); ok {
+			b.WriteString(s)
+		}
+		if extra, ok := v["extra"].([]any); ok {
+			componentText(b, extra)
+		}
+	}
+}
+
+// speechTitle turns the item id that GetTitle falls back to for untitled
+// quests into words, eg. "minecraft:soul_sand" is "Soul Sand".
+func speechTitle(title string) string {
+	ns, path, ok := strings.Cut(title, ":")
+	if !ok || ns == "" || strings.ContainsAny(title, " &§") {
+		return title
+	}
+	words := strings.FieldsFunc(path, func(r rune) bool { return r == '_' || r == '/' })
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
+
+// sentence ends s with a full stop unless it already has punctuation, so
+// that readers pause after titles.
+func sentence(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	if unicode.IsPunct(r[len(r)-1]) {
+		return s
+	}
+	return s + "."
+}
+
+// speechText returns the chapter's quests as plain text in reading order, for
+// proofreading by running it through a text-to-speech reader: awkward
+// phrasing is much easier to hear than to see. Formatting codes, JSON text
+// components and images are removed, and quests are read in the order they
+// are laid out.
+func speechText(ch *Chapter) string {
+	quests := make([]*Quest, len(ch.Quests))
+	copy(quests, ch.Quests)
+	sortReadingOrder(quests)
+
+	var b strings.Builder
+	b.WriteString(sentence(speechLine(ch.Title)))
+	b.WriteString("\n")
+	n := 0
+	for _, q := range quests {
+		title := speechLine(speechTitle(q.GetTitle()))
+		sub := speechLine(q.Subtitle)
+		var paras []string
+		for _, line := range strings.Split(q.Description, "\n") {
+			if l := speechLine(line); l != "" {
+				paras = append(paras, l)
+			}
+		}
+		if title == "" && sub == "" && len(paras) == 0 {
+			continue
+		}
+		n++
+		b.WriteString("\n" + speechSeparator + "\n\n")
+		if title == "" {
+			title = "Untitled"
+		}
+		fmt.Fprintf(&b, "Quest %d: %s\n", n, sentence(title))
+		if sub != "" {
+			b.WriteString(sentence(sub) + "\n")
+		}
+		for _, p := range paras {
+			b.WriteString("\n" + p + "\n")
+		}
+	}
+	return b.String()
+}
+
+// chatLine matches a description line written as chat, "<speaker> text".
+var chatLine = regexp.MustCompile(`^<([^<>]{1,32})>\s*(.+)$`)
+
+// narrationOrder returns the chapter's quests in an order that respects
+// their dependencies: no quest comes before one it depends on in the same
+// chapter. Among the quests that are available, the one first in reading
+// order comes next. Dependency cycles are broken in reading order.
+func narrationOrder(ch *Chapter) []*Quest {
+	quests := make([]*Quest, len(ch.Quests))
+	copy(quests, ch.Quests)
+	sortReadingOrder(quests)
+
+	inChapter := make(map[string]bool, len(quests))
+	for _, q := range quests {
+		inChapter[q.ID] = true
+	}
+	done := make(map[string]bool, len(quests))
+	ready := func(q *Quest) bool {
+		for _, dep := range q.Dependencies {
+			if inChapter[dep] && !done[dep] && dep != q.ID {
+				return false
+			}
+		}
+		return true
+	}
+	order := make([]*Quest, 0, len(quests))
+	for len(quests) > 0 {
+		next := 0
+		for i, q := range quests {
+			if ready(q) {
+				next = i
+				break
+			}
+		}
+		q := quests[next]
+		quests = append(quests[:next], quests[next+1:]...)
+		done[q.ID] = true
+		order = append(order, q)
+	}
+	return order
+}
+
+// narrationScript returns the chapter's text as a narration script for
+// trailers or walkthrough videos: the chapter as a [SECTION], then each quest
+// in narrationOrder as a numbered [QUEST] heading followed by its lines, each
+// marked with who speaks it. Lines written as chat, eg. "<Steve> Hello", are
+// spoken by Steve; the rest by speaker, the narrator.
+func narrationScript(ch *Chapter, speaker string) string {
+	if speaker == "" {
+		speaker = "NARRATOR"
+	}
+	titles := make(map[string]string, len(ch.Quests))
+	for _, q := range ch.Quests {
+		titles[q.ID] = speechLine(speechTitle(q.GetTitle()))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[SECTION] %s\n", speechLine(ch.Title))
+	n := 0
+	for _, q := range narrationOrder(ch) {
+		var lines []string
+		if sub := speechLine(q.Subtitle); sub != "" {
+			lines = append(lines, fmt.Sprintf("[%s] %s", speaker, sentence(sub)))
+		}
+		for _, line := range strings.Split(q.Description, "\n") {
+			l := speechLine(line)
+			if l == "" {
+				continue
+			}
+			if m := chatLine.FindStringSubmatch(l); m != nil {
+				lines = append(lines, fmt.Sprintf("[%s] %s", strings.TrimSpace(m[1]), m[2]))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("[%s] %s", speaker, l))
+		}
+		title := titles[q.ID]
+		if title == "" && len(lines) == 0 {
+			continue
+		}
+		n++
+		if title == "" {
+			title = "Untitled"
+		}
+		fmt.Fprintf(&b, "\n[QUEST %d] %s", n, title)
+		var after []string
+		for _, dep := range q.Dependencies {
+			if t, ok := titles[dep]; ok && dep != q.ID {
+				after = append(after, t)
+			}
+		}
+		if len(after) > 0 {
+			fmt.Fprintf(&b, " (after: %s)", strings.Join(after, ", "))
+		}
+		b.WriteString("\n")
+		for _, l := range lines {
+			b.WriteString(l + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpeechLine(t *testing.T) {
+	cases := map[string]string{
+		"Punch &2trees&r!":                             "Punch trees!",
+		"{@pagebreak}":                                 "",
+		"{image:mod:textures/x.png width:10}":          "",
+		`{"text":"See ","extra":[{"text":"&aStone"}]}`: "See Stone",
+		`["",{"text":"a"},"b"]`:                        "ab",
+	}
+	for in, want := range cases {
+		if got := speechLine(in); got != want {
+			t.Errorf("speechLine(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSpeechTitle(t *testing.T) {
+	if got := speechTitle("minecraft:soul_sand"); got != "Soul Sand" {
+		t.Errorf("got %q", got)
+	}
+	if got := speechTitle("Time: later"); got != "Time: later" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSpeechText(t *testing.T) {
+	ch := &Chapter{Title: "&6Start"}
+	for _, q := range []map[string]any{
+		{"id": "B", "x": 1.0, "y": 0.0, "title": "Second", "description": []any{"Two."}},
+		{"id": "A", "x": 0.0, "y": 0.0, "title": "First", "subtitle": "sub", "description": []any{"One", "", "&lMore"}},
+	} {
+		quest, err := NewQuest(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch.Quests = append(ch.Quests, quest)
+	}
+	want := "Start.\n\n* * *\n\nQuest 1: First.\nsub.\n\nOne\n\nMore\n\n* * *\n\nQuest 2: Second.\n\nTwo.\n"
+	if got := speechText(ch); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if strings.Contains(ch.Quests[0].ID, "A") {
+		t.Fatal("speechText reordered the chapter's quests")
+	}
+}
+
+func TestNarrationScript(t *testing.T) {
+	ch := &Chapter{Title: "&6Start"}
+	for _, q := range []map[string]any{
+		// laid out first, but needs C
+		{"id": "A", "x": 0.0, "y": 0.0, "title": "Smelt", "dependencies": []any{"C", "OTHER"}, "description": []any{"<&aSteve&r> Hot!", "Use a furnace."}},
+		{"id": "B", "x": 1.0, "y": 0.0, "title": "", "description": []any{}},
+		{"id": "C", "x": 0.0, "y": 5.0, "title": "Mine", "subtitle": "Dig", "description": []any{"Get ore"}},
+	} {
+		quest, err := NewQuest(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch.Quests = append(ch.Quests, quest)
+	}
+	var ids []string
+	for _, q := range narrationOrder(ch) {
+		ids = append(ids, q.ID)
+	}
+	if strings.Join(ids, " ") != "B C A" {
+		t.Errorf("narrationOrder = %v", ids)
+	}
+	want := "[SECTION] Start\n" +
+		"\n[QUEST 1] Mine\n[NARRATOR] Dig.\n[NARRATOR] Get ore\n" +
+		"\n[QUEST 2] Smelt (after: Mine)\n[Steve] Hot!\n[NARRATOR] Use a furnace.\n"
+	if got := narrationScript(ch, ""); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := narrationScript(ch, "Guide"); !strings.Contains(got, "[Guide] Dig.") {
+		t.Errorf("speaker not used:\n%s", got)
+	}
+
+	// cycles still list every quest
+	ch.Quests[2].Dependencies = []string{"A"}
+	if got := narrationOrder(ch); len(got) != 3 {
+		t.Errorf("narrationOrder with a cycle = %v", got)
+	}
+}
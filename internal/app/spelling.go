@@ -0,0 +1,480 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Dictionary is a source of correctly spelled words, the system's word list
+// or the lists given with --dict. The spelling page checks the words of quest
+// titles, subtitles and descriptions against it and against the pack's own
+// words: its allowlist, and the words of the names of the items its quests
+// use. Words with digits or capitals after the first letter, like RF or AE2,
+// are taken to be names and aren't checked. Without a dictionary only the
+// style checks run, which find a word written twice in a row, eg. "the the".
+type Dictionary interface {
+	// Known reports whether the lower cased word is spelled correctly.
+	Known(word string) bool
+}
+
+// WordList is a Dictionary of a list of words.
+type WordList struct {
+	words map[string]bool
+}
+
+// NewWordList returns a word list of words, in any case.
+func NewWordList(words []string) *WordList {
+	l := &WordList{words: make(map[string]bool, len(words))}
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			l.words[normalizeWord(w)] = true
+		}
+	}
+	return l
+}
+
+// LoadWordList reads the word lists at paths, one word per line. Hunspell
+// .dic files work too: their count line and affix flags are skipped, though
+// without the affixes they only hold the words' stems.
+func LoadWordList(paths ...string) (*WordList, error) {
+	var words []string
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") || strings.Trim(line, "0123456789") == "" {
+				continue
+			}
+			word, _, _ := strings.Cut(line, "/")
+			words = append(words, word)
+		}
+		f.Close()
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return NewWordList(words), nil
+}
+
+// Known reports whether word is in the list.
+func (l *WordList) Known(word string) bool {
+	return l != nil && l.words[word]
+}
+
+// Len returns how many words are in the list.
+func (l *WordList) Len() int { return len(l.words) }
+
+// SystemWordLists returns the system's word lists, used when --dict isn't
+// given.
+func SystemWordLists() []string {
+	var paths []string
+	for _, p := range []string{"/usr/share/dict/words"} {
+		if _, err := os.Stat(p); err == nil {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// spellingPath returns the allowlist file for the ftbquests dir root.
+func spellingPath(root string) string {
+	return filepath.Join(packDir(root), "spelling.txt")
+}
+
+// Allowlist is the pack's file backed list of its own words, such as mod and
+// item names, which are spelled right. It is kept in .qbedit/spelling.txt so
+// it travels with the pack. Accepting a suspected typo adds it here; ignoring
+// one only does so in its quest (see SpellIgnore).
+type Allowlist struct {
+	path  string
+	mu    sync.Mutex
+	words []string
+}
+
+// OpenAllowlist loads the allowlist at path, one word per line; a missing
+// file is an empty list.
+func OpenAllowlist(path string) (*Allowlist, error) {
+	l := &Allowlist{path: path}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.words = parseLines(string(b))
+	return l, nil
+}
+
+// Words returns the words of the list.
+func (l *Allowlist) Words() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.words)
+}
+
+// Set replaces the words of the list and saves it.
+func (l *Allowlist) Set(words []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.save(words)
+}
+
+// Add adds word to the list, if it doesn't have it in any case, and saves
+// it.
+func (l *Allowlist) Add(word string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if slices.ContainsFunc(l.words, func(w string) bool { return strings.EqualFold(w, word) }) {
+		return nil
+	}
+	return l.save(append(slices.Clone(l.words), word))
+}
+
+// save writes words to the file; l.mu is held.
+func (l *Allowlist) save(words []string) error {
+	l.words = words
+	if l.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, w := range words {
+		b.WriteString(w)
+		b.WriteByte('\n')
+	}
+	return writeFile(l.path, []byte(b.String()), 0644)
+}
+
+// SpellIgnore ignores a suspected typo, Word lower cased, in a quest.
+type SpellIgnore struct {
+	Quest string `json:"quest"`
+	Word  string `json:"word"`
+}
+
+// normalizeWord lower cases w and writes its apostrophes as '.
+func normalizeWord(w string) string {
+	return strings.ReplaceAll(strings.ToLower(w), "’", "'")
+}
+
+// isApostrophe reports whether r is an apostrophe, which may be inside a
+// word.
+func isApostrophe(r rune) bool { return r == '\'' || r == '’' }
+
+// textWord is a word of a line and its byte offset.
+type textWord struct {
+	text string
+	at   int
+}
+
+// splitWords returns the words of line: runs of letters and digits, with
+// the apostrophes between them.
+func splitWords(line string) []textWord {
+	var words []textWord
+	start := -1
+	for i, r := range line {
+		switch {
+		case isWordRune(r):
+			if start < 0 {
+				start = i
+			}
+		case isApostrophe(r) && start >= 0:
+			next, _ := utf8.DecodeRuneInString(line[i+utf8.RuneLen(r):])
+			if isWordRune(next) {
+				continue
+			}
+			fallthrough
+		default:
+			if start >= 0 {
+				words = append(words, textWord{line[start:i], start})
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		words = append(words, textWord{line[start:], start})
+	}
+	return words
+}
+
+// checkable reports whether w is a word to check the spelling of rather
+// than a name or a number.
+func checkable(w string) bool {
+	if utf8.RuneCountInString(w) < 2 {
+		return false
+	}
+	for i, r := range w {
+		if unicode.IsDigit(r) || r == '_' || (i > 0 && unicode.IsUpper(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// speller checks words against a dictionary and the pack's own words.
+type speller struct {
+	// dict is nil when there is no dictionary, and words aren't checked
+	dict Dictionary
+	// own are the pack's words, normalized
+	own map[string]bool
+}
+
+// newSpeller returns a speller for qb with dict, which may be nil, and the
+// pack's allowlist.
+func newSpeller(qb *QuestBook, dict Dictionary, allowed []string) *speller {
+	s := &speller{dict: dict, own: make(map[string]bool)}
+	for _, w := range allowed {
+		s.own[normalizeWord(w)] = true
+	}
+	for _, q := range qb.Quests {
+		tasks, rewards := questItems(q)
+		for _, id := range append(tasks, rewards...) {
+			for _, w := range itemWords(id) {
+				s.own[w] = true
+			}
+		}
+	}
+	return s
+}
+
+// has reports whether the normalized word w is in the dictionary or the
+// pack's words.
+func (s *speller) has(w string) bool {
+	return s.own[w] || s.dict.Known(w)
+}
+
+// known reports whether w, as written, is spelled right. Possessives and
+// plurals of known words are.
+func (s *speller) known(w string) bool {
+	w = normalizeWord(w)
+	w = strings.TrimSuffix(w, "'s")
+	if s.has(w) {
+		return true
+	}
+	for _, suffix := range []string{"s", "es"} {
+		if stem, ok := strings.CutSuffix(w, suffix); ok && len(stem) > 1 && s.has(stem) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns up to n known words one edit away from w, in w's case.
+func (s *speller) suggest(w string, n int) []string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	lower := []rune(normalizeWord(w))
+	var res []string
+	try := func(rs []rune) {
+		c := string(rs)
+		if len(res) < n && s.has(c) && !slices.Contains(res, c) {
+			res = append(res, c)
+		}
+	}
+	for i := range lower {
+		try(slices.Concat(lower[:i], lower[i+1:]))
+		if i+1 < len(lower) {
+			sw := slices.Clone(lower)
+			sw[i], sw[i+1] = sw[i+1], sw[i]
+			try(sw)
+		}
+	}
+	for i := range lower {
+		for _, r := range letters {
+			if r != lower[i] {
+				try(slices.Concat(lower[:i], []rune{r}, lower[i+1:]))
+			}
+		}
+	}
+	for i := 0; i <= len(lower); i++ {
+		for _, r := range letters {
+			try(slices.Concat(lower[:i], []rune{r}, lower[i:]))
+		}
+	}
+	if first, _ := utf8.DecodeRuneInString(w); unicode.IsUpper(first) {
+		for i, c := range res {
+			r, size := utf8.DecodeRuneInString(c)
+			res[i] = string(unicode.ToUpper(r)) + c[size:]
+		}
+	}
+	return res
+}
+
+// Kinds of SpellHit.
+const (
+	SpellUnknown = "spelling"
+	SpellRepeat  = "repeat"
+)
+
+// SpellHit is a suspected typo in a line of quest text.
+type SpellHit struct {
+	Chapter *Chapter
+	Quest   *Quest
+	// Field is title, subtitle or description; Line is the description line.
+	Field string
+	Line  int
+	Kind  string
+	// Word is the unknown word, or both words of a repeat, as written.
+	Word        string
+	Suggestions []string
+	// Before and After are the text of the line around Word, without codes.
+	Before, After string
+}
+
+// Key is how the hit is ignored in its quest.
+func (h SpellHit) Key() string { return normalizeWord(h.Word) }
+
+// checkLine returns the suspected typos in line, in order.
+func (s *speller) checkLine(line string) []SpellHit {
+	if t := strings.TrimSpace(line); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		return nil
+	}
+	text := stripCodes(line)
+	hit := func(kind string, start, end int) SpellHit {
+		return SpellHit{Kind: kind, Word: text[start:end], Before: text[:start], After: text[end:]}
+	}
+	var hits []SpellHit
+	words := splitWords(text)
+	for i, w := range words {
+		end := w.at + len(w.text)
+		if i > 0 {
+			prev := words[i-1]
+			if strings.EqualFold(prev.text, w.text) && strings.TrimSpace(text[prev.at+len(prev.text):w.at]) == "" && !strings.ContainsFunc(w.text, unicode.IsDigit) {
+				hits = append(hits, hit(SpellRepeat, prev.at, end))
+				continue
+			}
+		}
+		if s.dict == nil || !checkable(w.text) || s.known(w.text) {
+			continue
+		}
+		h := hit(SpellUnknown, w.at, end)
+		h.Suggestions = s.suggest(w.text, 5)
+		hits = append(hits, h)
+	}
+	return hits
+}
+
+// spellBook returns the suspected typos in every quest of qb, in chapter
+// order, leaving out the ones ignored in their quests.
+func spellBook(s *speller, ignored []SpellIgnore, qb *QuestBook) []SpellHit {
+	var hits []SpellHit
+	for _, ch := range qb.Chapters {
+		for _, q := range ch.Quests {
+			check := func(field string, line int, text string) {
+				for _, h := range s.checkLine(text) {
+					if slices.Contains(ignored, SpellIgnore{q.ID, h.Key()}) {
+						continue
+					}
+					h.Chapter, h.Quest, h.Field, h.Line = ch, q, field, line
+					hits = append(hits, h)
+				}
+			}
+			check("title", 0, q.Title)
+			check("subtitle", 0, q.Subtitle)
+			if q.Description != "" {
+				for i, line := range strings.Split(q.Description, "\n") {
+					check("description", i, line)
+				}
+			}
+		}
+	}
+	return hits
+}
+
+// spelling handles GET "/spelling".
+func (a *App) spelling(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cfg := a.Pack.Get()
+	words := a.SpellAllow.Words()
+	data := a.baseData(r, "Spelling")
+	data["HasDictionary"] = a.Dictionary != nil
+	data["Hits"] = spellBook(newSpeller(qb, a.Dictionary, words), cfg.SpellIgnored, qb)
+	data["Words"] = strings.Join(words, "\n")
+	data["Ignored"] = cfg.SpellIgnored
+	data["Msg"] = r.URL.Query().Get("msg")
+	a.render(w, "spelling.gohtml", data)
+}
+
+// spellingWords handles POST "/spelling/words", saving the pack's words.
+func (a *App) spellingWords(w http.ResponseWriter, r *http.Request) {
+	if err := a.SpellAllow.Set(parseLines(r.FormValue("words"))); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/spelling", http.StatusSeeOther)
+}
+
+// spellingAccept handles POST "/spelling/accept", which adds "word" to the
+// pack's words.
+func (a *App) spellingAccept(w http.ResponseWriter, r *http.Request) {
+	word := strings.TrimSpace(r.FormValue("word"))
+	if word == "" || strings.ContainsFunc(word, unicode.IsSpace) {
+		http.Error(w, fmt.Sprintf("not a word: %q", word), http.StatusBadRequest)
+		return
+	}
+	if err := a.SpellAllow.Add(word); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	msg := fmt.Sprintf("%q is one of the pack's words.", word)
+	http.Redirect(w, r, "/spelling?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// spellingIgnore handles POST "/spelling/ignore", which ignores "word" in
+// the quest "quest", or with "remove=1" checks it there again.
+func (a *App) spellingIgnore(w http.ResponseWriter, r *http.Request) {
+	ig := SpellIgnore{Quest: r.FormValue("quest"), Word: normalizeWord(r.FormValue("word"))}
+	remove := r.FormValue("remove") == "1"
+	if !remove {
+		if _, ok := a.QB().questMap[ig.Quest]; !ok {
+			http.Error(w, "unknown quest "+ig.Quest, http.StatusBadRequest)
+			return
+		}
+		if ig.Word == "" {
+			http.Error(w, "no word", http.StatusBadRequest)
+			return
+		}
+	}
+	// rejected is why the request can't be applied to the current settings
+	var rejected error
+	err := a.Pack.Update(func(cfg *PackConfig) error {
+		switch {
+		case remove && !slices.Contains(cfg.SpellIgnored, ig):
+			rejected = fmt.Errorf("%q isn't ignored in %s", ig.Word, ig.Quest)
+		case remove:
+			cfg.SpellIgnored = slices.DeleteFunc(slices.Clone(cfg.SpellIgnored), func(o SpellIgnore) bool { return o == ig })
+		case !slices.Contains(cfg.SpellIgnored, ig):
+			cfg.SpellIgnored = append(slices.Clone(cfg.SpellIgnored), ig)
+		}
+		return rejected
+	})
+	if rejected != nil {
+		http.Error(w, rejected.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	msg := fmt.Sprintf("%q is ignored in %s.", ig.Word, ig.Quest)
+	if remove {
+		msg = fmt.Sprintf("%q is checked in %s again.", ig.Word, ig.Quest)
+	}
+	http.Redirect(w, r, "/spelling?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
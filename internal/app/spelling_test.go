@@ -0,0 +1,110 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpelling(t *testing.T) {
+	dir := t.TempDir()
+	dic := filepath.Join(dir, "en.dic")
+	if err := os.WriteFile(dic, []byte("6\nsmelt/SG\nan\nthe\nore\ninto\ndon't\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict, err := LoadWordList(dic, dic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dict.Len() != 6 || !dict.Known("smelt") || dict.Known("6") {
+		t.Fatalf("dictionary has %d words", dict.Len())
+	}
+
+	s := &speller{dict: dict, own: map[string]bool{"iron": true, "ingot": true}}
+	for _, c := range []struct {
+		in   string
+		want []string
+	}{
+		{"&6Smelt&r the Iron ore into ingots", nil},
+		{"Don’t smlet the the AE2 RF ore's", []string{"smlet", "the the"}},
+		{"Smeltt an &lIron&r ingot", []string{"Smeltt"}},
+		{`{"text":"smlet"}`, nil},
+	} {
+		var got []string
+		for _, h := range s.checkLine(c.in) {
+			got = append(got, h.Word)
+		}
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("checkLine(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+	if got := s.suggest("Smlet", 5); strings.Join(got, ",") != "Smelt" {
+		t.Errorf("suggest = %q", got)
+	}
+	if hits := (&speller{own: map[string]bool{}}).checkLine("smlet the the ore"); len(hits) != 1 || hits[0].Kind != SpellRepeat {
+		t.Errorf("without a dictionary = %+v", hits)
+	}
+
+	a := testApp(t)
+	a.Dictionary = dict
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := ch.Quests[0]
+	q.Title = "Smlet Ore"
+	q.Subtitle = ""
+	q.Description = "Smelt the ore.\nInto Blorbs"
+	if err := ch.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	words := func() []string {
+		var w []string
+		for _, h := range spellBook(newSpeller(a.QB(), a.Dictionary, a.SpellAllow.Words()), a.Pack.Get().SpellIgnored, a.QB()) {
+			if h.Quest.ID == q.ID {
+				w = append(w, h.Word)
+			}
+		}
+		return w
+	}
+	if got := words(); strings.Join(got, ",") != "Smlet,Blorbs" {
+		t.Fatalf("hits = %q", got)
+	}
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post("/spelling/accept", url.Values{"word": {"blorb"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("accept: %d %s", rec.Code, rec.Body)
+	}
+	if b, err := os.ReadFile(spellingPath(a.Root())); err != nil || string(b) != "blorb\n" {
+		t.Errorf("allowlist = %q, %v", b, err)
+	}
+	if rec := post("/spelling/ignore", url.Values{"quest": {q.ID}, "word": {"Smlet"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("ignore: %d %s", rec.Code, rec.Body)
+	}
+	if got := words(); len(got) != 0 {
+		t.Errorf("hits after accept and ignore = %q", got)
+	}
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/spelling", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Check again") {
+		t.Errorf("page: %d", rec.Code)
+	}
+	if rec := post("/spelling/ignore", url.Values{"quest": {q.ID}, "word": {"smlet"}, "remove": {"1"}}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("remove: %d %s", rec.Code, rec.Body)
+	}
+	if got := words(); strings.Join(got, ",") != "Smlet" {
+		t.Errorf("hits after remove = %q", got)
+	}
+}
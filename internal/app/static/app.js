@@ -29,7 +29,7 @@ $(function() {
     if(theme === 'dark') root.classList.add('dark');
     else root.classList.remove('dark');
     var link = document.getElementById('toggle-theme');
-    if(link){ link.textContent = (theme === 'dark') ? 'Light mode' : 'Dark mode'; }
+    if(link){ link.textContent = link.getAttribute((theme === 'dark') ? 'data-light' : 'data-dark') || ((theme === 'dark') ? 'Light mode' : 'Dark mode'); }
   }
   (function(){
     // Determine theme: query param overrides localStorage
@@ -55,6 +55,123 @@ $(function() {
     }
   })();
 
+  // Book switcher, in a workspace of several books
+  (function(){
+    var sel = document.getElementById('book-switch');
+    if(!sel){ return; }
+    sel.addEventListener('change', function(){ window.location.href = sel.value; });
+  })();
+
+  // Live reload: the server announces when quest files were changed by
+  // another program. Pages without unsaved input reload right away; others
+  // get a banner so edits in progress aren't thrown away.
+  (function(){
+    if(!window.EventSource){ return; }
+    var dirty = false;
+    document.addEventListener('input', function(){ dirty = true; }, true);
+    // books in a workspace are served under their own prefix
+    var base = document.documentElement.getAttribute('data-base') || '';
+    var es = new EventSource(base + '/events');
+    es.addEventListener('reload', function(e){
+      if(!dirty){ window.location.reload(); return; }
+      if(document.getElementById('reload-banner')){ return; }
+      var b = document.createElement('div');
+      b.id = 'reload-banner';
+      b.className = 'reload-banner';
+      var msg = document.documentElement.getAttribute('data-msg-reload') || 'Quest files changed on disk (%s). <a>Reload</a> to see them; unsaved edits on this page will be lost.';
+      b.innerHTML = msg.replace('%s', $('<div>').text(e.data).html());
+      b.querySelector('a').addEventListener('click', function(){ window.location.reload(); });
+      document.body.appendChild(b);
+    });
+  })();
+
+  // Quick open: Ctrl+K (Cmd+K on macOS) opens a palette to jump to a
+  // chapter or quest by title. Arrows pick a result, Enter opens it and
+  // Escape closes the palette.
+  (function(){
+    var base = document.documentElement.getAttribute('data-base') || '';
+    var pal, input, list, results = [], sel = 0, seq = 0;
+    function render(){
+      list.innerHTML = '';
+      results.forEach(function(r, i){
+        var li = document.createElement('li');
+        li.className = (i === sel) ? 'selected' : '';
+        var title = document.createElement('span');
+        title.textContent = r.title || r.id;
+        var where = document.createElement('span');
+        where.className = 'muted';
+        where.textContent = ' ' + (r.kind === 'quest' ? (r.chapter_title || r.chapter) : (document.documentElement.getAttribute('data-msg-chapter') || 'chapter'));
+        li.appendChild(title);
+        li.appendChild(where);
+        li.addEventListener('mousedown', function(e){ e.preventDefault(); go(i); });
+        list.appendChild(li);
+      });
+    }
+    function go(i){
+      if(results[i]){ window.location.href = results[i].url; }
+    }
+    function search(){
+      var q = input.value, n = ++seq;
+      if(!q.trim()){ results = []; render(); return; }
+      fetch(base + '/api/search?q=' + encodeURIComponent(q)).then(function(res){ return res.json(); }).then(function(data){
+        // answers to earlier keystrokes may arrive late
+        if(n !== seq){ return; }
+        results = data.results || [];
+        sel = 0;
+        render();
+      });
+    }
+    function close(){ pal.style.display = 'none'; }
+    function open(){
+      if(!pal){
+        pal = document.createElement('div');
+        pal.className = 'quick-open';
+        pal.innerHTML = '<input type="text" autocomplete="off" /><ul></ul>';
+        input = pal.querySelector('input');
+        input.placeholder = document.documentElement.getAttribute('data-msg-jump') || 'Jump to a chapter or quest';
+        list = pal.querySelector('ul');
+        input.addEventListener('input', search);
+        input.addEventListener('blur', close);
+        input.addEventListener('keydown', function(e){
+          if(e.key === 'ArrowDown'){ sel = Math.min(sel + 1, results.length - 1); render(); e.preventDefault(); }
+          else if(e.key === 'ArrowUp'){ sel = Math.max(sel - 1, 0); render(); e.preventDefault(); }
+          else if(e.key === 'Enter'){ go(sel); e.preventDefault(); }
+          else if(e.key === 'Escape'){ close(); }
+        });
+        document.body.appendChild(pal);
+      }
+      pal.style.display = '';
+      input.select();
+      input.focus();
+    }
+    document.addEventListener('keydown', function(e){
+      if((e.ctrlKey || e.metaKey) && !e.altKey && (e.key === 'k' || e.key === 'K')){
+        e.preventDefault();
+        open();
+      }
+    });
+  })();
+
+  // UI language picker
+  (function(){
+    var sel = document.getElementById('ui-lang');
+    if(!sel){ return; }
+    sel.addEventListener('change', function(){
+      document.cookie = 'lang=' + sel.value + '; Path=/; Max-Age=31536000; SameSite=Lax';
+      window.location.reload();
+    });
+  })();
+
+  // Color vision preview picker
+  (function(){
+    var sel = document.getElementById('cvd-mode');
+    if(!sel){ return; }
+    sel.addEventListener('change', function(){
+      document.cookie = 'cvd=' + sel.value + '; Path=/; Max-Age=' + (sel.value ? 31536000 : 0) + '; SameSite=Lax';
+      window.location.reload();
+    });
+  })();
+
   const keyFor = (id) => 'grp:' + id;
   function setGroup(id, expand, persist=true) {
     var $list = $('[data-list="' + id + '"]');
@@ -100,4 +217,11 @@ $(function() {
       setGroup(id, expand);
     });
   });
+
+  // Chapter minimap, inlined so its quests are links
+  $('.minimap[data-src]').each(function(_, el) {
+    fetch(el.getAttribute('data-src')).then(function(res) {
+      return res.ok ? res.text() : '';
+    }).then(function(svg) { el.innerHTML = svg; });
+  });
 });
@@ -0,0 +1,242 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// stubRow is how many stub quests are placed in a row.
+const stubRow = 8
+
+// StubChapter is a chapter of a stub outline.
+type StubChapter struct {
+	Title string
+	// Name is the chapter's file name, and New is set if it doesn't exist
+	Name   string
+	New    bool
+	Quests []*StubQuest
+}
+
+// StubQuest is a quest of a stub outline.
+type StubQuest struct {
+	Title       string
+	Description []string
+}
+
+// stubIndent returns the width of the indentation of line, counting a tab
+// as 4 spaces, and the rest of it.
+func stubIndent(line string) (int, string) {
+	n := 0
+	for i, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n, line[i:]
+		}
+	}
+	return n, ""
+}
+
+// stubBullets are the list markers allowed before a quest title.
+var stubBullets = []string{"- ", "* ", "+ "}
+
+// parseStubs parses a stub outline, for turning a planning outline into
+// quests to fill in, and matches its chapters against qb. The outline is
+// plain text, one chapter per unindented line and one quest per line indented
+// under it; lines indented further are the description of the quest above:
+//
+//	# Getting Started
+//	- Punch a tree
+//	    Logs are the start of everything.
+//	- Craft a workbench
+//	Ore Processing
+//	  Find iron
+//
+// Markdown headings and list bullets are allowed, so that an outline can be
+// pasted from a design doc. A chapter whose title or file name is already in
+// the book gets the quests added after its own; other chapters are created.
+func parseStubs(qb *QuestBook, s string) ([]*StubChapter, error) {
+	var chapters []*StubChapter
+	var ch *StubChapter
+	var q *StubQuest
+	// questIndent is the indentation of the current chapter's quests, or -1
+	// before its first quest
+	questIndent := -1
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		indent, text := stubIndent(strings.TrimRight(line, " \t"))
+		if text == "" {
+			continue
+		}
+		bullet := false
+		for _, b := range stubBullets {
+			if rest, ok := strings.CutPrefix(text+" ", b); ok {
+				text, bullet = strings.TrimSpace(rest), true
+				break
+			}
+		}
+		switch {
+		case indent == 0 && !bullet:
+			title := strings.TrimSpace(strings.TrimLeft(text, "#"))
+			if title == "" {
+				continue
+			}
+			ch = &StubChapter{Title: title}
+			if err := ch.resolve(qb); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if seen[ch.Name] {
+				return nil, fmt.Errorf("line %d: chapter %s is listed twice", i+1, ch.Name)
+			}
+			seen[ch.Name] = true
+			chapters = append(chapters, ch)
+			q, questIndent = nil, -1
+		case ch == nil:
+			return nil, fmt.Errorf("line %d: a quest needs a chapter title above it", i+1)
+		case questIndent < 0 || indent == questIndent:
+			questIndent = indent
+			if text == "" {
+				q = nil
+				continue
+			}
+			q = &StubQuest{Title: text}
+			ch.Quests = append(ch.Quests, q)
+		case indent > questIndent:
+			if q != nil {
+				q.Description = append(q.Description, strings.TrimSpace(line))
+			}
+		default:
+			return nil, fmt.Errorf("line %d: indented less than the quests above it", i+1)
+		}
+	}
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("the outline has no chapters")
+	}
+	return chapters, nil
+}
+
+// resolve sets the name of ch to the chapter in qb with its title or file
+// name, or the name a new chapter would have.
+func (ch *StubChapter) resolve(qb *QuestBook) error {
+	name := chapterName(ch.Title)
+	for _, c := range qb.Chapters {
+		if c.Name == name || strings.EqualFold(stripCodes(c.Title), stripCodes(ch.Title)) {
+			ch.Name = c.Name
+			return nil
+		}
+	}
+	if !validChapterName.MatchString(name) {
+		return fmt.Errorf("chapter %q needs a title with letters or digits to name its file", ch.Title)
+	}
+	ch.Name, ch.New = name, true
+	return nil
+}
+
+// CreateStubs writes the quests of chapters, creating the new chapters in the
+// group groupID, and returns how many quests were created. Each quest gets a
+// checkmark task and depends on the quest before it in the outline, and the
+// quests are laid out in rows to be arranged in game. Everything is written
+// together.
+func (qb *QuestBook) CreateStubs(chapters []*StubChapter, groupID string) (int, error) {
+	if groupID != "" {
+		if _, ok := qb.groupMap[groupID]; !ok {
+			return 0, fmt.Errorf("unknown group %s", groupID)
+		}
+	}
+	t := newBookWrite(qb.Lang)
+	ids := qb.idSource()
+	order := qb.nextChapterOrder(groupID)
+	n := 0
+	for _, sc := range chapters {
+		path := qb.chapterPath(sc.Name)
+		var ch *Chapter
+		if sc.New {
+			ch = NewChapter(newChapterRaw(ids.Next(), sc.Name, sc.Title, groupID, order))
+			order++
+		} else {
+			var err error
+			if ch, err = NewChapterFromPath(path); err != nil {
+				return 0, fmt.Errorf("open chapter %s: %w", sc.Name, err)
+			}
+			ch.resolveLang(qb.Lang)
+		}
+		x0, y0 := linkPosition(ch)
+		prev := ""
+		for i, sq := range sc.Quests {
+			q, err := NewQuest(map[string]any{
+				"id":    ids.Next(),
+				"x":     decimalValue(x0 + float64(i%stubRow)*2),
+				"y":     decimalValue(y0 + float64(i/stubRow)*2),
+				"tasks": []any{map[string]any{"id": ids.Next(), "type": "checkmark"}},
+			})
+			if err != nil {
+				return 0, err
+			}
+			q.Title = sq.Title
+			q.Description = strings.Join(sq.Description, "\n")
+			if prev != "" {
+				q.Dependencies = []string{prev}
+			}
+			prev = q.ID
+			q.Chapter = ch
+			ch.Quests = append(ch.Quests, q)
+			ch.questMap[q.ID] = q
+			n++
+		}
+		if err := t.stageChapter(ch, path); err != nil {
+			return 0, err
+		}
+	}
+	return n, t.commit()
+}
+
+// stubs handles GET "/stubs", the outline form, and POST "/stubs", which
+// shows the chapters and quests an outline creates.
+func (a *App) stubs(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Quest stubs")
+	data["Msg"] = r.URL.Query().Get("msg")
+	if r.Method == http.MethodPost {
+		s, err := readUploadedText(r)
+		var chapters []*StubChapter
+		if err == nil {
+			chapters, err = parseStubs(a.QB(), s)
+		}
+		if err != nil {
+			data["StubsErr"] = err.Error()
+		} else {
+			data["Stubs"] = chapters
+		}
+		data["Outline"] = s
+	}
+	data["Group"] = r.FormValue("group")
+	a.render(w, "stubs.gohtml", data)
+}
+
+// stubsApply handles POST "/stubs/apply", which creates the quests of the
+// outline in "text", and new chapters in "group".
+func (a *App) stubsApply(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	chapters, err := parseStubs(qb, r.FormValue("text"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := qb.CreateStubs(chapters, r.FormValue("group"))
+	if err != nil {
+		http.Error(w, "create quests: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var names []string
+	for _, ch := range chapters {
+		names = append(names, ch.Name)
+	}
+	a.audit(r, "create quest stubs", strings.Join(names, ", "), nil, fmt.Sprintf("%d quests", n))
+	a.reload()
+	msg := fmt.Sprintf("Created %d quests in %d chapters.", n, len(chapters))
+	http.Redirect(w, r, "/stubs?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestStubs(t *testing.T) {
+	a := testApp(t)
+	test := a.QB().chapterMap["test"]
+	before := len(test.Quests)
+	outline := "# Getting Started\n" +
+		"- Punch a tree\n" +
+		"    Logs are the start of everything.\n" +
+		"    Any wood will do.\n" +
+		"\n" +
+		"- Craft a workbench\n" +
+		stripCodes(test.Title) + "\n" +
+		"\tOne more thing\n"
+	chapters, err := parseStubs(a.QB(), outline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chapters) != 2 || chapters[0].Name != "getting_started" || !chapters[0].New || chapters[1].Name != "test" || chapters[1].New {
+		t.Fatalf("chapters = %+v %+v", chapters[0], chapters[1])
+	}
+	if qs := chapters[0].Quests; len(qs) != 2 || qs[0].Title != "Punch a tree" || len(qs[0].Description) != 2 {
+		t.Fatalf("quests = %+v", qs)
+	}
+	for _, bad := range []string{"  Orphan quest\n", "Ch\n    Quest\n  Shallower\n", "Ch\nCH\n", "\n\n"} {
+		if _, err := parseStubs(a.QB(), bad); err == nil {
+			t.Errorf("parseStubs(%q) should fail", bad)
+		}
+	}
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(url.Values{"text": {outline}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post("/stubs"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "new chapter") {
+		t.Fatalf("preview: %d", rec.Code)
+	}
+	if rec := post("/stubs/apply"); rec.Code != http.StatusSeeOther {
+		t.Fatalf("apply: %d %s", rec.Code, rec.Body)
+	}
+	qb := a.QB()
+	ch := qb.chapterMap["getting_started"]
+	if ch == nil || len(ch.Quests) != 2 {
+		t.Fatalf("new chapter = %+v", ch)
+	}
+	first, second := ch.Quests[0], ch.Quests[1]
+	if first.Description != "Logs are the start of everything.\nAny wood will do." || len(first.Tasks) != 1 || first.Tasks[0].Base().Type != "checkmark" {
+		t.Errorf("first quest = %+v", first)
+	}
+	if len(second.Dependencies) != 1 || second.Dependencies[0] != first.ID || second.X != first.X+2 {
+		t.Errorf("second quest = %+v", second)
+	}
+	if test := qb.chapterMap["test"]; len(test.Quests) != before+1 || test.Quests[before].Title != "One more thing" {
+		t.Errorf("existing chapter has %d quests", len(test.Quests))
+	}
+}
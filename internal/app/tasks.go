@@ -0,0 +1,195 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/qbedit/internal/app/fbtid"
+)
+
+// Task is a single entry in a quest's tasks list.
+//
+// Like rewards, concrete task types model the fields we know how to edit and
+// keep their decoded compound so unmodeled fields survive a round-trip.
+type Task interface {
+	// Base returns the fields common to every task type.
+	Base() *TaskBase
+	// FormValue returns the task's primary value as shown in the editor.
+	FormValue() string
+	// FormCount returns the task's count, or 0 if it has none.
+	FormCount() int
+	// SetForm updates the task from the editor's value and count fields.
+	SetForm(value string, count int) error
+	// Sync writes the task's fields back into its raw compound.
+	Sync()
+}
+
+// TaskTypes are the task types that can be created from the quest editor.
+var TaskTypes = []string{"item", "checkmark", "advancement", "kill", "dimension", "biome", "structure", "xp"}
+
+// TaskBase holds the fields shared by all tasks.
+type TaskBase struct {
+	raw  map[string]any
+	ID   string
+	Type string
+}
+
+func (t *TaskBase) Base() *TaskBase { return t }
+
+// ItemTask requires the player to have (or submit) Count of an item.
+type ItemTask struct {
+	TaskBase
+	Item  string
+	Count int
+}
+
+func (t *ItemTask) FormValue() string { return t.Item }
+func (t *ItemTask) FormCount() int    { return t.Count }
+
+func (t *ItemTask) SetForm(value string, count int) (err error) {
+	t.Item, t.Count, err = itemFromForm("item task", t.ID, value, count)
+	return err
+}
+
+func (t *ItemTask) Sync() { syncItem(t.raw, t.Item, t.Count) }
+
+// CheckmarkTask is completed by clicking it; it has nothing to edit.
+type CheckmarkTask struct {
+	TaskBase
+}
+
+func (t *CheckmarkTask) FormValue() string { return "" }
+func (t *CheckmarkTask) FormCount() int    { return 0 }
+
+func (t *CheckmarkTask) SetForm(value string, count int) error { return nil }
+func (t *CheckmarkTask) Sync()                                 {}
+
+// StringTask is any task whose only field is a resource location, eg. the
+// advancement, dimension, biome or structure to reach.
+type StringTask struct {
+	TaskBase
+	Value string
+}
+
+// key returns the compound key holding the task's value, which matches the type.
+func (t *StringTask) key() string { return t.Type }
+
+func (t *StringTask) FormValue() string { return t.Value }
+func (t *StringTask) FormCount() int    { return 0 }
+
+func (t *StringTask) SetForm(value string, count int) error {
+	if value == "" {
+		return fmt.Errorf("%s task %s: missing %s", t.Type, t.ID, t.key())
+	}
+	t.Value = value
+	return nil
+}
+
+func (t *StringTask) Sync() { t.raw[t.key()] = t.Value }
+
+// KillTask requires killing Count of an entity.
+type KillTask struct {
+	TaskBase
+	Entity string
+	Count  int
+}
+
+func (t *KillTask) FormValue() string { return t.Entity }
+func (t *KillTask) FormCount() int    { return t.Count }
+
+func (t *KillTask) SetForm(value string, count int) error {
+	if value == "" {
+		return fmt.Errorf("kill task %s: missing entity", t.ID)
+	}
+	t.Entity = value
+	t.Count = max(count, 1)
+	return nil
+}
+
+func (t *KillTask) Sync() {
+	t.raw["entity"] = t.Entity
+	if M(t.raw).GetInt("value") != t.Count || !M(t.raw).Has("value") {
+		M(t.raw).SetInt("value", t.Count)
+	}
+}
+
+// XPTask requires spending experience; Amount is levels unless "points" is set.
+type XPTask struct {
+	TaskBase
+	Amount int
+}
+
+func (t *XPTask) FormValue() string { return strconv.Itoa(t.Amount) }
+func (t *XPTask) FormCount() int    { return 0 }
+
+func (t *XPTask) SetForm(value string, count int) error {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 1 {
+		return fmt.Errorf("xp task %s: invalid amount %q", t.ID, value)
+	}
+	t.Amount = n
+	return nil
+}
+
+func (t *XPTask) Sync() {
+	if M(t.raw).GetInt("value") != t.Amount || !M(t.raw).Has("value") {
+		M(t.raw).SetInt("value", t.Amount)
+	}
+}
+
+// UnknownTask is any task type we don't model; it is preserved as-is.
+type UnknownTask struct {
+	TaskBase
+}
+
+func (t *UnknownTask) FormValue() string { return "" }
+func (t *UnknownTask) FormCount() int    { return 0 }
+
+func (t *UnknownTask) SetForm(value string, count int) error { return nil }
+func (t *UnknownTask) Sync()                                 {}
+
+// NewTask decodes a task from its raw compound based on its "type".
+func NewTask(raw any) (Task, error) {
+	rm, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("new task expected compound, but got %T", raw)
+	}
+	m := M(rm)
+	base := TaskBase{raw: rm, ID: m.GetString("id"), Type: m.GetString("type")}
+
+	switch base.Type {
+	case "item", "":
+		// item tasks in older packs can omit the type
+		t := &ItemTask{TaskBase: base}
+		t.Item, t.Count = itemFromRaw(rm)
+		return t, nil
+	case "checkmark":
+		return &CheckmarkTask{TaskBase: base}, nil
+	case "advancement", "dimension", "biome", "structure":
+		return &StringTask{TaskBase: base, Value: m.GetString(base.Type)}, nil
+	case "kill":
+		return &KillTask{TaskBase: base, Entity: m.GetString("entity"), Count: max(m.GetInt("value"), 1)}, nil
+	case "xp":
+		return &XPTask{TaskBase: base, Amount: m.GetInt("value")}, nil
+	}
+	return &UnknownTask{TaskBase: base}, nil
+}
+
+// NewTaskOfType creates an empty task of type typ with the given id.
+func NewTaskOfType(typ, id string) (Task, error) {
+	if !slices.Contains(TaskTypes, typ) {
+		return nil, fmt.Errorf("unknown task type %q", typ)
+	}
+	return NewTask(map[string]any{"id": id, "type": typ})
+}
+
+// tasksFromForm rebuilds a quest's tasks from the parallel task_id,
+// task_type, task_value and task_count form fields; see entriesFromForm.
+func tasksFromForm(existing []Task, form url.Values, src *fbtid.Source) ([]Task, error) {
+	return entriesFromForm("task", existing, form, src, func(t Task) (string, string) {
+		return t.Base().ID, t.Base().Type
+	}, NewTaskOfType)
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jmoiron/qbedit/internal/app/fbtid"
+)
+
+func TestTasksFromForm(t *testing.T) {
+	item := map[string]any{"id": "T1", "type": "item", "item": map[string]any{"id": "minecraft:stone", "Count": int64(1), "tag": map[string]any{"Damage": int64(0)}}, "count": int64(4)}
+	kill := map[string]any{"id": "T2", "type": "kill", "entity": "minecraft:zombie", "value": int64(5)}
+	q, err := NewQuest(map[string]any{"id": "Q1", "tasks": []any{item, kill}})
+	if err != nil {
+		t.Fatalf("NewQuest: %v", err)
+	}
+
+	form := url.Values{
+		"task_id":    {"T1", "T2", "T9", ""},
+		"task_type":  {"item", "item", "checkmark", "dimension"},
+		"task_value": {"minecraft:stone", "minecraft:dirt", "", "minecraft:the_nether"},
+		"task_count": {"1", "3", "", ""},
+	}
+	tasks, err := tasksFromForm(q.Tasks, form, fbtid.NewSource(nil))
+	if err != nil {
+		t.Fatalf("tasksFromForm: %v", err)
+	}
+	q.Tasks = tasks
+	q.Sync()
+
+	tl := M(q.raw).GetAnys("tasks")
+	if len(tl) != 4 {
+		t.Fatalf("expected 4 tasks, got %d", len(tl))
+	}
+	t1 := tl[0].(map[string]any)
+	// the item compound is unchanged, so its NBT should be kept
+	if _, ok := t1["item"].(map[string]any); !ok {
+		t.Errorf("item compound was replaced: %#v", t1["item"])
+	}
+	if _, ok := t1["count"]; ok {
+		t.Errorf("count of 1 was written: %#v", t1["count"])
+	}
+	// T2 changed type, so it is a new item task rather than a kill task
+	t2 := M(tl[1].(map[string]any))
+	if t2.GetString("type") != "item" || t2.GetString("item") != "minecraft:dirt" || t2.GetInt("count") != 3 || t2.Has("entity") {
+		t.Errorf("retyped task mismatch: %#v", t2)
+	}
+	if t3 := M(tl[2].(map[string]any)); t3.GetString("id") != "T9" || t3.GetString("type") != "checkmark" {
+		t.Errorf("new checkmark task mismatch: %#v", t3)
+	}
+	if t4 := M(tl[3].(map[string]any)); t4.GetString("dimension") != "minecraft:the_nether" || len(t4.GetString("id")) != 16 {
+		t.Errorf("new dimension task mismatch: %#v", t4)
+	}
+
+	for _, bad := range []url.Values{
+		{"task_id": {""}, "task_type": {"item"}, "task_value": {""}, "task_count": {"1"}},
+		{"task_id": {""}, "task_type": {"fish"}, "task_value": {"x"}, "task_count": {""}},
+		{"task_id": {"", ""}, "task_type": {"item"}, "task_value": {"x"}, "task_count": {""}},
+	} {
+		if _, err := tasksFromForm(nil, bad, fbtid.NewSource(nil)); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+// tellrawText converts text with formatting codes or MiniMessage tags to a
+// /tellraw component list, for authors to reuse quest text in command blocks,
+// datapacks and scripts. Description lines are styled separately, as the
+// quest book shows them, and joined with newlines. The list starts with an
+// empty string: the game styles the rest of a list like its first element,
+// which would otherwise leak the first color.
+func tellrawText(s string) []any {
+	list := []any{""}
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			list = append(list, "\n")
+		}
+		for _, c := range mcformat.ToComponents(line) {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// TellrawQuest is a quest's text as /tellraw components. Empty fields are
+// left out.
+type TellrawQuest struct {
+	ID          string `json:"id"`
+	Title       []any  `json:"title,omitempty"`
+	Subtitle    []any  `json:"subtitle,omitempty"`
+	Description []any  `json:"description,omitempty"`
+}
+
+func tellrawQuest(q *Quest) TellrawQuest {
+	t := TellrawQuest{ID: q.ID}
+	if s := q.GetTitle(); s != "" {
+		t.Title = tellrawText(s)
+	}
+	if q.Subtitle != "" {
+		t.Subtitle = tellrawText(q.Subtitle)
+	}
+	if q.Description != "" {
+		t.Description = tellrawText(q.Description)
+	}
+	return t
+}
+
+// marshalTellraw encodes v as JSON without escaping &, < and >, which the
+// game doesn't need and which would make commands harder to read.
+func marshalTellraw(v any, indent string) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// writeTellraw writes quests as JSON, or with format=mcfunction as tellraw
+// commands, one per field, that can be pasted into a function file. A single
+// quest is written as an object rather than a list.
+func writeTellraw(w http.ResponseWriter, r *http.Request, name string, quests []TellrawQuest, single bool) {
+	download := r.URL.Query().Get("download") == "1"
+	if r.URL.Query().Get("format") == "mcfunction" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if download {
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".mcfunction"))
+		}
+		for _, q := range quests {
+			fmt.Fprintf(w, "# %s\n", q.ID)
+			for _, list := range [][]any{q.Title, q.Subtitle, q.Description} {
+				if list == nil {
+					continue
+				}
+				b, err := marshalTellraw(list, "")
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				fmt.Fprintf(w, "tellraw @a %s\n", b)
+			}
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if download {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".json"))
+	}
+	var v any = quests
+	if single && len(quests) == 1 {
+		v = quests[0]
+	}
+	b, err := marshalTellraw(v, "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
+
+// chapterTellraw handles GET "/chapter/{chapter}/tellraw", the text of every
+// quest in the chapter as /tellraw components.
+func (a *App) chapterTellraw(w http.ResponseWriter, r *http.Request) {
+	ch := a.QB().chapterMap[chi.URLParam(r, "chapter")]
+	if ch == nil {
+		http.NotFound(w, r)
+		return
+	}
+	quests := make([]TellrawQuest, 0, len(ch.Quests))
+	for _, q := range ch.Quests {
+		quests = append(quests, tellrawQuest(q))
+	}
+	writeTellraw(w, r, ch.Name+"-tellraw", quests, false)
+}
+
+// questTellraw handles GET "/chapter/{chapter}/{quest}/tellraw".
+func (a *App) questTellraw(w http.ResponseWriter, r *http.Request) {
+	q, ok := a.QB().questMap[chi.URLParam(r, "quest")]
+	if !ok || q.Chapter.Name != chi.URLParam(r, "chapter") {
+		http.NotFound(w, r)
+		return
+	}
+	writeTellraw(w, r, q.ID+"-tellraw", []TellrawQuest{tellrawQuest(q)}, true)
+}
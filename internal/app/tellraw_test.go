@@ -0,0 +1,23 @@
+package app
+
+import "testing"
+
+func TestTellrawText(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"plain", `["",{"text":"plain"}]`},
+		{"&6&lGold&r ingot", `["",{"text":"Gold","color":"gold","bold":true},{"text":" ingot"}]`},
+		// a color clears bold, as in the game
+		{"&lBig &cred", `["",{"text":"Big ","bold":true},{"text":"red","color":"red"}]`},
+		{"salt & pepper", `["",{"text":"salt & pepper"}]`},
+		{"&aone\n§btwo", `["",{"text":"one","color":"green"},"\n",{"text":"two","color":"aqua"}]`},
+	}
+	for _, c := range cases {
+		b, err := marshalTellraw(tellrawText(c.in), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != c.want {
+			t.Errorf("tellrawText(%q) = %s, want %s", c.in, b, c.want)
+		}
+	}
+}
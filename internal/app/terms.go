@@ -0,0 +1,336 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// TermRule is a preferred term and the variants writers tend to use instead,
+// eg. "Redstone Flux" for "RF". The terms page finds the variants, and the
+// preferred term written in another case, and replaces them per quest or
+// book-wide, to keep the book's wording consistent. Text is matched as whole
+// words, with formatting codes ignored.
+type TermRule struct {
+	Preferred string   `json:"preferred"`
+	Variants  []string `json:"variants,omitempty"`
+}
+
+// parseTermRules reads one rule per line, written "Preferred = variant,
+// variant". A line without variants only checks the preferred term's case.
+func parseTermRules(s string) ([]TermRule, error) {
+	var rules []TermRule
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pref, vars, _ := strings.Cut(line, "=")
+		rule := TermRule{Preferred: strings.TrimSpace(pref)}
+		if rule.Preferred == "" {
+			return nil, fmt.Errorf("line %d: no preferred term", i+1)
+		}
+		for _, v := range strings.Split(vars, ",") {
+			if v = strings.TrimSpace(v); v != "" && !slices.Contains(rule.Variants, v) {
+				rule.Variants = append(rule.Variants, v)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// formatTermRules writes rules in the form parseTermRules reads.
+func formatTermRules(rules []TermRule) string {
+	var b strings.Builder
+	for _, r := range rules {
+		b.WriteString(r.Preferred)
+		if len(r.Variants) > 0 {
+			b.WriteString(" = ")
+			b.WriteString(strings.Join(r.Variants, ", "))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// wordStart reports whether a word may start at byte i of s: it is at the
+// start of s, after a non-word character or right after a formatting code.
+func wordStart(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	r, n := utf8.DecodeLastRuneInString(s[:i])
+	if !isWordRune(r) {
+		return true
+	}
+	c, _ := utf8.DecodeLastRuneInString(s[:i-n])
+	return c == '&' || c == '§'
+}
+
+// wordEnd reports whether a word may end at byte i of s.
+func wordEnd(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return i == len(s) || !isWordRune(r)
+}
+
+// findTerm returns the byte offsets of whole word, case insensitive matches
+// of term in s.
+func findTerm(s, term string) []int {
+	var at []int
+	if term == "" {
+		return nil
+	}
+	for i := 0; i+len(term) <= len(s); {
+		if strings.EqualFold(s[i:i+len(term)], term) && wordStart(s, i) && wordEnd(s, i+len(term)) {
+			at = append(at, i)
+			i += len(term)
+			continue
+		}
+		_, n := utf8.DecodeRuneInString(s[i:])
+		i += n
+	}
+	return at
+}
+
+// TermMatch is a use of a term other than its preferred form.
+type TermMatch struct {
+	Found     string
+	Preferred string
+	// at is the byte offset of Found in the line.
+	at int
+}
+
+// termMatches returns the uses in line of variants of the rules, and of
+// preferred terms written in another case, in order. Longer terms are
+// matched first, so a variant containing another isn't reported twice.
+func termMatches(rules []TermRule, line string) []TermMatch {
+	type term struct{ text, preferred string }
+	var terms []term
+	for _, r := range rules {
+		terms = append(terms, term{r.Preferred, r.Preferred})
+		for _, v := range r.Variants {
+			terms = append(terms, term{v, r.Preferred})
+		}
+	}
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i].text) > len(terms[j].text) })
+	taken := make([]bool, len(line))
+	var matches []TermMatch
+	for _, t := range terms {
+	next:
+		for _, i := range findTerm(line, t.text) {
+			for j := i; j < i+len(t.text); j++ {
+				if taken[j] {
+					continue next
+				}
+			}
+			for j := i; j < i+len(t.text); j++ {
+				taken[j] = true
+			}
+			if found := line[i : i+len(t.text)]; found != t.preferred {
+				matches = append(matches, TermMatch{Found: found, Preferred: t.preferred, at: i})
+			}
+		}
+	}
+	slices.SortFunc(matches, func(a, b TermMatch) int { return a.at - b.at })
+	return matches
+}
+
+// fixTerms replaces each match in line with its preferred term.
+func fixTerms(rules []TermRule, line string) string {
+	if t := strings.TrimSpace(line); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		return line
+	}
+	matches := termMatches(rules, line)
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		line = line[:m.at] + m.Preferred + line[m.at+len(m.Found):]
+	}
+	return line
+}
+
+// TermIssue is a line of quest text that uses terms other than the preferred.
+type TermIssue struct {
+	Chapter *Chapter
+	Quest   *Quest
+	// Field is title, subtitle or description; Line is the description line.
+	Field   string
+	Line    int
+	Matches []TermMatch
+	Text    string
+	Fixed   string
+}
+
+// termIssues returns the term issues of every quest of qb, in chapter order.
+func termIssues(rules []TermRule, qb *QuestBook) []TermIssue {
+	var issues []TermIssue
+	for _, ch := range qb.Chapters {
+		for _, q := range ch.Quests {
+			check := func(field string, line int, text string) {
+				if fixed := fixTerms(rules, text); fixed != text {
+					issues = append(issues, TermIssue{Chapter: ch, Quest: q, Field: field, Line: line, Matches: termMatches(rules, text), Text: text, Fixed: fixed})
+				}
+			}
+			check("title", 0, q.Title)
+			check("subtitle", 0, q.Subtitle)
+			if q.Description != "" {
+				for i, line := range strings.Split(q.Description, "\n") {
+					check("description", i, line)
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// fixQuestTerms replaces variants in q's text fields and reports whether
+// any changed.
+func fixQuestTerms(rules []TermRule, q *Quest) bool {
+	changed := false
+	for _, f := range []*string{&q.Title, &q.Subtitle, &q.Description} {
+		if *f == "" {
+			continue
+		}
+		lines := strings.Split(*f, "\n")
+		for i, line := range lines {
+			lines[i] = fixTerms(rules, line)
+		}
+		if fixed := strings.Join(lines, "\n"); fixed != *f {
+			*f = fixed
+			changed = true
+		}
+	}
+	return changed
+}
+
+// WordCount is how often a word is used.
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+// minFrequentWord is the length of the shortest word counted, which leaves
+// out most articles and prepositions.
+const minFrequentWord = 4
+
+// wordFrequency returns the n words most used in the text of quests, without
+// formatting codes, most used first.
+func wordFrequency(quests []*Quest, n int) []WordCount {
+	counts := make(map[string]int)
+	for _, q := range quests {
+		text := strings.Join([]string{q.GetTitle(), q.Subtitle, q.Description}, "\n")
+		for _, w := range strings.FieldsFunc(strings.ToLower(stripCodes(text)), func(r rune) bool { return !isWordRune(r) && r != '\'' }) {
+			if w = strings.Trim(w, "'"); utf8.RuneCountInString(w) >= minFrequentWord {
+				counts[w]++
+			}
+		}
+	}
+	words := make([]WordCount, 0, len(counts))
+	for w, c := range counts {
+		words = append(words, WordCount{w, c})
+	}
+	slices.SortFunc(words, func(a, b WordCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Word, b.Word)
+	})
+	if len(words) > n {
+		words = words[:n]
+	}
+	return words
+}
+
+// terms handles GET "/terms". "chapter" limits the word frequency list to a
+// chapter.
+func (a *App) terms(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	cfg := a.Pack.Get()
+	quests := qb.Quests
+	name := r.URL.Query().Get("chapter")
+	if ch, ok := qb.chapterMap[name]; ok {
+		quests = ch.Quests
+	} else {
+		name = ""
+	}
+	data := a.baseData(r, "Terms")
+	data["Rules"] = formatTermRules(cfg.Terms)
+	data["Issues"] = termIssues(cfg.Terms, qb)
+	data["Words"] = wordFrequency(quests, 50)
+	data["Chapters"] = qb.Chapters
+	data["Selected"] = name
+	data["Msg"] = r.URL.Query().Get("msg")
+	a.render(w, "terms.gohtml", data)
+}
+
+// termsRules handles POST "/terms/rules", saving the pack's term list.
+func (a *App) termsRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := parseTermRules(r.FormValue("rules"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = a.Pack.Update(func(cfg *PackConfig) error {
+		cfg.Terms = rules
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/terms", http.StatusSeeOther)
+}
+
+// termsFix handles POST "/terms/fix", replacing variants with the preferred
+// terms in the quests listed in "ids", or in every quest with "ids=all".
+func (a *App) termsFix(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	isAjax := r.Header.Get("X-Requested-With") == "XMLHttpRequest" || strings.Contains(r.Header.Get("Accept"), "application/json")
+	if err := r.ParseForm(); err != nil {
+		writeError(w, isAjax, "invalid form", http.StatusBadRequest)
+		return
+	}
+	rules := a.Pack.Get().Terms
+	byChapter := make(map[string][]string)
+	if p := r.Form.Get("ids"); p == "all" {
+		for _, is := range termIssues(rules, qb) {
+			if !slices.Contains(byChapter[is.Chapter.Name], is.Quest.ID) {
+				byChapter[is.Chapter.Name] = append(byChapter[is.Chapter.Name], is.Quest.ID)
+			}
+		}
+	} else {
+		for _, id := range strings.Split(p, ",") {
+			if q, ok := qb.questMap[strings.TrimSpace(id)]; ok {
+				byChapter[q.Chapter.Name] = append(byChapter[q.Chapter.Name], q.ID)
+			}
+		}
+	}
+	edited, err := editChapters(qb, slices.Collect(maps.Keys(byChapter)), func(ch *Chapter) ([]string, error) {
+		var changed []string
+		for _, id := range byChapter[ch.Name] {
+			if q, ok := ch.questMap[id]; ok && fixQuestTerms(rules, q) {
+				changed = append(changed, id)
+			}
+		}
+		return changed, nil
+	})
+	if err != nil {
+		writeError(w, isAjax, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fixed := a.auditEdits(r, "terminology", "", edited)
+	if fixed > 0 {
+		a.reload()
+	}
+	if isAjax {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "fixed": fixed})
+		return
+	}
+	msg := fmt.Sprintf("Replaced terms in %d quests.", fixed)
+	http.Redirect(w, r, "/terms?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	rules, err := parseTermRules("Redstone Flux = RF, Flux\n\n# case only\nNether Star\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || len(rules[0].Variants) != 2 || rules[1].Variants != nil {
+		t.Fatalf("rules = %+v", rules)
+	}
+	if got, err := parseTermRules(formatTermRules(rules)); err != nil || formatTermRules(got) != formatTermRules(rules) {
+		t.Errorf("round trip = %+v, %v", got, err)
+	}
+	for _, c := range []struct{ in, want string }{
+		{"Makes 100 RF/t", "Makes 100 Redstone Flux/t"},
+		{"Store &6rf&r and Redstone Flux, not Flux", "Store &6Redstone Flux&r and Redstone Flux, not Redstone Flux"},
+		{"a §lnether star", "a §lNether Star"},
+		{"surfing and Starfish", "surfing and Starfish"},
+		{`{"text":"RF"}`, `{"text":"RF"}`},
+	} {
+		if got := fixTerms(rules, c.in); got != c.want {
+			t.Errorf("fixTerms(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	a := testApp(t)
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := ch.Quests[0]
+	q.Description = "Generates RF.\nNeeds a nether star."
+	if err := ch.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	cfg := a.Pack.Get()
+	cfg.Terms = rules
+	if err := a.Pack.Set(cfg); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	if issues := termIssues(rules, a.QB()); len(issues) != 2 {
+		t.Fatalf("%d issues", len(issues))
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/terms/fix", strings.NewReader(url.Values{"ids": {"all"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	a.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("fix: %d %s", rec.Code, rec.Body)
+	}
+	if got := a.QB().questMap[q.ID].Description; got != "Generates Redstone Flux.\nNeeds a Nether Star." {
+		t.Errorf("description = %q", got)
+	}
+	if issues := termIssues(rules, a.QB()); len(issues) != 0 {
+		t.Errorf("%d issues after fixing", len(issues))
+	}
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"strings"
+)
+
+// questText is a quest's searchable text.
+type questText struct {
+	// title, subtitle and description are the text as written
+	title, subtitle, description string
+	// fallback is GetTitle, which is the title unless the quest is untitled
+	fallback string
+
+	// fields are the text of each of searchFields
+	fields [3]textField
+	// hex is set if the text has hex colors, which stripCodes doesn't strip
+	// the way the colors page does
+	hex bool
+	// words are the lower cased words of all the fields, without duplicates
+	words []string
+}
+
+// textField is the text of a field with codes stripped, and lower cased.
+// The title has two, the title and its fallback.
+type textField struct {
+	raw   string
+	text  []string
+	lower []string
+}
+
+// textIndex is the searchable text of a book's quests. Batch search and the
+// colors page look at the text of every quest on every request, and stripping
+// codes and lower casing it each time is most of the work on a large pack, so
+// the index does that once, when the book is loaded, and keeps the words of
+// every quest so that literal terms only look at the quests that could match.
+type textIndex struct {
+	quests map[string]*questText
+	// words maps each lower cased word to the ids of the quests using it
+	words map[string][]string
+}
+
+// sameText reports whether t was built from the text q has now.
+func (t *questText) sameText(q *Quest) bool {
+	return t.title == q.Title && t.subtitle == q.Subtitle && t.description == q.Description && t.fallback == q.GetTitle()
+}
+
+// newQuestText strips and splits the text of q.
+func newQuestText(q *Quest) *questText {
+	t := &questText{title: q.Title, subtitle: q.Subtitle, description: q.Description, fallback: q.GetTitle()}
+	field := func(raw string, texts ...string) textField {
+		f := textField{raw: raw}
+		for _, s := range texts {
+			s = stripCodes(s)
+			f.text = append(f.text, s)
+			f.lower = append(f.lower, strings.ToLower(s))
+		}
+		return f
+	}
+	t.fields = [3]textField{
+		field(q.Title, q.Title, t.fallback),
+		field(q.Subtitle, q.Subtitle),
+		field(q.Description, q.Description),
+	}
+	seen := make(map[string]bool)
+	for _, f := range t.fields {
+		t.hex = t.hex || strings.Contains(f.raw, "&#") || strings.Contains(f.raw, "§#")
+		for _, s := range f.lower {
+			for _, w := range strings.FieldsFunc(s, func(r rune) bool { return !isWordRune(r) }) {
+				if !seen[w] {
+					seen[w] = true
+					t.words = append(t.words, w)
+				}
+			}
+		}
+	}
+	return t
+}
+
+// newTextIndex indexes the quests of qb, reusing the entries of prev, the
+// index of the book before it was loaded again, for unchanged quests, as most
+// quests haven't changed after a save. prev may be nil.
+func newTextIndex(qb *QuestBook, prev *textIndex) *textIndex {
+	idx := &textIndex{quests: make(map[string]*questText, len(qb.Quests)), words: make(map[string][]string)}
+	for _, q := range qb.Quests {
+		var t *questText
+		if prev != nil {
+			if old, ok := prev.quests[q.ID]; ok && old.sameText(q) {
+				t = old
+			}
+		}
+		if t == nil {
+			t = newQuestText(q)
+		}
+		idx.quests[q.ID] = t
+		for _, w := range t.words {
+			idx.words[w] = append(idx.words[w], q.ID)
+		}
+	}
+	return idx
+}
+
+// textIndex returns the book's text index, building it if the book wasn't
+// loaded with one.
+func (q *QuestBook) textIndex() *textIndex {
+	if q.text != nil {
+		return q.text
+	}
+	return newTextIndex(q, nil)
+}
+
+// quest returns the text of q, stripping it if q has changed since the
+// index was built.
+func (idx *textIndex) quest(q *Quest) *questText {
+	if t, ok := idx.quests[q.ID]; ok && t.sameText(q) {
+		return t
+	}
+	return newQuestText(q)
+}
+
+// candidates returns the ids of the quests which could match every matcher,
+// or nil if any of them can't be narrowed down by words; see word.
+func (idx *textIndex) candidates(ms []*matcher) map[string]bool {
+	var ids map[string]bool
+	for _, m := range ms {
+		w, ok := m.wordTerm()
+		if !ok {
+			return nil
+		}
+		found := make(map[string]bool)
+		// a term made of word characters is inside a single word of the
+		// text, so the quests having a word containing it are all that
+		// can match
+		for word, qids := range idx.words {
+			if !strings.Contains(word, w) {
+				continue
+			}
+			for _, id := range qids {
+				if ids == nil || ids[id] {
+					found[id] = true
+				}
+			}
+		}
+		ids = found
+	}
+	return ids
+}
+
+// wordTerm returns the lower cased term of a literal matcher, if it is made
+// only of word characters.
+func (m *matcher) wordTerm() (string, bool) {
+	if m.re != nil || m.term == "" {
+		return "", false
+	}
+	w := strings.ToLower(m.term)
+	for _, r := range w {
+		if !isWordRune(r) {
+			return "", false
+		}
+	}
+	return w, true
+}
+
+// matchLower reports whether m matches s, whose lower cased copy is lower.
+// Case-insensitive literal matchers look at lower, saving lower casing s
+// again.
+func (m *matcher) matchLower(s, lower string) bool {
+	if m.re == nil && m.ci && !m.word {
+		return strings.Contains(lower, m.term)
+	}
+	return m.match(s)
+}
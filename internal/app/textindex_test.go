@@ -0,0 +1,68 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTextIndex(t *testing.T) {
+	a := testApp(t)
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	ch, err := NewChapterFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.Quests) < 2 {
+		t.Skip("test chapter needs two quests")
+	}
+	q, other := ch.Quests[0], ch.Quests[1]
+	q.Title = "&6Ironwood &#ffaa00Sapling"
+	q.Description = "Plant it."
+	if err := ch.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+
+	idx := a.QB().textIndex()
+	qt := idx.quests[q.ID]
+	if qt == nil || qt.fields[0].lower[0] != "ironwood ffaa00sapling" || !qt.hex {
+		t.Fatalf("indexed %+v", qt)
+	}
+	for _, c := range []struct {
+		q    string
+		want bool
+	}{
+		{"wood", true},
+		{"IRONWOOD plant", true},
+		{"ironwood zzzz", false},
+	} {
+		ms, _ := searchMatchers(c.q, searchOptions{})
+		cand := idx.candidates(ms)
+		if cand == nil || cand[q.ID] != c.want {
+			t.Errorf("candidates(%q) = %v, want %s %v", c.q, cand, q.ID, c.want)
+		}
+	}
+	ms, _ := searchMatchers("it.", searchOptions{})
+	if idx.candidates(ms) != nil {
+		t.Error("a term with punctuation shouldn't narrow the quests")
+	}
+
+	// an unchanged quest keeps its entry when the book is loaded again
+	kept := idx.quests[other.ID]
+	q.Subtitle = "A tree"
+	if err := ch.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	a.reload()
+	idx = a.QB().textIndex()
+	if idx.quests[other.ID] != kept {
+		t.Error("unchanged quest was indexed again")
+	}
+	if idx.quests[q.ID] == qt || idx.quests[q.ID].fields[1].text[0] != "A tree" {
+		t.Error("changed quest wasn't indexed again")
+	}
+	ms, _ = searchMatchers("tree", searchOptions{Fields: []string{"subtitle"}})
+	if !idx.quest(a.QB().questMap[q.ID]).match(ms, []string{"subtitle"}) {
+		t.Error("subtitle didn't match")
+	}
+}
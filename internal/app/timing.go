@@ -0,0 +1,189 @@
+package app
+
+import (
+	"cmp"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Operations counted by timeOp, in the order they are reported. The counts
+// are for the whole process, so requests that overlap share them; edits are
+// serialized, so theirs are exact.
+const (
+	opParse  = "parse"
+	opSearch = "search"
+	opEncode = "encode"
+	opWrite  = "write"
+	opRender = "render"
+)
+
+var opNames = []string{opParse, opSearch, opEncode, opWrite, opRender}
+
+// opTotals are the nanoseconds spent in each operation since start up.
+var opTotals = func() map[string]*atomic.Int64 {
+	m := make(map[string]*atomic.Int64, len(opNames))
+	for _, op := range opNames {
+		m[op] = new(atomic.Int64)
+	}
+	return m
+}()
+
+// timeOp starts timing op, and returns the func that stops it:
+//
+//	defer timeOp(opEncode)()
+func timeOp(op string) func() {
+	start := time.Now()
+	return func() { opTotals[op].Add(int64(time.Since(start))) }
+}
+
+// opSnapshot returns the time spent in each operation so far.
+func opSnapshot() map[string]time.Duration {
+	m := make(map[string]time.Duration, len(opNames))
+	for _, op := range opNames {
+		m[op] = time.Duration(opTotals[op].Load())
+	}
+	return m
+}
+
+// routeSamples is how many of a route's latest durations are kept.
+const routeSamples = 256
+
+// routeTiming holds the durations of a route's requests.
+type routeTiming struct {
+	count int
+	max   time.Duration
+	// latest is a ring of the latest durations, next is where the next goes
+	latest []time.Duration
+	next   int
+}
+
+// RouteTiming summarizes the requests to a route.
+type RouteTiming struct {
+	Method string
+	Route  string
+	Count  int
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+// requestTimes records the durations of requests by route.
+type requestTimes struct {
+	mu     sync.Mutex
+	routes map[string]*routeTiming
+}
+
+// add records a request to the route key taking d.
+func (t *requestTimes) add(key string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.routes == nil {
+		t.routes = make(map[string]*routeTiming)
+	}
+	rt := t.routes[key]
+	if rt == nil {
+		rt = &routeTiming{}
+		t.routes[key] = rt
+	}
+	rt.count++
+	rt.max = max(rt.max, d)
+	if len(rt.latest) < routeSamples {
+		rt.latest = append(rt.latest, d)
+	} else {
+		rt.latest[rt.next] = d
+		rt.next = (rt.next + 1) % routeSamples
+	}
+}
+
+// Routes summarizes every route requested so far, slowest first by their
+// 90th percentile.
+func (t *requestTimes) Routes() []RouteTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []RouteTiming
+	for key, rt := range t.routes {
+		method, route, _ := strings.Cut(key, " ")
+		ds := slices.Clone(rt.latest)
+		slices.Sort(ds)
+		out = append(out, RouteTiming{
+			Method: method, Route: route, Count: rt.count, Max: rt.max,
+			P50: percentile(ds, 50), P90: percentile(ds, 90), P99: percentile(ds, 99),
+		})
+	}
+	slices.SortFunc(out, func(a, b RouteTiming) int {
+		if c := cmp.Compare(b.P90, a.P90); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method+a.Route, b.Method+b.Route)
+	})
+	return out
+}
+
+// percentile returns the p'th percentile of the sorted durations ds, by the
+// nearest rank.
+func percentile(ds []time.Duration, p int) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	i := (p*len(ds) + 99) / 100
+	return ds[max(i-1, 0)]
+}
+
+// timeRequests is middleware that records each request's duration by its
+// route pattern, keeping the latest durations for percentiles, so that slow
+// pages can be traced to a part of the request. Requests slower than
+// App.SlowRequest are logged with how much each operation's count grew while
+// they ran.
+func (a *App) timeRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		before := opSnapshot()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		took := time.Since(start)
+
+		route := "(unrouted)"
+		if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+			route = rc.RoutePattern()
+		}
+		a.requestTimes.add(r.Method+" "+route, took)
+		if a.SlowRequest <= 0 || took < a.SlowRequest {
+			return
+		}
+		args := []any{"method", r.Method, "path", r.URL.Path, "route", route, "status", ww.Status(), "took", took}
+		after := opSnapshot()
+		for _, op := range opNames {
+			if d := after[op] - before[op]; d > 0 {
+				args = append(args, op, d)
+			}
+		}
+		slog.Warn("slow request", args...)
+	})
+}
+
+// apiMetrics handles GET "/api/metrics": request latency percentiles by
+// route, in milliseconds, and the total time spent in each operation.
+func (a *App) apiMetrics(w http.ResponseWriter, r *http.Request) {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	var routes []map[string]any
+	for _, rt := range a.requestTimes.Routes() {
+		routes = append(routes, map[string]any{
+			"method": rt.Method, "route": rt.Route, "count": rt.Count,
+			"p50_ms": ms(rt.P50), "p90_ms": ms(rt.P90), "p99_ms": ms(rt.P99), "max_ms": ms(rt.Max),
+		})
+	}
+	ops := make(map[string]float64)
+	for op, d := range opSnapshot() {
+		ops[op+"_ms"] = ms(d)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "routes": routes, "operations": ops})
+}
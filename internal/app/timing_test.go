@@ -0,0 +1,73 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var ds []time.Duration
+	for i := 1; i <= 100; i++ {
+		ds = append(ds, time.Duration(i))
+	}
+	for p, want := range map[int]time.Duration{50: 50, 90: 90, 99: 99, 100: 100} {
+		if got := percentile(ds, p); got != want {
+			t.Errorf("percentile(%d) = %d, want %d", p, got, want)
+		}
+	}
+	if got := percentile(ds[:1], 50); got != 1 {
+		t.Errorf("percentile of one = %d", got)
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of none = %d", got)
+	}
+}
+
+func TestTimeRequests(t *testing.T) {
+	a := testApp(t)
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// every request is slow enough to log
+	a.SlowRequest = time.Nanosecond
+	h := a.Router()
+	q := a.QB().Chapters[0].Quests[0]
+	for _, path := range []string{"/chapter/test/" + q.ID, "/chapter/test/" + q.ID, "/batch/edit?q=the"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if !strings.Contains(logs.String(), "slow request") || !strings.Contains(logs.String(), "render=") {
+		t.Errorf("log:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "search=") {
+		t.Errorf("no search time logged:\n%s", logs.String())
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/metrics", nil))
+	var res struct {
+		Routes []struct {
+			Method, Route string
+			Count         int
+		}
+		Operations map[string]float64
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for _, r := range res.Routes {
+		counts[r.Method+" "+r.Route] = r.Count
+	}
+	if counts["GET /chapter/{chapter}/{quest}"] != 2 || counts["GET /batch/edit"] != 1 {
+		t.Errorf("routes = %+v", res.Routes)
+	}
+	if res.Operations["render_ms"] <= 0 {
+		t.Errorf("operations = %v", res.Operations)
+	}
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+// textComponent is the subset of Minecraft's JSON text format that FTB Quests
+// renders in descriptions.
+type textComponent struct {
+	Text       string          `json:"text"`
+	Color      string          `json:"color,omitempty"`
+	Underlined bool            `json:"underlined,omitempty"`
+	ClickEvent *clickEvent     `json:"clickEvent,omitempty"`
+	Extra      []textComponent `json:"extra,omitempty"`
+}
+
+type clickEvent struct {
+	Action string `json:"action"`
+	Value  string `json:"value"`
+}
+
+// tocID derives a stable quest id for a chapter's table of contents, so that
+// regenerating it updates the same quest rather than adding another.
+func tocID(ch *Chapter) string {
+	sum := sha1.Sum([]byte("qbedit-toc:" + ch.ID))
+	return strings.ToUpper(hex.EncodeToString(sum[:8]))
+}
+
+// tocLink returns a component that opens the quest or chapter id when clicked.
+func tocLink(title, id string) textComponent {
+	return textComponent{
+		Text:       stripCodes(title),
+		Color:      mcformat.FirstColor(title),
+		Underlined: true,
+		ClickEvent: &clickEvent{Action: "change_page", Value: id},
+	}
+}
+
+func tocLine(c textComponent) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// descriptions are SNBT strings; keep & and friends readable
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(c)
+	return strings.TrimSpace(buf.String())
+}
+
+// sortReadingOrder sorts quests top to bottom, then left to right.
+func sortReadingOrder(quests []*Quest) {
+	sort.SliceStable(quests, func(i, j int) bool {
+		yi, yj := M(quests[i].raw).GetFloat("y"), M(quests[j].raw).GetFloat("y")
+		if yi != yj {
+			return yi < yj
+		}
+		return M(quests[i].raw).GetFloat("x") < M(quests[j].raw).GetFloat("x")
+	})
+}
+
+// chapterTOCLines returns one line per quest in ch in reading order (top to
+// bottom, left to right), each followed by links to its dependencies.
+func chapterTOCLines(qb *QuestBook, ch *Chapter, skip string) []string {
+	quests := make([]*Quest, 0, len(ch.Quests))
+	for _, q := range ch.Quests {
+		if q.ID != skip {
+			quests = append(quests, q)
+		}
+	}
+	sortReadingOrder(quests)
+
+	lines := make([]string, 0, len(quests))
+	for _, q := range quests {
+		line := textComponent{Extra: []textComponent{{Text: "• "}, tocLink(q.GetTitle(), q.ID)}}
+		for i, dep := range q.Dependencies {
+			sep := ", "
+			if i == 0 {
+				sep = " ← "
+			}
+			line.Extra = append(line.Extra, textComponent{Text: sep, Color: "gray"})
+			title := dep
+			if dq, ok := qb.questMap[dep]; ok {
+				title = dq.GetTitle()
+			} else if dq, ok := ch.questMap[dep]; ok {
+				title = dq.GetTitle()
+			}
+			line.Extra = append(line.Extra, tocLink(title, dep))
+		}
+		lines = append(lines, tocLine(line))
+	}
+	return lines
+}
+
+// bookTOCLines returns one line per chapter in sidebar order, with group
+// titles as headings.
+func bookTOCLines(qb *QuestBook, skip string) []string {
+	var lines []string
+	chapterLine := func(c *Chapter) {
+		if c.ID == skip {
+			return
+		}
+		lines = append(lines, tocLine(textComponent{Extra: []textComponent{{Text: "• "}, tocLink(c.Title, c.ID)}}))
+	}
+	for _, it := range qb.TopItems() {
+		if it.Kind == "chapter" {
+			chapterLine(it.Chapter)
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "&l"+it.Group.Title)
+		for _, c := range it.Group.Chapters {
+			chapterLine(c)
+		}
+	}
+	return lines
+}
+
+// GenerateTOC creates or updates the table of contents quest in ch, which
+// lists ch's quests as clickable lines in its description. If book is true it
+// lists every chapter in qb instead. Pack authors often keep these by hand;
+// generating them lets them be regenerated after edits. Dependency titles are
+// resolved against qb, which should be the currently loaded book.
+func GenerateTOC(qb *QuestBook, ch *Chapter, book bool) *Quest {
+	id := tocID(ch)
+	q, ok := ch.questMap[id]
+	if !ok {
+		// place new quests to the left of the chapter's top-left quest
+		var minX, minY float64
+		for i, cq := range ch.Quests {
+			x, y := M(cq.raw).GetFloat("x"), M(cq.raw).GetFloat("y")
+			if i == 0 || x < minX {
+				minX = x
+			}
+			if i == 0 || y < minY {
+				minY = y
+			}
+		}
+		q, _ = NewQuest(map[string]any{
+			"id":    id,
+			"x":     decimalValue(minX - 2),
+			"y":     decimalValue(minY),
+			"shape": "rsquare",
+			"tasks": []any{map[string]any{"id": qb.idSource().Next(), "type": "checkmark"}},
+		})
+		q.Chapter = ch
+		ch.Quests = append(ch.Quests, q)
+		ch.questMap[id] = q
+	}
+
+	q.Title = "&6Table of Contents"
+	q.Subtitle = stripCodes(ch.Title)
+	lines := chapterTOCLines(qb, ch, id)
+	if book {
+		q.Subtitle = "All Chapters"
+		lines = bookTOCLines(qb, ch.ID)
+	}
+	q.Description = strings.Join(lines, "\n")
+	return q
+}
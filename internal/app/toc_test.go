@@ -0,0 +1,103 @@
+package app
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateTOC(t *testing.T) {
+	a := testApp(t)
+	qb := a.QB()
+	path := filepath.Join(a.Root(), "quests", "chapters", "test.snbt")
+	for _, tc := range []struct {
+		name     string
+		book     bool
+		subtitle string
+		lines    int
+	}{
+		{"chapter", false, stripCodes(qb.Chapters[0].Title), len(qb.Chapters[0].Quests)},
+		{"book", true, "All Chapters", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ch, err := NewChapterFromPath(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := len(ch.Quests)
+			q := GenerateTOC(qb, ch, tc.book)
+			if q.ID != tocID(ch) || q.Chapter != ch || len(ch.Quests) != n+1 || ch.questMap[q.ID] != q {
+				t.Fatalf("toc quest %s not added to the chapter", q.ID)
+			}
+			if q.Title != "&6Table of Contents" || q.Subtitle != tc.subtitle {
+				t.Errorf("title %q, subtitle %q", q.Title, q.Subtitle)
+			}
+			// the book's only chapter is the one the quest is in, so the
+			// book's table of contents is empty
+			lines := splitMultistring(q.Description)
+			if len(lines) != tc.lines {
+				t.Errorf("%d lines, want %d", len(lines), tc.lines)
+			}
+			for _, line := range lines {
+				var c textComponent
+				if err := json.Unmarshal([]byte(line), &c); err != nil {
+					t.Fatalf("line %q: %v", line, err)
+				}
+				if len(c.Extra) < 2 || c.Extra[1].ClickEvent == nil || c.Extra[1].ClickEvent.Value == q.ID {
+					t.Errorf("line %q doesn't link to a quest or chapter", line)
+				}
+			}
+			// regenerating updates the same quest
+			if again := GenerateTOC(qb, ch, tc.book); again != q || len(ch.Quests) != n+1 {
+				t.Errorf("regenerating added another quest")
+			}
+		})
+	}
+}
+
+func TestChapterTOCLines(t *testing.T) {
+	qb := &QuestBook{questMap: map[string]*Quest{}}
+	ch := &Chapter{questMap: map[string]*Quest{}}
+	for _, q := range []*Quest{
+		{ID: "C", Title: "Third", raw: map[string]any{"x": 0.0, "y": 2.0}, Dependencies: []string{"A", "GONE"}},
+		{ID: "B", Title: "&aSecond", raw: map[string]any{"x": 1.0, "y": 0.0}},
+		{ID: "A", Title: "First", raw: map[string]any{"x": 0.0, "y": 0.0}},
+		{ID: "T", Title: "Skipped", raw: map[string]any{"x": -1.0, "y": -1.0}},
+	} {
+		ch.Quests = append(ch.Quests, q)
+		ch.questMap[q.ID] = q
+		qb.questMap[q.ID] = q
+	}
+	want := []string{
+		`{"text":"","extra":[{"text":"• "},{"text":"First","underlined":true,"clickEvent":{"action":"change_page","value":"A"}}]}`,
+		`{"text":"","extra":[{"text":"• "},{"text":"Second","color":"green","underlined":true,"clickEvent":{"action":"change_page","value":"B"}}]}`,
+		`{"text":"","extra":[{"text":"• "},{"text":"Third","underlined":true,"clickEvent":{"action":"change_page","value":"C"}},` +
+			`{"text":" ← ","color":"gray"},{"text":"First","underlined":true,"clickEvent":{"action":"change_page","value":"A"}},` +
+			`{"text":", ","color":"gray"},{"text":"GONE","underlined":true,"clickEvent":{"action":"change_page","value":"GONE"}}]}`,
+	}
+	got := chapterTOCLines(qb, ch, "T")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBookTOCLines(t *testing.T) {
+	intro := &Chapter{ID: "I", Title: "Intro", OrderIndex: 0}
+	toc := &Chapter{ID: "T", Title: "Contents", OrderIndex: 1}
+	ores := &Chapter{ID: "O", Title: "&7Ores", GroupID: "G"}
+	qb := &QuestBook{
+		Chapters: []*Chapter{intro, toc, ores},
+		Groups:   []*Group{{ID: "G", Title: "Early Game", Chapters: []*Chapter{ores}}},
+	}
+	link := func(title, color, id string) string {
+		if color != "" {
+			color = `"color":"` + color + `",`
+		}
+		return `{"text":"","extra":[{"text":"• "},{"text":"` + title + `",` + color + `"underlined":true,"clickEvent":{"action":"change_page","value":"` + id + `"}}]}`
+	}
+	want := []string{link("Intro", "", "I"), "", "&lEarly Game", link("Ores", "gray", "O")}
+	if got := bookTOCLines(qb, "T"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,384 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Transform is a rewrite of the text of every quest a batch search matches,
+// eg. to give all the titles of a chapter a color. It rewrites a line at a
+// time: descriptions are rewritten line by line, as FTB Quests styles each
+// line on its own, and empty lines and lines holding JSON text components are
+// left alone. Formatting codes aren't text, so the casing transforms skip
+// them.
+type Transform struct {
+	Name, Label string
+	// Arg describes the transform's argument, or is "" if it takes none
+	Arg string
+	// check validates the argument, if the transform takes one
+	check func(arg string) error
+	// apply returns line rewritten
+	apply func(line string, tc *transformContext) string
+}
+
+// transformContext is what a transform knows about the quest it rewrites.
+type transformContext struct {
+	Arg string
+	// Sign is the sign of the quest's codes, for the codes a transform adds
+	Sign rune
+	// Items are the names of the items of the quest's tasks and rewards
+	Items []string
+}
+
+// transforms are the transforms the batch editor offers, in menu order. A new
+// transform only needs adding here.
+var transforms = []Transform{
+	{Name: "color", Label: "Color the text", Arg: "color code or #rrggbb", check: checkColor, apply: func(line string, tc *transformContext) string {
+		c, _ := parseColor(tc.Arg)
+		code := colorCode(c, tc.Sign)
+		if strings.HasPrefix(line, code) {
+			return line
+		}
+		return code + line
+	}},
+	{Name: "color-items", Label: "Color item names", Arg: "color code or #rrggbb", check: checkColor, apply: colorItemNames},
+	{Name: "prefix", Label: "Add a prefix", Arg: "text", check: checkNotEmpty, apply: func(line string, tc *transformContext) string {
+		if strings.HasPrefix(line, tc.Arg) {
+			return line
+		}
+		return tc.Arg + line
+	}},
+	{Name: "suffix", Label: "Add a suffix", Arg: "text", check: checkNotEmpty, apply: func(line string, tc *transformContext) string {
+		if strings.HasSuffix(line, tc.Arg) {
+			return line
+		}
+		return line + tc.Arg
+	}},
+	{Name: "title-case", Label: "Title Case", apply: func(line string, tc *transformContext) string {
+		return mapText(line, func(r rune, start bool) rune {
+			if start {
+				return unicode.ToUpper(r)
+			}
+			return r
+		})
+	}},
+	{Name: "upper", Label: "UPPER CASE", apply: func(line string, tc *transformContext) string {
+		return mapText(line, func(r rune, start bool) rune { return unicode.ToUpper(r) })
+	}},
+	{Name: "lower", Label: "lower case", apply: func(line string, tc *transformContext) string {
+		return mapText(line, func(r rune, start bool) rune { return unicode.ToLower(r) })
+	}},
+	{Name: "trim", Label: "Strip trailing whitespace", apply: func(line string, tc *transformContext) string {
+		return strings.TrimRight(line, " \t")
+	}},
+}
+
+// findTransform returns the transform called name.
+func findTransform(name string) (*Transform, bool) {
+	i := slices.IndexFunc(transforms, func(t Transform) bool { return t.Name == name })
+	if i < 0 {
+		return nil, false
+	}
+	return &transforms[i], true
+}
+
+func checkColor(arg string) error {
+	if _, ok := parseColor(arg); !ok {
+		return fmt.Errorf("invalid color %q: use a color code like &6 or a #rrggbb color", arg)
+	}
+	return nil
+}
+
+func checkNotEmpty(arg string) error {
+	if arg == "" {
+		return fmt.Errorf("the text to add is empty")
+	}
+	return nil
+}
+
+// mapText maps the runes of line that aren't part of a formatting code with
+// f; start is set for the first letter or digit of a word.
+func mapText(line string, f func(r rune, start bool) rune) string {
+	rs := []rune(line)
+	var b strings.Builder
+	inWord := false
+	for i := 0; i < len(rs); i++ {
+		if n := formatCodeLen(rs, i); n > 0 {
+			b.WriteString(string(rs[i : i+n]))
+			i += n - 1
+			continue
+		}
+		word := isWordRune(rs[i]) || (inWord && rs[i] == '\'')
+		b.WriteRune(f(rs[i], word && !inWord))
+		inWord = word
+	}
+	return b.String()
+}
+
+// formatCodeLen returns the length in runes of the color or format code at
+// rs[i], or 0 if there isn't one.
+func formatCodeLen(rs []rune, i int) int {
+	if n := colorCodeLen(rs, i); n > 0 {
+		return n
+	}
+	if i+1 < len(rs) && (rs[i] == signAmp || rs[i] == signSection) && isFormatCode(rs[i+1]) {
+		return 2
+	}
+	return 0
+}
+
+// activeCodes returns the codes styling the text at rs[end], to restore the
+// style after a colored span, or a reset if there are none.
+func activeCodes(rs []rune, end int, sign rune) string {
+	var codes []rune
+	for i := 0; i < end; i++ {
+		n := formatCodeLen(rs, i)
+		if n == 0 {
+			continue
+		}
+		switch c := unicode.ToLower(rs[i+1]); {
+		case c == 'r':
+			codes = nil
+		case n > 2 || isColorCode(c):
+			// a color clears the formats before it
+			codes = slices.Clone(rs[i : i+n])
+		default:
+			codes = append(codes, rs[i:i+n]...)
+		}
+		i += n - 1
+	}
+	if len(codes) == 0 {
+		return string(sign) + "r"
+	}
+	return string(codes)
+}
+
+// itemName returns the words of an item id, eg. "iron ingot" for
+// minecraft:iron_ingot, or "" for the ids of item filters.
+func itemName(id string) string {
+	ns, path, ok := strings.Cut(id, ":")
+	if !ok || ns == "itemfilters" {
+		return ""
+	}
+	return strings.TrimSpace(strings.ReplaceAll(path, "_", " "))
+}
+
+// colorItemNames wraps the names of the quest's items in line, in any case
+// and plural, in the color tc.Arg. Names already styled on their own, with a
+// code before them and a code or the end of the line after, are left alone.
+func colorItemNames(line string, tc *transformContext) string {
+	if len(tc.Items) == 0 {
+		return line
+	}
+	c, _ := parseColor(tc.Arg)
+	code := colorCode(c, tc.Sign)
+	// one pass over all the names, longest first, so a shorter name is not
+	// colored again inside a longer one; \b would not match between a code
+	// and the name, as in "&7Iron", so the boundaries are checked here
+	alts := make([]string, len(tc.Items))
+	for i, name := range tc.Items {
+		alts[i] = regexp.QuoteMeta(name)
+	}
+	re := regexp.MustCompile(`(?i)(?:` + strings.Join(alts, "|") + `)(?:e?s)?`)
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringIndex(line, -1) {
+		before, after := []rune(line[:m[0]]), []rune(line[m[1]:])
+		n := len(before)
+		coded := n >= 2 && formatCodeLen(before, n-2) == 2
+		if (n > 0 && !coded && isWordRune(before[n-1])) || (len(after) > 0 && isWordRune(after[0])) {
+			continue
+		}
+		if coded && (len(after) == 0 || formatCodeLen(after, 0) > 0) {
+			continue
+		}
+		b.WriteString(line[last:m[0]])
+		b.WriteString(code + line[m[0]:m[1]] + activeCodes([]rune(line), n, tc.Sign))
+		last = m[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// transformText applies t to each line of s that has text.
+func transformText(t *Transform, s string, tc *transformContext) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			continue
+		}
+		lines[i] = t.apply(line, tc)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TransformChange is a field a transform changes.
+type TransformChange struct {
+	Field, Before, After string
+}
+
+// TransformResult is what a transform changes in a quest.
+type TransformResult struct {
+	Chapter *Chapter
+	Quest   *Quest
+	Changes []TransformChange
+}
+
+// transformQuest applies t with arg to the fields of q, and returns what
+// changed.
+func transformQuest(t *Transform, arg string, q *Quest, fields []string) []TransformChange {
+	tc := &transformContext{Arg: arg, Sign: codeSign(signAmp, q.Title, q.Subtitle, q.Description)}
+	add := func(id string) {
+		if name := itemName(id); name != "" && !slices.Contains(tc.Items, name) {
+			tc.Items = append(tc.Items, name)
+		}
+	}
+	for _, task := range q.Tasks {
+		if it, ok := task.(*ItemTask); ok {
+			add(it.Item)
+		}
+	}
+	for _, r := range q.Rewards {
+		if it, ok := r.(*ItemReward); ok {
+			add(it.Item)
+		}
+	}
+	// longer names first, so "iron ingot" is colored before "iron"
+	sort.SliceStable(tc.Items, func(i, j int) bool { return len(tc.Items[i]) > len(tc.Items[j]) })
+
+	var changes []TransformChange
+	for _, f := range fields {
+		before := questField(q, f)
+		if after := transformText(t, before, tc); after != before {
+			changes = append(changes, TransformChange{Field: f, Before: before, After: after})
+		}
+	}
+	return changes
+}
+
+// transformOptions reads the transform, its argument and the fields to apply
+// it to from a form.
+func transformOptions(form url.Values) (*Transform, string, []string, error) {
+	t, ok := findTransform(form.Get("transform"))
+	if !ok {
+		return nil, "", nil, fmt.Errorf("unknown transform %q", form.Get("transform"))
+	}
+	arg := form.Get("arg")
+	if t.check != nil {
+		if err := t.check(arg); err != nil {
+			return nil, "", nil, err
+		}
+	}
+	var fields []string
+	for _, f := range form["field"] {
+		if slices.Contains(searchFields, f) && !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, "", nil, fmt.Errorf("choose the fields to transform")
+	}
+	return t, arg, fields, nil
+}
+
+// applyTransform applies t to the quests of matches, writing their chapters
+// together, and returns the changed quests' ids by chapter.
+func applyTransform(qb *QuestBook, matches []batchMatch, t *Transform, arg string, fields []string) (map[string][]string, error) {
+	byChapter := make(map[string][]string)
+	for _, m := range matches {
+		byChapter[m.Chapter.Name] = append(byChapter[m.Chapter.Name], m.Quest.ID)
+	}
+	return editChapters(qb, slices.Collect(maps.Keys(byChapter)), func(ch *Chapter) ([]string, error) {
+		var changed []string
+		for _, id := range byChapter[ch.Name] {
+			quest, ok := ch.questMap[id]
+			if !ok {
+				return nil, fmt.Errorf("quest %s not found", id)
+			}
+			changes := transformQuest(t, arg, quest, fields)
+			for _, c := range changes {
+				setQuestField(quest, c.Field, c.After)
+			}
+			if len(changes) > 0 {
+				changed = append(changed, id)
+			}
+		}
+		return changed, nil
+	})
+}
+
+// batchSearchKeys are the parameters of a batch search, which the transform
+// pages pass along.
+var batchSearchKeys = []string{"cg", "q", "no_title", "no_subtitle", "no_desc", "case", "regex", "word", "in", "ids"}
+
+// batchSearch returns the batch search parameters of form.
+func batchSearch(form url.Values) url.Values {
+	search := url.Values{}
+	for _, k := range batchSearchKeys {
+		if v, ok := form[k]; ok {
+			search[k] = v
+		}
+	}
+	return search
+}
+
+// batchTransform handles GET "/batch/transform", a preview of the transform
+// "transform" with "arg" applied to the "field"s of the quests of a batch
+// search, and POST "/batch/transform", which applies it.
+func (a *App) batchTransform(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	search := batchSearch(r.Form)
+	back := func(msg string) {
+		qs := batchSearch(r.Form)
+		qs.Set("msg", msg)
+		http.Redirect(w, r, "/batch/?"+qs.Encode(), http.StatusSeeOther)
+	}
+	t, arg, fields, err := transformOptions(r.Form)
+	if err != nil {
+		back(err.Error())
+		return
+	}
+	matches, err := batchMatches(qb, search)
+	if err != nil {
+		back("Invalid regular expression: " + err.Error())
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		changed, err := applyTransform(qb, matches, t, arg, fields)
+		if err != nil {
+			http.Error(w, "transform: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		n := a.auditEdits(r, "batch transform", t.Label+" "+arg, changed)
+		if n > 0 {
+			a.reload()
+		}
+		back(fmt.Sprintf("%s: changed %d quests.", t.Label, n))
+		return
+	}
+
+	var results []TransformResult
+	for _, m := range matches {
+		if changes := transformQuest(t, arg, m.Quest, fields); len(changes) > 0 {
+			results = append(results, TransformResult{Chapter: m.Chapter, Quest: m.Quest, Changes: changes})
+		}
+	}
+	data := a.baseData(r, "Transform")
+	data["Transform"], data["Arg"], data["Fields"] = t, arg, fields
+	data["Results"], data["MatchCount"] = results, len(matches)
+	data["Search"] = search
+	data["EditURL"] = "/batch/edit?" + search.Encode()
+	a.render(w, "transform.gohtml", data)
+}
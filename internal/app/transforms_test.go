@@ -0,0 +1,61 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTransforms(t *testing.T) {
+	q := &Quest{Title: "&6gold ingot's use", Description: "Smelt an Iron Ingot.  \n\n&7Iron ingots and iron\n{\"text\":\"json\"}"}
+	q.Tasks = []Task{&ItemTask{Item: "minecraft:iron_ingot"}, &ItemTask{Item: "minecraft:iron"}}
+	apply := func(name, arg, field string) string {
+		tr, ok := findTransform(name)
+		if !ok {
+			t.Fatalf("no transform %s", name)
+		}
+		changes := transformQuest(tr, arg, q, []string{field})
+		if len(changes) == 0 {
+			return questField(q, field)
+		}
+		return changes[0].After
+	}
+	for _, tc := range []struct{ name, arg, field, want string }{
+		{"title-case", "", "title", "&6Gold Ingot's Use"},
+		{"upper", "", "title", "&6GOLD INGOT'S USE"},
+		{"color", "c", "title", "&c&6gold ingot's use"},
+		{"color", "6", "title", "&6gold ingot's use"},
+		{"prefix", "> ", "description", "> Smelt an Iron Ingot.  \n\n> &7Iron ingots and iron\n{\"text\":\"json\"}"},
+		{"trim", "", "description", "Smelt an Iron Ingot.\n\n&7Iron ingots and iron\n{\"text\":\"json\"}"},
+		{"color-items", "e", "description", "Smelt an &eIron Ingot&r.  \n\n&7&eIron ingots&7 and &eiron&7\n{\"text\":\"json\"}"},
+	} {
+		if got := apply(tc.name, tc.arg, tc.field); got != tc.want {
+			t.Errorf("%s %q on %s = %q, want %q", tc.name, tc.arg, tc.field, got, tc.want)
+		}
+	}
+	if _, _, _, err := transformOptions(url.Values{"transform": {"color"}, "arg": {"pink"}, "field": {"title"}}); err == nil {
+		t.Error("invalid color accepted")
+	}
+
+	a := testApp(t)
+	form := url.Values{"q": {""}, "transform": {"suffix"}, "arg": {"!"}, "field": {"title"}}
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/batch/transform?"+form.Encode(), nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Apply to") {
+		t.Fatalf("preview: %d", rec.Code)
+	}
+	req := httptest.NewRequest("POST", "/batch/transform", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("apply: %d %s", rec.Code, rec.Body)
+	}
+	for _, q := range a.QB().Quests {
+		if q.Title != "" && !strings.HasSuffix(q.Title, "!") {
+			t.Errorf("quest %s title %q", q.ID, q.Title)
+		}
+	}
+}
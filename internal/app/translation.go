@@ -0,0 +1,384 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/qbedit/internal/app/mcformat"
+)
+
+// TextEntry is one translatable string. Quest is empty for a chapter title.
+// Translators work on a table of entries rather than on SNBT: it is exported
+// as CSV or JSON, edited offline, and imported again, and the import shows
+// what changed and only writes the rows that are accepted.
+type TextEntry struct {
+	Chapter string `json:"chapter"`
+	Quest   string `json:"quest"`
+	Field   string `json:"field"`
+	Text    string `json:"text"`
+}
+
+// textColumns is the header of the CSV export.
+var textColumns = []string{"chapter", "quest", "field", "text"}
+
+// exportText returns the book's text in chapter order. Empty fields are left
+// out; an import can't add text a quest doesn't have yet.
+func exportText(qb *QuestBook) []TextEntry {
+	var entries []TextEntry
+	for _, ch := range qb.Chapters {
+		if ch.Title != "" {
+			entries = append(entries, TextEntry{Chapter: ch.Name, Field: "title", Text: ch.Title})
+		}
+		for _, q := range ch.Quests {
+			for _, f := range searchFields {
+				if text := questField(q, f); text != "" {
+					entries = append(entries, TextEntry{Chapter: ch.Name, Quest: q.ID, Field: f, Text: text})
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// questField returns the text of one of searchFields.
+func questField(q *Quest, field string) string {
+	switch field {
+	case "title":
+		return q.Title
+	case "subtitle":
+		return q.Subtitle
+	case "description":
+		return q.Description
+	}
+	return ""
+}
+
+// setQuestField sets one of searchFields.
+func setQuestField(q *Quest, field, text string) {
+	switch field {
+	case "title":
+		q.Title = text
+	case "subtitle":
+		q.Subtitle = text
+	case "description":
+		q.Description = text
+	}
+}
+
+// writeTextCSV writes entries as CSV with a header row.
+func writeTextCSV(w io.Writer, entries []TextEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write(textColumns)
+	for _, e := range entries {
+		cw.Write([]string{e.Chapter, e.Quest, e.Field, e.Text})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readText reads entries exported by writeTextCSV, writeTextPlain or as
+// JSON. JSON is recognized by its leading '[', and plain text by its leading
+// entry header.
+func readText(r io.Reader) ([]TextEntry, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// spreadsheet programs like to add a byte order mark
+	s := strings.TrimPrefix(string(b), "\uFEFF")
+	if strings.HasPrefix(strings.TrimSpace(s), strings.TrimSpace(plainHeader)) {
+		return readTextPlain(s)
+	}
+	if strings.HasPrefix(strings.TrimSpace(s), "[") {
+		var entries []TextEntry
+		if err := json.Unmarshal([]byte(s), &entries); err != nil {
+			return nil, fmt.Errorf("reading JSON: %w", err)
+		}
+		return entries, nil
+	}
+
+	cr := csv.NewReader(strings.NewReader(s))
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	col := make(map[string]int)
+	for i, name := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range textColumns {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("reading CSV: missing %q column", name)
+		}
+	}
+	entries := make([]TextEntry, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		entries = append(entries, TextEntry{
+			Chapter: row[col["chapter"]],
+			Quest:   row[col["quest"]],
+			Field:   row[col["field"]],
+			Text:    row[col["text"]],
+		})
+	}
+	return entries, nil
+}
+
+// TextChange is an imported entry whose text differs from the book's. Err
+// is set for entries that can't be applied, eg. for a quest that no longer
+// exists.
+type TextChange struct {
+	TextEntry
+	Old string
+	Err string
+}
+
+// mapLines returns text with f applied to each of its lines.
+func mapLines(text string, f func(string) string) string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = f(l)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sameLines reports whether a and b have as many lines as each other, and
+// same reports true for each pair of them.
+func sameLines(a, b string, same func(a, b string) bool) bool {
+	al, bl := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(al) != len(bl) {
+		return false
+	}
+	for i := range al {
+		if !same(al[i], bl[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// miniMessageText converts text to MiniMessage tags line by line, for the
+// export's markup=minimessage.
+func miniMessageText(entries []TextEntry) {
+	for i := range entries {
+		entries[i].Text = mapLines(entries[i].Text, mcformat.ToMiniMessage)
+	}
+}
+
+// diffText compares imported entries with the book and returns the ones
+// that change something. Spreadsheets often turn \n line breaks into \r\n,
+// so those don't count as changes. Text exported with MiniMessage tags is
+// converted back to formatting codes where the book doesn't use MiniMessage.
+func diffText(qb *QuestBook, entries []TextEntry) []TextChange {
+	var changes []TextChange
+	for _, e := range entries {
+		e.Text = strings.ReplaceAll(e.Text, "\r\n", "\n")
+		c := TextChange{TextEntry: e}
+		ch, ok := qb.chapterMap[e.Chapter]
+		switch {
+		case !ok:
+			c.Err = "unknown chapter"
+		case e.Quest == "":
+			if e.Field != "title" {
+				c.Err = "unknown chapter field " + e.Field
+			}
+			c.Old = ch.Title
+		default:
+			q, ok := ch.questMap[e.Quest]
+			if !ok {
+				c.Err = "unknown quest"
+				break
+			}
+			if !slices.Contains(searchFields, e.Field) {
+				c.Err = "unknown field " + e.Field
+				break
+			}
+			c.Old = questField(q, e.Field)
+		}
+		if c.Err == "" && mcformat.IsMiniMessage(c.Text) && !mcformat.IsMiniMessage(c.Old) {
+			sign := codeSign(signAmp, c.Old)
+			c.Text = mapLines(c.Text, func(l string) string { return mcformat.ToLegacy(l, sign) })
+			if sameLines(c.Old, c.Text, mcformat.SameLook) {
+				// the codes may be written differently, but the text is the same
+				c.Text = c.Old
+			}
+		}
+		if c.Err == "" && c.Old == c.Text {
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// textExport handles GET "/export", downloading the book's text as CSV, as
+// JSON with format=json, or as plain text to make patches of with format=txt.
+// markup=minimessage writes formatting as MiniMessage tags instead of codes.
+func (a *App) textExport(w http.ResponseWriter, r *http.Request) {
+	entries := exportText(a.QB())
+	name := "quest-text-" + time.Now().Format("20060102")
+	if r.URL.Query().Get("markup") == "minimessage" {
+		miniMessageText(entries)
+		name += "-minimessage"
+	}
+	switch r.URL.Query().Get("format") {
+	case "txt":
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".txt"))
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := writeTextPlain(w, entries); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	case "json":
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".json"))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(b)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	if err := writeTextCSV(w, entries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// textImport handles GET "/import", the upload form, and POST "/import",
+// which shows the changes in an uploaded file without applying them. A
+// unified diff of the plain text export is applied to the book's text first.
+func (a *App) textImport(w http.ResponseWriter, r *http.Request) {
+	data := a.baseData(r, "Import Text")
+	if msg := r.URL.Query().Get("msg"); msg != "" {
+		data["ImportMsg"] = msg
+	}
+	if r.Method == http.MethodPost {
+		qb := a.QB()
+		var entries []TextEntry
+		s, err := readUploadedText(r)
+		if err == nil && isPatch(s) {
+			var rejected []string
+			entries, rejected, err = patchText(qb, s)
+			data["Rejected"] = rejected
+		} else if err == nil {
+			entries, err = readText(strings.NewReader(s))
+		}
+		if err != nil {
+			data["ImportErr"] = err.Error()
+		} else {
+			data["Changes"] = diffText(qb, entries)
+			data["Imported"] = true
+		}
+	}
+	a.render(w, "import.gohtml", data)
+}
+
+// readUploadedText returns the "file" upload, or the "text" field if there
+// is no file.
+func readUploadedText(r *http.Request) (string, error) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		return "", err
+	}
+	if f, _, err := r.FormFile("file"); err == nil {
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		return string(b), err
+	}
+	if text := r.FormValue("text"); strings.TrimSpace(text) != "" {
+		return text, nil
+	}
+	return "", fmt.Errorf("choose a file to import")
+}
+
+// textImportApply handles POST "/import/apply". The preview form posts the
+// chapter, quest, field, old and text of every change, and apply holds the
+// indexes of the ones to write. A change is skipped if the text in the book
+// no longer matches old.
+func (a *App) textImportApply(w http.ResponseWriter, r *http.Request) {
+	qb := a.QB()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	chapters, quests, fields := r.Form["chapter"], r.Form["quest"], r.Form["field"]
+	olds, texts := r.Form["old"], r.Form["text"]
+	if len(quests) != len(chapters) || len(fields) != len(chapters) || len(olds) != len(chapters) || len(texts) != len(chapters) {
+		http.Error(w, "mismatched import fields", http.StatusBadRequest)
+		return
+	}
+	byChapter := make(map[string][]TextChange)
+	for _, s := range r.Form["apply"] {
+		var i int
+		if _, err := fmt.Sscan(s, &i); err != nil || i < 0 || i >= len(chapters) {
+			continue
+		}
+		c := TextChange{TextEntry: TextEntry{Chapter: chapters[i], Quest: quests[i], Field: fields[i], Text: texts[i]}, Old: olds[i]}
+		byChapter[c.Chapter] = append(byChapter[c.Chapter], c)
+	}
+
+	var names []string
+	for name := range byChapter {
+		if _, ok := qb.chapterMap[name]; ok {
+			names = append(names, name)
+		}
+	}
+	applied, stale := 0, 0
+	edited, err := editChapters(qb, names, func(ch *Chapter) ([]string, error) {
+		var ids []string
+		title := false
+		for _, c := range byChapter[ch.Name] {
+			if c.Quest == "" {
+				if c.Field != "title" || ch.Title != c.Old {
+					stale++
+					continue
+				}
+				ch.Title, title = c.Text, true
+				continue
+			}
+			q, ok := ch.questMap[c.Quest]
+			if !ok || !slices.Contains(searchFields, c.Field) || questField(q, c.Field) != c.Old {
+				stale++
+				continue
+			}
+			setQuestField(q, c.Field, c.Text)
+			ids = append(ids, q.ID)
+		}
+		if !title {
+			return ids, nil
+		}
+		if ch.titleKey == "" {
+			// a keyed title is written to the lang file by stageChapter
+			ch.raw["title"] = ch.Title
+		}
+		applied++
+		if ids == nil {
+			ids = []string{}
+		}
+		return ids, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	applied += a.auditEdits(r, "import text", "", edited)
+	if applied > 0 {
+		a.reload()
+	}
+	msg := fmt.Sprintf("Applied changes to %d quests and chapters.", applied)
+	if stale > 0 {
+		msg += fmt.Sprintf(" %d changes were skipped because the text changed since the preview.", stale)
+	}
+	http.Redirect(w, r, "/import?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTextRoundTrip(t *testing.T) {
+	a := testApp(t)
+	qb := a.QB()
+	entries := exportText(qb)
+	if len(entries) == 0 {
+		t.Fatal("nothing exported")
+	}
+	var buf bytes.Buffer
+	if err := writeTextCSV(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readText(strings.NewReader(strings.ReplaceAll(buf.String(), "\n", "\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := diffText(qb, got); len(changes) != 0 {
+		t.Fatalf("unedited export has changes: %+v", changes)
+	}
+
+	q := qb.Chapters[0].Quests[0]
+	edited := []TextEntry{
+		{Chapter: "test", Quest: q.ID, Field: "title", Text: "Translated"},
+		{Chapter: "test", Quest: "FFFFFFFFFFFFFFFF", Field: "title", Text: "x"},
+	}
+	changes := diffText(qb, edited)
+	if len(changes) != 2 || changes[0].Old != q.Title || changes[0].Err != "" || changes[1].Err == "" {
+		t.Fatalf("got %+v", changes)
+	}
+
+	form := url.Values{"apply": {"0"}}
+	for _, c := range changes {
+		form.Add("chapter", c.Chapter)
+		form.Add("quest", c.Quest)
+		form.Add("field", c.Field)
+		form.Add("old", c.Old)
+		form.Add("text", c.Text)
+	}
+	req := httptest.NewRequest("POST", "/import/apply", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("apply: %d %s", rec.Code, rec.Body)
+	}
+	if got := a.QB().questMap[q.ID].Title; got != "Translated" {
+		t.Errorf("title is %q after import", got)
+	}
+
+	// the same preview again is stale now
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/import/apply", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	a.Router().ServeHTTP(rec, req)
+	if loc := rec.Header().Get("Location"); !strings.Contains(loc, "skipped") {
+		t.Errorf("expected a stale change to be skipped, got %q", loc)
+	}
+}
+
+func TestMiniMessageText(t *testing.T) {
+	a := testApp(t)
+	qb := a.QB()
+	q := qb.Chapters[0].Quests[0]
+	entries := []TextEntry{{Chapter: "test", Quest: q.ID, Field: "title", Text: q.Title}}
+	miniMessageText(entries)
+	if changes := diffText(qb, entries); len(changes) != 0 {
+		t.Errorf("unedited MiniMessage export has changes: %+v", changes)
+	}
+	entries[0].Text = "<gold>Gold <bold>bold</bold></gold>"
+	changes := diffText(qb, entries)
+	if len(changes) != 1 || changes[0].Text != "&6Gold &lbold" {
+		t.Errorf("changes = %+v", changes)
+	}
+
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/api/convert?to=minimessage&text="+url.QueryEscape("&6Gold"), nil))
+	var res struct{ Text string }
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.Text != "<gold>Gold</gold>" {
+		t.Errorf("convert: %d %s", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/api/convert?to=json&text="+url.QueryEscape("<red>Hi"), nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.Text != `["",{"text":"Hi","color":"red"}]` {
+		t.Errorf("convert to json: %d %s", rec.Code, rec.Body)
+	}
+}
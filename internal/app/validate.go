@@ -0,0 +1,191 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Kinds of book issues, in the order the issues page lists them.
+const (
+	IssueDuplicateID  = "duplicate-id"
+	IssueDanglingDep  = "dangling-dependency"
+	IssueUnreachable  = "unreachable-quest"
+	IssueNoEntry      = "no-entry-point"
+	IssueMissingTitle = "missing-title"
+	IssueUnbalanced   = "unbalanced-codes"
+	IssueEmptyChapter = "empty-chapter"
+	IssueInvalidItem  = "invalid-item"
+	IssueMisspelled   = "misspelled-key"
+	IssueMissingTable = "missing-reward-table"
+)
+
+// issueKinds describes each kind of issue for the issues page.
+var issueKinds = []struct{ Kind, Title, Description string }{
+	{IssueDuplicateID, "Duplicate IDs", "Groups, reward tables, chapters, quests, tasks, rewards and quest links that share an id; FTB Quests only loads one of them. The later one can be given a new id."},
+	{IssueDanglingDep, "Dangling dependencies", "Dependencies on quests that don't exist, which can leave a quest locked forever."},
+	{IssueUnreachable, "Unreachable quests", "Quests that can never be started: they are in a dependency cycle, or wait on quests that don't exist or can't be started either. Restructures often leave these behind."},
+	{IssueNoEntry, "Chapters without an entry point", "Chapters with quests none of which can ever be started."},
+	{IssueMissingTitle, "Missing titles", "Quests with no title and no item task to take one from."},
+	{IssueUnbalanced, "Unbalanced color codes", "Codes that style no text, and § signs that don't start a code."},
+	{IssueEmptyChapter, "Empty chapters", "Chapters without any quests."},
+	{IssueInvalidItem, "Invalid item IDs", "Item tasks and rewards whose item isn't a namespace:path id."},
+	{IssueMisspelled, "Misspelled keys", "Keys that known-good chapters don't use but are close to one they do, eg. dependancies; FTB Quests silently ignores them."},
+	{IssueMissingTable, "Missing reward tables", "Loot, random and choice rewards whose reward table isn't in quests/reward_tables; they give nothing."},
+}
+
+// validItemID matches resource locations, eg. minecraft:oak_log.
+var validItemID = regexp.MustCompile(`^[a-z0-9_.-]+:[a-z0-9_./-]+$`)
+
+// BookIssue is a problem found in the book. Quest is nil for chapter issues,
+// and Chapter too for issues with groups and reward tables.
+type BookIssue struct {
+	Kind    string
+	Chapter *Chapter
+	Quest   *Quest
+	Message string
+	// File is the file a duplicate id is in, relative to the quests
+	// directory, and ID the id; see dupids.go
+	File string
+	ID   string
+}
+
+// FixURL is the page the issue can be fixed on.
+func (is BookIssue) FixURL() string {
+	switch {
+	case is.Quest != nil:
+		return "/chapter/" + is.Chapter.Name + "/" + is.Quest.ID
+	case is.Chapter != nil:
+		return "/chapter/" + is.Chapter.Name
+	case strings.HasPrefix(is.File, "reward_tables/"):
+		return "/tables/" + strings.TrimSuffix(path.Base(is.File), ".snbt")
+	}
+	return "/chapters/order"
+}
+
+// validateBook returns the issues in qb, grouped by kind in the order of
+// issueKinds and in chapter order within a kind. Where the lint page checks
+// the style of the book's text, the issues page checks its structure: ids
+// that are used twice, dependencies on quests that don't exist, quests that
+// can never be started and chapters without any that can (see
+// unreachableQuests), quests nothing can name, codes that style no text,
+// chapters without quests, item ids the game can't resolve, misspelled keys
+// (see keycheck.go) and rewards rolling from reward tables that don't exist.
+// Each issue links to the page where it can be fixed.
+func validateBook(qb *QuestBook) []BookIssue {
+	byKind := make(map[string][]BookIssue)
+	add := func(kind string, ch *Chapter, q *Quest, format string, args ...any) {
+		byKind[kind] = append(byKind[kind], BookIssue{Kind: kind, Chapter: ch, Quest: q, Message: fmt.Sprintf(format, args...)})
+	}
+
+	byKind[IssueDuplicateID] = duplicateIDs(qb)
+
+	for _, ch := range qb.Chapters {
+		if len(ch.Quests) == 0 {
+			add(IssueEmptyChapter, ch, nil, "chapter has no quests")
+		}
+		misspelledKeys(ch, func(q *Quest, msg string) {
+			add(IssueMisspelled, ch, q, "%s", msg)
+		})
+		for _, q := range ch.Quests {
+			for _, t := range q.Tasks {
+				if it, ok := t.(*ItemTask); ok && !validItemID.MatchString(it.Item) {
+					add(IssueInvalidItem, ch, q, "item task %s has item %q", it.ID, it.Item)
+				}
+			}
+			for _, r := range q.Rewards {
+				if it, ok := r.(*ItemReward); ok && !validItemID.MatchString(it.Item) {
+					add(IssueInvalidItem, ch, q, "item reward %s has item %q", it.ID, it.Item)
+				}
+				if lr, ok := r.(*LootReward); ok && qb.RewardTable(r) == nil {
+					add(IssueMissingTable, ch, q, "%s reward %s rolls from unknown reward table %s", lr.Type, lr.ID, lr.TableID)
+				}
+			}
+			for _, dep := range q.Dependencies {
+				if _, ok := qb.questMap[dep]; !ok {
+					add(IssueDanglingDep, ch, q, "depends on unknown quest %s", dep)
+				}
+			}
+			if q.GetTitle() == "" {
+				add(IssueMissingTitle, ch, q, "quest %s has no title", q.ID)
+			}
+			for _, f := range searchFields {
+				text := questField(q, f)
+				for i, line := range strings.Split(text, "\n") {
+					if msg := unbalancedCodes(line); msg != "" {
+						where := f
+						if f == "description" {
+							where = fmt.Sprintf("description line %d", i+1)
+						}
+						add(IssueUnbalanced, ch, q, "%s: %s", where, msg)
+					}
+				}
+			}
+		}
+	}
+
+	unreachable := unreachableQuests(qb)
+	for _, ch := range qb.Chapters {
+		blocked := 0
+		for _, q := range ch.Quests {
+			if reason, ok := unreachable[q.ID]; ok {
+				add(IssueUnreachable, ch, q, "%s", reason)
+				blocked++
+			}
+		}
+		if blocked > 0 && blocked == len(ch.Quests) {
+			add(IssueNoEntry, ch, nil, "none of the chapter's %d quests can be started", blocked)
+		}
+	}
+
+	var issues []BookIssue
+	for _, k := range issueKinds {
+		issues = append(issues, byKind[k.Kind]...)
+	}
+	return issues
+}
+
+// unbalancedCodes describes the codes in line that style no text, or returns
+// "" if there are none: codes that end the line (see stripTrailingCodes) and
+// signs that don't start a code (see stripOrphanSigns). Lines holding JSON
+// text components are skipped.
+func unbalancedCodes(line string) string {
+	if t := strings.TrimSpace(line); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+		return ""
+	}
+	if stripOrphanSigns(line) != line {
+		return "a & or § doesn't start a code"
+	}
+	if stripTrailingCodes(line) != line {
+		rs := []rune(line)
+		return fmt.Sprintf("%q at the end styles no text", strings.TrimSpace(string(rs[trailingCodes(rs):])))
+	}
+	return ""
+}
+
+// issues handles GET "/issues", the book's structural problems grouped by
+// kind.
+func (a *App) issues(w http.ResponseWriter, r *http.Request) {
+	issues := validateBook(a.QB())
+	type group struct {
+		Kind, Title, Description string
+		Issues                   []BookIssue
+	}
+	var groups []group
+	for _, k := range issueKinds {
+		g := group{Kind: k.Kind, Title: k.Title, Description: k.Description}
+		for _, is := range issues {
+			if is.Kind == k.Kind {
+				g.Issues = append(g.Issues, is)
+			}
+		}
+		groups = append(groups, g)
+	}
+	data := a.baseData(r, "Issues")
+	data["Msg"] = r.URL.Query().Get("msg")
+	data["Groups"] = groups
+	data["Total"] = len(issues)
+	a.render(w, "issues.gohtml", data)
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateBook(t *testing.T) {
+	root := t.TempDir()
+	chapters := filepath.Join(root, "quests", "chapters")
+	if err := os.MkdirAll(chapters, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"../chapter_groups.snbt": "{ chapter_groups: [ ] }",
+		"a.snbt": `{ id: "00000000000000A0", title: "A", quests: [
+			{ id: "00000000000000A1", title: "&6Gold&r", tasks: [{ id: "00000000000000A2", type: "item", item: "minecraft:gold_ingot" }] }
+			{ id: "00000000000000A3", dependencies: ["00000000000000FF"], tasks: [{ id: "00000000000000A2", type: "item", item: "Gold Ingot" }] }
+			{ id: "00000000000000A4", description: ["fine", "&6trailing &l", "§z"] }
+		] }`,
+		"b.snbt": `{ id: "00000000000000B0", title: "B", quests: [ ] }`,
+	}
+	for name, s := range files {
+		if err := os.WriteFile(filepath.Join(chapters, name), []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	qb, err := NewQuestBook(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := make(map[string]int)
+	for _, is := range validateBook(qb) {
+		count[is.Kind]++
+	}
+	want := map[string]int{
+		IssueDuplicateID:  1,
+		IssueDanglingDep:  1,
+		IssueUnreachable:  1,
+		IssueMissingTitle: 1,
+		IssueUnbalanced:   2,
+		IssueEmptyChapter: 1,
+		IssueInvalidItem:  1,
+	}
+	for kind, n := range want {
+		if count[kind] != n {
+			t.Errorf("%s: got %d issues, want %d", kind, count[kind], n)
+		}
+	}
+}
+
+func TestUnreachableQuests(t *testing.T) {
+	root := t.TempDir()
+	chapters := filepath.Join(root, "quests", "chapters")
+	if err := os.MkdirAll(chapters, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"../chapter_groups.snbt": "{ chapter_groups: [ ] }",
+		// C2 and C3 wait on each other; C4 needs one of C1 and C3, C5 all
+		"c.snbt": `{ id: "00000000000000C0", title: "C", quests: [
+			{ id: "00000000000000C1", title: "Start" }
+			{ id: "00000000000000C2", title: "Loop", dependencies: ["00000000000000C1", "00000000000000C3"] }
+			{ id: "00000000000000C3", title: "Back", dependencies: ["00000000000000C2"] }
+			{ id: "00000000000000C4", dependencies: ["00000000000000C1", "00000000000000C3"], dependency_requirement: "one_completed" }
+			{ id: "00000000000000C5", dependencies: ["00000000000000C1", "00000000000000C3"], min_required_dependencies: 1 }
+			{ id: "00000000000000C6", title: "After", dependencies: ["00000000000000C4", "00000000000000C3"] }
+		] }`,
+		"d.snbt": `{ id: "00000000000000D0", title: "D", quests: [
+			{ id: "00000000000000D1", dependencies: ["00000000000000C6"] }
+		] }`,
+	}
+	for name, s := range files {
+		if err := os.WriteFile(filepath.Join(chapters, name), []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	qb, err := NewQuestBook(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := unreachableQuests(qb)
+	want := map[string]string{
+		"00000000000000C2": "is in a dependency cycle: Loop → Back → Loop",
+		"00000000000000C3": "is in a dependency cycle: Back → Loop → Back",
+		"00000000000000C6": "depends on unreachable quest Back",
+		"00000000000000D1": "depends on unreachable quest After",
+	}
+	if len(got) != len(want) {
+		t.Errorf("unreachable = %q", got)
+	}
+	for id, reason := range want {
+		if got[id] != reason {
+			t.Errorf("%s: got %q, want %q", id, got[id], reason)
+		}
+	}
+	var noEntry []string
+	for _, is := range validateBook(qb) {
+		if is.Kind == IssueNoEntry {
+			noEntry = append(noEntry, is.Chapter.Name)
+		}
+	}
+	if len(noEntry) != 1 || noEntry[0] != "d" {
+		t.Errorf("chapters without an entry point: %q", noEntry)
+	}
+}
+
+func TestUnbalancedCodes(t *testing.T) {
+	cases := map[string]bool{
+		"&6Gold":        false,
+		"&6Gold&r":      false,
+		"&6Gold &r&l":   true,
+		"Gold &6 ":      true,
+		"§zoops":        true,
+		"R & D":         false,
+		`{"text":"&6"}`: false,
+	}
+	for line, want := range cases {
+		if got := unbalancedCodes(line) != ""; got != want {
+			t.Errorf("unbalancedCodes(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
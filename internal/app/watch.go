@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long to wait for a burst of changes (the game saves
+// every chapter at once) to settle before reloading.
+const watchDebounce = 300 * time.Millisecond
+
+// ownWrites remembers the content hash of files qbedit wrote itself, so the
+// watcher doesn't treat our own saves as external edits.
+var ownWrites sync.Map // path -> [sha256.Size]byte
+
+func recordWrite(path string, b []byte) {
+	ownWrites.Store(path, sha256.Sum256(b))
+}
+
+// recordRemove notes that qbedit removed the file at path.
+func recordRemove(path string) {
+	ownWrites.Store(path, [sha256.Size]byte{})
+}
+
+// isOwnWrite returns true if the file at path is the content qbedit last
+// wrote there, or if qbedit removed it and it is still gone.
+func isOwnWrite(path string) bool {
+	v, ok := ownWrites.Load(path)
+	if !ok {
+		return false
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return v.([sha256.Size]byte) == [sha256.Size]byte{}
+	}
+	if err != nil {
+		return false
+	}
+	return v.([sha256.Size]byte) == sha256.Sum256(b)
+}
+
+// Watch reloads the quest book when files under its quests directory are
+// changed by another program, eg. the in-game editor or a text editor, until
+// ctx is cancelled. Open pages are told over server-sent events so they can
+// refresh.
+func (a *App) Watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	quests := filepath.Join(a.Root(), "quests")
+	for _, dir := range []string{quests, filepath.Join(quests, "chapters")} {
+		if err := w.Add(dir); err != nil {
+			return fmt.Errorf("watch %s: %w", dir, err)
+		}
+	}
+	// most books have no reward tables
+	tables := tablesDir(a.Root())
+	if fi, err := os.Stat(tables); err == nil && fi.IsDir() {
+		if err := w.Add(tables); err != nil {
+			return fmt.Errorf("watch %s: %w", tables, err)
+		}
+	}
+
+	var (
+		timer   *time.Timer
+		fire    <-chan time.Time
+		changed = make(map[string]bool)
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("watch", "error", err)
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if !strings.HasSuffix(ev.Name, ".snbt") || ev.Op == fsnotify.Chmod {
+				continue
+			}
+			changed[ev.Name] = true
+			if timer == nil {
+				timer = time.NewTimer(watchDebounce)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+			fire = timer.C
+		case <-fire:
+			fire = nil
+			var external []string
+			for path := range changed {
+				if !isOwnWrite(path) {
+					external = append(external, filepath.Base(path))
+				}
+			}
+			clear(changed)
+			if len(external) == 0 {
+				continue
+			}
+			sort.Strings(external)
+			slog.Info("reloading quest book after external change", "files", external)
+			a.reload()
+			a.events.publish("reload", strings.Join(external, " "))
+		}
+	}
+}
+
+// eventHub fans server-sent events out to every connected page.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan string]struct{}
+}
+
+func (h *eventHub) subscribe() chan string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs == nil {
+		h.subs = make(map[chan string]struct{})
+	}
+	ch := make(chan string, 4)
+	h.subs[ch] = struct{}{}
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, ch)
+}
+
+// publish sends an event to all subscribers. Slow subscribers miss events
+// rather than blocking the watcher.
+func (h *eventHub) publish(event, data string) {
+	msg := fmt.Sprintf("event: %s\ndata: %s\n\n", event, data)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// eventStream handles GET "/events", a server-sent event stream that announces
+// reloads caused by external edits.
+func (a *App) eventStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := a.events.subscribe()
+	defer a.events.unsubscribe(ch)
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-ch:
+			fmt.Fprint(w, msg)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// BookDir is a quest book found by FindBooks.
+type BookDir struct {
+	Name string
+	Root string
+}
+
+// isBookDir reports whether dir is an ftbquests dir.
+func isBookDir(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, "quests"))
+	return err == nil && fi.IsDir()
+}
+
+// bookName derives a book's name from its ftbquests dir: the dir's own name,
+// or the instance's for the usual <instance>/config/ftbquests.
+func bookName(root string) string {
+	dir := root
+	if filepath.Base(dir) == "ftbquests" && filepath.Base(filepath.Dir(dir)) == "config" {
+		dir = filepath.Dir(filepath.Dir(dir))
+	}
+	if name := chapterName(filepath.Base(dir)); name != "" {
+		return name
+	}
+	return "book"
+}
+
+// FindBooks returns the quest books in paths. A path is a book if it is an
+// ftbquests dir; otherwise the books are looked for in its subdirectories,
+// either directly or in their config/ftbquests as in a modpack instance.
+// Books are named after their directories, with a number added to repeated
+// names.
+func FindBooks(paths []string) ([]BookDir, error) {
+	var roots []string
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		if isBookDir(abs) {
+			roots = append(roots, abs)
+			continue
+		}
+		entries, err := os.ReadDir(abs)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, e := range entries {
+			if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			for _, dir := range []string{filepath.Join(abs, e.Name()), filepath.Join(abs, e.Name(), "config", "ftbquests")} {
+				if isBookDir(dir) {
+					roots = append(roots, dir)
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s has no quest books: expected an ftbquests dir, or a directory of them", p)
+		}
+	}
+
+	var books []BookDir
+	taken := make(map[string]bool)
+	for _, root := range roots {
+		if slices.ContainsFunc(books, func(b BookDir) bool { return b.Root == root }) {
+			continue
+		}
+		name := bookName(root)
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s_%d", bookName(root), i)
+		}
+		taken[name] = true
+		books = append(books, BookDir{Name: name, Root: root})
+	}
+	return books, nil
+}
+
+// Workspace serves several quest books at once, eg. a pack's normal and
+// expert mode books, or every instance of a launcher. Each book is an App of
+// its own, served under /b/<name>/, and the sidebar switches between them;
+// pages link to their own book's paths through the "base" template func. It
+// holds the books it serves by name.
+type Workspace struct {
+	books map[string]*App
+	names []string
+}
+
+// NewWorkspace serves apps, in order, under their names, which must be set
+// and different.
+func NewWorkspace(apps []*App) (*Workspace, error) {
+	ws := &Workspace{books: make(map[string]*App)}
+	for _, a := range apps {
+		if a.Name == "" || strings.Contains(a.Name, "/") {
+			return nil, fmt.Errorf("invalid book name %q", a.Name)
+		}
+		if _, ok := ws.books[a.Name]; ok {
+			return nil, fmt.Errorf("two books are called %s", a.Name)
+		}
+		a.Base, a.workspace = "/b/"+a.Name, ws
+		ws.books[a.Name] = a
+		ws.names = append(ws.names, a.Name)
+	}
+	return ws, nil
+}
+
+// Names returns the names of the books, or nil for a nil workspace, ie. a
+// book served on its own.
+func (ws *Workspace) Names() []string {
+	if ws == nil {
+		return nil
+	}
+	return ws.names
+}
+
+// Book returns the book called name, or nil.
+func (ws *Workspace) Book(name string) *App { return ws.books[name] }
+
+// Router serves each book under its base, and sends "/" to the first.
+func (ws *Workspace) Router() http.Handler {
+	mux := http.NewServeMux()
+	for _, name := range ws.names {
+		a := ws.books[name]
+		mux.Handle(a.Base+"/", http.StripPrefix(a.Base, a.Router()))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" || len(ws.names) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, ws.books[ws.names[0]].Base+"/", http.StatusFound)
+	})
+	return mux
+}
+
+// ShareRouter serves each book's share links under its base, like Router;
+// see App.ShareRouter.
+func (ws *Workspace) ShareRouter() http.Handler {
+	mux := http.NewServeMux()
+	for _, name := range ws.names {
+		a := ws.books[name]
+		mux.Handle(a.Base+"/", http.StripPrefix(a.Base, a.ShareRouter()))
+	}
+	return mux
+}
+
+// prefixRedirects adds the book's base to redirects to its own paths, which
+// handlers write without it.
+func (a *App) prefixRedirects(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&prefixWriter{ResponseWriter: w, base: a.Base}, r)
+	})
+}
+
+// prefixWriter prefixes a Location header holding a path with base.
+type prefixWriter struct {
+	http.ResponseWriter
+	base string
+}
+
+func (w *prefixWriter) WriteHeader(code int) {
+	loc := w.Header().Get("Location")
+	if strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") && loc != w.base && !strings.HasPrefix(loc, w.base+"/") {
+		w.Header().Set("Location", w.base+loc)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush lets the event stream through.
+func (w *prefixWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap is for http.ResponseController.
+func (w *prefixWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindBooks(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+	for _, d := range []string{
+		"Pack One/config/ftbquests/quests",
+		"expert/quests",
+		"notes",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(other, "expert", "quests"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	books, err := FindBooks([]string{dir, filepath.Join(dir, "expert"), filepath.Join(other, "expert")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BookDir{
+		{"pack_one", filepath.Join(dir, "Pack One", "config", "ftbquests")},
+		{"expert", filepath.Join(dir, "expert")},
+		{"expert_2", filepath.Join(other, "expert")},
+	}
+	if len(books) != len(want) {
+		t.Fatalf("books = %v", books)
+	}
+	for i := range want {
+		if books[i] != want[i] {
+			t.Errorf("book %d = %v, want %v", i, books[i], want[i])
+		}
+	}
+	if _, err := FindBooks([]string{filepath.Join(dir, "notes")}); err == nil {
+		t.Error("a directory without books should be an error")
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	one, two := testApp(t), testApp(t)
+	one.Name, two.Name = "one", "two"
+	if _, err := NewWorkspace([]*App{one, two, {Name: "one"}}); err == nil {
+		t.Error("repeated names should be an error")
+	}
+	ws, err := NewWorkspace([]*App{one, two})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := ws.Router()
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(httptest.NewRequest("GET", "/", nil)); rec.Header().Get("Location") != "/b/one/" {
+		t.Errorf("/ redirects to %q", rec.Header().Get("Location"))
+	}
+	rec := serve(httptest.NewRequest("GET", "/b/two/chapter/test", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `href="/b/two/chapter/test`) || !strings.Contains(body, `<option value="/b/one/" >one</option>`) {
+		t.Errorf("chapter page: %d", rec.Code)
+	}
+	if strings.Contains(body, `href="/chapter/`) {
+		t.Error("the page links outside its book")
+	}
+	rec = serve(httptest.NewRequest("GET", "/b/two/", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `href="/b/two/orphans"`) || strings.Contains(body, `href="/orphans"`) {
+		t.Error("the index's links leave the book")
+	}
+	for _, path := range []string{"/chapter/test", "/b/three/", "/b/one"} {
+		if rec := serve(httptest.NewRequest("GET", path, nil)); rec.Code == http.StatusOK {
+			t.Errorf("%s: %d", path, rec.Code)
+		}
+	}
+
+	// edits go to the book they were made in, and redirect within it
+	req := httptest.NewRequest("POST", "/b/two/chapters/new", strings.NewReader(url.Values{"title": {"Second"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = serve(req)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/b/two/chapter/second" {
+		t.Fatalf("create: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if two.QB().chapterMap["second"] == nil || one.QB().chapterMap["second"] != nil {
+		t.Error("the chapter was created in the wrong book")
+	}
+}
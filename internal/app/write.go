@@ -0,0 +1,361 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/jmoiron/qbedit/snbt"
+)
+
+// writeSNBT encodes v and writes it to path.
+//
+// At debug verbosity (-vv) it also logs a unified diff of the change, so a
+// terminal running qbedit doubles as a change monitor. Encode writes files on
+// a single line, so both sides are normalized through EncodeIndent first to
+// keep the diff readable.
+func writeSNBT(path string, v any) error {
+	var buf bytes.Buffer
+	done := timeOp(opEncode)
+	err := snbt.Encode(&buf, v)
+	done()
+	if err != nil {
+		return err
+	}
+	if slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		logWriteDiff(path, v)
+	}
+	old, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := checkProtected(path, old, buf.Bytes()); err != nil {
+		return err
+	}
+	recordWrite(path, buf.Bytes())
+	return writeFile(path, buf.Bytes(), 0644)
+}
+
+// tmpSuffix ends the names of the temporary files writes go through, which
+// watchers and copies of the book skip.
+const tmpSuffix = ".qbedit-tmp"
+
+// writeTemp writes b to a new temporary file next to path and syncs it to
+// disk, returning its name. The file gets the permissions of the file at
+// path, or perm if there is none yet, so that renaming it into place doesn't
+// change them.
+func writeTemp(path string, b []byte, perm fs.FileMode) (string, error) {
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*"+tmpSuffix)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// writeFile replaces the file at path with b, like os.WriteFile, but through
+// a temporary file that is renamed into place: a crash or a full disk leaves
+// either the old file or the new one, never a truncated one. An existing
+// file keeps its permissions; a new one gets perm.
+func writeFile(path string, b []byte, perm fs.FileMode) error {
+	defer timeOp(opWrite)()
+	tmp, err := writeTemp(path, b, perm)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir syncs the directory dir so that renames in it are on disk. Not
+// every system can sync a directory, so errors are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// writeSNBTFiles writes several files as a unit; see bookWrite. Files are
+// encoded in parallel.
+func writeSNBTFiles(files map[string]any) error {
+	t := newBookWrite(nil)
+	errs := make(chan error, len(files))
+	var wg sync.WaitGroup
+	for path, v := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- t.stageSNBT(path, v)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return t.commit()
+}
+
+// editChapters opens the chapters called names, with their text resolved
+// from qb's lang file, and calls edit on each in name order. The chapters
+// edit changes are written together, so an error leaves all of them as they
+// were. edit returns the ids of the quests it changed, or an empty but
+// non-nil slice if it changed only the chapter itself; nil means nothing
+// changed. The ids are returned sorted, by chapter.
+func editChapters(qb *QuestBook, names []string, edit func(ch *Chapter) ([]string, error)) (map[string][]string, error) {
+	edited := make(map[string][]string)
+	t := newBookWrite(qb.Lang)
+	for _, name := range slices.Sorted(slices.Values(names)) {
+		path := qb.chapterPath(name)
+		ch, err := NewChapterFromPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("open chapter %s: %w", name, err)
+		}
+		ch.resolveLang(qb.Lang)
+		ids, err := edit(ch)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if ids == nil {
+			continue
+		}
+		if err := t.stageChapter(ch, path); err != nil {
+			return nil, fmt.Errorf("saving chapter %s: %w", name, err)
+		}
+		slices.Sort(ids)
+		edited[name] = slices.Compact(ids)
+	}
+	if len(edited) == 0 {
+		return edited, nil
+	}
+	if err := t.commit(); err != nil {
+		return nil, fmt.Errorf("saving chapters: %w", err)
+	}
+	return edited, nil
+}
+
+// A bookWrite is a change to several files of the book that is written as a
+// unit. Bulk operations stage the new contents of every file they touch, each
+// of which is checked to decode again, and nothing is written until commit.
+// Staging is safe from several goroutines.
+//
+// Commit writes every file to a temporary file next to its destination
+// before renaming them into place one by one, in path order. If a rename
+// fails, the files already replaced are restored, so an error part way
+// through doesn't leave the book half updated. Nothing is written if a
+// file's change touches protected content; see protect.go.
+type bookWrite struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	// lang is a copy of the book's lang file that staged chapters write
+	// their keyed text to; it is written with them if it changed.
+	lang        *LangFile
+	langChanged bool
+}
+
+// commitMu keeps commits from interleaving.
+var commitMu sync.Mutex
+
+// renameFile is os.Rename; tests replace it to make commits fail.
+var renameFile = os.Rename
+
+// newBookWrite returns an empty write. Chapters staged with stageChapter
+// write their keyed text to a copy of lang, which may be nil.
+func newBookWrite(lang *LangFile) *bookWrite {
+	return &bookWrite{files: make(map[string][]byte), lang: lang.clone()}
+}
+
+// stage sets the new contents of the file at path.
+func (t *bookWrite) stage(path string, b []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files[path] = b
+}
+
+// stageSNBT encodes v as the new contents of the file at path.
+func (t *bookWrite) stageSNBT(path string, v any) error {
+	defer timeOp(opEncode)()
+	var buf bytes.Buffer
+	if err := snbt.Encode(&buf, v); err != nil {
+		return fmt.Errorf("encoding %s: %w", path, err)
+	}
+	if _, err := snbt.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		return fmt.Errorf("encoding %s: result doesn't decode: %w", path, err)
+	}
+	if slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		logWriteDiff(path, v)
+	}
+	t.stage(path, buf.Bytes())
+	return nil
+}
+
+// stageChapter stages ch to be written to path, as saveChapter writes it.
+func (t *bookWrite) stageChapter(ch *Chapter, path string) error {
+	t.mu.Lock()
+	changed := false
+	if ch.titleKey != "" {
+		changed = t.lang.Set(ch.titleKey, ch.Title)
+	}
+	for _, q := range ch.Quests {
+		c, err := q.unresolveLang(t.lang)
+		if err != nil {
+			t.mu.Unlock()
+			return err
+		}
+		changed = changed || c
+	}
+	t.langChanged = t.langChanged || changed
+	t.mu.Unlock()
+	ch.Sync()
+	return t.stageSNBT(path, ch.raw)
+}
+
+// stageLang stages l to be written to its path.
+func (t *bookWrite) stageLang(l *LangFile) {
+	t.stage(l.Path, l.encode())
+}
+
+// Len returns how many files are staged.
+func (t *bookWrite) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := len(t.files)
+	if t.langChanged {
+		n++
+	}
+	return n
+}
+
+// commit writes the staged files.
+func (t *bookWrite) commit() error {
+	if t.langChanged {
+		t.stageLang(t.lang)
+		t.langChanged = false
+	}
+	commitMu.Lock()
+	defer commitMu.Unlock()
+	defer timeOp(opWrite)()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	paths := slices.Sorted(maps.Keys(t.files))
+	temps := make(map[string]string, len(paths))
+	cleanup := func() {
+		for _, tmp := range temps {
+			os.Remove(tmp)
+		}
+	}
+	// originals are kept to restore if a later file can't be written; nil
+	// means the file is new
+	originals := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			cleanup()
+			return err
+		}
+		originals[path] = b
+		if err := checkProtected(path, b, t.files[path]); err != nil {
+			cleanup()
+			return err
+		}
+		tmp, err := writeTemp(path, t.files[path], 0644)
+		if err != nil {
+			cleanup()
+			return err
+		}
+		temps[path] = tmp
+	}
+	for i, path := range paths {
+		recordWrite(path, t.files[path])
+		if err := renameFile(temps[path], path); err != nil {
+			cleanup()
+			rerr := rollback(paths[:i], originals)
+			return errors.Join(fmt.Errorf("writing %s: %w; %d earlier files restored", path, err, i), rerr)
+		}
+		delete(temps, path)
+	}
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		if dir := filepath.Dir(path); !dirs[dir] {
+			dirs[dir] = true
+			syncDir(dir)
+		}
+	}
+	return nil
+}
+
+// rollback restores the files at paths to their original contents, removing
+// those that didn't exist.
+func rollback(paths []string, originals map[string][]byte) error {
+	var errs []error
+	for _, path := range slices.Backward(paths) {
+		b := originals[path]
+		if b == nil {
+			recordRemove(path)
+			errs = append(errs, os.Remove(path))
+			continue
+		}
+		recordWrite(path, b)
+		errs = append(errs, writeFile(path, b, 0644))
+	}
+	return errors.Join(errs...)
+}
+
+// logWriteDiff logs the difference between the file at path and v.
+func logWriteDiff(path string, v any) {
+	var old, cur bytes.Buffer
+	if f, err := os.Open(path); err == nil {
+		ov, err := snbt.Decode(f)
+		f.Close()
+		if err != nil {
+			slog.Debug("write diff: decoding old file", "path", path, "error", err)
+			return
+		}
+		if err := snbt.EncodeIndent(&old, ov); err != nil {
+			slog.Debug("write diff: encoding old file", "path", path, "error", err)
+			return
+		}
+	}
+	if err := snbt.EncodeIndent(&cur, v); err != nil {
+		slog.Debug("write diff: encoding", "path", path, "error", err)
+		return
+	}
+	d := unifiedDiff(path, path, old.String(), cur.String(), 3)
+	if d == "" {
+		slog.Debug(fmt.Sprintf("write %s (unchanged)", path))
+		return
+	}
+	slog.Debug(fmt.Sprintf("write %s\n%s", path, d))
+}
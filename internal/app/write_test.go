@@ -0,0 +1,147 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBookWriteRollback(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name+".snbt") }
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(path(name), []byte(`{ name: "`+name+`" }`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		b, err := os.ReadFile(path(name))
+		if err != nil {
+			return err.Error()
+		}
+		return string(b)
+	}
+	files := map[string]any{}
+	for _, name := range []string{"a", "b", "c", "new"} {
+		files[path(name)] = map[string]any{"name": name + "2"}
+	}
+
+	// the third rename fails; the first two files are put back
+	renames := 0
+	renameFile = func(from, to string) error {
+		if renames++; renames == 3 {
+			return errors.New("disk full")
+		}
+		return os.Rename(from, to)
+	}
+	defer func() { renameFile = os.Rename }()
+	err := writeSNBTFiles(files)
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("writeSNBTFiles = %v", err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if got := read(name); got != `{ name: "`+name+`" }` {
+			t.Errorf("%s = %s after rollback", name, got)
+		}
+	}
+	if _, err := os.Stat(path("new")); !os.IsNotExist(err) {
+		t.Errorf("new file left behind: %v", err)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "*.qbedit-tmp")); len(left) > 0 {
+		t.Errorf("temporary files left: %v", left)
+	}
+
+	renameFile = os.Rename
+	if err := writeSNBTFiles(files); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "new"} {
+		if got := read(name); got != `{ name: "`+name+`2" }` {
+			t.Errorf("%s = %s", name, got)
+		}
+	}
+}
+
+func TestWriteFileKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.snbt")
+	if err := os.WriteFile(path, []byte(`{ }`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSNBT(path, map[string]any{"name": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSNBTFiles(map[string]any{path: map[string]any{"name": "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v after writing", fi.Mode().Perm())
+	}
+
+	if err := writeFile(filepath.Join(dir, "new.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "new.json")); err != nil || fi.Mode().Perm() != 0644 {
+		t.Errorf("new file: %v %v", fi, err)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "*"+tmpSuffix)); len(left) > 0 {
+		t.Errorf("temporary files left: %v", left)
+	}
+}
+
+func TestEditChapters(t *testing.T) {
+	a := chapterApp(t)
+	qb := a.QB()
+	read := func() map[string]string {
+		files := make(map[string]string)
+		for _, c := range qb.Chapters {
+			b, _ := os.ReadFile(qb.chapterPath(c.Name))
+			files[c.Name] = string(b)
+		}
+		return files
+	}
+	before := read()
+
+	// an error part way through writes nothing
+	_, err := editChapters(qb, []string{"alpha", "beta"}, func(ch *Chapter) ([]string, error) {
+		if ch.Name == "beta" {
+			return nil, errors.New("failed")
+		}
+		ch.Title = "Changed"
+		return []string{}, nil
+	})
+	if err == nil || err.Error() != "beta: failed" {
+		t.Errorf("editChapters = %v", err)
+	}
+	if after := read(); after["alpha"] != before["alpha"] {
+		t.Error("alpha was written despite the error")
+	}
+
+	// only the chapters edit reports changing are written
+	edited, err := editChapters(qb, []string{"gamma", "alpha", "beta"}, func(ch *Chapter) ([]string, error) {
+		switch ch.Name {
+		case "alpha":
+			ch.raw["title"] = "Changed"
+			return []string{}, nil
+		case "gamma":
+			ch.raw["title"] = "Unwritten"
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edited) != 1 || edited["alpha"] == nil {
+		t.Errorf("edited = %v", edited)
+	}
+	after := read()
+	if after["alpha"] == before["alpha"] || after["beta"] != before["beta"] || after["gamma"] != before["gamma"] {
+		t.Error("wrote the wrong chapters")
+	}
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"net/http"
 
 	"github.com/jmoiron/qbedit/internal/app"
+	"github.com/jmoiron/qbedit/internal/app/mdns"
 	flag "github.com/spf13/pflag"
 )
 
@@ -22,16 +31,66 @@ func main() {
 		showVersion bool
 		verbose     int
 		quit        bool
+		compare     string
+		progress    string
+		lang        string
+		langDir     string
+		shareSecret string
+		shareAddr   string
+		prefsPath   string
+		auditPath   string
+		langFile    string
+		useGit      bool
+		watch       bool
+		assets      string
+		items       string
+		dicts       []string
+		open        bool
+		advertise   string
+		backupEvery time.Duration
+		backupDir   string
+		backupKeep  int
+		reference   string
+		slow        time.Duration
 	)
 
-	flag.StringVar(&listen, "addr", "0.0.0.0:8222", "listen address for the web UI (host:port)")
+	flag.StringVar(&listen, "addr", "0.0.0.0:8222", "listen address for the web UI (host:port); port 0 picks a free port")
+	flag.BoolVar(&open, "open", false, "open the web UI in the default browser once it is ready")
+	flag.StringVar(&advertise, "mdns", "", "advertise the web UI on the LAN with mDNS under this name, so it shows up in service browsers; --mdns alone uses \"qbedit on <host>\"")
+	flag.Lookup("mdns").NoOptDefVal = "-"
 	flag.StringVar(&mcVersion, "mcv", "1.20.1", "Minecraft version (e.g., 1.20.1)")
 	flag.BoolVar(&showVersion, "version", false, "print version and exit")
-	flag.CountVarP(&verbose, "verbose", "v", "increase verbosity; repeat for more detail")
+	flag.CountVarP(&verbose, "verbose", "v", "increase verbosity; repeat for more detail (-vv logs diffs of written files)")
+	flag.StringVar(&compare, "compare", "", "second ftbquests dir (eg. an expert mode book) for the compare page")
+	flag.StringVar(&progress, "progress", "", "directory of team progress files (eg. <world>/ftbquests) for the progress page; found automatically in the instance's worlds")
+	flag.StringVar(&lang, "lang", "", "default UI language when the browser's isn't available (eg. de)")
+	flag.StringVar(&langDir, "translations", "", "directory of <lang>.json UI translations to load")
+	flag.StringVar(&shareSecret, "share-secret", os.Getenv("QBEDIT_SHARE_SECRET"), "secret for signing quest share links, so they survive restarts (default $QBEDIT_SHARE_SECRET)")
+	flag.StringVar(&shareAddr, "share-addr", "", "listen address (host:port) serving read-only quest share links and nothing else; quests can't be shared without it")
+	flag.StringVar(&prefsPath, "prefs", app.DefaultPrefsPath(), "file for per-browser preferences such as starred quests")
+	flag.StringVar(&auditPath, "audit", app.DefaultAuditPath(), "file to record edits in, for the activity page")
+	flag.StringVar(&langFile, "lang-file", "", "lang file (eg. kubejs/assets/<pack>/lang/en_us.json) for quest text written as {translation.keys}; found automatically next to the ftbquests dir")
+	flag.BoolVar(&useGit, "git", false, "commit every edit to the git repository the ftbquests dir is in")
+	flag.BoolVar(&watch, "watch", true, "reload when quest files are changed by another program")
+	flag.StringVar(&assets, "assets", "", "directory of resource packs and mod jars (eg. the instance's mods dir) to show item icons from")
+	flag.StringVar(&items, "items", "", "JSON list of item ids (eg. a registry dump) to suggest and check item ids against; taken from --assets if not given")
+	flag.StringSliceVar(&dicts, "dict", nil, "word lists, one word per line, for the spelling page to check quest text against; the system's word list if not given")
+	flag.DurationVar(&backupEvery, "backup-interval", 0, "copy the quests dir to the backup area and check it this often (eg. 1h), for long running hosted instances; 0 disables backups")
+	flag.StringVar(&backupDir, "backup-dir", "", "backup area for --backup-interval (default .qbedit/backups in the ftbquests dir)")
+	flag.IntVar(&backupKeep, "backup-keep", 48, "how many backups to keep; 0 keeps them all")
+	flag.StringVar(&reference, "reference", "", "known-good chapter file or ftbquests dir whose keys the issues page accepts, besides the shipped samples, when it looks for misspelled keys")
+	flag.DurationVar(&slow, "slow", time.Second, "log requests slower than this with the time spent parsing, searching, encoding, writing and rendering; 0 logs none")
 	flag.BoolVarP(&quit, "quit", "q", false, "initialize (load templates, parse chapters), then exit without serving")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "usage: qbedit [options] <ftbquests-dir>\n\n")
+		fmt.Fprintf(os.Stderr, "usage: qbedit [options] <ftbquests-dir>...\n")
+		fmt.Fprintf(os.Stderr, "       qbedit [options] check <ftbquests-dir>...\n")
+		fmt.Fprintf(os.Stderr, "       qbedit [options] eval <ftbquests-dir> <query>\n\n")
+		fmt.Fprintf(os.Stderr, "Several ftbquests dirs, or a directory of them or of modpack instances, are\n")
+		fmt.Fprintf(os.Stderr, "served as a workspace with a book switcher, each book under /b/<name>/.\n\n")
+		fmt.Fprintf(os.Stderr, "check prints the books' lint issues and uses of blocked terms instead of\n")
+		fmt.Fprintf(os.Stderr, "serving them, and exits with status 1 if it finds any. eval prints the result\n")
+		fmt.Fprintf(os.Stderr, "of a console query, eg. \"count(quests[?repeatable])\", as JSON.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
@@ -43,22 +102,49 @@ func main() {
 		return
 	}
 
-	if flag.NArg() != 1 {
+	args := flag.Args()
+	check := len(args) > 1 && args[0] == "check"
+	if check {
+		args = args[1:]
+	}
+	var query string
+	eval := len(args) > 0 && args[0] == "eval"
+	if eval {
+		if len(args) != 3 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		args, query = args[1:2], args[2]
+	}
+	if len(args) < 1 {
 		flag.Usage()
 		os.Exit(2)
 	}
 
-	root := flag.Arg(0)
-	abs, err := filepath.Abs(root)
-	if err != nil {
-		log.Fatalf("resolve dir: %v", err)
+	for _, dir := range args {
+		info, err := os.Stat(dir)
+		if err != nil {
+			log.Fatalf("invalid directory: %v", err)
+		}
+		if !info.IsDir() {
+			log.Fatalf("not a directory: %s", dir)
+		}
 	}
-	info, err := os.Stat(abs)
+	books, err := app.FindBooks(args)
 	if err != nil {
-		log.Fatalf("invalid directory: %v", err)
+		if len(args) > 1 {
+			log.Fatalf("find quest books: %v", err)
+		}
+		// a single dir is served as the book even before it has quests
+		abs, err := filepath.Abs(args[0])
+		if err != nil {
+			log.Fatalf("resolve dir: %v", err)
+		}
+		books = []app.BookDir{{Root: abs}}
 	}
-	if !info.IsDir() {
-		log.Fatalf("not a directory: %s", abs)
+	workspace := len(books) > 1
+	if workspace && langFile != "" {
+		log.Fatalf("--lang-file needs a single quest book; each book of a workspace uses the lang file found next to it")
 	}
 
 	debugf := func(format string, args ...any) {
@@ -67,26 +153,270 @@ func main() {
 		}
 	}
 
+	// -vv enables debug logging, which includes a diff of every file written
+	if verbose > 1 {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	}
+
 	debugf("verbosity: %d", verbose)
-	fmt.Printf("qbedit %s\n", version)
+	// eval's output is only the result, for piping into other tools
+	if !eval {
+		fmt.Printf("qbedit %s\n", version)
+	}
 
-	// Start app server
-	a, err := app.New(abs, mcVersion, verbose)
+	// settings shared by every book
+	prefs, err := app.OpenPrefs(prefsPath)
 	if err != nil {
-		log.Fatalf("init: %v", err)
+		log.Fatalf("load preferences: %v", err)
+	}
+	var icons *app.IconSet
+	var registry *app.ItemRegistry
+	if assets != "" {
+		if icons, err = app.OpenIcons(assets); err != nil {
+			log.Fatalf("load assets: %v", err)
+		}
+		debugf("assets: %d item icons", icons.Len())
+	}
+	if items != "" {
+		if registry, err = app.LoadItemRegistry(items); err != nil {
+			log.Fatalf("load items: %v", err)
+		}
+	} else if icons != nil {
+		registry = app.NewItemRegistry(icons.IDs())
+	}
+	if registry != nil {
+		debugf("items: %d known", registry.Len())
+	}
+	if len(dicts) == 0 {
+		dicts = app.SystemWordLists()
+	}
+	var dictionary *app.WordList
+	if len(dicts) > 0 {
+		if dictionary, err = app.LoadWordList(dicts...); err != nil {
+			log.Fatalf("load dictionary: %v", err)
+		}
+		debugf("dictionary: %d words", dictionary.Len())
+	}
+	if reference != "" {
+		if err := app.AddReference(reference); err != nil {
+			log.Fatalf("load reference chapters: %v", err)
+		}
+	}
+	if backupEvery > 0 && backupEvery < time.Minute {
+		log.Fatalf("--backup-interval %s is too short; use at least 1m", backupEvery)
+	}
+
+	var apps []*app.App
+	for _, b := range books {
+		a, err := app.New(b.Root, mcVersion, verbose)
+		if err != nil {
+			log.Fatalf("init %s: %v", b.Root, err)
+		}
+		a.Name = b.Name
+		if compare != "" {
+			if a.CompareRoot, err = filepath.Abs(compare); err != nil {
+				log.Fatalf("resolve compare dir: %v", err)
+			}
+		}
+		if progress != "" {
+			if a.ProgressDir, err = filepath.Abs(progress); err != nil {
+				log.Fatalf("resolve progress dir: %v", err)
+			}
+		}
+		if langDir != "" {
+			if err := a.Messages.LoadDir(langDir); err != nil {
+				log.Fatalf("load translations: %v", err)
+			}
+		}
+		if lang != "" {
+			a.Messages.Default = lang
+		}
+		if langFile != "" {
+			if err := a.SetLangFile(langFile); err != nil {
+				log.Fatalf("load lang file: %v", err)
+			}
+		}
+		a.SlowRequest = slow
+		a.Prefs = prefs
+		// each book of a workspace keeps its own activity
+		if workspace {
+			a.Audit = app.OpenAuditLog(strings.TrimSuffix(auditPath, ".jsonl") + "-" + b.Name + ".jsonl")
+		} else {
+			a.Audit = app.OpenAuditLog(auditPath)
+		}
+		if useGit {
+			if a.Git, err = app.OpenGitRepo(b.Root); err != nil {
+				log.Fatalf("git: %v", err)
+			}
+		}
+		if shareSecret != "" {
+			a.SetShareSecret(shareSecret)
+		}
+		a.Icons, a.Items = icons, registry
+		if dictionary != nil {
+			a.Dictionary = dictionary
+		}
+		qb := a.QB()
+		if workspace {
+			log.Printf("book %s: %d chapters in %s", b.Name, len(qb.Chapters), b.Root)
+		} else {
+			log.Printf("scan summary: %d parsed, %d failed", len(qb.Chapters), len(qb.Failures))
+		}
+		apps = append(apps, a)
+	}
+	if eval {
+		b, err := apps[0].Eval(query)
+		if err != nil {
+			log.Fatalf("eval: %v", err)
+		}
+		fmt.Printf("%s\n", b)
+		return
+	}
+	if check {
+		found := 0
+		for _, a := range apps {
+			out := io.Writer(os.Stdout)
+			if workspace {
+				out = &prefixWriter{w: os.Stdout, prefix: a.Name + ": "}
+			}
+			found += a.Check(out)
+		}
+		log.Printf("check: %d problems found", found)
+		if found > 0 {
+			os.Exit(1)
+		}
+		return
 	}
-	log.Printf("scan summary: %d parsed, %d failed", len(a.QB.Chapters), 0)
 	if quit {
-		log.Printf("initialized successfully; loaded %d chapters; quitting (--quit)", len(a.QB.Chapters))
+		chapters := 0
+		for _, a := range apps {
+			chapters += len(a.QB().Chapters)
+		}
+		log.Printf("initialized successfully; loaded %d chapters; quitting (--quit)", chapters)
 		return
 	}
-	log.Printf("listening on http://%s (mc %s)", listen, mcVersion)
-	if err := httpListenAndServe(listen, a.Router()); err != nil {
+	for _, a := range apps {
+		if backupEvery > 0 {
+			dir := backupDir
+			if dir == "" {
+				dir = app.DefaultBackupDir(a.Root())
+			} else if workspace {
+				dir = filepath.Join(dir, a.Name)
+			}
+			a.Backups = &app.Backups{Dir: dir, Keep: backupKeep, Interval: backupEvery}
+			go a.RunBackups(context.Background())
+		}
+		if watch {
+			go func() {
+				if err := a.Watch(context.Background()); err != nil {
+					log.Printf("watch %s: %v", a.Root(), err)
+				}
+			}()
+		}
+	}
+	handler, shareHandler := apps[0].Router(), apps[0].ShareRouter()
+	if workspace {
+		ws, err := app.NewWorkspace(apps)
+		if err != nil {
+			log.Fatalf("workspace: %v", err)
+		}
+		handler, shareHandler = ws.Router(), ws.ShareRouter()
+	}
+	if shareAddr != "" {
+		sl, err := net.Listen("tcp", shareAddr)
+		if err != nil {
+			log.Fatalf("share server: %v", err)
+		}
+		for _, a := range apps {
+			a.ShareAddr = sl.Addr().String()
+		}
+		log.Printf("serving share links on %s", sl.Addr())
+		go func() {
+			if err := httpServe(sl, shareHandler); err != nil {
+				log.Fatalf("share server: %v", err)
+			}
+		}()
+	}
+	l, err := net.Listen("tcp", listen)
+	if err != nil {
+		log.Fatalf("server: %v", err)
+	}
+	url := "http://" + browseAddr(l.Addr())
+	log.Printf("listening on %s (mc %s)", url, mcVersion)
+	if open {
+		if err := openBrowser(url); err != nil {
+			log.Printf("open browser: %v", err)
+		}
+	}
+	if advertise != "" {
+		advertiseLAN(advertise, l.Addr().(*net.TCPAddr))
+	}
+	if err := httpServe(l, handler); err != nil {
 		log.Fatalf("server: %v", err)
 	}
 }
 
-// httpListenAndServe exists to facilitate testing/mocking if desired.
-var httpListenAndServe = func(addr string, h http.Handler) error {
-	return http.ListenAndServe(addr, h)
+// advertiseLAN advertises the web UI listening at addr with mDNS under name,
+// or "qbedit on <host>" if name is "-", and withdraws it when interrupted.
+func advertiseLAN(name string, addr *net.TCPAddr) {
+	if addr.IP.IsLoopback() {
+		log.Printf("mdns: not advertising; listening on %s isn't reachable from the LAN", addr)
+		return
+	}
+	ips := mdns.LocalIPs(addr.IP)
+	if len(ips) == 0 {
+		log.Printf("mdns: not advertising; no IPv4 address to advertise")
+		return
+	}
+	if name == "-" {
+		host, _ := os.Hostname()
+		host, _, _ = strings.Cut(host, ".")
+		name = "qbedit on " + host
+	}
+	srv, err := mdns.Advertise(mdns.Service{
+		Instance: name,
+		Port:     addr.Port,
+		IPs:      ips,
+		Text:     []string{"path=/", "version=" + version},
+	})
+	if err != nil {
+		log.Printf("mdns: %v", err)
+		return
+	}
+	log.Printf("advertising %q on the LAN", name)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		srv.Close()
+		// exit the way the signal would have without the handler
+		signal.Stop(sig)
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			p.Signal(s)
+		}
+	}()
+}
+
+// prefixWriter writes lines to w with prefix before each.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	lines := strings.SplitAfter(string(b), "\n")
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		if _, err := io.WriteString(p.w, p.prefix+line); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// httpServe exists to facilitate testing/mocking if desired.
+var httpServe = func(l net.Listener, h http.Handler) error {
+	return http.Serve(l, h)
 }
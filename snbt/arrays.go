@@ -0,0 +1,41 @@
+package snbt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ByteArray is an SNBT byte array like "[B; 1b, 2b]".
+type ByteArray []int8
+
+// IntArray is an SNBT int array like "[I; 1, 2]".
+type IntArray []int32
+
+// LongArray is an SNBT long array like "[L; 1l, 2l]".
+type LongArray []int64
+
+func (a ByteArray) SNBT() string {
+	return encodeArray("B", len(a), func(i int) string { return strconv.FormatInt(int64(a[i]), 10) + "b" })
+}
+
+func (a IntArray) SNBT() string {
+	return encodeArray("I", len(a), func(i int) string { return strconv.FormatInt(int64(a[i]), 10) })
+}
+
+func (a LongArray) SNBT() string {
+	return encodeArray("L", len(a), func(i int) string { return strconv.FormatInt(a[i], 10) + "l" })
+}
+
+func encodeArray(kind string, n int, elem func(i int) string) string {
+	var b strings.Builder
+	b.WriteString("[" + kind + ";")
+	for i := range n {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte(' ')
+		b.WriteString(elem(i))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
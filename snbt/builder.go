@@ -9,6 +9,9 @@ import (
 type Builder struct {
 	stack []any
 	keys  []string
+	// errPos is one more than the rune position of the first value that
+	// parsed but isn't valid, eg. an array element out of range.
+	errPos int
 }
 
 // helper stack ops
@@ -30,7 +33,7 @@ func (b *Builder) peek() any {
 
 // Public helpers used from grammar actions
 func (b *Builder) BeginCompound()  { b.push(map[string]any{}) }
-func (b *Builder) SetKey(k string) { b.keys = append(b.keys, k) }
+func (b *Builder) SetKey(k string) { b.keys = append(b.keys, unescape(k)) }
 func (b *Builder) PairSet() {
 	v := b.pop()
 	top := b.peek()
@@ -55,13 +58,52 @@ func (b *Builder) ListAppend() {
 }
 
 func (b *Builder) PushString(s string) {
-	// s is the inner content (no quotes). Unescape via strconv.Unquote.
-	// Build a quoted string and unquote; fall back to raw on error.
-	if unq, err := strconv.Unquote("\"" + s + "\""); err == nil {
-		b.push(unq)
-		return
+	// s is the inner content (no quotes)
+	b.push(unescape(s))
+}
+
+// unescape replaces the escapes of a quoted string's content s, eg. \" or
+// \u00e9, with what they stand for. A backslash that doesn't start one is
+// kept, as the encoder writes it escaped, so that it reads back the same.
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
 	}
-	b.push(s)
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		switch e := s[i+1]; e {
+		case '\\', '"', '/':
+			b.WriteByte(e)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			r, err := strconv.ParseUint(s[i+2:min(i+6, len(s))], 16, 32)
+			if err != nil || i+6 > len(s) {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(c)
+			continue
+		}
+		i++
+	}
+	return b.String()
 }
 
 func (b *Builder) PushNumber(s string) {
@@ -124,6 +166,23 @@ func (b *Builder) PushShort(s string) {
 	b.push(Short{Sign: sign, Digits: digits, Suffix: 's'})
 }
 
+// PushByte parses a byte with 'b' suffix.
+func (b *Builder) PushByte(s string) {
+	if s == "" {
+		return
+	}
+	sign := 1
+	if s[0] == '-' {
+		sign = -1
+		s = s[1:]
+	} else if s[0] == '+' {
+		s = s[1:]
+	}
+	// strip suffix
+	digits := s[:len(s)-1]
+	b.push(Byte{Sign: sign, Digits: digits, Suffix: 'b'})
+}
+
 // PushLong parses a long with 'l' suffix.
 func (b *Builder) PushLong(s string) {
 	if s == "" {
@@ -172,3 +231,55 @@ func containsDotOrExp(s string) bool {
 	}
 	return false
 }
+
+// BeginArray starts a typed array; kind is "B", "I" or "L".
+func (b *Builder) BeginArray(kind string) {
+	switch kind {
+	case "B":
+		b.push(ByteArray{})
+	case "I":
+		b.push(IntArray{})
+	default:
+		b.push(LongArray{})
+	}
+}
+
+// ArrayAppend adds the number s, which starts at rune pos, to the array being
+// built. Numbers of the wrong type or out of the array's range are recorded
+// as an error at pos.
+func (b *Builder) ArrayAppend(s string, pos int) {
+	suffix := byte(0)
+	if n := len(s); n > 0 && (s[n-1] < '0' || s[n-1] > '9') {
+		suffix, s = s[n-1]|0x20, s[:n-1]
+	}
+	switch a := b.peek().(type) {
+	case ByteArray:
+		v, err := strconv.ParseInt(s, 10, 8)
+		if err != nil || suffix != 'b' {
+			b.fail(pos)
+			return
+		}
+		b.stack[len(b.stack)-1] = append(a, int8(v))
+	case IntArray:
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || suffix != 0 {
+			b.fail(pos)
+			return
+		}
+		b.stack[len(b.stack)-1] = append(a, int32(v))
+	case LongArray:
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || suffix != 'l' {
+			b.fail(pos)
+			return
+		}
+		b.stack[len(b.stack)-1] = append(a, v)
+	}
+}
+
+// fail records an invalid value at rune pos; only the first is kept.
+func (b *Builder) fail(pos int) {
+	if b.errPos == 0 {
+		b.errPos = pos + 1
+	}
+}
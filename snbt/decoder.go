@@ -0,0 +1,556 @@
+package snbt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Decoder reads SNBT from a stream one token at a time, so that large
+// files can be scanned, or decoded a value at a time, without holding the
+// whole input or its decoded value in memory. It accepts the same input as
+// Decode and produces the same value types.
+//
+// Several values may follow each other in the input, separated by
+// whitespace.
+type Decoder struct {
+	r *bufio.Reader
+	// stack are the compounds and lists the decoder is in.
+	stack []frame
+	// newline is set when a newline was skipped since the last token, which
+	// separates elements like a comma does.
+	newline bool
+
+	// position of the next rune
+	offset    int
+	line, col int
+	// recent are the last runes of the current line, for error snippets.
+	recent []rune
+	err    error
+}
+
+type frame struct {
+	delim Delim
+	// items is how many keys or values the container has so far.
+	items int
+	// value is set in a compound after a key, when its value is next.
+	value bool
+}
+
+// Token is a token of SNBT input: a Delim, a Key, or a value of one of the
+// types Decode returns other than compounds and lists. Typed arrays are a
+// single token.
+type Token = any
+
+// Delim is one of '{', '}', '[' or ']'.
+type Delim rune
+
+func (d Delim) String() string { return string(d) }
+
+// Key is the name of a compound's entry. Quoted keys are unescaped, like
+// quoted string values.
+type Key string
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), line: 1, col: 1}
+}
+
+// InputOffset is the byte offset of the decoder's position in the input.
+func (d *Decoder) InputOffset() int64 { return int64(d.offset) }
+
+// Token returns the next token in the input, or io.EOF at its end. A compound
+// is a '{' Delim, its entries as a Key followed by the value's tokens, and a
+// '}' Delim; a list is its values between '[' and ']'. Invalid input returns a
+// *ParseError, which is then returned by every later call.
+func (d *Decoder) Token() (Token, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	tok, err := d.token()
+	if err != nil {
+		d.err = err
+	}
+	return tok, err
+}
+
+func (d *Decoder) token() (Token, error) {
+	if len(d.stack) == 0 {
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if _, err := d.peek(); err == io.EOF {
+			return nil, io.EOF
+		}
+		return d.value()
+	}
+	f := &d.stack[len(d.stack)-1]
+	if f.value {
+		// the key read up to its value
+		f.value = false
+		return d.value()
+	}
+	if err := d.skipSpace(); err != nil {
+		return nil, err
+	}
+	r, err := d.peek()
+	if err != nil {
+		return nil, d.syntaxError(err)
+	}
+	if (f.delim == '{' && r == '}') || (f.delim == '[' && r == ']') {
+		d.next()
+		d.stack = d.stack[:len(d.stack)-1]
+		d.newline = false
+		d.skipWS()
+		return Delim(r), nil
+	}
+	if f.items > 0 {
+		switch {
+		case r == ',':
+			d.next()
+			if err := d.skipSpace(); err != nil {
+				return nil, err
+			}
+		case !d.newline:
+			return nil, d.syntaxError(nil)
+		}
+	}
+	f.items++
+	if f.delim == '[' {
+		return d.value()
+	}
+	k, err := d.key()
+	if err != nil {
+		return nil, err
+	}
+	d.skipWS()
+	if r, err := d.peek(); err != nil || r != ':' {
+		return nil, d.syntaxError(err)
+	}
+	d.next()
+	d.skipWS()
+	f.value = true
+	return k, nil
+}
+
+// More reports whether there is another element in the current compound or
+// list, or another value in the input at the top level.
+func (d *Decoder) More() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.stack) > 0 && d.stack[len(d.stack)-1].value {
+		return true
+	}
+	if err := d.skipSpace(); err != nil {
+		d.err = err
+		return false
+	}
+	r, err := d.peek()
+	return err == nil && r != '}' && r != ']'
+}
+
+// Decode reads the next value from the input, and returns it as Decode
+// would. In a compound it is called after reading the value's Key; called at
+// the end of a compound or list, it returns an error.
+func (d *Decoder) Decode() (Value, error) {
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case Delim:
+		switch tok {
+		case '{':
+			m := make(map[string]any)
+			for {
+				t, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				k, ok := t.(Key)
+				if !ok {
+					// the closing '}'
+					return m, nil
+				}
+				v, err := d.Decode()
+				if err != nil {
+					return nil, err
+				}
+				m[string(k)] = v
+			}
+		case '[':
+			l := []any{}
+			for d.More() {
+				v, err := d.Decode()
+				if err != nil {
+					return nil, err
+				}
+				l = append(l, v)
+			}
+			if _, err := d.Token(); err != nil {
+				return nil, err
+			}
+			return l, nil
+		}
+		return nil, fmt.Errorf("snbt: Decode: unexpected %q", rune(tok))
+	case Key:
+		return nil, fmt.Errorf("snbt: Decode: unexpected key %q", string(tok))
+	}
+	return tok, nil
+}
+
+// value reads a value's first token.
+func (d *Decoder) value() (Token, error) {
+	r, err := d.peek()
+	if err != nil {
+		return nil, d.syntaxError(err)
+	}
+	d.newline = false
+	var tok Token
+	switch {
+	case r == '{' || r == '[':
+		d.next()
+		d.skipWS()
+		if k, err := d.peek(); err == nil && r == '[' && (k == 'B' || k == 'I' || k == 'L') {
+			if tok, err = d.array(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		d.stack = append(d.stack, frame{delim: Delim(r)})
+		return Delim(r), nil
+	case r == '"':
+		s, err := d.quoted()
+		if err != nil {
+			return nil, err
+		}
+		tok = unescape(s)
+	case r == 't' || r == 'f':
+		word := d.word()
+		if word != "true" && word != "false" {
+			return nil, d.syntaxError(nil)
+		}
+		tok = word == "true"
+	case r == '+' || r == '-' || (r >= '0' && r <= '9'):
+		if tok, err = d.number(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, d.syntaxError(nil)
+	}
+	// eg. 10x or 1.5 have a value at their start, but aren't values
+	if r, err := d.peek(); err == nil && !strings.ContainsRune(" \t\r\n,}]#/", r) {
+		return nil, d.syntaxError(nil)
+	}
+	d.skipWS()
+	return tok, nil
+}
+
+// array reads a typed array after its '['.
+func (d *Decoder) array() (Token, error) {
+	kind, _ := d.peek()
+	d.next()
+	if r, err := d.peek(); err != nil || r != ';' {
+		return nil, d.syntaxError(err)
+	}
+	d.next()
+	d.skipWS()
+	var (
+		bytes ByteArray
+		ints  IntArray
+		longs LongArray
+	)
+	for n := 0; ; n++ {
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		r, err := d.peek()
+		if err != nil {
+			return nil, d.syntaxError(err)
+		}
+		if r == ']' {
+			d.next()
+			break
+		}
+		if n > 0 {
+			switch {
+			case r == ',':
+				d.next()
+				if err := d.skipSpace(); err != nil {
+					return nil, err
+				}
+			case !d.newline:
+				return nil, d.syntaxError(nil)
+			}
+		}
+		d.newline = false
+		var b strings.Builder
+		if r, _ := d.peek(); r == '+' || r == '-' {
+			d.next()
+			b.WriteRune(r)
+		}
+		digits := d.digits()
+		b.WriteString(digits)
+		suffix, _ := d.peek()
+		switch kind {
+		case 'B':
+			v, err := strconv.ParseInt(b.String(), 10, 8)
+			if digits == "" || err != nil || (suffix != 'b' && suffix != 'B') {
+				return nil, d.syntaxError(nil)
+			}
+			d.next()
+			bytes = append(bytes, int8(v))
+		case 'I':
+			v, err := strconv.ParseInt(b.String(), 10, 32)
+			if digits == "" || err != nil {
+				return nil, d.syntaxError(nil)
+			}
+			ints = append(ints, int32(v))
+		case 'L':
+			v, err := strconv.ParseInt(b.String(), 10, 64)
+			if digits == "" || err != nil || (suffix != 'l' && suffix != 'L') {
+				return nil, d.syntaxError(nil)
+			}
+			d.next()
+			longs = append(longs, v)
+		}
+		d.skipWS()
+	}
+	switch kind {
+	case 'B':
+		return append(ByteArray{}, bytes...), nil
+	case 'I':
+		return append(IntArray{}, ints...), nil
+	}
+	return append(LongArray{}, longs...), nil
+}
+
+// key reads an unquoted or quoted key.
+func (d *Decoder) key() (Key, error) {
+	r, err := d.peek()
+	if err != nil {
+		return "", d.syntaxError(err)
+	}
+	d.newline = false
+	if r == '"' {
+		s, err := d.quoted()
+		return Key(unescape(s)), err
+	}
+	if !(r == '_' || isLetter(r) || isDigit(r)) {
+		return "", d.syntaxError(nil)
+	}
+	var b strings.Builder
+	for {
+		r, err := d.peek()
+		if err != nil || !(r == '_' || r == '-' || r == '.' || isLetter(r) || isDigit(r)) {
+			return Key(b.String()), nil
+		}
+		d.next()
+		b.WriteRune(r)
+	}
+}
+
+// quoted reads a double quoted string and returns it as written, without the
+// quotes.
+func (d *Decoder) quoted() (string, error) {
+	d.next()
+	var b strings.Builder
+	for {
+		r, err := d.peek()
+		if err != nil {
+			return "", d.syntaxError(err)
+		}
+		d.next()
+		switch r {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			b.WriteRune(r)
+			// an escaped quote doesn't end the string, and an escaped
+			// backslash doesn't escape what follows it
+			if r, err := d.peek(); err == nil && strings.ContainsRune(`\"/bfnrt`, r) {
+				d.next()
+				b.WriteRune(r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// number reads a number and its suffix.
+func (d *Decoder) number() (Token, error) {
+	var b strings.Builder
+	sign := 1
+	if r, _ := d.peek(); r == '+' || r == '-' {
+		d.next()
+		if r == '-' {
+			sign = -1
+		}
+		b.WriteRune(r)
+	}
+	digits := d.digits()
+	if digits == "" {
+		return nil, d.syntaxError(nil)
+	}
+	b.WriteString(digits)
+	frac, dot := "", false
+	if r, err := d.peek(); err == nil && r == '.' {
+		d.next()
+		dot = true
+		if frac = d.digits(); frac == "" {
+			return nil, d.syntaxError(nil)
+		}
+	}
+	r, err := d.peek()
+	if err != nil {
+		r = 0
+	}
+	switch {
+	case r == 'd' || r == 'D':
+		d.next()
+		return Decimal{Sign: sign, Int: digits, Frac: frac, Suffix: 'd'}, nil
+	case r == 'f' || r == 'F':
+		d.next()
+		return FloatNum{Sign: sign, Int: digits, Frac: frac, Suffix: 'f'}, nil
+	case dot:
+		return nil, d.syntaxError(nil)
+	case r == 'l' || r == 'L':
+		d.next()
+		return Long{Sign: sign, Digits: digits, Suffix: 'l'}, nil
+	case r == 's' || r == 'S':
+		d.next()
+		return Short{Sign: sign, Digits: digits, Suffix: 's'}, nil
+	case r == 'b' || r == 'B':
+		d.next()
+		return Byte{Sign: sign, Digits: digits, Suffix: 'b'}, nil
+	}
+	if i, err := strconv.ParseInt(b.String(), 10, 64); err == nil {
+		return i, nil
+	}
+	return b.String(), nil
+}
+
+func (d *Decoder) digits() string {
+	var b strings.Builder
+	for {
+		r, err := d.peek()
+		if err != nil || !isDigit(r) {
+			return b.String()
+		}
+		d.next()
+		b.WriteRune(r)
+	}
+}
+
+func (d *Decoder) word() string {
+	var b strings.Builder
+	for {
+		r, err := d.peek()
+		if err != nil || !isLetter(r) {
+			return b.String()
+		}
+		d.next()
+		b.WriteRune(r)
+	}
+}
+
+// skipWS skips spaces and tabs.
+func (d *Decoder) skipWS() {
+	for {
+		r, err := d.peek()
+		if err != nil || (r != ' ' && r != '\t') {
+			return
+		}
+		d.next()
+	}
+}
+
+// skipSpace skips whitespace, newlines and comments.
+func (d *Decoder) skipSpace() error {
+	for {
+		r, err := d.peek()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch r {
+		case ' ', '\t':
+			d.next()
+		case '\r', '\n':
+			d.next()
+			d.newline = true
+		case '#', '/':
+			d.next()
+			if r == '/' {
+				if r, err := d.peek(); err != nil || r != '/' {
+					return d.syntaxError(err)
+				}
+			}
+			for {
+				r, err := d.peek()
+				if err != nil || r == '\n' || r == '\r' {
+					break
+				}
+				d.next()
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (d *Decoder) peek() (rune, error) {
+	r, _, err := d.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	d.r.UnreadRune()
+	return r, nil
+}
+
+// next consumes the rune peek returned.
+func (d *Decoder) next() {
+	r, n, _ := d.r.ReadRune()
+	d.offset += n
+	if r == '\n' {
+		d.line++
+		d.col = 1
+		d.recent = d.recent[:0]
+		return
+	}
+	d.col++
+	d.recent = append(d.recent, r)
+	if len(d.recent) >= snippetWidth {
+		d.recent = append(d.recent[:0], d.recent[len(d.recent)-snippetWidth/2:]...)
+	}
+}
+
+// syntaxError returns a *ParseError at the decoder's position, or err if
+// reading failed for another reason than the input's end.
+func (d *Decoder) syntaxError(err error) error {
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	before := d.recent
+	if len(before) > snippetWidth/2 {
+		before = before[len(before)-snippetWidth/2:]
+	}
+	snippet := []rune(string(before))
+	for len(snippet) < snippetWidth {
+		r, _, err := d.r.ReadRune()
+		if err != nil || r == '\n' || r == '\r' {
+			break
+		}
+		snippet = append(snippet, r)
+	}
+	return &ParseError{Line: d.line, Col: d.col, Offset: d.offset, Snippet: string(snippet)}
+}
+
+func isLetter(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') }
+func isDigit(r rune) bool  { return r >= '0' && r <= '9' }
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -189,3 +190,99 @@ func encodeFloat(w io.Writer, f float64) {
 	}
 	io.WriteString(w, s)
 }
+
+// EncodeIndent encodes v like Encode, but puts each compound entry and each
+// non-trivial list element on its own line, indented with tabs, in the style
+// FTB Quests writes its files. Short lists of scalars stay on one line.
+func EncodeIndent(w io.Writer, v Value) error {
+	if err := encodeIndent(w, v, 0); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// maxInlineList is the longest encoded scalar list that EncodeIndent keeps on
+// a single line.
+const maxInlineList = 80
+
+func encodeIndent(w io.Writer, v any, depth int) error {
+	switch x := v.(type) {
+	case map[string]any:
+		if len(x) == 0 {
+			io.WriteString(w, "{ }")
+			return nil
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		io.WriteString(w, "{\n")
+		for _, k := range keys {
+			writeTabs(w, depth+1)
+			encodeKey(w, k)
+			io.WriteString(w, ": ")
+			if err := encodeIndent(w, x[k], depth+1); err != nil {
+				return err
+			}
+			io.WriteString(w, "\n")
+		}
+		writeTabs(w, depth)
+		io.WriteString(w, "}")
+		return nil
+	case []any:
+		if len(x) == 0 {
+			io.WriteString(w, "[ ]")
+			return nil
+		}
+		if inline, ok := inlineList(x); ok {
+			io.WriteString(w, inline)
+			return nil
+		}
+		io.WriteString(w, "[\n")
+		for _, it := range x {
+			writeTabs(w, depth+1)
+			if err := encodeIndent(w, it, depth+1); err != nil {
+				return err
+			}
+			io.WriteString(w, "\n")
+		}
+		writeTabs(w, depth)
+		io.WriteString(w, "]")
+		return nil
+	}
+	return encodeValue(w, v)
+}
+
+// inlineList returns the single line encoding of l if it holds only scalars
+// and is short enough to keep on one line.
+func inlineList(l []any) (string, bool) {
+	for _, it := range l {
+		switch it.(type) {
+		case map[string]any, []any:
+			return "", false
+		}
+	}
+	var b strings.Builder
+	b.WriteString("[")
+	for i, it := range l {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := encodeValue(&b, it); err != nil {
+			return "", false
+		}
+	}
+	b.WriteString("]")
+	if b.Len() > maxInlineList {
+		return "", false
+	}
+	return b.String(), true
+}
+
+func writeTabs(w io.Writer, n int) {
+	for range n {
+		io.WriteString(w, "\t")
+	}
+}
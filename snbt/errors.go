@@ -1,7 +1,64 @@
 package snbt
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
 
 var (
 	ErrNotImplemented = errors.New("snbt: not implemented yet")
 )
+
+// snippetWidth is the most runes of the offending line a ParseError keeps.
+const snippetWidth = 80
+
+// ParseError is returned by Decode for input that isn't valid SNBT. Its
+// position is where the parser got furthest before failing, which is usually
+// at or just after the mistake.
+type ParseError struct {
+	// Line and Col are 1-based; Col counts runes.
+	Line, Col int
+	// Offset is the byte offset of the position in the input.
+	Offset int
+	// Snippet is the text of the line around the position.
+	Snippet string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("snbt: syntax error at line %d, column %d: %q", e.Line, e.Col, e.Snippet)
+}
+
+// newParseError locates the rune offset pos in input.
+func newParseError(input string, pos int) *ParseError {
+	e := &ParseError{Line: 1, Offset: len(input)}
+	lineStart, n := 0, 0
+	for i, r := range input {
+		if n == pos {
+			e.Offset = i
+			break
+		}
+		n++
+		if r == '\n' {
+			e.Line++
+			lineStart = i + 1
+		}
+	}
+	e.Col = utf8.RuneCountInString(input[lineStart:e.Offset]) + 1
+
+	line := input[lineStart:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimRight(line, "\r")
+	// keep a window around the position of long lines, eg. chapters written
+	// on a single line
+	runes := []rune(line)
+	if len(runes) > snippetWidth {
+		start := min(max(e.Col-1-snippetWidth/2, 0), len(runes)-snippetWidth)
+		runes = runes[start : start+snippetWidth]
+	}
+	e.Snippet = string(runes)
+	return e
+}
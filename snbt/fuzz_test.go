@@ -0,0 +1,86 @@
+package snbt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fuzzSeeds adds the real chapter files next to the tests, and a few small
+// values that have been trouble before, to the corpus of f.
+func fuzzSeeds(f *testing.F) {
+	files, _ := filepath.Glob("*.snbt")
+	for _, path := range files {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	for _, s := range []string{
+		`{}`, `[]`, `[ ]`, `[{}]`, `1`, `{a:1,}`, `{a:1b b:2.5d c:-3L}`,
+		`{x:1.0d, y:-0.5d, z:1e3f}`, `[B;1b,2b]`, `[I;1,2,3]`, `[L;1L]`,
+		`{s:"a \"quote\" and \\ slash", t:'single'}`, `{"quoted key":"ü ✓ \n"}`,
+		"{\n\ta: 1\n\tb: [\n\t\t\"x\"\n\t]\n}", `{a:true,b:false}`,
+	} {
+		f.Add([]byte(s))
+	}
+}
+
+// FuzzDecode checks that any input decodes or fails with an error, and that
+// whatever decodes can be encoded again.
+func FuzzDecode(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		if err := Encode(&buf, v); err != nil {
+			t.Fatalf("encode of decoded %q: %v", data, err)
+		}
+		buf.Reset()
+		if err := EncodeIndent(&buf, v); err != nil {
+			t.Fatalf("indented encode of decoded %q: %v", data, err)
+		}
+		DecodeLenient(bytes.NewReader(data))
+	})
+}
+
+// FuzzRoundTrip checks that a decoded value survives being encoded and
+// decoded again, and that encoding it is stable.
+func FuzzRoundTrip(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v1, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for _, encode := range []func(*bytes.Buffer, Value) error{
+			func(b *bytes.Buffer, v Value) error { return Encode(b, v) },
+			func(b *bytes.Buffer, v Value) error { return EncodeIndent(b, v) },
+		} {
+			var buf1 bytes.Buffer
+			if err := encode(&buf1, v1); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			v2, err := Decode(bytes.NewReader(buf1.Bytes()))
+			if err != nil {
+				t.Fatalf("decode of encoded %q: %v\n%s", data, err, buf1.Bytes())
+			}
+			if !reflect.DeepEqual(v1, v2) {
+				t.Fatalf("round trip of %q changed it: %s\n%s", data, diff(v1, v2, "$"), buf1.Bytes())
+			}
+			var buf2 bytes.Buffer
+			if err := encode(&buf2, v2); err != nil {
+				t.Fatalf("encode2: %v", err)
+			}
+			if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+				t.Fatalf("encoding of %q isn't stable:\n%s\n%s", data, buf1.Bytes(), buf2.Bytes())
+			}
+		}
+	})
+}
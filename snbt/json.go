@@ -0,0 +1,296 @@
+package snbt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonTags maps the tags to the suffix or array kind they stand for.
+var jsonTags = map[string]byte{
+	"$b": 'b', "$s": 's', "$l": 'l', "$f": 'f', "$d": 'd',
+	"$B": 'B', "$I": 'I', "$L": 'L',
+}
+
+// ToJSON returns v as indented JSON. SNBT has more number types than JSON, so
+// ints and doubles without a suffix are written as plain JSON numbers, and
+// the rest are tagged as objects with a single key:
+//
+//	1b           {"$b": 1}
+//	1s           {"$s": 1}
+//	1l           {"$l": 1}
+//	1.5f         {"$f": 1.5}
+//	1.5d         {"$d": 1.5}
+//	[B; 1b, 2b]  {"$B": [1, 2]}
+//	[I; 1, 2]    {"$I": [1, 2]}
+//	[L; 1l, 2l]  {"$L": [1, 2]}
+//
+// A compound key that starts with "$" gets another "$" so it can't be taken
+// for a tag. Plain JSON numbers with a decimal point or an exponent are read
+// back as doubles with the d suffix, since SNBT has no unsuffixed decimals.
+func ToJSON(v Value) ([]byte, error) {
+	j, err := toJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(j); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonNumber returns the JSON form of a number split into sign, integer and
+// fractional digits.
+func jsonNumber(sign int, i, frac string) json.Number {
+	var b strings.Builder
+	if sign < 0 {
+		b.WriteByte('-')
+	}
+	if i == "" {
+		i = "0"
+	}
+	b.WriteString(i)
+	if frac != "" {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return json.Number(b.String())
+}
+
+func tag(t string, v any) map[string]any { return map[string]any{t: v} }
+
+func toJSON(v Value) (any, error) {
+	switch x := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(x))
+		for k, e := range x {
+			j, err := toJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(k, "$") {
+				k = "$" + k
+			}
+			m[k] = j
+		}
+		return m, nil
+	case []any:
+		l := make([]any, len(x))
+		for i, e := range x {
+			j, err := toJSON(e)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = j
+		}
+		return l, nil
+	case string, bool:
+		return x, nil
+	case int:
+		return json.Number(strconv.Itoa(x)), nil
+	case int64:
+		return json.Number(strconv.FormatInt(x, 10)), nil
+	case float64:
+		var b strings.Builder
+		encodeFloat(&b, x)
+		return json.Number(b.String()), nil
+	case Byte:
+		return tag("$b", jsonNumber(x.Sign, x.Digits, "")), nil
+	case Short:
+		return tag("$s", jsonNumber(x.Sign, x.Digits, "")), nil
+	case Long:
+		return tag("$l", jsonNumber(x.Sign, x.Digits, "")), nil
+	case FloatNum:
+		return tag("$f", jsonNumber(x.Sign, x.Int, x.Frac)), nil
+	case Decimal:
+		return tag("$d", jsonNumber(x.Sign, x.Int, x.Frac)), nil
+	case ByteArray:
+		return tag("$B", arrayJSON(len(x), func(i int) int64 { return int64(x[i]) })), nil
+	case IntArray:
+		return tag("$I", arrayJSON(len(x), func(i int) int64 { return int64(x[i]) })), nil
+	case LongArray:
+		return tag("$L", arrayJSON(len(x), func(i int) int64 { return x[i] })), nil
+	}
+	return nil, fmt.Errorf("snbt: unsupported type %T", v)
+}
+
+func arrayJSON(n int, elem func(i int) int64) []any {
+	l := make([]any, n)
+	for i := range n {
+		l[i] = json.Number(strconv.FormatInt(elem(i), 10))
+	}
+	return l
+}
+
+// FromJSON reads JSON written by ToJSON, or by hand following its
+// convention, into a Value that encodes back to the SNBT it came from. JSON
+// numbers without a decimal point or exponent are ints, the rest Decimals;
+// null has no SNBT form and is an error.
+func FromJSON(data []byte) (Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var j any
+	if err := dec.Decode(&j); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("snbt: more than one JSON value")
+	}
+	return fromJSON(j, "")
+}
+
+// fromJSON converts j, found at path, which is used in errors.
+func fromJSON(j any, path string) (Value, error) {
+	switch x := j.(type) {
+	case nil:
+		return nil, fmt.Errorf("snbt: %s: null has no SNBT form", pathName(path))
+	case string, bool:
+		return x, nil
+	case json.Number:
+		s := x.String()
+		if !strings.ContainsAny(s, ".eE") {
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+		if strings.ContainsAny(s, "eE") {
+			f, err := x.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("snbt: %s: %w", pathName(path), err)
+			}
+			s = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		sign, i, frac, err := splitNumber(s)
+		if err != nil {
+			return nil, fmt.Errorf("snbt: %s: %w", pathName(path), err)
+		}
+		return Decimal{Sign: sign, Int: i, Frac: frac, Suffix: 'd'}, nil
+	case []any:
+		l := make([]any, len(x))
+		for i, e := range x {
+			v, err := fromJSON(e, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			l[i] = v
+		}
+		return l, nil
+	case map[string]any:
+		if len(x) == 1 {
+			for k, e := range x {
+				if kind, ok := jsonTags[k]; ok {
+					return fromTagged(kind, e, path)
+				}
+			}
+		}
+		m := make(map[string]any, len(x))
+		for k, e := range x {
+			v, err := fromJSON(e, path+"."+k)
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(k, "$") {
+				if !strings.HasPrefix(k, "$$") {
+					return nil, fmt.Errorf("snbt: %s: unknown tag %s in a compound; write a key starting with $ as $%s", pathName(path), k, k)
+				}
+				k = k[1:]
+			}
+			m[k] = v
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("snbt: %s: unsupported JSON %T", pathName(path), j)
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "top level"
+	}
+	return strings.TrimPrefix(path, ".")
+}
+
+// splitNumber splits the number s into sign, integer and fractional digits.
+func splitNumber(s string) (sign int, i, frac string, err error) {
+	sign = 1
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		sign, s = -1, rest
+	}
+	i, frac, _ = strings.Cut(s, ".")
+	if i == "" || strings.Trim(i, "0123456789") != "" || strings.Trim(frac, "0123456789") != "" {
+		return 0, "", "", fmt.Errorf("%q isn't a plain decimal number", s)
+	}
+	return sign, i, frac, nil
+}
+
+// fromTagged converts the value of a tag for kind, a suffix or array kind.
+func fromTagged(kind byte, j any, path string) (Value, error) {
+	fail := func(err error) (Value, error) {
+		return nil, fmt.Errorf("snbt: %s: $%c: %w", pathName(path), kind, err)
+	}
+	switch kind {
+	case 'B', 'I', 'L':
+		l, ok := j.([]any)
+		if !ok {
+			return fail(fmt.Errorf("want a list of numbers"))
+		}
+		bits := map[byte]int{'B': 8, 'I': 32, 'L': 64}[kind]
+		ns := make([]int64, len(l))
+		for i, e := range l {
+			num, ok := e.(json.Number)
+			if !ok {
+				return fail(fmt.Errorf("element %d isn't a number", i))
+			}
+			n, err := strconv.ParseInt(num.String(), 10, bits)
+			if err != nil {
+				return fail(fmt.Errorf("element %d: %w", i, err))
+			}
+			ns[i] = n
+		}
+		switch kind {
+		case 'B':
+			a := make(ByteArray, len(ns))
+			for i, n := range ns {
+				a[i] = int8(n)
+			}
+			return a, nil
+		case 'I':
+			a := make(IntArray, len(ns))
+			for i, n := range ns {
+				a[i] = int32(n)
+			}
+			return a, nil
+		}
+		return LongArray(ns), nil
+	}
+
+	num, ok := j.(json.Number)
+	if !ok {
+		return fail(fmt.Errorf("want a number"))
+	}
+	sign, i, frac, err := splitNumber(num.String())
+	if err != nil {
+		return fail(err)
+	}
+	switch kind {
+	case 'f':
+		return FloatNum{Sign: sign, Int: i, Frac: frac, Suffix: 'f'}, nil
+	case 'd':
+		return Decimal{Sign: sign, Int: i, Frac: frac, Suffix: 'd'}, nil
+	}
+	if frac != "" {
+		return fail(fmt.Errorf("%s isn't a whole number", num))
+	}
+	switch kind {
+	case 'b':
+		return Byte{Sign: sign, Digits: i, Suffix: 'b'}, nil
+	case 's':
+		return Short{Sign: sign, Digits: i, Suffix: 's'}, nil
+	}
+	return Long{Sign: sign, Digits: i, Suffix: 'l'}, nil
+}
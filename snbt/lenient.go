@@ -0,0 +1,143 @@
+package snbt
+
+import (
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// DecodeLenient parses SNBT like Decode, but skips compounds in lists that
+// don't parse rather than failing, so that one broken quest doesn't make a
+// whole chapter unreadable. It returns what could be parsed along with an
+// error for each skipped compound, positioned in the original input.
+//
+// The input is still rejected, with the error that stopped it, if a mistake
+// isn't inside a compound in a list, eg. in the top level compound itself.
+func DecodeLenient(r io.Reader) (Value, []*ParseError, error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	src := string(input)
+	work := []rune(src)
+	var skipped []*ParseError
+	for {
+		v, err := decodeString(string(work))
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			return v, skipped, err
+		}
+		// positions are in runes, which blanking out keeps in place
+		pos := utf8.RuneCountInString(string(work)[:pe.Offset])
+		start, end, at, ok := enclosingElement(work, pos)
+		pe = newParseError(src, at)
+		if !ok {
+			return nil, skipped, pe
+		}
+		skipped = append(skipped, pe)
+		blankElement(work, start, end)
+	}
+}
+
+// enclosingElement returns the runes from the opening to the closing brace
+// of the innermost compound around pos that is an element of a list, and
+// where the mistake is.
+//
+// The parser lets strings span lines, so a missing closing quote makes it
+// fail somewhere later in the file. FTB Quests never writes a line break in a
+// string, so if a string is left open at the end of a line before pos, the
+// mistake is taken to be there instead.
+func enclosingElement(src []rune, pos int) (start, end, at int, ok bool) {
+	type frame struct {
+		open  rune
+		start int
+	}
+	type span struct{ start, end int }
+	var (
+		stack    []frame
+		elements []span
+		quote    rune
+		quoteAt  int
+		unclosed = -1
+	)
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if quote != 0 {
+			switch c {
+			case '\\':
+				i++
+			case quote:
+				quote = 0
+			case '\n':
+				// scan the rest of the line again as if the quote wasn't
+				// there, so its brackets still count
+				if unclosed < 0 && quoteAt < pos {
+					unclosed = quoteAt
+				}
+				quote, i = 0, quoteAt
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote, quoteAt = c, i
+		case '{', '[':
+			stack = append(stack, frame{c, i})
+		case '}', ']':
+			open := '{'
+			if c == ']' {
+				open = '['
+			}
+			// frames left open inside this one are dropped
+			j := len(stack) - 1
+			for j >= 0 && stack[j].open != open {
+				j--
+			}
+			if j < 0 {
+				continue
+			}
+			f := stack[j]
+			stack = stack[:j]
+			if open == '{' && j > 0 && stack[j-1].open == '[' {
+				elements = append(elements, span{f.start, i})
+			}
+		}
+	}
+	if unclosed >= 0 {
+		pos = unclosed
+	}
+	best := -1
+	for _, e := range elements {
+		if e.start <= pos && pos <= e.end && e.start > best {
+			best, start, end = e.start, e.start, e.end
+		}
+	}
+	return start, end, pos, best >= 0
+}
+
+// blankElement replaces the list element from start to end, and the comma
+// separating it from its neighbors, with spaces. Line breaks are kept so
+// later errors are reported on the right line.
+func blankElement(src []rune, start, end int) {
+	for i := start; i <= end; i++ {
+		if src[i] != '\n' {
+			src[i] = ' '
+		}
+	}
+	isSpace := func(c rune) bool { return c == ' ' || c == '\t' || c == '\r' || c == '\n' }
+	i := end + 1
+	for i < len(src) && isSpace(src[i]) {
+		i++
+	}
+	if i < len(src) && src[i] == ',' {
+		src[i] = ' '
+		return
+	}
+	i = start - 1
+	for i >= 0 && isSpace(src[i]) {
+		i--
+	}
+	if i >= 0 && src[i] == ',' {
+		src[i] = ' '
+	}
+}
@@ -0,0 +1,599 @@
+package snbt
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MarshalValue returns v as a Value of the types Decode returns, to Encode.
+func MarshalValue(v any) (Value, error) {
+	val, ok, err := marshalValue(reflect.ValueOf(v), options{})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("snbt: cannot marshal nil %T", v)
+	}
+	return val, nil
+}
+
+// Marshal returns the SNBT encoding of v, mapping Go values to SNBT the way
+// encoding/json maps them to JSON. Structs are compounds, with each exported
+// field's key taken from its "snbt" tag, or its name:
+//
+//	type Quest struct {
+//		ID      string         `snbt:"id"`
+//		X       float64        `snbt:"x"`
+//		Size    float64        `snbt:"size,omitempty"`
+//		Count   int64          `snbt:"count,long"`
+//		Rest    map[string]any `snbt:",rest"`
+//		Scratch string         `snbt:"-"`
+//	}
+//
+// omitempty leaves out zero values. SNBT has no null, so nil pointers,
+// interfaces, maps and slices are always left out of compounds. A ",rest"
+// map[string]any receives the keys no other field has on Unmarshal, and is
+// written back with them on Marshal, so data a struct doesn't model survives
+// a round trip.
+//
+// Numbers are written with the suffix of their type: int8 as a byte (3b),
+// int16 as a short (3s), float32 as a float (1.5f) and float64 as a double
+// (1.5d); other integers are written plainly, or as a long (3l) with the
+// "long" option. Bools are written as true and false, or as the bytes 1b and
+// 0b with the "byte" option. The Byte, Short, Long, FloatNum and Decimal
+// types keep a number exactly as written, and ByteArray, IntArray and
+// LongArray are typed arrays.
+func Marshal(v any) ([]byte, error) {
+	val, err := MarshalValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, val); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes data and stores it in the value v points to, mapping SNBT
+// to Go values as Marshal does. A bool is read from true and false or from a
+// byte. Numbers convert between any of the number types as long as the value
+// fits, and typed arrays convert to slices.
+func Unmarshal(data []byte, v any) error {
+	val, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return UnmarshalValue(val, v)
+}
+
+// UnmarshalValue stores a decoded Value in the value v points to.
+func UnmarshalValue(val Value, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("snbt: Unmarshal needs a non-nil pointer, got %T", v)
+	}
+	return unmarshalValue(val, rv.Elem(), "")
+}
+
+// An UnmarshalTypeError is a value that can't be stored in a Go type.
+type UnmarshalTypeError struct {
+	// Value describes the SNBT value, eg. "string" or "number 300".
+	Value string
+	Type  reflect.Type
+	// Field is the path of keys to the value, eg. "quests.tasks.count".
+	Field string
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("snbt: cannot unmarshal %s into %s of type %s", e.Value, e.Field, e.Type)
+	}
+	return fmt.Sprintf("snbt: cannot unmarshal %s into Go value of type %s", e.Value, e.Type)
+}
+
+var (
+	byteType     = reflect.TypeOf(Byte{})
+	shortType    = reflect.TypeOf(Short{})
+	longType     = reflect.TypeOf(Long{})
+	floatNumType = reflect.TypeOf(FloatNum{})
+	decimalType  = reflect.TypeOf(Decimal{})
+	anyMapType   = reflect.TypeOf(map[string]any{})
+
+	byteArrayType = reflect.TypeOf(ByteArray{})
+	intArrayType  = reflect.TypeOf(IntArray{})
+	longArrayType = reflect.TypeOf(LongArray{})
+)
+
+// field is a struct field's place in a compound.
+type field struct {
+	key       string
+	index     []int
+	omitEmpty bool
+	opts      options
+}
+
+// options are how a field's value is written.
+type options struct {
+	// long writes integers as longs.
+	long bool
+	// byte writes bools as the bytes 1b and 0b.
+	byte bool
+}
+
+// structFields returns the fields of struct type t, and the index of its
+// ",rest" field, if it has one. Fields of embedded structs without a tag are
+// included as if they were t's.
+func structFields(t reflect.Type) (fields []field, rest []int) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("snbt")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			sub, subRest := structFields(f.Type)
+			for _, sf := range sub {
+				sf.index = append([]int{i}, sf.index...)
+				fields = append(fields, sf)
+			}
+			if subRest != nil && rest == nil {
+				rest = append([]int{i}, subRest...)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if hasOption(opts, "rest") {
+			if f.Type == anyMapType {
+				rest = []int{i}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, field{
+			key:       name,
+			index:     []int{i},
+			omitEmpty: hasOption(opts, "omitempty"),
+			opts:      options{long: hasOption(opts, "long"), byte: hasOption(opts, "byte")},
+		})
+	}
+	return fields, rest
+}
+
+func hasOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalValue returns v as a Value, written with opts; ok is false for nil
+// values, which SNBT can't represent.
+func marshalValue(v reflect.Value, opts options) (val Value, ok bool, err error) {
+	if !v.IsValid() {
+		return nil, false, nil
+	}
+	switch v.Type() {
+	case byteType, shortType, longType, floatNumType, decimalType:
+		return v.Interface(), true, nil
+	case byteArrayType, intArrayType, longArrayType:
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		return v.Interface(), true, nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		return marshalValue(v.Elem(), opts)
+	case reflect.String:
+		return v.String(), true, nil
+	case reflect.Bool:
+		if opts.byte {
+			return boolByte(v.Bool()), true, nil
+		}
+		return v.Bool(), true, nil
+	case reflect.Int8:
+		return byteOf(v.Int()), true, nil
+	case reflect.Int16:
+		return shortOf(v.Int()), true, nil
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if opts.long {
+			return longOf(v.Int()), true, nil
+		}
+		return v.Int(), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, false, fmt.Errorf("snbt: %d overflows a long", v.Uint())
+		}
+		if opts.long {
+			return longOf(int64(v.Uint())), true, nil
+		}
+		return int64(v.Uint()), true, nil
+	case reflect.Float32:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, false, fmt.Errorf("snbt: unsupported float %v", f)
+		}
+		sign, i, frac := splitFloat(f, 32)
+		return FloatNum{Sign: sign, Int: i, Frac: frac, Suffix: 'f'}, true, nil
+	case reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, false, fmt.Errorf("snbt: unsupported float %v", f)
+		}
+		sign, i, frac := splitFloat(f, 64)
+		return Decimal{Sign: sign, Int: i, Frac: frac, Suffix: 'd'}, true, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, false, nil
+		}
+		l := make([]any, 0, v.Len())
+		for i := range v.Len() {
+			e, ok, err := marshalValue(v.Index(i), opts)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				return nil, false, fmt.Errorf("snbt: cannot marshal nil list element %d", i)
+			}
+			l = append(l, e)
+		}
+		return l, true, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false, fmt.Errorf("snbt: unsupported map key type %s", v.Type().Key())
+		}
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		m := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			e, ok, err := marshalValue(it.Value(), opts)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				m[it.Key().String()] = e
+			}
+		}
+		return m, true, nil
+	case reflect.Struct:
+		fields, rest := structFields(v.Type())
+		m := make(map[string]any, len(fields))
+		if rest != nil {
+			for k, e := range v.FieldByIndex(rest).Interface().(map[string]any) {
+				m[k] = e
+			}
+		}
+		for _, f := range fields {
+			fv := v.FieldByIndex(f.index)
+			if f.omitEmpty && fv.IsZero() {
+				delete(m, f.key)
+				continue
+			}
+			e, ok, err := marshalValue(fv, f.opts)
+			if err != nil {
+				return nil, false, fmt.Errorf("%s: %w", f.key, err)
+			}
+			if ok {
+				m[f.key] = e
+			} else {
+				delete(m, f.key)
+			}
+		}
+		return m, true, nil
+	}
+	return nil, false, fmt.Errorf("snbt: unsupported type %s", v.Type())
+}
+
+func byteOf(i int64) Byte {
+	if i < 0 {
+		return Byte{Sign: -1, Digits: strconv.FormatInt(-i, 10), Suffix: 'b'}
+	}
+	return Byte{Sign: 1, Digits: strconv.FormatInt(i, 10), Suffix: 'b'}
+}
+
+func boolByte(b bool) Byte {
+	if b {
+		return byteOf(1)
+	}
+	return byteOf(0)
+}
+
+func shortOf(i int64) Short {
+	if i < 0 {
+		return Short{Sign: -1, Digits: strconv.FormatInt(-i, 10), Suffix: 's'}
+	}
+	return Short{Sign: 1, Digits: strconv.FormatInt(i, 10), Suffix: 's'}
+}
+
+func longOf(i int64) Long {
+	if i < 0 {
+		return Long{Sign: -1, Digits: strconv.FormatUint(uint64(-i), 10), Suffix: 'l'}
+	}
+	return Long{Sign: 1, Digits: strconv.FormatInt(i, 10), Suffix: 'l'}
+}
+
+// splitFloat returns the parts of f written in decimal without an exponent.
+func splitFloat(f float64, bits int) (sign int, i, frac string) {
+	sign = 1
+	if math.Signbit(f) {
+		sign, f = -1, -f
+	}
+	s := strconv.FormatFloat(f, 'f', -1, bits)
+	i, frac, _ = strings.Cut(s, ".")
+	return sign, i, frac
+}
+
+// number returns a numeric Value as an integer or a float; isInt reports
+// which, and ok whether val is a number at all.
+func number(val Value) (i int64, f float64, isInt, ok bool) {
+	parseInt := func(sign int, digits string) (int64, float64, bool, bool) {
+		s := digits
+		if sign < 0 {
+			s = "-" + digits
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, 0, false, false
+		}
+		return n, float64(n), true, true
+	}
+	switch x := val.(type) {
+	case int64:
+		return x, float64(x), true, true
+	case int:
+		return int64(x), float64(x), true, true
+	case float64:
+		return 0, x, false, true
+	case Byte:
+		return parseInt(x.Sign, x.Digits)
+	case Short:
+		return parseInt(x.Sign, x.Digits)
+	case Long:
+		return parseInt(x.Sign, x.Digits)
+	case FloatNum:
+		return 0, x.Float(), false, true
+	case Decimal:
+		return 0, x.Float(), false, true
+	}
+	return 0, 0, false, false
+}
+
+// describe describes val for errors.
+func describe(val Value) string {
+	switch x := val.(type) {
+	case map[string]any:
+		return "compound"
+	case []any:
+		return "list"
+	case ByteArray, IntArray, LongArray:
+		return "array " + x.(SelfEncoder).SNBT()
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case SelfEncoder:
+		return "number " + x.SNBT()
+	}
+	return fmt.Sprintf("number %v", val)
+}
+
+func unmarshalValue(val Value, v reflect.Value, path string) error {
+	mismatch := func() error {
+		return &UnmarshalTypeError{Value: describe(val), Type: v.Type(), Field: path}
+	}
+	switch v.Type() {
+	case byteType, shortType, longType, floatNumType, decimalType:
+		if reflect.TypeOf(val) == v.Type() {
+			v.Set(reflect.ValueOf(val))
+			return nil
+		}
+		if b, ok := val.(bool); ok && v.Type() == byteType {
+			v.Set(reflect.ValueOf(boolByte(b)))
+			return nil
+		}
+		i, f, isInt, ok := number(val)
+		if !ok {
+			return mismatch()
+		}
+		switch v.Type() {
+		case byteType:
+			if !isInt || i < math.MinInt8 || i > math.MaxInt8 {
+				return mismatch()
+			}
+			v.Set(reflect.ValueOf(byteOf(i)))
+		case shortType:
+			if !isInt || i < math.MinInt16 || i > math.MaxInt16 {
+				return mismatch()
+			}
+			v.Set(reflect.ValueOf(shortOf(i)))
+		case longType:
+			if !isInt {
+				return mismatch()
+			}
+			v.Set(reflect.ValueOf(longOf(i)))
+		case floatNumType:
+			sign, ip, frac := splitFloat(f, 32)
+			v.Set(reflect.ValueOf(FloatNum{Sign: sign, Int: ip, Frac: frac, Suffix: 'f'}))
+		case decimalType:
+			sign, ip, frac := splitFloat(f, 64)
+			v.Set(reflect.ValueOf(Decimal{Sign: sign, Int: ip, Frac: frac, Suffix: 'd'}))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(val, v.Elem(), path)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return mismatch()
+		}
+		v.Set(reflect.ValueOf(val))
+		return nil
+	case reflect.String:
+		s, ok := val.(string)
+		if !ok {
+			return mismatch()
+		}
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		switch x := val.(type) {
+		case bool:
+			v.SetBool(x)
+		case Byte:
+			v.SetBool(x.Bool())
+		default:
+			return mismatch()
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, f, isInt, ok := number(val)
+		if ok && !isInt && f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			i, isInt = int64(f), true
+		}
+		if !isInt || v.OverflowInt(i) {
+			return mismatch()
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, f, isInt, ok := number(val)
+		if ok && !isInt && f == math.Trunc(f) && f >= 0 && f < math.MaxInt64 {
+			i, isInt = int64(f), true
+		}
+		if !isInt || i < 0 || v.OverflowUint(uint64(i)) {
+			return mismatch()
+		}
+		v.SetUint(uint64(i))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		_, f, _, ok := number(val)
+		if !ok || v.OverflowFloat(f) {
+			return mismatch()
+		}
+		v.SetFloat(f)
+		return nil
+	case reflect.Slice:
+		l, ok := arrayElems(val)
+		if !ok {
+			return mismatch()
+		}
+		if reflect.TypeOf(val) == v.Type() {
+			v.Set(reflect.ValueOf(val))
+			return nil
+		}
+		s := reflect.MakeSlice(v.Type(), len(l), len(l))
+		for i, e := range l {
+			if err := unmarshalValue(e, s.Index(i), path); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		l, ok := arrayElems(val)
+		if !ok || len(l) != v.Len() {
+			return mismatch()
+		}
+		for i, e := range l {
+			if err := unmarshalValue(e, v.Index(i), path); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		m, ok := val.(map[string]any)
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		out := reflect.MakeMapWithSize(v.Type(), len(m))
+		for k, e := range m {
+			ev := reflect.New(v.Type().Elem()).Elem()
+			if err := unmarshalValue(e, ev, joinPath(path, k)); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), ev)
+		}
+		v.Set(out)
+		return nil
+	case reflect.Struct:
+		m, ok := val.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		fields, rest := structFields(v.Type())
+		known := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			known[f.key] = true
+			e, ok := m[f.key]
+			if !ok {
+				continue
+			}
+			if err := unmarshalValue(e, v.FieldByIndex(f.index), joinPath(path, f.key)); err != nil {
+				return err
+			}
+		}
+		if rest != nil {
+			r := make(map[string]any)
+			for k, e := range m {
+				if !known[k] {
+					r[k] = e
+				}
+			}
+			v.FieldByIndex(rest).Set(reflect.ValueOf(r))
+		}
+		return nil
+	}
+	return mismatch()
+}
+
+// arrayElems returns the elements of a list or typed array.
+func arrayElems(val Value) ([]any, bool) {
+	var l []any
+	switch x := val.(type) {
+	case []any:
+		return x, true
+	case ByteArray:
+		for _, e := range x {
+			l = append(l, int64(e))
+		}
+	case IntArray:
+		for _, e := range x {
+			l = append(l, int64(e))
+		}
+	case LongArray:
+		for _, e := range x {
+			l = append(l, e)
+		}
+	default:
+		return nil, false
+	}
+	return l, true
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
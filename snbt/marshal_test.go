@@ -0,0 +1,261 @@
+package snbt
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testTask struct {
+	ID    string `snbt:"id"`
+	Type  string `snbt:"type"`
+	Item  string `snbt:"item,omitempty"`
+	Count int64  `snbt:"count,long,omitempty"`
+}
+
+type testPos struct {
+	X float64 `snbt:"x"`
+	Y float64 `snbt:"y"`
+}
+
+type testQuest struct {
+	testPos
+	ID           string         `snbt:"id"`
+	Title        string         `snbt:"title,omitempty"`
+	Dependencies []string       `snbt:"dependencies,omitempty"`
+	Tasks        []testTask     `snbt:"tasks"`
+	Size         float32        `snbt:"size,omitempty"`
+	Rest         map[string]any `snbt:",rest"`
+	Scratch      string         `snbt:"-"`
+}
+
+func TestMarshalNumbers(t *testing.T) {
+	type numbers struct {
+		Int     int      `snbt:"int"`
+		Int64   int64    `snbt:"int64"`
+		Short   int16    `snbt:"short"`
+		Long    int64    `snbt:"long,long"`
+		Float   float32  `snbt:"float"`
+		Double  float64  `snbt:"double"`
+		Uint    uint8    `snbt:"uint"`
+		Exact   Decimal  `snbt:"exact"`
+		ExactF  FloatNum `snbt:"exact_f"`
+		ExactS  Short    `snbt:"exact_s"`
+		ExactL  Long     `snbt:"exact_l"`
+		Bool    bool     `snbt:"bool"`
+		Integer float64  `snbt:"integer"`
+	}
+	in := numbers{
+		Int: -3, Int64: 1 << 40, Short: -12, Long: 5000000000, Float: 1.5, Double: -0.25, Uint: 200,
+		Exact:  Decimal{Sign: 1, Int: "2", Frac: "50", Suffix: 'd'},
+		ExactF: FloatNum{Sign: -1, Int: "0", Frac: "10", Suffix: 'f'},
+		ExactS: Short{Sign: 1, Digits: "007", Suffix: 's'},
+		ExactL: Long{Sign: -1, Digits: "9", Suffix: 'l'},
+		Bool:   true, Integer: 4,
+	}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"int: -3", "int64: 1099511627776", "short: -12s", "long: 5000000000l", "float: 1.5f", "double: -0.25d", "uint: 200", "exact: 2.50d", "exact_f: -0.10f", "exact_s: 007s", "exact_l: -9l", "bool: true", "integer: 4d"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("%s: no %q", b, want)
+		}
+	}
+	var out numbers
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+
+	// numbers convert between types when they fit
+	var conv struct {
+		A int16    `snbt:"a"`
+		B float64  `snbt:"b"`
+		C int      `snbt:"c"`
+		D Long     `snbt:"d"`
+		E Decimal  `snbt:"e"`
+		F float32  `snbt:"f"`
+		G uint16   `snbt:"g"`
+		H FloatNum `snbt:"h"`
+	}
+	if err := Unmarshal([]byte("{ a: 3, b: 2s, c: 4.0d, d: 12, e: 3, f: 1.25d, g: 9l, h: 2 }"), &conv); err != nil {
+		t.Fatal(err)
+	}
+	if conv.A != 3 || conv.B != 2 || conv.C != 4 || conv.D.SNBT() != "12l" || conv.E.SNBT() != "3d" || conv.F != 1.25 || conv.G != 9 || conv.H.SNBT() != "2f" {
+		t.Errorf("converted = %+v", conv)
+	}
+
+	var ute *UnmarshalTypeError
+	for _, in := range []string{"{ a: 40000 }", "{ a: 1.5d }", "{ a: \"1\" }", "{ g: -1 }", "{ d: 1.5d }", "{ b: true }"} {
+		if err := Unmarshal([]byte(in), &conv); !errors.As(err, &ute) {
+			t.Errorf("Unmarshal(%s) = %v", in, err)
+		}
+	}
+	if err := Unmarshal([]byte("{ c: [1] }"), &conv); !errors.As(err, &ute) || ute.Field != "c" || ute.Value != "list" {
+		t.Errorf("error = %v", err)
+	}
+	if _, err := Marshal(struct{ F float64 }{F: 1 / zero()}); err == nil {
+		t.Error("marshalled an infinite float")
+	}
+}
+
+func zero() float64 { return 0 }
+
+func TestMarshalStruct(t *testing.T) {
+	src := `{ id: "0001", title: "Hello", x: 1.5d, y: -2.0d, size: 2.0f, shape: "gear", tasks: [{ id: "0002", type: "item", item: "minecraft:stone", count: 16l }, { id: "0003", type: "checkmark" }] }`
+	var q testQuest
+	if err := Unmarshal([]byte(src), &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.ID != "0001" || q.X != 1.5 || q.Y != -2 || q.Size != 2 || len(q.Tasks) != 2 || q.Tasks[0].Count != 16 || q.Tasks[1].Item != "" {
+		t.Errorf("quest = %+v", q)
+	}
+	if !reflect.DeepEqual(q.Rest, map[string]any{"shape": "gear"}) {
+		t.Errorf("rest = %v", q.Rest)
+	}
+
+	q.Scratch = "not written"
+	q.Title = ""
+	q.Dependencies = []string{"0009"}
+	q.Rest["title"] = "shadowed by Title"
+	b, err := Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := Decode(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatalf("%s: %v", b, err)
+	}
+	want, _ := Decode(strings.NewReader(`{ id: "0001", x: 1.5d, y: -2d, size: 2f, shape: "gear", dependencies: ["0009"], tasks: [{ id: "0002", type: "item", item: "minecraft:stone", count: 16l }, { id: "0003", type: "checkmark" }] }`))
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("marshalled %s", b)
+	}
+
+	// maps, pointers and interfaces
+	type wrap struct {
+		Quests map[string]*testQuest `snbt:"quests"`
+		Any    any                   `snbt:"any"`
+		Nil    *testQuest            `snbt:"nil"`
+	}
+	w := wrap{Quests: map[string]*testQuest{"a": {ID: "a", Tasks: []testTask{}}}, Any: []any{int64(1), "x"}}
+	b, err = Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w2 wrap
+	if err := Unmarshal(b, &w2); err != nil {
+		t.Fatal(err)
+	}
+	if w2.Quests["a"].ID != "a" || !reflect.DeepEqual(w2.Any, w.Any) || w2.Nil != nil || strings.Contains(string(b), "nil") {
+		t.Errorf("%s = %+v", b, w2)
+	}
+	if err := Unmarshal([]byte("{}"), w2); err == nil {
+		t.Error("unmarshalled into a non-pointer")
+	}
+}
+
+func TestUnmarshalChapter(t *testing.T) {
+	b, err := os.ReadFile("test_chapter.snbt")
+	if err != nil {
+		t.Skip("test_chapter.snbt not present; skipping")
+	}
+	// tasks are left untyped: items are ids or compounds, depending on the
+	// version
+	type quest struct {
+		testPos
+		ID    string           `snbt:"id"`
+		Tasks []map[string]any `snbt:"tasks,omitempty"`
+		Rest  map[string]any   `snbt:",rest"`
+	}
+	var ch struct {
+		ID     string         `snbt:"id"`
+		Quests []quest        `snbt:"quests"`
+		Rest   map[string]any `snbt:",rest"`
+	}
+	if err := Unmarshal(b, &ch); err != nil {
+		t.Fatal(err)
+	}
+	if ch.ID == "" || len(ch.Quests) == 0 {
+		t.Fatalf("chapter = %+v", ch)
+	}
+	out, err := Marshal(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// untyped fields survive; typed numbers are written with their type's
+	// suffix, so compare after both sides are unmarshalled again
+	var again = ch
+	again.Quests, again.Rest = nil, nil
+	if err := Unmarshal(out, &again); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Quests, ch.Quests) || !reflect.DeepEqual(again.Rest, ch.Rest) {
+		t.Error("chapter changed in a round trip")
+	}
+}
+
+func TestMarshalArrays(t *testing.T) {
+	type tag struct {
+		UUID  IntArray  `snbt:"uuid"`
+		Seen  LongArray `snbt:"seen,omitempty"`
+		Bytes []int8    `snbt:"bytes"`
+		Ints  []int64   `snbt:"ints"`
+	}
+	b, err := Marshal(tag{UUID: IntArray{1, -2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "[I; 1, -2]") || strings.Contains(string(b), "seen") {
+		t.Errorf("Marshal = %s", b)
+	}
+
+	var got tag
+	if err := Unmarshal([]byte(`{ uuid: [I; 3, 4], seen: [L; 5l], bytes: [B; 6b], ints: [I; 7] }`), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := tag{UUID: IntArray{3, 4}, Seen: LongArray{5}, Bytes: []int8{6}, Ints: []int64{7}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal = %+v", got)
+	}
+	// a list of numbers fills a typed array, and values must fit
+	if err := Unmarshal([]byte(`{ uuid: [1, 2] }`), &got); err != nil || !reflect.DeepEqual(got.UUID, IntArray{1, 2}) {
+		t.Errorf("list into IntArray: %v, %v", got.UUID, err)
+	}
+	if err := Unmarshal([]byte(`{ bytes: [I; 300] }`), &got); !errors.As(err, new(*UnmarshalTypeError)) {
+		t.Errorf("out of range: %v", err)
+	}
+}
+
+func TestMarshalBytes(t *testing.T) {
+	type flags struct {
+		Glow   bool `snbt:"glow,byte"`
+		Hidden bool `snbt:"hidden"`
+		Level  int8 `snbt:"level"`
+		Raw    Byte `snbt:"raw"`
+	}
+	b, err := Marshal(flags{Glow: true, Level: -3, Raw: byteOf(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"glow: 1b", "hidden: false", "level: -3b", "raw: 2b"} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("Marshal = %s, missing %q", b, s)
+		}
+	}
+
+	var got flags
+	if err := Unmarshal([]byte(`{ glow: 1b, hidden: true, level: 7b, raw: 1 }`), &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (flags{Glow: true, Hidden: true, Level: 7, Raw: byteOf(1)}); got != want {
+		t.Errorf("Unmarshal = %+v", got)
+	}
+	if err := Unmarshal([]byte(`{ raw: 300 }`), &got); !errors.As(err, new(*UnmarshalTypeError)) {
+		t.Errorf("out of range byte: %v", err)
+	}
+}
@@ -22,6 +22,29 @@ func (s Short) SNBT() string {
 	return s.Digits + string(s.Suffix)
 }
 
+// Byte preserves an SNBT byte value like "1b". Flags in item NBT are often
+// written as the bytes 0b and 1b rather than false and true.
+type Byte struct {
+	Sign   int
+	Digits string
+	Suffix byte // 'b' or 'B'
+}
+
+func (b Byte) SNBT() string {
+	if b.Suffix == 0 {
+		b.Suffix = 'b'
+	}
+	if b.Sign < 0 {
+		return "-" + b.Digits + string(b.Suffix)
+	}
+	return b.Digits + string(b.Suffix)
+}
+
+// Bool reports whether b is non-zero, as a flag.
+func (b Byte) Bool() bool {
+	return strings.Trim(b.Digits, "0") != ""
+}
+
 // Long preserves an SNBT long value like "123l".
 type Long struct {
 	Sign   int
@@ -1,6 +1,7 @@
 package snbt
 
 import (
+	"errors"
 	"io"
 )
 
@@ -10,25 +11,37 @@ import (
 // - map[string]any for compounds
 // - []any for lists
 // - string for strings
-// - float64 / int64 for numbers (initial)
+// - float64 / int64 for numbers (initial); Byte, Short, Long, FloatNum, Decimal with a suffix
 // - bool for booleans
 type Value = any
 
 // Decode parses SNBT from an io.Reader into a generic Value using the generated parser.
+// Invalid input returns a *ParseError.
 func Decode(r io.Reader) (Value, error) {
 	input, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
+	return decodeString(string(input))
+}
+
+func decodeString(input string) (Value, error) {
 	var p SNBT
-	p.Buffer = string(input)
+	p.Buffer = input
 	if err := p.Init(); err != nil {
 		return nil, err
 	}
 	if err := p.Parse(); err != nil {
+		var pe *parseError
+		if errors.As(err, &pe) {
+			return nil, newParseError(p.Buffer, int(pe.max.begin))
+		}
 		return nil, err
 	}
 	p.Execute()
+	if p.errPos > 0 {
+		return nil, newParseError(p.Buffer, p.errPos-1)
+	}
 	if len(p.stack) == 0 {
 		return nil, nil
 	}
@@ -1,6 +1,6 @@
 package snbt
 
-// Code generated by peg -switch -inline -strict -output snbt_parser.go snbt.peg DO NOT EDIT.
+// Code generated by /tmp/go-build164449099/b001/exe/peg -switch -inline -strict -output snbt_parser.go snbt.peg DO NOT EDIT.
 
 import (
 	"fmt"
@@ -25,6 +25,8 @@ const (
 	ruleKey
 	ruleList
 	ruleListItem
+	ruleArray
+	ruleArrayItem
 	ruleString
 	ruleStringInner
 	ruleEscape
@@ -35,6 +37,7 @@ const (
 	ruleFloatS
 	ruleLong
 	ruleShort
+	ruleByte
 	ruleInteger
 	ruleDigits
 	ruleSign
@@ -69,6 +72,9 @@ const (
 	ruleAction10
 	ruleAction11
 	ruleAction12
+	ruleAction13
+	ruleAction14
+	ruleAction15
 )
 
 var rul3s = [...]string{
@@ -80,6 +86,8 @@ var rul3s = [...]string{
 	"Key",
 	"List",
 	"ListItem",
+	"Array",
+	"ArrayItem",
 	"String",
 	"StringInner",
 	"Escape",
@@ -90,6 +98,7 @@ var rul3s = [...]string{
 	"FloatS",
 	"Long",
 	"Short",
+	"Byte",
 	"Integer",
 	"Digits",
 	"Sign",
@@ -124,6 +133,9 @@ var rul3s = [...]string{
 	"Action10",
 	"Action11",
 	"Action12",
+	"Action13",
+	"Action14",
+	"Action15",
 }
 
 type token32 struct {
@@ -240,7 +252,7 @@ type SNBT struct {
 
 	Buffer string
 	buffer []rune
-	rules  [52]func() bool
+	rules  [58]func() bool
 	parse  func(rule ...int) error
 	reset  func()
 	Pretty bool
@@ -353,20 +365,26 @@ func (p *SNBT) Execute() {
 		case ruleAction4:
 			p.ListAppend()
 		case ruleAction5:
-			p.PushString(text)
+			p.BeginArray(text)
 		case ruleAction6:
-			p.PushDecimal(text)
+			p.ArrayAppend(text, begin)
 		case ruleAction7:
-			p.PushFloat(text)
+			p.PushString(text)
 		case ruleAction8:
-			p.PushLong(text)
+			p.PushDecimal(text)
 		case ruleAction9:
-			p.PushShort(text)
+			p.PushFloat(text)
 		case ruleAction10:
-			p.PushNumber(text)
+			p.PushLong(text)
 		case ruleAction11:
-			p.PushBool(false)
+			p.PushShort(text)
 		case ruleAction12:
+			p.PushByte(text)
+		case ruleAction13:
+			p.PushNumber(text)
+		case ruleAction14:
+			p.PushBool(false)
+		case ruleAction15:
 			p.PushBool(true)
 
 		}
@@ -491,7 +509,7 @@ func (p *SNBT) Init(options ...func(*SNBT) error) error {
 			position, tokenIndex = position0, tokenIndex0
 			return false
 		},
-		/* 1 Value <- <(Boolean / ((&('"') String) | (&('[') List) | (&('{') Compound) | (&('+' | '-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') Number)))> */
+		/* 1 Value <- <(Array / ((&('"') String) | (&('[') List) | (&('{') Compound) | (&('F' | 'T' | 'f' | 't') Boolean) | (&('+' | '-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') Number)))> */
 		func() bool {
 			position3, tokenIndex3 := position, tokenIndex
 			{
@@ -500,221 +518,76 @@ func (p *SNBT) Init(options ...func(*SNBT) error) error {
 					position5, tokenIndex5 := position, tokenIndex
 					{
 						position7 := position
+						if !_rules[ruleLBRACKET]() {
+							goto l6
+						}
 						{
-							position8, tokenIndex8 := position, tokenIndex
+							position8 := position
 							{
-								position10 := position
-								{
-									position11, tokenIndex11 := position, tokenIndex
-									{
-										position13, tokenIndex13 := position, tokenIndex
-										if buffer[position] != rune('t') {
-											goto l14
-										}
-										position++
-										goto l13
-									l14:
-										position, tokenIndex = position13, tokenIndex13
-										if buffer[position] != rune('T') {
-											goto l12
-										}
-										position++
-									}
-								l13:
-									{
-										position15, tokenIndex15 := position, tokenIndex
-										if buffer[position] != rune('r') {
-											goto l16
-										}
-										position++
-										goto l15
-									l16:
-										position, tokenIndex = position15, tokenIndex15
-										if buffer[position] != rune('R') {
-											goto l12
-										}
-										position++
-									}
-								l15:
-									{
-										position17, tokenIndex17 := position, tokenIndex
-										if buffer[position] != rune('u') {
-											goto l18
-										}
-										position++
-										goto l17
-									l18:
-										position, tokenIndex = position17, tokenIndex17
-										if buffer[position] != rune('U') {
-											goto l12
-										}
-										position++
-									}
-								l17:
-									{
-										position19, tokenIndex19 := position, tokenIndex
-										if buffer[position] != rune('e') {
-											goto l20
-										}
-										position++
-										goto l19
-									l20:
-										position, tokenIndex = position19, tokenIndex19
-										if buffer[position] != rune('E') {
-											goto l12
-										}
-										position++
-									}
-								l19:
-									goto l11
-								l12:
-									position, tokenIndex = position11, tokenIndex11
-									if buffer[position] != rune('1') {
-										goto l9
+								switch buffer[position] {
+								case 'L':
+									if buffer[position] != rune('L') {
+										goto l6
 									}
 									position++
-									{
-										position21, tokenIndex21 := position, tokenIndex
-										if buffer[position] != rune('b') {
-											goto l22
-										}
-										position++
-										goto l21
-									l22:
-										position, tokenIndex = position21, tokenIndex21
-										if buffer[position] != rune('B') {
-											goto l9
-										}
-										position++
-									}
-								l21:
-								}
-							l11:
-								if !_rules[ruleWSP]() {
-									goto l9
-								}
-								{
-									add(ruleAction12, position)
-								}
-								add(ruleTrue, position10)
-							}
-							goto l8
-						l9:
-							position, tokenIndex = position8, tokenIndex8
-							{
-								position24 := position
-								{
-									position25, tokenIndex25 := position, tokenIndex
-									{
-										position27, tokenIndex27 := position, tokenIndex
-										if buffer[position] != rune('f') {
-											goto l28
-										}
-										position++
-										goto l27
-									l28:
-										position, tokenIndex = position27, tokenIndex27
-										if buffer[position] != rune('F') {
-											goto l26
-										}
-										position++
-									}
-								l27:
-									{
-										position29, tokenIndex29 := position, tokenIndex
-										if buffer[position] != rune('a') {
-											goto l30
-										}
-										position++
-										goto l29
-									l30:
-										position, tokenIndex = position29, tokenIndex29
-										if buffer[position] != rune('A') {
-											goto l26
-										}
-										position++
-									}
-								l29:
-									{
-										position31, tokenIndex31 := position, tokenIndex
-										if buffer[position] != rune('l') {
-											goto l32
-										}
-										position++
-										goto l31
-									l32:
-										position, tokenIndex = position31, tokenIndex31
-										if buffer[position] != rune('L') {
-											goto l26
-										}
-										position++
-									}
-								l31:
-									{
-										position33, tokenIndex33 := position, tokenIndex
-										if buffer[position] != rune('s') {
-											goto l34
-										}
-										position++
-										goto l33
-									l34:
-										position, tokenIndex = position33, tokenIndex33
-										if buffer[position] != rune('S') {
-											goto l26
-										}
-										position++
-									}
-								l33:
-									{
-										position35, tokenIndex35 := position, tokenIndex
-										if buffer[position] != rune('e') {
-											goto l36
-										}
-										position++
-										goto l35
-									l36:
-										position, tokenIndex = position35, tokenIndex35
-										if buffer[position] != rune('E') {
-											goto l26
-										}
-										position++
-									}
-								l35:
-									goto l25
-								l26:
-									position, tokenIndex = position25, tokenIndex25
-									if buffer[position] != rune('0') {
+								case 'I':
+									if buffer[position] != rune('I') {
 										goto l6
 									}
 									position++
-									{
-										position37, tokenIndex37 := position, tokenIndex
-										if buffer[position] != rune('b') {
-											goto l38
-										}
-										position++
-										goto l37
-									l38:
-										position, tokenIndex = position37, tokenIndex37
-										if buffer[position] != rune('B') {
-											goto l6
-										}
-										position++
+								default:
+									if buffer[position] != rune('B') {
+										goto l6
 									}
-								l37:
+									position++
 								}
-							l25:
-								if !_rules[ruleWSP]() {
-									goto l6
+							}
+
+							add(rulePegText, position8)
+						}
+						if buffer[position] != rune(';') {
+							goto l6
+						}
+						position++
+						if !_rules[ruleWSP]() {
+							goto l6
+						}
+						{
+							add(ruleAction5, position)
+						}
+						{
+							position11, tokenIndex11 := position, tokenIndex
+							if !_rules[rule_]() {
+								goto l11
+							}
+							if !_rules[ruleArrayItem]() {
+								goto l11
+							}
+						l13:
+							{
+								position14, tokenIndex14 := position, tokenIndex
+								if !_rules[ruleSep]() {
+									goto l14
 								}
-								{
-									add(ruleAction11, position)
+								if !_rules[ruleArrayItem]() {
+									goto l14
 								}
-								add(ruleFalse, position24)
+								goto l13
+							l14:
+								position, tokenIndex = position14, tokenIndex14
 							}
+							goto l12
+						l11:
+							position, tokenIndex = position11, tokenIndex11
 						}
-					l8:
-						add(ruleBoolean, position7)
+					l12:
+						if !_rules[rule_]() {
+							goto l6
+						}
+						if !_rules[ruleRBRACKET]() {
+							goto l6
+						}
+						add(ruleArray, position7)
 					}
 					goto l5
 				l6:
@@ -723,16 +596,16 @@ func (p *SNBT) Init(options ...func(*SNBT) error) error {
 						switch buffer[position] {
 						case '"':
 							{
-								position41 := position
+								position16 := position
 								if !_rules[ruleDQUOTE]() {
 									goto l3
 								}
 								{
-									position42 := position
+									position17 := position
 									if !_rules[ruleStringInner]() {
 										goto l3
 									}
-									add(rulePegText, position42)
+									add(rulePegText, position17)
 								}
 								if !_rules[ruleDQUOTE]() {
 									goto l3
@@ -741,74 +614,58 @@ func (p *SNBT) Init(options ...func(*SNBT) error) error {
 									goto l3
 								}
 								{
-									add(ruleAction5, position)
+									add(ruleAction7, position)
 								}
-								add(ruleString, position41)
+								add(ruleString, position16)
 							}
 						case '[':
 							{
-								position44 := position
-								{
-									position45 := position
-									if buffer[position] != rune('[') {
-										goto l3
-									}
-									position++
-									if !_rules[ruleWSP]() {
-										goto l3
-									}
-									add(ruleLBRACKET, position45)
+								position19 := position
+								if !_rules[ruleLBRACKET]() {
+									goto l3
 								}
 								{
 									add(ruleAction3, position)
 								}
 								{
-									position47, tokenIndex47 := position, tokenIndex
+									position21, tokenIndex21 := position, tokenIndex
 									if !_rules[rule_]() {
-										goto l47
+										goto l21
 									}
 									if !_rules[ruleListItem]() {
-										goto l47
+										goto l21
 									}
-								l49:
+								l23:
 									{
-										position50, tokenIndex50 := position, tokenIndex
+										position24, tokenIndex24 := position, tokenIndex
 										if !_rules[ruleSep]() {
-											goto l50
+											goto l24
 										}
 										if !_rules[ruleListItem]() {
-											goto l50
+											goto l24
 										}
-										goto l49
-									l50:
-										position, tokenIndex = position50, tokenIndex50
+										goto l23
+									l24:
+										position, tokenIndex = position24, tokenIndex24
 									}
-									goto l48
-								l47:
-									position, tokenIndex = position47, tokenIndex47
+									goto l22
+								l21:
+									position, tokenIndex = position21, tokenIndex21
 								}
-							l48:
+							l22:
 								if !_rules[rule_]() {
 									goto l3
 								}
-								{
-									position51 := position
-									if buffer[position] != rune(']') {
-										goto l3
-									}
-									position++
-									if !_rules[ruleWSP]() {
-										goto l3
-									}
-									add(ruleRBRACKET, position51)
+								if !_rules[ruleRBRACKET]() {
+									goto l3
 								}
-								add(ruleList, position44)
+								add(ruleList, position19)
 							}
 						case '{':
 							{
-								position52 := position
+								position25 := position
 								{
-									position53 := position
+									position26 := position
 									if buffer[position] != rune('{') {
 										goto l3
 									}
@@ -816,42 +673,42 @@ func (p *SNBT) Init(options ...func(*SNBT) error) error {
 									if !_rules[ruleWSP]() {
 										goto l3
 									}
-									add(ruleLBRACE, position53)
+									add(ruleLBRACE, position26)
 								}
 								{
 									add(ruleAction0, position)
 								}
 								{
-									position55, tokenIndex55 := position, tokenIndex
+									position28, tokenIndex28 := position, tokenIndex
 									if !_rules[rule_]() {
-										goto l55
+										goto l28
 									}
 									if !_rules[rulePair]() {
-										goto l55
+										goto l28
 									}
-								l57:
+								l30:
 									{
-										position58, tokenIndex58 := position, tokenIndex
+										position31, tokenIndex31 := position, tokenIndex
 										if !_rules[ruleSep]() {
-											goto l58
+											goto l31
 										}
 										if !_rules[rulePair]() {
-											goto l58
+											goto l31
 										}
-										goto l57
-									l58:
-										position, tokenIndex = position58, tokenIndex58
+										goto l30
+									l31:
+										position, tokenIndex = position31, tokenIndex31
 									}
-									goto l56
-								l55:
-									position, tokenIndex = position55, tokenIndex55
+									goto l29
+								l28:
+									position, tokenIndex = position28, tokenIndex28
 								}
-							l56:
+							l29:
 								if !_rules[rule_]() {
 									goto l3
 								}
 								{
-									position59 := position
+									position32 := position
 									if buffer[position] != rune('}') {
 										goto l3
 									}
@@ -859,253 +716,465 @@ func (p *SNBT) Init(options ...func(*SNBT) error) error {
 									if !_rules[ruleWSP]() {
 										goto l3
 									}
-									add(ruleRBRACE, position59)
+									add(ruleRBRACE, position32)
+								}
+								add(ruleCompound, position25)
+							}
+						case 'F', 'T', 'f', 't':
+							{
+								position33 := position
+								{
+									position34, tokenIndex34 := position, tokenIndex
+									{
+										position36 := position
+										{
+											position37, tokenIndex37 := position, tokenIndex
+											if buffer[position] != rune('t') {
+												goto l38
+											}
+											position++
+											goto l37
+										l38:
+											position, tokenIndex = position37, tokenIndex37
+											if buffer[position] != rune('T') {
+												goto l35
+											}
+											position++
+										}
+									l37:
+										{
+											position39, tokenIndex39 := position, tokenIndex
+											if buffer[position] != rune('r') {
+												goto l40
+											}
+											position++
+											goto l39
+										l40:
+											position, tokenIndex = position39, tokenIndex39
+											if buffer[position] != rune('R') {
+												goto l35
+											}
+											position++
+										}
+									l39:
+										{
+											position41, tokenIndex41 := position, tokenIndex
+											if buffer[position] != rune('u') {
+												goto l42
+											}
+											position++
+											goto l41
+										l42:
+											position, tokenIndex = position41, tokenIndex41
+											if buffer[position] != rune('U') {
+												goto l35
+											}
+											position++
+										}
+									l41:
+										{
+											position43, tokenIndex43 := position, tokenIndex
+											if buffer[position] != rune('e') {
+												goto l44
+											}
+											position++
+											goto l43
+										l44:
+											position, tokenIndex = position43, tokenIndex43
+											if buffer[position] != rune('E') {
+												goto l35
+											}
+											position++
+										}
+									l43:
+										if !_rules[ruleWSP]() {
+											goto l35
+										}
+										{
+											add(ruleAction15, position)
+										}
+										add(ruleTrue, position36)
+									}
+									goto l34
+								l35:
+									position, tokenIndex = position34, tokenIndex34
+									{
+										position46 := position
+										{
+											position47, tokenIndex47 := position, tokenIndex
+											if buffer[position] != rune('f') {
+												goto l48
+											}
+											position++
+											goto l47
+										l48:
+											position, tokenIndex = position47, tokenIndex47
+											if buffer[position] != rune('F') {
+												goto l3
+											}
+											position++
+										}
+									l47:
+										{
+											position49, tokenIndex49 := position, tokenIndex
+											if buffer[position] != rune('a') {
+												goto l50
+											}
+											position++
+											goto l49
+										l50:
+											position, tokenIndex = position49, tokenIndex49
+											if buffer[position] != rune('A') {
+												goto l3
+											}
+											position++
+										}
+									l49:
+										{
+											position51, tokenIndex51 := position, tokenIndex
+											if buffer[position] != rune('l') {
+												goto l52
+											}
+											position++
+											goto l51
+										l52:
+											position, tokenIndex = position51, tokenIndex51
+											if buffer[position] != rune('L') {
+												goto l3
+											}
+											position++
+										}
+									l51:
+										{
+											position53, tokenIndex53 := position, tokenIndex
+											if buffer[position] != rune('s') {
+												goto l54
+											}
+											position++
+											goto l53
+										l54:
+											position, tokenIndex = position53, tokenIndex53
+											if buffer[position] != rune('S') {
+												goto l3
+											}
+											position++
+										}
+									l53:
+										{
+											position55, tokenIndex55 := position, tokenIndex
+											if buffer[position] != rune('e') {
+												goto l56
+											}
+											position++
+											goto l55
+										l56:
+											position, tokenIndex = position55, tokenIndex55
+											if buffer[position] != rune('E') {
+												goto l3
+											}
+											position++
+										}
+									l55:
+										if !_rules[ruleWSP]() {
+											goto l3
+										}
+										{
+											add(ruleAction14, position)
+										}
+										add(ruleFalse, position46)
+									}
 								}
-								add(ruleCompound, position52)
+							l34:
+								add(ruleBoolean, position33)
 							}
 						default:
 							{
-								position60 := position
+								position58 := position
 								{
-									position61, tokenIndex61 := position, tokenIndex
+									position59, tokenIndex59 := position, tokenIndex
 									{
-										position63 := position
+										position61 := position
 										{
-											position64 := position
+											position62 := position
 											{
-												position65, tokenIndex65 := position, tokenIndex
+												position63, tokenIndex63 := position, tokenIndex
 												if !_rules[ruleSign]() {
-													goto l65
+													goto l63
 												}
-												goto l66
-											l65:
-												position, tokenIndex = position65, tokenIndex65
+												goto l64
+											l63:
+												position, tokenIndex = position63, tokenIndex63
 											}
-										l66:
+										l64:
 											if !_rules[ruleDigits]() {
-												goto l62
+												goto l60
 											}
 											{
-												position67, tokenIndex67 := position, tokenIndex
+												position65, tokenIndex65 := position, tokenIndex
 												if buffer[position] != rune('.') {
-													goto l67
+													goto l65
 												}
 												position++
 												if !_rules[ruleDigits]() {
-													goto l67
+													goto l65
 												}
-												goto l68
-											l67:
-												position, tokenIndex = position67, tokenIndex67
+												goto l66
+											l65:
+												position, tokenIndex = position65, tokenIndex65
 											}
-										l68:
+										l66:
 											{
-												position69, tokenIndex69 := position, tokenIndex
+												position67, tokenIndex67 := position, tokenIndex
 												if buffer[position] != rune('d') {
-													goto l70
+													goto l68
 												}
 												position++
-												goto l69
-											l70:
-												position, tokenIndex = position69, tokenIndex69
+												goto l67
+											l68:
+												position, tokenIndex = position67, tokenIndex67
 												if buffer[position] != rune('D') {
-													goto l62
+													goto l60
 												}
 												position++
 											}
-										l69:
-											add(rulePegText, position64)
+										l67:
+											add(rulePegText, position62)
 										}
 										if !_rules[ruleWSP]() {
-											goto l62
+											goto l60
 										}
 										{
-											add(ruleAction6, position)
+											add(ruleAction8, position)
 										}
-										add(ruleDecimal, position63)
+										add(ruleDecimal, position61)
 									}
-									goto l61
-								l62:
-									position, tokenIndex = position61, tokenIndex61
+									goto l59
+								l60:
+									position, tokenIndex = position59, tokenIndex59
 									{
-										position73 := position
+										position71 := position
 										{
-											position74 := position
+											position72 := position
 											{
-												position75, tokenIndex75 := position, tokenIndex
+												position73, tokenIndex73 := position, tokenIndex
 												if !_rules[ruleSign]() {
-													goto l75
+													goto l73
 												}
-												goto l76
-											l75:
-												position, tokenIndex = position75, tokenIndex75
+												goto l74
+											l73:
+												position, tokenIndex = position73, tokenIndex73
 											}
-										l76:
+										l74:
 											if !_rules[ruleDigits]() {
-												goto l72
+												goto l70
 											}
 											{
-												position77, tokenIndex77 := position, tokenIndex
+												position75, tokenIndex75 := position, tokenIndex
 												if buffer[position] != rune('.') {
-													goto l77
+													goto l75
 												}
 												position++
 												if !_rules[ruleDigits]() {
-													goto l77
+													goto l75
 												}
-												goto l78
-											l77:
-												position, tokenIndex = position77, tokenIndex77
+												goto l76
+											l75:
+												position, tokenIndex = position75, tokenIndex75
 											}
-										l78:
+										l76:
 											{
-												position79, tokenIndex79 := position, tokenIndex
+												position77, tokenIndex77 := position, tokenIndex
 												if buffer[position] != rune('f') {
-													goto l80
+													goto l78
 												}
 												position++
-												goto l79
-											l80:
-												position, tokenIndex = position79, tokenIndex79
+												goto l77
+											l78:
+												position, tokenIndex = position77, tokenIndex77
 												if buffer[position] != rune('F') {
-													goto l72
+													goto l70
 												}
 												position++
 											}
-										l79:
-											add(rulePegText, position74)
+										l77:
+											add(rulePegText, position72)
 										}
 										if !_rules[ruleWSP]() {
-											goto l72
+											goto l70
 										}
 										{
-											add(ruleAction7, position)
+											add(ruleAction9, position)
 										}
-										add(ruleFloatS, position73)
+										add(ruleFloatS, position71)
 									}
-									goto l61
-								l72:
-									position, tokenIndex = position61, tokenIndex61
+									goto l59
+								l70:
+									position, tokenIndex = position59, tokenIndex59
 									{
-										position83 := position
+										position81 := position
 										{
-											position84 := position
+											position82 := position
 											{
-												position85, tokenIndex85 := position, tokenIndex
+												position83, tokenIndex83 := position, tokenIndex
 												if !_rules[ruleSign]() {
-													goto l85
+													goto l83
 												}
-												goto l86
-											l85:
-												position, tokenIndex = position85, tokenIndex85
+												goto l84
+											l83:
+												position, tokenIndex = position83, tokenIndex83
 											}
-										l86:
+										l84:
 											if !_rules[ruleDigits]() {
-												goto l82
+												goto l80
 											}
 											{
-												position87, tokenIndex87 := position, tokenIndex
+												position85, tokenIndex85 := position, tokenIndex
 												if buffer[position] != rune('l') {
-													goto l88
+													goto l86
 												}
 												position++
-												goto l87
-											l88:
-												position, tokenIndex = position87, tokenIndex87
+												goto l85
+											l86:
+												position, tokenIndex = position85, tokenIndex85
 												if buffer[position] != rune('L') {
-													goto l82
+													goto l80
 												}
 												position++
 											}
-										l87:
-											add(rulePegText, position84)
+										l85:
+											add(rulePegText, position82)
 										}
 										if !_rules[ruleWSP]() {
-											goto l82
+											goto l80
 										}
 										{
-											add(ruleAction8, position)
+											add(ruleAction10, position)
 										}
-										add(ruleLong, position83)
+										add(ruleLong, position81)
 									}
-									goto l61
-								l82:
-									position, tokenIndex = position61, tokenIndex61
+									goto l59
+								l80:
+									position, tokenIndex = position59, tokenIndex59
 									{
-										position91 := position
+										position89 := position
 										{
-											position92 := position
+											position90 := position
 											{
-												position93, tokenIndex93 := position, tokenIndex
+												position91, tokenIndex91 := position, tokenIndex
 												if !_rules[ruleSign]() {
-													goto l93
+													goto l91
 												}
-												goto l94
-											l93:
-												position, tokenIndex = position93, tokenIndex93
+												goto l92
+											l91:
+												position, tokenIndex = position91, tokenIndex91
 											}
-										l94:
+										l92:
 											if !_rules[ruleDigits]() {
-												goto l90
+												goto l88
 											}
 											{
-												position95, tokenIndex95 := position, tokenIndex
+												position93, tokenIndex93 := position, tokenIndex
 												if buffer[position] != rune('s') {
-													goto l96
+													goto l94
 												}
 												position++
-												goto l95
-											l96:
-												position, tokenIndex = position95, tokenIndex95
+												goto l93
+											l94:
+												position, tokenIndex = position93, tokenIndex93
 												if buffer[position] != rune('S') {
-													goto l90
+													goto l88
 												}
 												position++
 											}
-										l95:
-											add(rulePegText, position92)
+										l93:
+											add(rulePegText, position90)
 										}
 										if !_rules[ruleWSP]() {
-											goto l90
+											goto l88
 										}
 										{
-											add(ruleAction9, position)
+											add(ruleAction11, position)
 										}
-										add(ruleShort, position91)
+										add(ruleShort, position89)
 									}
-									goto l61
-								l90:
-									position, tokenIndex = position61, tokenIndex61
+									goto l59
+								l88:
+									position, tokenIndex = position59, tokenIndex59
 									{
-										position98 := position
+										position97 := position
 										{
-											position99 := position
+											position98 := position
 											{
-												position100, tokenIndex100 := position, tokenIndex
+												position99, tokenIndex99 := position, tokenIndex
 												if !_rules[ruleSign]() {
-													goto l100
+													goto l99
 												}
+												goto l100
+											l99:
+												position, tokenIndex = position99, tokenIndex99
+											}
+										l100:
+											if !_rules[ruleDigits]() {
+												goto l96
+											}
+											{
+												position101, tokenIndex101 := position, tokenIndex
+												if buffer[position] != rune('b') {
+													goto l102
+												}
+												position++
 												goto l101
-											l100:
-												position, tokenIndex = position100, tokenIndex100
+											l102:
+												position, tokenIndex = position101, tokenIndex101
+												if buffer[position] != rune('B') {
+													goto l96
+												}
+												position++
 											}
 										l101:
+											add(rulePegText, position98)
+										}
+										if !_rules[ruleWSP]() {
+											goto l96
+										}
+										{
+											add(ruleAction12, position)
+										}
+										add(ruleByte, position97)
+									}
+									goto l59
+								l96:
+									position, tokenIndex = position59, tokenIndex59
+									{
+										position104 := position
+										{
+											position105 := position
+											{
+												position106, tokenIndex106 := position, tokenIndex
+												if !_rules[ruleSign]() {
+													goto l106
+												}
+												goto l107
+											l106:
+												position, tokenIndex = position106, tokenIndex106
+											}
+										l107:
 											if !_rules[ruleDigits]() {
 												goto l3
 											}
-											add(rulePegText, position99)
+											add(rulePegText, position105)
 										}
 										if !_rules[ruleWSP]() {
 											goto l3
 										}
 										{
-											add(ruleAction10, position)
+											add(ruleAction13, position)
 										}
-										add(ruleInteger, position98)
+										add(ruleInteger, position104)
 									}
 								}
-							l61:
-								add(ruleNumber, position60)
+							l59:
+								add(ruleNumber, position58)
 							}
 						}
 					}
@@ -1123,654 +1192,770 @@ func (p *SNBT) Init(options ...func(*SNBT) error) error {
 		nil,
 		/* 3 Pair <- <(Key COLON Value Action1)> */
 		func() bool {
-			position104, tokenIndex104 := position, tokenIndex
+			position110, tokenIndex110 := position, tokenIndex
 			{
-				position105 := position
+				position111 := position
 				{
-					position106 := position
+					position112 := position
 					{
-						position107, tokenIndex107 := position, tokenIndex
+						position113, tokenIndex113 := position, tokenIndex
 						{
-							position109 := position
+							position115 := position
 							{
 								switch buffer[position] {
 								case '_':
 									if buffer[position] != rune('_') {
-										goto l108
+										goto l114
+									}
+									position++
+								case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+									if c := buffer[position]; c < rune('0') || c > rune('9') {
+										goto l114
 									}
 									position++
 								case 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l', 'm', 'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z':
 									if c := buffer[position]; c < rune('a') || c > rune('z') {
-										goto l108
+										goto l114
 									}
 									position++
 								default:
 									if c := buffer[position]; c < rune('A') || c > rune('Z') {
-										goto l108
+										goto l114
 									}
 									position++
 								}
 							}
 
-						l111:
+						l117:
 							{
-								position112, tokenIndex112 := position, tokenIndex
+								position118, tokenIndex118 := position, tokenIndex
 								{
 									switch buffer[position] {
 									case '.':
 										if buffer[position] != rune('.') {
-											goto l112
+											goto l118
 										}
 										position++
 									case '-':
 										if buffer[position] != rune('-') {
-											goto l112
+											goto l118
 										}
 										position++
 									case '_':
 										if buffer[position] != rune('_') {
-											goto l112
+											goto l118
 										}
 										position++
 									case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 										if c := buffer[position]; c < rune('0') || c > rune('9') {
-											goto l112
+											goto l118
 										}
 										position++
 									case 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l', 'm', 'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z':
 										if c := buffer[position]; c < rune('a') || c > rune('z') {
-											goto l112
+											goto l118
 										}
 										position++
 									default:
 										if c := buffer[position]; c < rune('A') || c > rune('Z') {
-											goto l112
+											goto l118
 										}
 										position++
 									}
 								}
 
-								goto l111
-							l112:
-								position, tokenIndex = position112, tokenIndex112
+								goto l117
+							l118:
+								position, tokenIndex = position118, tokenIndex118
 							}
-							add(rulePegText, position109)
+							add(rulePegText, position115)
 						}
-						goto l107
-					l108:
-						position, tokenIndex = position107, tokenIndex107
+						goto l113
+					l114:
+						position, tokenIndex = position113, tokenIndex113
 						if !_rules[ruleDQUOTE]() {
-							goto l104
+							goto l110
 						}
 						{
-							position114 := position
+							position120 := position
 							if !_rules[ruleStringInner]() {
-								goto l104
+								goto l110
 							}
-							add(rulePegText, position114)
+							add(rulePegText, position120)
 						}
 						if !_rules[ruleDQUOTE]() {
-							goto l104
+							goto l110
 						}
 					}
-				l107:
+				l113:
 					if !_rules[ruleWSP]() {
-						goto l104
+						goto l110
 					}
 					{
 						add(ruleAction2, position)
 					}
-					add(ruleKey, position106)
+					add(ruleKey, position112)
 				}
 				{
-					position116 := position
+					position122 := position
 					if buffer[position] != rune(':') {
-						goto l104
+						goto l110
 					}
 					position++
 					if !_rules[ruleWSP]() {
-						goto l104
+						goto l110
 					}
-					add(ruleCOLON, position116)
+					add(ruleCOLON, position122)
 				}
 				if !_rules[ruleValue]() {
-					goto l104
+					goto l110
 				}
 				{
 					add(ruleAction1, position)
 				}
-				add(rulePair, position105)
+				add(rulePair, position111)
 			}
 			return true
-		l104:
-			position, tokenIndex = position104, tokenIndex104
+		l110:
+			position, tokenIndex = position110, tokenIndex110
 			return false
 		},
-		/* 4 Key <- <((<(((&('_') '_') | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z])) ((&('.') '.') | (&('-') '-') | (&('_') '_') | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]))*)> / (DQUOTE <StringInner> DQUOTE)) WSP Action2)> */
+		/* 4 Key <- <((<(((&('_') '_') | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z])) ((&('.') '.') | (&('-') '-') | (&('_') '_') | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]))*)> / (DQUOTE <StringInner> DQUOTE)) WSP Action2)> */
 		nil,
 		/* 5 List <- <(LBRACKET Action3 (_ ListItem (Sep ListItem)*)? _ RBRACKET)> */
 		nil,
 		/* 6 ListItem <- <(Value Action4)> */
 		func() bool {
-			position120, tokenIndex120 := position, tokenIndex
+			position126, tokenIndex126 := position, tokenIndex
 			{
-				position121 := position
+				position127 := position
 				if !_rules[ruleValue]() {
-					goto l120
+					goto l126
 				}
 				{
 					add(ruleAction4, position)
 				}
-				add(ruleListItem, position121)
+				add(ruleListItem, position127)
+			}
+			return true
+		l126:
+			position, tokenIndex = position126, tokenIndex126
+			return false
+		},
+		/* 7 Array <- <(LBRACKET <((&('L') 'L') | (&('I') 'I') | (&('B') 'B'))> ';' WSP Action5 (_ ArrayItem (Sep ArrayItem)*)? _ RBRACKET)> */
+		nil,
+		/* 8 ArrayItem <- <(<(Sign? Digits ((&('L') 'L') | (&('l') 'l') | (&('B') 'B') | (&('b') 'b'))?)> WSP Action6)> */
+		func() bool {
+			position130, tokenIndex130 := position, tokenIndex
+			{
+				position131 := position
+				{
+					position132 := position
+					{
+						position133, tokenIndex133 := position, tokenIndex
+						if !_rules[ruleSign]() {
+							goto l133
+						}
+						goto l134
+					l133:
+						position, tokenIndex = position133, tokenIndex133
+					}
+				l134:
+					if !_rules[ruleDigits]() {
+						goto l130
+					}
+					{
+						position135, tokenIndex135 := position, tokenIndex
+						{
+							switch buffer[position] {
+							case 'L':
+								if buffer[position] != rune('L') {
+									goto l135
+								}
+								position++
+							case 'l':
+								if buffer[position] != rune('l') {
+									goto l135
+								}
+								position++
+							case 'B':
+								if buffer[position] != rune('B') {
+									goto l135
+								}
+								position++
+							default:
+								if buffer[position] != rune('b') {
+									goto l135
+								}
+								position++
+							}
+						}
+
+						goto l136
+					l135:
+						position, tokenIndex = position135, tokenIndex135
+					}
+				l136:
+					add(rulePegText, position132)
+				}
+				if !_rules[ruleWSP]() {
+					goto l130
+				}
+				{
+					add(ruleAction6, position)
+				}
+				add(ruleArrayItem, position131)
 			}
 			return true
-		l120:
-			position, tokenIndex = position120, tokenIndex120
+		l130:
+			position, tokenIndex = position130, tokenIndex130
 			return false
 		},
-		/* 7 String <- <(DQUOTE <StringInner> DQUOTE WSP Action5)> */
+		/* 9 String <- <(DQUOTE <StringInner> DQUOTE WSP Action7)> */
 		nil,
-		/* 8 StringInner <- <(Escape / (!'"' .))*> */
+		/* 10 StringInner <- <(Escape / (!'"' .))*> */
 		func() bool {
 			{
-				position125 := position
-			l126:
+				position141 := position
+			l142:
 				{
-					position127, tokenIndex127 := position, tokenIndex
+					position143, tokenIndex143 := position, tokenIndex
 					{
-						position128, tokenIndex128 := position, tokenIndex
+						position144, tokenIndex144 := position, tokenIndex
 						{
-							position130 := position
+							position146 := position
 							{
-								position131, tokenIndex131 := position, tokenIndex
+								position147, tokenIndex147 := position, tokenIndex
 								if buffer[position] != rune('\\') {
-									goto l132
+									goto l148
 								}
 								position++
 								{
 									switch buffer[position] {
 									case 't':
 										if buffer[position] != rune('t') {
-											goto l132
+											goto l148
 										}
 										position++
 									case 'r':
 										if buffer[position] != rune('r') {
-											goto l132
+											goto l148
 										}
 										position++
 									case 'n':
 										if buffer[position] != rune('n') {
-											goto l132
+											goto l148
 										}
 										position++
 									case 'f':
 										if buffer[position] != rune('f') {
-											goto l132
+											goto l148
 										}
 										position++
 									case 'b':
 										if buffer[position] != rune('b') {
-											goto l132
+											goto l148
 										}
 										position++
 									case '/':
 										if buffer[position] != rune('/') {
-											goto l132
+											goto l148
 										}
 										position++
 									case '"':
 										if buffer[position] != rune('"') {
-											goto l132
+											goto l148
 										}
 										position++
 									default:
 										if buffer[position] != rune('\\') {
-											goto l132
+											goto l148
 										}
 										position++
 									}
 								}
 
-								goto l131
-							l132:
-								position, tokenIndex = position131, tokenIndex131
+								goto l147
+							l148:
+								position, tokenIndex = position147, tokenIndex147
 								{
-									position134 := position
+									position150 := position
 									if buffer[position] != rune('\\') {
-										goto l129
+										goto l145
 									}
 									position++
 									if buffer[position] != rune('u') {
-										goto l129
+										goto l145
 									}
 									position++
 									if !_rules[ruleHex]() {
-										goto l129
+										goto l145
 									}
 									if !_rules[ruleHex]() {
-										goto l129
+										goto l145
 									}
 									if !_rules[ruleHex]() {
-										goto l129
+										goto l145
 									}
 									if !_rules[ruleHex]() {
-										goto l129
+										goto l145
 									}
-									add(ruleUnicode, position134)
+									add(ruleUnicode, position150)
 								}
 							}
-						l131:
-							add(ruleEscape, position130)
+						l147:
+							add(ruleEscape, position146)
 						}
-						goto l128
-					l129:
-						position, tokenIndex = position128, tokenIndex128
+						goto l144
+					l145:
+						position, tokenIndex = position144, tokenIndex144
 						{
-							position135, tokenIndex135 := position, tokenIndex
+							position151, tokenIndex151 := position, tokenIndex
 							if buffer[position] != rune('"') {
-								goto l135
+								goto l151
 							}
 							position++
-							goto l127
-						l135:
-							position, tokenIndex = position135, tokenIndex135
+							goto l143
+						l151:
+							position, tokenIndex = position151, tokenIndex151
 						}
 						if !matchDot() {
-							goto l127
+							goto l143
 						}
 					}
-				l128:
-					goto l126
-				l127:
-					position, tokenIndex = position127, tokenIndex127
+				l144:
+					goto l142
+				l143:
+					position, tokenIndex = position143, tokenIndex143
 				}
-				add(ruleStringInner, position125)
+				add(ruleStringInner, position141)
 			}
 			return true
 		},
-		/* 9 Escape <- <(('\\' ((&('t') 't') | (&('r') 'r') | (&('n') 'n') | (&('f') 'f') | (&('b') 'b') | (&('/') '/') | (&('"') '"') | (&('\\') '\\'))) / Unicode)> */
+		/* 11 Escape <- <(('\\' ((&('t') 't') | (&('r') 'r') | (&('n') 'n') | (&('f') 'f') | (&('b') 'b') | (&('/') '/') | (&('"') '"') | (&('\\') '\\'))) / Unicode)> */
 		nil,
-		/* 10 Unicode <- <('\\' 'u' Hex Hex Hex Hex)> */
+		/* 12 Unicode <- <('\\' 'u' Hex Hex Hex Hex)> */
 		nil,
-		/* 11 Hex <- <((&('a' | 'b' | 'c' | 'd' | 'e' | 'f') [a-f]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F') [A-F]) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]))> */
+		/* 13 Hex <- <((&('a' | 'b' | 'c' | 'd' | 'e' | 'f') [a-f]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F') [A-F]) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]))> */
 		func() bool {
-			position138, tokenIndex138 := position, tokenIndex
+			position154, tokenIndex154 := position, tokenIndex
 			{
-				position139 := position
+				position155 := position
 				{
 					switch buffer[position] {
 					case 'a', 'b', 'c', 'd', 'e', 'f':
 						if c := buffer[position]; c < rune('a') || c > rune('f') {
-							goto l138
+							goto l154
 						}
 						position++
 					case 'A', 'B', 'C', 'D', 'E', 'F':
 						if c := buffer[position]; c < rune('A') || c > rune('F') {
-							goto l138
+							goto l154
 						}
 						position++
 					default:
 						if c := buffer[position]; c < rune('0') || c > rune('9') {
-							goto l138
+							goto l154
 						}
 						position++
 					}
 				}
 
-				add(ruleHex, position139)
+				add(ruleHex, position155)
 			}
 			return true
-		l138:
-			position, tokenIndex = position138, tokenIndex138
+		l154:
+			position, tokenIndex = position154, tokenIndex154
 			return false
 		},
-		/* 12 Number <- <(Decimal / FloatS / Long / Short / Integer)> */
+		/* 14 Number <- <(Decimal / FloatS / Long / Short / Byte / Integer)> */
+		nil,
+		/* 15 Decimal <- <(<(Sign? Digits ('.' Digits)? ('d' / 'D'))> WSP Action8)> */
 		nil,
-		/* 13 Decimal <- <(<(Sign? Digits ('.' Digits)? ('d' / 'D'))> WSP Action6)> */
+		/* 16 FloatS <- <(<(Sign? Digits ('.' Digits)? ('f' / 'F'))> WSP Action9)> */
 		nil,
-		/* 14 FloatS <- <(<(Sign? Digits ('.' Digits)? ('f' / 'F'))> WSP Action7)> */
+		/* 17 Long <- <(<(Sign? Digits ('l' / 'L'))> WSP Action10)> */
 		nil,
-		/* 15 Long <- <(<(Sign? Digits ('l' / 'L'))> WSP Action8)> */
+		/* 18 Short <- <(<(Sign? Digits ('s' / 'S'))> WSP Action11)> */
 		nil,
-		/* 16 Short <- <(<(Sign? Digits ('s' / 'S'))> WSP Action9)> */
+		/* 19 Byte <- <(<(Sign? Digits ('b' / 'B'))> WSP Action12)> */
 		nil,
-		/* 17 Integer <- <(<(Sign? Digits)> WSP Action10)> */
+		/* 20 Integer <- <(<(Sign? Digits)> WSP Action13)> */
 		nil,
-		/* 18 Digits <- <[0-9]+> */
+		/* 21 Digits <- <[0-9]+> */
 		func() bool {
-			position147, tokenIndex147 := position, tokenIndex
+			position164, tokenIndex164 := position, tokenIndex
 			{
-				position148 := position
+				position165 := position
 				if c := buffer[position]; c < rune('0') || c > rune('9') {
-					goto l147
+					goto l164
 				}
 				position++
-			l149:
+			l166:
 				{
-					position150, tokenIndex150 := position, tokenIndex
+					position167, tokenIndex167 := position, tokenIndex
 					if c := buffer[position]; c < rune('0') || c > rune('9') {
-						goto l150
+						goto l167
 					}
 					position++
-					goto l149
-				l150:
-					position, tokenIndex = position150, tokenIndex150
+					goto l166
+				l167:
+					position, tokenIndex = position167, tokenIndex167
 				}
-				add(ruleDigits, position148)
+				add(ruleDigits, position165)
 			}
 			return true
-		l147:
-			position, tokenIndex = position147, tokenIndex147
+		l164:
+			position, tokenIndex = position164, tokenIndex164
 			return false
 		},
-		/* 19 Sign <- <('+' / '-')> */
+		/* 22 Sign <- <('+' / '-')> */
 		func() bool {
-			position151, tokenIndex151 := position, tokenIndex
+			position168, tokenIndex168 := position, tokenIndex
 			{
-				position152 := position
+				position169 := position
 				{
-					position153, tokenIndex153 := position, tokenIndex
+					position170, tokenIndex170 := position, tokenIndex
 					if buffer[position] != rune('+') {
-						goto l154
+						goto l171
 					}
 					position++
-					goto l153
-				l154:
-					position, tokenIndex = position153, tokenIndex153
+					goto l170
+				l171:
+					position, tokenIndex = position170, tokenIndex170
 					if buffer[position] != rune('-') {
-						goto l151
+						goto l168
 					}
 					position++
 				}
-			l153:
-				add(ruleSign, position152)
+			l170:
+				add(ruleSign, position169)
 			}
 			return true
-		l151:
-			position, tokenIndex = position151, tokenIndex151
+		l168:
+			position, tokenIndex = position168, tokenIndex168
 			return false
 		},
-		/* 20 Boolean <- <(True / False)> */
-		nil,
-		/* 21 False <- <(((('f' / 'F') ('a' / 'A') ('l' / 'L') ('s' / 'S') ('e' / 'E')) / ('0' ('b' / 'B'))) WSP Action11)> */
-		nil,
-		/* 22 True <- <(((('t' / 'T') ('r' / 'R') ('u' / 'U') ('e' / 'E')) / ('1' ('b' / 'B'))) WSP Action12)> */
+		/* 23 Boolean <- <(True / False)> */
 		nil,
-		/* 23 LBRACE <- <('{' WSP)> */
+		/* 24 False <- <(('f' / 'F') ('a' / 'A') ('l' / 'L') ('s' / 'S') ('e' / 'E') WSP Action14)> */
 		nil,
-		/* 24 RBRACE <- <('}' WSP)> */
+		/* 25 True <- <(('t' / 'T') ('r' / 'R') ('u' / 'U') ('e' / 'E') WSP Action15)> */
 		nil,
-		/* 25 LBRACKET <- <('[' WSP)> */
+		/* 26 LBRACE <- <('{' WSP)> */
 		nil,
-		/* 26 RBRACKET <- <(']' WSP)> */
+		/* 27 RBRACE <- <('}' WSP)> */
 		nil,
-		/* 27 COLON <- <(':' WSP)> */
+		/* 28 LBRACKET <- <('[' WSP)> */
+		func() bool {
+			position177, tokenIndex177 := position, tokenIndex
+			{
+				position178 := position
+				if buffer[position] != rune('[') {
+					goto l177
+				}
+				position++
+				if !_rules[ruleWSP]() {
+					goto l177
+				}
+				add(ruleLBRACKET, position178)
+			}
+			return true
+		l177:
+			position, tokenIndex = position177, tokenIndex177
+			return false
+		},
+		/* 29 RBRACKET <- <(']' WSP)> */
+		func() bool {
+			position179, tokenIndex179 := position, tokenIndex
+			{
+				position180 := position
+				if buffer[position] != rune(']') {
+					goto l179
+				}
+				position++
+				if !_rules[ruleWSP]() {
+					goto l179
+				}
+				add(ruleRBRACKET, position180)
+			}
+			return true
+		l179:
+			position, tokenIndex = position179, tokenIndex179
+			return false
+		},
+		/* 30 COLON <- <(':' WSP)> */
 		nil,
-		/* 28 COMMA <- <','> */
+		/* 31 COMMA <- <','> */
 		nil,
-		/* 29 DQUOTE <- <'"'> */
+		/* 32 DQUOTE <- <'"'> */
 		func() bool {
-			position164, tokenIndex164 := position, tokenIndex
+			position183, tokenIndex183 := position, tokenIndex
 			{
-				position165 := position
+				position184 := position
 				if buffer[position] != rune('"') {
-					goto l164
+					goto l183
 				}
 				position++
-				add(ruleDQUOTE, position165)
+				add(ruleDQUOTE, position184)
 			}
 			return true
-		l164:
-			position, tokenIndex = position164, tokenIndex164
+		l183:
+			position, tokenIndex = position183, tokenIndex183
 			return false
 		},
-		/* 30 Sep <- <((COMMA _) / ENDL)> */
+		/* 33 Sep <- <((COMMA _) / ENDL)> */
 		func() bool {
-			position166, tokenIndex166 := position, tokenIndex
+			position185, tokenIndex185 := position, tokenIndex
 			{
-				position167 := position
+				position186 := position
 				{
-					position168, tokenIndex168 := position, tokenIndex
+					position187, tokenIndex187 := position, tokenIndex
 					{
-						position170 := position
+						position189 := position
 						if buffer[position] != rune(',') {
-							goto l169
+							goto l188
 						}
 						position++
-						add(ruleCOMMA, position170)
+						add(ruleCOMMA, position189)
 					}
 					if !_rules[rule_]() {
-						goto l169
+						goto l188
 					}
-					goto l168
-				l169:
-					position, tokenIndex = position168, tokenIndex168
+					goto l187
+				l188:
+					position, tokenIndex = position187, tokenIndex187
 					{
-						position171 := position
+						position190 := position
 						if !_rules[ruleWSP]() {
-							goto l166
+							goto l185
 						}
 						if !_rules[ruleEOL]() {
-							goto l166
+							goto l185
 						}
 						if !_rules[ruleWSP]() {
-							goto l166
+							goto l185
 						}
-					l172:
+					l191:
 						{
-							position173, tokenIndex173 := position, tokenIndex
+							position192, tokenIndex192 := position, tokenIndex
 							if !_rules[ruleWSP]() {
-								goto l173
+								goto l192
 							}
 							if !_rules[ruleEOL]() {
-								goto l173
+								goto l192
 							}
 							if !_rules[ruleWSP]() {
-								goto l173
+								goto l192
 							}
-							goto l172
-						l173:
-							position, tokenIndex = position173, tokenIndex173
+							goto l191
+						l192:
+							position, tokenIndex = position192, tokenIndex192
 						}
-						add(ruleENDL, position171)
+						add(ruleENDL, position190)
 					}
 				}
-			l168:
-				add(ruleSep, position167)
+			l187:
+				add(ruleSep, position186)
 			}
 			return true
-		l166:
-			position, tokenIndex = position166, tokenIndex166
+		l185:
+			position, tokenIndex = position185, tokenIndex185
 			return false
 		},
-		/* 31 _ <- <((&('#' | '/') Comment) | (&('\n' | '\r') EOL) | (&('\t' | ' ') WS))*> */
+		/* 34 _ <- <((&('#' | '/') Comment) | (&('\n' | '\r') EOL) | (&('\t' | ' ') WS))*> */
 		func() bool {
 			{
-				position175 := position
-			l176:
+				position194 := position
+			l195:
 				{
-					position177, tokenIndex177 := position, tokenIndex
+					position196, tokenIndex196 := position, tokenIndex
 					{
 						switch buffer[position] {
 						case '#', '/':
 							{
-								position179 := position
+								position198 := position
 								{
-									position180, tokenIndex180 := position, tokenIndex
+									position199, tokenIndex199 := position, tokenIndex
 									if buffer[position] != rune('#') {
-										goto l181
+										goto l200
 									}
 									position++
-									goto l180
-								l181:
-									position, tokenIndex = position180, tokenIndex180
+									goto l199
+								l200:
+									position, tokenIndex = position199, tokenIndex199
 									if buffer[position] != rune('/') {
-										goto l177
+										goto l196
 									}
 									position++
 									if buffer[position] != rune('/') {
-										goto l177
+										goto l196
 									}
 									position++
 								}
-							l180:
-							l182:
+							l199:
+							l201:
 								{
-									position183, tokenIndex183 := position, tokenIndex
+									position202, tokenIndex202 := position, tokenIndex
 									{
-										position184, tokenIndex184 := position, tokenIndex
+										position203, tokenIndex203 := position, tokenIndex
 										if !_rules[ruleEOL]() {
-											goto l184
+											goto l203
 										}
-										goto l183
-									l184:
-										position, tokenIndex = position184, tokenIndex184
+										goto l202
+									l203:
+										position, tokenIndex = position203, tokenIndex203
 									}
 									if !matchDot() {
-										goto l183
+										goto l202
 									}
-									goto l182
-								l183:
-									position, tokenIndex = position183, tokenIndex183
+									goto l201
+								l202:
+									position, tokenIndex = position202, tokenIndex202
 								}
 								if !_rules[ruleEOL]() {
-									goto l177
+									goto l196
 								}
-								add(ruleComment, position179)
+								add(ruleComment, position198)
 							}
 						case '\n', '\r':
 							if !_rules[ruleEOL]() {
-								goto l177
+								goto l196
 							}
 						default:
 							if !_rules[ruleWS]() {
-								goto l177
+								goto l196
 							}
 						}
 					}
 
-					goto l176
-				l177:
-					position, tokenIndex = position177, tokenIndex177
+					goto l195
+				l196:
+					position, tokenIndex = position196, tokenIndex196
 				}
-				add(rule_, position175)
+				add(rule_, position194)
 			}
 			return true
 		},
-		/* 32 WS <- <(' ' / '\t')> */
+		/* 35 WS <- <(' ' / '\t')> */
 		func() bool {
-			position185, tokenIndex185 := position, tokenIndex
+			position204, tokenIndex204 := position, tokenIndex
 			{
-				position186 := position
+				position205 := position
 				{
-					position187, tokenIndex187 := position, tokenIndex
+					position206, tokenIndex206 := position, tokenIndex
 					if buffer[position] != rune(' ') {
-						goto l188
+						goto l207
 					}
 					position++
-					goto l187
-				l188:
-					position, tokenIndex = position187, tokenIndex187
+					goto l206
+				l207:
+					position, tokenIndex = position206, tokenIndex206
 					if buffer[position] != rune('\t') {
-						goto l185
+						goto l204
 					}
 					position++
 				}
-			l187:
-				add(ruleWS, position186)
+			l206:
+				add(ruleWS, position205)
 			}
 			return true
-		l185:
-			position, tokenIndex = position185, tokenIndex185
+		l204:
+			position, tokenIndex = position204, tokenIndex204
 			return false
 		},
-		/* 33 ENDL <- <(WSP EOL WSP)+> */
+		/* 36 ENDL <- <(WSP EOL WSP)+> */
 		nil,
-		/* 34 WSP <- <WS*> */
+		/* 37 WSP <- <WS*> */
 		func() bool {
 			{
-				position191 := position
-			l192:
+				position210 := position
+			l211:
 				{
-					position193, tokenIndex193 := position, tokenIndex
+					position212, tokenIndex212 := position, tokenIndex
 					if !_rules[ruleWS]() {
-						goto l193
+						goto l212
 					}
-					goto l192
-				l193:
-					position, tokenIndex = position193, tokenIndex193
+					goto l211
+				l212:
+					position, tokenIndex = position212, tokenIndex212
 				}
-				add(ruleWSP, position191)
+				add(ruleWSP, position210)
 			}
 			return true
 		},
-		/* 35 EOL <- <(('\r' '\n') / '\r' / '\n')> */
+		/* 38 EOL <- <(('\r' '\n') / '\r' / '\n')> */
 		func() bool {
-			position194, tokenIndex194 := position, tokenIndex
+			position213, tokenIndex213 := position, tokenIndex
 			{
-				position195 := position
+				position214 := position
 				{
-					position196, tokenIndex196 := position, tokenIndex
+					position215, tokenIndex215 := position, tokenIndex
 					if buffer[position] != rune('\r') {
-						goto l197
+						goto l216
 					}
 					position++
 					if buffer[position] != rune('\n') {
-						goto l197
+						goto l216
 					}
 					position++
-					goto l196
-				l197:
-					position, tokenIndex = position196, tokenIndex196
+					goto l215
+				l216:
+					position, tokenIndex = position215, tokenIndex215
 					if buffer[position] != rune('\r') {
-						goto l198
+						goto l217
 					}
 					position++
-					goto l196
-				l198:
-					position, tokenIndex = position196, tokenIndex196
+					goto l215
+				l217:
+					position, tokenIndex = position215, tokenIndex215
 					if buffer[position] != rune('\n') {
-						goto l194
+						goto l213
 					}
 					position++
 				}
-			l196:
-				add(ruleEOL, position195)
+			l215:
+				add(ruleEOL, position214)
 			}
 			return true
-		l194:
-			position, tokenIndex = position194, tokenIndex194
+		l213:
+			position, tokenIndex = position213, tokenIndex213
 			return false
 		},
-		/* 36 Comment <- <(('#' / ('/' '/')) (!EOL .)* EOL)> */
+		/* 39 Comment <- <(('#' / ('/' '/')) (!EOL .)* EOL)> */
+		nil,
+		/* 41 Action0 <- <{ p.BeginCompound() }> */
+		nil,
+		/* 42 Action1 <- <{ p.PairSet() }> */
+		nil,
 		nil,
-		/* 38 Action0 <- <{ p.BeginCompound() }> */
+		/* 44 Action2 <- <{ p.SetKey(text) }> */
 		nil,
-		/* 39 Action1 <- <{ p.PairSet() }> */
+		/* 45 Action3 <- <{ p.BeginList() }> */
 		nil,
+		/* 46 Action4 <- <{ p.ListAppend() }> */
 		nil,
-		/* 41 Action2 <- <{ p.SetKey(text) }> */
+		/* 47 Action5 <- <{ p.BeginArray(text) }> */
 		nil,
-		/* 42 Action3 <- <{ p.BeginList() }> */
+		/* 48 Action6 <- <{ p.ArrayAppend(text, begin) }> */
 		nil,
-		/* 43 Action4 <- <{ p.ListAppend() }> */
+		/* 49 Action7 <- <{ p.PushString(text) }> */
 		nil,
-		/* 44 Action5 <- <{ p.PushString(text) }> */
+		/* 50 Action8 <- <{ p.PushDecimal(text) }> */
 		nil,
-		/* 45 Action6 <- <{ p.PushDecimal(text) }> */
+		/* 51 Action9 <- <{ p.PushFloat(text) }> */
 		nil,
-		/* 46 Action7 <- <{ p.PushFloat(text) }> */
+		/* 52 Action10 <- <{ p.PushLong(text) }> */
 		nil,
-		/* 47 Action8 <- <{ p.PushLong(text) }> */
+		/* 53 Action11 <- <{ p.PushShort(text) }> */
 		nil,
-		/* 48 Action9 <- <{ p.PushShort(text) }> */
+		/* 54 Action12 <- <{ p.PushByte(text) }> */
 		nil,
-		/* 49 Action10 <- <{ p.PushNumber(text) }> */
+		/* 55 Action13 <- <{ p.PushNumber(text) }> */
 		nil,
-		/* 50 Action11 <- <{ p.PushBool(false)}> */
+		/* 56 Action14 <- <{ p.PushBool(false)}> */
 		nil,
-		/* 51 Action12 <- <{ p.PushBool(true) }> */
+		/* 57 Action15 <- <{ p.PushBool(true) }> */
 		nil,
 	}
 	p.rules = _rules
@@ -2,9 +2,12 @@ package snbt
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -316,3 +319,338 @@ func TestParse_NewlineSeparatedPairs_NoComma(t *testing.T) {
 		t.Fatalf("decode failed: %v", err)
 	}
 }
+
+func TestEncodeIndent_RoundTrip(t *testing.T) {
+	in := `{ id: "A", quests: [ { dependencies: ["B", "C"], description: ["one", "two"], x: 1.5d } ], empty: [ ], nested: { } }`
+	v, err := Decode(bytes.NewReader([]byte(in)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := EncodeIndent(&buf, v); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	want := "{\n\tempty: [ ]\n\tid: \"A\"\n\tnested: { }\n\tquests: [\n\t\t{\n\t\t\tdependencies: [\"B\", \"C\"]\n\t\t\tdescription: [\"one\", \"two\"]\n\t\t\tx: 1.5d\n\t\t}\n\t]\n}\n"
+	if buf.String() != want {
+		t.Fatalf("indent mismatch:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	v2, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reparse: %v", err)
+	}
+	if d := diff(v, v2, ""); d != "" {
+		t.Fatalf("round-trip mismatch: %s", d)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		in             string
+		line, col, off int
+		snippet        string
+	}{
+		{"{a:", 1, 3, 2, "{a:"},
+		{"{\n\ttitle: \"ok\"\n\tx: ]\n}", 3, 4, 18, "\tx: ]"},
+		{"{ t: \"é\", x: }", 1, 13, 13, "{ t: \"é\", x: }"},
+	}
+	for _, c := range cases {
+		_, err := Decode(strings.NewReader(c.in))
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%q: error %v isn't a ParseError", c.in, err)
+			continue
+		}
+		if pe.Line != c.line || pe.Col != c.col || pe.Offset != c.off || pe.Snippet != c.snippet {
+			t.Errorf("%q: got %+v", c.in, *pe)
+		}
+	}
+
+	long := `{ title: "` + strings.Repeat("x", 200) + `", bad: ] }`
+	_, err := Decode(strings.NewReader(long))
+	var pe *ParseError
+	if !errors.As(err, &pe) || len(pe.Snippet) != snippetWidth || !strings.Contains(pe.Snippet, "bad") {
+		t.Errorf("long line: %v", err)
+	}
+}
+
+func TestDecodeLenient(t *testing.T) {
+	src := "{\n" +
+		"\ttitle: \"Chapter\"\n" +
+		"\tquests: [\n" +
+		"\t\t{ id: \"A\", x: 1.0d }\n" +
+		"\t\t{ id: \"B\", x: }\n" +
+		"\t\t{ id: \"C\", title: \"unclosed }\n" +
+		"\t\t{ id: \"D\", tasks: [{ item: \"minecraft:dirt\" }] }\n" +
+		"\t]\n" +
+		"\tlinks: [{ id: \"E\" }, { id: \"F\", y: : 1 }]\n" +
+		"}\n"
+	v, skipped, err := DecodeLenient(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, q := range v.(map[string]any)["quests"].([]any) {
+		ids = append(ids, q.(map[string]any)["id"].(string))
+	}
+	if strings.Join(ids, " ") != "A D" {
+		t.Errorf("quests = %v", ids)
+	}
+	if links := v.(map[string]any)["links"].([]any); len(links) != 1 {
+		t.Errorf("links = %v", links)
+	}
+	var lines []int
+	for _, pe := range skipped {
+		lines = append(lines, pe.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{5, 6, 9}) {
+		t.Errorf("skipped on lines %v", lines)
+	}
+
+	// valid input decodes as with Decode
+	if _, skipped, err := DecodeLenient(strings.NewReader(`{ a: [{ b: 1 }] }`)); err != nil || len(skipped) != 0 {
+		t.Errorf("valid input: %v, %v", skipped, err)
+	}
+	// mistakes outside a list element can't be skipped
+	if _, _, err := DecodeLenient(strings.NewReader("{ a: 1, b: }")); !errors.As(err, new(*ParseError)) {
+		t.Errorf("top level mistake: %v", err)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	inputs := []string{
+		`{ title: "Hello", count: 1, active: true, tags: ["a", "b"] }`,
+		"{\n\ta: 1b\n\tb: 0b, c: -2.5d, d: 3f\n\t\"quoted key\": 4L\n\te: 5s\n}",
+		`["esc \"q\" \\", "\u00e9\n", [], {}, [[1], [2, 3]], +7, 99999999999999999999]`,
+		"# comment\n{ x: [\n\t{ a: 1 }\n\t{ a: 2 }\n] } // done\n",
+		"{ completed: { 0A1B2C3D4E5F6789: 1700000000000L, 9F: 1L } }",
+		"42",
+	}
+	for _, name := range []string{"test_chapter.snbt", "test_rt.snbt", "test_rt2.snbt"} {
+		if b, err := os.ReadFile(name); err == nil {
+			inputs = append(inputs, string(b))
+		}
+	}
+	for _, in := range inputs {
+		want, err := Decode(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("Decode(%.40q): %v", in, err)
+		}
+		d := NewDecoder(strings.NewReader(in))
+		got, err := d.Decode()
+		if err != nil {
+			t.Errorf("Decoder.Decode(%.40q): %v", in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decoder.Decode(%.40q) = %v, want %v", in, got, want)
+		}
+		if _, err := d.Token(); err != io.EOF {
+			t.Errorf("after %.40q: %v, want EOF", in, err)
+		}
+	}
+
+	// a value at a time from a list
+	d := NewDecoder(strings.NewReader(`{ quests: [{ id: "a" }, { id: "b" }] }`))
+	var toks []Token
+	for len(toks) < 3 {
+		tok, err := d.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		toks = append(toks, tok)
+	}
+	if !reflect.DeepEqual(toks, []Token{Delim('{'), Key("quests"), Delim('[')}) {
+		t.Fatalf("tokens = %v", toks)
+	}
+	var ids []string
+	for d.More() {
+		v, err := d.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, v.(map[string]any)["id"].(string))
+	}
+	if fmt.Sprint(ids) != "[a b]" {
+		t.Errorf("ids = %v", ids)
+	}
+
+	for _, in := range []string{"{ a: 1 b: 2 }", "[1, 2, ]", "{ a:\n1 }", "1.5", "10x", `{ a: "x }`, "[1, 2"} {
+		if _, err := Decode(strings.NewReader(in)); err == nil {
+			t.Fatalf("Decode(%q) succeeded", in)
+		}
+		_, err := NewDecoder(strings.NewReader(in)).Decode()
+		if err == nil {
+			t.Errorf("Decoder.Decode(%q) succeeded", in)
+			continue
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Line != 1 {
+			t.Errorf("Decoder.Decode(%q) = %v", in, err)
+		}
+	}
+	pe := new(ParseError)
+	d = NewDecoder(strings.NewReader("{\n\ta: 1\n\tb: ]\n}"))
+	_, err := d.Decode()
+	if !errors.As(err, &pe) || pe.Line != 3 || pe.Col != 5 || pe.Offset != 12 || pe.Snippet != "\tb: ]" {
+		t.Errorf("error = %#v", err)
+	}
+}
+
+func TestArrays(t *testing.T) {
+	in := `{ bytes: [B; 1b, -128b, 127B], ints: [I; 1, -2147483648], longs: [L; 5l, -9L], empty: [I;], nested: [[B; 0b], [L;]] }`
+	v, err := Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"bytes":  ByteArray{1, -128, 127},
+		"ints":   IntArray{1, -2147483648},
+		"longs":  LongArray{5, -9},
+		"empty":  IntArray{},
+		"nested": []any{ByteArray{0}, LongArray{}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("Decode = %#v", v)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"[B; 1b, -128b, 127b]", "[I; 1, -2147483648]", "[L; 5l, -9l]", "[I;]"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("encoded %q doesn't contain %q", buf.String(), s)
+		}
+	}
+	rt, err := Decode(&buf)
+	if err != nil || !reflect.DeepEqual(rt, want) {
+		t.Errorf("round trip = %#v, %v", rt, err)
+	}
+
+	d := NewDecoder(strings.NewReader(in))
+	if got, err := d.Decode(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Decoder.Decode = %#v, %v", got, err)
+	}
+
+	for _, bad := range []string{"[B; 128b]", "[B; 1]", "[I; 2147483648]", "[I; 1l]", "[L; 1]", "[B; 1b, 2b, x]", "[X; 1]"} {
+		_, err := Decode(strings.NewReader(bad))
+		if !errors.As(err, new(*ParseError)) {
+			t.Errorf("Decode(%q): %v, want a ParseError", bad, err)
+		}
+		if _, err := NewDecoder(strings.NewReader(bad)).Decode(); !errors.As(err, new(*ParseError)) {
+			t.Errorf("Decoder.Decode(%q): %v, want a ParseError", bad, err)
+		}
+	}
+	var pe *ParseError
+	if _, err := Decode(strings.NewReader("[I; 1, 99999999999]")); !errors.As(err, &pe) || pe.Col != 8 {
+		t.Errorf("out of range error: %v", err)
+	}
+}
+
+func TestByte(t *testing.T) {
+	in := `{ a: 1b, b: -5B, c: 0b, d: true, e: [0b, 127b] }`
+	v, err := Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]any)
+	if m["a"] != (Byte{Sign: 1, Digits: "1", Suffix: 'b'}) || m["d"] != true {
+		t.Errorf("Decode = %#v", m)
+	}
+	if !m["a"].(Byte).Bool() || m["c"].(Byte).Bool() {
+		t.Errorf("Bool: 1b = %v, 0b = %v", m["a"].(Byte).Bool(), m["c"].(Byte).Bool())
+	}
+	if got, err := NewDecoder(strings.NewReader(in)).Decode(); err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("Decoder.Decode = %#v, %v", got, err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"a: 1b", "b: -5b", "c: 0b", "d: true", "0b, 127b"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Encode = %s, missing %q", buf.String(), s)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	for _, c := range []struct{ in, want string }{
+		{`plain`, "plain"},
+		{`a \"b\" \\ c\/d`, `a "b" \ c/d`},
+		{`line\nnext\ttab`, "line\nnext\ttab"},
+		{`é\u001b`, "é\x1b"},
+		{"raw\nnewline \\\"", "raw\nnewline \""},
+		{`\q \u12 end\`, `\q \u12 end\`},
+	} {
+		if got := unescape(c.in); got != c.want {
+			t.Errorf("unescape(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+	v, err := Decode(strings.NewReader(`{"k\"ey": "v\/al", plain: "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := v.(map[string]any); m[`k"ey`] != "v/al" {
+		t.Errorf("decoded %#v", m)
+	}
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	in := `{ "$weird": 1, count: 16l, flag: 1b, dmg: -3s, size: 0.5f, x: -0.75d, n: 7, name: "a\"b", on: true, list: [ { a: 1 } ], ba: [B; 1b, -2b], ia: [I; 3], la: [L; 4l] }`
+	v, err := Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ToJSON(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"$$weird": 1`, `"$l": 16`, `"$b": 1`, `"$s": -3`, `"$f": 0.5`, `"$d": -0.75`, `"n": 7`, `"$B": [`} {
+		if !bytes.Contains(b, []byte(want)) {
+			t.Errorf("json lacks %s:\n%s", want, b)
+		}
+	}
+	back, err := FromJSON(b)
+	if err != nil {
+		t.Fatalf("from json: %v\n%s", err, b)
+	}
+	var want, got bytes.Buffer
+	if err := Encode(&want, v); err != nil {
+		t.Fatal(err)
+	}
+	if err := Encode(&got, back); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("round trip:\n got %s\nwant %s", got.String(), want.String())
+	}
+
+	f, err := os.ReadFile("test_chapter.snbt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := Decode(bytes.NewReader(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err = ToJSON(ch); err != nil {
+		t.Fatal(err)
+	}
+	if back, err = FromJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ch, back) {
+		t.Error("the sample chapter changed in a round trip through JSON")
+	}
+
+	if v, err := FromJSON([]byte(`[1.50, 2e3, 3]`)); err != nil || !reflect.DeepEqual(v, []any{Decimal{1, "1", "50", 'd'}, Decimal{1, "2000", "", 'd'}, int64(3)}) {
+		t.Errorf("plain numbers = %#v, %v", v, err)
+	}
+
+	for _, bad := range []string{`null`, `{"a": null}`, `{"$x": 1, "b": 2}`, `{"$b": 1.5}`, `{"$B": [300]}`, `{"$s": "1"}`, `1 2`} {
+		if _, err := FromJSON([]byte(bad)); err == nil {
+			t.Errorf("FromJSON(%s) should fail", bad)
+		}
+	}
+}